@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/01moynul/taptosell-golang/internal/database"
+	"github.com/01moynul/taptosell-golang/internal/pii"
+)
+
+// encryptBatchSize is how many rows encrypt-pii reads per query.
+const encryptBatchSize = 500
+
+// piiColumn is one encrypted column and how to address its rows.
+type piiColumn struct {
+	name  string // pii column constant, e.g. pii.UserICNumber
+	table string
+	field string
+}
+
+var piiColumns = []piiColumn{
+	{pii.UserICNumber, "users", "ic_number"},
+	{pii.UserSSMNumber, "users", "ssm_number"},
+	{pii.WithdrawalBankDetails, "withdrawal_requests", "bank_details"},
+	{pii.MessagingSecret, "messaging_providers", "secret"},
+	{pii.WebhookSecret, "webhook_endpoints", "secret"},
+}
+
+// piiKeys converts the configured PII keys for the pii package.
+func piiKeys(keys []config.EncryptionKey) []pii.Key {
+	out := make([]pii.Key, len(keys))
+	for i, k := range keys {
+		out[i] = pii.Key{ID: k.ID, Key: k.Key}
+	}
+	return out
+}
+
+// encryptPIICommand implements `api encrypt-pii`: every plaintext value, and
+// every value sealed with an older key, is rewritten with the current key.
+// It is safe to re-run; values that are already current are skipped.
+func encryptPIICommand() error {
+	keys, err := config.LoadPIIKeys()
+	if err != nil {
+		return err
+	}
+	cipher, err := pii.New(piiKeys(keys))
+	if err != nil {
+		return err
+	}
+	dbCfg, err := config.LoadDB()
+	if err != nil {
+		return err
+	}
+	db, err := database.OpenDB(dbCfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	for _, col := range piiColumns {
+		n, err := encryptColumn(ctx, db, cipher, col)
+		if err != nil {
+			return fmt.Errorf("%s: %w", col.name, err)
+		}
+		log.Printf("🔒 %s: %d value(s) encrypted", col.name, n)
+	}
+	return nil
+}
+
+// encryptColumn walks col by primary key and rewrites values that are not
+// sealed with the current key. Each row is updated only if it is unchanged
+// since it was read, so it can run against a live database.
+func encryptColumn(ctx context.Context, db *sql.DB, cipher *pii.Cipher, col piiColumn) (int, error) {
+	selectQuery := fmt.Sprintf(
+		"SELECT id, %[1]s FROM %[2]s WHERE id > ? AND %[1]s IS NOT NULL AND %[1]s <> '' ORDER BY id LIMIT ?",
+		col.field, col.table)
+	updateQuery := fmt.Sprintf("UPDATE %[2]s SET %[1]s = ? WHERE id = ? AND %[1]s = ?", col.field, col.table)
+
+	type row struct {
+		id     int64
+		stored string
+	}
+	var lastID int64
+	updated := 0
+	for {
+		rows, err := db.QueryContext(ctx, selectQuery, lastID, encryptBatchSize)
+		if err != nil {
+			return updated, err
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.stored); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, err
+		}
+		if len(batch) == 0 {
+			return updated, nil
+		}
+
+		for _, r := range batch {
+			lastID = r.id
+			if cipher.Current(r.stored) {
+				continue
+			}
+			plain, err := cipher.Decrypt(col.name, r.stored)
+			if err != nil {
+				return updated, fmt.Errorf("row %d: %w", r.id, err)
+			}
+			sealed, err := cipher.Encrypt(col.name, plain)
+			if err != nil {
+				return updated, err
+			}
+			res, err := db.ExecContext(ctx, updateQuery, sealed, r.id, r.stored)
+			if err != nil {
+				return updated, fmt.Errorf("row %d: %w", r.id, err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				updated++
+			}
+		}
+	}
+}
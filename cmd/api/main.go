@@ -1,14 +1,36 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/01moynul/taptosell-golang/internal/ai" // ADDED: Import AI package
+	"github.com/01moynul/taptosell-golang/internal/audit"
+	"github.com/01moynul/taptosell-golang/internal/auth"
+	"github.com/01moynul/taptosell-golang/internal/backup"
+	"github.com/01moynul/taptosell-golang/internal/cache"
+	"github.com/01moynul/taptosell-golang/internal/captcha"
+	"github.com/01moynul/taptosell-golang/internal/config"
 	"github.com/01moynul/taptosell-golang/internal/database"
+	"github.com/01moynul/taptosell-golang/internal/errreport"
+	"github.com/01moynul/taptosell-golang/internal/events"
 	"github.com/01moynul/taptosell-golang/internal/handlers"
+	"github.com/01moynul/taptosell-golang/internal/i18n"
+	"github.com/01moynul/taptosell-golang/internal/imageproc"
+	"github.com/01moynul/taptosell-golang/internal/jobs"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/pii"
+	"github.com/01moynul/taptosell-golang/internal/retention"
 	"github.com/01moynul/taptosell-golang/internal/routes"
+	"github.com/01moynul/taptosell-golang/internal/settings"
+	"github.com/01moynul/taptosell-golang/internal/status"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/01moynul/taptosell-golang/internal/tracing"
+	"github.com/01moynul/taptosell-golang/internal/uploads"
+	"github.com/01moynul/taptosell-golang/internal/webhooks"
 	"github.com/joho/godotenv"
 )
 
@@ -18,34 +40,85 @@ func main() {
 		log.Println("WARNING: Could not find or load .env file. Relying on system environment variables.")
 	}
 
+	// 0a. --- `api migrate ...` & `api encrypt-pii` Subcommands ---
+	// Schema management only needs the primary database settings; it exits when done.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrateCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// encrypt-pii rewrites existing IC, SSM & bank details with the current PII key.
+	if len(os.Args) > 1 && os.Args[1] == "encrypt-pii" {
+		if err := encryptPIICommand(); err != nil {
+			log.Fatalf("PII encryption failed: %v", err)
+		}
+		return
+	}
+
+	// 0b. --- Load & Validate Configuration ---
+	// Every missing or malformed variable is reported at once.
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("CRITICAL ERROR: %v", err)
+	}
+	auth.Configure(authKeys(cfg.Auth.JWTKeys), cfg.Auth.TokenTTL)
+	logLevel, _ := logging.ParseLevel(cfg.LogLevel) // validated by config.Load
+	logging.SetLevel(logLevel)
+
+	// 0c. --- Message Catalogs (Accept-Language translations) ---
+	if err := i18n.Load(cfg.I18n.Dir); err != nil {
+		log.Fatalf("Failed to load message catalogs: %v", err)
+	}
+
+	// 0d. --- Tracing (OpenTelemetry, off without OTEL_EXPORTER_OTLP_ENDPOINT) ---
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
 	// 1. --- Main Database Connection (Read/Write) ---
-	db, err := database.OpenDB()
+	db, err := database.OpenDB(cfg.DB)
 	if err != nil {
 		log.Fatalf("Failed to connect to primary database: %v", err)
 	}
 	defer db.Close()
 
-	// 2. --- AI Database Connection (Read-Only) ---
-	readOnlyDSN := os.Getenv("DB_DSN_READONLY")
-	if readOnlyDSN == "" {
-		log.Fatalf("CRITICAL ERROR: DB_DSN_READONLY environment variable is not set. Cannot run AI components.")
+	// 1a. --- Schema Drift Check ---
+	// Refuse to start when the database does not match the embedded migrations.
+	if err := checkSchema(db, cfg.DB.AutoMigrate); err != nil {
+		log.Fatalf("Database schema check failed: %v", err)
 	}
 
-	dbReadOnly, err := database.OpenDBWithDSN(readOnlyDSN)
+	// 1b. --- Read Replica Connection (Optional) ---
+	// Heavy read endpoints (search, dashboards) use this pool.
+	// Without DB_DSN_REPLICA they simply share the primary pool.
+	readDB, err := database.OpenReplica(cfg.DB)
+	if err != nil {
+		log.Fatalf("Failed to connect to read replica: %v", err)
+	}
+	if readDB == nil {
+		readDB = db
+	} else {
+		defer readDB.Close()
+	}
+
+	// 1c. --- Error Reporting (Sentry or the log, plus the app_errors table) ---
+	reporter, err := errreport.New(cfg.Errors, cfg.Env, db)
+	if err != nil {
+		log.Fatalf("Failed to set up error reporting: %v", err)
+	}
+
+	// 2. --- AI Database Connection (Read-Only) ---
+	dbReadOnly, err := database.OpenReadOnly(cfg.DB)
 	if err != nil {
 		log.Fatalf("CRITICAL ERROR: Failed to connect to AI read-only database: %v", err)
 	}
 	defer dbReadOnly.Close()
 
 	// 3. --- AI Service Initialization ---
-	// CORRECTED: Use the NAME of the variable, not the value.
-	geminiKey := os.Getenv("GEMINI_API_KEY")
-	if geminiKey == "" {
-		log.Fatal("CRITICAL ERROR: GEMINI_API_KEY environment variable is not set.")
-	}
-
-	// FIX: Pass both geminiKey AND dbReadOnly
-	aiService, err := ai.NewAIService(geminiKey, dbReadOnly)
+	aiService, err := ai.NewAIService(cfg.AI.GeminiAPIKey, dbReadOnly)
 	if err != nil {
 		log.Fatalf("Failed to initialize AI Service: %v", err)
 	}
@@ -53,35 +126,340 @@ func main() {
 	// Note: Depending on implementation, we might defer closing the client here.
 	// e.g., defer aiService.Client.Close()
 
+	// 3b. --- Cache (Redis, or in-memory when REDIS_URL is unset) ---
+	appCache := cache.New(cfg.Cache.RedisURL)
+	defer appCache.Close()
+
+	// 3c. --- Background Job Queue & Domain Events ---
+	// Async event subscribers run on the queue; it is drained on shutdown.
+	jobQueue := jobs.NewQueue(cfg.Jobs.Workers, cfg.Jobs.QueueSize)
+	bus := events.NewBus(jobQueue)
+	// Exports get their own workers: one can run for minutes.
+	exportQueue := jobs.NewQueue(cfg.Exports.Workers, cfg.Jobs.QueueSize)
+
+	// 3d. --- PII Encryption (IC, SSM & bank details) ---
+	piiCipher, err := pii.New(piiKeys(cfg.PII.Keys))
+	if err != nil {
+		log.Fatalf("Invalid PII_KEYS: %v", err)
+	}
+	if piiCipher == nil {
+		log.Println("WARNING: PII_KEYS is not set; IC, SSM and bank details are stored in plaintext.")
+	}
+
+	// --- Product Photo Pipeline (loads IMAGE_WATERMARK) ---
+	imageProcessor, err := imageproc.New(cfg.Images)
+	if err != nil {
+		log.Fatalf("Invalid image settings: %v", err)
+	}
+
 	// --- Application Setup ---
 	// We inject ALL dependencies (DBs and AI Service) into the Handlers struct.
 	app := &handlers.Handlers{
+		Config:     cfg,
 		DB:         db,         // Primary Read/Write connection
+		ReadDB:     readDB,     // Replica (or primary) for heavy reads
 		DBReadOnly: dbReadOnly, // Read-Only connection for AI security
 		AIService:  aiService,  // ADDED: Injected AI Service
+		Cache:      appCache,
+		Settings:   settings.NewStore(db, appCache),
+		Status:     status.NewStore(db, appCache),
+		Store:      store.New(db, readDB),
+		Events:     bus,
+		Reporter:   reporter,
+		Audit:      audit.NewRecorder(db, cfg.Audit.CaptureCapacity),
+		Uploads:    uploads.New(cfg.Storage, cfg.HTTP.BaseURL, cfg.Auth.JWTSecret),
+		Images:     imageProcessor,
+		PII:        piiCipher,
+		Captcha:    captcha.New(cfg.Captcha),
+		Webhooks:   webhooks.NewClient(!cfg.IsProduction()),
+
+		ExportQueue: exportQueue,
+		ExportURLs:  uploads.NewExportSigner(cfg.Auth.JWTSecret, cfg.Exports.URLTTL),
+	}
+	app.RegisterSubscribers(bus)
+
+	// 3e. --- Runtime Settings (persisted LOG_LEVEL / DB_SLOW_QUERY_THRESHOLD overrides, email & SMS providers) ---
+	if err := app.ApplyRuntimeSettings(context.Background()); err != nil {
+		log.Printf("WARNING: Could not load runtime settings: %v", err)
 	}
 	// --- 4. Background Workers (Cron) ---
 	// Start the "Garbage Collector" in a separate thread (Goroutine).
-	// It runs every 1 hour to clean up unpaid orders.
+	// It runs every UNPAID_ORDER_CHECK_INTERVAL to clean up unpaid orders.
+	// workerCtx is cancelled on shutdown; workers.Wait() lets the current run finish.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(1)
 	go func() {
-		// Create a ticker that ticks every 1 hour
-		ticker := time.NewTicker(10 * time.Second) // TEMPORARY TEST
+		defer workers.Done()
+
+		ticker := time.NewTicker(cfg.UnpaidOrders.CheckInterval)
 		defer ticker.Stop()
 
 		log.Println("🕒 Background Worker Started: Monitoring for overdue orders...")
 
-		for range ticker.C {
-			// This code runs every time the clock hits 1 hour
-			app.ProcessOverdueOrders()
+		for {
+			select {
+			case <-workerCtx.Done():
+				log.Println("🕒 Background Worker Stopped")
+				return
+			case <-ticker.C:
+				app.ProcessOverdueOrders(workerCtx)
+			}
+		}
+	}()
+
+	// 4b. Retention: hard-delete soft-deleted rows past their window.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		retention.Run(workerCtx, db, cfg.Retention)
+	}()
+
+	// 4c. Nightly database backup (only with BACKUP_TARGET).
+	if cfg.Backup.Target != "" {
+		backups, err := backup.NewRunner(workerCtx, db, cfg.DB.PrimaryDSN, cfg.Backup)
+		if err != nil {
+			log.Fatalf("Failed to set up backups: %v", err)
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			backup.Schedule(workerCtx, backups, cfg.Backup.Hour)
+		}()
+	}
+
+	// 4d. Runtime logging settings changed on other instances.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		app.WatchRuntimeSettings(workerCtx)
+	}()
+
+	// 4e. JWT key rotation from the secrets provider (SECRETS_REFRESH_INTERVAL).
+	if cfg.Auth.SecretsRefresh > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			refreshJWTKeys(workerCtx, cfg.Auth.SecretsRefresh)
+		}()
+	}
+
+	// 4f. Dispute deadlines: refund disputes the supplier did not answer in time.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(cfg.Disputes.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				app.ProcessDisputeDeadlines(workerCtx)
+			}
+		}
+	}()
+
+	// 4g. Pre-orders: convert the ones whose stock has arrived.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(cfg.Preorders.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				app.ProcessPreorders(workerCtx)
+			}
+		}
+	}()
+
+	// 4h. Supplier vacations: clear the ones past their end date.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(cfg.Vacations.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				app.ProcessVacations(workerCtx)
+			}
+		}
+	}()
+
+	// 4i. Late shipments: alert suppliers of lines past their ships-by date.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(cfg.Shipping.LateCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				app.ProcessLateShipments(workerCtx)
+			}
+		}
+	}()
+
+	// 4j. Wallet ledger: check every balance_after against the exact running sum.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(cfg.Wallet.ReconcileInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				app.ProcessWalletReconciliation(workerCtx)
+			}
+		}
+	}()
+
+	// 4k. Exports: delete expired files and fail exports a restart cut short.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				app.ProcessExports(workerCtx)
+			}
+		}
+	}()
+
+	// 4l. Low-stock digest: one email a day per supplier (LOW_STOCK_DIGEST).
+	if cfg.Notifications.LowStockDigest {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			hour := cfg.Notifications.LowStockDigestHour
+			for {
+				now := time.Now()
+				next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+				if !next.After(now) {
+					next = next.AddDate(0, 0, 1)
+				}
+				select {
+				case <-workerCtx.Done():
+					return
+				case <-time.After(time.Until(next)):
+					app.ProcessLowStockDigest(workerCtx)
+				}
+			}
+		}()
+	}
+
+	// 4m. Webhooks: fan new wallet transactions out to endpoints and send due deliveries.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(cfg.Webhooks.DispatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				app.ProcessWebhooks(workerCtx)
+			}
+		}
+	}()
+
+	// 4n. AI chat quotas: clear the daily counters at midnight.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-time.After(time.Until(next)):
+				app.ProcessAIQuotas(workerCtx)
+			}
+		}
+	}()
+
+	// 4o. Watchlist: tell dropshippers about price changes and restocks of watched products.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(cfg.Notifications.WatchlistInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				app.ProcessWatchlist(workerCtx)
+			}
+		}
+	}()
+
+	// 4p. Duplicate listings: queue the same item listed by several suppliers for managers.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(cfg.Products.DuplicateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				app.ProcessDuplicates(workerCtx)
+			}
 		}
 	}()
 
 	// --- Router Setup ---
 	router := routes.SetupRouter(app)
 
-	// --- Start Server ---
-	log.Println("Starting TapToSell v2 API server on port 8080...")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// --- 5. Start Server (blocks until SIGINT/SIGTERM) ---
+	srv := newServer(cfg.HTTP, router)
+	if err := serve(srv, cfg.HTTP); err != nil {
+		log.Printf("Shutdown did not finish cleanly: %v", err)
+	}
+
+	// --- 6. Graceful Shutdown ---
+	// Requests are drained; stop the workers before the deferred
+	// Close calls tear down the cache and the DB pools.
+	stopWorkers()
+	workers.Wait()
+
+	queueCtx, cancelQueue := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	if err := jobQueue.Shutdown(queueCtx); err != nil {
+		log.Printf("Job queue did not drain in time: %v", err)
+	}
+	cancelQueue()
+	// A running export that does not finish in time is marked failed; the
+	// client can start it again.
+	exportCtx, cancelExports := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	if err := exportQueue.Shutdown(exportCtx); err != nil {
+		log.Printf("Export queue did not drain in time: %v", err)
+	}
+	cancelExports()
+
+	// Flush the spans of the last requests and jobs.
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Printf("Tracing exporter did not flush: %v", err)
 	}
+	cancelTracing()
+	reporter.Flush(5 * time.Second)
+	log.Println("Closing database pools and cache...")
 }
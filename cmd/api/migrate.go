@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/01moynul/taptosell-golang/internal/database"
+	"github.com/01moynul/taptosell-golang/internal/migrate"
+	"github.com/01moynul/taptosell-golang/migrations"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up             apply all pending migrations
+  down [n]       revert the last n migrations (default 1)
+  status         list migrations and whether they are applied
+  force <v>      record versions <= v as applied without running them
+                 (adopt a hand-migrated database, or clear a dirty version)`
+
+// migrateCommand loads the database settings and runs `api migrate ...`.
+func migrateCommand(args []string) error {
+	dbCfg, err := config.LoadDB()
+	if err != nil {
+		return err
+	}
+	db, err := database.OpenDB(dbCfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return runMigrate(db, args)
+}
+
+// runMigrate implements the `api migrate ...` subcommand.
+func runMigrate(db *sql.DB, args []string) error {
+	ctx := context.Background()
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := m.Up(ctx)
+		for _, v := range ran {
+			log.Printf("[Migrate] applied %d", v)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			log.Println("[Migrate] schema is up to date")
+		}
+		return nil
+
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return fmt.Errorf("down: invalid count %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, n)
+		for _, v := range reverted {
+			log.Printf("[Migrate] reverted %d", v)
+		}
+		return err
+
+	case "status":
+		applied, err := m.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		state := make(map[int64]migrate.Applied, len(applied))
+		for _, a := range applied {
+			state[a.Version] = a
+		}
+		for _, mig := range m.Migrations() {
+			a, ok := state[mig.Version]
+			switch {
+			case !ok:
+				fmt.Fprintf(os.Stdout, "%04d  %-40s pending\n", mig.Version, mig.Name)
+			case a.Dirty:
+				fmt.Fprintf(os.Stdout, "%04d  %-40s DIRTY\n", mig.Version, mig.Name)
+			case a.Checksum != mig.Checksum:
+				fmt.Fprintf(os.Stdout, "%04d  %-40s applied %s (CHECKSUM MISMATCH)\n", mig.Version, mig.Name, a.AppliedAt.Format("2006-01-02 15:04"))
+			default:
+				fmt.Fprintf(os.Stdout, "%04d  %-40s applied %s\n", mig.Version, mig.Name, a.AppliedAt.Format("2006-01-02 15:04"))
+			}
+			delete(state, mig.Version)
+		}
+		for _, a := range applied {
+			if _, unknown := state[a.Version]; unknown {
+				fmt.Fprintf(os.Stdout, "%04d  %-40s applied, UNKNOWN to this binary\n", a.Version, a.Name)
+			}
+		}
+		return nil
+
+	case "force":
+		if len(args) < 2 {
+			return errors.New("force: version required")
+		}
+		v, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || v < 0 {
+			return fmt.Errorf("force: invalid version %q", args[1])
+		}
+		if err := m.Force(ctx, v); err != nil {
+			return err
+		}
+		log.Printf("[Migrate] forced schema version to %d", v)
+		return nil
+	}
+
+	return errors.New(migrateUsage)
+}
+
+// checkSchema refuses to boot on schema drift. With autoMigrate (DB_AUTO_MIGRATE=true),
+// pending migrations are applied first (other drift still stops the boot).
+func checkSchema(db *sql.DB, autoMigrate bool) error {
+	ctx := context.Background()
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	if autoMigrate {
+		ran, err := m.Up(ctx)
+		for _, v := range ran {
+			log.Printf("[Migrate] applied %d", v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := m.Check(ctx); err != nil {
+		return fmt.Errorf("%w\nrun `api migrate status` for details", err)
+	}
+	return nil
+}
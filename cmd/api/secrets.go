@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/auth"
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+)
+
+// authKeys converts the configured JWT keys for the auth package.
+func authKeys(keys []config.SigningKey) []auth.Key {
+	out := make([]auth.Key, len(keys))
+	for i, k := range keys {
+		out[i] = auth.Key{ID: k.ID, Secret: []byte(k.Secret)}
+	}
+	return out
+}
+
+// refreshJWTKeys re-reads the JWT keys from the secrets provider every interval
+// until ctx is cancelled. A failed read keeps the current keys.
+func refreshJWTKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("🔑 JWT key refresh started: every %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			keys, err := config.LoadJWTKeys()
+			if err != nil {
+				logging.Errorf("[Secrets] Keeping current JWT keys: %v", err)
+				continue
+			}
+			auth.SetKeys(authKeys(keys))
+			logging.Debugf("[Secrets] JWT keys refreshed (%d key(s), signing with %q)", len(keys), keys[0].ID)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+)
+
+// newServer builds the HTTP server with explicit timeouts, so slow or idle
+// clients cannot hold connections (and goroutines) open forever.
+func newServer(cfg config.HTTP, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// serve runs srv until SIGINT/SIGTERM, then stops accepting connections and
+// waits up to cfg.ShutdownTimeout for in-flight requests to finish.
+// A listener that fails to start is fatal; a drain that overruns the budget is
+// returned so the caller can still release its resources.
+func serve(srv *http.Server, cfg config.HTTP) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Starting TapToSell v2 API server on port %s...", cfg.Port)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		// The listener failed before any signal (e.g. port already in use).
+		log.Fatalf("Failed to start server: %v", err)
+	case <-ctx.Done():
+	}
+	stop() // a second Ctrl+C now kills the process immediately
+
+	log.Printf("Shutdown signal received, draining requests (up to %s)...", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Println("HTTP server stopped")
+	return nil
+}
@@ -0,0 +1,719 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+)
+
+// versionMask has one bit per apiversion.Supported entry.
+type versionMask uint
+
+// result is one response a handler can write.
+type result struct {
+	status      int
+	versions    versionMask
+	contentType string  // "" for no body
+	schema      *schema // nil: the error envelope (isError) or no body
+	isError     bool
+}
+
+// handlerFacts is what the code of one handler shows about its contract.
+type handlerFacts struct {
+	body       types.Type // bound JSON body
+	bodyMasks  versionMask
+	query      []*parameter
+	multipart  []*formField
+	results    []result
+	headerType string // Content-Type set by hand (streamed CSV and the like)
+}
+
+type formField struct {
+	name string
+	file bool
+	list bool
+}
+
+// analyzer reads handlers: the calls on the *gin.Context, apierror helpers,
+// and the same-package helpers the context is passed to.
+type analyzer struct {
+	b         *schemaBuilder
+	version   versionMask // the version being documented
+	versions  []int64     // apiversion.Supported
+	errStatus map[string]int
+	funcs     map[string]funcSource     // by types.Func.FullName
+	vars      map[types.Object]ast.Expr // package-level var initializers
+}
+
+// funcSource is a function declaration and the package that declares it.
+type funcSource struct {
+	decl *ast.FuncDecl
+	pkg  *pkg
+}
+
+func newAnalyzer(b *schemaBuilder, version versionMask, versions []int64, errStatus map[string]int, pkgs ...*pkg) *analyzer {
+	a := &analyzer{b: b, version: version, versions: versions, errStatus: errStatus, funcs: map[string]funcSource{}, vars: map[types.Object]ast.Expr{}}
+	for _, p := range pkgs {
+		for _, f := range p.files {
+			for _, d := range f.Decls {
+				switch d := d.(type) {
+				case *ast.FuncDecl:
+					if obj, ok := p.info.Defs[d.Name].(*types.Func); ok && d.Body != nil {
+						a.funcs[obj.FullName()] = funcSource{decl: d, pkg: p}
+					}
+				case *ast.GenDecl:
+					for _, s := range d.Specs {
+						if vs, ok := s.(*ast.ValueSpec); ok && len(vs.Values) == len(vs.Names) {
+							for i, name := range vs.Names {
+								a.vars[p.info.Defs[name]] = vs.Values[i]
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return a
+}
+
+func (a *analyzer) all() versionMask { return versionMask(1)<<len(a.versions) - 1 }
+
+// handler analyzes a route's handler expression, evaluated in p.
+func (a *analyzer) handler(p *pkg, e ast.Expr) (*handlerFacts, *ast.FuncDecl) {
+	facts := &handlerFacts{}
+	w := &walker{a: a, facts: facts, stack: map[*ast.FuncDecl]bool{}}
+	switch e := e.(type) {
+	case *ast.FuncLit:
+		w.walk(p, e.Body, e.Body, a.all())
+		return facts, nil
+	case *ast.CallExpr: // a factory such as h.Batch(router)
+		if src, ok := a.callee(p, e); ok {
+			w.fn(src, a.all())
+			return facts, src.decl
+		}
+	case *ast.SelectorExpr:
+		if obj, ok := p.info.Uses[e.Sel].(*types.Func); ok {
+			if src, ok := a.funcs[obj.FullName()]; ok {
+				w.fn(src, a.all())
+				return facts, src.decl
+			}
+		}
+	}
+	return facts, nil
+}
+
+func (a *analyzer) callee(p *pkg, call *ast.CallExpr) (funcSource, bool) {
+	var id *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return funcSource{}, false
+	}
+	obj, ok := p.info.Uses[id].(*types.Func)
+	if !ok {
+		return funcSource{}, false
+	}
+	src, ok := a.funcs[obj.FullName()]
+	return src, ok
+}
+
+type walker struct {
+	a     *analyzer
+	facts *handlerFacts
+	stack map[*ast.FuncDecl]bool
+	// typeArgs are the type arguments of the generic helper being read
+	// by returned, by type parameter name.
+	typeArgs map[string]types.Type
+}
+
+func (w *walker) fn(src funcSource, mask versionMask) {
+	if w.stack[src.decl] {
+		return
+	}
+	w.stack[src.decl] = true
+	defer delete(w.stack, src.decl)
+	w.walk(src.pkg, src.decl.Body, src.decl.Body, mask)
+}
+
+// walk visits n, narrowing mask inside `if version >= apiversion.V2` style
+// branches. body is the enclosing function body, for local variables.
+func (w *walker) walk(p *pkg, body *ast.BlockStmt, n ast.Node, mask versionMask) {
+	w.a.inspect(p, n, mask, func(n ast.Node, mask versionMask) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			w.call(p, body, call, mask)
+		}
+		return true
+	})
+}
+
+// inspect is ast.Inspect that tracks the versions each node runs for,
+// through version conditions and the early returns after them:
+//
+//	if apiversion.From(c) >= apiversion.V2 {
+//		c.JSON(...) // v2
+//		return
+//	}
+//	c.JSON(...) // v1
+func (a *analyzer) inspect(p *pkg, n ast.Node, mask versionMask, f func(ast.Node, versionMask) bool) {
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			a.block(p, n.List, mask, f)
+			return false
+		case *ast.CaseClause:
+			a.block(p, n.Body, mask, f)
+			return false
+		case *ast.IfStmt:
+			if yes, ok := a.versionCond(p, n.Cond); ok {
+				if n.Init != nil {
+					a.inspect(p, n.Init, mask, f)
+				}
+				a.inspect(p, n.Body, mask&yes, f)
+				if n.Else != nil {
+					a.inspect(p, n.Else, mask&^yes, f)
+				}
+				return false
+			}
+		}
+		return f(n, mask)
+	})
+}
+
+func (a *analyzer) block(p *pkg, list []ast.Stmt, mask versionMask, f func(ast.Node, versionMask) bool) {
+	for _, stmt := range list {
+		if mask == 0 {
+			return
+		}
+		a.inspect(p, stmt, mask, f)
+		if ifStmt, ok := stmt.(*ast.IfStmt); ok {
+			if yes, ok := a.versionCond(p, ifStmt.Cond); ok {
+				if terminates(ifStmt.Body) {
+					mask &^= yes
+				}
+				if els, ok := ifStmt.Else.(*ast.BlockStmt); ok && terminates(els) {
+					mask &= yes
+				}
+			}
+		}
+	}
+}
+
+// terminates reports whether a block always ends in a return.
+func terminates(b *ast.BlockStmt) bool {
+	if len(b.List) == 0 {
+		return false
+	}
+	_, ok := b.List[len(b.List)-1].(*ast.ReturnStmt)
+	return ok
+}
+
+// versionCond reports, for a comparison of an apiversion.Version with a
+// constant, the versions for which it holds.
+func (a *analyzer) versionCond(p *pkg, cond ast.Expr) (versionMask, bool) {
+	bin, ok := cond.(*ast.BinaryExpr)
+	if !ok || !isVersion(p.info.TypeOf(bin.X)) {
+		return 0, false
+	}
+	tv := p.info.Types[bin.Y]
+	if tv.Value == nil {
+		return 0, false
+	}
+	var yes versionMask
+	for i, v := range a.versions {
+		if constant.Compare(constant.MakeInt64(v), bin.Op, tv.Value) {
+			yes |= 1 << i
+		}
+	}
+	return yes, true
+}
+
+func isVersion(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Name() == "Version" && n.Obj().Pkg() != nil && strings.HasSuffix(n.Obj().Pkg().Path(), "/apiversion")
+}
+
+func isGinContext(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Name() == "Context" && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "github.com/gin-gonic/gin"
+}
+
+func (w *walker) call(p *pkg, body *ast.BlockStmt, call *ast.CallExpr, mask versionMask) {
+	sel, isSel := call.Fun.(*ast.SelectorExpr)
+
+	// Methods of the *gin.Context.
+	if isSel && isGinContext(p.info.TypeOf(sel.X)) {
+		w.contextCall(p, body, sel.Sel.Name, call.Args, mask)
+		return
+	}
+
+	// apierror helpers.
+	if isSel {
+		if obj, ok := p.info.Uses[sel.Sel].(*types.Func); ok && obj.Pkg() != nil && strings.HasSuffix(obj.Pkg().Path(), "/apierror") {
+			status, ok := w.a.errStatus[obj.Name()]
+			if !ok && len(call.Args) >= 2 { // Abort, WithDetails
+				status, ok = intValue(p, call.Args[1])
+			}
+			if ok {
+				w.facts.results = append(w.facts.results, result{status: status, versions: mask, isError: true})
+			}
+			return
+		}
+	}
+
+	// Listings: the pagination.ListSpec literal passed along says what they
+	// accept. Helpers that forward a spec they were given are skipped.
+	for _, arg := range call.Args {
+		if lit, ok := ast.Unparen(arg).(*ast.CompositeLit); ok && isListSpec(p.info.TypeOf(lit)) {
+			w.facts.query = append(w.facts.query, w.listParams(p, lit)...)
+		}
+	}
+
+	// Helpers of this module that get the context.
+	src, ok := w.a.callee(p, call)
+	if !ok {
+		return
+	}
+	for _, arg := range call.Args {
+		if isGinContext(p.info.TypeOf(arg)) {
+			w.fn(src, mask)
+			return
+		}
+	}
+}
+
+func (w *walker) contextCall(p *pkg, body *ast.BlockStmt, method string, args []ast.Expr, mask versionMask) {
+	f := w.facts
+	arg := func(i int) (string, bool) {
+		if i >= len(args) {
+			return "", false
+		}
+		return stringValue(p, args[i])
+	}
+	switch method {
+	case "ShouldBindJSON", "BindJSON", "ShouldBind", "Bind", "ShouldBindWith", "ShouldBindBodyWith":
+		if len(args) > 0 {
+			if ptr, ok := p.info.TypeOf(args[0]).(*types.Pointer); ok {
+				f.body, f.bodyMasks = ptr.Elem(), f.bodyMasks|mask
+			}
+		}
+	case "ShouldBindQuery", "BindQuery":
+		if len(args) > 0 {
+			if st, ok := derefStruct(p.info.TypeOf(args[0])); ok {
+				f.query = append(f.query, w.a.b.queryParams(st)...)
+			}
+		}
+	case "Query", "DefaultQuery", "GetQuery":
+		if name, ok := arg(0); ok {
+			f.query = append(f.query, &parameter{Name: name, In: "query", Schema: &schema{Type: "string"}})
+		}
+	case "QueryArray", "GetQueryArray":
+		if name, ok := arg(0); ok {
+			f.query = append(f.query, &parameter{Name: name, In: "query", Schema: &schema{Type: "array", Items: &schema{Type: "string"}}})
+		}
+	case "FormFile":
+		if name, ok := arg(0); ok {
+			f.multipart = append(f.multipart, &formField{name: name, file: true})
+		}
+	case "PostForm", "DefaultPostForm", "GetPostForm":
+		if name, ok := arg(0); ok {
+			f.multipart = append(f.multipart, &formField{name: name})
+		}
+	case "PostFormArray", "GetPostFormArray":
+		if name, ok := arg(0); ok {
+			f.multipart = append(f.multipart, &formField{name: name, list: true})
+		}
+	case "JSON", "IndentedJSON", "PureJSON", "SecureJSON", "AbortWithStatusJSON":
+		s := w.jsonSchema(p, body, args[1])
+		for _, status := range statusValues(p, body, args[0]) {
+			f.results = append(f.results, result{status: status, versions: mask, contentType: "application/json", schema: s})
+		}
+	case "Data":
+		if status, ok := intValue(p, args[0]); ok {
+			ct, ok := arg(1)
+			if !ok {
+				ct = "application/octet-stream"
+			}
+			f.results = append(f.results, result{status: status, versions: mask, contentType: ct, schema: &schema{Type: "string", Format: "binary"}})
+		}
+	case "String":
+		if status, ok := intValue(p, args[0]); ok {
+			f.results = append(f.results, result{status: status, versions: mask, contentType: "text/plain", schema: &schema{Type: "string"}})
+		}
+	case "Status", "AbortWithStatus", "Redirect":
+		for _, status := range statusValues(p, body, args[0]) {
+			r := result{status: status, versions: mask}
+			// A body streamed after c.Header("Content-Type", ...) and c.Status.
+			if f.headerType != "" && status >= 200 && status < 300 && status != 204 {
+				r.contentType, r.schema = f.headerType, &schema{Type: "string"}
+			}
+			f.results = append(f.results, r)
+		}
+	case "File", "FileAttachment", "FileFromFS":
+		f.results = append(f.results, result{status: 200, versions: mask, contentType: "application/octet-stream", schema: &schema{Type: "string", Format: "binary"}})
+	case "SSEvent", "Stream":
+		f.results = append(f.results, result{status: 200, versions: mask, contentType: "text/event-stream", schema: &schema{Type: "string"}})
+	case "Header":
+		if name, ok := arg(0); ok && strings.EqualFold(name, "Content-Type") {
+			if ct, ok := arg(1); ok {
+				f.headerType, _, _ = strings.Cut(ct, ";")
+			}
+		}
+	}
+}
+
+func isListSpec(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Name() == "ListSpec" && n.Obj().Pkg() != nil && strings.HasSuffix(n.Obj().Pkg().Path(), "/pagination")
+}
+
+// listParams are the query parameters of a pagination.ListSpec literal, as
+// pagination.ParseList reads them. Only the bracketed spellings are documented.
+func (w *walker) listParams(p *pkg, lit *ast.CompositeLit) []*parameter {
+	var filters []string
+	sorts := []string{"-createdAt", "createdAt"}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, _ := kv.Key.(*ast.Ident)
+		switch values := w.strings(p, kv.Value); {
+		case key == nil:
+		case key.Name == "Filters":
+			filters = values
+		case key.Name == "Sorts" && len(values) > 0:
+			sorts = values
+		}
+	}
+
+	enum := make([]interface{}, len(sorts))
+	for i, s := range sorts {
+		enum[i] = s
+	}
+	params := []*parameter{
+		{Name: "page[size]", In: "query", Schema: &schema{Type: "integer"}},
+		{Name: "page[cursor]", In: "query", Description: "The nextCursor of the previous page.", Schema: &schema{Type: "string"}},
+		{Name: "sort", In: "query", Schema: &schema{Type: "string", Enum: enum, Default: sorts[0]}},
+	}
+	for _, name := range filters {
+		params = append(params, &parameter{Name: "filter[" + name + "]", In: "query", Schema: &schema{Type: "string"}})
+	}
+	return params
+}
+
+// strings returns the constants of a []string literal, or of the package-level
+// variable it names.
+func (w *walker) strings(p *pkg, e ast.Expr) []string {
+	e = ast.Unparen(e)
+	if id, ok := e.(*ast.Ident); ok {
+		if init, ok := w.a.vars[p.info.Uses[id]]; ok {
+			e = init
+		}
+	}
+	lit, ok := e.(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, elt := range lit.Elts {
+		if s, ok := stringValue(p, elt); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// jsonSchema is the schema of a value passed to c.JSON. gin.H literals, and
+// local gin.H variables filled key by key, are described key by key.
+func (w *walker) jsonSchema(p *pkg, body *ast.BlockStmt, e ast.Expr) *schema {
+	e = ast.Unparen(e)
+	switch e := e.(type) {
+	case *ast.CompositeLit:
+		if isStringMap(p.info.TypeOf(e)) {
+			s := &schema{Type: "object", Properties: map[string]*schema{}}
+			w.addKeys(p, body, s, e)
+			return s
+		}
+	case *ast.Ident:
+		obj, ok := p.info.Uses[e].(*types.Var)
+		if !ok || body == nil {
+			break
+		}
+		if isStringMap(obj.Type()) {
+			if s := w.mapVar(p, body, obj); s != nil {
+				return s
+			}
+		}
+		if isEmptyInterface(obj.Type()) {
+			if s := w.interfaceVar(p, body, obj); s != nil {
+				return s
+			}
+		}
+	case *ast.CallExpr:
+		if isEmptyInterface(p.info.TypeOf(e)) {
+			if src, ok := w.a.callee(p, e); ok {
+				return w.returned(src, 0, instance(p, e))
+			}
+		}
+	}
+	return w.a.b.orAny(w.subst(p.info.TypeOf(e)))
+}
+
+// subst replaces the type parameters in t with typeArgs.
+func (w *walker) subst(t types.Type) types.Type {
+	switch t := t.(type) {
+	case *types.TypeParam:
+		if arg, ok := w.typeArgs[t.Obj().Name()]; ok {
+			return arg
+		}
+	case *types.Slice:
+		return types.NewSlice(w.subst(t.Elem()))
+	case *types.Pointer:
+		return types.NewPointer(w.subst(t.Elem()))
+	case *types.Map:
+		return types.NewMap(w.subst(t.Key()), w.subst(t.Elem()))
+	}
+	return t
+}
+
+// instance lists the type arguments of a call to a generic function.
+func instance(p *pkg, call *ast.CallExpr) map[string]types.Type {
+	id, ok := call.Fun.(*ast.Ident)
+	if sel, isSel := call.Fun.(*ast.SelectorExpr); isSel {
+		id, ok = sel.Sel, true
+	}
+	if !ok {
+		return nil
+	}
+	inst, ok := p.info.Instances[id]
+	if !ok {
+		return nil
+	}
+	sig := p.info.Uses[id].(*types.Func).Type().(*types.Signature)
+	args := map[string]types.Type{}
+	for i := 0; i < inst.TypeArgs.Len(); i++ {
+		args[sig.TypeParams().At(i).Obj().Name()] = inst.TypeArgs.At(i)
+	}
+	return args
+}
+
+// interfaceVar describes a local interface{} from what is assigned to it:
+// a value, or the result of a helper of this module (see returned).
+func (w *walker) interfaceVar(p *pkg, body *ast.BlockStmt, obj *types.Var) *schema {
+	var found []*schema
+	ast.Inspect(body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok {
+			return true
+		}
+		for i, lhs := range assign.Lhs {
+			id, ok := lhs.(*ast.Ident)
+			if !ok || (p.info.Defs[id] != obj && p.info.Uses[id] != obj) {
+				continue
+			}
+			if len(assign.Rhs) == len(assign.Lhs) {
+				found = appendUnique(found, w.jsonSchema(p, body, assign.Rhs[i]))
+				continue
+			}
+			if call, ok := assign.Rhs[0].(*ast.CallExpr); ok {
+				if src, ok := w.a.callee(p, call); ok {
+					found = appendUnique(found, w.returned(src, i, instance(p, call)))
+				}
+			}
+		}
+		return true
+	})
+	return oneOf(found)
+}
+
+// returned describes result index of a helper, over the return statements
+// that apply to the version being documented.
+func (w *walker) returned(src funcSource, index int, typeArgs map[string]types.Type) *schema {
+	if w.stack[src.decl] {
+		return nil
+	}
+	w.stack[src.decl] = true
+	defer delete(w.stack, src.decl)
+	outer := w.typeArgs
+	w.typeArgs = typeArgs
+	defer func() { w.typeArgs = outer }()
+
+	var found []*schema
+	w.a.inspect(src.pkg, src.decl.Body, w.a.all(), func(n ast.Node, mask versionMask) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			if mask&w.a.version != 0 && index < len(n.Results) && !isNil(src.pkg, n.Results[index]) {
+				found = appendUnique(found, w.jsonSchema(src.pkg, src.decl.Body, n.Results[index]))
+			}
+		}
+		return true
+	})
+	return oneOf(found)
+}
+
+func oneOf(list []*schema) *schema {
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+	return &schema{OneOf: list}
+}
+
+func isNil(p *pkg, e ast.Expr) bool {
+	return p.info.Types[e].IsNil()
+}
+
+func isEmptyInterface(t types.Type) bool {
+	i, ok := t.Underlying().(*types.Interface)
+	return ok && i.Empty()
+}
+
+// statusValues is the status an expression holds: a constant, or the
+// constants assigned to a local variable.
+func statusValues(p *pkg, body *ast.BlockStmt, e ast.Expr) []int {
+	if v, ok := intValue(p, e); ok {
+		return []int{v}
+	}
+	id, ok := e.(*ast.Ident)
+	if !ok || body == nil {
+		return nil
+	}
+	obj := p.info.Uses[id]
+	var statuses []int
+	ast.Inspect(body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || len(assign.Lhs) != len(assign.Rhs) {
+			return true
+		}
+		for i, lhs := range assign.Lhs {
+			if lid, ok := lhs.(*ast.Ident); ok && (p.info.Defs[lid] == obj || p.info.Uses[lid] == obj) {
+				if v, ok := intValue(p, assign.Rhs[i]); ok {
+					statuses = append(statuses, v)
+				}
+			}
+		}
+		return true
+	})
+	return statuses
+}
+
+func (w *walker) addKeys(p *pkg, body *ast.BlockStmt, s *schema, lit *ast.CompositeLit) {
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		if key, ok := stringValue(p, kv.Key); ok {
+			s.Properties[key] = w.jsonSchema(p, body, kv.Value)
+		}
+	}
+}
+
+// mapVar describes a local map from its literal and its m["key"] = v assignments.
+func (w *walker) mapVar(p *pkg, body *ast.BlockStmt, obj *types.Var) *schema {
+	var s *schema
+	ast.Inspect(body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || len(assign.Lhs) != len(assign.Rhs) {
+			return true
+		}
+		for i, lhs := range assign.Lhs {
+			switch lhs := lhs.(type) {
+			case *ast.Ident:
+				if p.info.Defs[lhs] != obj && p.info.Uses[lhs] != obj {
+					continue
+				}
+				if lit, ok := assign.Rhs[i].(*ast.CompositeLit); ok {
+					if s == nil {
+						s = &schema{Type: "object", Properties: map[string]*schema{}}
+					}
+					w.addKeys(p, body, s, lit)
+				}
+			case *ast.IndexExpr:
+				id, ok := lhs.X.(*ast.Ident)
+				if !ok || p.info.Uses[id] != obj {
+					continue
+				}
+				if key, ok := stringValue(p, lhs.Index); ok {
+					if s == nil {
+						s = &schema{Type: "object", Properties: map[string]*schema{}}
+					}
+					s.Properties[key] = w.jsonSchema(p, body, assign.Rhs[i])
+				}
+			}
+		}
+		return true
+	})
+	return s
+}
+
+func isStringMap(t types.Type) bool {
+	m, ok := t.Underlying().(*types.Map)
+	if !ok {
+		return false
+	}
+	k, ok := m.Key().Underlying().(*types.Basic)
+	return ok && k.Kind() == types.String
+}
+
+func stringValue(p *pkg, e ast.Expr) (string, bool) {
+	tv := p.info.Types[e]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+func intValue(p *pkg, e ast.Expr) (int, bool) {
+	tv := p.info.Types[e]
+	if tv.Value == nil || tv.Value.Kind() != constant.Int {
+		return 0, false
+	}
+	v, ok := constant.Int64Val(tv.Value)
+	return int(v), ok
+}
+
+// readErrorStatuses maps the apierror helpers (NotFound, ...) to the status
+// they respond with, from their calls to Abort.
+func readErrorStatuses(files []*ast.File, httpStatus func(name string) (int, bool)) map[string]int {
+	statuses := map[string]int{}
+	for _, f := range files {
+		for _, d := range f.Decls {
+			fn, ok := d.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || fn.Recv != nil {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || len(call.Args) < 2 {
+					return true
+				}
+				if id, ok := call.Fun.(*ast.Ident); !ok || id.Name != "Abort" {
+					return true
+				}
+				sel, ok := call.Args[1].(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				if status, ok := httpStatus(sel.Sel.Name); ok {
+					if _, seen := statuses[fn.Name.Name]; !seen {
+						statuses[fn.Name.Name] = status
+					}
+				}
+				return true
+			})
+		}
+	}
+	return statuses
+}
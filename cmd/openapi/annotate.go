@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// annotations are the @ lines of a handler's doc comment, in the swag style:
+//
+//	@Summary  Short title
+//	@Description  More text (may repeat)
+//	@Tags     products, supplier
+//	@Param    q query string false "Search text"
+//	@Param    body body models.Thing true "Overrides the bound body"
+//	@Success  200 {object} object{cart=dto.Cart} v2
+//	@Produce  text/csv
+//
+// @Success and @Failure take an optional version (v1, v2) after the type;
+// they replace what the code shows for that status.
+type annotations struct {
+	summary     string
+	description []string
+	tags        []string
+	params      []*parameter
+	body        *schema
+	responses   []annotatedResponse
+	produce     string
+}
+
+type annotatedResponse struct {
+	status  int
+	version string // "" for every version
+	schema  *schema
+	desc    string
+}
+
+// docText splits a doc comment into its prose, minus the "X is the handler
+// for METHOD /path" line, and its annotations.
+func (a *analyzer) docText(doc *ast.CommentGroup, scope *types.Package) (string, *annotations, error) {
+	ann := &annotations{}
+	if doc == nil {
+		return "", ann, nil
+	}
+	var prose []string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "@") {
+			if strings.Contains(line, " is the handler for ") || isRouteLine(line) || strings.HasPrefix(line, "[FIXED]") || strings.HasPrefix(line, "[NEW]") {
+				continue
+			}
+			prose = append(prose, line)
+			continue
+		}
+		key, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		if err := a.annotate(ann, key, rest, scope); err != nil {
+			return "", nil, fmt.Errorf("%s: %w", line, err)
+		}
+	}
+	return strings.TrimSpace(strings.Join(prose, "\n")), ann, nil
+}
+
+// isRouteLine matches a doc line that is only a route, e.g. "GET /v1/supplier/dashboard-stats".
+func isRouteLine(line string) bool {
+	method, path, ok := strings.Cut(line, " ")
+	return ok && routeMethods[method] && strings.HasPrefix(path, "/") && !strings.Contains(path, " ")
+}
+
+func (a *analyzer) annotate(ann *annotations, key, rest string, scope *types.Package) error {
+	switch key {
+	case "@Summary":
+		ann.summary = rest
+	case "@Description":
+		ann.description = append(ann.description, rest)
+	case "@Tags":
+		for _, t := range strings.Split(rest, ",") {
+			ann.tags = append(ann.tags, strings.TrimSpace(t))
+		}
+	case "@Produce":
+		ann.produce = rest
+	case "@Param":
+		fields, desc := splitQuoted(rest)
+		if len(fields) < 4 {
+			return fmt.Errorf("want: name in type required \"description\"")
+		}
+		s, err := a.typeExpr(fields[2], scope)
+		if err != nil {
+			return err
+		}
+		if fields[1] == "body" {
+			ann.body = s
+			return nil
+		}
+		ann.params = append(ann.params, &parameter{Name: fields[0], In: fields[1], Required: fields[3] == "true", Description: desc, Schema: s})
+	case "@Success", "@Failure":
+		fields, desc := splitQuoted(rest)
+		if len(fields) < 3 {
+			return fmt.Errorf("want: status {object|array} type [version] [\"description\"]")
+		}
+		status, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return err
+		}
+		s, err := a.typeExpr(fields[2], scope)
+		if err != nil {
+			return err
+		}
+		switch fields[1] {
+		case "{array}":
+			s = &schema{Type: "array", Items: s}
+		case "{object}", "{string}":
+		default:
+			return fmt.Errorf("unknown kind %s", fields[1])
+		}
+		r := annotatedResponse{status: status, schema: s, desc: desc}
+		if len(fields) > 3 {
+			r.version = fields[3]
+		}
+		ann.responses = append(ann.responses, r)
+	default:
+		return fmt.Errorf("unknown annotation %s", key)
+	}
+	return nil
+}
+
+// typeExpr parses a type in an annotation: a Go type name (dto.Cart, or
+// unqualified for the handlers package), []T, map[string]T, one of string,
+// integer, number, boolean, object, any, or object{key=T,...}.
+func (a *analyzer) typeExpr(expr string, scope *types.Package) (*schema, error) {
+	switch {
+	case strings.HasPrefix(expr, "[]"):
+		items, err := a.typeExpr(expr[2:], scope)
+		return &schema{Type: "array", Items: items}, err
+	case strings.HasPrefix(expr, "map[string]"):
+		elem, err := a.typeExpr(expr[len("map[string]"):], scope)
+		return &schema{Type: "object", AdditionalProperties: elem}, err
+	case strings.HasPrefix(expr, "object{") && strings.HasSuffix(expr, "}"):
+		s := &schema{Type: "object", Properties: map[string]*schema{}}
+		for _, field := range splitTop(expr[len("object{") : len(expr)-1]) {
+			name, typ, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("want key=type in %s", expr)
+			}
+			fs, err := a.typeExpr(typ, scope)
+			if err != nil {
+				return nil, err
+			}
+			s.Properties[name] = fs
+		}
+		return s, nil
+	}
+	switch expr {
+	case "string", "integer", "number", "boolean", "object":
+		return &schema{Type: expr}, nil
+	case "file":
+		return &schema{Type: "string", Format: "binary"}, nil
+	case "any":
+		return &schema{}, nil
+	}
+
+	pkgName, name, qualified := strings.Cut(expr, ".")
+	look := scope
+	if qualified {
+		look = nil
+		for _, imp := range append(scope.Imports(), scope) {
+			if imp.Name() == pkgName {
+				look = imp
+			}
+		}
+		if look == nil {
+			return nil, fmt.Errorf("package %s is not imported by %s", pkgName, scope.Name())
+		}
+	} else {
+		name = pkgName
+	}
+	obj, ok := look.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("unknown type %s", expr)
+	}
+	return a.b.orAny(obj.Type()), nil
+}
+
+// splitTop splits on the commas that are not inside braces.
+func splitTop(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// splitQuoted splits an annotation into its fields and a trailing "quoted" text.
+func splitQuoted(s string) ([]string, string) {
+	desc := ""
+	if i := strings.Index(s, `"`); i >= 0 {
+		desc = strings.Trim(strings.TrimSpace(s[i:]), `"`)
+		s = s[:i]
+	}
+	return strings.FieldsFunc(s, unicode.IsSpace), desc
+}
+
+// humanize turns a handler name into a summary: GetPendingProducts -> "Get pending products".
+func humanize(name string) string {
+	var words []string
+	start := 0
+	runes := []rune(name)
+	for i := 1; i < len(runes); i++ {
+		// A word starts at an upper-case letter after a lower-case one, or at
+		// the last capital of an acronym ("SKUCheck" -> "SKU", "Check").
+		if unicode.IsUpper(runes[i]) && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	for i := 1; i < len(words); i++ {
+		if w := words[i]; len(w) > 1 && !unicode.IsUpper(rune(w[1])) {
+			words[i] = strings.ToLower(w)
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// pkg is one type-checked package of the module.
+type pkg struct {
+	types *types.Package
+	info  *types.Info
+	files []*ast.File
+}
+
+// loader type-checks packages from source, importing their dependencies from
+// the compiler's export data (go list -export), which keeps a run under a
+// few seconds.
+type loader struct {
+	fset    *token.FileSet
+	exports map[string]string // import path -> export data file
+	dirs    map[string]string // import path -> source directory
+	files   map[string][]string
+	imp     types.Importer
+	parsed  map[string]*parsedFile // parsed on demand, for field comments
+}
+
+// parsedFile is a source file parsed outside the type-checked packages.
+type parsedFile struct {
+	fset *token.FileSet
+	file *ast.File
+}
+
+func newLoader(patterns ...string) (*loader, error) {
+	args := append([]string{"list", "-export", "-deps", "-f", "{{.ImportPath}}\t{{.Export}}\t{{.Dir}}\t{{join .GoFiles \",\"}}"}, patterns...)
+	cmd := exec.Command("go", args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w", err)
+	}
+
+	l := &loader{
+		fset:    token.NewFileSet(),
+		exports: map[string]string{},
+		dirs:    map[string]string{},
+		files:   map[string][]string{},
+		parsed:  map[string]*parsedFile{},
+	}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		parts := strings.Split(sc.Text(), "\t")
+		if len(parts) != 4 {
+			continue
+		}
+		l.exports[parts[0]], l.dirs[parts[0]] = parts[1], parts[2]
+		if parts[3] != "" {
+			l.files[parts[0]] = strings.Split(parts[3], ",")
+		}
+	}
+	l.imp = importer.ForCompiler(l.fset, "gc", func(path string) (io.ReadCloser, error) {
+		file, ok := l.exports[path]
+		if !ok || file == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(file)
+	})
+	return l, nil
+}
+
+// check type-checks the package at path from its source.
+func (l *loader) check(path string) (*pkg, error) {
+	var files []*ast.File
+	for _, name := range l.files[path] {
+		f, err := parser.ParseFile(l.fset, filepath.Join(l.dirs[path], name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	info := &types.Info{
+		Types:     map[ast.Expr]types.TypeAndValue{},
+		Defs:      map[*ast.Ident]types.Object{},
+		Uses:      map[*ast.Ident]types.Object{},
+		Instances: map[*ast.Ident]types.Instance{},
+	}
+	conf := types.Config{Importer: l.imp}
+	tp, err := conf.Check(path, l.fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+	return &pkg{types: tp, info: info, files: files}, nil
+}
+
+// parse returns the syntax of a package's files without type-checking them.
+func (l *loader) parse(path string) ([]*ast.File, error) {
+	var files []*ast.File
+	for _, name := range l.files[path] {
+		f, err := l.parseFile(filepath.Join(l.dirs[path], name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f.file)
+	}
+	return files, nil
+}
+
+func (l *loader) parseFile(filename string) (*parsedFile, error) {
+	if f, ok := l.parsed[filename]; ok {
+		return f, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	f := &parsedFile{fset: fset, file: file}
+	l.parsed[filename] = f
+	return f, nil
+}
+
+// fieldDoc returns the comment on the struct field declared at pos, if any.
+// Imported packages only carry file and line, so the field is found by line.
+func (l *loader) fieldDoc(pos token.Pos) string {
+	p := l.fset.Position(pos)
+	if !p.IsValid() || !strings.HasSuffix(p.Filename, ".go") {
+		return ""
+	}
+	f, err := l.parseFile(p.Filename)
+	if err != nil {
+		return ""
+	}
+	doc := ""
+	ast.Inspect(f.file, func(n ast.Node) bool {
+		field, ok := n.(*ast.Field)
+		if !ok || doc != "" || len(field.Names) == 0 {
+			return doc == ""
+		}
+		if f.fset.Position(field.Names[0].Pos()).Line != p.Line {
+			return true
+		}
+		switch {
+		case field.Doc != nil:
+			doc = field.Doc.Text()
+		case field.Comment != nil:
+			doc = field.Comment.Text()
+		}
+		// Section markers ("--- Dimensions ---") are not about the field.
+		if strings.HasPrefix(strings.TrimSpace(doc), "---") {
+			doc = ""
+		}
+		return false
+	})
+	return strings.TrimSpace(doc)
+}
@@ -0,0 +1,446 @@
+// Command openapi generates the OpenAPI 3 documents that internal/openapi
+// serves at /v1/openapi.json and /v2/openapi.json. It reads the routes from
+// routes.registerAPI and type-checks internal/handlers, so the schemas are
+// those of the structs the handlers actually bind and encode: request bodies
+// from ShouldBindJSON, query parameters from c.Query, ShouldBindQuery and
+// pagination.ListSpec literals,
+// responses from c.JSON (gin.H literals key by key) and the apierror helpers,
+// per API version where a handler branches on apiversion. Regenerate after
+// changing a route, a handler or a model:
+//
+//	go generate ./internal/openapi
+//
+// What the code cannot show (a value typed interface{}, a better summary) is
+// added with annotations in the handler's doc comment; see annotations.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+)
+
+const module = "github.com/01moynul/taptosell-golang"
+
+func main() {
+	out := flag.String("out", ".", "directory to write v<N>.json into")
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("openapi: ")
+
+	outDir, err := filepath.Abs(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	root, err := moduleRoot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		log.Fatal(err)
+	}
+
+	g, err := newGenerator()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, v := range apiversion.Supported {
+		doc, err := g.document(v)
+		if err != nil {
+			log.Fatal(err)
+		}
+		b, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		name := filepath.Join(outDir, "v"+v.String()+".json")
+		if err := os.WriteFile(name, append(b, '\n'), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// generator holds the loaded packages shared by every version's document.
+type generator struct {
+	l            *loader
+	handlers     *pkg
+	routes       *pkg
+	docs         *pkg // internal/openapi, whose Spec serves the documents
+	routeList    []route
+	errStatus    map[string]int
+	errTypes     map[bool]types.Type // by "v2 envelope"
+	captchaHead  string
+	versionValue []int64
+}
+
+func newGenerator() (*generator, error) {
+	l, err := newLoader("./internal/handlers", "./internal/routes")
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{l: l, errTypes: map[bool]types.Type{}}
+	if g.handlers, err = l.check(module + "/internal/handlers"); err != nil {
+		return nil, err
+	}
+	if g.routes, err = l.check(module + "/internal/routes"); err != nil {
+		return nil, err
+	}
+	if g.docs, err = l.check(module + "/internal/openapi"); err != nil {
+		return nil, err
+	}
+
+	middlewareFiles, err := l.parse(module + "/internal/middleware")
+	if err != nil {
+		return nil, err
+	}
+	if g.routeList, err = readRoutes(g.routes, readRoleMiddleware(middlewareFiles)); err != nil {
+		return nil, err
+	}
+
+	httpPkg, err := l.imp.Import("net/http")
+	if err != nil {
+		return nil, err
+	}
+	apierrorFiles, err := l.parse(module + "/internal/apierror")
+	if err != nil {
+		return nil, err
+	}
+	g.errStatus = readErrorStatuses(apierrorFiles, func(name string) (int, bool) {
+		c, ok := httpPkg.Scope().Lookup(name).(*types.Const)
+		if !ok {
+			return 0, false
+		}
+		v, ok := constant.Int64Val(c.Val())
+		return int(v), ok
+	})
+
+	apierrorPkg, err := l.imp.Import(module + "/internal/apierror")
+	if err != nil {
+		return nil, err
+	}
+	g.errTypes[false] = apierrorPkg.Scope().Lookup("Response").Type()
+	g.errTypes[true] = apierrorPkg.Scope().Lookup("V2Response").Type()
+
+	middlewarePkg, err := l.imp.Import(module + "/internal/middleware")
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := middlewarePkg.Scope().Lookup("CaptchaHeader").(*types.Const); ok {
+		g.captchaHead = constant.StringVal(c.Val())
+	}
+
+	for _, v := range apiversion.Supported {
+		g.versionValue = append(g.versionValue, int64(v))
+	}
+	return g, nil
+}
+
+// document builds the OpenAPI document of one API version.
+func (g *generator) document(v apiversion.Version) (*document, error) {
+	bit := versionMask(0)
+	for i, sv := range apiversion.Supported {
+		if sv == v {
+			bit = 1 << i
+		}
+	}
+	b := newSchemaBuilder(g.l, module)
+	a := newAnalyzer(b, bit, g.versionValue, g.errStatus, g.handlers, g.routes, g.docs)
+	errSchema := b.of(g.errTypes[v >= apiversion.V2])
+
+	doc := &document{
+		OpenAPI: "3.0.3",
+		Info: info{
+			Title:   "TapToSell API",
+			Version: "v" + v.String(),
+			Description: "Generated from the routes and handlers by cmd/openapi. Errors share one envelope " +
+				"(see the apierror package); IDs in paths accept the public UUID.",
+		},
+		Servers: []server{{URL: v.Prefix()}},
+		Paths:   map[string]*pathItem{},
+		Components: components{
+			Schemas: b.schemas,
+			SecuritySchemes: map[string]*securityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "The token from POST /login."},
+			},
+		},
+	}
+
+	opIDs := map[string]int{}
+	tags := map[string]bool{}
+	for _, rt := range g.routeList {
+		facts, decl := a.handler(g.routes, rt.handler)
+		name := handlerName(rt, decl)
+		desc, ann := "", &annotations{}
+		if decl != nil {
+			var err error
+			if desc, ann, err = a.docText(decl.Doc, g.handlers.types); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+
+		op := &operation{
+			OperationID: name,
+			Summary:     ann.summary,
+			Description: strings.TrimSpace(strings.Join(append([]string{desc}, ann.description...), "\n\n")),
+			Tags:        ann.tags,
+			Responses:   map[string]*response{},
+		}
+		if opIDs[name]++; opIDs[name] > 1 {
+			op.OperationID = name + strconv.Itoa(opIDs[name])
+		}
+		if op.Summary == "" {
+			op.Summary = humanize(name)
+		}
+		if len(op.Tags) == 0 {
+			op.Tags = []string{pathTag(rt.path)}
+		}
+		for _, t := range op.Tags {
+			tags[t] = true
+		}
+		if len(rt.roles) > 0 {
+			op.Description = strings.TrimSpace("Roles: " + strings.Join(rt.roles, ", ") + ".\n\n" + op.Description)
+		}
+
+		op.Parameters = g.parameters(rt, facts, ann)
+		op.RequestBody = requestBodyOf(rt, facts, ann, b)
+
+		results := facts.results
+		if rt.secured {
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+			results = append(results, result{status: http.StatusUnauthorized, versions: bit, isError: true})
+			if len(rt.roles) > 0 {
+				results = append(results, result{status: http.StatusForbidden, versions: bit, isError: true})
+			}
+		}
+		g.responses(op, results, ann, bit, v, errSchema, facts.headerType)
+
+		item := doc.Paths[rt.path]
+		if item == nil {
+			item = &pathItem{}
+			doc.Paths[rt.path] = item
+		}
+		(*item)[strings.ToLower(rt.method)] = op
+	}
+
+	for t := range tags {
+		doc.Tags = append(doc.Tags, tag{Name: t})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	return doc, nil
+}
+
+func (g *generator) parameters(rt route, facts *handlerFacts, ann *annotations) []*parameter {
+	var params []*parameter
+	for _, name := range pathParams(rt.path) {
+		p := &parameter{Name: name, In: "path", Required: true, Schema: &schema{Type: "string"}}
+		for _, id := range rt.publicIDs {
+			if id == name {
+				p.Description = "The public UUID (or the numeric ID while LEGACY_NUMERIC_IDS is on)."
+			}
+		}
+		params = append(params, p)
+	}
+	if rt.captcha && g.captchaHead != "" {
+		params = append(params, &parameter{Name: g.captchaHead, In: "header",
+			Description: "CAPTCHA token, required while the captcha_enabled setting is on (see GET /captcha).",
+			Schema:      &schema{Type: "string"}})
+	}
+
+	seen := map[string]*parameter{}
+	for _, p := range append(facts.query, ann.params...) {
+		key := p.In + ":" + p.Name
+		if old, ok := seen[key]; ok {
+			// Annotations and bound structs know more than a bare c.Query.
+			*old = *p
+			continue
+		}
+		seen[key] = p
+		if p.In == "path" {
+			for _, existing := range params {
+				if existing.In == "path" && existing.Name == p.Name {
+					*existing = *p
+					existing.Required = true
+				}
+			}
+			continue
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+func requestBodyOf(rt route, facts *handlerFacts, ann *annotations, b *schemaBuilder) *requestBody {
+	if rt.method == "GET" {
+		return nil
+	}
+	content := map[string]*mediaType{}
+	switch {
+	case ann.body != nil:
+		content["application/json"] = &mediaType{Schema: ann.body}
+	case facts.body != nil:
+		content["application/json"] = &mediaType{Schema: b.orAny(facts.body)}
+	}
+	if len(facts.multipart) > 0 {
+		form := &schema{Type: "object", Properties: map[string]*schema{}}
+		for _, f := range facts.multipart {
+			fs := &schema{Type: "string"}
+			if f.file {
+				fs.Format = "binary"
+			}
+			if f.list {
+				fs = &schema{Type: "array", Items: fs}
+			}
+			form.Properties[f.name] = fs
+		}
+		content["multipart/form-data"] = &mediaType{Schema: form}
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	return &requestBody{Required: facts.body != nil || ann.body != nil, Content: content}
+}
+
+// responses fills op.Responses with the results that apply to the version.
+func (g *generator) responses(op *operation, results []result, ann *annotations, bit versionMask, v apiversion.Version, errSchema *schema, headerType string) {
+	annotated := map[int]bool{}
+	for _, r := range ann.responses {
+		if r.version == "" || r.version == "v"+v.String() {
+			annotated[r.status] = true
+		}
+	}
+
+	byStatus := map[int][]result{}
+	for _, r := range results {
+		if r.versions&bit == 0 || annotated[r.status] {
+			continue
+		}
+		byStatus[r.status] = append(byStatus[r.status], r)
+	}
+	for _, r := range ann.responses {
+		if r.version != "" && r.version != "v"+v.String() {
+			continue
+		}
+		ct := "application/json"
+		if ann.produce != "" {
+			ct = ann.produce
+		}
+		byStatus[r.status] = append(byStatus[r.status], result{status: r.status, contentType: ct, schema: r.schema})
+		if r.desc != "" {
+			op.Responses[strconv.Itoa(r.status)] = &response{Description: r.desc}
+		}
+	}
+
+	hasSuccess := false
+	for status := range byStatus {
+		if status < 400 {
+			hasSuccess = true
+		}
+	}
+	if !hasSuccess {
+		r := result{status: http.StatusOK}
+		ct := headerType
+		if ann.produce != "" {
+			ct = ann.produce
+		}
+		if ct != "" {
+			r.contentType, r.schema = ct, &schema{Type: "string", Format: "binary"}
+		}
+		byStatus[http.StatusOK] = []result{r}
+	}
+
+	for status, rs := range byStatus {
+		key := strconv.Itoa(status)
+		resp := op.Responses[key]
+		if resp == nil {
+			resp = &response{Description: http.StatusText(status)}
+			op.Responses[key] = resp
+		}
+		schemas := map[string][]*schema{}
+		for _, r := range rs {
+			switch {
+			case r.isError:
+				schemas["application/json"] = appendUnique(schemas["application/json"], errSchema)
+			case r.contentType != "":
+				schemas[r.contentType] = appendUnique(schemas[r.contentType], r.schema)
+			}
+		}
+		for ct, list := range schemas {
+			if resp.Content == nil {
+				resp.Content = map[string]*mediaType{}
+			}
+			if len(list) == 1 {
+				resp.Content[ct] = &mediaType{Schema: list[0]}
+			} else {
+				resp.Content[ct] = &mediaType{Schema: &schema{OneOf: list}}
+			}
+		}
+	}
+}
+
+func appendUnique(list []*schema, s *schema) []*schema {
+	if s == nil {
+		s = &schema{}
+	}
+	b, _ := json.Marshal(s)
+	for _, old := range list {
+		if ob, _ := json.Marshal(old); string(ob) == string(b) {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// handlerName is the handler's Go name, the operation ID. Inline handlers
+// are named after their route, e.g. getProfileMe.
+func handlerName(rt route, decl *ast.FuncDecl) string {
+	if decl != nil {
+		return decl.Name.Name
+	}
+	name := strings.ToLower(rt.method)
+	for _, s := range strings.FieldsFunc(rt.path, func(r rune) bool { return r == '/' || r == '-' }) {
+		if !strings.HasPrefix(s, "{") {
+			name += strings.ToUpper(s[:1]) + s[1:]
+		}
+	}
+	return name
+}
+
+// pathTag groups an operation by its first path segment.
+func pathTag(p string) string {
+	for _, s := range strings.Split(p, "/") {
+		if s != "" && !strings.HasPrefix(s, "{") {
+			return s
+		}
+	}
+	return "api"
+}
+
+// moduleRoot finds the directory of go.mod, from the working directory up.
+func moduleRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("go.mod not found")
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"path"
+	"strings"
+)
+
+// route is one API route as registered in routes.registerAPI.
+type route struct {
+	method    string
+	path      string   // OpenAPI form ("/products/{id}"), without the version prefix
+	handler   ast.Expr // h.Name, h.Factory(...) or a func literal
+	secured   bool     // behind middleware.AuthMiddleware
+	roles     []string // the roles middleware allows; none: any
+	captcha   bool
+	publicIDs []string // path parameters resolved by middleware.PublicID
+}
+
+// group is what a gin.RouterGroup adds to its routes.
+type group struct {
+	prefix  string
+	secured bool
+	roles   []string
+	captcha bool
+}
+
+// middlewareEffect is what one middleware adds to a route.
+type middlewareEffect struct {
+	secured  bool
+	roles    []string
+	captcha  bool
+	publicID string
+}
+
+var routeMethods = map[string]bool{"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true}
+
+// readRoutes walks registerAPI in the routes package. roleMiddleware maps the
+// role middlewares (SupplierMiddleware, ...) to the roles they allow.
+func readRoutes(p *pkg, roleMiddleware map[string][]string) ([]route, error) {
+	var fn *ast.FuncDecl
+	for _, f := range p.files {
+		for _, d := range f.Decls {
+			if d, ok := d.(*ast.FuncDecl); ok && d.Name.Name == "registerAPI" {
+				fn = d
+			}
+		}
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("routes.registerAPI not found")
+	}
+
+	r := &routeReader{p: p, roleMiddleware: roleMiddleware, groups: map[string]*group{}, vars: map[string]ast.Expr{}}
+	r.block(fn.Body.List)
+	return r.routes, nil
+}
+
+type routeReader struct {
+	p              *pkg
+	roleMiddleware map[string][]string
+	groups         map[string]*group
+	vars           map[string]ast.Expr // middleware held in local variables
+	routes         []route
+}
+
+func (r *routeReader) block(stmts []ast.Stmt) {
+	for _, s := range stmts {
+		r.stmt(s)
+	}
+}
+
+func (r *routeReader) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		r.block(s.List)
+	case *ast.IfStmt:
+		r.block(s.Body.List)
+		if s.Else != nil {
+			r.stmt(s.Else)
+		}
+	case *ast.RangeStmt:
+		r.block(s.Body.List)
+	case *ast.ForStmt:
+		r.block(s.Body.List)
+	case *ast.AssignStmt:
+		if len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+			return
+		}
+		name, ok := s.Lhs[0].(*ast.Ident)
+		if !ok {
+			return
+		}
+		if call, ok := s.Rhs[0].(*ast.CallExpr); ok {
+			if g := r.group(call); g != nil {
+				r.groups[name.Name] = g
+				return
+			}
+		}
+		r.vars[name.Name] = s.Rhs[0]
+	case *ast.ExprStmt:
+		call, ok := s.X.(*ast.CallExpr)
+		if !ok {
+			return
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return
+		}
+		recv, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return
+		}
+		g := r.groups[recv.Name]
+		if g == nil {
+			return
+		}
+		switch {
+		case sel.Sel.Name == "Use":
+			for _, arg := range call.Args {
+				g.apply(r.middleware(arg))
+			}
+		case routeMethods[sel.Sel.Name] && len(call.Args) >= 2:
+			r.route(g, sel.Sel.Name, call)
+		}
+	}
+}
+
+// group returns the group a Group call creates, or nil for other calls.
+// Groups made from the engine are the version's root.
+func (r *routeReader) group(call *ast.CallExpr) *group {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Group" || len(call.Args) == 0 {
+		return nil
+	}
+	parent := &group{prefix: "/"}
+	if recv, ok := sel.X.(*ast.Ident); ok && r.groups[recv.Name] != nil {
+		parent = r.groups[recv.Name]
+		prefix, ok := r.stringValue(call.Args[0])
+		if !ok {
+			return nil
+		}
+		g := *parent
+		g.prefix = joinPath(parent.prefix, prefix)
+		g.roles = append([]string(nil), parent.roles...)
+		parent = &g
+	}
+	for _, arg := range call.Args[1:] {
+		parent.apply(r.middleware(arg))
+	}
+	return parent
+}
+
+func (r *routeReader) route(g *group, method string, call *ast.CallExpr) {
+	rel, ok := r.stringValue(call.Args[0])
+	if !ok {
+		return
+	}
+	rt := route{
+		method:  method,
+		path:    openAPIPath(joinPath(g.prefix, rel)),
+		handler: call.Args[len(call.Args)-1],
+		secured: g.secured,
+		roles:   g.roles,
+		captcha: g.captcha,
+	}
+	for _, arg := range call.Args[1 : len(call.Args)-1] {
+		m := r.middleware(arg)
+		rt.secured = rt.secured || m.secured
+		rt.captcha = rt.captcha || m.captcha
+		if m.roles != nil {
+			rt.roles = m.roles
+		}
+		if m.publicID != "" {
+			rt.publicIDs = append(rt.publicIDs, m.publicID)
+		}
+	}
+	r.routes = append(r.routes, rt)
+}
+
+// middleware recognises the middleware.* calls that change the contract.
+func (r *routeReader) middleware(e ast.Expr) middlewareEffect {
+	if id, ok := e.(*ast.Ident); ok && r.vars[id.Name] != nil {
+		e = r.vars[id.Name]
+	}
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return middlewareEffect{}
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return middlewareEffect{}
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "middleware" {
+		return middlewareEffect{}
+	}
+	switch sel.Sel.Name {
+	case "AuthMiddleware":
+		return middlewareEffect{secured: true}
+	case "Captcha":
+		return middlewareEffect{captcha: true}
+	case "RequireRole":
+		var roles []string
+		for _, arg := range call.Args[1:] {
+			if s, ok := r.stringValue(arg); ok {
+				roles = append(roles, s)
+			}
+		}
+		return middlewareEffect{roles: roles}
+	case "PublicID":
+		if len(call.Args) >= 3 {
+			if s, ok := r.stringValue(call.Args[2]); ok {
+				return middlewareEffect{publicID: s}
+			}
+		}
+	}
+	if roles, ok := r.roleMiddleware[sel.Sel.Name]; ok {
+		return middlewareEffect{roles: roles}
+	}
+	return middlewareEffect{}
+}
+
+func (r *routeReader) stringValue(e ast.Expr) (string, bool) {
+	tv, ok := r.p.info.Types[e]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+func (g *group) apply(m middlewareEffect) {
+	g.secured = g.secured || m.secured
+	g.captcha = g.captcha || m.captcha
+	if m.roles != nil {
+		g.roles = m.roles
+	}
+}
+
+// readRoleMiddleware finds the middleware functions that only wrap
+// requireRole(db, message, roles...) and the roles each allows.
+func readRoleMiddleware(files []*ast.File) map[string][]string {
+	roles := map[string][]string{}
+	for _, f := range files {
+		for _, d := range f.Decls {
+			fn, ok := d.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || len(fn.Body.List) != 1 {
+				continue
+			}
+			ret, ok := fn.Body.List[0].(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != 1 {
+				continue
+			}
+			call, ok := ret.Results[0].(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			if id, ok := call.Fun.(*ast.Ident); !ok || id.Name != "requireRole" || len(call.Args) < 3 {
+				continue
+			}
+			var allowed []string
+			for _, arg := range call.Args[2:] {
+				if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					allowed = append(allowed, strings.Trim(lit.Value, `"`))
+				}
+			}
+			if len(allowed) > 0 {
+				roles[fn.Name.Name] = allowed
+			}
+		}
+	}
+	return roles
+}
+
+// joinPath joins a group prefix and a route path the way gin does.
+func joinPath(base, rel string) string {
+	if rel == "" {
+		return base
+	}
+	p := path.Join(base, rel)
+	if strings.HasSuffix(rel, "/") && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p
+}
+
+// openAPIPath turns gin's ":id" and "*path" segments into "{id}" and "{path}".
+func openAPIPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// pathParams lists the parameter names in an OpenAPI path.
+func pathParams(p string) []string {
+	var names []string
+	for _, s := range strings.Split(p, "/") {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			names = append(names, s[1:len(s)-1])
+		}
+	}
+	return names
+}
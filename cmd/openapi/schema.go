@@ -0,0 +1,392 @@
+package main
+
+import (
+	"go/constant"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// schemaBuilder turns Go types into JSON schemas the way encoding/json
+// encodes them. Named structs become shared components; everything else is
+// inlined.
+type schemaBuilder struct {
+	l       *loader
+	module  string
+	schemas map[string]*schema
+	names   map[*types.TypeName]string
+}
+
+func newSchemaBuilder(l *loader, module string) *schemaBuilder {
+	return &schemaBuilder{l: l, module: module, schemas: map[string]*schema{}, names: map[*types.TypeName]string{}}
+}
+
+// wellKnown are types with their own JSON encoding, by "<import path>.<name>".
+var wellKnown = map[string]func() *schema{
+	"time.Time":                func() *schema { return &schema{Type: "string", Format: "date-time"} },
+	"time.Duration":            func() *schema { return &schema{Type: "integer", Format: "int64", Description: "Nanoseconds."} },
+	"encoding/json.RawMessage": func() *schema { return &schema{} },
+	// json.RawMessage is an alias of jsontext.Value under GOEXPERIMENT=jsonv2.
+	"encoding/json/jsontext.Value": func() *schema { return &schema{} },
+	"github.com/gin-gonic/gin.H": func() *schema {
+		return &schema{Type: "object", AdditionalProperties: true}
+	},
+	"github.com/01moynul/taptosell-golang/internal/money.Money": func() *schema {
+		return &schema{Type: "number", Format: "decimal", Description: "Ringgit, at most two decimals."}
+	},
+}
+
+// of returns the schema of values of type t, or nil for types encoding/json
+// cannot encode (functions, channels).
+func (b *schemaBuilder) of(t types.Type) *schema {
+	switch t := types.Unalias(t).(type) {
+	case *types.Named:
+		return b.named(t)
+	case *types.Pointer:
+		s := b.of(t.Elem())
+		if s == nil {
+			return nil
+		}
+		if s.Ref != "" {
+			return &schema{AllOf: []*schema{s}, Nullable: true}
+		}
+		s.Nullable = true
+		return s
+	case *types.Basic:
+		return basic(t)
+	case *types.Slice:
+		if isByte(t.Elem()) {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: b.orAny(t.Elem())}
+	case *types.Array:
+		return &schema{Type: "array", Items: b.orAny(t.Elem())}
+	case *types.Map:
+		return &schema{Type: "object", AdditionalProperties: b.orAny(t.Elem())}
+	case *types.Interface, *types.TypeParam:
+		return &schema{}
+	case *types.Struct:
+		return b.object(t)
+	}
+	return nil
+}
+
+func (b *schemaBuilder) orAny(t types.Type) *schema {
+	if s := b.of(t); s != nil {
+		return s
+	}
+	return &schema{}
+}
+
+func (b *schemaBuilder) named(t *types.Named) *schema {
+	obj := t.Obj()
+	if obj.Pkg() == nil { // error
+		return &schema{}
+	}
+	key := obj.Pkg().Path() + "." + obj.Name()
+	if known, ok := wellKnown[key]; ok {
+		return known()
+	}
+	if hasMethod(t, "MarshalJSON") {
+		return &schema{Description: obj.Pkg().Name() + "." + obj.Name() + " (custom JSON encoding)"}
+	}
+	if hasMethod(t, "MarshalText") {
+		return &schema{Type: "string"}
+	}
+	if t.TypeArgs().Len() > 0 {
+		return b.of(t.Underlying())
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		return &schema{Ref: "#/components/schemas/" + b.component(obj, u)}
+	case *types.Basic:
+		s := basic(u)
+		if s != nil && strings.HasPrefix(obj.Pkg().Path(), b.module) {
+			s.Enum = enumOf(obj)
+		}
+		return s
+	}
+	return b.of(t.Underlying())
+}
+
+// component registers a named struct under "<package>.<Name>".
+func (b *schemaBuilder) component(obj *types.TypeName, st *types.Struct) string {
+	if name, ok := b.names[obj]; ok {
+		return name
+	}
+	name := obj.Pkg().Name() + "." + obj.Name()
+	for i := 2; b.schemas[name] != nil; i++ {
+		name = obj.Pkg().Name() + "." + obj.Name() + strconv.Itoa(i)
+	}
+	b.names[obj] = name
+	b.schemas[name] = &schema{} // placeholder for recursive types
+	*b.schemas[name] = *b.object(st)
+	return name
+}
+
+// object is the schema of a struct: its encoded fields, embedded structs'
+// fields promoted, with the binding tags as constraints.
+func (b *schemaBuilder) object(st *types.Struct) *schema {
+	s := &schema{Type: "object", Properties: map[string]*schema{}}
+	b.addFields(s, st)
+	if len(s.Properties) == 0 {
+		s.Properties = nil
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+func (b *schemaBuilder) addFields(s *schema, st *types.Struct) {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		name, opts, _ := strings.Cut(tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if f.Embedded() && name == "" {
+			if inner, ok := derefStruct(f.Type()); ok {
+				b.addFields(s, inner)
+				continue
+			}
+		}
+		if !f.Exported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name()
+		}
+
+		fs := b.of(f.Type())
+		if fs == nil {
+			continue
+		}
+		if hasOption(opts, "string") {
+			fs = &schema{Type: "string", Nullable: fs.Nullable}
+		}
+		if constrain(fs, tag.Get("binding")) {
+			s.Required = append(s.Required, name)
+		}
+		if doc := b.l.fieldDoc(f.Pos()); doc != "" {
+			if fs.Ref != "" {
+				fs = &schema{AllOf: []*schema{fs}}
+			}
+			fs.Description = strings.TrimSpace(strings.Join([]string{doc, fs.Description}, "\n\n"))
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// queryParams lists the fields of a ShouldBindQuery struct by their form tags.
+func (b *schemaBuilder) queryParams(st *types.Struct) []*parameter {
+	var params []*parameter
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		if f.Embedded() {
+			if inner, ok := derefStruct(f.Type()); ok {
+				params = append(params, b.queryParams(inner)...)
+				continue
+			}
+		}
+		name, _, _ := strings.Cut(tag.Get("form"), ",")
+		if !f.Exported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name()
+		}
+		fs := b.orAny(f.Type())
+		fs.Nullable = false
+		required := constrain(fs, tag.Get("binding"))
+		params = append(params, &parameter{Name: name, In: "query", Required: required, Description: b.l.fieldDoc(f.Pos()), Schema: fs})
+	}
+	return params
+}
+
+// constrain applies a binding tag to s and reports whether it makes the
+// field required. Rules after "dive" apply to the elements.
+func constrain(s *schema, binding string) (required bool) {
+	if binding == "" {
+		return false
+	}
+	target := s
+	for _, rule := range strings.Split(binding, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			switch {
+			case target.Items != nil:
+				target = target.Items
+			case target.AdditionalProperties != nil:
+				if elem, ok := target.AdditionalProperties.(*schema); ok {
+					target = elem
+				}
+			}
+			continue
+		case "keys", "endkeys", "omitempty", "omitnil":
+			continue
+		case "required":
+			if target == s {
+				required = true
+			}
+			continue
+		}
+		if target.Ref != "" || len(target.AllOf) > 0 {
+			continue
+		}
+		applyRule(target, name, arg)
+	}
+	return required
+}
+
+func applyRule(s *schema, name, arg string) {
+	n, numErr := strconv.ParseFloat(arg, 64)
+	bound := func(num **float64, length **int) {
+		if numErr != nil {
+			return
+		}
+		switch s.Type {
+		case "integer", "number":
+			*num = &n
+		case "string", "array":
+			i := int(n)
+			*length = &i
+		}
+	}
+	switch name {
+	case "min", "gte":
+		if s.Type == "array" {
+			bound(new(*float64), &s.MinItems)
+		} else {
+			bound(&s.Minimum, &s.MinLength)
+		}
+	case "max", "lte":
+		if s.Type == "array" {
+			bound(new(*float64), &s.MaxItems)
+		} else {
+			bound(&s.Maximum, &s.MaxLength)
+		}
+	case "gt":
+		bound(&s.Minimum, &s.MinLength)
+		s.ExclusiveMinimum = s.Minimum != nil
+	case "lt":
+		bound(&s.Maximum, &s.MaxLength)
+		s.ExclusiveMaximum = s.Maximum != nil
+	case "len":
+		if s.Type == "array" {
+			bound(new(*float64), &s.MinItems)
+			bound(new(*float64), &s.MaxItems)
+		} else {
+			bound(&s.Minimum, &s.MinLength)
+			bound(&s.Maximum, &s.MaxLength)
+		}
+	case "oneof":
+		s.Enum = nil
+		for _, v := range strings.Fields(arg) {
+			if s.Type == "integer" || s.Type == "number" {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					s.Enum = append(s.Enum, f)
+					continue
+				}
+			}
+			s.Enum = append(s.Enum, strings.Trim(v, "'"))
+		}
+	case "email":
+		s.Format = "email"
+	case "url", "uri", "http_url":
+		s.Format = "uri"
+	case "uuid", "uuid4":
+		s.Format = "uuid"
+	case "datetime":
+		s.Format = "date-time"
+	default:
+		// Custom validators (see apierror.RegisterValidators) are named as formats.
+		if s.Format == "" && arg == "" && s.Type == "string" {
+			s.Format = name
+		}
+	}
+}
+
+func basic(t *types.Basic) *schema {
+	info := t.Info()
+	switch {
+	case info&types.IsBoolean != 0:
+		return &schema{Type: "boolean"}
+	case info&types.IsInteger != 0:
+		switch t.Kind() {
+		case types.Int64, types.Uint64, types.Int, types.Uint:
+			return &schema{Type: "integer", Format: "int64"}
+		}
+		return &schema{Type: "integer", Format: "int32"}
+	case info&types.IsFloat != 0:
+		if t.Kind() == types.Float32 {
+			return &schema{Type: "number", Format: "float"}
+		}
+		return &schema{Type: "number", Format: "double"}
+	case info&types.IsString != 0:
+		return &schema{Type: "string"}
+	}
+	return nil
+}
+
+// enumOf lists the constants declared with a named string or integer type,
+// in declaration order.
+func enumOf(obj *types.TypeName) []interface{} {
+	var consts []*types.Const
+	scope := obj.Pkg().Scope()
+	for _, name := range scope.Names() {
+		if c, ok := scope.Lookup(name).(*types.Const); ok && types.Identical(c.Type(), obj.Type()) {
+			consts = append(consts, c)
+		}
+	}
+	sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+	var enum []interface{}
+	for _, c := range consts {
+		switch c.Val().Kind() {
+		case constant.String:
+			enum = append(enum, constant.StringVal(c.Val()))
+		case constant.Int:
+			v, _ := constant.Int64Val(c.Val())
+			enum = append(enum, v)
+		}
+	}
+	return enum
+}
+
+func hasMethod(t types.Type, name string) bool {
+	for _, typ := range []types.Type{t, types.NewPointer(t)} {
+		ms := types.NewMethodSet(typ)
+		for i := 0; i < ms.Len(); i++ {
+			if ms.At(i).Obj().Name() == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func derefStruct(t types.Type) (*types.Struct, bool) {
+	if p, ok := types.Unalias(t).(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	return st, ok
+}
+
+func isByte(t types.Type) bool {
+	b, ok := types.Unalias(t).(*types.Basic)
+	return ok && b.Kind() == types.Byte
+}
+
+func hasOption(opts, want string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == want {
+			return true
+		}
+	}
+	return false
+}
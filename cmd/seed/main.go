@@ -0,0 +1,439 @@
+// Command seed fills a database with realistic load-test data: suppliers,
+// dropshippers, products (simple and variable), orders and wallet history.
+//
+// It uses the same DB_DSN_PRIMARY as the API. Every seeded user gets an
+// email under @seed.taptosell.test and every SKU starts with "SEED-", so the
+// data is easy to spot (and delete) later.
+//
+// Example:
+//
+//	go run ./cmd/seed -suppliers 50 -dropshippers 500 -products 200 -orders 40
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/01moynul/taptosell-golang/internal/database"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/joho/godotenv"
+)
+
+// seedConfig holds the volumes requested on the command line.
+type seedConfig struct {
+	Suppliers           int
+	Dropshippers        int
+	ProductsPerSupplier int
+	MaxVariants         int
+	OrdersPerUser       int
+	Days                int
+	Password            string
+	BatchSize           int
+}
+
+// seededProduct is what the order generator needs to know about a product.
+type seededProduct struct {
+	ID         int64
+	SupplierID int64
+	Price      money.Money
+	VariantIDs []int64
+	Prices     []money.Money // variant prices, same order as VariantIDs
+}
+
+// seedCommissionRate is the commission (percent) of every seeded product.
+const seedCommissionRate = 5.0
+
+var (
+	adjectives = []string{"Classic", "Premium", "Eco", "Slim", "Vintage", "Smart", "Compact", "Deluxe", "Sport", "Urban", "Soft", "Wireless"}
+	nouns      = []string{"Backpack", "T-Shirt", "Water Bottle", "Phone Case", "Sneakers", "Desk Lamp", "Earbuds", "Hoodie", "Wallet", "Yoga Mat", "Sunglasses", "Mug"}
+	colors     = []string{"Black", "White", "Red", "Blue", "Green", "Grey", "Pink", "Navy"}
+	sizes      = []string{"XS", "S", "M", "L", "XL", "XXL"}
+	firstNames = []string{"Aisyah", "Daniel", "Mei Ling", "Arjun", "Nurul", "Jason", "Siti", "Hafiz", "Priya", "Wei Jie", "Farah", "Kumar"}
+	lastNames  = []string{"Tan", "Abdullah", "Lim", "Raj", "Wong", "Ismail", "Lee", "Ng", "Rahman", "Chong", "Singh", "Yusof"}
+	orderMix   = []string{"completed", "completed", "completed", "shipped", "shipped", "processing", "on-hold", "cancelled"}
+)
+
+func main() {
+	cfg := seedConfig{}
+	flag.IntVar(&cfg.Suppliers, "suppliers", 10, "number of suppliers to create")
+	flag.IntVar(&cfg.Dropshippers, "dropshippers", 50, "number of dropshippers to create")
+	flag.IntVar(&cfg.ProductsPerSupplier, "products", 50, "products per supplier")
+	flag.IntVar(&cfg.MaxVariants, "max-variants", 6, "maximum variants per variable product (0 = simple products only)")
+	flag.IntVar(&cfg.OrdersPerUser, "orders", 20, "orders per dropshipper")
+	flag.IntVar(&cfg.Days, "days", 180, "spread created_at timestamps over this many past days")
+	flag.StringVar(&cfg.Password, "password", "password123", "password for every seeded account")
+	flag.IntVar(&cfg.BatchSize, "batch", 500, "rows per transaction")
+	randSeed := flag.Int64("seed", time.Now().UnixNano(), "random seed (reuse it to reproduce a data set)")
+	flag.Parse()
+
+	if cfg.BatchSize < 1 || cfg.Days < 1 || cfg.MaxVariants < 0 {
+		log.Fatal("-batch and -days must be at least 1, -max-variants cannot be negative")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("WARNING: Could not find or load .env file. Relying on system environment variables.")
+	}
+
+	dbCfg, err := config.LoadDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := database.OpenDB(dbCfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to primary database: %v", err)
+	}
+	defer db.Close()
+
+	s := &seeder{
+		db:    db,
+		cfg:   cfg,
+		rnd:   rand.New(rand.NewSource(*randSeed)),
+		runID: time.Now().Format("20060102150405"),
+		now:   time.Now(),
+	}
+	log.Printf("Seeding run %s (seed %d)", s.runID, *randSeed)
+
+	if err := s.run(context.Background()); err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+}
+
+type seeder struct {
+	db    *sql.DB
+	cfg   seedConfig
+	rnd   *rand.Rand
+	runID string
+	now   time.Time
+
+	passwordHash string
+	categoryIDs  []int64
+	brandIDs     []int64
+}
+
+func (s *seeder) run(ctx context.Context) error {
+	start := time.Now()
+
+	// 1. Hash the shared password once (bcrypt is deliberately slow).
+	var password models.Password
+	if err := password.Set(s.cfg.Password); err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+	s.passwordHash = password.Hash
+
+	// 2. Reuse whatever taxonomy already exists.
+	var err error
+	if s.categoryIDs, err = s.loadIDs(ctx, "SELECT id FROM categories"); err != nil {
+		return fmt.Errorf("load categories: %w", err)
+	}
+	if s.brandIDs, err = s.loadIDs(ctx, "SELECT id FROM brands"); err != nil {
+		return fmt.Errorf("load brands: %w", err)
+	}
+
+	// 3. Users
+	supplierIDs, err := s.createUsers(ctx, "supplier", s.cfg.Suppliers)
+	if err != nil {
+		return fmt.Errorf("create suppliers: %w", err)
+	}
+	dropshipperIDs, err := s.createUsers(ctx, "dropshipper", s.cfg.Dropshippers)
+	if err != nil {
+		return fmt.Errorf("create dropshippers: %w", err)
+	}
+	log.Printf("Created %d suppliers and %d dropshippers", len(supplierIDs), len(dropshipperIDs))
+
+	// 4. Products & Variants
+	products, err := s.createProducts(ctx, supplierIDs)
+	if err != nil {
+		return fmt.Errorf("create products: %w", err)
+	}
+	log.Printf("Created %d products", len(products))
+
+	// 5. Orders & Wallet History
+	orderCount, txCount, err := s.createOrders(ctx, dropshipperIDs, products)
+	if err != nil {
+		return fmt.Errorf("create orders: %w", err)
+	}
+	log.Printf("Created %d orders and %d wallet transactions", orderCount, txCount)
+
+	log.Printf("Done in %s", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func (s *seeder) loadIDs(ctx context.Context, query string) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// randomTime returns a timestamp within the configured window.
+func (s *seeder) randomTime() time.Time {
+	window := time.Duration(s.cfg.Days) * 24 * time.Hour
+	return s.now.Add(-time.Duration(s.rnd.Int63n(int64(window) + 1)))
+}
+
+// inBatches runs fn for [0, n) with one transaction per BatchSize items.
+func (s *seeder) inBatches(ctx context.Context, n int, fn func(tx *sql.Tx, i int) error) error {
+	for start := 0; start < n; start += s.cfg.BatchSize {
+		end := start + s.cfg.BatchSize
+		if end > n {
+			end = n
+		}
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for i := start; i < end; i++ {
+			if err := fn(tx, i); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *seeder) createUsers(ctx context.Context, role string, n int) ([]int64, error) {
+	ids := make([]int64, 0, n)
+	query := `INSERT INTO users (role, status, email, password_hash, full_name, phone_number, created_at, updated_at, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	err := s.inBatches(ctx, n, func(tx *sql.Tx, i int) error {
+		name := firstNames[s.rnd.Intn(len(firstNames))] + " " + lastNames[s.rnd.Intn(len(lastNames))]
+		email := fmt.Sprintf("%s-%s-%d@seed.taptosell.test", role, s.runID, i+1)
+		phone := fmt.Sprintf("+601%d%07d", s.rnd.Intn(10), s.rnd.Intn(10000000))
+		created := s.now.Add(-time.Duration(s.cfg.Days) * 24 * time.Hour) // users predate their activity
+
+		res, err := tx.ExecContext(ctx, query, role, "active", email, s.passwordHash, name, phone, created, created, 1)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	return ids, err
+}
+
+func (s *seeder) createProducts(ctx context.Context, supplierIDs []int64) ([]seededProduct, error) {
+	total := len(supplierIDs) * s.cfg.ProductsPerSupplier
+	products := make([]seededProduct, 0, total)
+
+	productQuery := `
+		INSERT INTO products
+		(supplier_id, name, description, price_to_tts, stock_quantity, sku,
+		is_variable, status, created_at, updated_at,
+		weight, pkg_length, pkg_width, pkg_height, commission_rate,
+		category, brand, srp, weight_grams,
+		images, video_url, size_chart, variation_images)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	variantQuery := `INSERT INTO product_variants (product_id, sku, price_to_tts, stock_quantity, options, commission_rate, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	err := s.inBatches(ctx, total, func(tx *sql.Tx, i int) error {
+		supplierID := supplierIDs[i/s.cfg.ProductsPerSupplier]
+		name := adjectives[s.rnd.Intn(len(adjectives))] + " " + nouns[s.rnd.Intn(len(nouns))]
+		price := money.Money(500 + s.rnd.Intn(20000)) // RM 5.00 - 205.00
+		stock := 10 + s.rnd.Intn(500)
+		sku := fmt.Sprintf("SEED-%s-%06d", s.runID, i+1)
+		created := s.randomTime()
+		weight := float64(50+s.rnd.Intn(2000)) / 1000
+		isVariable := s.cfg.MaxVariants > 0 && s.rnd.Intn(3) == 0
+		images, _ := json.Marshal([]string{fmt.Sprintf("/uploads/seed/%d-1.jpg", i+1), fmt.Sprintf("/uploads/seed/%d-2.jpg", i+1)})
+
+		// Build variants first so the product row can carry the roll-up price/stock.
+		type variant struct {
+			sku     string
+			price   money.Money
+			stock   int
+			options []models.ProductVariantOption
+		}
+		var variants []variant
+		if isVariable {
+			count := 2 + s.rnd.Intn(s.cfg.MaxVariants)
+			if count > s.cfg.MaxVariants {
+				count = s.cfg.MaxVariants
+			}
+			stock, price = 0, 0
+			for v := 0; v < count; v++ {
+				vp := money.Money(500 + s.rnd.Intn(20000))
+				vs := 5 + s.rnd.Intn(200)
+				variants = append(variants, variant{
+					sku:   fmt.Sprintf("%s-V%d", sku, v+1),
+					price: vp,
+					stock: vs,
+					options: []models.ProductVariantOption{
+						{Name: "Color", Value: colors[v%len(colors)]},
+						{Name: "Size", Value: sizes[s.rnd.Intn(len(sizes))]},
+					},
+				})
+				stock += vs
+				if price == 0 || vp < price {
+					price = vp
+				}
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, productQuery,
+			supplierID, name, "Seeded product for load testing. "+name+" in assorted colours and sizes.",
+			price, stock, sku,
+			isVariable, "active", created, created,
+			weight, 30.0, 20.0, 10.0, seedCommissionRate,
+			"Uncategorized", "Generic", price.Percent(130), int(weight*1000),
+			string(images), "", "null", "{}",
+		)
+		if err != nil {
+			return err
+		}
+		productID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		p := seededProduct{ID: productID, SupplierID: supplierID, Price: price}
+
+		for _, v := range variants {
+			opts, _ := json.Marshal(v.options)
+			vres, err := tx.ExecContext(ctx, variantQuery, productID, v.sku, v.price, v.stock, string(opts), nil, created, created)
+			if err != nil {
+				return err
+			}
+			variantID, err := vres.LastInsertId()
+			if err != nil {
+				return err
+			}
+			p.VariantIDs = append(p.VariantIDs, variantID)
+			p.Prices = append(p.Prices, v.price)
+		}
+
+		if len(s.categoryIDs) > 0 {
+			cid := s.categoryIDs[s.rnd.Intn(len(s.categoryIDs))]
+			if _, err := tx.ExecContext(ctx, "INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)", productID, cid); err != nil {
+				return err
+			}
+		}
+		if len(s.brandIDs) > 0 {
+			bid := s.brandIDs[s.rnd.Intn(len(s.brandIDs))]
+			if _, err := tx.ExecContext(ctx, "INSERT INTO product_brands (product_id, brand_id) VALUES (?, ?)", productID, bid); err != nil {
+				return err
+			}
+		}
+
+		products = append(products, p)
+		return nil
+	})
+	return products, err
+}
+
+// createOrders generates each dropshipper's orders in chronological order so
+// wallet balances (balance_after) stay consistent: a top-up is recorded whenever
+// the balance would not cover the next paid order.
+func (s *seeder) createOrders(ctx context.Context, dropshipperIDs []int64, products []seededProduct) (int, int, error) {
+	if len(products) == 0 {
+		return 0, 0, nil
+	}
+
+	orderQuery := `INSERT INTO orders (user_id, status, total, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
+	itemQuery := `INSERT INTO order_items (order_id, product_id, variant_id, supplier_id, quantity, unit_price, commission_rate, commission, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	walletQuery := `INSERT INTO wallet_transactions (user_id, type, status, amount, balance_after, notes, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	orderCount, txCount := 0, 0
+	err := s.inBatches(ctx, len(dropshipperIDs), func(tx *sql.Tx, i int) error {
+		userID := dropshipperIDs[i]
+
+		times := make([]time.Time, s.cfg.OrdersPerUser)
+		for t := range times {
+			times[t] = s.randomTime()
+		}
+		sort.Slice(times, func(a, b int) bool { return times[a].Before(times[b]) })
+
+		var balance money.Money
+		for _, created := range times {
+			status := orderMix[s.rnd.Intn(len(orderMix))]
+
+			// a. Pick 1-4 line items
+			type line struct {
+				productID  int64
+				supplierID int64
+				variantID  *int64
+				qty        int
+				price      money.Money
+			}
+			var lines []line
+			var total money.Money
+			for n := 1 + s.rnd.Intn(4); n > 0; n-- {
+				p := products[s.rnd.Intn(len(products))]
+				l := line{productID: p.ID, supplierID: p.SupplierID, qty: 1 + s.rnd.Intn(3), price: p.Price}
+				if len(p.VariantIDs) > 0 {
+					v := s.rnd.Intn(len(p.VariantIDs))
+					l.variantID = &p.VariantIDs[v]
+					l.price = p.Prices[v]
+				}
+				total += l.price.Mul(l.qty)
+				lines = append(lines, l)
+			}
+
+			// b. Top up first if this order will be paid from the wallet
+			paid := status != "on-hold" && status != "cancelled"
+			if paid && balance < total {
+				topup := ((total-balance)/(100*money.Ringgit) + 1) * 100 * money.Ringgit
+				balance += topup
+				if _, err := tx.ExecContext(ctx, walletQuery, userID, "topup", "completed", topup, balance, "Seed top-up", created.Add(-time.Minute)); err != nil {
+					return err
+				}
+				txCount++
+			}
+
+			// c. Order + items
+			res, err := tx.ExecContext(ctx, orderQuery, userID, status, total, created, created)
+			if err != nil {
+				return err
+			}
+			orderID, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			for _, l := range lines {
+				commission := l.price.Mul(l.qty).Percent(seedCommissionRate)
+				if _, err := tx.ExecContext(ctx, itemQuery, orderID, l.productID, l.variantID, l.supplierID, l.qty, l.price, seedCommissionRate, commission, created); err != nil {
+					return err
+				}
+			}
+			orderCount++
+
+			// d. Payment
+			if paid {
+				balance -= total
+				if _, err := tx.ExecContext(ctx, walletQuery, userID, "order_payment", "completed", -total, balance, fmt.Sprintf("Payment for Order ID %d", orderID), created); err != nil {
+					return err
+				}
+				txCount++
+			}
+		}
+		return nil
+	})
+	return orderCount, txCount, err
+}
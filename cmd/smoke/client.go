@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// client calls the API under test.
+type client struct {
+	baseURL string // ends in /v1
+	http    *http.Client
+}
+
+// call sends body as JSON (when non-nil) and fails unless the response has
+// wantStatus; out, when non-nil, receives the decoded response body.
+func (c *client) call(ctx context.Context, method, path, token string, body interface{}, wantStatus int, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != wantStatus {
+		// The error envelope carries the request ID, which finds the server-side log line.
+		var apiErr struct {
+			Error     string `json:"error"`
+			RequestID string `json:"requestId"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		if apiErr.Error != "" {
+			return fmt.Errorf("%s %s: status %d, want %d: %s (request %s)", method, path, res.StatusCode, wantStatus, apiErr.Error, apiErr.RequestID)
+		}
+		return fmt.Errorf("%s %s: status %d, want %d", method, path, res.StatusCode, wantStatus)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// smokePassword is used for both accounts the run creates.
+const smokePassword = "smoke-test-password"
+
+// run executes the scripted flow and reports whether every step passed.
+func (r *runner) run(ctx context.Context) bool {
+	r.supplier = account{email: r.email("supplier"), password: smokePassword}
+	r.dropshipper = account{email: r.email("dropshipper"), password: smokePassword}
+	r.manager = account{email: r.cfg.ManagerEmail, password: r.cfg.ManagerPassword}
+
+	// 1. --- Reachability ---
+	r.do("ping", func() error {
+		return r.client.call(ctx, http.MethodGet, "/ping", "", nil, http.StatusOK, nil)
+	})
+
+	// 2. --- Registration & Verification ---
+	r.do("register supplier", func() error {
+		return r.client.call(ctx, http.MethodPost, "/register/supplier", "", map[string]interface{}{
+			"fullName":        "Smoke Supplier",
+			"email":           r.supplier.email,
+			"password":        r.supplier.password,
+			"phoneNumber":     "0100000000",
+			"registrationKey": r.cfg.RegistrationKey,
+			"companyName":     "Smoke Test Sdn Bhd",
+		}, http.StatusCreated, nil)
+	})
+	r.do("register dropshipper", func() error {
+		return r.client.call(ctx, http.MethodPost, "/register/dropshipper", "", map[string]interface{}{
+			"fullName":    "Smoke Dropshipper",
+			"email":       r.dropshipper.email,
+			"password":    r.dropshipper.password,
+			"phoneNumber": "0100000001",
+		}, http.StatusCreated, nil)
+	})
+	r.do("verify supplier email", func() error { return r.verify(ctx, r.supplier.email) })
+	r.do("verify dropshipper email", func() error { return r.verify(ctx, r.dropshipper.email) })
+
+	// 3. --- Logins ---
+	r.do("log in as supplier", func() error { return r.login(ctx, &r.supplier) })
+	r.do("log in as dropshipper", func() error { return r.login(ctx, &r.dropshipper) })
+	r.do("log in as manager", func() error { return r.login(ctx, &r.manager) })
+
+	// 4. --- Catalogue ---
+	r.do("load categories", func() error {
+		var out struct {
+			Categories []struct {
+				ID int64 `json:"id"`
+			} `json:"categories"`
+		}
+		if err := r.client.call(ctx, http.MethodGet, "/categories", "", nil, http.StatusOK, &out); err != nil {
+			return err
+		}
+		if len(out.Categories) == 0 {
+			return errors.New("no categories exist; create one before running the smoke test")
+		}
+		r.categoryID = out.Categories[0].ID
+		return nil
+	})
+	r.do("create product", func() error {
+		var out struct {
+			ProductID int64  `json:"productId"`
+			PublicID  string `json:"publicId"`
+		}
+		err := r.client.call(ctx, http.MethodPost, "/products", r.supplier.token, map[string]interface{}{
+			"name":         "Smoke Test Product " + r.runID,
+			"description":  "Created by cmd/smoke; deleted at the end of the run.",
+			"status":       "pending",
+			"brandName":    "Smoke Test",
+			"category_ids": []int64{r.categoryID},
+			"images":       []string{"https://example.com/smoke.png"},
+			"simpleProduct": map[string]interface{}{
+				"sku":   "SMOKE-" + r.runID,
+				"price": 10,
+				"stock": 5,
+				"srp":   15,
+			},
+		}, http.StatusCreated, &out)
+		r.productID, r.productPublicID = out.ProductID, out.PublicID
+		return err
+	})
+	r.do("approve product", func() error {
+		return r.client.call(ctx, http.MethodPatch, "/manager/products/"+r.productPublicID+"/approve", r.manager.token, nil, http.StatusOK, nil)
+	})
+
+	// 5. --- Purchase ---
+	r.do("top up dropshipper wallet", func() error {
+		return r.client.call(ctx, http.MethodPost, "/dropshipper/wallet/topup", r.dropshipper.token,
+			map[string]interface{}{"amount": 50}, http.StatusOK, nil)
+	})
+	r.do("add product to cart", func() error {
+		return r.client.call(ctx, http.MethodPost, "/dropshipper/cart/items", r.dropshipper.token,
+			map[string]interface{}{"product_id": r.productID, "quantity": 1}, http.StatusCreated, nil)
+	})
+	r.do("check out", func() error {
+		var out struct {
+			Status string `json:"status"`
+		}
+		if err := r.client.call(ctx, http.MethodPost, "/dropshipper/checkout", r.dropshipper.token, nil, http.StatusCreated, &out); err != nil {
+			return err
+		}
+		if out.Status != "processing" {
+			return fmt.Errorf("order status is %q, want processing (the wallet should cover it)", out.Status)
+		}
+		return nil
+	})
+
+	return !r.failed
+}
+
+// verify reads the emailed code from the database and submits it.
+func (r *runner) verify(ctx context.Context, email string) error {
+	var code string
+	if err := r.db.QueryRowContext(ctx, "SELECT verification_code FROM users WHERE email = ? AND verification_code IS NOT NULL", email).Scan(&code); err != nil {
+		return fmt.Errorf("read verification code: %w", err)
+	}
+	return r.client.call(ctx, http.MethodPost, "/auth/verify-email", "", map[string]string{"email": email, "code": code}, http.StatusOK, nil)
+}
+
+func (r *runner) login(ctx context.Context, a *account) error {
+	var out struct {
+		Token string `json:"token"`
+		User  struct {
+			ID int64 `json:"id"`
+		} `json:"user"`
+	}
+	if err := r.client.call(ctx, http.MethodPost, "/login", "", map[string]string{"email": a.email, "password": a.password}, http.StatusOK, &out); err != nil {
+		return err
+	}
+	a.token, a.id = out.Token, out.User.ID
+	return nil
+}
+
+// cleanup soft-deletes what the run created, whether or not it passed, so
+// smoke data never shows up in the catalogue. Orders stay for the audit trail.
+func (r *runner) cleanup(ctx context.Context) {
+	if r.productID != 0 {
+		if _, err := r.db.ExecContext(ctx, "UPDATE products SET deleted_at = NOW() WHERE id = ? AND deleted_at IS NULL", r.productID); err != nil {
+			log.Printf("WARN  cleanup: product %d: %v", r.productID, err)
+		}
+	}
+	for _, email := range []string{r.supplier.email, r.dropshipper.email} {
+		if _, err := r.db.ExecContext(ctx, "UPDATE users SET deleted_at = NOW() WHERE email = ? AND deleted_at IS NULL", email); err != nil {
+			log.Printf("WARN  cleanup: user %s: %v", email, err)
+		}
+	}
+}
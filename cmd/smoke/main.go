@@ -0,0 +1,157 @@
+// Command smoke runs a scripted end-to-end flow against a deployed API and
+// reports pass/fail per step. Run it after every deployment:
+//
+//	go run ./cmd/smoke -base-url https://api.example.com -manager-email ops@example.com
+//
+// The flow: register a supplier and a dropshipper, verify both emails, create
+// a product, approve it as a manager, top up the dropshipper's wallet, add the
+// product to the cart and check out. Email verification codes are read from
+// the database (DB_DSN_PRIMARY, as for cmd/seed), since the smoke test cannot
+// read a mailbox. Every account it creates uses an @smoke.taptosell.test
+// email and is soft-deleted, with its product, when the run ends.
+//
+// The exit status is 1 when any step failed.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/01moynul/taptosell-golang/internal/database"
+	"github.com/joho/godotenv"
+)
+
+// smokeConfig holds the command line.
+type smokeConfig struct {
+	BaseURL         string
+	RegistrationKey string
+	ManagerEmail    string
+	ManagerPassword string
+	Timeout         time.Duration
+}
+
+func main() {
+	cfg := smokeConfig{}
+	flag.StringVar(&cfg.BaseURL, "base-url", envOr("SMOKE_BASE_URL", "http://localhost:8080"), "API base URL, without /v1")
+	flag.StringVar(&cfg.RegistrationKey, "registration-key", os.Getenv("SMOKE_SUPPLIER_KEY"), "supplier registration key (settings.supplier_registration_key)")
+	flag.StringVar(&cfg.ManagerEmail, "manager-email", os.Getenv("SMOKE_MANAGER_EMAIL"), "manager account that approves the product")
+	flag.StringVar(&cfg.ManagerPassword, "manager-password", os.Getenv("SMOKE_MANAGER_PASSWORD"), "password of -manager-email")
+	flag.DurationVar(&cfg.Timeout, "timeout", 15*time.Second, "timeout per HTTP request")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("WARNING: Could not find or load .env file. Relying on system environment variables.")
+	}
+	if cfg.ManagerEmail == "" || cfg.ManagerPassword == "" {
+		log.Fatal("-manager-email and -manager-password (or SMOKE_MANAGER_EMAIL / SMOKE_MANAGER_PASSWORD) are required")
+	}
+
+	dbCfg, err := config.LoadDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := database.OpenDB(dbCfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to primary database: %v", err)
+	}
+	defer db.Close()
+
+	r := &runner{
+		cfg:    cfg,
+		db:     db,
+		client: &client{baseURL: cfg.BaseURL + "/v1", http: &http.Client{Timeout: cfg.Timeout}},
+		runID:  time.Now().Format("20060102150405"),
+	}
+	ok := r.run(context.Background())
+	r.cleanup(context.Background())
+
+	r.summary()
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// step is one reported check.
+type step struct {
+	name     string
+	err      error
+	skipped  bool
+	duration time.Duration
+}
+
+type runner struct {
+	cfg    smokeConfig
+	db     *sql.DB
+	client *client
+	runID  string
+	steps  []step
+	failed bool
+
+	// State handed from step to step.
+	supplier, dropshipper, manager account
+	categoryID                     int64
+	productID                      int64
+	productPublicID                string
+}
+
+// account is a user the flow logs in as.
+type account struct {
+	email    string
+	password string
+	id       int64
+	token    string
+}
+
+// do runs fn as a named step. After the first failure the remaining steps
+// are reported as skipped, since each one builds on the previous.
+func (r *runner) do(name string, fn func() error) {
+	if r.failed {
+		r.steps = append(r.steps, step{name: name, skipped: true})
+		log.Printf("SKIP  %s", name)
+		return
+	}
+	start := time.Now()
+	err := fn()
+	s := step{name: name, err: err, duration: time.Since(start)}
+	r.steps = append(r.steps, s)
+	if err != nil {
+		r.failed = true
+		log.Printf("FAIL  %s (%s): %v", name, s.duration.Round(time.Millisecond), err)
+		return
+	}
+	log.Printf("PASS  %s (%s)", name, s.duration.Round(time.Millisecond))
+}
+
+func (r *runner) summary() {
+	var passed, failed, skipped int
+	for _, s := range r.steps {
+		switch {
+		case s.skipped:
+			skipped++
+		case s.err != nil:
+			failed++
+		default:
+			passed++
+		}
+	}
+	log.Printf("Smoke run %s against %s: %d passed, %d failed, %d skipped", r.runID, r.cfg.BaseURL, passed, failed, skipped)
+}
+
+// email builds a unique address for this run.
+func (r *runner) email(role string) string {
+	return fmt.Sprintf("smoke-%s-%s@smoke.taptosell.test", role, r.runID)
+}
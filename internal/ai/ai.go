@@ -5,14 +5,23 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
+	"regexp"
 	"strings"
 
+	"github.com/01moynul/taptosell-golang/internal/logging"
 	"github.com/google/generative-ai-go/genai"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/option"
 	// REMOVED: iterator import
 )
 
+// tracer covers the chat as a whole, every Gemini round trip and every tool call.
+var tracer = otel.Tracer("github.com/01moynul/taptosell-golang/internal/ai")
+
 // AIService holds the Gemini client and the read-only database connection.
 type AIService struct {
 	Client *genai.Client
@@ -30,13 +39,27 @@ func NewAIService(apiKey string, dbReadOnly *sql.DB) (*AIService, error) {
 }
 
 // UPDATED: Now returns (response string, totalTokens int, err error)
-func (s *AIService) GenerateResponse(ctx context.Context, userMessage string, userRole string, modelName string) (string, int, error) {
+func (s *AIService) GenerateResponse(ctx context.Context, userMessage string, userRole string, modelName string) (reply string, totalTokens int, err error) {
 	// 1. Use the model name passed from the handler (dynamic configuration)
 	if modelName == "" {
 		modelName = "gemini-1.5-flash" // Fallback default
 	}
 	model := s.Client.GenerativeModel(modelName)
 
+	ctx, span := tracer.Start(ctx, "ai.GenerateResponse", trace.WithAttributes(
+		semconv.GenAISystemGCPGemini,
+		semconv.GenAIRequestModel(modelName),
+		attribute.String("user.role", userRole),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("gen_ai.usage.total_tokens", totalTokens))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	// 2. Define Tools (Same as before)
 	sqlTool := &genai.Tool{
 		FunctionDeclarations: []*genai.FunctionDeclaration{
@@ -71,7 +94,7 @@ func (s *AIService) GenerateResponse(ctx context.Context, userMessage string, us
 
 	// 4. Execute Chat
 	cs := model.StartChat()
-	res, err := cs.SendMessage(ctx, genai.Text(userMessage))
+	res, err := s.send(ctx, cs, modelName, genai.Text(userMessage))
 	if err != nil {
 		return "", 0, fmt.Errorf("error sending message: %w", err)
 	}
@@ -79,7 +102,6 @@ func (s *AIService) GenerateResponse(ctx context.Context, userMessage string, us
 	// 5. Handle Response & Count Tokens
 	// We need to track tokens across the whole conversation (initial prompt + tool use)
 	// Note: Gemini Go SDK UsageMetadata is on the Response object.
-	totalTokens := 0
 	if res.UsageMetadata != nil {
 		totalTokens += int(res.UsageMetadata.TotalTokenCount)
 	}
@@ -104,15 +126,20 @@ func (s *AIService) GenerateResponse(ctx context.Context, userMessage string, us
 			if !ok {
 				return "", totalTokens, fmt.Errorf("invalid query argument")
 			}
-			log.Printf("🤖 AI running SQL: %s", query)
+			logging.Debugf("🤖 AI running SQL: %s", query)
 
-			sqlResult, sqlErr := s.runReadOnlyQuery(query)
+			toolCtx, toolSpan := tracer.Start(ctx, "ai.tool run_readonly_sql", trace.WithAttributes(semconv.GenAIToolName(funcCall.Name)))
+			sqlResult, sqlErr := s.runReadOnlyQuery(toolCtx, query)
 			if sqlErr != nil {
+				// The error goes back to Gemini, which usually retries with a fixed query.
+				toolSpan.RecordError(sqlErr)
+				toolSpan.SetStatus(codes.Error, sqlErr.Error())
 				sqlResult = fmt.Sprintf("SQL Error: %v", sqlErr)
 			}
+			toolSpan.End()
 
 			// Send Tool Response back to Gemini
-			res, err = cs.SendMessage(ctx, genai.FunctionResponse{
+			res, err = s.send(ctx, cs, modelName, genai.FunctionResponse{
 				Name:     "run_readonly_sql",
 				Response: map[string]interface{}{"result": sqlResult},
 			})
@@ -132,13 +159,78 @@ func (s *AIService) GenerateResponse(ctx context.Context, userMessage string, us
 	}
 }
 
-// runReadOnlyQuery (Same as before)
-func (s *AIService) runReadOnlyQuery(query string) (string, error) {
+// send is one Gemini round trip, traced on its own so slow model turns stand
+// out from slow tool calls.
+func (s *AIService) send(ctx context.Context, cs *genai.ChatSession, modelName string, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	ctx, span := tracer.Start(ctx, "gemini.SendMessage", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.GenAISystemGCPGemini,
+		semconv.GenAIRequestModel(modelName),
+	))
+	defer span.End()
+
+	res, err := cs.SendMessage(ctx, parts...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if res.UsageMetadata != nil {
+		span.SetAttributes(
+			semconv.GenAIUsageInputTokens(int(res.UsageMetadata.PromptTokenCount)),
+			semconv.GenAIUsageOutputTokens(int(res.UsageMetadata.CandidatesTokenCount)),
+		)
+	}
+	return res, nil
+}
+
+// deniedTables hold personal data of people other than the caller (a
+// dropshipper's customers and the addresses on their orders), secrets or raw
+// request bodies. The assistant is open to every role and its queries are not
+// scoped to the caller, so it may not read them even when asked by name; the
+// schemas are denied so it cannot look them up either.
+var deniedTables = map[string]bool{
+	"customers":           true,
+	"orders":              true,
+	"request_captures":    true,
+	"dispute_evidence":    true,
+	"channels":            true,
+	"messaging_providers": true,
+	"webhook_endpoints":   true,
+	"backups":             true,
+	"app_errors":          true,
+	"information_schema":  true,
+	"performance_schema":  true,
+	"mysql":               true,
+	"sys":                 true,
+}
+
+// sqlIdentifier matches a bare or backquoted identifier.
+var sqlIdentifier = regexp.MustCompile("`([^`]+)`|[A-Za-z_][A-Za-z0-9_$]*")
+
+// deniedTable returns the first denied table the query names, or "".
+func deniedTable(query string) string {
+	for _, m := range sqlIdentifier.FindAllStringSubmatch(query, -1) {
+		name := m[0]
+		if m[1] != "" {
+			name = m[1]
+		}
+		if name = strings.ToLower(name); deniedTables[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// runReadOnlyQuery runs the model's SELECT on the read-only connection.
+func (s *AIService) runReadOnlyQuery(ctx context.Context, query string) (string, error) {
 	normalized := strings.ToUpper(query)
 	if strings.Contains(normalized, "UPDATE") || strings.Contains(normalized, "DELETE") || strings.Contains(normalized, "DROP") || strings.Contains(normalized, "INSERT") {
 		return "", fmt.Errorf("security violation: modify operations are not allowed")
 	}
-	rows, err := s.DB.Query(query)
+	if table := deniedTable(query); table != "" {
+		return "", fmt.Errorf("security violation: %s is not available to the assistant", table)
+	}
+	rows, err := s.DB.QueryContext(ctx, query)
 	if err != nil {
 		return "", err
 	}
@@ -177,8 +269,10 @@ func (s *AIService) runReadOnlyQuery(query string) (string, error) {
 // getSchemaDefinition (Same as before)
 func (s *AIService) getSchemaDefinition() string {
 	return `
-	- users (id, role [dropshipper, supplier, admin], status [unverified, pending, active, suspended], email, full_name, phone_number, company_name, ssm_number, city, state)
-	- products (id, supplier_id, name, description, category, brand, price_to_tts, srp, stock_quantity, status [pending_review, active, inactive, rejected], weight_grams)
+	- users (id, role [dropshipper, supplier, admin], status [unverified, pending, active, suspended], email, full_name, phone_number, company_name, city, state, vacation_started_at, vacation_ends_at [NULL = until turned off], handling_days, order_cutoff, sst_number [NULL = not SST-registered])
+	- products (id, supplier_id, name, description, category, brand, price_to_tts, srp, stock_quantity, status [pending_review, active, inactive, rejected], rejection_reason [last rejection, cleared on approval], weight_grams, rating_avg, rating_count, is_preorder, preorder_available_at, preorder_limit, preorder_reserved, shipping_restrictions [SET of battery, liquid, fragile, oversize], min_order_qty, order_increment, handling_days [NULL = supplier setting], order_cutoff [NULL = supplier setting])
+	- product_reviews (id, product_id, dropshipper_id, order_id, rating [1-5], comment, supplier_reply, status [published, flagged, hidden], created_at)
+	- product_questions (id, product_id, dropshipper_id, question, answer [NULL = unanswered], status [published, hidden], created_at, answered_at)
 	- categories (id, name, slug, parent_id)
 	- brands (id, name, slug)
 	- carts (id, user_id)
@@ -186,14 +280,28 @@ func (s *AIService) getSchemaDefinition() string {
 	- inventory_items (id, user_id, name, sku, price, stock, promoted_product_id)
 	- inventory_categories (id, user_id, name, slug)
 	- inventory_brands (id, user_id, name, slug)
-	- wallet_transactions (id, user_id, type [topup, order_payment, withdrawal, refund, payout], status, amount, balance_after, created_at)
-	- withdrawal_requests (id, user_id, amount, status [pending, approved, rejected], bank_details, rejection_reason)
+	- wallet_transactions (id, user_id, type [topup, order_payment, withdrawal, refund, payout, promo_credit], status, amount, balance_after, created_at)
+	- withdrawal_requests (id, user_id, amount, status [pending, approved, rejected], rejection_reason)
 	- price_appeals (id, product_id, supplier_id, old_price, new_price, status, reason)
+	- disputes (id, order_id, dropshipper_id, supplier_id, reason [non_delivery, wrong_item, damaged, other], status [open, under_review, resolved, withdrawn], respond_by, refund_amount, supplier_amount, platform_amount, created_at, resolved_at)
+	- promotions (id, name, code [NULL = automatic campaign], kind [percent, fixed], value, max_discount, min_spend, category_id, first_order_only, usage_limit, per_user_limit, starts_at, ends_at, is_active)
+	- watchlist (id, dropshipper_id, product_id, last_price, in_stock, created_at) [products dropshippers watch for price changes and restocks]
+	- duplicate_clusters (id, status [open, merged, rejected, dismissed], kept_product_id, resolved_by, resolution_note, created_at, resolved_at) [suspected duplicate listings across suppliers]
+	- duplicate_pairs (product_a, product_b, cluster_id, reasons [comma-separated: barcode, title, image], similarity, created_at) [two linked listings of a cluster]
+	- product_exports (dropshipper_id, product_id, export_count, first_exported_at, last_exported_at) [products dropshippers exported to list on their own storefronts]
+	- referrals (id, referrer_id, referee_id, status [pending, rewarded, rejected], referrer_reward, referee_reward, reject_reason, created_at, rewarded_at)
+	- order_discounts (id, order_id, promotion_id, user_id, code, description, amount, created_at)
+	- tax_rates (category_id, rate [SST percent], updated_by, updated_at)
+	- order_tax_lines (id, order_id, supplier_id, rate, taxable_amount, tax_amount)
+	- invoices (id, order_id, supplier_id, supplier_sst_number, subtotal, tax_total, total, issued_at)
 	- notifications (id, user_id, message, is_read)
-	- plans (id, name, price, duration_days, ai_credits_included, is_public)
+	- plans (id, name, price, duration_days, ai_credits_included, ai_daily_requests [NULL = no cap], ai_daily_tokens [NULL = no cap], is_public)
 	- user_subscriptions (id, user_id, plan_id, status, expires_at)
 	- ai_user_credits (id, user_id, credits_remaining)
 	- ai_chat_history (id, user_id, user_message, ai_response, tokens_used, cost_incurred)
+	- ai_usage_daily (user_id, day, requests, tokens) [today's AI chat use; ended days are deleted]
+	- ai_moderation_events (id, user_id, direction [input, output], category [blocked_topic, data_extraction, data_leak], rule, created_at) [AI chat messages and replies blocked by moderation]
 	- settings (setting_key, setting_value, description)
+	- status_entries (id, kind [incident, maintenance, release], title, severity [info, minor, major, critical], release_tag, starts_at, ends_at, blocks_writes, is_published)
 	`
 }
@@ -0,0 +1,21 @@
+package ai
+
+import "testing"
+
+func TestDeniedTable(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"SELECT name, phone, address_line1 FROM customers WHERE id = 1", "customers"},
+		{"select * from `Customers`", "customers"},
+		{"SELECT ship_to FROM orders o JOIN users u ON u.id = o.user_id", "orders"},
+		{"SELECT table_name FROM information_schema.tables", "information_schema"},
+		{"SELECT COUNT(*) FROM order_items WHERE order_id = 3", ""},
+		{"SELECT name, price_to_tts FROM products WHERE status = 'active'", ""},
+	}
+	for _, tt := range tests {
+		if got := deniedTable(tt.query); got != tt.want {
+			t.Errorf("deniedTable(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Package apierror defines the error response contract shared by every handler
+// and middleware:
+//
+//	{
+//	  "error":     "Human readable message",   // unchanged key, so older clients keep working
+//	  "code":      "validation_failed",        // stable machine-readable code
+//	  "fields":    [{"field": "variants[0].price", "message": "must be 0 or greater"}],
+//	  "details":   {...},                       // optional, code-specific data (see WithDetails)
+//	  "requestId": "4b1c..."                    // matches the X-Request-ID response header
+//	}
+//
+// That is the v1 shape. From /v2 on, the same fields nest under "error", so a
+// client tells an error from data by one key and "error" is never a string:
+//
+//	{"error": {"code": "validation_failed", "message": "...", "fields": [...], "details": {...}, "requestId": "4b1c..."}}
+//
+// Handlers call the helpers (BadRequest, NotFound, Internal, ...) instead of
+// writing gin.H{"error": ...} by hand.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/01moynul/taptosell-golang/internal/i18n"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// RequestIDKey is the gin context key holding the request ID (set by middleware.RequestID).
+const RequestIDKey = "requestId"
+
+// Code is a stable, machine-readable error identifier.
+type Code string
+
+const (
+	CodeBadRequest         Code = "bad_request"
+	CodeValidation         Code = "validation_failed"
+	CodeUnauthorized       Code = "unauthorized"
+	CodePaymentRequired    Code = "payment_required"
+	CodeForbidden          Code = "forbidden"
+	CodeNotFound           Code = "not_found"
+	CodeConflict           Code = "conflict"
+	CodeQuantityRule       Code = "quantity_rule" // details: the product's minimum and pack size
+	CodeTooLarge           Code = "too_large"
+	CodeQuotaExceeded      Code = "quota_exceeded"
+	CodeUnsupportedMedia   Code = "unsupported_media_type"
+	CodeTimeout            Code = "timeout"
+	CodeServiceUnavailable Code = "service_unavailable"
+	CodeInternal           Code = "internal_error"
+)
+
+// FieldError is one invalid input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Response is the JSON body of every error.
+type Response struct {
+	Error     string       `json:"error"`
+	Code      Code         `json:"code"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	Details   interface{}  `json:"details,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+// V2Response is the JSON body of every error from /v2 on.
+type V2Response struct {
+	Error V2Error `json:"error"`
+}
+
+// V2Error is the error of a V2Response.
+type V2Error struct {
+	Code      Code         `json:"code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	Details   interface{}  `json:"details,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+// Abort writes the error envelope and stops the handler chain.
+// Server errors are also recorded in c.Errors for the logger and the error reporter,
+// in English; the body is translated into the request's language (middleware.Locale)
+// and shaped for the request's API version (middleware.APIVersion).
+func Abort(c *gin.Context, status int, code Code, message string, fields ...FieldError) {
+	abort(c, status, code, message, nil, fields)
+}
+
+// WithDetails responds like Abort with code-specific details the client can
+// act on, such as the limits a cart quantity broke.
+func WithDetails(c *gin.Context, status int, code Code, message string, details interface{}) {
+	abort(c, status, code, message, details, nil)
+}
+
+// Body returns the error envelope Abort would write, without writing it, for
+// responses that carry an error inside them (one item of a batch).
+func Body(c *gin.Context, code Code, message string) interface{} {
+	return body(c, code, message, nil, nil)
+}
+
+func abort(c *gin.Context, status int, code Code, message string, details interface{}, fields []FieldError) {
+	if status >= http.StatusInternalServerError {
+		_ = c.Error(errors.New(message))
+	}
+	c.AbortWithStatusJSON(status, body(c, code, message, details, fields))
+}
+
+// body builds the envelope in the request's language and API version.
+func body(c *gin.Context, code Code, message string, details interface{}, fields []FieldError) interface{} {
+	lang := c.GetString(i18n.ContextKey)
+	for i := range fields {
+		fields[i].Message = i18n.T(lang, fields[i].Message)
+	}
+	if apiversion.From(c) >= apiversion.V2 {
+		return V2Response{Error: V2Error{
+			Code:      code,
+			Message:   i18n.T(lang, message),
+			Fields:    fields,
+			Details:   details,
+			RequestID: c.GetString(RequestIDKey),
+		}}
+	}
+	return Response{
+		Error:     i18n.T(lang, message),
+		Code:      code,
+		Fields:    fields,
+		Details:   details,
+		RequestID: c.GetString(RequestIDKey),
+	}
+}
+
+// BadRequest responds 400 for malformed or semantically invalid input.
+func BadRequest(c *gin.Context, message string) {
+	Abort(c, http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// Unauthorized responds 401 for missing or invalid credentials.
+func Unauthorized(c *gin.Context, message string) {
+	Abort(c, http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// PaymentRequired responds 402 when the wallet cannot cover an operation.
+func PaymentRequired(c *gin.Context, message string) {
+	Abort(c, http.StatusPaymentRequired, CodePaymentRequired, message)
+}
+
+// Forbidden responds 403 when the user may not act on the resource.
+func Forbidden(c *gin.Context, message string) {
+	Abort(c, http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound responds 404.
+func NotFound(c *gin.Context, message string) {
+	Abort(c, http.StatusNotFound, CodeNotFound, message)
+}
+
+// Conflict responds 409 when the request clashes with the current state.
+func Conflict(c *gin.Context, message string) {
+	Abort(c, http.StatusConflict, CodeConflict, message)
+}
+
+// TooLarge responds 413 when an upload or request body exceeds its limit.
+func TooLarge(c *gin.Context, message string) {
+	Abort(c, http.StatusRequestEntityTooLarge, CodeTooLarge, message)
+}
+
+// QuotaExceeded responds 429 when the user has used up an allowance; set
+// Retry-After first.
+func QuotaExceeded(c *gin.Context, message string) {
+	Abort(c, http.StatusTooManyRequests, CodeQuotaExceeded, message)
+}
+
+// UnsupportedMediaType responds 415 for a body in a format the route does not accept.
+func UnsupportedMediaType(c *gin.Context, message string) {
+	Abort(c, http.StatusUnsupportedMediaType, CodeUnsupportedMedia, message)
+}
+
+// Timeout responds 504 when the request ran out of time.
+func Timeout(c *gin.Context, message string) {
+	Abort(c, http.StatusGatewayTimeout, CodeTimeout, message)
+}
+
+// ServiceUnavailable responds 503 (maintenance mode, dependencies down).
+func ServiceUnavailable(c *gin.Context, message string) {
+	Abort(c, http.StatusServiceUnavailable, CodeServiceUnavailable, message)
+}
+
+// Internal responds 500. The message must not contain raw error text;
+// log the cause separately (the request ID ties the log line to the response).
+func Internal(c *gin.Context, message string) {
+	Abort(c, http.StatusInternalServerError, CodeInternal, message)
+}
+
+// Validation responds 400 for a failed ShouldBind* call. Validator errors and
+// values of the wrong type (including amounts finer than a sen) are translated
+// into per-field messages; other binding errors (bad JSON) become a single message.
+func Validation(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		TooLarge(c, "Request body is too large")
+		return
+	}
+	if fields := ValidationFields(err); fields != nil {
+		Abort(c, http.StatusBadRequest, CodeValidation, "Invalid input", fields...)
+		return
+	}
+	if field, ok := typeFieldError(err); ok {
+		Abort(c, http.StatusBadRequest, CodeValidation, "Invalid input", field)
+		return
+	}
+	Abort(c, http.StatusBadRequest, CodeValidation, bindingMessage(err))
+}
+
+// ValidationFields translates validator errors into per-field messages, as
+// Validation does; it returns nil for any other error. Handlers validating
+// input that did not come from a request body (rows of an uploaded file) use it.
+func ValidationFields(err error) []FieldError {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+	fields := make([]FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)})
+	}
+	return fields
+}
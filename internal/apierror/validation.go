@@ -0,0 +1,152 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// UseJSONFieldNames makes the validator report fields by their json tag
+// ("category_ids", "variants[0].price") instead of the Go field name.
+// Call it once at startup, before the router serves requests.
+func UseJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return f.Name
+	})
+}
+
+// fieldPath drops the top-level struct name: "CreateProductInput.variants[0].price" -> "variants[0].price".
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+// fieldMessage turns a validation tag into a short message for the UI.
+func fieldMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(param, " ", ", ")
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be " + param + " or greater"
+	case "lt":
+		return "must be less than " + param
+	case "lte":
+		return "must be " + param + " or less"
+	case "min":
+		if isCollection(fe.Kind()) {
+			return "must contain at least " + param + " item(s)"
+		}
+		return "must be at least " + param + lengthUnit(fe.Kind())
+	case "max":
+		if isCollection(fe.Kind()) {
+			return "must contain at most " + param + " item(s)"
+		}
+		return "must be at most " + param + lengthUnit(fe.Kind())
+	case "len":
+		return "must be exactly " + param + lengthUnit(fe.Kind())
+	case "url":
+		return "must be a valid URL"
+	case "startswith":
+		return "must start with " + param
+	case "myphone":
+		return "must be a Malaysian phone number, like 012-345 6789"
+	case "postcode":
+		return "must be a 5-digit postcode"
+	case "slug":
+		return "must be lowercase letters, digits and dashes, like mens-clothing"
+	case "price":
+		return "must be between 0 and 1000000.00"
+	}
+	return fmt.Sprintf("failed the %q rule", fe.Tag())
+}
+
+func isCollection(k reflect.Kind) bool {
+	return k == reflect.Slice || k == reflect.Array || k == reflect.Map
+}
+
+func lengthUnit(k reflect.Kind) string {
+	if k == reflect.String {
+		return " characters"
+	}
+	return ""
+}
+
+// typeFieldError is the field error for a JSON value of the wrong type, when
+// the decoder knows which field it was in.
+func typeFieldError(err error) (FieldError, bool) {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field == "" {
+		return FieldError{}, false
+	}
+	if typeErr.Type == reflect.TypeOf(money.Money(0)) {
+		return FieldError{Field: typeErr.Field, Message: "must be an amount with at most 2 decimal places"}, true
+	}
+	return FieldError{Field: typeErr.Field, Message: "has the wrong type (expected " + jsonTypeName(typeErr.Type) + ")"}, true
+}
+
+// bindingMessage describes non-validator binding errors without leaking Go type names.
+func bindingMessage(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "Request body is empty"
+	case errors.As(err, &typeErr):
+		if typeErr.Type == reflect.TypeOf(money.Money(0)) {
+			return "Amounts must be numbers with at most 2 decimal places"
+		}
+		if typeErr.Field != "" {
+			return fmt.Sprintf("Field %q has the wrong type (expected %s)", typeErr.Field, jsonTypeName(typeErr.Type))
+		}
+		return "Request body has the wrong type"
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return "Request body is not valid JSON"
+	}
+	return err.Error()
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Ptr:
+		return jsonTypeName(t.Elem())
+	}
+	return "number"
+}
@@ -0,0 +1,67 @@
+package apierror
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// maxPrice is the highest amount the "price" tag accepts (RM 1,000,000).
+const maxPrice = 1_000_000 * money.Ringgit
+
+var (
+	// myPhonePattern is a Malaysian number in local form: mobile 01x (10 or
+	// 11 digits) or a landline area code 03-09 (9 or 10 digits).
+	myPhonePattern  = regexp.MustCompile(`^0(1\d{8,9}|[3-9]\d{7,8})$`)
+	postcodePattern = regexp.MustCompile(`^\d{5}$`)
+	slugPattern     = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+)
+
+// RegisterValidators adds the binding tags shared by the input types:
+//
+//	myphone   a Malaysian phone number; spaces, dashes, brackets and a +60 prefix are allowed
+//	postcode  a 5-digit Malaysian postcode
+//	slug      lowercase letters and digits in dash-separated words ("mens-clothing")
+//	price     a money.Money from 0 to RM 1,000,000 (sen precision is enforced when decoding)
+//
+// Call it once at startup, before the router serves requests.
+func RegisterValidators() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	_ = v.RegisterValidation("myphone", func(fl validator.FieldLevel) bool {
+		return IsMalaysianPhone(fl.Field().String())
+	})
+	_ = v.RegisterValidation("postcode", func(fl validator.FieldLevel) bool {
+		return postcodePattern.MatchString(fl.Field().String())
+	})
+	_ = v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slugPattern.MatchString(fl.Field().String())
+	})
+	_ = v.RegisterValidation("price", func(fl validator.FieldLevel) bool {
+		m, ok := fl.Field().Interface().(money.Money)
+		return ok && m >= 0 && m <= maxPrice
+	})
+}
+
+// IsMalaysianPhone reports whether s is a Malaysian phone number, written
+// locally ("012-345 6789") or internationally ("+60 12-345 6789").
+func IsMalaysianPhone(s string) bool {
+	digits := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9':
+			return r
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '+':
+			return -1
+		}
+		return 'x' // any other character fails the pattern
+	}, s)
+	if strings.HasPrefix(digits, "60") {
+		digits = digits[1:]
+	}
+	return myPhonePattern.MatchString(digits)
+}
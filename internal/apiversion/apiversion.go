@@ -0,0 +1,64 @@
+// Package apiversion tells handlers which API version a request was made
+// against. Every version serves the same routes; a version only changes the
+// shape of some responses:
+//
+//	v1  the original shapes; kept stable until its sunset date
+//	v2  errors nest under "error" ({"error": {"code", "message", ...}});
+//	    cart lines carry their variant and PUT/DELETE address one line;
+//	    responses use the typed shapes of package dto (camelCase keys only)
+//
+// middleware.APIVersion reads the version from the path prefix of every
+// request; handlers that differ between versions branch on From.
+package apiversion
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKey is the gin context key holding the request's Version (set by middleware.APIVersion).
+const ContextKey = "apiVersion"
+
+// Version is a major API version.
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+)
+
+// Supported lists the versions the router mounts, oldest first.
+var Supported = []Version{V1, V2}
+
+// Latest is the version new clients should use.
+const Latest = V2
+
+// Prefix is the version's route prefix, as in "/v2".
+func (v Version) Prefix() string {
+	return "/v" + v.String()
+}
+
+func (v Version) String() string {
+	return strconv.Itoa(int(v))
+}
+
+// FromPath returns the supported version a path starts with ("/v2/cart" is V2).
+func FromPath(path string) (Version, bool) {
+	for _, v := range Supported {
+		p := v.Prefix()
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// From returns the request's version; paths outside a version (uploads) count as V1.
+func From(c *gin.Context) Version {
+	if v, ok := c.Get(ContextKey); ok {
+		return v.(Version)
+	}
+	return V1
+}
@@ -0,0 +1,122 @@
+// Package audit keeps the raw requests and responses of webhook and payment
+// endpoints in the request_captures table, a capped table of the most recent
+// captures, so disputes can be settled from what was actually exchanged and
+// webhook deliveries can be replayed.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+)
+
+// Capture kinds. A webhook is authenticated by its signature and handlers must
+// be idempotent for it, so it may be replayed as often as needed; a payment is
+// kept for inspection only and never replayed (see ReplayRequestCapture).
+const (
+	KindWebhook = "webhook"
+	KindPayment = "payment"
+)
+
+// MaxBodyBytes caps how much of each request and response body is kept.
+const MaxBodyBytes = 1 << 20
+
+// replayKey marks the context of a replayed request with the original capture ID.
+// It is a context value, not a header, so clients cannot fake a replay.
+type replayKey struct{}
+
+// WithReplay marks ctx as the replay of capture id.
+func WithReplay(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, replayKey{}, id)
+}
+
+// ReplayOf returns the capture ID ctx replays, if any.
+func ReplayOf(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(replayKey{}).(int64)
+	return id, ok
+}
+
+// SignatureHeaders are checked in order; the first present one is stored as the signature.
+var SignatureHeaders = []string{"X-Signature", "X-Hub-Signature-256", "Stripe-Signature", "X-Webhook-Signature"}
+
+// redactedHeaders never reach the table.
+var redactedHeaders = map[string]bool{"Authorization": true, "Cookie": true, "X-Api-Key": true}
+
+// Recorder writes captures and trims the table to capacity rows.
+type Recorder struct {
+	db       *sql.DB
+	capacity int64
+}
+
+// NewRecorder returns a recorder keeping at most capacity captures in db.
+func NewRecorder(db *sql.DB, capacity int) *Recorder {
+	return &Recorder{db: db, capacity: int64(capacity)}
+}
+
+// Record stores c. It runs detached from the request context so a request
+// that timed out is still captured. Failures are logged, never returned:
+// the request itself already completed.
+func (r *Recorder) Record(ctx context.Context, c models.RequestCapture) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+
+	headers, err := json.Marshal(c.RequestHeaders)
+	if err != nil {
+		headers = []byte("{}")
+	}
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO request_captures
+			(kind, request_id, user_id, method, route, path, request_headers, request_body, signature, response_status, response_body, replay_of)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.Kind, c.RequestID, c.UserID, c.Method, c.Route, truncate(c.Path, 1024), string(headers),
+		c.RequestBody, truncate(c.Signature, 1024), c.ResponseStatus, c.ResponseBody, c.ReplayOf)
+	if err != nil {
+		logging.Errorf("[Audit] Could not capture %s %s (request %s): %v", c.Method, c.Path, c.RequestID, err)
+		return
+	}
+
+	// Same ring-buffer trim as errreport.DB: IDs only grow.
+	id, err := res.LastInsertId()
+	if err != nil || id <= r.capacity {
+		return
+	}
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM request_captures WHERE id <= ?", id-r.capacity); err != nil {
+		logging.Errorf("[Audit] Could not trim request_captures: %v", err)
+	}
+}
+
+// Headers copies h without credentials.
+func Headers(h http.Header) map[string][]string {
+	out := make(map[string][]string, len(h))
+	for k, v := range h {
+		if redactedHeaders[http.CanonicalHeaderKey(k)] {
+			out[k] = []string{"[redacted]"}
+			continue
+		}
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+// Signature returns the first signature header present in h.
+func Signature(h http.Header) string {
+	for _, name := range SignatureHeaders {
+		if v := strings.TrimSpace(h.Get(name)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
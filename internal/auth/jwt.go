@@ -2,34 +2,88 @@ package auth
 
 import (
 	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// We will hardcode our secret key for now.
-// In a real production app, we would load this from a secure .env file.
-// This key is used to "sign" our passports so we know they are real.
-var jwtSecretKey = []byte("A_VERY_SECURE_SECRET_KEY_REPLACE_LATER")
+// Key is one signing secret. ID goes into the token's "kid" header, so a
+// token is checked against the key that signed it even after a rotation.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// keys "sign" our passports so we know they are real: the first one signs,
+// all of them verify. Set from JWT_KEYS / JWT_SECRET by Configure at startup
+// and swapped by SetKeys when the secrets are rotated.
+var keys atomic.Pointer[[]Key]
+
+// tokenTTL is how long an issued token stays valid (JWT_TTL).
+var tokenTTL = 72 * time.Hour
+
+// Configure sets the signing keys and token lifetime. It must be called
+// before the first token is issued or validated.
+func Configure(signingKeys []Key, ttl time.Duration) {
+	SetKeys(signingKeys)
+	if ttl > 0 {
+		tokenTTL = ttl
+	}
+}
+
+// SetKeys replaces the signing keys; safe to call while requests are served.
+func SetKeys(signingKeys []Key) {
+	k := append([]Key(nil), signingKeys...)
+	keys.Store(&k)
+}
+
+// currentKeys returns the configured keys, or nil before Configure.
+func currentKeys() []Key {
+	if k := keys.Load(); k != nil {
+		return *k
+	}
+	return nil
+}
+
+// Claims is what a valid token says about its user. Role and Status are
+// copies taken at login: AuthMiddleware hands them to the role middlewares,
+// which need no database lookup, and only checks the user's (cached) row for
+// revocation. Tokens issued before they were added carry neither (empty strings).
+type Claims struct {
+	UserID   int64
+	Role     string
+	Status   string
+	IssuedAt time.Time
+}
 
-// GenerateToken creates a new JWT (passport) for a given user ID.
-func GenerateToken(userID int64) (string, error) {
+// GenerateToken creates a new JWT (passport) for a user with their current role and status.
+func GenerateToken(userID int64, role, status string) (string, error) {
 	// 1. Create the "claims" (the data inside the passport).
-	// We are claiming that this token is for a specific 'userID'.
-	// We also set an expiration time (72 hours).
+	// We are claiming that this token is for a specific 'userID' and role.
+	// We also set an expiration time (tokenTTL, 72 hours by default).
 	claims := jwt.MapClaims{
-		"sub": userID,                                // "sub" (Subject) is the standard claim for User ID
-		"exp": time.Now().Add(time.Hour * 72).Unix(), // Expires in 3 days
-		"iat": time.Now().Unix(),                     // "iat" (Issued At)
+		"sub":    userID,                          // "sub" (Subject) is the standard claim for User ID
+		"role":   role,                            // dropshipper, supplier, manager or administrator
+		"status": status,                          // account status at login
+		"exp":    time.Now().Add(tokenTTL).Unix(), // Expiry
+		"iat":    time.Now().Unix(),               // "iat" (Issued At)
 	}
 
 	// 2. Create the token object
 	// We sign it using the 'HS256' algorithm and our claims.
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	// 3. Sign the token with our secret key
+	// 3. Sign the token with the newest key, naming it in the "kid" header
 	// This creates the final, secure token string.
-	tokenString, err := token.SignedString(jwtSecretKey)
+	signing := currentKeys()
+	if len(signing) == 0 {
+		return "", errors.New("jwt secret not configured")
+	}
+	if signing[0].ID != "" {
+		token.Header["kid"] = signing[0].ID
+	}
+	tokenString, err := token.SignedString(signing[0].Secret)
 	if err != nil {
 		return "", err
 	}
@@ -38,8 +92,8 @@ func GenerateToken(userID int64) (string, error) {
 }
 
 // ValidateToken parses and validates a JWT token string.
-// It returns the user ID (subject) if the token is valid.
-func ValidateToken(tokenString string) (int64, error) {
+// It returns the token's claims if the token is valid.
+func ValidateToken(tokenString string) (*Claims, error) {
 	// 1. Parse the token string.
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// 2. Check the signing method.
@@ -48,11 +102,29 @@ func ValidateToken(tokenString string) (int64, error) {
 			return nil, errors.New("unexpected signing method")
 		}
 
-		// 3. Return our secret key for validation.
-		return jwtSecretKey, nil
+		// 3. Return the key(s) the token may have been signed with.
+		verifying := currentKeys()
+		if len(verifying) == 0 {
+			return nil, errors.New("jwt secret not configured")
+		}
+		kid, _ := token.Header["kid"].(string)
+		if kid != "" {
+			for _, k := range verifying {
+				if k.ID == kid {
+					return k.Secret, nil
+				}
+			}
+			return nil, errors.New("unknown signing key")
+		}
+		// Tokens from before key IDs: any current key may have signed them.
+		set := jwt.VerificationKeySet{}
+		for _, k := range verifying {
+			set.Keys = append(set.Keys, k.Secret)
+		}
+		return set, nil
 	})
 	if err != nil {
-		return 0, err // Token parsing failed (e.g., expired, malformed)
+		return nil, err // Token parsing failed (e.g., expired, malformed)
 	}
 
 	// 4. Check if the token is valid and get the claims.
@@ -60,11 +132,19 @@ func ValidateToken(tokenString string) (int64, error) {
 		// 5. Get the user ID ("sub") from the claims.
 		userIDFloat, ok := claims["sub"].(float64)
 		if !ok {
-			return 0, errors.New("invalid subject claim")
+			return nil, errors.New("invalid subject claim")
 		}
 		// Convert the float64 (JSON's number type) to int64
-		return int64(userIDFloat), nil
+		c := &Claims{UserID: int64(userIDFloat)}
+
+		// 6. Role, status and issue time (missing on older tokens)
+		c.Role, _ = claims["role"].(string)
+		c.Status, _ = claims["status"].(string)
+		if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+			c.IssuedAt = iat.Time
+		}
+		return c, nil
 	}
 
-	return 0, errors.New("invalid token")
+	return nil, errors.New("invalid token")
 }
@@ -0,0 +1,257 @@
+// Package backup takes a nightly logical dump of the primary database with
+// mysqldump, uploads it gzip-compressed to object storage (see Target) and
+// rotates old dumps. Every run is recorded in the backups table, which
+// GET /v1/admin/backups reports.
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/go-sql-driver/mysql"
+)
+
+// lockName is the MySQL named lock that keeps several API instances from
+// dumping at the same time.
+const lockName = "taptosell_backup"
+
+// Dump names look like taptosell-20261017T030000Z.sql.gz, so they sort by time.
+const (
+	namePrefix = "taptosell-"
+	nameSuffix = ".sql.gz"
+)
+
+// ErrLocked is returned by Run when another instance is already backing up.
+var ErrLocked = errors.New("backup: another backup is running")
+
+// Runner takes backups of one database.
+type Runner struct {
+	DB     *sql.DB // the database to dump, also used for the backups table
+	DSN    *mysql.Config
+	Target Target
+	Keep   int    // dumps kept after rotation
+	Binary string // mysqldump executable
+}
+
+// NewRunner builds a Runner from the primary DSN and the backup settings.
+func NewRunner(ctx context.Context, db *sql.DB, primaryDSN string, cfg config.Backup) (*Runner, error) {
+	dsn, err := mysql.ParseDSN(primaryDSN)
+	if err != nil {
+		return nil, err
+	}
+	target, err := OpenTarget(ctx, cfg.Target)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{DB: db, DSN: dsn, Target: target, Keep: cfg.Keep, Binary: cfg.Mysqldump}, nil
+}
+
+// Run takes one backup and rotates old ones. The outcome is recorded in the
+// backups table; the returned error is the same as the recorded one.
+func (r *Runner) Run(ctx context.Context) error {
+	// 1. --- Single Runner Across Instances ---
+	conn, err := r.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", lockName).Scan(&locked); err != nil {
+		return err
+	}
+	if locked.Int64 != 1 {
+		return ErrLocked
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT RELEASE_LOCK(?)", lockName)
+
+	// 2. --- Record the Run ---
+	started := time.Now().UTC()
+	name := namePrefix + started.Format("20060102T150405Z") + nameSuffix
+	res, err := r.DB.ExecContext(ctx,
+		"INSERT INTO backups (status, target, object_name, started_at) VALUES ('running', ?, ?, ?)",
+		r.Target.String(), name, started)
+	if err != nil {
+		return err
+	}
+	runID, _ := res.LastInsertId()
+
+	// 3. --- Dump & Upload ---
+	size, dumpErr := r.dumpTo(ctx, name)
+
+	// 4. --- Finish the Record (even if ctx was cancelled meanwhile) ---
+	finishCtx := context.WithoutCancel(ctx)
+	if dumpErr != nil {
+		_, err = r.DB.ExecContext(finishCtx,
+			"UPDATE backups SET status = 'failed', error = ?, finished_at = ? WHERE id = ?",
+			dumpErr.Error(), time.Now().UTC(), runID)
+	} else {
+		_, err = r.DB.ExecContext(finishCtx,
+			"UPDATE backups SET status = 'succeeded', size_bytes = ?, finished_at = ? WHERE id = ?",
+			size, time.Now().UTC(), runID)
+	}
+	if err != nil {
+		logging.Errorf("[Backup] Could not record the result of run %d: %v", runID, err)
+	}
+	if dumpErr != nil {
+		return dumpErr
+	}
+
+	// 5. --- Rotate ---
+	if err := r.rotate(ctx); err != nil {
+		// The new dump is safe; an old one lingering is not worth failing the run.
+		logging.Errorf("[Backup] Rotation failed: %v", err)
+	}
+	return nil
+}
+
+// dumpTo streams mysqldump | gzip into the target and returns the compressed size.
+func (r *Runner) dumpTo(ctx context.Context, name string) (int64, error) {
+	cmd := exec.CommandContext(ctx, r.Binary, r.dumpArgs()...)
+	// The password goes through the environment, never the process list.
+	cmd.Env = append(os.Environ(), "MYSQL_PWD="+r.DSN.Passwd)
+	var stderr bytes.Buffer
+	cmd.Stderr = &limitedWriter{w: &stderr, n: 4096}
+
+	pr, pw := io.Pipe()
+	gz := gzip.NewWriter(pw)
+	cmd.Stdout = gz
+
+	go func() {
+		err := cmd.Run()
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+		if msg := strings.TrimSpace(stderr.String()); err != nil && msg != "" {
+			err = fmt.Errorf("mysqldump: %w: %s", err, msg)
+		} else if err != nil {
+			err = fmt.Errorf("mysqldump: %w", err)
+		}
+		pw.CloseWithError(err) // nil closes normally
+	}()
+
+	counter := &countingReader{r: pr}
+	if err := r.Target.Put(ctx, name, counter); err != nil {
+		pr.CloseWithError(err) // stops mysqldump if the upload failed first
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+func (r *Runner) dumpArgs() []string {
+	args := []string{
+		"--single-transaction", // consistent InnoDB snapshot without locking writers
+		"--quick",
+		"--routines",
+		"--triggers",
+		"--no-tablespaces",
+		"--user=" + r.DSN.User,
+	}
+	if r.DSN.Net == "unix" {
+		args = append(args, "--socket="+r.DSN.Addr)
+	} else if host, port, err := net.SplitHostPort(r.DSN.Addr); err == nil {
+		args = append(args, "--host="+host, "--port="+port)
+	} else {
+		args = append(args, "--host="+r.DSN.Addr)
+	}
+	return append(args, r.DSN.DBName)
+}
+
+// rotate deletes all but the newest Keep dumps.
+func (r *Runner) rotate(ctx context.Context) error {
+	names, err := r.Target.List(ctx)
+	if err != nil {
+		return err
+	}
+	var dumps []string
+	for _, n := range names {
+		if strings.HasPrefix(n, namePrefix) && strings.HasSuffix(n, nameSuffix) {
+			dumps = append(dumps, n)
+		}
+	}
+	if len(dumps) <= r.Keep {
+		return nil
+	}
+	sort.Strings(dumps) // oldest first
+	for _, n := range dumps[:len(dumps)-r.Keep] {
+		if err := r.Target.Delete(ctx, n); err != nil {
+			return fmt.Errorf("delete %s: %w", n, err)
+		}
+		logging.Infof("[Backup] Rotated out %s", n)
+	}
+	return nil
+}
+
+// Schedule runs a backup every day at hour (0-23, server local time) until ctx is cancelled.
+func Schedule(ctx context.Context, r *Runner, hour int) {
+	logging.Infof("💾 Backup Job Started: daily at %02d:00 to %s, keeping %d", hour, r.Target, r.Keep)
+	for {
+		next := nextRun(time.Now(), hour)
+		select {
+		case <-ctx.Done():
+			logging.Infof("💾 Backup Job Stopped")
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		start := time.Now()
+		switch err := r.Run(ctx); {
+		case err == nil:
+			logging.Infof("[Backup] Finished in %s", time.Since(start).Round(time.Second))
+		case errors.Is(err, ErrLocked):
+			logging.Infof("[Backup] Skipped: another instance is running it")
+		case ctx.Err() == nil:
+			logging.Errorf("[Backup] Failed: %v", err)
+		}
+	}
+}
+
+// nextRun is the next hour:00 after now.
+func nextRun(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// limitedWriter keeps the first n bytes (enough for mysqldump's error) and drops the rest.
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.n > 0 {
+		keep := p
+		if len(keep) > l.n {
+			keep = keep[:l.n]
+		}
+		l.n -= len(keep)
+		l.w.Write(keep)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,134 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+)
+
+// Target is where dumps are uploaded.
+type Target interface {
+	// Put stores the content of r under name.
+	Put(ctx context.Context, name string, r io.Reader) error
+	// List returns the names of every stored object, in any order.
+	List(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, name string) error
+	// String is the target URL, recorded with every run.
+	String() string
+}
+
+// OpenTarget parses BACKUP_TARGET: file:///var/backups/taptosell for a local
+// (or mounted) directory, or gs://bucket/prefix for Google Cloud Storage using
+// Application Default Credentials.
+func OpenTarget(ctx context.Context, rawURL string) (Target, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("backup: invalid target %q: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("backup: target %q has no directory", rawURL)
+		}
+		return &dirTarget{dir: u.Path}, nil
+	case "gs":
+		svc, err := storage.NewService(ctx, option.WithScopes(storage.DevstorageReadWriteScope))
+		if err != nil {
+			return nil, fmt.Errorf("backup: cloud storage client: %w", err)
+		}
+		prefix := strings.Trim(u.Path, "/")
+		if prefix != "" {
+			prefix += "/"
+		}
+		return &gcsTarget{svc: svc, bucket: u.Host, prefix: prefix}, nil
+	default:
+		return nil, fmt.Errorf("backup: unsupported target scheme %q (use file:// or gs://)", u.Scheme)
+	}
+}
+
+// --- Local Directory ---
+
+type dirTarget struct {
+	dir string
+}
+
+func (t *dirTarget) Put(_ context.Context, name string, r io.Reader) error {
+	if err := os.MkdirAll(t.dir, 0o750); err != nil {
+		return err
+	}
+	// Write under a temporary name so a failed dump never looks complete.
+	tmp, err := os.CreateTemp(t.dir, ".partial-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after the rename
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(t.dir, name))
+}
+
+func (t *dirTarget) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(t.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+func (t *dirTarget) Delete(_ context.Context, name string) error {
+	return os.Remove(filepath.Join(t.dir, name))
+}
+
+func (t *dirTarget) String() string { return "file://" + t.dir }
+
+// --- Google Cloud Storage ---
+
+type gcsTarget struct {
+	svc    *storage.Service
+	bucket string
+	prefix string
+}
+
+func (t *gcsTarget) Put(ctx context.Context, name string, r io.Reader) error {
+	obj := &storage.Object{Name: t.prefix + name, ContentType: "application/gzip"}
+	_, err := t.svc.Objects.Insert(t.bucket, obj).Media(r).Context(ctx).Do()
+	return err
+}
+
+func (t *gcsTarget) List(ctx context.Context) ([]string, error) {
+	var names []string
+	err := t.svc.Objects.List(t.bucket).Prefix(t.prefix).Pages(ctx, func(page *storage.Objects) error {
+		for _, obj := range page.Items {
+			names = append(names, strings.TrimPrefix(obj.Name, t.prefix))
+		}
+		return nil
+	})
+	return names, err
+}
+
+func (t *gcsTarget) Delete(ctx context.Context, name string) error {
+	return t.svc.Objects.Delete(t.bucket, t.prefix+name).Context(ctx).Do()
+}
+
+func (t *gcsTarget) String() string { return "gs://" + t.bucket + "/" + t.prefix }
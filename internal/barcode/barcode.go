@@ -0,0 +1,65 @@
+// Package barcode validates the GS1 barcodes suppliers put on products:
+// EAN-8, UPC-A (12 digits), EAN-13 and GTIN-14. They are all GTINs, and a
+// shorter one is the same item as its zero-padded longer forms (UPC-A
+// 036000291452 is EAN-13 0036000291452), so lookups compare every form.
+package barcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalid is returned by Parse for anything but a GTIN with the right check digit.
+var ErrInvalid = errors.New("barcode must be an EAN-8, UPC-A, EAN-13 or GTIN-14 with a valid check digit")
+
+// lengths are the GTIN lengths, shortest first.
+var lengths = []int{8, 12, 13, 14}
+
+// Parse normalizes a scanned or typed barcode (spaces and hyphens dropped)
+// and checks its length and check digit.
+func Parse(s string) (string, error) {
+	code := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(s))
+	if !validLength(len(code)) || strings.Trim(code, "0") == "" {
+		return "", ErrInvalid
+	}
+	sum := 0
+	for i := len(code) - 1; i >= 0; i-- {
+		d := code[i]
+		if d < '0' || d > '9' {
+			return "", ErrInvalid
+		}
+		// Weights alternate 1, 3, 1, ... from the check digit leftwards.
+		if (len(code)-1-i)%2 == 1 {
+			sum += 3 * int(d-'0')
+		} else {
+			sum += int(d - '0')
+		}
+	}
+	if sum%10 != 0 {
+		return "", ErrInvalid
+	}
+	return code, nil
+}
+
+// Forms lists the GTIN lengths code can be written in: itself, zero-padded
+// to each longer length, and without leading zeros down to the shortest.
+// code must come from Parse.
+func Forms(code string) []string {
+	digits := strings.TrimLeft(code, "0")
+	var forms []string
+	for _, n := range lengths {
+		if len(digits) <= n {
+			forms = append(forms, strings.Repeat("0", n-len(digits))+digits)
+		}
+	}
+	return forms
+}
+
+func validLength(n int) bool {
+	for _, l := range lengths {
+		if n == l {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package barcode
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+		err            bool
+	}{
+		{"EAN-8", "96385074", "96385074", false},
+		{"EAN-8 bad check digit", "96385075", "", true},
+		{"UPC-A", "036000291452", "036000291452", false},
+		{"UPC-A bad check digit", "036000291453", "", true},
+		{"EAN-13", "4006381333931", "4006381333931", false},
+		{"EAN-13 bad check digit", "4006381333932", "", true},
+		{"EAN-13 with leading zero", "0036000291452", "0036000291452", false},
+		{"GTIN-14", "10036000291459", "10036000291459", false},
+		{"spaces and hyphens", " 400-6381 333931 ", "4006381333931", false},
+		{"wrong length", "4006381333", "", true},
+		{"letters", "40063813339A1", "", true},
+		{"all zeros", "00000000", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.err {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("Parse(%q) = %q, %v; want ErrInvalid", tt.in, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Parse(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestForms(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want []string
+	}{
+		{"EAN-8", "96385074", []string{"96385074", "000096385074", "0000096385074", "00000096385074"}},
+		{"UPC-A is its zero-padded EAN-13", "036000291452", []string{"036000291452", "0036000291452", "00036000291452"}},
+		{"EAN-13 with leading zero is its UPC-A", "0036000291452", []string{"036000291452", "0036000291452", "00036000291452"}},
+		{"EAN-13", "4006381333931", []string{"4006381333931", "04006381333931"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Forms(tt.code); !slices.Equal(got, tt.want) {
+				t.Fatalf("Forms(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
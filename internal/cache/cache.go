@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Cache is the small key/value API the handlers use for hot reads.
+// Values are stored as JSON, so any JSON-serializable struct can be cached.
+type Cache interface {
+	// Get loads the value stored at key into dest. It returns false on a miss.
+	Get(ctx context.Context, key string, dest interface{}) (bool, error)
+	// Set stores value at key for ttl (0 = no expiry).
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Delete removes the given keys. Missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+	// Close releases connections; call it once on shutdown.
+	Close() error
+}
+
+// --- Cache Keys ---
+// Every write handler that changes one of these must delete the matching key.
+const (
+	KeyCategoryTree       = "taxonomy:categories"
+	KeyBrandList          = "taxonomy:brands"
+	KeySettings           = "settings:all"
+	KeyMaintenanceWindows = "status:maintenance"
+)
+
+// ProductKey is the key for a single product's detail payload.
+func ProductKey(productID int64) string {
+	return fmt.Sprintf("product:%d", productID)
+}
+
+// New returns a Redis-backed cache when redisURL (REDIS_URL) is set and reachable,
+// otherwise an in-process memory cache (fine for a single API instance).
+func New(redisURL string) Cache {
+	if redisURL == "" {
+		log.Println("REDIS_URL not set, using in-memory cache")
+		return NewMemory()
+	}
+
+	rc, err := NewRedis(redisURL)
+	if err != nil {
+		log.Printf("Redis unavailable (%v), falling back to in-memory cache", err)
+		return NewMemory()
+	}
+	log.Println("Redis cache connected successfully")
+	return rc
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time // zero = never expires
+}
+
+// Memory is an in-process Cache. Entries are JSON-encoded so callers get
+// copies, exactly like they would from Redis.
+type Memory struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+}
+
+// NewMemory creates an empty in-memory cache.
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]memoryEntry)}
+}
+
+func (m *Memory) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	m.mu.RLock()
+	entry, ok := m.entries[key]
+	m.mu.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		m.mu.Lock()
+		delete(m.entries, key)
+		m.mu.Unlock()
+		return false, nil
+	}
+	if err := json.Unmarshal(entry.data, dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *Memory) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	entry := memoryEntry{data: data}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	m.entries[key] = entry
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// Close is a no-op; the entries are simply garbage collected.
+func (m *Memory) Close() error {
+	return nil
+}
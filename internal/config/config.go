@@ -0,0 +1,1115 @@
+// Package config loads every setting the API needs from the environment into
+// a typed struct at startup. Load validates everything in one pass and reports
+// all missing or malformed variables together, so a bad deploy fails fast with
+// one clear message instead of at the first request that needs a value.
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/secrets"
+	"github.com/01moynul/taptosell-golang/internal/shipping"
+	"github.com/01moynul/taptosell-golang/internal/sku"
+)
+
+// Config is the complete application configuration.
+type Config struct {
+	Env           string // APP_ENV: "development" (default) or "production"
+	LogLevel      string // LOG_LEVEL: debug, info (default), warn or error; changeable at runtime
+	HTTP          HTTP
+	DB            DB
+	Auth          Auth
+	AI            AI
+	Cache         Cache
+	Storage       Storage
+	Images        Images
+	Retention     Retention
+	Jobs          Jobs
+	Exports       Exports
+	Tracing       Tracing
+	Errors        ErrorReporting
+	Backup        Backup
+	Audit         Audit
+	PII           PII
+	Captcha       Captcha
+	Disputes      Disputes
+	Referrals     Referrals
+	Preorders     Preorders
+	UnpaidOrders  UnpaidOrders
+	Risk          Risk
+	Vacations     Vacations
+	I18n          I18n
+	Products      Products
+	Shipping      Shipping
+	Tax           Tax
+	Wallet        Wallet
+	Notifications Notifications
+	Webhooks      Webhooks
+}
+
+// HTTP holds the web server settings.
+type HTTP struct {
+	Port    string // PORT (default 8080)
+	BaseURL string // BASE_URL, public URL used to build upload links (default http://localhost:PORT)
+
+	// CORSOrigins are the browser origins allowed to call the API with credentials
+	// (CORS_ALLOWED_ORIGINS, comma-separated). An entry may use a wildcard subdomain,
+	// as in https://*.taptosell.com. Required in production; development defaults
+	// to the Vite dev server. The older single-origin CORS_ALLOWED_ORIGIN is still read.
+	CORSOrigins []string
+
+	// LegacyNumericIDs keeps accepting numeric IDs in /products/:id, /orders/:id and
+	// /users/:id next to public UUIDs (LEGACY_NUMERIC_IDS, default true). Turn it off
+	// once every client uses publicId.
+	LegacyNumericIDs bool
+
+	// GraphQL serves POST /v1/graphql, the storefront's one-request reads of
+	// products, categories, cart and orders (GRAPHQL_ENABLED, default false).
+	GraphQL bool
+
+	// BatchMaxRequests caps the sub-requests of one POST /v1/batch
+	// (HTTP_BATCH_MAX_REQUESTS, default 10).
+	BatchMaxRequests int
+
+	// PublicCacheMaxAge and PublicCacheStale are the Cache-Control of the public
+	// storefront reads under /v1/public (PUBLIC_CACHE_MAX_AGE, default 60s, and
+	// PUBLIC_CACHE_STALE, the stale-while-revalidate window, default 5m). A CDN
+	// in front of the API serves them without reaching it within max-age.
+	PublicCacheMaxAge time.Duration
+	PublicCacheStale  time.Duration
+
+	// V1DeprecatedAt and V1Sunset schedule the end of /v1 (API_V1_DEPRECATED_AT
+	// and API_V1_SUNSET, dates like 2027-06-30; unset by default). Once deprecated,
+	// /v1 responses carry Deprecation, Sunset and a Link to /v2; the routes keep
+	// working until they are removed in a release after the sunset date.
+	V1DeprecatedAt time.Time
+	V1Sunset       time.Time
+
+	ReadHeaderTimeout time.Duration // HTTP_READ_HEADER_TIMEOUT (default 5s)
+	ReadTimeout       time.Duration // HTTP_READ_TIMEOUT, whole request incl. uploads (default 30s)
+	WriteTimeout      time.Duration // HTTP_WRITE_TIMEOUT (default 30s)
+	IdleTimeout       time.Duration // HTTP_IDLE_TIMEOUT, keep-alive connections (default 120s)
+	MaxHeaderBytes    int           // HTTP_MAX_HEADER_BYTES (default 1 MiB)
+	MaxJSONBytes      int64         // HTTP_MAX_JSON_BYTES, non-upload request bodies (default 1 MiB)
+	HSTS              bool          // HTTP_HSTS, send Strict-Transport-Security (default true in production)
+	ShutdownTimeout   time.Duration // HTTP_SHUTDOWN_TIMEOUT, drain budget on SIGINT/SIGTERM (default 20s)
+}
+
+// DB holds the connection settings of every pool.
+type DB struct {
+	PrimaryDSN  string // DB_DSN_PRIMARY (required)
+	ReplicaDSN  string // DB_DSN_REPLICA (optional; reads go to the primary without it)
+	ReadOnlyDSN string // DB_DSN_READONLY (required; used by the AI SQL tool)
+
+	Pool         PoolConfig // DB_MAX_OPEN_CONNS, ...
+	ReplicaPool  PoolConfig // DB_REPLICA_MAX_OPEN_CONNS, ...
+	ReadOnlyPool PoolConfig // DB_READONLY_MAX_OPEN_CONNS, ...
+
+	SlowQueryThreshold time.Duration // DB_SLOW_QUERY_THRESHOLD (default 200ms; 0 disables)
+	AutoMigrate        bool          // DB_AUTO_MIGRATE: apply pending migrations on boot
+}
+
+// PoolConfig holds the connection pool settings applied to a *sql.DB.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// DefaultPoolConfig matches the values the API has always run with.
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    25,
+		MaxIdleConns:    25,
+		ConnMaxLifetime: 5 * time.Minute,
+		ConnMaxIdleTime: 0, // 0 = idle connections are not closed based on idle time
+	}
+}
+
+// Auth holds the token settings.
+type Auth struct {
+	// JWTKeys sign and verify tokens. The first one signs; the others are still
+	// accepted, so tokens issued before a rotation stay valid until they expire.
+	// Read from JWT_KEYS ("kid:secret,kid:secret", newest first) or, without it,
+	// from JWT_SECRET as a single key without an ID. Secrets need 32+ characters.
+	JWTKeys   []SigningKey
+	JWTSecret string        // secret of the signing key; document URL signatures derive from it
+	TokenTTL  time.Duration // JWT_TTL (default 72h)
+
+	// RevocationCheck reads the user's row on every authenticated request
+	// (AUTH_REVOCATION_CHECK, default false). Off, each read is cached for
+	// UserCacheTTL, so a deleted or suspended user or a revoked session is
+	// refused within that time; on, straight away.
+	RevocationCheck bool
+	// UserCacheTTL is how long a user's role, status and revocation time are
+	// cached by the auth middleware (AUTH_USER_CACHE_TTL, default 30s).
+	UserCacheTTL time.Duration
+
+	// SecretsRefresh is how often the JWT keys are re-read from the secrets
+	// provider, so a rotation needs no restart (SECRETS_REFRESH_INTERVAL,
+	// default 0: only at startup). DSNs and API keys are read at startup only.
+	SecretsRefresh time.Duration
+}
+
+// SigningKey is one JWT signing secret and the key ID ("kid") its tokens carry.
+type SigningKey struct {
+	ID     string
+	Secret string
+}
+
+// AI holds the Gemini settings, the daily chat caps of users without an
+// active subscription (plans set their own; 0 is no cap), and what
+// moderation violations cost an account within a day.
+type AI struct {
+	GeminiAPIKey      string // GEMINI_API_KEY (required)
+	FreeDailyRequests int    // AI_FREE_DAILY_REQUESTS (default 20)
+	FreeDailyTokens   int    // AI_FREE_DAILY_TOKENS (default 20000)
+
+	ThrottleAfter     int           // AI_THROTTLE_AFTER, violations before the daily cap drops (default 3)
+	ThrottledRequests int           // AI_THROTTLED_DAILY_REQUESTS, the dropped cap (default 5)
+	SuspendAfter      int           // AI_SUSPEND_AFTER, violations before AI access is suspended (default 6)
+	Suspension        time.Duration // AI_SUSPENSION (default 168h)
+
+	// BlockedTopics are blocked beyond moderation.DefaultTopics
+	// (AI_BLOCKED_TOPICS, comma-separated).
+	BlockedTopics []string
+}
+
+// Cache holds the hot-read cache settings.
+type Cache struct {
+	RedisURL string // REDIS_URL (optional; in-memory cache without it)
+}
+
+// Storage holds where uploaded files are written and how uploads are checked.
+type Storage struct {
+	UploadDir string // UPLOAD_DIR, public catalogue images served at /uploads (default ./uploads)
+
+	// DocumentDir holds private supplier documents, served only through signed
+	// URLs (DOCUMENT_DIR, default ./documents). Must not be inside UPLOAD_DIR.
+	// Documents uploaded before it existed must be moved here by hand.
+	DocumentDir    string
+	DocumentURLTTL time.Duration // DOCUMENT_URL_TTL, lifetime of a signed document link (default 15m)
+
+	MaxFileBytes    int64  // UPLOAD_MAX_FILE_BYTES (default 10 MiB)
+	MaxRequestBytes int64  // UPLOAD_MAX_REQUEST_BYTES, whole multipart body (default 25 MiB)
+	ScanCommand     string // UPLOAD_SCAN_COMMAND, virus scanner run per file, e.g. "clamdscan --no-summary" (default: none)
+
+	// ImageBackend is where catalogue images are kept (IMAGE_STORAGE: local,
+	// the default, or s3). Documents always stay in DOCUMENT_DIR.
+	ImageBackend string
+	S3           S3
+}
+
+// Images holds the product photo pipeline (POST /v1/supplier/images/process).
+type Images struct {
+	Size int // IMAGE_SIZE, width and height of a processed photo in pixels (default 1000)
+
+	// BackgroundAPIURL is a remove.bg-style background-removal API
+	// (IMAGE_BACKGROUND_API_URL, default none: plain backdrops are cleared
+	// locally, within BackgroundTolerance per colour channel).
+	BackgroundAPIURL    string
+	BackgroundAPIKey    string // IMAGE_BACKGROUND_API_KEY (secret)
+	BackgroundTolerance int    // IMAGE_BACKGROUND_TOLERANCE, 0-255 (default 24)
+
+	Watermark string // IMAGE_WATERMARK, path to a PNG drawn on request (default none)
+}
+
+// S3 is an S3-compatible bucket (AWS S3, Cloudflare R2, MinIO, ...) for
+// catalogue images, addressed path-style.
+type S3 struct {
+	Endpoint        string // S3_ENDPOINT, e.g. https://s3.ap-southeast-1.amazonaws.com (required for s3)
+	Region          string // S3_REGION (default us-east-1; R2 uses auto)
+	Bucket          string // S3_BUCKET (required for s3)
+	Prefix          string // S3_PREFIX, key prefix such as products/ (default none)
+	AccessKeyID     string // S3_ACCESS_KEY_ID (required for s3)
+	SecretAccessKey string // S3_SECRET_ACCESS_KEY (secret; required for s3)
+
+	// PublicURL is the base URL objects are served from, such as a CDN in front
+	// of the bucket (S3_PUBLIC_URL; default ENDPOINT/BUCKET).
+	PublicURL string
+}
+
+// Retention holds how long soft-deleted rows are kept before the retention
+// job hard-deletes them. 0 keeps them forever.
+type Retention struct {
+	Interval       time.Duration // RETENTION_INTERVAL, how often the job runs (default 24h)
+	Users          time.Duration // RETENTION_USERS (default 0: accounts back financial records)
+	Products       time.Duration // RETENTION_PRODUCTS (default 2160h = 90 days)
+	InventoryItems time.Duration // RETENTION_INVENTORY_ITEMS (default 2160h = 90 days)
+	Orders         time.Duration // RETENTION_ORDERS (default 0)
+}
+
+// Jobs sizes the in-process background job queue (async event subscribers).
+type Jobs struct {
+	Workers   int // JOBS_WORKERS (default 4)
+	QueueSize int // JOBS_QUEUE_SIZE, buffered jobs before Enqueue reports full (default 1000)
+}
+
+// Exports configures the asynchronous CSV exports (POST /v1/exports). They run
+// on their own worker pool, so a long export never delays event subscribers.
+type Exports struct {
+	Dir       string        // EXPORT_DIR, finished files, private like DOCUMENT_DIR (default ./exports)
+	Workers   int           // EXPORT_WORKERS, exports generated at once (default 2)
+	MaxActive int           // EXPORT_MAX_ACTIVE, queued or running exports per user (default 3)
+	URLTTL    time.Duration // EXPORT_URL_TTL, lifetime of a signed download link (default 15m)
+	Retention time.Duration // EXPORT_RETENTION, how long a finished file is kept (default 24h)
+}
+
+// Tracing holds the OpenTelemetry exporter settings.
+type Tracing struct {
+	Endpoint    string  // OTEL_EXPORTER_OTLP_ENDPOINT, e.g. http://tempo:4318 (optional; tracing is off without it)
+	ServiceName string  // OTEL_SERVICE_NAME (default taptosell-api)
+	SampleRatio float64 // OTEL_TRACES_SAMPLE_RATIO, share of new traces kept (default 1)
+}
+
+// ErrorReporting holds where panics and 5xx responses are reported.
+type ErrorReporting struct {
+	SentryDSN string // SENTRY_DSN (optional; reports only go to the log without it)
+	Release   string // APP_RELEASE, e.g. a git SHA, to tie errors to a deploy
+
+	// LogCapacity is how many recent errors the app_errors table keeps for
+	// GET /v1/admin/errors (ERROR_LOG_CAPACITY, default 5000; 0 disables it).
+	LogCapacity int
+}
+
+// Backup holds the nightly database dump settings.
+type Backup struct {
+	Target    string // BACKUP_TARGET, file:///dir or gs://bucket/prefix (optional; no backups without it)
+	Hour      int    // BACKUP_HOUR, local hour of the daily run (default 3)
+	Keep      int    // BACKUP_KEEP, dumps kept by rotation (default 14)
+	Mysqldump string // BACKUP_MYSQLDUMP, path of the mysqldump binary (default mysqldump)
+}
+
+// Audit holds the webhook and payment request capture settings.
+type Audit struct {
+	CaptureCapacity int // AUDIT_CAPTURE_CAPACITY, captures kept in request_captures (default 10000)
+}
+
+// Disputes holds the dispute deadlines.
+type Disputes struct {
+	// ResponseWindow is how long a supplier has to answer a dispute before it
+	// resolves to a full refund (DISPUTE_RESPONSE_WINDOW, default 72h).
+	ResponseWindow time.Duration
+	// OpenWindow is how long after an order's last update a dispute may still be
+	// opened (DISPUTE_OPEN_WINDOW, default 720h = 30 days).
+	OpenWindow    time.Duration
+	CheckInterval time.Duration // DISPUTE_CHECK_INTERVAL, how often deadlines are enforced (default 15m)
+}
+
+// Preorders holds the pre-order conversion job. A restock converts the
+// pre-orders waiting for that product at once; the job catches the rest.
+type Preorders struct {
+	CheckInterval time.Duration // PREORDER_CHECK_INTERVAL, how often waiting pre-orders are re-checked (default 10m)
+}
+
+// UnpaidOrders holds the job that cancels on-hold orders left unpaid, with a
+// penalty strike, giving back their stock and wallet hold. The dropshipper is
+// reminded 24 hours before.
+type UnpaidOrders struct {
+	Days          int           // UNPAID_ORDER_DAYS, how long an on-hold order may stay unpaid (default 1)
+	CheckInterval time.Duration // UNPAID_ORDER_CHECK_INTERVAL, how often unpaid orders are checked (default 10m)
+}
+
+// Risk holds the fraud checks at checkout and wallet top-up (see package
+// risk). Operations that score ReviewScore or more wait in the manager review
+// queue instead of going through.
+type Risk struct {
+	MaxOrdersPerHour int // RISK_MAX_ORDERS_PER_HOUR, a dropshipper's orders in an hour before the next is flagged (default 10)
+	MaxFailedTopups  int // RISK_MAX_FAILED_TOPUPS, failed top-ups in 24 hours before the next is flagged (default 3)
+	ReviewScore      int // RISK_REVIEW_SCORE, the score that sends an operation to review (default 50)
+
+	// DisposableDomains are throwaway email domains beyond the built-in list
+	// (RISK_DISPOSABLE_DOMAINS, comma-separated).
+	DisposableDomains []string
+}
+
+// Vacations holds the supplier vacation job. Listings come back at the end
+// date by themselves; the job clears the setting and notifies the supplier.
+type Vacations struct {
+	CheckInterval time.Duration // VACATION_CHECK_INTERVAL, how often ended vacations are cleared (default 15m)
+}
+
+// Products holds catalogue settings.
+type Products struct {
+	// SKUPattern builds the SKU of a product or variant saved without one
+	// (SKU_PATTERN, default SUP{supplierID}-{category}-{seq}; see package sku).
+	SKUPattern sku.Pattern
+
+	// MinMargin is the least a product's SRP must leave dropshippers over its
+	// price, in percent of the SRP (PRODUCT_MIN_MARGIN, default 0: the SRP
+	// only has to cover the price; see package pricing).
+	MinMargin float64
+
+	// DuplicateInterval is how often the catalogue is scanned for the same
+	// item listed by several suppliers (DUPLICATE_CHECK_INTERVAL, default 6h).
+	DuplicateInterval time.Duration
+	// DuplicateSimilarity is how alike two titles must be to flag the
+	// listings, 0 to 1 (DUPLICATE_TITLE_SIMILARITY, default 0.8; see package
+	// dedupe). A shared barcode or image is flagged regardless.
+	DuplicateSimilarity float64
+}
+
+// Shipping holds the couriers checkout and shipping check product restrictions
+// against (SHIPPING_COURIERS, e.g. "jnt:battery+liquid,poslaju"; see package
+// shipping), and the processing time behind "ships by" dates. Without
+// couriers none is excluded.
+type Shipping struct {
+	Couriers []shipping.Courier
+
+	// HandlingDays is the processing time of suppliers who set none
+	// (SHIPPING_HANDLING_DAYS, business days, default 2).
+	HandlingDays int
+	// Timezone decides calendar days and cutoffs (SHIPPING_TIMEZONE, default Asia/Kuala_Lumpur).
+	Timezone          *time.Location
+	LateCheckInterval time.Duration // SHIPPING_LATE_CHECK_INTERVAL, how often late shipments are flagged (default 30m)
+}
+
+// Tax holds the SST applied at checkout. Managers set per-category rates; the
+// default covers products in no rated category.
+type Tax struct {
+	DefaultRate float64 // SST_DEFAULT_RATE, percent (default 0: untaxed)
+}
+
+// Wallet holds the ledger reconciliation job, which checks every balance_after
+// against the exact running sum of the user's transactions.
+type Wallet struct {
+	ReconcileInterval time.Duration // WALLET_RECONCILE_INTERVAL, how often the ledger is checked (default 1h)
+}
+
+// Notifications holds notification batching: notifications of one kind (e.g.
+// product approvals) arriving within the window collapse into one summary.
+type Notifications struct {
+	BatchWindow time.Duration // NOTIFICATION_BATCH_WINDOW, since the last one of a kind (default 10m; 0 turns batching off)
+
+	// Low-stock alerts are in-app notifications; suppliers can also get one
+	// email a day listing them.
+	LowStockDigest     bool // LOW_STOCK_DIGEST, send the daily low-stock email (default false)
+	LowStockDigestHour int  // LOW_STOCK_DIGEST_HOUR, local hour of the email (default 8)
+
+	// WatchlistInterval is how often watched products are checked for price
+	// changes and restocks (WATCHLIST_CHECK_INTERVAL, default 5m).
+	WatchlistInterval time.Duration
+}
+
+// Webhooks configures outbound webhooks (POST /v1/webhooks). Outside
+// production they may also target http:// and private addresses, for local
+// receivers.
+type Webhooks struct {
+	DispatchInterval time.Duration // WEBHOOK_DISPATCH_INTERVAL, how often new events are fanned out and sent (default 5s)
+	MaxEndpoints     int           // WEBHOOK_MAX_ENDPOINTS, endpoints per user (default 5)
+}
+
+// I18n holds the message catalogs. The English and Malay catalogs are built
+// in; files in Dir add languages or override entries.
+type I18n struct {
+	Dir string // I18N_DIR, directory of <lang>.json catalogs (optional)
+}
+
+// Referrals holds the referral rewards, paid as wallet promo credit when a
+// referred dropshipper completes their first qualifying order.
+type Referrals struct {
+	ReferrerReward money.Money // REFERRAL_REWARD, RM to the referrer (default 10; 0 turns rewards off)
+	RefereeReward  money.Money // REFERRAL_REFEREE_REWARD, RM to the new dropshipper (default 0)
+	MinOrderTotal  money.Money // REFERRAL_MIN_ORDER_TOTAL, smallest order that qualifies (default 30)
+	MonthlyCap     int         // REFERRAL_MONTHLY_CAP, rewards per referrer per calendar month (default 20)
+}
+
+// Captcha holds the bot check for registration, login and resend-code. The
+// 'captcha_enabled' setting turns it on; without a provider it stays off.
+type Captcha struct {
+	Provider string // CAPTCHA_PROVIDER: turnstile or hcaptcha (default unset: off)
+	SiteKey  string // CAPTCHA_SITE_KEY, public key the frontend renders the widget with
+	Secret   string // CAPTCHA_SECRET (required with a provider)
+}
+
+// PII holds the keys that encrypt sensitive columns (see package pii).
+type PII struct {
+	// Keys are read from PII_KEYS ("kid:base64key,...", 32-byte AES keys, newest
+	// first). Required in production; without it development stores plaintext.
+	Keys []EncryptionKey
+}
+
+// EncryptionKey is one AES-256 key and the ID stored with what it encrypts.
+type EncryptionKey struct {
+	ID  string
+	Key []byte
+}
+
+// IsProduction reports whether APP_ENV is "production".
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// Load reads and validates the full configuration. Secrets come from the
+// provider selected by SECRETS_PROVIDER (see package secrets).
+func Load() (*Config, error) {
+	l := newLoader()
+	cfg := &Config{
+		Env:      l.oneOf("APP_ENV", "development", "development", "production"),
+		LogLevel: l.oneOf("LOG_LEVEL", "info", "debug", "info", "warn", "error"),
+		DB:       l.db(),
+		Auth: Auth{
+			JWTKeys:         l.jwtKeys(),
+			TokenTTL:        l.duration("JWT_TTL", 72*time.Hour),
+			RevocationCheck: l.boolean("AUTH_REVOCATION_CHECK", false),
+			UserCacheTTL:    l.duration("AUTH_USER_CACHE_TTL", 30*time.Second),
+			SecretsRefresh:  l.duration("SECRETS_REFRESH_INTERVAL", 0),
+		},
+		AI: AI{
+			GeminiAPIKey:      l.requiredSecret("GEMINI_API_KEY"),
+			FreeDailyRequests: l.integer("AI_FREE_DAILY_REQUESTS", 20, 0),
+			FreeDailyTokens:   l.integer("AI_FREE_DAILY_TOKENS", 20000, 0),
+			ThrottleAfter:     l.integer("AI_THROTTLE_AFTER", 3, 1),
+			ThrottledRequests: l.integer("AI_THROTTLED_DAILY_REQUESTS", 5, 1),
+			SuspendAfter:      l.integer("AI_SUSPEND_AFTER", 6, 1),
+			Suspension:        l.duration("AI_SUSPENSION", 7*24*time.Hour),
+		},
+		Cache: Cache{
+			RedisURL: l.secret("REDIS_URL"),
+		},
+		Storage: Storage{
+			UploadDir:       l.optional("UPLOAD_DIR", "./uploads"),
+			DocumentDir:     l.optional("DOCUMENT_DIR", "./documents"),
+			DocumentURLTTL:  l.duration("DOCUMENT_URL_TTL", 15*time.Minute),
+			MaxFileBytes:    int64(l.integer("UPLOAD_MAX_FILE_BYTES", 10<<20, 1)),
+			MaxRequestBytes: int64(l.integer("UPLOAD_MAX_REQUEST_BYTES", 25<<20, 1)),
+			ScanCommand:     l.optional("UPLOAD_SCAN_COMMAND", ""),
+			ImageBackend:    l.oneOf("IMAGE_STORAGE", "local", "local", "s3"),
+		},
+		Images: Images{
+			Size:                l.integer("IMAGE_SIZE", 1000, 100),
+			BackgroundAPIURL:    l.optional("IMAGE_BACKGROUND_API_URL", ""),
+			BackgroundAPIKey:    l.secret("IMAGE_BACKGROUND_API_KEY"),
+			BackgroundTolerance: l.integer("IMAGE_BACKGROUND_TOLERANCE", 24, 0),
+			Watermark:           l.optional("IMAGE_WATERMARK", ""),
+		},
+		Retention: Retention{
+			Interval:       l.duration("RETENTION_INTERVAL", 24*time.Hour),
+			Users:          l.duration("RETENTION_USERS", 0),
+			Products:       l.duration("RETENTION_PRODUCTS", 90*24*time.Hour),
+			InventoryItems: l.duration("RETENTION_INVENTORY_ITEMS", 90*24*time.Hour),
+			Orders:         l.duration("RETENTION_ORDERS", 0),
+		},
+		Jobs: Jobs{
+			Workers:   l.integer("JOBS_WORKERS", 4, 1),
+			QueueSize: l.integer("JOBS_QUEUE_SIZE", 1000, 1),
+		},
+		Exports: Exports{
+			Dir:       l.optional("EXPORT_DIR", "./exports"),
+			Workers:   l.integer("EXPORT_WORKERS", 2, 1),
+			MaxActive: l.integer("EXPORT_MAX_ACTIVE", 3, 1),
+			URLTTL:    l.duration("EXPORT_URL_TTL", 15*time.Minute),
+			Retention: l.duration("EXPORT_RETENTION", 24*time.Hour),
+		},
+		Tracing: Tracing{
+			Endpoint:    l.optional("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			ServiceName: l.optional("OTEL_SERVICE_NAME", "taptosell-api"),
+			SampleRatio: l.ratio("OTEL_TRACES_SAMPLE_RATIO", 1),
+		},
+		Errors: ErrorReporting{
+			SentryDSN:   l.secret("SENTRY_DSN"),
+			Release:     l.optional("APP_RELEASE", ""),
+			LogCapacity: l.integer("ERROR_LOG_CAPACITY", 5000, 0),
+		},
+		Backup: Backup{
+			Target:    l.optional("BACKUP_TARGET", ""),
+			Hour:      l.integer("BACKUP_HOUR", 3, 0),
+			Keep:      l.integer("BACKUP_KEEP", 14, 1),
+			Mysqldump: l.optional("BACKUP_MYSQLDUMP", "mysqldump"),
+		},
+		Audit: Audit{
+			CaptureCapacity: l.integer("AUDIT_CAPTURE_CAPACITY", 10000, 1),
+		},
+		Disputes: Disputes{
+			ResponseWindow: l.duration("DISPUTE_RESPONSE_WINDOW", 72*time.Hour),
+			OpenWindow:     l.duration("DISPUTE_OPEN_WINDOW", 30*24*time.Hour),
+			CheckInterval:  l.duration("DISPUTE_CHECK_INTERVAL", 15*time.Minute),
+		},
+		Preorders: Preorders{
+			CheckInterval: l.duration("PREORDER_CHECK_INTERVAL", 10*time.Minute),
+		},
+		UnpaidOrders: UnpaidOrders{
+			Days:          l.integer("UNPAID_ORDER_DAYS", 1, 1),
+			CheckInterval: l.duration("UNPAID_ORDER_CHECK_INTERVAL", 10*time.Minute),
+		},
+		Risk: Risk{
+			MaxOrdersPerHour: l.integer("RISK_MAX_ORDERS_PER_HOUR", 10, 1),
+			MaxFailedTopups:  l.integer("RISK_MAX_FAILED_TOPUPS", 3, 1),
+			ReviewScore:      l.integer("RISK_REVIEW_SCORE", 50, 1),
+		},
+		Vacations: Vacations{
+			CheckInterval: l.duration("VACATION_CHECK_INTERVAL", 15*time.Minute),
+		},
+		I18n: I18n{
+			Dir: l.optional("I18N_DIR", ""),
+		},
+		Referrals: Referrals{
+			ReferrerReward: l.money("REFERRAL_REWARD", 10*money.Ringgit),
+			RefereeReward:  l.money("REFERRAL_REFEREE_REWARD", 0),
+			MinOrderTotal:  l.money("REFERRAL_MIN_ORDER_TOTAL", 30*money.Ringgit),
+			MonthlyCap:     l.integer("REFERRAL_MONTHLY_CAP", 20, 1),
+		},
+		Tax: Tax{
+			DefaultRate: l.percent("SST_DEFAULT_RATE", 0),
+		},
+		Wallet: Wallet{
+			ReconcileInterval: l.duration("WALLET_RECONCILE_INTERVAL", time.Hour),
+		},
+		Notifications: Notifications{
+			BatchWindow:        l.duration("NOTIFICATION_BATCH_WINDOW", 10*time.Minute),
+			LowStockDigest:     l.boolean("LOW_STOCK_DIGEST", false),
+			LowStockDigestHour: l.integer("LOW_STOCK_DIGEST_HOUR", 8, 0),
+			WatchlistInterval:  l.duration("WATCHLIST_CHECK_INTERVAL", 5*time.Minute),
+		},
+		Webhooks: Webhooks{
+			DispatchInterval: l.duration("WEBHOOK_DISPATCH_INTERVAL", 5*time.Second),
+			MaxEndpoints:     l.integer("WEBHOOK_MAX_ENDPOINTS", 5, 1),
+		},
+		Captcha: Captcha{
+			Provider: l.optional("CAPTCHA_PROVIDER", ""),
+			SiteKey:  l.optional("CAPTCHA_SITE_KEY", ""),
+			Secret:   l.secret("CAPTCHA_SECRET"),
+		},
+	}
+
+	port := l.optional("PORT", "8080")
+
+	skuPattern := l.optional("SKU_PATTERN", sku.DefaultPattern)
+	if p, err := sku.Parse(skuPattern); err != nil {
+		l.invalid("SKU_PATTERN", skuPattern, err.Error())
+	} else {
+		cfg.Products.SKUPattern = p
+	}
+	cfg.Products.MinMargin = l.percent("PRODUCT_MIN_MARGIN", 0)
+	cfg.Products.DuplicateInterval = l.duration("DUPLICATE_CHECK_INTERVAL", 6*time.Hour)
+	cfg.Products.DuplicateSimilarity = l.ratio("DUPLICATE_TITLE_SIMILARITY", 0.8)
+	if cfg.Products.DuplicateSimilarity == 0 {
+		l.invalid("DUPLICATE_TITLE_SIMILARITY", "0", "must be above 0")
+	}
+
+	cfg.Shipping.HandlingDays = l.integer("SHIPPING_HANDLING_DAYS", 2, 0)
+	cfg.Shipping.LateCheckInterval = l.duration("SHIPPING_LATE_CHECK_INTERVAL", 30*time.Minute)
+	timezone := l.optional("SHIPPING_TIMEZONE", "Asia/Kuala_Lumpur")
+	if loc, err := time.LoadLocation(timezone); err != nil {
+		l.invalid("SHIPPING_TIMEZONE", timezone, "must be an IANA time zone like Asia/Kuala_Lumpur")
+	} else {
+		cfg.Shipping.Timezone = loc
+	}
+
+	couriers := l.optional("SHIPPING_COURIERS", "")
+	if list, err := shipping.ParseCouriers(couriers); err != nil {
+		l.invalid("SHIPPING_COURIERS", couriers, err.Error())
+	} else {
+		cfg.Shipping.Couriers = list
+	}
+	for _, domain := range strings.Split(l.optional("RISK_DISPOSABLE_DOMAINS", ""), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			cfg.Risk.DisposableDomains = append(cfg.Risk.DisposableDomains, domain)
+		}
+	}
+	for _, topic := range strings.Split(l.optional("AI_BLOCKED_TOPICS", ""), ",") {
+		if topic = strings.ToLower(strings.TrimSpace(topic)); topic != "" {
+			cfg.AI.BlockedTopics = append(cfg.AI.BlockedTopics, topic)
+		}
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		l.invalid("PORT", port, "must be a number")
+	}
+	cfg.HTTP = HTTP{
+		Port:        port,
+		BaseURL:     strings.TrimSuffix(l.optional("BASE_URL", "http://localhost:"+port), "/"),
+		CORSOrigins: l.origins("CORS_ALLOWED_ORIGINS", cfg.IsProduction()),
+
+		LegacyNumericIDs: l.boolean("LEGACY_NUMERIC_IDS", true),
+		GraphQL:          l.boolean("GRAPHQL_ENABLED", false),
+		BatchMaxRequests: l.integer("HTTP_BATCH_MAX_REQUESTS", 10, 1),
+		V1DeprecatedAt:   l.date("API_V1_DEPRECATED_AT"),
+		V1Sunset:         l.date("API_V1_SUNSET"),
+
+		PublicCacheMaxAge: l.duration("PUBLIC_CACHE_MAX_AGE", time.Minute),
+		PublicCacheStale:  l.duration("PUBLIC_CACHE_STALE", 5*time.Minute),
+
+		ReadHeaderTimeout: l.duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       l.duration("HTTP_READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:      l.duration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       l.duration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		MaxHeaderBytes:    l.integer("HTTP_MAX_HEADER_BYTES", 1<<20, 4096),
+		MaxJSONBytes:      int64(l.integer("HTTP_MAX_JSON_BYTES", 1<<20, 1024)),
+		HSTS:              l.boolean("HTTP_HSTS", cfg.IsProduction()),
+		ShutdownTimeout:   l.duration("HTTP_SHUTDOWN_TIMEOUT", 20*time.Second),
+	}
+
+	if !cfg.HTTP.V1Sunset.IsZero() && cfg.HTTP.V1DeprecatedAt.IsZero() {
+		l.invalid("API_V1_SUNSET", cfg.HTTP.V1Sunset.Format("2006-01-02"), "needs API_V1_DEPRECATED_AT")
+	} else if !cfg.HTTP.V1Sunset.IsZero() && !cfg.HTTP.V1Sunset.After(cfg.HTTP.V1DeprecatedAt) {
+		l.invalid("API_V1_SUNSET", cfg.HTTP.V1Sunset.Format("2006-01-02"), "must be after API_V1_DEPRECATED_AT")
+	}
+	if cfg.Storage.DocumentURLTTL <= 0 {
+		l.invalid("DOCUMENT_URL_TTL", cfg.Storage.DocumentURLTTL.String(), "must be positive")
+	}
+	if rel, err := filepath.Rel(cfg.Storage.UploadDir, cfg.Storage.DocumentDir); err == nil && !strings.HasPrefix(rel, "..") {
+		l.invalid("DOCUMENT_DIR", cfg.Storage.DocumentDir, "must not be inside UPLOAD_DIR, which is public")
+	}
+	if cfg.Exports.URLTTL <= 0 {
+		l.invalid("EXPORT_URL_TTL", cfg.Exports.URLTTL.String(), "must be positive")
+	}
+	if cfg.Exports.Retention < cfg.Exports.URLTTL {
+		l.invalid("EXPORT_RETENTION", cfg.Exports.Retention.String(), "must be at least EXPORT_URL_TTL")
+	}
+	if rel, err := filepath.Rel(cfg.Storage.UploadDir, cfg.Exports.Dir); err == nil && !strings.HasPrefix(rel, "..") {
+		l.invalid("EXPORT_DIR", cfg.Exports.Dir, "must not be inside UPLOAD_DIR, which is public")
+	}
+	if cfg.Storage.ImageBackend == "s3" {
+		cfg.Storage.S3 = S3{
+			Endpoint:        strings.TrimSuffix(l.required("S3_ENDPOINT"), "/"),
+			Region:          l.optional("S3_REGION", "us-east-1"),
+			Bucket:          l.required("S3_BUCKET"),
+			Prefix:          l.optional("S3_PREFIX", ""),
+			AccessKeyID:     l.required("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: l.requiredSecret("S3_SECRET_ACCESS_KEY"),
+		}
+		cfg.Storage.S3.PublicURL = strings.TrimSuffix(l.optional("S3_PUBLIC_URL", cfg.Storage.S3.Endpoint+"/"+cfg.Storage.S3.Bucket), "/")
+	}
+
+	if cfg.Images.Size > 4000 {
+		l.invalid("IMAGE_SIZE", strconv.Itoa(cfg.Images.Size), "must be at most 4000")
+	}
+	if cfg.Images.BackgroundTolerance > 255 {
+		l.invalid("IMAGE_BACKGROUND_TOLERANCE", strconv.Itoa(cfg.Images.BackgroundTolerance), "must be between 0 and 255")
+	}
+
+	if cfg.Backup.Hour > 23 {
+		l.invalid("BACKUP_HOUR", strconv.Itoa(cfg.Backup.Hour), "must be between 0 and 23")
+	}
+	if cfg.Notifications.LowStockDigestHour > 23 {
+		l.invalid("LOW_STOCK_DIGEST_HOUR", strconv.Itoa(cfg.Notifications.LowStockDigestHour), "must be between 0 and 23")
+	}
+
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"DISPUTE_RESPONSE_WINDOW", cfg.Disputes.ResponseWindow},
+		{"DISPUTE_OPEN_WINDOW", cfg.Disputes.OpenWindow},
+		{"DISPUTE_CHECK_INTERVAL", cfg.Disputes.CheckInterval},
+		{"PREORDER_CHECK_INTERVAL", cfg.Preorders.CheckInterval},
+		{"UNPAID_ORDER_CHECK_INTERVAL", cfg.UnpaidOrders.CheckInterval},
+		{"VACATION_CHECK_INTERVAL", cfg.Vacations.CheckInterval},
+		{"SHIPPING_LATE_CHECK_INTERVAL", cfg.Shipping.LateCheckInterval},
+		{"WALLET_RECONCILE_INTERVAL", cfg.Wallet.ReconcileInterval},
+		{"WEBHOOK_DISPATCH_INTERVAL", cfg.Webhooks.DispatchInterval},
+		{"AI_SUSPENSION", cfg.AI.Suspension},
+		{"WATCHLIST_CHECK_INTERVAL", cfg.Notifications.WatchlistInterval},
+		{"DUPLICATE_CHECK_INTERVAL", cfg.Products.DuplicateInterval},
+	} {
+		if d.value <= 0 {
+			l.invalid(d.key, d.value.String(), "must be positive")
+		}
+	}
+
+	if cfg.Retention.Interval <= 0 {
+		l.invalid("RETENTION_INTERVAL", cfg.Retention.Interval.String(), "must be positive")
+	}
+	if cfg.HTTP.PublicCacheMaxAge < 0 {
+		l.invalid("PUBLIC_CACHE_MAX_AGE", cfg.HTTP.PublicCacheMaxAge.String(), "must not be negative")
+	}
+	if cfg.HTTP.PublicCacheStale < 0 {
+		l.invalid("PUBLIC_CACHE_STALE", cfg.HTTP.PublicCacheStale.String(), "must not be negative")
+	}
+	if cfg.Auth.UserCacheTTL < 0 {
+		l.invalid("AUTH_USER_CACHE_TTL", cfg.Auth.UserCacheTTL.String(), "must not be negative")
+	}
+
+	cfg.PII.Keys = l.piiKeys(cfg.IsProduction())
+
+	switch cfg.Captcha.Provider {
+	case "":
+	case "turnstile", "hcaptcha":
+		if cfg.Captcha.Secret == "" {
+			l.missing = append(l.missing, "CAPTCHA_SECRET")
+		}
+	default:
+		l.invalid("CAPTCHA_PROVIDER", cfg.Captcha.Provider, "must be turnstile or hcaptcha")
+	}
+
+	if len(cfg.Auth.JWTKeys) > 0 {
+		cfg.Auth.JWTSecret = cfg.Auth.JWTKeys[0].Secret
+	}
+
+	return cfg, l.err()
+}
+
+// LoadDB reads and validates only the primary database settings.
+// Tools that just need a connection (cmd/seed) use it instead of Load.
+func LoadDB() (DB, error) {
+	l := newLoader()
+	cfg := DB{
+		PrimaryDSN:         l.requiredSecret("DB_DSN_PRIMARY"),
+		Pool:               l.pool("DB"),
+		SlowQueryThreshold: l.duration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+	}
+	return cfg, l.err()
+}
+
+func (l *loader) db() DB {
+	return DB{
+		PrimaryDSN:         l.requiredSecret("DB_DSN_PRIMARY"),
+		ReplicaDSN:         l.secret("DB_DSN_REPLICA"),
+		ReadOnlyDSN:        l.requiredSecret("DB_DSN_READONLY"),
+		Pool:               l.pool("DB"),
+		ReplicaPool:        l.pool("DB_REPLICA"),
+		ReadOnlyPool:       l.pool("DB_READONLY"),
+		SlowQueryThreshold: l.duration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+		AutoMigrate:        l.boolean("DB_AUTO_MIGRATE", false),
+	}
+}
+
+//
+// --- Loader ---
+//
+
+// loader reads variables and collects every problem instead of stopping at the first.
+type loader struct {
+	missing   []string
+	malformed []string
+	secrets   secrets.Provider
+}
+
+// newLoader selects the secrets provider; a bad provider setting is reported
+// with the other problems.
+func newLoader() *loader {
+	l := &loader{}
+	provider, err := secrets.FromEnv()
+	if err != nil {
+		l.malformed = append(l.malformed, err.Error())
+	}
+	l.secrets = provider
+	return l
+}
+
+// LoadJWTKeys re-reads only the JWT keys, for rotation without a restart.
+func LoadJWTKeys() ([]SigningKey, error) {
+	l := newLoader()
+	keys := l.jwtKeys()
+	return keys, l.err()
+}
+
+// secret reads key through the secrets provider (falling back to the environment).
+func (l *loader) secret(key string) string {
+	v, err := secrets.Get(context.Background(), l.secrets, key)
+	if err != nil {
+		l.malformed = append(l.malformed, fmt.Sprintf("%s could not be read: %v", key, err))
+	}
+	return v
+}
+
+func (l *loader) requiredSecret(key string) string {
+	v := l.secret(key)
+	if v == "" {
+		l.missing = append(l.missing, key)
+	}
+	return v
+}
+
+// jwtKeys reads JWT_KEYS, or JWT_SECRET as a single key without an ID.
+func (l *loader) jwtKeys() []SigningKey {
+	var keys []SigningKey
+	key := "JWT_KEYS"
+	if raw := l.secret(key); raw != "" {
+		if keys = l.keyList(key, raw); keys == nil {
+			return nil
+		}
+	} else {
+		key = "JWT_SECRET"
+		secret := l.requiredSecret(key)
+		if secret == "" {
+			return nil
+		}
+		keys = []SigningKey{{Secret: secret}}
+	}
+
+	for _, k := range keys {
+		if len(k.Secret) < 32 {
+			l.invalid(key, "(hidden)", "secrets must be at least 32 characters")
+			return nil
+		}
+	}
+	return keys
+}
+
+// LoadPIIKeys reads only the PII encryption keys, for `api encrypt-pii`.
+func LoadPIIKeys() ([]EncryptionKey, error) {
+	l := newLoader()
+	keys := l.piiKeys(true)
+	return keys, l.err()
+}
+
+// piiKeys reads PII_KEYS; required reports a missing value.
+func (l *loader) piiKeys(required bool) []EncryptionKey {
+	const key = "PII_KEYS"
+	raw := l.secret(key)
+	if raw == "" {
+		if required {
+			l.missing = append(l.missing, key)
+		}
+		return nil
+	}
+	var keys []EncryptionKey
+	for _, k := range l.keyList(key, raw) {
+		data, err := base64.StdEncoding.DecodeString(k.Secret)
+		if err != nil || len(data) != 32 {
+			l.invalid(key, "(hidden)", fmt.Sprintf("key %q must be 32 bytes, base64-encoded", k.ID))
+			return nil
+		}
+		keys = append(keys, EncryptionKey{ID: k.ID, Key: data})
+	}
+	return keys
+}
+
+// keyList parses "kid:secret,kid:secret"; it reports a problem and returns nil
+// for a malformed list.
+func (l *loader) keyList(key, raw string) []SigningKey {
+	var keys []SigningKey
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(raw, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || !validKeyID(id) || seen[id] {
+			l.invalid(key, "(hidden)", "must be kid:secret pairs with unique IDs of letters, digits, '.', '_' or '-'")
+			return nil
+		}
+		seen[id] = true
+		keys = append(keys, SigningKey{ID: id, Secret: secret})
+	}
+	return keys
+}
+
+func validKeyID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (l *loader) required(key string) string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		l.missing = append(l.missing, key)
+	}
+	return v
+}
+
+func (l *loader) optional(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+func (l *loader) invalid(key, value, reason string) {
+	l.malformed = append(l.malformed, fmt.Sprintf("%s=%q %s", key, value, reason))
+}
+
+func (l *loader) oneOf(key, def string, allowed ...string) string {
+	v := l.optional(key, def)
+	for _, a := range allowed {
+		if v == a {
+			return v
+		}
+	}
+	l.invalid(key, v, "must be one of "+strings.Join(allowed, ", "))
+	return def
+}
+
+func (l *loader) duration(key string, def time.Duration) time.Duration {
+	raw := l.optional(key, "")
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		l.invalid(key, raw, "must be a duration like 500ms, 5m or 72h")
+		return def
+	}
+	return d
+}
+
+func (l *loader) integer(key string, def, min int) int {
+	raw := l.optional(key, "")
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < min {
+		l.invalid(key, raw, fmt.Sprintf("must be an integer >= %d", min))
+		return def
+	}
+	return n
+}
+
+func (l *loader) boolean(key string, def bool) bool {
+	raw := l.optional(key, "")
+	if raw == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		l.invalid(key, raw, "must be true or false")
+		return def
+	}
+	return b
+}
+
+// date reads a calendar date (UTC midnight); unset is the zero time.
+func (l *loader) date(key string) time.Time {
+	raw := l.optional(key, "")
+	if raw == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		l.invalid(key, raw, "must be a date like 2027-06-30")
+		return time.Time{}
+	}
+	return t
+}
+
+// ratio reads a number between 0 and 1.
+func (l *loader) ratio(key string, def float64) float64 {
+	raw := l.optional(key, "")
+	if raw == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || f < 0 || f > 1 {
+		l.invalid(key, raw, "must be a number between 0 and 1")
+		return def
+	}
+	return f
+}
+
+// percent reads a number between 0 and 100.
+func (l *loader) percent(key string, def float64) float64 {
+	raw := l.optional(key, "")
+	if raw == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || !(f >= 0 && f <= 100) {
+		l.invalid(key, raw, "must be a percentage between 0 and 100")
+		return def
+	}
+	return f
+}
+
+// money reads a non-negative RM amount.
+func (l *loader) money(key string, def money.Money) money.Money {
+	raw := l.optional(key, "")
+	if raw == "" {
+		return def
+	}
+	m, err := money.Parse(raw)
+	if err != nil || m < 0 {
+		l.invalid(key, raw, "must be an amount >= 0")
+		return def
+	}
+	return m
+}
+
+// devOrigins is the development CORS profile: the Vite dev server and preview.
+var devOrigins = []string{"http://localhost:5173", "http://127.0.0.1:5173", "http://localhost:4173"}
+
+// origins reads a comma-separated list of CORS origins (scheme://host[:port]).
+// "*" is rejected: the API sends credentials, which browsers refuse to pair with
+// a wildcard origin. Without a value, production reports the key as missing and
+// development falls back to devOrigins.
+func (l *loader) origins(key string, production bool) []string {
+	raw := l.optional(key, l.optional("CORS_ALLOWED_ORIGIN", ""))
+	if raw == "" {
+		if production {
+			l.missing = append(l.missing, key)
+			return nil
+		}
+		return devOrigins
+	}
+
+	var out []string
+	for _, origin := range strings.Split(raw, ",") {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if !validOrigin(origin) {
+			l.invalid(key, origin, "must be an origin like https://app.example.com or https://*.example.com")
+			continue
+		}
+		out = append(out, strings.ToLower(origin))
+	}
+	return out
+}
+
+// validOrigin accepts http(s)://host[:port], where host may start with "*."
+// and nothing follows the port.
+func validOrigin(origin string) bool {
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || (scheme != "http" && scheme != "https") {
+		return false
+	}
+	if h, port, hasPort := strings.Cut(host, ":"); hasPort {
+		if _, err := strconv.Atoi(port); err != nil {
+			return false
+		}
+		host = h
+	}
+	host = strings.TrimPrefix(host, "*.")
+	return host != "" && !strings.ContainsAny(host, "/*?#@ ")
+}
+
+// pool reads PREFIX_MAX_OPEN_CONNS, PREFIX_MAX_IDLE_CONNS,
+// PREFIX_CONN_MAX_LIFETIME and PREFIX_CONN_MAX_IDLE_TIME.
+func (l *loader) pool(prefix string) PoolConfig {
+	def := DefaultPoolConfig()
+	cfg := PoolConfig{
+		MaxOpenConns:    l.integer(prefix+"_MAX_OPEN_CONNS", def.MaxOpenConns, 1),
+		MaxIdleConns:    l.integer(prefix+"_MAX_IDLE_CONNS", def.MaxIdleConns, 0),
+		ConnMaxLifetime: l.duration(prefix+"_CONN_MAX_LIFETIME", def.ConnMaxLifetime),
+		ConnMaxIdleTime: l.duration(prefix+"_CONN_MAX_IDLE_TIME", def.ConnMaxIdleTime),
+	}
+
+	// Idle connections above the open limit would just be closed again.
+	if cfg.MaxIdleConns > cfg.MaxOpenConns {
+		cfg.MaxIdleConns = cfg.MaxOpenConns
+	}
+	return cfg
+}
+
+// err reports every problem at once, or nil.
+func (l *loader) err() error {
+	if len(l.missing) == 0 && len(l.malformed) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("invalid configuration:")
+	if len(l.missing) > 0 {
+		b.WriteString("\n  missing: " + strings.Join(l.missing, ", "))
+	}
+	for _, inv := range l.malformed {
+		b.WriteString("\n  invalid: " + inv)
+	}
+	return fmt.Errorf("%s", b.String())
+}
@@ -3,49 +3,63 @@ package database
 import (
 	"database/sql"
 	"log"
-	"os" // ADDED: To read the primary DSN from the environment
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/go-sql-driver/mysql"
 )
 
 // OpenDB initializes and returns the primary Read/Write connection pool.
-// It now reads the DSN from the environment variable (or hardcoded fallback).
-func OpenDB() (*sql.DB, error) {
-	// 1. Define the Data Source Name (DSN)
-	// We read the DSN from an environment variable first, falling back to the hardcoded string.
-	// NOTE: You should create a DB_DSN environment variable for the primary connection later.
-	dsn := os.Getenv("DB_DSN_PRIMARY")
-	if dsn == "" {
-		// FALLBACK: Use the hardcoded DSN if environment variable is not set.
-		dsn = "root:X4#j$Ds2N749bruqtnm%MMNx1xvzrSZQwyYw33FT1%y7v!4CzPRdVr6L$nJnzcbv@tcp(127.0.0.1:3306)/taptosell_golang?parseTime=true"
+func OpenDB(cfg config.DB) (*sql.DB, error) {
+	return OpenDBWithDSN(cfg.PrimaryDSN, cfg.Pool, cfg.SlowQueryThreshold)
+}
+
+// OpenReplica opens the read-replica pool used by heavy read endpoints
+// (search, dashboards). It returns (nil, nil) when DB_DSN_REPLICA is not set,
+// in which case callers should route reads to the primary.
+func OpenReplica(cfg config.DB) (*sql.DB, error) {
+	if cfg.ReplicaDSN == "" {
+		return nil, nil
 	}
+	return OpenDBWithDSN(cfg.ReplicaDSN, cfg.ReplicaPool, cfg.SlowQueryThreshold)
+}
 
-	// Delegate the rest of the setup to the generic function
-	return OpenDBWithDSN(dsn)
+// OpenReadOnly opens the restricted pool used by the AI SQL tool.
+func OpenReadOnly(cfg config.DB) (*sql.DB, error) {
+	return OpenDBWithDSN(cfg.ReadOnlyDSN, cfg.ReadOnlyPool, cfg.SlowQueryThreshold)
 }
 
 // OpenDBWithDSN is a generic function to create and configure a DB connection pool
-// using any provided DSN string. This is used for BOTH the primary and read-only pools.
-func OpenDBWithDSN(dsn string) (*sql.DB, error) {
-	// 2. Open a new connection pool.
-	db, err := sql.Open("mysql", dsn)
+// using any provided DSN string. This is used for the primary, replica and read-only pools.
+// slowQueryThreshold <= 0 disables slow query logging; it applies to every pool
+// (see SetSlowQueryThreshold).
+func OpenDBWithDSN(dsn string, pool config.PoolConfig, slowQueryThreshold time.Duration) (*sql.DB, error) {
+	// 1. Open a new connection pool.
+	// The connector is wrapped so every statement is timed (see instrument.go).
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := mysql.NewConnector(cfg)
 	if err != nil {
 		return nil, err
 	}
+	SetSlowQueryThreshold(slowQueryThreshold)
+	db := sql.OpenDB(&instrumentedConnector{Connector: connector, inst: &instrumentation{}})
 
-	// 3. Configure the connection pool settings.
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	// 2. Configure the connection pool settings.
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
-	// 4. Ping the database to verify the connection.
+	// 3. Ping the database to verify the connection.
 	err = db.Ping()
 	if err != nil {
 		log.Printf("Error connecting to database with DSN: %v", err)
 		return nil, err
 	}
 
-	log.Println("Database connection pool established successfully (Generic DSN)")
+	log.Printf("Database connection pool established successfully (max open: %d, max idle: %d)", pool.MaxOpenConns, pool.MaxIdleConns)
 	return db, nil
 }
@@ -0,0 +1,285 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/logging"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//
+// --- Query Instrumentation ---
+//
+// The MySQL connector is wrapped at the driver level, so every *sql.DB,
+// *sql.Tx and prepared statement is timed without changing any handler code.
+// Statements slower than the threshold are logged with their bound
+// parameters hashed: the log shows whether two slow calls used the same
+// values without leaking emails, tokens or amounts.
+//
+// Statements run inside a traced request also get a client span carrying the
+// SQL text (never the arguments).
+//
+
+// tracer names the spans of database statements.
+var tracer = otel.Tracer("github.com/01moynul/taptosell-golang/internal/database")
+
+// slowThreshold is shared by every pool and can be changed at runtime
+// (PATCH /v1/manager/logging); <= 0 disables slow query logging.
+var slowThreshold atomic.Int64
+
+// SetSlowQueryThreshold changes the slow query threshold of every pool.
+func SetSlowQueryThreshold(d time.Duration) {
+	slowThreshold.Store(int64(d))
+}
+
+// SlowQueryThreshold returns the active threshold.
+func SlowQueryThreshold() time.Duration {
+	return time.Duration(slowThreshold.Load())
+}
+
+// instrumentation holds the state shared by all wrapped connections of a pool.
+type instrumentation struct{}
+
+// start is called before every statement; the returned func must be called
+// with the outcome. Statements without a traced parent (startup, background
+// workers) get no span of their own, so they do not flood the trace backend
+// with single-span roots.
+func (i *instrumentation) start(ctx context.Context, query string) func(args []driver.NamedValue, err error) {
+	begin := time.Now()
+
+	var span trace.Span
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		_, span = tracer.Start(ctx, spanName(query),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNameMySQL,
+				semconv.DBQueryText(compactQuery(query)),
+			),
+		)
+	}
+
+	return func(args []driver.NamedValue, err error) {
+		i.observe(query, args, time.Since(begin), err)
+		if span == nil {
+			return
+		}
+		// ErrSkip is retried as a prepared statement with its own span;
+		// this one is never ended, so it is never exported.
+		if errors.Is(err, driver.ErrSkip) {
+			return
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// spanName is the statement's verb ("SELECT", "UPDATE", ...), which keeps
+// span names low-cardinality; the full SQL is in db.query.text.
+func spanName(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// observe is called after every statement with its duration.
+func (i *instrumentation) observe(query string, args []driver.NamedValue, d time.Duration, err error) {
+	// ErrSkip is the driver asking database/sql to retry via a prepared statement;
+	// that retry is observed separately.
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	threshold := SlowQueryThreshold()
+	if threshold <= 0 || d < threshold {
+		return
+	}
+
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+	}
+	logging.Warnf("[SlowQuery] %s | %s | args=%s | %s", d.Round(time.Microsecond), compactQuery(query), hashArgs(args), status)
+}
+
+// compactQuery collapses the whitespace of multi-line SQL so it fits on one log line.
+func compactQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// hashArgs renders each bound parameter as a short SHA-256 prefix (NULL stays NULL).
+func hashArgs(args []driver.NamedValue) string {
+	hashed := make([]string, len(args))
+	for i, a := range args {
+		if a.Value == nil {
+			hashed[i] = "NULL"
+			continue
+		}
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%T:%v", a.Value, a.Value)))
+		hashed[i] = hex.EncodeToString(sum[:4])
+	}
+	return "[" + strings.Join(hashed, ",") + "]"
+}
+
+// --- Connector ---
+
+type instrumentedConnector struct {
+	driver.Connector
+	inst *instrumentation
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, inst: c.inst}, nil
+}
+
+// --- Connection ---
+// The MySQL driver implements every optional interface below; the fallbacks
+// only matter if a different driver is ever plugged in.
+
+type instrumentedConn struct {
+	driver.Conn
+	inst *instrumentation
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	done := c.inst.start(ctx, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	done(args, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	done := c.inst.start(ctx, query)
+	res, err := execer.ExecContext(ctx, query, args)
+	done(args, err)
+	return res, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, query: query, inst: c.inst}, nil
+}
+
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() // fallback for drivers without BeginTx
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// --- Prepared Statement ---
+
+type instrumentedStmt struct {
+	driver.Stmt
+	query string
+	inst  *instrumentation
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	done := s.inst.start(ctx, s.query)
+	var res driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = execer.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(namedToValues(args))
+	}
+	done(args, err)
+	return res, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	done := s.inst.start(ctx, s.query)
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedToValues(args))
+	}
+	done(args, err)
+	return rows, err
+}
+
+func (s *instrumentedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func namedToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	return values
+}
@@ -0,0 +1,216 @@
+// Package dedupe finds listings of the same item by different suppliers: the
+// same barcode, a near-identical title, or a shared image. It only pairs up
+// listings; the duplicate job groups the pairs into clusters for review.
+package dedupe
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Why two listings were paired.
+const (
+	ReasonBarcode = "barcode"
+	ReasonTitle   = "title"
+	ReasonImage   = "image"
+)
+
+// minTitleTokens is the fewest significant words a title needs to be matched
+// on: short generic names ("Phone Case") are not evidence of a duplicate.
+const minTitleTokens = 3
+
+// maxGroup is the largest set of listings sharing one barcode or image that is
+// still paired up. A bigger one is a placeholder picture or a made-up code,
+// and would flood the queue.
+const maxGroup = 20
+
+// stopWords carry no meaning in a title (English and Malay).
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "for": true, "with": true, "of": true, "in": true,
+	"dan": true, "untuk": true, "dengan": true, "yang": true,
+}
+
+// Listing is one product as the detector sees it.
+type Listing struct {
+	ID         int64
+	SupplierID int64
+	Title      string
+	Barcodes   []string // product and variant barcodes, normalized
+	Images     []string
+}
+
+// Pair is two listings of different suppliers that look like the same item
+// (A < B). Similarity is their title similarity, 0 to 1.
+type Pair struct {
+	A, B       int64
+	Reasons    []string // in the order barcode, title, image
+	Similarity float64
+}
+
+// Tokens normalizes a title into its significant words: lower case, split on
+// anything but letters and digits, stop words dropped, each word once.
+func Tokens(title string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(fields))
+	tokens := fields[:0]
+	for _, f := range fields {
+		if stopWords[f] || seen[f] {
+			continue
+		}
+		seen[f] = true
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
+
+// Similarity is the Jaccard index of two token sets.
+func Similarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	in := make(map[string]bool, len(a))
+	for _, t := range a {
+		in[t] = true
+	}
+	shared := 0
+	for _, t := range b {
+		if in[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// Pairs returns every pair of listings by different suppliers that share a
+// barcode or an image, or whose titles are at least threshold similar,
+// ordered by A then B.
+func Pairs(listings []Listing, threshold float64) []Pair {
+	found := map[[2]int64]*Pair{}
+	add := func(x, y Listing, reason string) {
+		if x.SupplierID == y.SupplierID {
+			return
+		}
+		if x.ID > y.ID {
+			x, y = y, x
+		}
+		key := [2]int64{x.ID, y.ID}
+		p := found[key]
+		if p == nil {
+			p = &Pair{A: x.ID, B: y.ID}
+			found[key] = p
+		}
+		for _, r := range p.Reasons {
+			if r == reason {
+				return
+			}
+		}
+		p.Reasons = append(p.Reasons, reason)
+	}
+
+	// 1. --- Shared Barcodes & Images ---
+	byBarcode, byImage := map[string][]int{}, map[string][]int{}
+	for i, l := range listings {
+		for _, code := range l.Barcodes {
+			byBarcode[code] = appendOnce(byBarcode[code], i)
+		}
+		for _, img := range l.Images {
+			byImage[img] = appendOnce(byImage[img], i)
+		}
+	}
+	for _, group := range []struct {
+		index  map[string][]int
+		reason string
+	}{{byBarcode, ReasonBarcode}, {byImage, ReasonImage}} {
+		for _, members := range group.index {
+			if len(members) < 2 || len(members) > maxGroup {
+				continue
+			}
+			for i := range members {
+				for _, j := range members[i+1:] {
+					add(listings[members[i]], listings[j], group.reason)
+				}
+			}
+		}
+	}
+
+	// 2. --- Similar Titles (prefix filtering) ---
+	// With the tokens of each title sorted rarest first, two titles at least
+	// threshold similar must share a token among the first
+	// len - ceil(threshold*len) + 1 of either. Only those prefixes are
+	// indexed, so common words never make everything a candidate.
+	tokens := make([][]string, len(listings))
+	freq := map[string]int{}
+	for i, l := range listings {
+		tokens[i] = Tokens(l.Title)
+		for _, t := range tokens[i] {
+			freq[t]++
+		}
+	}
+	index := map[string][]int{}
+	for i, ts := range tokens {
+		if len(ts) < minTitleTokens {
+			continue
+		}
+		sort.Slice(ts, func(a, b int) bool {
+			if freq[ts[a]] != freq[ts[b]] {
+				return freq[ts[a]] < freq[ts[b]]
+			}
+			return ts[a] < ts[b]
+		})
+		prefix := len(ts) - int(math.Ceil(threshold*float64(len(ts)))) + 1
+		compared := map[int]bool{}
+		for _, t := range ts[:min(prefix, len(ts))] {
+			for _, j := range index[t] {
+				if compared[j] {
+					continue
+				}
+				compared[j] = true
+				if s := Similarity(ts, tokens[j]); s >= threshold {
+					add(listings[j], listings[i], ReasonTitle)
+				}
+			}
+			index[t] = append(index[t], i)
+		}
+	}
+
+	// 3. --- Order & Score ---
+	pairs := make([]Pair, 0, len(found))
+	byID := make(map[int64]int, len(listings))
+	for i, l := range listings {
+		byID[l.ID] = i
+	}
+	for _, p := range found {
+		p.Similarity = Similarity(tokens[byID[p.A]], tokens[byID[p.B]])
+		sort.Slice(p.Reasons, func(a, b int) bool { return reasonOrder(p.Reasons[a]) < reasonOrder(p.Reasons[b]) })
+		pairs = append(pairs, *p)
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a].A != pairs[b].A {
+			return pairs[a].A < pairs[b].A
+		}
+		return pairs[a].B < pairs[b].B
+	})
+	return pairs
+}
+
+func reasonOrder(r string) int {
+	switch r {
+	case ReasonBarcode:
+		return 0
+	case ReasonTitle:
+		return 1
+	}
+	return 2
+}
+
+// appendOnce appends i unless it is already last (listings are visited in order).
+func appendOnce(s []int, i int) []int {
+	if len(s) > 0 && s[len(s)-1] == i {
+		return s
+	}
+	return append(s, i)
+}
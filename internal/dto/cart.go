@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// Cart is the body of GET /v2/dropshipper/cart.
+type Cart struct {
+	Items     []CartLine  `json:"items"`
+	Subtotal  money.Money `json:"subtotal"`
+	ItemCount int         `json:"itemCount"`
+}
+
+// CartLine is one product (or variant) in the cart.
+type CartLine struct {
+	ProductID int64               `json:"productId"`
+	VariantID *int64              `json:"variantId"` // null for a simple product; PUT/DELETE take it as ?variantId=
+	Name      string              `json:"name"`
+	SKU       string              `json:"sku"`
+	Price     money.Money         `json:"price"`
+	Quantity  int                 `json:"quantity"`
+	Stock     int                 `json:"stock"`
+	LineTotal money.Money         `json:"lineTotal"`
+	Options   []map[string]string `json:"options"`
+
+	// Preorder: checkout places this line as a pre-order. SupplierAway: the
+	// supplier is on vacation and checkout refuses the cart until it is removed.
+	Preorder     bool `json:"preorder"`
+	SupplierAway bool `json:"supplierAway"`
+
+	// Couriers accepting the restrictions (null without SHIPPING_COURIERS);
+	// when none does, checkout refuses the cart until the line is removed.
+	ShippingRestrictions []string `json:"shippingRestrictions"`
+	Couriers             []string `json:"couriers"`
+	Shippable            bool     `json:"shippable"`
+
+	// Quantity steppers: at least MinQuantity, in steps of Increment.
+	MinQuantity   int  `json:"minQuantity"`
+	Increment     int  `json:"increment"`
+	QuantityValid bool `json:"quantityValid"`
+
+	// When the supplier must ship the line by if the order is paid now; null
+	// for a pre-order line, which has no date until its stock arrives.
+	ShipsBy *time.Time `json:"shipsBy"`
+}
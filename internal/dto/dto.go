@@ -0,0 +1,20 @@
+// Package dto holds the response shapes of API v2, one type per resource.
+// Handlers keep building the v1 shapes as before and, from v2 on, answer with
+// these types, so every v2 response follows the same rules:
+//
+//   - keys are lowerCamelCase, and each value has exactly one key (no
+//     "product_id" next to "productId", no "name" repeated as "product_name");
+//   - lists are [] when empty, never null; an absent optional value is null;
+//   - IDs are numbers, money is a number in RM (money.Money), times are RFC 3339.
+//
+// Request bodies keep their v1 field names. Adding a field to a type here is
+// fine; renaming or removing one needs a new API version.
+package dto
+
+// List returns s, or an empty slice for nil, so it encodes as [] rather than null.
+func List[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
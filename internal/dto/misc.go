@@ -0,0 +1,21 @@
+package dto
+
+// OrderPayment is the body of POST /v2/dropshipper/orders/:id/pay.
+type OrderPayment struct {
+	Message string `json:"message"`
+	Status  string `json:"status"` // the order's new status
+}
+
+// ChatReply is the body of POST /v2/ai/chat.
+type ChatReply struct {
+	Response     string `json:"response"`
+	TokensUsed   int    `json:"tokensUsed"`
+	CostIncurred string `json:"costIncurred"` // RM, 4 decimals
+}
+
+// SupplierDocuments are signed links to a supplier's verification documents;
+// a document not uploaded yet is null.
+type SupplierDocuments struct {
+	SSMDocument   *string `json:"ssmDocument"`
+	BankStatement *string `json:"bankStatement"`
+}
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pricing"
+)
+
+// ProductDetail is the edit-form view of a product (GET /v2/products/:id for
+// its supplier and staff).
+type ProductDetail struct {
+	ID          int64  `json:"id"`
+	PublicID    string `json:"publicId"`
+	SupplierID  int64  `json:"supplierId"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	// RejectionReason is the reason of the last rejection, until approval.
+	RejectionReason *string `json:"rejectionReason"`
+	IsVariable      bool    `json:"isVariable"`
+	SKU             *string `json:"sku"`     // simple products
+	Barcode         *string `json:"barcode"` // simple products
+	Version         int     `json:"version"`
+
+	PriceToTTS     money.Money `json:"priceToTTS"`
+	SRP            money.Money `json:"srp"`
+	StockQuantity  int         `json:"stockQuantity"`
+	CommissionRate *float64    `json:"commissionRate"`
+	// Pricing is the margin, platform fee and supplier payout of a unit.
+	Pricing pricing.Breakdown `json:"pricing"`
+
+	Weight            *float64          `json:"weight"`
+	PackageDimensions PackageDimensions `json:"packageDimensions"`
+
+	// The configured couriers accepting ShippingRestrictions (null when
+	// SHIPPING_COURIERS is not set, [] when none does).
+	ShippingRestrictions []string `json:"shippingRestrictions"`
+	Couriers             []string `json:"couriers"`
+
+	MinOrderQty    int                   `json:"minOrderQty"`
+	OrderIncrement int                   `json:"orderIncrement"`
+	ProcessingTime models.ProcessingTime `json:"processingTime"`
+	ShipsBy        time.Time             `json:"shipsBy"`
+
+	Images          []string               `json:"images"`
+	VideoURL        string                 `json:"videoUrl"`
+	SizeChart       map[string]interface{} `json:"sizeChart"`
+	VariationImages map[string]string      `json:"variationImages"`
+
+	BrandID     int64    `json:"brandId"`
+	BrandName   string   `json:"brandName"`
+	CategoryIDs []int64  `json:"categoryIds"`
+	Tags        []string `json:"tags"`
+
+	Variants []ProductVariant `json:"variants"`
+}
+
+// PackageDimensions are a product's package size in cm.
+type PackageDimensions struct {
+	Length float64 `json:"length"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// ProductVariant is one variant in the edit form.
+type ProductVariant struct {
+	SKU            string                        `json:"sku"`
+	Barcode        *string                       `json:"barcode"`
+	Price          money.Money                   `json:"price"`
+	Stock          int                           `json:"stock"`
+	SRP            money.Money                   `json:"srp"`
+	Options        []models.ProductVariantOption `json:"options"`
+	CommissionRate *float64                      `json:"commissionRate"`
+}
@@ -1,16 +1,44 @@
 package email
 
 import (
-	"fmt" // For formatting strings
 	"log" // For printing to the console
+	"strings"
+	"sync/atomic"
+
+	"github.com/01moynul/taptosell-golang/internal/i18n"
 )
 
-// SendEmail is our placeholder email function.
-// In the future, this will use a real email API (like SendGrid).
+// Sender delivers one plain-text email.
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+// sender is the active Sender; managers switch it at runtime
+// (PUT /v1/manager/messaging/email). Until then emails go to the log.
+var sender atomic.Value // holds senderBox
+
+// senderBox keeps every stored value the same concrete type, as atomic.Value requires.
+type senderBox struct{ Sender }
+
+// SetSender replaces the active Sender; safe to call while requests are served.
+func SetSender(s Sender) {
+	sender.Store(senderBox{s})
+}
+
+// SendEmail sends an email through the active Sender.
 func SendEmail(to string, subject string, body string) error {
-	// --- THIS IS OUR PLACEHOLDER ---
-	// Instead of sending a real email, we log it to the console.
-	// This lets us "see" the email and test our code without an API key.
+	if box, ok := sender.Load().(senderBox); ok {
+		return box.Send(to, subject, body)
+	}
+	return LogSender{}.Send(to, subject, body)
+}
+
+// LogSender is our placeholder sender: it "sends" by logging the email,
+// so we can see it and test our code without a mail server.
+type LogSender struct{}
+
+// Send logs the email to the console.
+func (LogSender) Send(to, subject, body string) error {
 	log.Println("====================================================")
 	log.Printf("--- NEW EMAIL (PLACEHOLDER) ---")
 	log.Printf("To: %s", to)
@@ -19,15 +47,16 @@ func SendEmail(to string, subject string, body string) error {
 	log.Println(body)
 	log.Println("====================================================")
 
-	return nil // Assume success for now
+	return nil
 }
 
 // SendVerificationEmail is a helper that uses our main SendEmail function.
-func SendVerificationEmail(to string, code string) error {
-	subject := "Verify your TapToSell Account"
+// lang is the recipient's language (the request's Accept-Language).
+func SendVerificationEmail(to string, code string, lang string) error {
+	subject := i18n.T(lang, "Verify your TapToSell Account")
 
 	// We create a simple text body for the email.
-	body := fmt.Sprintf(
+	body := i18n.Sprintf(lang,
 		"Welcome to TapToSell!\n\nYour verification code is: %s\n\nThis code will expire in 15 minutes.",
 		code,
 	)
@@ -35,3 +64,28 @@ func SendVerificationEmail(to string, code string) error {
 	// Call our main email sender
 	return SendEmail(to, subject, body)
 }
+
+// SendPasswordResetEmail sends the token that POST /v1/auth/reset-password takes.
+func SendPasswordResetEmail(to string, token string, lang string) error {
+	subject := i18n.T(lang, "Reset your TapToSell password")
+
+	body := i18n.Sprintf(lang,
+		"We received a request to reset your password.\n\nYour reset code is: %s\n\nThis code will expire in 1 hour. If you did not ask for it, you can ignore this email.",
+		token,
+	)
+
+	return SendEmail(to, subject, body)
+}
+
+// SendLowStockDigestEmail lists the supplier's products that went low on stock
+// since the last digest, one "name: N left" line each.
+func SendLowStockDigestEmail(to string, lines []string, lang string) error {
+	subject := i18n.T(lang, "Products running low on stock")
+
+	body := i18n.Sprintf(lang,
+		"These products went below your low-stock level since the last email:\n\n%s\n\nRestock them so your dropshippers can keep selling them.",
+		strings.Join(lines, "\n"),
+	)
+
+	return SendEmail(to, subject, body)
+}
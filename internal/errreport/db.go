@@ -0,0 +1,82 @@
+package errreport
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"time"
+)
+
+// DB keeps the most recent reports in the app_errors table, a ring buffer of
+// capacity rows, for the admin error viewer (GET /v1/admin/errors).
+type DB struct {
+	db       *sql.DB
+	capacity int64
+}
+
+// NewDB returns a reporter writing to db that keeps at most capacity rows.
+func NewDB(db *sql.DB, capacity int) *DB {
+	return &DB{db: db, capacity: int64(capacity)}
+}
+
+// Report inserts the row and trims the oldest ones. It runs detached from the
+// request context: a timed-out request (504) still has to be recorded.
+func (d *DB) Report(ctx context.Context, r Report) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+
+	var userID interface{}
+	if id, err := strconv.ParseInt(r.UserID, 10, 64); err == nil {
+		userID = id
+	}
+	var stack interface{}
+	if r.Stack != nil {
+		stack = string(r.Stack)
+	}
+
+	res, err := d.db.ExecContext(ctx, `
+		INSERT INTO app_errors (request_id, trace_id, user_id, method, route, path, status, is_panic, message, stack)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.RequestID, r.TraceID, userID, r.Method, r.Route, truncate(r.Path, 1024), r.Status, r.Panic, r.Err.Error(), stack)
+	if err != nil {
+		log.Printf("[ErrorLog] Could not record error for request %s: %v", r.RequestID, err)
+		return
+	}
+
+	// IDs only grow, so everything capacity rows behind the new one is out of the ring.
+	id, err := res.LastInsertId()
+	if err != nil || id <= d.capacity {
+		return
+	}
+	if _, err := d.db.ExecContext(ctx, "DELETE FROM app_errors WHERE id <= ?", id-d.capacity); err != nil {
+		log.Printf("[ErrorLog] Could not trim app_errors: %v", err)
+	}
+}
+
+// Flush is a no-op: Report writes synchronously.
+func (d *DB) Flush(time.Duration) bool { return true }
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// Multi fans every report out to several reporters.
+type Multi []Reporter
+
+func (m Multi) Report(ctx context.Context, r Report) {
+	for _, reporter := range m {
+		reporter.Report(ctx, r)
+	}
+}
+
+func (m Multi) Flush(timeout time.Duration) bool {
+	ok := true
+	for _, reporter := range m {
+		ok = reporter.Flush(timeout) && ok
+	}
+	return ok
+}
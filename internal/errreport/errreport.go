@@ -0,0 +1,69 @@
+// Package errreport forwards panics and 5xx responses to an error tracker.
+// The recovery middleware builds a Report per failure; which Reporter gets it
+// (Sentry, or the log when no DSN is configured) is decided once at startup.
+package errreport
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+)
+
+// Report is one failed request.
+type Report struct {
+	Err   error
+	Panic bool   // true when Err was recovered from a panic
+	Stack []byte // goroutine stack of the panic; nil otherwise
+
+	Status    int
+	Method    string
+	Route     string // route template, e.g. /v1/orders/:id
+	Path      string
+	RequestID string
+	UserID    string // empty for anonymous requests
+	TraceID   string // empty when the request was not traced
+}
+
+// Reporter delivers reports. Report must not block the request for long;
+// Flush waits up to timeout for queued reports before the process exits.
+type Reporter interface {
+	Report(ctx context.Context, r Report)
+	Flush(timeout time.Duration) bool
+}
+
+// New returns the Sentry reporter when SENTRY_DSN is set, the log reporter
+// otherwise; either way reports are also kept in db for the admin error viewer
+// unless ERROR_LOG_CAPACITY is 0.
+func New(cfg config.ErrorReporting, env string, db *sql.DB) (Reporter, error) {
+	var primary Reporter = Log{}
+	if cfg.SentryDSN != "" {
+		s, err := NewSentry(cfg, env)
+		if err != nil {
+			return nil, err
+		}
+		primary = s
+	}
+	if cfg.LogCapacity == 0 {
+		return primary, nil
+	}
+	return Multi{primary, NewDB(db, cfg.LogCapacity)}, nil
+}
+
+// Log writes reports to the standard logger; the default without a tracker.
+type Log struct{}
+
+func (Log) Report(_ context.Context, r Report) {
+	kind := "Error"
+	if r.Panic {
+		kind = "Panic"
+	}
+	log.Printf("[%s] %d %s %s | request=%s user=%s trace=%s | %v", kind, r.Status, r.Method, r.Path, r.RequestID, r.UserID, r.TraceID, r.Err)
+	if r.Stack != nil {
+		log.Printf("[%s] request=%s stack:\n%s", kind, r.RequestID, r.Stack)
+	}
+}
+
+func (Log) Flush(time.Duration) bool { return true }
@@ -0,0 +1,60 @@
+package errreport
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/getsentry/sentry-go"
+)
+
+// Sentry sends reports to Sentry. Events are sent asynchronously by the SDK.
+type Sentry struct {
+	client *sentry.Client
+}
+
+// NewSentry creates a Sentry client for cfg.SentryDSN. env becomes the Sentry environment.
+func NewSentry(cfg config.ErrorReporting, env string) (*Sentry, error) {
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              cfg.SentryDSN,
+		Environment:      env,
+		Release:          cfg.Release,
+		AttachStacktrace: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Sentry{client: client}, nil
+}
+
+// Report must be called from the recovering goroutine for panics: the SDK
+// captures the current stack, which still includes the panicking frames.
+func (s *Sentry) Report(_ context.Context, r Report) {
+	// A fresh hub per report keeps tags of concurrent requests apart.
+	hub := sentry.NewHub(s.client, sentry.NewScope())
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("request_id", r.RequestID)
+		scope.SetTag("route", r.Route)
+		scope.SetTag("status", strconv.Itoa(r.Status))
+		if r.TraceID != "" {
+			scope.SetTag("trace_id", r.TraceID)
+		}
+		if r.UserID != "" {
+			scope.SetUser(sentry.User{ID: r.UserID})
+		}
+		scope.SetContext("request", sentry.Context{
+			"method": r.Method,
+			"path":   r.Path,
+		})
+		if r.Panic {
+			scope.SetLevel(sentry.LevelFatal)
+		}
+	})
+	hub.CaptureException(r.Err)
+}
+
+// Flush waits for queued events to be sent.
+func (s *Sentry) Flush(timeout time.Duration) bool {
+	return s.client.Flush(timeout)
+}
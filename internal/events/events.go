@@ -0,0 +1,173 @@
+// Package events is the in-process domain event bus. Handlers publish what
+// happened (an order was paid, a product approved) after their transaction
+// commits; notifications, cache invalidation and channel sync subscribe to it
+// instead of being inlined in every handler.
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/01moynul/taptosell-golang/internal/jobs"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// Event is a fact that already happened and was committed.
+type Event interface {
+	EventName() string
+}
+
+// OrderPaid is published when an order becomes ready to fulfil: its payment is
+// taken from the wallet at checkout or later through PayOrder, or a pre-order
+// (paid at checkout) gets its stock and converts to processing.
+type OrderPaid struct {
+	OrderID       int64
+	OrderPublicID string // for links that leave the API
+	UserID        int64  // the paying dropshipper
+	Total         money.Money
+}
+
+func (OrderPaid) EventName() string { return "order.paid" }
+
+// OrderCompleted is published when a dropshipper confirms delivery and the
+// supplier is paid out (not when a dispute resolution closes the order).
+type OrderCompleted struct {
+	OrderID int64
+	UserID  int64 // the dropshipper
+	Total   money.Money
+}
+
+func (OrderCompleted) EventName() string { return "order.completed" }
+
+// ProductApproved is published when a manager approves a pending product.
+type ProductApproved struct {
+	ProductID   int64
+	SupplierID  int64
+	ProductName string
+}
+
+func (ProductApproved) EventName() string { return "product.approved" }
+
+// ProductRestocked is published when a supplier sets the stock of a simple
+// product, so open pre-orders waiting for it can convert.
+type ProductRestocked struct {
+	ProductID int64
+	Stock     int
+}
+
+func (ProductRestocked) EventName() string { return "product.restocked" }
+
+// StockLow is published when an order, or the supplier's own adjustment, takes
+// the stock of a product or variant below the low_stock_threshold setting.
+type StockLow struct {
+	ProductID int64
+	VariantID *int64 // nil for a simple product
+	Stock     int
+	Threshold int
+}
+
+func (StockLow) EventName() string { return "stock.low" }
+
+// WithdrawalApproved is published when a manager approves a withdrawal request.
+type WithdrawalApproved struct {
+	WithdrawalID int64
+	UserID       int64
+	Amount       money.Money
+}
+
+func (WithdrawalApproved) EventName() string { return "withdrawal.approved" }
+
+// DisputeUpdated is published whenever a dispute is opened or changes status
+// (supplier response, manager or deadline resolution, withdrawal).
+type DisputeUpdated struct {
+	DisputeID      int64
+	OrderID        int64
+	DropshipperID  int64
+	SupplierID     int64
+	Status         string // the new status: open, under_review, resolved, withdrawn
+	RefundAmount   money.Money
+	SupplierAmount money.Money
+	Automatic      bool // resolved because the supplier missed the response deadline
+}
+
+func (DisputeUpdated) EventName() string { return "dispute.updated" }
+
+// handler is a type-erased subscriber.
+type handler struct {
+	name string // for logs
+	fn   func(ctx context.Context, e Event) error
+}
+
+// Bus dispatches events to subscribers: synchronous ones run inside Publish,
+// asynchronous ones are handed to the job queue.
+type Bus struct {
+	mu    sync.RWMutex
+	sync  map[string][]handler
+	async map[string][]handler
+	queue *jobs.Queue
+}
+
+// NewBus creates a bus whose async subscribers run on queue.
+func NewBus(queue *jobs.Queue) *Bus {
+	return &Bus{
+		sync:  make(map[string][]handler),
+		async: make(map[string][]handler),
+		queue: queue,
+	}
+}
+
+// On subscribes fn to events of type E; it runs in-process during Publish.
+// Keep these fast (cache invalidation, a single insert).
+func On[E Event](b *Bus, name string, fn func(ctx context.Context, e E) error) {
+	eventName, h := typed(name, fn)
+	b.subscribe(b.sync, eventName, h)
+}
+
+// OnAsync subscribes fn to events of type E; it runs later on the job queue,
+// with retries, and never slows down the request that published the event.
+func OnAsync[E Event](b *Bus, name string, fn func(ctx context.Context, e E) error) {
+	eventName, h := typed(name, fn)
+	b.subscribe(b.async, eventName, h)
+}
+
+// typed erases the event type of a subscriber; the zero E names the event.
+func typed[E Event](name string, fn func(ctx context.Context, e E) error) (string, handler) {
+	var zero E
+	return zero.EventName(), handler{
+		name: name,
+		fn:   func(ctx context.Context, e Event) error { return fn(ctx, e.(E)) },
+	}
+}
+
+func (b *Bus) subscribe(into map[string][]handler, eventName string, h handler) {
+	b.mu.Lock()
+	into[eventName] = append(into[eventName], h)
+	b.mu.Unlock()
+}
+
+// Publish delivers e. Subscriber failures are logged, never returned: the
+// change that caused the event is already committed.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	syncHandlers := b.sync[e.EventName()]
+	asyncHandlers := b.async[e.EventName()]
+	b.mu.RUnlock()
+
+	for _, h := range syncHandlers {
+		if err := h.fn(ctx, e); err != nil {
+			logging.Errorf("[Events] %s subscriber %s failed: %v", e.EventName(), h.name, err)
+		}
+	}
+
+	for _, h := range asyncHandlers {
+		h := h
+		job := jobs.Job{
+			Name: e.EventName() + "/" + h.name,
+			Run:  func(ctx context.Context) error { return h.fn(ctx, e) },
+		}
+		if err := b.queue.Enqueue(job); err != nil {
+			logging.Errorf("[Events] Could not queue %s: %v", job.Name, err)
+		}
+	}
+}
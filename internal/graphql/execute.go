@@ -0,0 +1,427 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Schema is the entry point of every query.
+type Schema struct {
+	Query *Object
+}
+
+// Object is a GraphQL object type.
+type Object struct {
+	Name   string
+	Fields map[string]*FieldDef
+}
+
+// FieldDef resolves a field an Object declares; set exactly one of Resolve
+// and Batch. Type is the Object of the value (or of each element of a list);
+// nil leaves the value's fields to its JSON encoding.
+type FieldDef struct {
+	Type    *Object
+	Resolve func(ctx context.Context, parent interface{}, args Args) (interface{}, error)
+
+	// Batch resolves the field for every parent of a level at once, the
+	// dataloader pattern: a list of N products costs one call, not N.
+	// It returns one value per parent, in order.
+	Batch func(ctx context.Context, parents []interface{}, args Args) ([]interface{}, error)
+}
+
+// Request is the body of a GraphQL POST.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response is the result of a query; data is null when the query could not run.
+type Response struct {
+	Data   *OrderedMap `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// Error is one failed field (or the whole query when Path is empty).
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Execute parses and runs req. Parse errors leave Data nil; resolver errors
+// null their field and are listed in Errors.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	ops, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	var op *Operation
+	switch {
+	case req.OperationName != "":
+		for _, o := range ops {
+			if o.Name == req.OperationName {
+				op = o
+			}
+		}
+		if op == nil {
+			return &Response{Errors: []Error{{Message: fmt.Sprintf("unknown operation %q", req.OperationName)}}}
+		}
+	case len(ops) > 1:
+		return &Response{Errors: []Error{{Message: "operationName is required when the document has several operations"}}}
+	default:
+		op = ops[0]
+	}
+
+	vars := map[string]interface{}{}
+	for name, def := range op.Variables {
+		vars[name] = def
+		if v, ok := req.Variables[name]; ok {
+			vars[name] = v
+		}
+	}
+
+	e := &executor{vars: vars}
+	results := e.selectionSet(ctx, s.Query, []interface{}{nil}, op.Selections, nil)
+	if e.cost > MaxCost {
+		return &Response{Errors: []Error{{Message: fmt.Sprintf("the query is too expensive (more than %d fields and objects); select fewer fields or smaller lists", MaxCost)}}}
+	}
+	return &Response{Data: results[0], Errors: e.errors}
+}
+
+type executor struct {
+	vars   map[string]interface{}
+	errors []Error
+	cost   int // fields and objects resolved so far, see MaxCost
+}
+
+// spend adds n to the cost and reports whether the query is still in budget.
+func (e *executor) spend(n int) bool {
+	e.cost += n
+	return e.cost <= MaxCost
+}
+
+func (e *executor) fail(path []interface{}, err error) {
+	e.errors = append(e.errors, Error{Message: err.Error(), Path: path})
+}
+
+// selectionSet resolves fields on every parent of one level; the result has
+// one map per parent. Nested selections of all the parents are resolved
+// together, so Batch fields see the whole level.
+func (e *executor) selectionSet(ctx context.Context, obj *Object, parents []interface{}, fields []*Field, path []interface{}) []*OrderedMap {
+	results := make([]*OrderedMap, len(parents))
+	for i := range results {
+		results[i] = &OrderedMap{}
+	}
+	var jsonParents []map[string]interface{} // parents as JSON, decoded on first use
+
+	for _, f := range fields {
+		if !e.spend(len(parents)) {
+			return results
+		}
+		fieldPath := append(append([]interface{}{}, path...), f.Alias)
+		if f.Name == "__typename" {
+			for _, r := range results {
+				r.Set(f.Alias, objectName(obj))
+			}
+			continue
+		}
+
+		// 1. --- Resolve the Field for Every Parent ---
+		var def *FieldDef
+		if obj != nil {
+			def = obj.Fields[f.Name]
+		}
+		values := make([]interface{}, len(parents))
+		switch {
+		case def != nil:
+			args, err := e.args(f.Args)
+			if err != nil {
+				e.fail(fieldPath, err)
+				break
+			}
+			if def.Batch != nil {
+				batch, err := def.Batch(ctx, parents, args)
+				if err == nil && len(batch) != len(parents) {
+					err = fmt.Errorf("resolver returned %d values for %d parents", len(batch), len(parents))
+				}
+				if err != nil {
+					e.fail(fieldPath, err)
+					break
+				}
+				values = batch
+				break
+			}
+			for i, parent := range parents {
+				v, err := def.Resolve(ctx, parent, args)
+				if err != nil {
+					e.fail(fieldPath, err)
+					continue
+				}
+				values[i] = v
+			}
+		case len(parents) == 1 && parents[0] == nil: // the root has no JSON to fall back to
+			e.fail(fieldPath, fmt.Errorf("unknown field %q on %s", f.Name, objectName(obj)))
+		default:
+			if jsonParents == nil {
+				var err error
+				if jsonParents, err = toJSONMaps(parents); err != nil {
+					e.fail(fieldPath, err)
+					break
+				}
+			}
+			for i, m := range jsonParents {
+				if m != nil {
+					values[i] = m[f.Name]
+				}
+			}
+		}
+
+		// 2. --- Leaves ---
+		var childType *Object
+		if def != nil {
+			childType = def.Type
+		}
+		if len(f.Selections) == 0 {
+			if childType != nil {
+				e.fail(fieldPath, fmt.Errorf("field %q needs a selection of subfields", f.Name))
+				for _, r := range results {
+					r.Set(f.Alias, nil)
+				}
+				continue
+			}
+			for i, r := range results {
+				r.Set(f.Alias, values[i])
+			}
+			continue
+		}
+
+		// 3. --- Nested Selections, One Level for All Parents ---
+		var children []interface{}
+		for _, v := range values {
+			if items, ok := list(v); ok {
+				for _, item := range items {
+					if !isNil(item) {
+						children = append(children, item)
+					}
+				}
+			} else if !isNil(v) {
+				children = append(children, v)
+			}
+		}
+		if !e.spend(len(children)) {
+			return results
+		}
+		nested := e.selectionSet(ctx, childType, children, f.Selections, fieldPath)
+		next := 0
+		for i, v := range values {
+			switch items, ok := list(v); {
+			case ok:
+				out := make([]interface{}, len(items))
+				for j, item := range items {
+					if !isNil(item) {
+						out[j] = nested[next]
+						next++
+					}
+				}
+				results[i].Set(f.Alias, out)
+			case !isNil(v):
+				results[i].Set(f.Alias, nested[next])
+				next++
+			default:
+				results[i].Set(f.Alias, nil)
+			}
+		}
+	}
+	return results
+}
+
+// args replaces variables with the request's values.
+func (e *executor) args(raw map[string]Value) (Args, error) {
+	args := Args{}
+	for name, v := range raw {
+		resolved, err := e.resolve(v)
+		if err != nil {
+			return nil, err
+		}
+		args[name] = resolved
+	}
+	return args, nil
+}
+
+func (e *executor) resolve(v Value) (interface{}, error) {
+	switch v := v.(type) {
+	case Variable:
+		value, ok := e.vars[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not declared", v)
+		}
+		return value, nil
+	case []Value:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			var err error
+			if out[i], err = e.resolve(v[i]); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case map[string]Value:
+		out := make(map[string]interface{}, len(v))
+		for k := range v {
+			var err error
+			if out[k], err = e.resolve(v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// isNil reports whether v is nil or a nil pointer or map.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func objectName(obj *Object) string {
+	if obj == nil {
+		return "Object"
+	}
+	return obj.Name
+}
+
+// list returns the elements of a slice value (nil slices included).
+func list(v interface{}) ([]interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	if items, ok := v.([]interface{}); ok {
+		return items, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, true
+}
+
+// toJSONMaps decodes each parent's JSON encoding; numbers keep their exact
+// text, so money still renders with two decimals.
+func toJSONMaps(parents []interface{}) ([]map[string]interface{}, error) {
+	out := make([]map[string]interface{}, len(parents))
+	for i, p := range parents {
+		if p == nil {
+			continue
+		}
+		if m, ok := p.(map[string]interface{}); ok {
+			out[i] = m
+			continue
+		}
+		b, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			return nil, fmt.Errorf("%T is not an object", p)
+		}
+		out[i] = m
+	}
+	return out, nil
+}
+
+// Args are a field's arguments, variables resolved.
+type Args map[string]interface{}
+
+// String returns the argument as text ("" when absent); numbers are formatted.
+func (a Args) String(name string) string {
+	switch v := a[name].(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+// Int returns the argument as an int, or def when it is absent or null.
+func (a Args) Int(name string, def int) (int, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return def, nil
+	case int64:
+		return int(v), nil
+	case float64: // numbers in the JSON variables
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an integer", name)
+}
+
+// OrderedMap is a JSON object that keeps the order of the selection.
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// Set adds or replaces key.
+func (m *OrderedMap) Set(key string, v interface{}) {
+	if m.values == nil {
+		m.values = map[string]interface{}{}
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = v
+}
+
+// Get returns key's value.
+func (m *OrderedMap) Get(key string) interface{} {
+	return m.values[key]
+}
+
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		v, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
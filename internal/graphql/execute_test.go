@@ -0,0 +1,59 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// listSchema has items(n: Int): a list of n objects, each with items again.
+func listSchema() *Schema {
+	item := &Object{Name: "Item"}
+	items := &FieldDef{Type: item, Resolve: func(_ context.Context, _ interface{}, args Args) (interface{}, error) {
+		n, err := args.Int("n", 1)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]map[string]interface{}, n)
+		for i := range out {
+			out[i] = map[string]interface{}{"id": i}
+		}
+		return out, nil
+	}}
+	item.Fields = map[string]*FieldDef{"items": items}
+	return &Schema{Query: &Object{Name: "Query", Fields: map[string]*FieldDef{"items": items}}}
+}
+
+func TestExecuteCostBudget(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		ok    bool
+	}{
+		{"small list", `{ items(n: 100) { id } }`, true},
+		{"nested lists multiply", `{ items(n: 100) { items(n: 100) { items(n: 100) { id } } } }`, false},
+		{"aliases count", `{ ` + aliases(20) + ` }`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := listSchema().Execute(context.Background(), Request{Query: tt.query})
+			if got := resp.Data != nil; got != tt.ok {
+				t.Fatalf("data returned = %v, want %v (errors %v)", got, tt.ok, resp.Errors)
+			}
+			if !tt.ok && !strings.Contains(resp.Errors[0].Message, "too expensive") {
+				t.Fatalf("error = %q, want the budget error", resp.Errors[0].Message)
+			}
+		})
+	}
+}
+
+// aliases selects a 1000-item list under n different aliases.
+func aliases(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteString("x")
+		b.WriteString(strings.Repeat("y", i))
+		b.WriteString(": items(n: 1000) { id } ")
+	}
+	return b.String()
+}
@@ -0,0 +1,502 @@
+// Package graphql executes the subset of GraphQL the storefront needs:
+// queries (no mutations or subscriptions) with aliases, arguments, variables,
+// nested selection sets and __typename. Fragments, directives and
+// introspection are not supported; clients send plain queries.
+//
+// Fields resolve through a Schema of Objects. A field an Object does not
+// declare is read from the parent value's JSON encoding, so the models' json
+// tags name the fields exactly as the REST API does.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxDepth bounds the nesting of selection sets in one query.
+const MaxDepth = 8
+
+// MaxCost bounds the work of one query at execution, where list sizes are
+// known: every field resolved on every parent costs one, and so does every
+// object a field returns. Aliases are separate fields and cost the same. A
+// query over budget stops and returns no data.
+const MaxCost = 20000
+
+// Field is one selected field.
+type Field struct {
+	Alias      string // the response key; the name when no alias was given
+	Name       string
+	Args       map[string]Value
+	Selections []*Field
+}
+
+// Operation is one query of a document.
+type Operation struct {
+	Name       string
+	Variables  map[string]Value // default values of the declared variables (nil when none)
+	Selections []*Field
+}
+
+// Value is a parsed argument: nil, bool, int64, float64, string, []Value,
+// map[string]Value, or a Variable.
+type Value interface{}
+
+// Variable is a "$name" reference, replaced by the request's variable at execution.
+type Variable string
+
+// Parse reads a query document.
+func Parse(src string) ([]*Operation, error) {
+	p := &parser{lex: lexer{src: strings.TrimPrefix(src, "\ufeff")}}
+	p.next()
+	var ops []*Operation
+	for p.tok.kind != tokEOF {
+		op, err := p.operation()
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("the document has no operation")
+	}
+	return ops, nil
+}
+
+type parser struct {
+	lex   lexer
+	tok   token
+	err   error
+	depth int
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+	if p.err != nil {
+		p.tok = token{kind: tokEOF}
+	}
+}
+
+func (p *parser) fail(format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.err = fmt.Errorf("line %d: %s", p.tok.line, fmt.Sprintf(format, args...))
+	return p.err
+}
+
+// expect consumes the punctuator s.
+func (p *parser) expect(s string) error {
+	if p.tok.kind != tokPunct || p.tok.text != s {
+		return p.fail("expected %q, found %s", s, p.tok)
+	}
+	p.next()
+	return p.err
+}
+
+func (p *parser) is(s string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == s
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.fail("expected a name, found %s", p.tok)
+	}
+	n := p.tok.text
+	p.next()
+	return n, p.err
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{}
+	if p.tok.kind == tokName {
+		switch p.tok.text {
+		case "query":
+			p.next()
+		case "mutation", "subscription":
+			return nil, p.fail("%s operations are not supported", p.tok.text)
+		case "fragment":
+			return nil, p.fail("fragments are not supported")
+		default:
+			return nil, p.fail("unexpected %s", p.tok)
+		}
+		if p.tok.kind == tokName {
+			op.Name, _ = p.name()
+		}
+		if p.is("(") {
+			if err := p.variableDefinitions(op); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if p.is("@") {
+		return nil, p.fail("directives are not supported")
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = sel
+	return op, nil
+}
+
+func (p *parser) variableDefinitions(op *Operation) error {
+	op.Variables = map[string]Value{}
+	p.next()
+	for !p.is(")") {
+		if err := p.expect("$"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		var def Value
+		if p.is("=") {
+			p.next()
+			if def, err = p.value(true); err != nil {
+				return err
+			}
+		}
+		op.Variables[name] = def
+	}
+	p.next()
+	return p.err
+}
+
+// skipType reads a type reference ("ID!", "[String]"); types are not checked.
+func (p *parser) skipType() error {
+	if p.is("[") {
+		p.next()
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.is("!") {
+		p.next()
+	}
+	return p.err
+}
+
+func (p *parser) selectionSet() ([]*Field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	p.depth++
+	if p.depth > MaxDepth {
+		return nil, p.fail("the query is nested more than %d levels deep", MaxDepth)
+	}
+	var fields []*Field
+	for !p.is("}") {
+		if p.is("...") {
+			return nil, p.fail("fragments are not supported")
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, p.fail("empty selection set")
+	}
+	p.depth--
+	p.next()
+	return fields, p.err
+}
+
+func (p *parser) field() (*Field, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f := &Field{Alias: name, Name: name}
+	if p.is(":") {
+		p.next()
+		if f.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is("(") {
+		f.Args = map[string]Value{}
+		p.next()
+		for !p.is(")") {
+			arg, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if f.Args[arg], err = p.value(false); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+	}
+	if p.is("@") {
+		return nil, p.fail("directives are not supported")
+	}
+	if p.is("{") {
+		if f.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, p.err
+}
+
+// value reads an argument value; constant values (variable defaults) cannot
+// reference variables.
+func (p *parser) value(constant bool) (Value, error) {
+	t := p.tok
+	switch {
+	case t.kind == tokPunct && t.text == "$" && !constant:
+		p.next()
+		name, err := p.name()
+		return Variable(name), err
+	case t.kind == tokInt:
+		p.next()
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, p.fail("integer %s out of range", t.text)
+		}
+		return n, p.err
+	case t.kind == tokFloat:
+		p.next()
+		f, _ := strconv.ParseFloat(t.text, 64)
+		return f, p.err
+	case t.kind == tokString:
+		p.next()
+		return t.text, p.err
+	case t.kind == tokName:
+		p.next()
+		switch t.text {
+		case "true":
+			return true, p.err
+		case "false":
+			return false, p.err
+		case "null":
+			return nil, p.err
+		}
+		return t.text, p.err // enum values are passed as strings
+	case t.kind == tokPunct && t.text == "[":
+		p.next()
+		list := []Value{}
+		for !p.is("]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.next()
+		return list, p.err
+	case t.kind == tokPunct && t.text == "{":
+		p.next()
+		obj := map[string]Value{}
+		for !p.is("}") {
+			key, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[key], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+		return obj, p.err
+	}
+	return nil, p.fail("expected a value, found %s", t)
+}
+
+//
+// --- Lexer ---
+//
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of document"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type lexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (l *lexer) next() (token, error) {
+	if l.line == 0 {
+		l.line = 1
+	}
+	// Whitespace, commas and comments are insignificant.
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		if ch == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		}
+		if ch == '\n' {
+			l.line++
+		}
+		if ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r' && ch != ',' {
+			break
+		}
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, line: l.line}, nil
+	}
+
+	start := l.pos
+	ch := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokPunct, text: "...", line: l.line}, nil
+	case strings.ContainsRune("!$():=@[]{}|", rune(ch)):
+		l.pos++
+		return token{kind: tokPunct, text: string(ch), line: l.line}, nil
+	case ch == '_' || isLetter(ch):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokName, text: l.src[start:l.pos], line: l.line}, nil
+	case ch == '-' || isDigit(ch):
+		return l.number()
+	case ch == '"':
+		return l.string()
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, fmt.Errorf("line %d: unexpected character %q", l.line, r)
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() {
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	digits()
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokFloat
+		l.pos++
+		digits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		digits()
+	}
+	text := l.src[start:l.pos]
+	if text == "-" || strings.HasSuffix(text, ".") || strings.HasSuffix(text, "e") || strings.HasSuffix(text, "E") {
+		return token{}, fmt.Errorf("line %d: invalid number %q", l.line, text)
+	}
+	return token{kind: kind, text: text, line: l.line}, nil
+}
+
+func (l *lexer) string() (token, error) {
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		return token{}, fmt.Errorf("line %d: block strings are not supported", l.line)
+	}
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		switch {
+		case ch == '"':
+			l.pos++
+			return token{kind: tokString, text: b.String(), line: l.line}, nil
+		case ch == '\n':
+			return token{}, fmt.Errorf("line %d: unterminated string", l.line)
+		case ch == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, fmt.Errorf("line %d: unterminated string", l.line)
+			}
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, fmt.Errorf("line %d: invalid unicode escape", l.line)
+				}
+				n, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, fmt.Errorf("line %d: invalid unicode escape", l.line)
+				}
+				b.WriteRune(rune(n))
+				l.pos += 4
+			default:
+				return token{}, fmt.Errorf("line %d: invalid escape \\%c", l.line, esc)
+			}
+		default:
+			b.WriteByte(ch)
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("line %d: unterminated string", l.line)
+}
+
+func isLetter(ch byte) bool { return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' }
+func isDigit(ch byte) bool  { return ch >= '0' && ch <= '9' }
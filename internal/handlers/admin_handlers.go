@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/events"
 	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/store"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,76 +21,61 @@ import (
 //
 
 // GetPendingProducts is the handler for GET /v1/manager/products/pending
-// It retrieves all products with the status "pending".
+// It returns one page of the products with the status "pending", oldest
+// first, and how many are waiting in total.
 func (h *Handlers) GetPendingProducts(c *gin.Context) {
-	// 1. --- Build Query ---
-	query := `
-		SELECT 
-			id, supplier_id, sku, name, description, price_to_tts, stock_quantity, 
-			is_variable, status, created_at, updated_at,
-			weight, pkg_length, pkg_width, pkg_height
-		FROM products
-		WHERE status = ?
-		ORDER BY created_at ASC`
-
-	args := []interface{}{"pending"}
-
-	// 2. --- Execute Query ---
-	rows, err := h.DB.Query(query, args...)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+	ctx := c.Request.Context()
+
+	list, ok := parseList(c, pagination.ListSpec{Sorts: []string{"createdAt"}})
+	if !ok {
 		return
 	}
-	defer rows.Close()
+	page := list.Page
 
-	// 3. --- Scan Rows into Slice ---
-	var products []*models.Product
-	for rows.Next() {
-		var product models.Product
-		// [FIX] We scan directly into the struct.
-		// Since models.Product now uses *float64 for Weight/Dimensions,
-		// rows.Scan handles NULLs automatically (setting the pointer to nil).
-		if err := rows.Scan(
-			&product.ID,
-			&product.SupplierID,
-			&product.SKU,
-			&product.Name,
-			&product.Description,
-			&product.PriceToTTS,
-			&product.StockQuantity,
-			&product.IsVariable,
-			&product.Status,
-			&product.CreatedAt,
-			&product.UpdatedAt,
-			&product.Weight,
-			&product.PkgLength,
-			&product.PkgWidth,
-			&product.PkgHeight,
-		); err != nil {
-			fmt.Printf("Scan Error: %v\n", err) // Log scan errors to console
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan product row"})
-			return
-		}
-		products = append(products, &product)
+	// 1. --- Load One Page of the Review Queue (oldest first) ---
+	products, err := h.Store.Products.ListByStatus(ctx, "pending", page)
+	if err != nil {
+		apierror.Internal(c, "Database query failed")
+		return
 	}
-	if err = rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error iterating product rows"})
+	products, nextCursor := pagination.Paginate(page, products, productCursor)
+	total, err := h.Store.Products.CountByStatus(ctx, "pending")
+	if err != nil {
+		apierror.Internal(c, "Database query failed")
 		return
 	}
 
-	// 4. --- Send Success Response ---
+	// 2. --- Attach Categories, Brands & Variants ---
+	// One query per relation for the whole queue (avoids N+1).
+	if err := h.Store.Products.LoadRelations(ctx, products); err != nil {
+		apierror.Internal(c, "Failed to load product relations")
+		return
+	}
+
+	// 3. --- Send Success Response ---
+	if products == nil {
+		products = []*models.Product{}
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"products": products,
+		"products":   products,
+		"total":      total,
+		"nextCursor": nextCursor,
 	})
 }
 
 // ApproveProduct is the handler for PATCH /v1/manager/products/:id/approve
 func (h *Handlers) ApproveProduct(c *gin.Context) {
-	productIDStr := c.Param("id")
+	ctx := c.Request.Context()
 
-	tx, err := h.DB.Begin()
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.NotFound(c, "Product not found or not pending")
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
@@ -93,59 +84,68 @@ func (h *Handlers) ApproveProduct(c *gin.Context) {
 	var productName string
 	// Step 1: Get data and lock row.
 	// Note: We check for 'pending' in the query to match your current handler logic.
-	err = tx.QueryRow("SELECT supplier_id, name FROM products WHERE id = ? AND status = 'pending' FOR UPDATE", productIDStr).Scan(&supplierID, &productName)
+	err = tx.QueryRowContext(ctx, "SELECT supplier_id, name FROM products WHERE id = ? AND status = 'pending' AND deleted_at IS NULL FOR UPDATE", productID).Scan(&supplierID, &productName)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found or not pending"})
+			apierror.NotFound(c, "Product not found or not pending")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		apierror.Internal(c, "Database error")
 		return
 	}
 
 	// Step 2: Update status to 'active' (Matches your SQL ENUM)
-	query := `UPDATE products SET status = 'active', updated_at = NOW() WHERE id = ?`
-	_, err = tx.Exec(query, productIDStr)
+	// The reason of an earlier rejection no longer applies; it stays in the revisions.
+	query := `UPDATE products SET status = 'active', rejection_reason = NULL, updated_at = NOW(), version = version + 1 WHERE id = ?`
+	_, err = tx.ExecContext(ctx, query, productID)
 	if err != nil {
 		fmt.Printf("SQL Error: %v\n", err) // This will now show the ENUM mismatch if it persisted
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+		apierror.Internal(c, "Failed to update status")
 		return
 	}
 
-	// Step 3: Notification
-	message := fmt.Sprintf("Your product \"%s\" has been approved!", productName)
-	if err := h.AddNotification(tx, supplierID, message, "/supplier/products"); err != nil {
-		fmt.Printf("Notification Error: %v\n", err)
-	}
-
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Commit failed"})
+		apierror.Internal(c, "Commit failed")
 		return
 	}
 
+	// Step 3: Side effects (notification, cache, channel re-sync) via subscribers
+	h.Events.Publish(ctx, events.ProductApproved{ProductID: productID, SupplierID: supplierID, ProductName: productName})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Product approved successfully"})
 }
 
 // RejectProductInput defines the JSON input for rejecting a product.
 type RejectProductInput struct {
-	Reason string `json:"reason" binding:"required"`
+	Reason string `json:"reason" binding:"required,max=500"`
 }
 
 // RejectProduct is the handler for PATCH /v1/manager/products/:id/reject
+// The reason is kept on the product for the supplier (until approval) and in
+// its revision history; the supplier fixes the product and resubmits it.
 func (h *Handlers) RejectProduct(c *gin.Context) {
-	productIDStr := c.Param("id")
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found or was not pending approval")
+		return
+	}
 
 	// 1. --- Bind & Validate JSON ---
 	var input RejectProductInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
 	// 2. --- Begin Transaction ---
-	tx, err := h.DB.Begin()
+	tx, err := h.Store.Begin(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
@@ -153,25 +153,34 @@ func (h *Handlers) RejectProduct(c *gin.Context) {
 	// 3. --- Get Product Info ---
 	var supplierID int64
 	var productName string
-	err = tx.QueryRow("SELECT supplier_id, name FROM products WHERE id = ? AND status = 'pending' FOR UPDATE", productIDStr).Scan(&supplierID, &productName)
+	err = tx.QueryRowContext(ctx, "SELECT supplier_id, name FROM products WHERE id = ? AND status = 'pending' AND deleted_at IS NULL FOR UPDATE", productID).Scan(&supplierID, &productName)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found or was not pending approval"})
+			apierror.NotFound(c, "Product not found or was not pending approval")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get product details"})
+		apierror.Internal(c, "Failed to get product details")
+		return
+	}
+	before, err := tx.Products.Snapshot(ctx, productID)
+	if err != nil {
+		apierror.Internal(c, "Failed to get product details")
 		return
 	}
 
 	// 4. --- Update Database ---
 	query := `
 		UPDATE products
-		SET status = ?, updated_at = ?
+		SET status = ?, rejection_reason = ?, updated_at = ?, version = version + 1
 		WHERE id = ? AND status = ?`
 
-	_, err = tx.Exec(query, "rejected", time.Now(), productIDStr, "pending")
+	_, err = tx.ExecContext(ctx, query, "rejected", input.Reason, time.Now(), productID, "pending")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject product"})
+		apierror.Internal(c, "Failed to reject product")
+		return
+	}
+	if err := h.recordRevision(ctx, tx, productID, managerID, "reject", nil, &input.Reason, before); err != nil {
+		apierror.Internal(c, "Failed to record revision")
 		return
 	}
 
@@ -179,23 +188,69 @@ func (h *Handlers) RejectProduct(c *gin.Context) {
 	message := fmt.Sprintf("Your product \"%s\" was rejected. Reason: %s", productName, input.Reason)
 	link := fmt.Sprintf("/supplier/products")
 
-	if err := h.AddNotification(tx, supplierID, message, link); err != nil {
+	if err := h.AddBatchedNotification(ctx, tx, supplierID, "product_rejected", message, link); err != nil {
 		fmt.Printf("RejectProduct Notification Error: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+		apierror.Internal(c, "Failed to send notification")
 		return
 	}
 
 	// 6. --- Commit Transaction ---
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		apierror.Internal(c, "Failed to commit transaction")
 		return
 	}
+	h.invalidateProducts(ctx, productID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Product rejected successfully",
 	})
 }
 
+// UpdatePendingProduct is the handler for PUT /v1/manager/products/:id
+// A manager fixes a pending product before approving it (a typo, a wrong
+// category) with the same fields as the supplier's edit. The change lands in
+// the product's revision history and the supplier is notified. The status
+// itself only changes through approve and reject.
+func (h *Handlers) UpdatePendingProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found or not pending")
+		return
+	}
+
+	// 1. --- Bind & Validate JSON ---
+	var input UpdateProductInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if input.Status != nil {
+		apierror.BadRequest(c, "Approve or reject the product to change its status")
+		return
+	}
+
+	// 2. --- Load the Pending Product ---
+	// Approving or rejecting bumps the version, so the update below fails
+	// with 409 if the review finished in the meantime.
+	product, err := h.Store.Products.Get(ctx, productID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		apierror.Internal(c, "Database error")
+		return
+	}
+	if err != nil || product.Status != "pending" {
+		apierror.NotFound(c, "Product not found or not pending")
+		return
+	}
+
+	// 3. --- Apply (records the revision and notifies the supplier) ---
+	h.updateProduct(c, product, managerID, &input)
+}
+
 // ... (GetSettings and UpdateSettings remain unchanged) ...
 // You can keep the existing code for Settings below this point.
 //
@@ -211,11 +266,13 @@ type Setting struct {
 
 // GetSettings is the handler for GET /v1/manager/settings
 func (h *Handlers) GetSettings(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	query := "SELECT setting_key, setting_value, description FROM settings"
 
-	rows, err := h.DB.Query(query)
+	rows, err := h.DB.QueryContext(ctx, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		apierror.Internal(c, "Database query failed")
 		return
 	}
 	defer rows.Close()
@@ -225,7 +282,7 @@ func (h *Handlers) GetSettings(c *gin.Context) {
 		var s Setting
 		var desc sql.NullString
 		if err := rows.Scan(&s.Key, &s.Value, &desc); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan setting row"})
+			apierror.Internal(c, "Failed to scan setting row")
 			return
 		}
 		s.Description = desc.String
@@ -243,20 +300,22 @@ type UpdateSettingsInput struct {
 
 // UpdateSettings is the handler for PATCH /v1/manager/settings
 func (h *Handlers) UpdateSettings(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var input UpdateSettingsInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
 	if len(input.Settings) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No settings provided to update"})
+		apierror.BadRequest(c, "No settings provided to update")
 		return
 	}
 
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
@@ -266,24 +325,25 @@ func (h *Handlers) UpdateSettings(c *gin.Context) {
 		VALUES (?, ?)
 		ON DUPLICATE KEY UPDATE setting_value = VALUES(setting_value)
 	`
-	stmt, err := tx.Prepare(query)
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare update statement"})
+		apierror.Internal(c, "Failed to prepare update statement")
 		return
 	}
 	defer stmt.Close()
 
 	for key, value := range input.Settings {
-		if _, err := stmt.Exec(key, value); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update setting: %s", key)})
+		if _, err := stmt.ExecContext(ctx, key, value); err != nil {
+			apierror.Internal(c, fmt.Sprintf("Failed to update setting: %s", key))
 			return
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		apierror.Internal(c, "Failed to commit transaction")
 		return
 	}
+	h.Settings.Invalidate(ctx)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Settings updated successfully",
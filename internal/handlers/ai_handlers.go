@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/01moynul/taptosell-golang/internal/dto"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,83 +21,164 @@ type ChatInput struct {
 
 // ChatAI handles the interaction with the AI Assistant.
 func (h *Handlers) ChatAI(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. Get User Context
-	userID, exists := c.Get("userID")
+	userID_raw, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		apierror.Unauthorized(c, "Unauthorized")
 		return
 	}
+	userID := userID_raw.(int64)
 	role, _ := c.Get("userRole")
 	userRole := role.(string)
 
 	// 2. Parse Input
 	var input ChatInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
-	// 3. Get AI Settings (Model & Price) from DB
-	// We fetch them live so the Admin can change them instantly.
-	var modelName string
-	var pricePer1kStr string
+	// 2b. Moderation (staff are exempt, as from the quota below)
+	// A suspended user is turned away, and a blocked message never reaches
+	// the model; it counts as a violation instead of a request.
+	capped := userRole != "manager" && userRole != "administrator"
+	rules := h.moderationRules()
+	var violations int
+	if capped {
+		suspendedUntil, n, err := h.aiStanding(ctx, userID)
+		if err != nil {
+			apierror.Internal(c, "Failed to check AI quota")
+			return
+		}
+		if suspendedUntil != nil {
+			apierror.Forbidden(c, fmt.Sprintf("Your access to the AI assistant is suspended until %s.", suspendedUntil.UTC().Format(time.RFC3339)))
+			return
+		}
+		if v := rules.CheckInput(input.Message); v != nil {
+			if err := h.recordAIViolation(ctx, userID, "input", input.Message, v); err != nil {
+				fmt.Printf("Failed to record AI violation for user %d: %v\n", userID, err)
+			}
+			apierror.BadRequest(c, "This message was blocked by moderation. Repeated attempts limit your access to the assistant.")
+			return
+		}
+		violations = n
+	}
+
+	// 3. Daily Quota (staff are not capped; recent violations shrink it)
+	now := time.Now()
+	day := aiDay(now)
+	var quota aiQuota
+	if capped {
+		var err error
+		quota, err = h.aiQuotaFor(ctx, userID)
+		if err != nil {
+			apierror.Internal(c, "Failed to check AI quota")
+			return
+		}
+		quota = h.throttleAIQuota(quota, violations)
+		ok, err := h.reserveAIRequest(ctx, userID, quota, day)
+		if err != nil {
+			apierror.Internal(c, "Failed to check AI quota")
+			return
+		}
+		if !ok {
+			h.setAIQuotaHeaders(c, userID, quota, now)
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(aiDayEnd(now)).Seconds())+1))
+			apierror.QuotaExceeded(c, "You have used up today's AI chat allowance. It resets at midnight.")
+			return
+		}
+	}
 
+	// 4. Get AI Settings (Model & Price) from DB
+	// Read through the settings cache, which UpdateSettings invalidates.
 	// Fetch Model
-	err := h.DB.QueryRow("SELECT setting_value FROM settings WHERE setting_key = 'ai_model'").Scan(&modelName)
+	modelName, err := h.Settings.Get(ctx, "ai_model")
 	if err != nil {
 		modelName = "gemini-1.5-flash" // Default fallback
 	}
 
 	// Fetch Price
-	err = h.DB.QueryRow("SELECT setting_value FROM settings WHERE setting_key = 'ai_price_per_1k_tokens'").Scan(&pricePer1kStr)
+	pricePer1kStr, err := h.Settings.Get(ctx, "ai_price_per_1k_tokens")
 	if err != nil {
 		pricePer1kStr = "0.00" // Default fallback
 	}
 	pricePer1k, _ := strconv.ParseFloat(pricePer1kStr, 64)
 
-	// 4. Call the AI Service
+	// 5. Call the AI Service
 	aiResponse, tokenCount, err := h.AIService.GenerateResponse(c.Request.Context(), input.Message, userRole, modelName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "AI Service unavailable: " + err.Error()})
+		if capped {
+			h.releaseAIRequest(context.WithoutCancel(ctx), userID, day)
+		}
+		apierror.Internal(c, "AI Service unavailable: "+err.Error())
 		return
 	}
 
-	// 5. Calculate Cost
+	// Replies are plain text (markdown at most): markup a prompt coaxed out of the
+	// model is stripped before it is stored or shown.
+	aiResponse = sanitize.Text(aiResponse)
+
+	// A reply that leaks credentials or other users' data is withheld.
+	if capped {
+		if v := rules.CheckOutput(aiResponse); v != nil {
+			if err := h.recordAIViolation(context.WithoutCancel(ctx), userID, "output", aiResponse, v); err != nil {
+				fmt.Printf("Failed to record AI violation for user %d: %v\n", userID, err)
+			}
+			aiResponse = aiWithheldReply
+		}
+	}
+
+	// 6. Calculate Cost
 	// Formula: (Tokens Used / 1000) * Price Per 1k
 	cost := (float64(tokenCount) / 1000.0) * pricePer1k
 
-	// 6. Transaction: Deduct Credit & Save History
-	tx, err := h.DB.Begin()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction failed"})
+	// 7. Transaction: Deduct Credit, Count Tokens & Save History
+	// The reply is sent even if this fails: the model already answered.
+	if err := h.recordChat(ctx, userID, userRole, day, capped, input.Message, aiResponse, tokenCount, cost); err != nil {
+		fmt.Printf("Failed to record AI chat: %v\n", err)
+	}
+	if capped {
+		h.setAIQuotaHeaders(c, userID, quota, now)
+	}
+
+	// 8. Return Response
+	if apiversion.From(c) >= apiversion.V2 {
+		c.JSON(http.StatusOK, dto.ChatReply{Response: aiResponse, TokensUsed: tokenCount, CostIncurred: fmt.Sprintf("%.4f", cost)})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"response":      aiResponse,
+		"tokens_used":   tokenCount,
+		"cost_incurred": fmt.Sprintf("%.4f", cost), // Send back cost so UI can show "You spent RM 0.002"
+	})
+}
 
-	// A. Deduct Credits
-	_, err = tx.Exec("UPDATE ai_user_credits SET credits_remaining = credits_remaining - ? WHERE user_id = ?", cost, userID)
+// recordChat deducts the cost of a chat from the user's credits (going
+// negative when they run out mid-chat), adds its tokens to today's usage when
+// capped, and saves it to the history.
+func (h *Handlers) recordChat(ctx context.Context, userID int64, userRole, day string, capped bool, message, reply string, tokens int, cost float64) error {
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		tx.Rollback()
-		// Note: If they run out mid-chat, they go negative. That is acceptable for now.
-		fmt.Printf("Failed to deduct credits: %v\n", err)
+		return err
 	}
+	defer tx.Rollback()
 
-	// B. Save History
+	if _, err := tx.ExecContext(ctx, "UPDATE ai_user_credits SET credits_remaining = credits_remaining - ? WHERE user_id = ?", cost, userID); err != nil {
+		return fmt.Errorf("deduct credits: %w", err)
+	}
+	if capped {
+		if _, err := tx.ExecContext(ctx, "UPDATE ai_usage_daily SET tokens = tokens + ? WHERE user_id = ? AND day = ?", tokens, userID, day); err != nil {
+			return fmt.Errorf("count tokens: %w", err)
+		}
+	}
 	query := `
 		INSERT INTO ai_chat_history (user_id, user_role, user_message, ai_response, tokens_used, cost_incurred)
 		VALUES (?, ?, ?, ?, ?, ?)
 	`
-	_, err = tx.Exec(query, userID, userRole, input.Message, aiResponse, tokenCount, cost)
-	if err != nil {
-		tx.Rollback()
-		fmt.Printf("Failed to save history: %v\n", err)
-	} else {
-		tx.Commit()
+	if _, err := tx.ExecContext(ctx, query, userID, userRole, message, reply, tokens, cost); err != nil {
+		return fmt.Errorf("save history: %w", err)
 	}
-
-	// 7. Return Response
-	c.JSON(http.StatusOK, gin.H{
-		"response":      aiResponse,
-		"tokens_used":   tokenCount,
-		"cost_incurred": fmt.Sprintf("%.4f", cost), // Send back cost so UI can show "You spent RM 0.002"
-	})
+	return tx.Commit()
 }
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/moderation"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- AI Chat Moderation ---
+//
+// Messages and replies of the AI assistant go through package moderation.
+// A refused message or withheld reply is logged as a violation. Within a
+// day, AI_THROTTLE_AFTER violations drop the account's daily request cap to
+// AI_THROTTLED_DAILY_REQUESTS, and AI_SUSPEND_AFTER suspend its AI access for
+// AI_SUSPENSION (a manager can lift it). Staff chats are not moderated.
+
+// aiViolationWindow is how far back violations count against an account.
+const aiViolationWindow = 24 * time.Hour
+
+// maxExcerptRunes bounds what a violation keeps of the message or reply.
+const maxExcerptRunes = 500
+
+// aiWithheldReply replaces a reply that failed moderation.
+const aiWithheldReply = "Sorry, I can't share that information."
+
+// moderationRules are the deployment's moderation settings.
+func (h *Handlers) moderationRules() moderation.Rules {
+	return moderation.Rules{Topics: h.Config.AI.BlockedTopics}
+}
+
+// aiStanding is what past violations cost the user right now: the end of a
+// running suspension, and the violations that count (see countAIViolations).
+func (h *Handlers) aiStanding(ctx context.Context, userID int64) (suspendedUntil *time.Time, violations int, err error) {
+	var until sql.NullTime
+	if err := h.DB.QueryRowContext(ctx, "SELECT ai_suspended_until FROM users WHERE id = ?", userID).Scan(&until); err != nil {
+		return nil, 0, err
+	}
+	if until.Valid && until.Time.After(time.Now()) {
+		suspendedUntil = &until.Time
+	}
+	violations, err = countAIViolations(ctx, h.DB, userID, time.Now())
+	return suspendedUntil, violations, err
+}
+
+// countAIViolations counts the user's violations within aiViolationWindow of
+// now, after their last suspension ended (or was lifted): a suspension
+// settles the violations behind it.
+func countAIViolations(ctx context.Context, q Querier, userID int64, now time.Time) (int, error) {
+	var n int
+	err := q.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM ai_moderation_events e JOIN users u ON u.id = e.user_id
+		WHERE e.user_id = ? AND e.created_at >= ? AND (u.ai_suspended_until IS NULL OR e.created_at >= u.ai_suspended_until)`,
+		userID, now.Add(-aiViolationWindow)).Scan(&n)
+	return n, err
+}
+
+// throttleAIQuota lowers quota for a user with violations, once they reach AI_THROTTLE_AFTER.
+func (h *Handlers) throttleAIQuota(quota aiQuota, violations int) aiQuota {
+	limit := h.Config.AI.ThrottledRequests
+	if violations >= h.Config.AI.ThrottleAfter && (quota.Requests == 0 || quota.Requests > limit) {
+		quota.Requests = limit
+	}
+	return quota
+}
+
+// recordAIViolation logs a violation in text (the message for direction
+// "input", the reply for "output") and suspends the user's AI access once
+// they reach AI_SUSPEND_AFTER.
+func (h *Handlers) recordAIViolation(ctx context.Context, userID int64, direction, text string, v *moderation.Violation) error {
+	now := time.Now()
+	excerpt := text
+	if utf8.RuneCountInString(excerpt) > maxExcerptRunes {
+		excerpt = string([]rune(excerpt)[:maxExcerptRunes])
+	}
+	logging.Warnf("[AI] Moderation: User %d, %s %s (%s)", userID, direction, v.Category, v.Rule)
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO ai_moderation_events (user_id, direction, category, rule, excerpt, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, userID, direction, v.Category, v.Rule, excerpt, now); err != nil {
+		return err
+	}
+	violations, err := countAIViolations(ctx, tx, userID, now)
+	if err != nil {
+		return err
+	}
+	if violations == h.Config.AI.SuspendAfter {
+		until := now.Add(h.Config.AI.Suspension)
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET ai_suspended_until = ? WHERE id = ?", until, userID); err != nil {
+			return err
+		}
+		message := fmt.Sprintf("Your access to the AI assistant is suspended until %s after repeated policy violations.", until.UTC().Format(time.RFC3339))
+		if err := h.AddNotification(ctx, tx, userID, message, "/ai"); err != nil {
+			return err
+		}
+		logging.Warnf("[AI] Suspended User %d until %s", userID, until.UTC().Format(time.RFC3339))
+	}
+	return tx.Commit()
+}
+
+//
+// --- Manager: Violations & Suspensions ---
+//
+
+// aiViolationCursor is the pagination key for the violation log.
+func aiViolationCursor(e models.AIModerationEvent) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: e.CreatedAt, ID: e.ID}
+}
+
+// GetAIViolations is the handler for GET /v1/manager/ai/violations
+// It lists moderation violations, newest first; filter[userId] narrows them
+// to one account.
+func (h *Handlers) GetAIViolations(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"userId"}})
+	if !ok {
+		return
+	}
+	where, args := " WHERE 1 = 1", []interface{}{}
+	if raw := list.Filter("userId"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 1 {
+			apierror.BadRequest(c, "userId must be a number")
+			return
+		}
+		where, args = where+" AND e.user_id = ?", append(args, id)
+	}
+
+	page := list.Page
+	cursorCond, cursorArgs := page.Where("e.created_at", "e.id")
+	query := `
+		SELECT e.id, e.user_id, e.direction, e.category, e.rule, e.excerpt, e.created_at,
+			u.full_name, u.email, u.ai_suspended_until
+		FROM ai_moderation_events e JOIN users u ON u.id = e.user_id` +
+		where + cursorCond + page.OrderLimit("e.created_at", "e.id")
+	rows, err := h.DB.QueryContext(ctx, query, append(args, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch AI violations")
+		return
+	}
+	defer rows.Close()
+
+	events := []models.AIModerationEvent{}
+	for rows.Next() {
+		var e models.AIModerationEvent
+		var until sql.NullTime
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Direction, &e.Category, &e.Rule, &e.Excerpt, &e.CreatedAt,
+			&e.UserName, &e.UserEmail, &until); err != nil {
+			apierror.Internal(c, "Failed to scan AI violation")
+			return
+		}
+		if until.Valid && until.Time.After(time.Now()) {
+			e.AISuspendedUntil = &until.Time
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	events, nextCursor := pagination.Paginate(page, events, aiViolationCursor)
+	c.JSON(http.StatusOK, gin.H{"violations": events, "nextCursor": nextCursor})
+}
+
+// LiftAISuspension is the handler for DELETE /v1/manager/users/:id/ai-suspension
+// It ends the user's AI suspension now. Their violations stay in the log
+// but, as after any suspension, no longer count against them.
+func (h *Handlers) LiftAISuspension(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var until sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT id, ai_suspended_until FROM users WHERE id = ? AND deleted_at IS NULL FOR UPDATE", id).Scan(&userID, &until)
+	if err == sql.ErrNoRows {
+		apierror.NotFound(c, "User not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "DB error")
+		return
+	}
+	if !until.Valid || !until.Time.After(time.Now()) {
+		apierror.Conflict(c, "The user's AI access is not suspended")
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET ai_suspended_until = ? WHERE id = ?", time.Now(), userID); err != nil {
+		apierror.Internal(c, "Failed to lift suspension")
+		return
+	}
+	if err := h.AddNotification(ctx, tx, userID, "Your access to the AI assistant has been restored.", "/ai"); err != nil {
+		apierror.Internal(c, "Failed to notify user")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "AI suspension lifted"})
+}
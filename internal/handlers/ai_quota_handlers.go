@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- AI Chat Quotas ---
+//
+// Every chat counts against the user's daily allowance: a number of requests
+// and of tokens, set per subscription plan (AI_FREE_DAILY_* without one; 0 or
+// NULL is no cap). Days are server days; the background worker deletes the
+// counters of ended days. Staff are not capped.
+
+// aiQuota is a daily allowance; 0 is no cap.
+type aiQuota struct {
+	Requests, Tokens int
+}
+
+// aiDay is the usage day of t, as stored in ai_usage_daily.day.
+func aiDay(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// aiQuotaFor is the allowance of the user's active plan, or the free one.
+func (h *Handlers) aiQuotaFor(ctx context.Context, userID int64) (aiQuota, error) {
+	var requests, tokens sql.NullInt64
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT p.ai_daily_requests, p.ai_daily_tokens
+		FROM user_subscriptions s JOIN plans p ON p.id = s.plan_id
+		WHERE s.user_id = ? AND s.status = 'active' AND s.expires_at > ?`,
+		userID, time.Now()).Scan(&requests, &tokens)
+	if errors.Is(err, sql.ErrNoRows) {
+		return aiQuota{Requests: h.Config.AI.FreeDailyRequests, Tokens: h.Config.AI.FreeDailyTokens}, nil
+	}
+	if err != nil {
+		return aiQuota{}, err
+	}
+	return aiQuota{Requests: int(requests.Int64), Tokens: int(tokens.Int64)}, nil
+}
+
+// reserveAIRequest counts one more request of the user today, unless that
+// would exceed quota; ok is false when the allowance is used up.
+func (h *Handlers) reserveAIRequest(ctx context.Context, userID int64, quota aiQuota, day string) (bool, error) {
+	if _, err := h.DB.ExecContext(ctx,
+		"INSERT IGNORE INTO ai_usage_daily (user_id, day, requests, tokens) VALUES (?, ?, 0, 0)", userID, day); err != nil {
+		return false, err
+	}
+	res, err := h.DB.ExecContext(ctx, `
+		UPDATE ai_usage_daily SET requests = requests + 1
+		WHERE user_id = ? AND day = ? AND (? = 0 OR requests < ?) AND (? = 0 OR tokens < ?)`,
+		userID, day, quota.Requests, quota.Requests, quota.Tokens, quota.Tokens)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// releaseAIRequest gives back a request reserved for a chat that failed.
+func (h *Handlers) releaseAIRequest(ctx context.Context, userID int64, day string) {
+	if _, err := h.DB.ExecContext(ctx,
+		"UPDATE ai_usage_daily SET requests = requests - 1 WHERE user_id = ? AND day = ? AND requests > 0", userID, day); err != nil {
+		logging.Errorf("[AI] Failed to release the request of User %d: %v", userID, err)
+	}
+}
+
+// setAIQuotaHeaders reports what is left of the allowance today:
+// X-AI-Requests-Limit/-Remaining and X-AI-Tokens-Limit/-Remaining for the
+// capped ones, and X-AI-Quota-Reset, when the day ends (RFC 3339).
+func (h *Handlers) setAIQuotaHeaders(c *gin.Context, userID int64, quota aiQuota, now time.Time) {
+	var requests, tokens int
+	err := h.DB.QueryRowContext(c.Request.Context(),
+		"SELECT requests, tokens FROM ai_usage_daily WHERE user_id = ? AND day = ?", userID, aiDay(now)).Scan(&requests, &tokens)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logging.Errorf("[AI] Failed to read the usage of User %d: %v", userID, err)
+		return
+	}
+	for _, q := range []struct {
+		name        string
+		limit, used int
+	}{
+		{"Requests", quota.Requests, requests},
+		{"Tokens", quota.Tokens, tokens},
+	} {
+		if q.limit > 0 {
+			c.Header("X-AI-"+q.name+"-Limit", strconv.Itoa(q.limit))
+			c.Header("X-AI-"+q.name+"-Remaining", strconv.Itoa(max(q.limit-q.used, 0)))
+		}
+	}
+	c.Header("X-AI-Quota-Reset", aiDayEnd(now).UTC().Format(time.RFC3339))
+}
+
+// aiDayEnd is the start of the usage day after t.
+func aiDayEnd(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+}
+
+// ProcessAIQuotas deletes the AI chat counters of the days that have ended,
+// which starts everyone's allowance afresh. The background worker calls it
+// at midnight.
+func (h *Handlers) ProcessAIQuotas(ctx context.Context) {
+	res, err := h.DB.ExecContext(ctx, "DELETE FROM ai_usage_daily WHERE day < ?", aiDay(time.Now()))
+	if err != nil {
+		logging.Errorf("[AI] Error resetting chat quotas: %v", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		logging.Infof("[AI] Reset %d daily chat counters", n)
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Admin: Backup Status ---
+//
+
+const backupColumns = "id, status, target, object_name, size_bytes, error, started_at, finished_at"
+
+func scanBackup(row interface{ Scan(...interface{}) error }, b *models.Backup) error {
+	return row.Scan(&b.ID, &b.Status, &b.Target, &b.ObjectName, &b.SizeBytes, &b.Error, &b.StartedAt, &b.FinishedAt)
+}
+
+// GetBackupStatus is the handler for GET /v1/admin/backups
+// It reports the latest run, the latest successful run and the last 10 runs.
+func (h *Handlers) GetBackupStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Latest Run & Latest Success ---
+	last, err := h.findBackup(ctx, "SELECT "+backupColumns+" FROM backups ORDER BY started_at DESC, id DESC LIMIT 1")
+	if err != nil {
+		apierror.Internal(c, "Failed to load backup status")
+		return
+	}
+	lastSuccess, err := h.findBackup(ctx, "SELECT "+backupColumns+" FROM backups WHERE status = 'succeeded' ORDER BY started_at DESC, id DESC LIMIT 1")
+	if err != nil {
+		apierror.Internal(c, "Failed to load backup status")
+		return
+	}
+
+	// 2. --- Recent History ---
+	rows, err := h.DB.QueryContext(ctx, "SELECT "+backupColumns+" FROM backups ORDER BY started_at DESC, id DESC LIMIT 10")
+	if err != nil {
+		apierror.Internal(c, "Failed to load backup history")
+		return
+	}
+	defer rows.Close()
+	recent := []models.Backup{}
+	for rows.Next() {
+		var b models.Backup
+		if err := scanBackup(rows, &b); err != nil {
+			apierror.Internal(c, "Failed to read backup history")
+			return
+		}
+		recent = append(recent, b)
+	}
+
+	// 3. --- Send Response ---
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":     h.Config.Backup.Target != "",
+		"schedule":    gin.H{"hour": h.Config.Backup.Hour, "keep": h.Config.Backup.Keep},
+		"last":        last,
+		"lastSuccess": lastSuccess,
+		"recent":      recent,
+	})
+}
+
+// findBackup returns the single row of query, or nil when there is none.
+func (h *Handlers) findBackup(ctx context.Context, query string) (*models.Backup, error) {
+	var b models.Backup
+	err := scanBackup(h.DB.QueryRowContext(ctx, query), &b)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
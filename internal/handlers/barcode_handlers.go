@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/barcode"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Barcodes ---
+//
+// Simple products and variants may carry an EAN/UPC barcode (see package
+// barcode). Like SKUs, a supplier's barcodes are unique across their products
+// and variants; the same barcode at two suppliers is the same item, which is
+// what the lookup is for.
+
+// maxBarcodeMatches bounds GET /v1/products/lookup.
+const maxBarcodeMatches = 20
+
+// parseBarcodes normalizes the barcodes sent for a simple product or its
+// variants in place, and rejects those failing the check digit. Blank ones
+// stay blank.
+func parseBarcodes(simple *SimpleProductInput, variants []VariantInput) error {
+	codes := make([]*string, 0, len(variants)+1)
+	if simple != nil {
+		codes = append(codes, simple.Barcode)
+	}
+	for i := range variants {
+		codes = append(codes, variants[i].Barcode)
+	}
+	for _, code := range codes {
+		if code == nil || strings.TrimSpace(*code) == "" {
+			continue
+		}
+		parsed, err := barcode.Parse(*code)
+		if err != nil {
+			return fmt.Errorf("Invalid barcode %q: %s", *code, err)
+		}
+		*code = parsed
+	}
+	return nil
+}
+
+// checkBarcodes checks the barcodes of one product save (parsed, blank or nil
+// for none) against each other and the supplier's other products and
+// variants; productID is the product being edited (0 on create). tx must hold
+// LockSKUs, which assignSKUs takes.
+func checkBarcodes(ctx context.Context, tx *store.Tx, supplierID, productID int64, codes []*string) error {
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if code == nil || *code == "" {
+			continue
+		}
+		forms := barcode.Forms(*code)
+		gtin := forms[len(forms)-1] // the 14-digit form
+		if seen[gtin] {
+			return &skuConflictError{message: fmt.Sprintf("Barcode %s is used more than once in this product.", *code), barcode: true}
+		}
+		seen[gtin] = true
+		use, err := tx.Products.FindBarcode(ctx, supplierID, forms, productID)
+		if err == nil {
+			return &skuConflictError{message: fmt.Sprintf("Barcode %s is already used by your product \"%s\".", *code, use.ProductName), barcode: true}
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameBarcode compares two optional barcodes; nil and "" are both none.
+func sameBarcode(a, b *string) bool {
+	var x, y string
+	if a != nil {
+		x = *a
+	}
+	if b != nil {
+		y = *b
+	}
+	return x == y
+}
+
+// BarcodeMatch is a catalogue product found by its barcode; VariantID is the
+// variant carrying it on a variable product.
+type BarcodeMatch struct {
+	Product   *models.CatalogueProduct `json:"product"`
+	VariantID *int64                   `json:"variantId,omitempty"`
+}
+
+// LookupBarcode is the handler for GET /v1/products/lookup
+// It finds the catalogue products with ?barcode= (any GTIN form of it), for
+// dropshippers scanning the item in hand. Several suppliers may sell it.
+func (h *Handlers) LookupBarcode(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Parse the Barcode ---
+	code, err := barcode.Parse(c.Query("barcode"))
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	// 2. --- Find the Products (replica) ---
+	matches, err := h.Store.Products.LookupBarcode(ctx, barcode.Forms(code), maxBarcodeMatches)
+	if err != nil {
+		apierror.Internal(c, "Failed to look up barcode")
+		return
+	}
+	out := make([]BarcodeMatch, 0, len(matches))
+	for _, m := range matches {
+		p, err := h.loadCatalogueProduct(ctx, m.ProductID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue // left the catalogue in the meantime
+		}
+		if err != nil {
+			apierror.Internal(c, "Failed to look up barcode")
+			return
+		}
+		out = append(out, BarcodeMatch{Product: p, VariantID: m.VariantID})
+	}
+	c.JSON(http.StatusOK, gin.H{"barcode": code, "matches": out})
+}
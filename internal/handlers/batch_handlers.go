@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/gin-gonic/gin"
+)
+
+// BatchRequest is one sub-request of POST /batch: a read. Path is relative to
+// the API version of the batch ("/dashboard/stats?range=7d"); a path that
+// already starts with the version prefix is accepted as is.
+type BatchRequest struct {
+	Method string `json:"method" binding:"required"` // GET; anything else fails that item
+	Path   string `json:"path" binding:"required,startswith=/"`
+}
+
+type BatchInput struct {
+	Requests []BatchRequest `json:"requests" binding:"required,min=1,dive"`
+}
+
+// BatchResponse is the outcome of one sub-request. Body is the JSON the route
+// returned, or a string for a non-JSON response.
+type BatchResponse struct {
+	Status int         `json:"status"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+// Batch is the handler for POST /v1/batch
+// It runs up to HTTP_BATCH_MAX_REQUESTS reads through router, one after the
+// other and in the order sent, with the caller's credentials and language, and
+// returns their responses in the same order. A failing sub-request does not
+// stop the others: each result carries its own status. Only GETs run, and not
+// streams or exports, which would hold the batch until its timeout; those items
+// fail with 400 without being sent. Every sub-request goes through the route's
+// own middleware (auth, role checks), so a batch can do nothing the caller
+// could not do in separate calls.
+func (h *Handlers) Batch(router http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		// 1. --- Validate Input ---
+		var input BatchInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			apierror.Validation(c, err)
+			return
+		}
+		if max := h.Config.HTTP.BatchMaxRequests; len(input.Requests) > max {
+			apierror.BadRequest(c, fmt.Sprintf("A batch can hold at most %d requests", max))
+			return
+		}
+		prefix := apiversion.From(c).Prefix()
+		paths := make([]string, len(input.Requests))
+		for i, sub := range input.Requests {
+			path := sub.Path
+			if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+				path = prefix + path
+			}
+			u, err := url.ParseRequestURI(path)
+			if err != nil {
+				apierror.BadRequest(c, fmt.Sprintf("requests[%d].path is not a valid path", i))
+				return
+			}
+			if strings.TrimSuffix(u.Path, "/") == prefix+"/batch" {
+				apierror.BadRequest(c, "Batches cannot be nested")
+				return
+			}
+			paths[i] = path
+		}
+
+		// 2. --- Run Each Sub-request Through the Router ---
+		requestID := c.GetString(apierror.RequestIDKey)
+		responses := make([]BatchResponse, len(input.Requests))
+		for i, sub := range input.Requests {
+			if sub.Method != http.MethodGet {
+				responses[i] = BatchResponse{Status: http.StatusBadRequest,
+					Body: apierror.Body(c, apierror.CodeBadRequest, "A batch can only hold GET requests")}
+				continue
+			}
+			if batchUnsupported(paths[i]) {
+				responses[i] = BatchResponse{Status: http.StatusBadRequest,
+					Body: apierror.Body(c, apierror.CodeBadRequest, "Streams and exports cannot run in a batch")}
+				continue
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, paths[i], nil)
+			if err != nil {
+				apierror.Internal(c, "Failed to build batch request")
+				return
+			}
+			// The caller's credentials and language; no Accept-Encoding, so bodies come back uncompressed.
+			for _, name := range []string{"Authorization", "Cookie", "Accept-Language", "User-Agent"} {
+				if v := c.GetHeader(name); v != "" {
+					req.Header.Set(name, v)
+				}
+			}
+			// Sub-requests log as <batch ID>.<index>, so their lines group under the batch.
+			req.Header.Set("X-Request-ID", requestID+"."+strconv.Itoa(i))
+			req.RemoteAddr = c.Request.RemoteAddr
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			responses[i] = BatchResponse{Status: rec.Code, Body: batchBody(rec)}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"responses": responses})
+	}
+}
+
+// batchUnsupported reports whether path is a stream (GET /wallet/stream) or an
+// export (GET .../export, the export files), which a batch does not run.
+func batchUnsupported(path string) bool {
+	u, err := url.ParseRequestURI(path)
+	if err != nil {
+		return true
+	}
+	p := strings.TrimSuffix(u.Path, "/")
+	last := p[strings.LastIndex(p, "/")+1:]
+	return last == "stream" || last == "export" || strings.Contains(p, "/exports/files/")
+}
+
+// batchBody embeds a JSON response as is and anything else as text.
+func batchBody(rec *httptest.ResponseRecorder) interface{} {
+	if rec.Body.Len() == 0 {
+		return nil
+	}
+	if strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") && json.Valid(rec.Body.Bytes()) {
+		return json.RawMessage(rec.Body.Bytes())
+	}
+	return rec.Body.String()
+}
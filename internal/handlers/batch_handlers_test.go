@@ -0,0 +1,23 @@
+package handlers
+
+import "testing"
+
+func TestBatchUnsupported(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/v1/wallet/stream", true},
+		{"/v1/dropshipper/orders/export?from=2026-01-01", true},
+		{"/v1/supplier/orders/export/", true},
+		{"/v1/exports/files/orders-1.csv", true},
+		{"/v1/exports/42", false},
+		{"/v1/dashboard/stats?range=7d", false},
+		{"/v1/dropshipper/wallet", false},
+	}
+	for _, tt := range tests {
+		if got := batchUnsupported(tt.path); got != tt.want {
+			t.Errorf("batchUnsupported(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/01moynul/taptosell-golang/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCaptchaConfig is the handler for GET /v1/captcha
+// It tells the frontend whether to render the CAPTCHA widget on the
+// registration, login and resend-code forms, and with which site key.
+func (h *Handlers) GetCaptchaConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	enabled, _ := h.Settings.Get(ctx, middleware.SettingCaptchaEnabled)
+	if enabled != "true" || h.Captcha == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":  true,
+		"provider": h.Captcha.Provider,
+		"siteKey":  h.Captcha.SiteKey,
+		"header":   middleware.CaptchaHeader,
+	})
+}
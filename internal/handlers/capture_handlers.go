@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/audit"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Admin: Webhook & Payment Captures ---
+//
+
+// GetRequestCaptures is the handler for GET /v1/admin/captures
+// Filters: kind, route, status, userId, requestId. Bodies and headers are left
+// out of the list; fetch one capture by ID to see them.
+func (h *Handlers) GetRequestCaptures(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"kind", "route", "status", "userId", "requestId"}})
+	if !ok {
+		return
+	}
+	page := list.Page
+
+	// 1. --- Build Filters ---
+	where := "WHERE 1 = 1"
+	var args []interface{}
+	for _, f := range []struct{ param, column string }{{"kind", "kind"}, {"route", "route"}, {"requestId", "request_id"}} {
+		if v := list.Filter(f.param); v != "" {
+			where += " AND " + f.column + " = ?"
+			args = append(args, v)
+		}
+	}
+	for _, f := range []struct{ param, column string }{{"status", "response_status"}, {"userId", "user_id"}} {
+		if v := list.Filter(f.param); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				apierror.BadRequest(c, f.param+" must be a number")
+				return
+			}
+			where += " AND " + f.column + " = ?"
+			args = append(args, n)
+		}
+	}
+	seek, seekArgs := page.Where("created_at", "id")
+	args = append(args, seekArgs...)
+
+	// 2. --- Query (newest first) ---
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT id, kind, request_id, user_id, method, route, path, signature, response_status, replay_of, created_at
+		FROM request_captures `+where+seek+page.OrderLimit("created_at", "id"), args...)
+	if err != nil {
+		apierror.Internal(c, "Failed to load captures")
+		return
+	}
+	defer rows.Close()
+
+	captures := []models.RequestCapture{}
+	for rows.Next() {
+		var rc models.RequestCapture
+		if err := rows.Scan(&rc.ID, &rc.Kind, &rc.RequestID, &rc.UserID, &rc.Method, &rc.Route, &rc.Path, &rc.Signature, &rc.ResponseStatus, &rc.ReplayOf, &rc.CreatedAt); err != nil {
+			apierror.Internal(c, "Failed to read captures")
+			return
+		}
+		captures = append(captures, rc)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Failed to read captures")
+		return
+	}
+	captures, nextCursor := pagination.Paginate(page, captures, func(rc models.RequestCapture) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: rc.CreatedAt, ID: rc.ID}
+	})
+
+	// 3. --- Send Response ---
+	c.JSON(http.StatusOK, gin.H{
+		"captures":   captures,
+		"nextCursor": nextCursor,
+	})
+}
+
+// GetRequestCapture is the handler for GET /v1/admin/captures/:id
+func (h *Handlers) GetRequestCapture(c *gin.Context) {
+	rc, ok := h.loadRequestCapture(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"capture": rc})
+}
+
+// ReplayRequestCapture is the handler for POST /v1/admin/captures/:id/replay
+// It sends a stored webhook through router again, exactly as received
+// (signature included), and returns our new response. The replay is captured
+// too, with replayOf pointing at the original.
+//
+// Payments are never replayed: that would act as the user, against whatever
+// their cart or wallet holds now. For a payment on an order the conflict
+// carries the order's current status, read under lock, so an admin can see
+// whether it still needs paying.
+func (h *Handlers) ReplayRequestCapture(router http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		// 1. --- Load & Check ---
+		rc, ok := h.loadRequestCapture(c)
+		if !ok {
+			return
+		}
+		if rc.Kind != audit.KindWebhook {
+			h.refusePaymentReplay(c, rc)
+			return
+		}
+
+		// 2. --- Rebuild the Request ---
+		req, err := http.NewRequestWithContext(audit.WithReplay(ctx, rc.ID), rc.Method, rc.Path, bytes.NewBufferString(rc.RequestBody))
+		if err != nil {
+			apierror.Internal(c, "Stored request cannot be rebuilt")
+			return
+		}
+		for name, values := range rc.RequestHeaders {
+			// Credentials were redacted at capture time; the replay carries none.
+			if len(values) == 1 && values[0] == "[redacted]" {
+				continue
+			}
+			req.Header[name] = values
+		}
+
+		// 3. --- Run It Through the Router ---
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		c.JSON(http.StatusOK, gin.H{
+			"replayOf":       rc.ID,
+			"responseStatus": rec.Code,
+			"responseBody":   rec.Body.String(),
+		})
+	}
+}
+
+// refusePaymentReplay answers a replay of a payment capture with 409, adding
+// the order's status when the captured route names one of the user's orders.
+func (h *Handlers) refusePaymentReplay(c *gin.Context, rc *models.RequestCapture) {
+	ctx := c.Request.Context()
+	const message = "Payments cannot be replayed; the user has to pay or cancel the order again"
+
+	ref, ok := capturedOrderRef(rc.Route, rc.Path)
+	if !ok || rc.UserID == nil {
+		apierror.Conflict(c, message)
+		return
+	}
+	orderID, err := store.ResolveID(ctx, h.DB, store.TableOrders, ref, true)
+	if err != nil {
+		apierror.Conflict(c, message)
+		return
+	}
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+	order, err := tx.Orders.GetForUpdate(ctx, orderID, *rc.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Conflict(c, message)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to load the captured order")
+		return
+	}
+	apierror.WithDetails(c, http.StatusConflict, apierror.CodeConflict, message, gin.H{
+		"orderId":     order.ID,
+		"orderStatus": order.Status,
+	})
+}
+
+// capturedOrderRef returns the :id (a public or numeric order ID) of a
+// captured dropshipper order route such as /v1/dropshipper/orders/:id/pay,
+// taken from the request path.
+func capturedOrderRef(route, path string) (string, bool) {
+	if !strings.Contains(route, "/dropshipper/orders/:id/") {
+		return "", false
+	}
+	path, _, _ = strings.Cut(path, "?")
+	routeParts, pathParts := strings.Split(route, "/"), strings.Split(path, "/")
+	if len(routeParts) != len(pathParts) {
+		return "", false
+	}
+	for i, part := range routeParts {
+		if part == ":id" {
+			return pathParts[i], pathParts[i] != ""
+		}
+	}
+	return "", false
+}
+
+// loadRequestCapture reads the capture named by :id, writing the error response itself.
+func (h *Handlers) loadRequestCapture(c *gin.Context) (*models.RequestCapture, bool) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.BadRequest(c, "Invalid capture ID")
+		return nil, false
+	}
+
+	var rc models.RequestCapture
+	var headers string
+	err = h.DB.QueryRowContext(ctx, `
+		SELECT id, kind, request_id, user_id, method, route, path, request_headers, request_body,
+		       signature, response_status, response_body, replay_of, created_at
+		FROM request_captures WHERE id = ?`, id).
+		Scan(&rc.ID, &rc.Kind, &rc.RequestID, &rc.UserID, &rc.Method, &rc.Route, &rc.Path, &headers, &rc.RequestBody,
+			&rc.Signature, &rc.ResponseStatus, &rc.ResponseBody, &rc.ReplayOf, &rc.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.NotFound(c, "Capture not found (it may have rotated out)")
+		return nil, false
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to load capture")
+		return nil, false
+	}
+	if err := json.Unmarshal([]byte(headers), &rc.RequestHeaders); err != nil {
+		apierror.Internal(c, "Stored headers are corrupt")
+		return nil, false
+	}
+	return &rc, true
+}
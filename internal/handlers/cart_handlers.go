@@ -1,11 +1,21 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/01moynul/taptosell-golang/internal/dto"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/shipping"
+	"github.com/01moynul/taptosell-golang/internal/store"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,12 +25,12 @@ import (
 
 // getOrCreateCartID finds a user's active cart or creates one.
 // This is a helper function to be used within a transaction.
-func (h *Handlers) getOrCreateCartID(tx *sql.Tx, userID int64) (int64, error) {
+func (h *Handlers) getOrCreateCartID(ctx context.Context, tx *sql.Tx, userID int64) (int64, error) {
 	var cartID int64
 
 	// 1. Try to find an existing cart
 	query := "SELECT id FROM carts WHERE user_id = ?"
-	err := tx.QueryRow(query, userID).Scan(&cartID)
+	err := tx.QueryRowContext(ctx, query, userID).Scan(&cartID)
 
 	if err == nil {
 		return cartID, nil // Found it
@@ -30,7 +40,7 @@ func (h *Handlers) getOrCreateCartID(tx *sql.Tx, userID int64) (int64, error) {
 	if err == sql.ErrNoRows {
 		now := time.Now()
 		insertQuery := "INSERT INTO carts (user_id, created_at, updated_at) VALUES (?, ?, ?)"
-		result, err := tx.Exec(insertQuery, userID, now, now)
+		result, err := tx.ExecContext(ctx, insertQuery, userID, now, now)
 		if err != nil {
 			return 0, err // Failed to create
 		}
@@ -56,122 +66,295 @@ type AddToCartInput struct {
 
 // [FIXED] AddToCart: Handles both Simple and Variable Products
 func (h *Handlers) AddToCart(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	dropshipperID := userID_raw.(int64)
 
 	var input AddToCartInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction failed"})
+		apierror.Internal(c, "Transaction failed")
 		return
 	}
 	defer tx.Rollback()
 
-	cartID, err := h.getOrCreateCartID(tx, dropshipperID)
+	cartID, err := h.getOrCreateCartID(ctx, tx, dropshipperID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cart initialization failed"})
+		apierror.Internal(c, "Cart initialization failed")
 		return
 	}
 
 	// [FIX] Phase 8.4: Determine price and stock based on Variant vs Base Product
 	var stock int
-	var price float64
+	var price money.Money
+	var preorder bool // simple products taking pre-orders can go past the stock (capped at checkout)
 
 	// If VariantID is provided and > 0, check the VARIANT table
 	if input.VariantID != nil && *input.VariantID > 0 {
-		err = tx.QueryRow(`
+		err = tx.QueryRowContext(ctx, `
 			SELECT stock_quantity, price_to_tts 
 			FROM product_variants 
 			WHERE id = ? AND product_id = ?`,
 			*input.VariantID, input.ProductID).Scan(&stock, &price)
 
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Selected variant not found"})
+			apierror.NotFound(c, "Selected variant not found")
 			return
 		}
 	} else {
 		// Otherwise, check the BASE PRODUCT table
-		err = tx.QueryRow(`
-			SELECT stock_quantity, price_to_tts 
+		err = tx.QueryRowContext(ctx, `
+			SELECT stock_quantity, price_to_tts, is_preorder
 			FROM products 
-			WHERE id = ? AND status = 'active'`,
-			input.ProductID).Scan(&stock, &price)
+			WHERE id = ? AND status = 'active' AND deleted_at IS NULL`,
+			input.ProductID).Scan(&stock, &price, &preorder)
 
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found or inactive"})
+			apierror.NotFound(c, "Product not found or inactive")
 			return
 		}
 	}
 
-	if stock < input.Quantity {
-		c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock"})
+	// Suppliers on vacation take no new orders.
+	if away, err := supplierAway(ctx, tx, input.ProductID); err != nil {
+		apierror.Internal(c, "Failed to check the supplier")
+		return
+	} else if away {
+		apierror.Conflict(c, "This product's supplier is on vacation and is not taking orders right now")
+		return
+	}
+
+	if stock < input.Quantity && !preorder {
+		apierror.Conflict(c, "Insufficient stock")
 		return
 	}
 
 	// [FIX] Manual Check-and-Update to avoid SQL "Unique NULL" headaches
-	var existingQty int
-	var checkQuery string
-	var checkArgs []interface{}
+	existingQty, exists, err := cartLineQuantity(ctx, tx, cartID, input.ProductID, input.VariantID)
+	if err != nil {
+		apierror.Internal(c, "Failed to update cart items")
+		return
+	}
 
-	if input.VariantID != nil && *input.VariantID > 0 {
-		checkQuery = "SELECT quantity FROM cart_items WHERE cart_id = ? AND product_id = ? AND variant_id = ?"
-		checkArgs = []interface{}{cartID, input.ProductID, *input.VariantID}
-	} else {
-		checkQuery = "SELECT quantity FROM cart_items WHERE cart_id = ? AND product_id = ? AND variant_id IS NULL"
-		checkArgs = []interface{}{cartID, input.ProductID}
+	// The minimum and pack size apply to the whole line, including what is already in the cart.
+	rule, ruleErr := quantityRuleOf(ctx, tx, input.ProductID)
+	if ruleErr != nil {
+		apierror.Internal(c, "Failed to check the product's order quantity")
+		return
+	}
+	if !checkQuantity(c, http.StatusBadRequest, rule, existingQty+input.Quantity) {
+		return
 	}
 
-	err = tx.QueryRow(checkQuery, checkArgs...).Scan(&existingQty)
+	if err := addCartLine(ctx, tx, cartID, input.ProductID, input.VariantID, input.Quantity, exists); err != nil {
+		apierror.Internal(c, "Failed to update cart items")
+		return
+	}
 
-	if err == nil {
-		// Item exists -> Update Quantity
-		updateQuery := "UPDATE cart_items SET quantity = quantity + ?, updated_at = NOW() WHERE cart_id = ? AND product_id = ?"
-		updateArgs := []interface{}{input.Quantity, cartID, input.ProductID}
-
-		if input.VariantID != nil && *input.VariantID > 0 {
-			updateQuery += " AND variant_id = ?"
-			updateArgs = append(updateArgs, *input.VariantID)
-		} else {
-			updateQuery += " AND variant_id IS NULL"
-		}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Commit failed")
+		return
+	}
 
-		_, err = tx.Exec(updateQuery, updateArgs...)
-	} else {
-		// Item does not exist -> Insert New
-		_, err = tx.Exec(`
+	c.JSON(http.StatusCreated, gin.H{"message": "Item added to cart"})
+}
+
+// cartLineQuantity is the quantity of a product (or one of its variants)
+// already in a cart; exists is false when the cart has no such line.
+func cartLineQuantity(ctx context.Context, q Querier, cartID, productID int64, variantID *int64) (qty int, exists bool, err error) {
+	query := "SELECT quantity FROM cart_items WHERE cart_id = ? AND product_id = ? AND variant_id IS NULL"
+	args := []interface{}{cartID, productID}
+	if variantID != nil && *variantID > 0 {
+		query = "SELECT quantity FROM cart_items WHERE cart_id = ? AND product_id = ? AND variant_id = ?"
+		args = append(args, *variantID)
+	}
+	err = q.QueryRowContext(ctx, query, args...).Scan(&qty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return qty, err == nil, err
+}
+
+// addCartLine adds quantity to a cart line, or inserts the line when it does
+// not exist yet (see cartLineQuantity).
+func addCartLine(ctx context.Context, q Querier, cartID, productID int64, variantID *int64, quantity int, exists bool) error {
+	if !exists {
+		_, err := q.ExecContext(ctx, `
 			INSERT INTO cart_items (cart_id, product_id, variant_id, quantity, updated_at)
 			VALUES (?, ?, ?, ?, NOW())`,
-			cartID, input.ProductID, input.VariantID, input.Quantity)
+			cartID, productID, variantID, quantity)
+		return err
+	}
+
+	updateQuery := "UPDATE cart_items SET quantity = quantity + ?, updated_at = NOW() WHERE cart_id = ? AND product_id = ?"
+	updateArgs := []interface{}{quantity, cartID, productID}
+	if variantID != nil && *variantID > 0 {
+		updateQuery += " AND variant_id = ?"
+		updateArgs = append(updateArgs, *variantID)
+	} else {
+		updateQuery += " AND variant_id IS NULL"
 	}
+	_, err := q.ExecContext(ctx, updateQuery, updateArgs...)
+	return err
+}
+
+// ReorderLine is a line of a past order as POST /orders/:id/reorder found it.
+type ReorderLine struct {
+	ProductID    int64        `json:"productId"`
+	VariantID    *int64       `json:"variantId,omitempty"`
+	Name         string       `json:"name"`
+	Quantity     int          `json:"quantity"`
+	OrderedPrice money.Money  `json:"orderedPrice"`     // the unit price on the order
+	Price        *money.Money `json:"price,omitempty"`  // today's, when it changed
+	Reason       string       `json:"reason,omitempty"` // why the line could not be added
+}
+
+// Reorder is the handler for POST /v1/dropshipper/orders/:id/reorder
+// It adds every line of a past order to the cart again, at today's prices.
+// Lines that can no longer be ordered as they were (product gone, supplier on
+// vacation, not enough stock, new quantity rules) are left out and reported,
+// and re-priced lines are flagged.
+func (h *Handlers) Reorder(c *gin.Context) {
+	ctx := c.Request.Context()
 
+	// 1. --- Get IDs & the Order ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+	o, err := h.Store.Orders.GetForUser(ctx, orderID, dropshipperID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order")
+		return
+	}
+	items, err := h.Store.Orders.Items(ctx, o.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart items"})
+		apierror.Internal(c, "Failed to fetch order items")
 		return
 	}
 
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Transaction failed")
+		return
+	}
+	defer tx.Rollback()
+
+	cartID, err := h.getOrCreateCartID(ctx, tx, dropshipperID)
+	if err != nil {
+		apierror.Internal(c, "Cart initialization failed")
+		return
+	}
+
+	// 2. --- Check & Add Each Line ---
+	added, repriced, unavailable := []ReorderLine{}, []ReorderLine{}, []ReorderLine{}
+	for _, item := range items {
+		line := ReorderLine{
+			ProductID:    item.ProductID,
+			VariantID:    item.VariantID,
+			Name:         item.ProductName,
+			Quantity:     item.Quantity,
+			OrderedPrice: item.UnitPrice,
+		}
+
+		// Today's price and stock; the variant must still belong to a live product.
+		var price money.Money
+		var stock int
+		var preorder bool
+		var variantGone bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(v.price_to_tts, p.price_to_tts), COALESCE(v.stock_quantity, p.stock_quantity),
+				p.is_preorder = 1 AND v.id IS NULL, ? AND v.id IS NULL
+			FROM products p
+			LEFT JOIN product_variants v ON v.id = ? AND v.product_id = p.id
+			WHERE p.id = ? AND p.status = 'active' AND `+store.NotDeleted("p"),
+			item.VariantID != nil, item.VariantID, item.ProductID).Scan(&price, &stock, &preorder, &variantGone)
+		if err == sql.ErrNoRows || variantGone {
+			line.Reason = "This product is no longer available"
+			unavailable = append(unavailable, line)
+			continue
+		}
+		if err != nil {
+			apierror.Internal(c, "Failed to check order items")
+			return
+		}
+
+		away, err := supplierAway(ctx, tx, item.ProductID)
+		if err != nil {
+			apierror.Internal(c, "Failed to check the supplier")
+			return
+		}
+		existingQty, exists, err := cartLineQuantity(ctx, tx, cartID, item.ProductID, item.VariantID)
+		if err != nil {
+			apierror.Internal(c, "Failed to update cart items")
+			return
+		}
+		rule, err := quantityRuleOf(ctx, tx, item.ProductID)
+		if err != nil {
+			apierror.Internal(c, "Failed to check the product's order quantity")
+			return
+		}
+		switch {
+		case away:
+			line.Reason = "This product's supplier is on vacation and is not taking orders right now"
+		case stock < existingQty+item.Quantity && !preorder:
+			line.Reason = fmt.Sprintf("Only %d in stock", max(stock-existingQty, 0))
+		case !rule.allows(existingQty + item.Quantity):
+			line.Reason = rule.message(existingQty + item.Quantity)
+		}
+		if line.Reason != "" {
+			unavailable = append(unavailable, line)
+			continue
+		}
+
+		if err := addCartLine(ctx, tx, cartID, item.ProductID, item.VariantID, item.Quantity, exists); err != nil {
+			apierror.Internal(c, "Failed to update cart items")
+			return
+		}
+		if price != item.UnitPrice {
+			line.Price = &price
+			repriced = append(repriced, line)
+		}
+		added = append(added, line)
+	}
+
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Commit failed"})
+		apierror.Internal(c, "Commit failed")
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Item added to cart"})
+	// 3. --- Report ---
+	c.JSON(http.StatusOK, gin.H{
+		"message":     fmt.Sprintf("%d of %d items added to your cart", len(added), len(items)),
+		"added":       added,
+		"repriced":    repriced,
+		"unavailable": unavailable,
+	})
 }
 
 // CartItemResponse is a helper struct for the GetCart handler
 // NO CHANGE: The JSON response struct remains the same for the frontend.
 type CartItemResponse struct {
-	ProductID int64   `json:"productId"`
-	Name      string  `json:"name"`
-	SKU       string  `json:"sku"`
-	Price     float64 `json:"price"` // This is the 'TapToSell' price
-	Quantity  int     `json:"quantity"`
-	LineTotal float64 `json:"lineTotal"`
-	Stock     int     `json:"stock"`
+	ProductID int64       `json:"productId"`
+	Name      string      `json:"name"`
+	SKU       string      `json:"sku"`
+	Price     money.Money `json:"price"` // This is the 'TapToSell' price
+	Quantity  int         `json:"quantity"`
+	LineTotal money.Money `json:"lineTotal"`
+	Stock     int         `json:"stock"`
 }
 
 // GetCart is the handler for GET /v1/dropshipper/cart
@@ -181,52 +364,78 @@ func (h *Handlers) GetCart(c *gin.Context) {
 	userID_raw, _ := c.Get("userID")
 	dropshipperID := userID_raw.(int64)
 
-	var cartID int64
-	err := h.DB.QueryRow("SELECT id FROM carts WHERE user_id = ?", dropshipperID).Scan(&cartID)
+	cart, err := h.cartContents(c.Request.Context(), dropshipperID, apiversion.From(c))
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{"items": []interface{}{}, "subtotal": 0})
+		apierror.Internal(c, "Failed to fetch cart")
 		return
 	}
+	c.JSON(http.StatusOK, cart)
+}
+
+// cartContents is the body of GET /vN/dropshipper/cart for a dropshipper.
+// From v2 on it is a dto.Cart, whose lines carry their variantId.
+func (h *Handlers) cartContents(ctx context.Context, dropshipperID int64, version apiversion.Version) (interface{}, error) {
+	var cartID int64
+	err := h.DB.QueryRowContext(ctx, "SELECT id FROM carts WHERE user_id = ?", dropshipperID).Scan(&cartID)
+	if err != nil {
+		if version >= apiversion.V2 {
+			return dto.Cart{Items: []dto.CartLine{}}, nil
+		}
+		return gin.H{"items": []interface{}{}, "subtotal": 0}, nil
+	}
 
 	// [CHANGE 1] Added 'v.options' to the SELECT statement
 	query := `
 		SELECT
 			ci.product_id, 
+			ci.variant_id,
 			p.name, 
 			COALESCE(v.sku, p.sku) as display_sku, 
 			COALESCE(v.price_to_tts, p.price_to_tts) as unit_price, 
 			ci.quantity, 
 			COALESCE(v.stock_quantity, p.stock_quantity) as stock,
-            v.options  -- <--- WE NEED THIS
+            v.options,  -- <--- WE NEED THIS
+			(p.is_preorder = 1 AND ci.variant_id IS NULL) as preorder_open,
+			EXISTS (SELECT 1 FROM users s WHERE s.id = p.supplier_id AND ` + store.SupplierAway("s") + `) as supplier_away,
+			p.shipping_restrictions, p.min_order_qty, p.order_increment,
+			` + processingColumns("p", "su") + `
 		FROM cart_items ci
 		JOIN products p ON ci.product_id = p.id
+		JOIN users su ON su.id = p.supplier_id
 		LEFT JOIN product_variants v ON ci.variant_id = v.id
-		WHERE ci.cart_id = ? AND p.status = 'active'
+		WHERE ci.cart_id = ? AND p.status = 'active' AND ` + store.NotDeleted("p") + `
 	`
-	rows, err := h.DB.Query(query, cartID)
+	rows, err := h.DB.QueryContext(ctx, query, time.Now(), cartID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
-		return
+		return nil, err
 	}
 	defer rows.Close()
 
 	var items []gin.H
-	var subtotal float64
+	var lines []dto.CartLine
+	var subtotal money.Money
 
 	for rows.Next() {
 		var pid int64
+		var variantID sql.NullInt64
 		var name, sku string
-		var price float64
+		var price money.Money
 		var qty, stock int
 		var optionsJSON []byte // [CHANGE 2] Buffer to catch the JSON string
+		var preorderOpen, supplierAway bool
+		var restrictions string
+		var rule QuantityRule
+		var handlingDays sql.NullInt64
+		var cutoff sql.NullString
 
 		// [CHANGE 3] Scan the optionsJSON
-		err := rows.Scan(&pid, &name, &sku, &price, &qty, &stock, &optionsJSON)
+		err := rows.Scan(&pid, &variantID, &name, &sku, &price, &qty, &stock, &optionsJSON, &preorderOpen, &supplierAway, &restrictions, &rule.MinQuantity, &rule.Increment, &handlingDays, &cutoff)
 		if err != nil {
 			continue
 		}
 
-		lineTotal := price * float64(qty)
+		couriers := shipping.Compatible(h.Config.Shipping.Couriers, shipping.Split(restrictions))
+		lineTotal := price.Mul(qty)
 		subtotal += lineTotal
 
 		// [CHANGE 4] Parse the options so React can display "Color: Red"
@@ -235,6 +444,41 @@ func (h *Handlers) GetCart(c *gin.Context) {
 			_ = json.Unmarshal(optionsJSON, &options)
 		}
 
+		// A pre-order line has no ships-by date until its stock arrives.
+		var shipsBy *time.Time
+		if !(preorderOpen && stock < qty) {
+			t := h.shipsBy(handlingDays, cutoff, time.Now())
+			shipsBy = &t
+		}
+
+		if version >= apiversion.V2 {
+			var variant *int64
+			if variantID.Valid {
+				variant = &variantID.Int64
+			}
+			lines = append(lines, dto.CartLine{
+				ProductID:            pid,
+				VariantID:            variant,
+				Name:                 name,
+				SKU:                  sku,
+				Price:                price,
+				Quantity:             qty,
+				Stock:                stock,
+				LineTotal:            lineTotal,
+				Options:              dto.List(options),
+				Preorder:             preorderOpen && stock < qty,
+				SupplierAway:         supplierAway,
+				ShippingRestrictions: shipping.Split(restrictions),
+				Couriers:             couriers,
+				Shippable:            shipping.Shippable(h.Config.Shipping.Couriers, shipping.Split(restrictions)),
+				MinQuantity:          rule.MinQuantity,
+				Increment:            rule.Increment,
+				QuantityValid:        rule.allows(qty),
+				ShipsBy:              shipsBy,
+			})
+			continue
+		}
+
 		items = append(items, gin.H{
 			"product_id":   pid,
 			"product_name": name, // Ensure frontend uses this key
@@ -245,16 +489,36 @@ func (h *Handlers) GetCart(c *gin.Context) {
 			"quantity":     qty,
 			"stock":        stock,
 			"lineTotal":    lineTotal,
-			"options":      options, // <--- Send the parsed options to Frontend
+			"options":      options,                     // <--- Send the parsed options to Frontend
+			"preorder":     preorderOpen && stock < qty, // checkout will place this line as a pre-order
+			"supplierAway": supplierAway,                // on vacation: checkout refuses the cart until removed
+
+			// Couriers accepting the restrictions (null without SHIPPING_COURIERS);
+			// when none does, checkout refuses the cart until the item is removed.
+			"shippingRestrictions": shipping.Split(restrictions),
+			"couriers":             couriers,
+			"shippable":            shipping.Shippable(h.Config.Shipping.Couriers, shipping.Split(restrictions)),
+
+			// Quantity steppers: at least minQuantity, in steps of increment.
+			// A line added before the rule changed has quantityValid false.
+			"minQuantity":   rule.MinQuantity,
+			"increment":     rule.Increment,
+			"quantityValid": rule.allows(qty),
+
+			// When the supplier must ship this line by, if the order is paid now.
+			"shipsBy": shipsBy,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	if version >= apiversion.V2 {
+		return dto.Cart{Items: dto.List(lines), Subtotal: subtotal, ItemCount: len(lines)}, nil
+	}
+	return gin.H{
 		"items":       items,
 		"subtotal":    subtotal,
 		"total_items": len(items),
 		"grand_total": subtotal,
-	})
+	}, nil
 }
 
 // UpdateCartItemInput defines the JSON for updating an item's quantity.
@@ -262,71 +526,134 @@ type UpdateCartItemInput struct {
 	Quantity int `json:"quantity" binding:"required,gte=0"` // gte=0 allows setting quantity to 0, which we'll treat as a delete
 }
 
+// cartLine is the cart line PUT and DELETE /cart/items/:product_id address.
+// v1 addresses every line of the product at once; from v2 on, ?variantId=
+// picks one variant's line and its absence the product's own line.
+type cartLine struct {
+	allVariants bool
+	variantID   *int64
+}
+
+// parseCartLine reads the line of the request, answering 400 for a bad variantId.
+func parseCartLine(c *gin.Context) (cartLine, bool) {
+	if apiversion.From(c) < apiversion.V2 {
+		return cartLine{allVariants: true}, true
+	}
+	raw := c.Query("variantId")
+	if raw == "" {
+		return cartLine{}, true
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		apierror.BadRequest(c, "variantId must be a positive integer")
+		return cartLine{}, false
+	}
+	return cartLine{variantID: &id}, true
+}
+
+// where narrows a cart_items query on cart_id and product_id to the line.
+func (l cartLine) where() (string, []interface{}) {
+	switch {
+	case l.allVariants:
+		return "", nil
+	case l.variantID != nil:
+		return " AND variant_id = ?", []interface{}{*l.variantID}
+	}
+	return " AND variant_id IS NULL", nil
+}
+
 // UpdateCartItem is the handler for PUT /v1/dropshipper/cart/items/:product_id
+// (and /v2, where ?variantId= picks the line; see cartLine).
 func (h *Handlers) UpdateCartItem(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get IDs ---
 	userID_raw, _ := c.Get("userID")
 	dropshipperID := userID_raw.(int64)
 	productIDStr := c.Param("product_id")
+	line, ok := parseCartLine(c)
+	if !ok {
+		return
+	}
 
 	// 2. --- Bind & Validate JSON ---
 	var input UpdateCartItemInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
 	// 3. --- Get User's Cart ID ---
 	var cartID int64
-	err := h.DB.QueryRow("SELECT id FROM carts WHERE user_id = ?", dropshipperID).Scan(&cartID)
+	err := h.DB.QueryRowContext(ctx, "SELECT id FROM carts WHERE user_id = ?", dropshipperID).Scan(&cartID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+			apierror.NotFound(c, "Cart not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find cart"})
+		apierror.Internal(c, "Failed to find cart")
 		return
 	}
 
 	// --- Handle Quantity ---
 	if input.Quantity == 0 {
 		// If quantity is 0, this is a "delete" request.
-		h.deleteCartItem(c, cartID, productIDStr)
+		h.deleteCartItem(c, cartID, productIDStr, line)
 		return
 	}
 
 	// 4. --- Check Stock ---
 	// UPDATED: Select stock_quantity
 	var stock int
-	err = h.DB.QueryRow("SELECT stock_quantity FROM products WHERE id = ? AND status = 'active'", productIDStr).Scan(&stock)
+	var preorder bool
+	var rule QuantityRule
+	err = h.DB.QueryRowContext(ctx, "SELECT id, stock_quantity, is_preorder, min_order_qty, order_increment FROM products WHERE id = ? AND status = 'active' AND deleted_at IS NULL", productIDStr).
+		Scan(&rule.ProductID, &stock, &preorder, &rule.MinQuantity, &rule.Increment)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			apierror.NotFound(c, "Product not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check product stock"})
+		apierror.Internal(c, "Failed to check product stock")
 		return
 	}
-	if stock < input.Quantity {
-		c.JSON(http.StatusConflict, gin.H{"error": "Not enough stock available for this quantity"})
+	// A variant line is bound by the variant's stock (variants take no pre-orders).
+	if line.variantID != nil {
+		err = h.DB.QueryRowContext(ctx, "SELECT stock_quantity FROM product_variants WHERE id = ? AND product_id = ?", *line.variantID, rule.ProductID).Scan(&stock)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				apierror.NotFound(c, "Selected variant not found")
+				return
+			}
+			apierror.Internal(c, "Failed to check product stock")
+			return
+		}
+		preorder = false
+	}
+	if stock < input.Quantity && !preorder {
+		apierror.Conflict(c, "Not enough stock available for this quantity")
+		return
+	}
+	if !checkQuantity(c, http.StatusBadRequest, rule, input.Quantity) {
 		return
 	}
 
 	// 5. --- Execute Update ---
+	lineFilter, lineArgs := line.where()
 	query := `
 		UPDATE cart_items
 		SET quantity = ?, updated_at = ?
-		WHERE cart_id = ? AND product_id = ?`
+		WHERE cart_id = ? AND product_id = ?` + lineFilter
 
-	result, err := h.DB.Exec(query, input.Quantity, time.Now(), cartID, productIDStr)
+	result, err := h.DB.ExecContext(ctx, query, append([]interface{}{input.Quantity, time.Now(), cartID, productIDStr}, lineArgs...)...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item"})
+		apierror.Internal(c, "Failed to update item")
 		return
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in cart"})
+		apierror.NotFound(c, "Item not found in cart")
 		return
 	}
 
@@ -335,41 +662,51 @@ func (h *Handlers) UpdateCartItem(c *gin.Context) {
 }
 
 // DeleteCartItem is the handler for DELETE /v1/dropshipper/cart/items/:product_id
+// (and /v2, where ?variantId= picks the line; see cartLine).
 func (h *Handlers) DeleteCartItem(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get IDs ---
 	userID_raw, _ := c.Get("userID")
 	dropshipperID := userID_raw.(int64)
 	productIDStr := c.Param("product_id")
+	line, ok := parseCartLine(c)
+	if !ok {
+		return
+	}
 
 	// 2. --- Get User's Cart ID ---
 	var cartID int64
-	err := h.DB.QueryRow("SELECT id FROM carts WHERE user_id = ?", dropshipperID).Scan(&cartID)
+	err := h.DB.QueryRowContext(ctx, "SELECT id FROM carts WHERE user_id = ?", dropshipperID).Scan(&cartID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+			apierror.NotFound(c, "Cart not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find cart"})
+		apierror.Internal(c, "Failed to find cart")
 		return
 	}
 
 	// 3. --- Call delete helper ---
-	h.deleteCartItem(c, cartID, productIDStr)
+	h.deleteCartItem(c, cartID, productIDStr, line)
 }
 
 // deleteCartItem is a helper to DRY up the delete logic
-func (h *Handlers) deleteCartItem(c *gin.Context, cartID int64, productIDStr string) {
+func (h *Handlers) deleteCartItem(c *gin.Context, cartID int64, productIDStr string, line cartLine) {
+	ctx := c.Request.Context()
+
 	// Execute atomic delete, checking both cart_id and product_id
-	query := "DELETE FROM cart_items WHERE cart_id = ? AND product_id = ?"
-	result, err := h.DB.Exec(query, cartID, productIDStr)
+	lineFilter, lineArgs := line.where()
+	query := "DELETE FROM cart_items WHERE cart_id = ? AND product_id = ?" + lineFilter
+	result, err := h.DB.ExecContext(ctx, query, append([]interface{}{cartID, productIDStr}, lineArgs...)...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete item"})
+		apierror.Internal(c, "Failed to delete item")
 		return
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in cart"})
+		apierror.NotFound(c, "Item not found in cart")
 		return
 	}
 
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Channel Sync Handlers (Dropshipper-Only) ---
+//
+
+// ChannelSyncStatus is a helper struct for the GetChannelSyncStatus handler.
+// It summarizes one linked channel together with its failed listings.
+type ChannelSyncStatus struct {
+	models.Channel
+	PendingPushes  int                     `json:"pendingPushes"`
+	SyncedListings int                     `json:"syncedListings"`
+	FailedCount    int                     `json:"failedCount"`
+	FailedListings []models.ChannelListing `json:"failedListings"`
+}
+
+// GetChannelSyncStatus is the handler for GET /v1/dropshipper/channels/status
+// It returns every channel linked by the dropshipper with its last sync time,
+// pending push count, and the failed listings (with their error messages).
+func (h *Handlers) GetChannelSyncStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Dropshipper ID ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+
+	// 2. --- Query Channels with Listing Counts ---
+	// A single aggregate query avoids one COUNT(*) round-trip per channel.
+	query := `
+		SELECT
+			ch.id, ch.user_id, ch.platform, ch.shop_name, ch.status,
+			ch.last_synced_at, ch.created_at, ch.updated_at,
+			COALESCE(SUM(cl.sync_status = 'pending'), 0) AS pending_pushes,
+			COALESCE(SUM(cl.sync_status = 'synced'), 0) AS synced_listings,
+			COALESCE(SUM(cl.sync_status = 'failed'), 0) AS failed_count
+		FROM channels ch
+		LEFT JOIN channel_listings cl ON cl.channel_id = ch.id
+		WHERE ch.user_id = ?
+		GROUP BY ch.id
+		ORDER BY ch.created_at ASC
+	`
+	rows, err := h.DB.QueryContext(ctx, query, dropshipperID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch channels")
+		return
+	}
+	defer rows.Close()
+
+	var channels []*ChannelSyncStatus
+	channelIndex := make(map[int64]*ChannelSyncStatus)
+	for rows.Next() {
+		var s ChannelSyncStatus
+		if err := rows.Scan(
+			&s.ID, &s.UserID, &s.Platform, &s.ShopName, &s.Status,
+			&s.LastSyncedAt, &s.CreatedAt, &s.UpdatedAt,
+			&s.PendingPushes, &s.SyncedListings, &s.FailedCount,
+		); err != nil {
+			apierror.Internal(c, "Failed to scan channel row")
+			return
+		}
+		s.FailedListings = []models.ChannelListing{}
+		channels = append(channels, &s)
+		channelIndex[s.ID] = &s
+	}
+	if err = rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating channel rows")
+		return
+	}
+
+	// 3. --- Query Failed Listings (All Channels at Once) ---
+	failedQuery := `
+		SELECT
+			cl.id, cl.channel_id, cl.product_id, cl.external_id, cl.sync_status,
+			cl.last_error, cl.attempts, cl.last_attempt_at, cl.created_at, cl.updated_at,
+			p.name
+		FROM channel_listings cl
+		JOIN channels ch ON cl.channel_id = ch.id
+		JOIN products p ON cl.product_id = p.id
+		WHERE ch.user_id = ? AND cl.sync_status = 'failed'
+		ORDER BY cl.updated_at DESC
+	`
+	failedRows, err := h.DB.QueryContext(ctx, failedQuery, dropshipperID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch failed listings")
+		return
+	}
+	defer failedRows.Close()
+
+	for failedRows.Next() {
+		var l models.ChannelListing
+		if err := failedRows.Scan(
+			&l.ID, &l.ChannelID, &l.ProductID, &l.ExternalID, &l.SyncStatus,
+			&l.LastError, &l.Attempts, &l.LastAttemptAt, &l.CreatedAt, &l.UpdatedAt,
+			&l.ProductName,
+		); err != nil {
+			apierror.Internal(c, "Failed to scan listing row")
+			return
+		}
+		if ch, ok := channelIndex[l.ChannelID]; ok {
+			ch.FailedListings = append(ch.FailedListings, l)
+		}
+	}
+	if err = failedRows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating listing rows")
+		return
+	}
+
+	// 4. --- Send Response ---
+	if channels == nil {
+		channels = []*ChannelSyncStatus{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"channels": channels,
+	})
+}
+
+// RetryChannelListing is the handler for POST /v1/dropshipper/channels/listings/:id/retry
+// It moves a failed listing back to 'pending' so the sync worker pushes it again.
+func (h *Handlers) RetryChannelListing(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	listingID := c.Param("id")
+
+	// 2. --- Verify Ownership & Status ---
+	var status string
+	checkQuery := `
+		SELECT cl.sync_status
+		FROM channel_listings cl
+		JOIN channels ch ON cl.channel_id = ch.id
+		WHERE cl.id = ? AND ch.user_id = ?
+	`
+	err := h.DB.QueryRowContext(ctx, checkQuery, listingID, dropshipperID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.NotFound(c, "Listing not found")
+			return
+		}
+		apierror.Internal(c, "Failed to fetch listing")
+		return
+	}
+
+	if status != "failed" {
+		apierror.Conflict(c, "Only failed listings can be retried")
+		return
+	}
+
+	// 3. --- Re-queue the Listing ---
+	// The status guard in the WHERE clause protects against a concurrent retry.
+	updateQuery := `
+		UPDATE channel_listings
+		SET sync_status = 'pending', last_error = NULL, updated_at = ?
+		WHERE id = ? AND sync_status = 'failed'
+	`
+	result, err := h.DB.ExecContext(ctx, updateQuery, time.Now(), listingID)
+	if err != nil {
+		apierror.Internal(c, "Failed to queue retry")
+		return
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		apierror.Conflict(c, "Listing is no longer in a failed state")
+		return
+	}
+
+	// 4. --- Send Response ---
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Listing queued for retry",
+		"syncStatus": "pending",
+	})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+// duplicateMessages are the 409 messages for unique keys that several
+// handlers can hit, matched by a fragment of the index name.
+var duplicateMessages = []struct{ key, message string }{
+	{"email", "An account with this email already exists."},
+	{"sku", "You already have a product with this SKU."},
+}
+
+// respondDuplicate answers 409 when err is a unique-key violation and reports
+// whether it did. fallback is used for keys without a shared message (a brand
+// or category slug, for example).
+func respondDuplicate(c *gin.Context, err error, fallback string) bool {
+	key, ok := store.DuplicateKey(err)
+	if !ok {
+		return false
+	}
+	for _, d := range duplicateMessages {
+		if strings.Contains(key, d.key) {
+			apierror.Conflict(c, d.message)
+			return true
+		}
+	}
+	apierror.Conflict(c, fallback)
+	return true
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- End-Customer CRM (Dropshipper-Only) ---
+//
+// A dropshipper's customers are the people their orders ship to. Checkout
+// records the customer it ships to (matched by phone number) or reuses a saved
+// one by ID; the order keeps a copy of the address in orders.ship_to.
+
+// CustomerInput defines the JSON for a customer, at checkout or on its own.
+type CustomerInput struct {
+	Name         string `json:"name" binding:"required,max=255"`
+	Phone        string `json:"phone" binding:"required,max=32,myphone"`
+	AddressLine1 string `json:"addressLine1" binding:"required,max=255"`
+	AddressLine2 string `json:"addressLine2" binding:"max=255"`
+	City         string `json:"city" binding:"required,max=100"`
+	State        string `json:"state" binding:"required,max=100"`
+	Postcode     string `json:"postcode" binding:"required,postcode"`
+	Notes        string `json:"notes" binding:"max=1000"`
+}
+
+// errCustomerPhone is returned for a phone number without enough digits.
+var errCustomerPhone = errors.New("phone must have 7 to 15 digits")
+
+// customerFromInput sanitizes input into a customer of userID.
+func customerFromInput(userID int64, input CustomerInput) (models.Customer, error) {
+	phone := normalizePhone(input.Phone)
+	if len(phone) < 7 || len(phone) > 15 {
+		return models.Customer{}, errCustomerPhone
+	}
+	return models.Customer{
+		UserID:       userID,
+		Name:         sanitize.Text(input.Name),
+		Phone:        phone,
+		AddressLine1: sanitize.Text(input.AddressLine1),
+		AddressLine2: sanitize.Text(input.AddressLine2),
+		City:         sanitize.Text(input.City),
+		State:        sanitize.Text(input.State),
+		Postcode:     sanitize.Text(input.Postcode),
+		Notes:        sanitize.Text(input.Notes),
+	}, nil
+}
+
+// customerColumns is the column list scanned by scanCustomer.
+const customerColumns = `
+	c.id, c.user_id, c.name, c.phone, c.address_line1, c.address_line2, c.city, c.state,
+	c.postcode, c.notes, c.created_at, c.updated_at`
+
+func scanCustomer(row interface{ Scan(...interface{}) error }, extra ...interface{}) (models.Customer, error) {
+	var cu models.Customer
+	err := row.Scan(append([]interface{}{
+		&cu.ID, &cu.UserID, &cu.Name, &cu.Phone, &cu.AddressLine1, &cu.AddressLine2, &cu.City, &cu.State,
+		&cu.Postcode, &cu.Notes, &cu.CreatedAt, &cu.UpdatedAt,
+	}, extra...)...)
+	return cu, err
+}
+
+// getCustomer loads one of userID's customers.
+func getCustomer(ctx context.Context, q Querier, id, userID int64) (*models.Customer, error) {
+	cu, err := scanCustomer(q.QueryRowContext(ctx,
+		"SELECT "+customerColumns+" FROM customers c WHERE c.id = ? AND c.user_id = ?", id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cu, nil
+}
+
+// recordCustomer saves the customer a checkout ships to. A customer with the
+// same phone number is updated with the new details (and keeps its notes when
+// none are given) instead of being duplicated. It sets cu.ID.
+func recordCustomer(ctx context.Context, q Querier, cu *models.Customer) error {
+	now := time.Now()
+	result, err := q.ExecContext(ctx, `
+		INSERT INTO customers
+		(user_id, name, phone, address_line1, address_line2, city, state, postcode, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			id = LAST_INSERT_ID(id), name = VALUES(name), address_line1 = VALUES(address_line1),
+			address_line2 = VALUES(address_line2), city = VALUES(city), state = VALUES(state),
+			postcode = VALUES(postcode), notes = IF(VALUES(notes) = '', notes, VALUES(notes)),
+			updated_at = VALUES(updated_at)`,
+		cu.UserID, cu.Name, cu.Phone, cu.AddressLine1, cu.AddressLine2, cu.City, cu.State, cu.Postcode, cu.Notes, now, now)
+	if err != nil {
+		return err
+	}
+	cu.ID, err = result.LastInsertId()
+	return err
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// customerCursor is the pagination key for customer listings.
+func customerCursor(cu models.Customer) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: cu.CreatedAt, ID: cu.ID}
+}
+
+// GetMyCustomers is the handler for GET /v1/dropshipper/customers
+// filter[q] matches the name or phone. Each customer carries an order count and last order date.
+func (h *Handlers) GetMyCustomers(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Dropshipper ID & Filters ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"q"}})
+	if !ok {
+		return
+	}
+	page := list.Page
+
+	where := " WHERE c.user_id = ?"
+	args := []interface{}{dropshipperID}
+	if q := list.Filter("q"); q != "" {
+		// Phones are stored as digits, so "012-345" matches by its digits.
+		pattern, phonePattern := "%"+escapeLike(q)+"%", "%"+escapeLike(q)+"%"
+		if digits := normalizePhone(q); digits != "" {
+			phonePattern = "%" + digits + "%"
+		}
+		where += " AND (c.name LIKE ? OR c.phone LIKE ?)"
+		args = append(args, pattern, phonePattern)
+	}
+
+	// 2. --- Query Customers (Keyset Pagination) ---
+	cursorCond, cursorArgs := page.Where("c.created_at", "c.id")
+	query := "SELECT " + customerColumns + `, COUNT(o.id), MAX(o.created_at)
+		FROM customers c
+		LEFT JOIN orders o ON o.customer_id = c.id AND o.deleted_at IS NULL` +
+		where + cursorCond + " GROUP BY c.id" + page.OrderLimit("c.created_at", "c.id")
+
+	rows, err := h.readDB().QueryContext(ctx, query, append(args, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch customers")
+		return
+	}
+	defer rows.Close()
+
+	customers := []models.Customer{}
+	for rows.Next() {
+		var count int
+		var lastOrder sql.NullTime
+		cu, err := scanCustomer(rows, &count, &lastOrder)
+		if err != nil {
+			apierror.Internal(c, "Failed to scan customer")
+			return
+		}
+		cu.OrderCount, cu.LastOrderAt = count, lastOrder
+		customers = append(customers, cu)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	// 3. --- Return Response ---
+	customers, nextCursor := pagination.Paginate(page, customers, customerCursor)
+	c.JSON(http.StatusOK, gin.H{"customers": customers, "nextCursor": nextCursor})
+}
+
+// GetMyCustomer is the handler for GET /v1/dropshipper/customers/:id
+// It returns the customer with one page of their order history, newest first.
+func (h *Handlers) GetMyCustomer(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Customer not found")
+		return
+	}
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	page := list.Page
+
+	// 2. --- Fetch Customer & Orders ---
+	cu, err := getCustomer(ctx, h.readDB(), customerID, dropshipperID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Customer not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch customer")
+		return
+	}
+	orders, err := h.Store.Orders.ListByCustomer(ctx, dropshipperID, customerID, page)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch orders")
+		return
+	}
+
+	// 3. --- Return Response ---
+	orders, nextCursor := pagination.Paginate(page, orders, orderCursor)
+	c.JSON(http.StatusOK, gin.H{
+		"customer":   cu,
+		"orders":     orders,
+		"nextCursor": nextCursor,
+	})
+}
+
+// CreateCustomer is the handler for POST /v1/dropshipper/customers
+func (h *Handlers) CreateCustomer(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Dropshipper ID & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+
+	var input CustomerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	cu, err := customerFromInput(dropshipperID, input)
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	// 2. --- Insert ---
+	now := time.Now()
+	cu.CreatedAt, cu.UpdatedAt = now, now
+	result, err := h.DB.ExecContext(ctx, `
+		INSERT INTO customers
+		(user_id, name, phone, address_line1, address_line2, city, state, postcode, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		cu.UserID, cu.Name, cu.Phone, cu.AddressLine1, cu.AddressLine2, cu.City, cu.State, cu.Postcode, cu.Notes, now, now)
+	if err != nil {
+		if respondDuplicate(c, err, "You already have a customer with this phone number.") {
+			return
+		}
+		apierror.Internal(c, "Failed to create customer")
+		return
+	}
+	cu.ID, _ = result.LastInsertId()
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Customer created", "customer": cu})
+}
+
+// UpdateCustomer is the handler for PUT /v1/dropshipper/customers/:id
+// Past orders keep the address they shipped to.
+func (h *Handlers) UpdateCustomer(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Customer not found")
+		return
+	}
+
+	var input CustomerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	cu, err := customerFromInput(dropshipperID, input)
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	// 2. --- Verify Ownership & Save ---
+	existing, err := getCustomer(ctx, h.DB, customerID, dropshipperID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Customer not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch customer")
+		return
+	}
+	cu.ID, cu.CreatedAt, cu.UpdatedAt = existing.ID, existing.CreatedAt, time.Now()
+	_, err = h.DB.ExecContext(ctx, `
+		UPDATE customers
+		SET name = ?, phone = ?, address_line1 = ?, address_line2 = ?, city = ?, state = ?, postcode = ?,
+		    notes = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?`,
+		cu.Name, cu.Phone, cu.AddressLine1, cu.AddressLine2, cu.City, cu.State, cu.Postcode,
+		cu.Notes, cu.UpdatedAt, cu.ID, dropshipperID)
+	if err != nil {
+		if respondDuplicate(c, err, "You already have a customer with this phone number.") {
+			return
+		}
+		apierror.Internal(c, "Failed to update customer")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer updated", "customer": cu})
+}
+
+// DeleteCustomer is the handler for DELETE /v1/dropshipper/customers/:id
+// The customer is removed for good; their orders keep the shipping address.
+func (h *Handlers) DeleteCustomer(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Customer not found")
+		return
+	}
+
+	// 2. --- Unlink Orders & Delete ---
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM customers WHERE id = ? AND user_id = ?", customerID, dropshipperID)
+	if err != nil {
+		apierror.Internal(c, "Failed to delete customer")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		apierror.NotFound(c, "Customer not found")
+		return
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE orders SET customer_id = NULL WHERE customer_id = ? AND user_id = ?", customerID, dropshipperID); err != nil {
+		apierror.Internal(c, "Failed to unlink orders")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer deleted"})
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/money"
 	"github.com/gin-gonic/gin"
 )
 
@@ -11,38 +13,40 @@ import (
 //
 
 type DropshipperStats struct {
-	WalletBalance    float64 `json:"walletBalance"`
-	ProcessingOrders int     `json:"processingOrders"`
-	ActionRequired   int     `json:"actionRequired"` // Count of 'on-hold' orders
+	WalletBalance    money.Money `json:"walletBalance"`
+	ProcessingOrders int         `json:"processingOrders"`
+	ActionRequired   int         `json:"actionRequired"` // Count of 'on-hold' orders
 }
 
 // GetDropshipperStats returns KPI data for the dropshipper dashboard
 // GET /v1/dropshipper/dashboard-stats
 func (h *Handlers) GetDropshipperStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
 
 	stats := DropshipperStats{}
 
 	// 1. Wallet Balance
-	balance, err := h.GetWalletBalance(h.DB, userID)
+	balance, err := h.GetWalletBalance(ctx, h.readDB(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wallet balance"})
+		apierror.Internal(c, "Failed to get wallet balance")
 		return
 	}
 	stats.WalletBalance = balance
 
 	// 2. Processing Orders Count
-	err = h.DB.QueryRow("SELECT COUNT(*) FROM orders WHERE user_id = ? AND status = 'processing'", userID).Scan(&stats.ProcessingOrders)
+	err = h.readDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM orders WHERE user_id = ? AND status = 'processing' AND deleted_at IS NULL", userID).Scan(&stats.ProcessingOrders)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count processing orders"})
+		apierror.Internal(c, "Failed to count processing orders")
 		return
 	}
 
 	// 3. Action Required (On-Hold) Count
-	err = h.DB.QueryRow("SELECT COUNT(*) FROM orders WHERE user_id = ? AND status = 'on-hold'", userID).Scan(&stats.ActionRequired)
+	err = h.readDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM orders WHERE user_id = ? AND status = 'on-hold' AND deleted_at IS NULL", userID).Scan(&stats.ActionRequired)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count on-hold orders"})
+		apierror.Internal(c, "Failed to count on-hold orders")
 		return
 	}
 
@@ -55,19 +59,23 @@ func (h *Handlers) GetDropshipperStats(c *gin.Context) {
 
 type SupplierStats struct {
 	// Private Inventory KPIs (Tab A)
-	TotalValuation float64 `json:"totalValuation"`
-	LowStockCount  int     `json:"lowStockCount"`
+	TotalValuation money.Money `json:"totalValuation"`
+	LowStockCount  int         `json:"lowStockCount"`
 
 	// Marketplace KPIs (Tab B)
-	AvailableBalance float64 `json:"availableBalance"`
-	PendingBalance   float64 `json:"pendingBalance"`
-	LiveProducts     int     `json:"liveProducts"`
-	UnderReview      int     `json:"underReview"`
+	AvailableBalance money.Money `json:"availableBalance"`
+	PendingBalance   money.Money `json:"pendingBalance"`
+	LiveProducts     int         `json:"liveProducts"`
+	UnderReview      int         `json:"underReview"`
+
+	UnansweredQuestions int `json:"unansweredQuestions"` // visible questions on their products without an answer
 }
 
 // GetSupplierStats returns KPI data for the supplier dashboard
 // GET /v1/supplier/dashboard-stats
 func (h *Handlers) GetSupplierStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	supplierID := userID_raw.(int64)
 
@@ -78,11 +86,11 @@ func (h *Handlers) GetSupplierStats(c *gin.Context) {
 	queryValuation := `
 		SELECT COALESCE(SUM(cost_price * stock_quantity), 0)
 		FROM inventory_items
-		WHERE user_id = ?
+		WHERE user_id = ? AND deleted_at IS NULL
 	`
-	err := h.DB.QueryRow(queryValuation, supplierID).Scan(&stats.TotalValuation)
+	err := h.readDB().QueryRowContext(ctx, queryValuation, supplierID).Scan(&stats.TotalValuation)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate valuation"})
+		apierror.Internal(c, "Failed to calculate valuation")
 		return
 	}
 
@@ -90,46 +98,52 @@ func (h *Handlers) GetSupplierStats(c *gin.Context) {
 	queryLowStock := `
 		SELECT COUNT(*)
 		FROM inventory_items
-		WHERE user_id = ? AND stock_quantity < 10
+		WHERE user_id = ? AND stock_quantity < 10 AND deleted_at IS NULL
 	`
-	err = h.DB.QueryRow(queryLowStock, supplierID).Scan(&stats.LowStockCount)
+	err = h.readDB().QueryRowContext(ctx, queryLowStock, supplierID).Scan(&stats.LowStockCount)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count low stock"})
+		apierror.Internal(c, "Failed to count low stock")
 		return
 	}
 
 	// 3. Wallet: Available Balance
-	stats.AvailableBalance, err = h.GetWalletBalance(h.DB, supplierID)
+	stats.AvailableBalance, err = h.GetWalletBalance(ctx, h.readDB(), supplierID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wallet balance"})
+		apierror.Internal(c, "Failed to get wallet balance")
 		return
 	}
 
 	// 4. Wallet: Pending Balance
-	// Logic matches 'withdrawal_handlers.go': Sum of items in 'shipped' orders
-	queryPending := `
-		SELECT COALESCE(SUM(oi.unit_price * oi.quantity), 0)
-		FROM order_items oi
-		JOIN orders o ON oi.order_id = o.id
-		JOIN products p ON oi.product_id = p.id
-		WHERE p.supplier_id = ? AND o.status = 'shipped'
-	`
-	err = h.DB.QueryRow(queryPending, supplierID).Scan(&stats.PendingBalance)
+	// Same as the supplier wallet: their share of the lines in 'shipped' orders
+	stats.PendingBalance, err = h.pendingBalance(ctx, h.readDB(), supplierID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pending balance"})
+		apierror.Internal(c, "Failed to get pending balance")
 		return
 	}
 
 	// 5. Marketplace Product Counts
-	err = h.DB.QueryRow("SELECT COUNT(*) FROM products WHERE supplier_id = ? AND status = 'active'", supplierID).Scan(&stats.LiveProducts)
+	err = h.readDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM products WHERE supplier_id = ? AND status = 'active' AND deleted_at IS NULL", supplierID).Scan(&stats.LiveProducts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count live products"})
+		apierror.Internal(c, "Failed to count live products")
 		return
 	}
 
-	err = h.DB.QueryRow("SELECT COUNT(*) FROM products WHERE supplier_id = ? AND status = 'pending'", supplierID).Scan(&stats.UnderReview)
+	err = h.readDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM products WHERE supplier_id = ? AND status = 'pending' AND deleted_at IS NULL", supplierID).Scan(&stats.UnderReview)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count pending products"})
+		apierror.Internal(c, "Failed to count pending products")
+		return
+	}
+
+	// 6. Product Q&A
+	queryUnanswered := `
+		SELECT COUNT(*)
+		FROM product_questions q
+		JOIN products p ON q.product_id = p.id
+		WHERE p.supplier_id = ? AND q.answer IS NULL AND q.status = 'published' AND p.deleted_at IS NULL
+	`
+	err = h.readDB().QueryRowContext(ctx, queryUnanswered, supplierID).Scan(&stats.UnansweredQuestions)
+	if err != nil {
+		apierror.Internal(c, "Failed to count unanswered questions")
 		return
 	}
 
@@ -149,34 +163,36 @@ type ManagerStats struct {
 // GetManagerStats returns KPI data for the manager dashboard
 // GET /v1/manager/dashboard-stats
 func (h *Handlers) GetManagerStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	stats := ManagerStats{}
 
 	// 1. Pending Products
-	err := h.DB.QueryRow("SELECT COUNT(*) FROM products WHERE status = 'pending'").Scan(&stats.PendingProducts)
+	err := h.readDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM products WHERE status = 'pending' AND deleted_at IS NULL").Scan(&stats.PendingProducts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count pending products"})
+		apierror.Internal(c, "Failed to count pending products")
 		return
 	}
 
 	// 2. Pending Withdrawal Requests
-	err = h.DB.QueryRow("SELECT COUNT(*) FROM withdrawal_requests WHERE status = 'pending'").Scan(&stats.WithdrawalRequests)
+	err = h.readDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM withdrawal_requests WHERE status = 'pending'").Scan(&stats.WithdrawalRequests)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count withdrawal requests"})
+		apierror.Internal(c, "Failed to count withdrawal requests")
 		return
 	}
 
 	// 3. Pending Price Appeals
-	err = h.DB.QueryRow("SELECT COUNT(*) FROM price_appeals WHERE status = 'pending'").Scan(&stats.PriceAppeals)
+	err = h.readDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM price_appeals WHERE status = 'pending'").Scan(&stats.PriceAppeals)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count price appeals"})
+		apierror.Internal(c, "Failed to count price appeals")
 		return
 	}
 
 	// 4. Total Active Users (Dropshippers + Suppliers)
 	// [NEW] We count only active users to give a realistic view of the user base
-	err = h.DB.QueryRow("SELECT COUNT(*) FROM users WHERE status = 'active'").Scan(&stats.TotalUsers)
+	err = h.readDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE status = 'active' AND deleted_at IS NULL").Scan(&stats.TotalUsers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users"})
+		apierror.Internal(c, "Failed to count users")
 		return
 	}
 
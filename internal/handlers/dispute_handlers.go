@@ -0,0 +1,819 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/events"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Dispute Resolution ---
+//
+// A dropshipper disputes a paid order that is not completed yet. While the
+// dispute is open the order cannot be completed, so the supplier payout is
+// held. The supplier answers before respond_by (or the dispute resolves to a
+// full refund), then a manager splits the order's value (orderValue) between a refund to the dropshipper, a payout
+// to the supplier and what the platform keeps.
+
+// maxDisputeEvidence caps the files attached to one dispute.
+const maxDisputeEvidence = 20
+
+// disputeColumns is the column list scanned by scanDispute.
+const disputeColumns = `
+	d.id, d.order_id, d.dropshipper_id, d.supplier_id, d.reason, d.description, d.status,
+	d.supplier_response, d.respond_by, d.responded_at, d.refund_amount, d.supplier_amount,
+	d.platform_amount, d.resolution_note, d.resolved_by, d.resolved_at, d.created_at, d.updated_at,
+	o.public_id, o.total`
+
+func scanDispute(row interface{ Scan(...interface{}) error }) (models.Dispute, error) {
+	var d models.Dispute
+	err := row.Scan(
+		&d.ID, &d.OrderID, &d.DropshipperID, &d.SupplierID, &d.Reason, &d.Description, &d.Status,
+		&d.SupplierResponse, &d.RespondBy, &d.RespondedAt, &d.RefundAmount, &d.SupplierAmount,
+		&d.PlatformAmount, &d.ResolutionNote, &d.ResolvedBy, &d.ResolvedAt, &d.CreatedAt, &d.UpdatedAt,
+		&d.OrderPublicID, &d.OrderTotal,
+	)
+	return d, err
+}
+
+// getDispute loads one dispute; lock adds FOR UPDATE (use it on a transaction).
+func getDispute(ctx context.Context, q Querier, id int64, lock bool) (*models.Dispute, error) {
+	query := "SELECT " + disputeColumns + " FROM disputes d JOIN orders o ON d.order_id = o.id WHERE d.id = ?"
+	if lock {
+		query += " FOR UPDATE"
+	}
+	d, err := scanDispute(q.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// disputeCursor is the pagination key for dispute listings.
+func disputeCursor(d models.Dispute) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+}
+
+// listDisputes answers one page of the list of disputes matching where
+// (starting with "WHERE").
+func (h *Handlers) listDisputes(c *gin.Context, list pagination.List, where string, args ...interface{}) {
+	ctx := c.Request.Context()
+
+	page := list.Page
+	cursorCond, cursorArgs := page.Where("d.created_at", "d.id")
+	query := "SELECT " + disputeColumns + " FROM disputes d JOIN orders o ON d.order_id = o.id " +
+		where + cursorCond + page.OrderLimit("d.created_at", "d.id")
+
+	rows, err := h.DB.QueryContext(ctx, query, append(args, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch disputes")
+		return
+	}
+	defer rows.Close()
+
+	disputes := []models.Dispute{}
+	for rows.Next() {
+		d, err := scanDispute(rows)
+		if err != nil {
+			apierror.Internal(c, "Failed to scan dispute")
+			return
+		}
+		disputes = append(disputes, d)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	disputes, nextCursor := pagination.Paginate(page, disputes, disputeCursor)
+	c.JSON(http.StatusOK, gin.H{"disputes": disputes, "nextCursor": nextCursor})
+}
+
+// respondDispute answers a single dispute with its evidence links, after
+// checking that the caller may see it (allowed returns false to answer 404).
+func (h *Handlers) respondDispute(c *gin.Context, allowed func(d *models.Dispute) bool) {
+	ctx := c.Request.Context()
+
+	disputeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+	d, err := getDispute(ctx, h.DB, disputeID, false)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !allowed(d)) {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch dispute")
+		return
+	}
+
+	if d.Evidence, err = h.disputeEvidence(ctx, d.ID); err != nil {
+		apierror.Internal(c, "Failed to fetch dispute evidence")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dispute": d})
+}
+
+// disputeEvidence returns the dispute's files with fresh signed links.
+func (h *Handlers) disputeEvidence(ctx context.Context, disputeID int64) ([]models.DisputeEvidence, error) {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT id, dispute_id, user_id, file_name, note, created_at
+		FROM dispute_evidence WHERE dispute_id = ? ORDER BY id`, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	evidence := []models.DisputeEvidence{}
+	for rows.Next() {
+		var e models.DisputeEvidence
+		if err := rows.Scan(&e.ID, &e.DisputeID, &e.UserID, &e.FileName, &e.Note, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.URL = h.Uploads.DocumentURLs.URL(h.Config.HTTP.BaseURL, e.FileName)
+		evidence = append(evidence, e)
+	}
+	return evidence, rows.Err()
+}
+
+// publishDispute announces a dispute's new status (notifications to both sides).
+func (h *Handlers) publishDispute(ctx context.Context, d *models.Dispute, automatic bool) {
+	e := events.DisputeUpdated{
+		DisputeID:     d.ID,
+		OrderID:       d.OrderID,
+		DropshipperID: d.DropshipperID,
+		SupplierID:    d.SupplierID,
+		Status:        d.Status,
+		Automatic:     automatic,
+	}
+	if d.RefundAmount != nil {
+		e.RefundAmount = *d.RefundAmount
+	}
+	if d.SupplierAmount != nil {
+		e.SupplierAmount = *d.SupplierAmount
+	}
+	h.Events.Publish(ctx, e)
+}
+
+//
+// --- Dropshipper ---
+//
+
+// OpenDisputeInput defines the JSON for opening a dispute
+type OpenDisputeInput struct {
+	Reason      string `json:"reason" binding:"required,oneof=non_delivery wrong_item damaged other"`
+	Description string `json:"description" binding:"required,max=5000"`
+}
+
+// OpenDispute is the handler for POST /v1/dropshipper/orders/:id/disputes
+func (h *Handlers) OpenDispute(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+
+	var input OpenDisputeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	description := sanitize.Text(input.Description)
+	if description == "" {
+		apierror.BadRequest(c, "A description of the problem is required")
+		return
+	}
+
+	// 2. --- Begin Transaction ---
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	// 3. --- Check the Order (row locked, so it cannot complete meanwhile) ---
+	order, err := tx.Orders.GetForUpdate(ctx, orderID, dropshipperID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order")
+		return
+	}
+	if order.Status != "processing" && order.Status != "shipped" {
+		apierror.BadRequest(c, "Only paid orders that are not completed yet can be disputed")
+		return
+	}
+	if time.Since(order.UpdatedAt) > h.Config.Disputes.OpenWindow {
+		apierror.BadRequest(c, "This order is too old to dispute")
+		return
+	}
+	supplierID, err := tx.Orders.SupplierID(ctx, orderID)
+	if err != nil {
+		apierror.Internal(c, "Failed to find the order's supplier")
+		return
+	}
+
+	// 4. --- Create the Dispute ---
+	now := time.Now()
+	d := &models.Dispute{
+		OrderID:       orderID,
+		DropshipperID: dropshipperID,
+		SupplierID:    supplierID,
+		Reason:        input.Reason,
+		Description:   description,
+		Status:        "open",
+		RespondBy:     now.Add(h.Config.Disputes.ResponseWindow),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		OrderPublicID: order.PublicID,
+		OrderTotal:    order.Total,
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO disputes
+		(order_id, dropshipper_id, supplier_id, reason, description, status, respond_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.OrderID, d.DropshipperID, d.SupplierID, d.Reason, d.Description, d.Status, d.RespondBy, d.CreatedAt, d.UpdatedAt)
+	if err != nil {
+		if respondDuplicate(c, err, "This order already has a dispute.") {
+			return
+		}
+		apierror.Internal(c, "Failed to open dispute")
+		return
+	}
+	d.ID, _ = result.LastInsertId()
+
+	// 5. --- Commit & Notify ---
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	h.publishDispute(ctx, d, false)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Dispute opened. The supplier has been asked to respond.", "dispute": d})
+}
+
+// GetMyDisputes is the handler for GET /v1/dropshipper/disputes
+func (h *Handlers) GetMyDisputes(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	h.listDisputes(c, list, "WHERE d.dropshipper_id = ?", userID_raw.(int64))
+}
+
+// GetMyDispute is the handler for GET /v1/dropshipper/disputes/:id
+func (h *Handlers) GetMyDispute(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	h.respondDispute(c, func(d *models.Dispute) bool { return d.DropshipperID == dropshipperID })
+}
+
+// WithdrawDispute is the handler for POST /v1/dropshipper/disputes/:id/withdraw
+// The dropshipper drops an unresolved dispute; the order can be completed again.
+func (h *Handlers) WithdrawDispute(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	disputeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	d, err := getDispute(ctx, tx, disputeID, true)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && d.DropshipperID != dropshipperID) {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch dispute")
+		return
+	}
+	if d.Status != "open" && d.Status != "under_review" {
+		apierror.Conflict(c, "This dispute is already closed")
+		return
+	}
+
+	d.Status, d.UpdatedAt = "withdrawn", time.Now()
+	if _, err := tx.ExecContext(ctx, "UPDATE disputes SET status = ?, updated_at = ? WHERE id = ?", d.Status, d.UpdatedAt, d.ID); err != nil {
+		apierror.Internal(c, "Failed to withdraw dispute")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	h.publishDispute(ctx, d, false)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dispute withdrawn", "dispute": d})
+}
+
+//
+// --- Supplier ---
+//
+
+// GetSupplierDisputes is the handler for GET /v1/supplier/disputes
+func (h *Handlers) GetSupplierDisputes(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	h.listDisputes(c, list, "WHERE d.supplier_id = ?", userID_raw.(int64))
+}
+
+// GetSupplierDispute is the handler for GET /v1/supplier/disputes/:id
+func (h *Handlers) GetSupplierDispute(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	h.respondDispute(c, func(d *models.Dispute) bool { return d.SupplierID == supplierID })
+}
+
+// RespondToDisputeInput defines the JSON for the supplier's side of a dispute
+type RespondToDisputeInput struct {
+	Response string `json:"response" binding:"required,max=5000"`
+}
+
+// RespondToDispute is the handler for POST /v1/supplier/disputes/:id/respond
+// Answering in time moves the dispute to a manager instead of an automatic refund.
+func (h *Handlers) RespondToDispute(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	disputeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+
+	var input RespondToDisputeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	response := sanitize.Text(input.Response)
+	if response == "" {
+		apierror.BadRequest(c, "A response is required")
+		return
+	}
+
+	// 2. --- Lock & Check the Dispute ---
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	d, err := getDispute(ctx, tx, disputeID, true)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && d.SupplierID != supplierID) {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch dispute")
+		return
+	}
+	if d.Status != "open" {
+		apierror.Conflict(c, "This dispute is no longer awaiting your response")
+		return
+	}
+	now := time.Now()
+	if now.After(d.RespondBy) {
+		apierror.Conflict(c, "The response deadline has passed")
+		return
+	}
+
+	// 3. --- Record the Response ---
+	d.Status, d.UpdatedAt = "under_review", now
+	d.SupplierResponse = sql.NullString{String: response, Valid: true}
+	d.RespondedAt = sql.NullTime{Time: now, Valid: true}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE disputes SET status = ?, supplier_response = ?, responded_at = ?, updated_at = ?
+		WHERE id = ?`, d.Status, response, now, now, d.ID)
+	if err != nil {
+		apierror.Internal(c, "Failed to record response")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	h.publishDispute(ctx, d, false)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Response recorded. A manager will review the dispute.", "dispute": d})
+}
+
+//
+// --- Both Sides: Evidence ---
+//
+
+// AddDisputeEvidence is the handler for POST /v1/disputes/:id/evidence
+// Either party uploads one "file" (image or PDF) with an optional "note".
+func (h *Handlers) AddDisputeEvidence(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs ---
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+	disputeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+
+	// 2. --- Check the Caller Is a Party ---
+	d, err := getDispute(ctx, h.DB, disputeID, false)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && userID != d.DropshipperID && userID != d.SupplierID) {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch dispute")
+		return
+	}
+	if d.Status != "open" && d.Status != "under_review" {
+		apierror.Conflict(c, "Evidence can only be added while the dispute is open")
+		return
+	}
+	var count int
+	if err := h.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM dispute_evidence WHERE dispute_id = ?", d.ID).Scan(&count); err != nil {
+		apierror.Internal(c, "Failed to check evidence")
+		return
+	}
+	if count >= maxDisputeEvidence {
+		apierror.Conflict(c, fmt.Sprintf("A dispute can have at most %d files", maxDisputeEvidence))
+		return
+	}
+
+	// 3. --- Save the File Privately ---
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Uploads.MaxRequestBytes)
+	file, err := c.FormFile("file")
+	if err != nil {
+		h.uploadError(c, err, "No file uploaded")
+		return
+	}
+	name, err := h.Uploads.Documents.Save(ctx, file)
+	if err != nil {
+		h.uploadError(c, err, "Failed to save evidence")
+		return
+	}
+	note := sanitize.Text(c.PostForm("note"))
+	if runes := []rune(note); len(runes) > 1000 {
+		note = string(runes[:1000])
+	}
+
+	// 4. --- Record It ---
+	e := models.DisputeEvidence{DisputeID: d.ID, UserID: userID, FileName: name, Note: note, CreatedAt: time.Now()}
+	result, err := h.DB.ExecContext(ctx, `
+		INSERT INTO dispute_evidence (dispute_id, user_id, file_name, note, created_at)
+		VALUES (?, ?, ?, ?, ?)`, e.DisputeID, e.UserID, e.FileName, e.Note, e.CreatedAt)
+	if err != nil {
+		apierror.Internal(c, "Failed to record evidence")
+		return
+	}
+	e.ID, _ = result.LastInsertId()
+	e.URL = h.Uploads.DocumentURLs.URL(h.Config.HTTP.BaseURL, e.FileName)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Evidence added", "evidence": e})
+}
+
+//
+// --- Manager: Adjudication ---
+//
+
+// GetDisputes is the handler for GET /v1/manager/disputes
+// filter[status] picks a status (e.g. under_review for the review queue).
+func (h *Handlers) GetDisputes(c *gin.Context) {
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"status"}})
+	if !ok {
+		return
+	}
+	switch status := list.Filter("status"); status {
+	case "":
+		h.listDisputes(c, list, "WHERE 1 = 1")
+	case "open", "under_review", "resolved", "withdrawn":
+		h.listDisputes(c, list, "WHERE d.status = ?", status)
+	default:
+		apierror.BadRequest(c, "status must be one of open, under_review, resolved, withdrawn")
+	}
+}
+
+// GetDispute is the handler for GET /v1/manager/disputes/:id
+func (h *Handlers) GetDispute(c *gin.Context) {
+	h.respondDispute(c, func(*models.Dispute) bool { return true })
+}
+
+// ResolveDisputeInput defines the JSON for a manager's decision.
+// The refund is at most what the dropshipper paid, and both amounts together at
+// most orderValue; whatever they leave of it stays with the platform.
+type ResolveDisputeInput struct {
+	RefundAmount   *money.Money `json:"refundAmount" binding:"required,gte=0"`
+	SupplierAmount *money.Money `json:"supplierAmount" binding:"required,gte=0"`
+	Note           string       `json:"note" binding:"required,max=5000"`
+}
+
+// ResolveDispute is the handler for PATCH /v1/manager/disputes/:id/resolve
+func (h *Handlers) ResolveDispute(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+	disputeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+
+	var input ResolveDisputeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	// 2. --- Begin Transaction ---
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	// 3. --- Lock & Check the Dispute ---
+	d, err := getDispute(ctx, tx, disputeID, true)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch dispute")
+		return
+	}
+	if d.Status != "open" && d.Status != "under_review" {
+		apierror.Conflict(c, "This dispute is already closed")
+		return
+	}
+
+	refund, payout := *input.RefundAmount, *input.SupplierAmount
+
+	// 4. --- Move the Money & Close ---
+	productIDs, err := h.settleDispute(ctx, tx, d, refund, payout, sanitize.Text(input.Note), &managerID)
+	if err != nil {
+		if errors.Is(err, errOrderNotDisputable) {
+			apierror.Conflict(c, err.Error())
+			return
+		}
+		var split *disputeSplitError
+		if errors.As(err, &split) {
+			apierror.BadRequest(c, split.Error())
+			return
+		}
+		apierror.Internal(c, "Failed to resolve dispute")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	h.invalidateProducts(ctx, productIDs...)
+	h.publishDispute(ctx, d, false)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dispute resolved", "dispute": d})
+}
+
+// errOrderNotDisputable means the order left the paid, uncompleted states
+// (it should not while a dispute holds it).
+var errOrderNotDisputable = errors.New("the order is no longer awaiting completion")
+
+// disputeSplitError is a refund and payout the order cannot cover.
+type disputeSplitError struct{ message string }
+
+func (e *disputeSplitError) Error() string { return e.message }
+
+// settleDispute refunds the dropshipper, pays the suppliers, marks the dispute
+// resolved and closes the order (completed with a payout, cancelled without),
+// all on tx. A payout is split between the order's suppliers by the value of
+// their lines. An unshipped order cancelled gives its stock back, like
+// CancelOrder; the products touched are returned for cache invalidation after
+// the commit. resolvedBy is nil for the deadline job. It updates d in place.
+func (h *Handlers) settleDispute(ctx context.Context, tx *store.Tx, d *models.Dispute, refund, payout money.Money, note string, resolvedBy *int64) ([]int64, error) {
+	// A. Lock the order: it must still hold the money
+	order, err := tx.Orders.GetForUpdate(ctx, d.OrderID, d.DropshipperID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != "processing" && order.Status != "shipped" {
+		return nil, errOrderNotDisputable
+	}
+
+	// The refund is of what the dropshipper paid; refund and payout together
+	// are of what completing the order would pay out.
+	base := orderValue(order)
+	if refund > order.Total {
+		return nil, &disputeSplitError{fmt.Sprintf("refundAmount cannot exceed what the dropshipper paid (RM %s)", order.Total)}
+	}
+	if refund+payout > base {
+		return nil, &disputeSplitError{fmt.Sprintf("refundAmount + supplierAmount cannot exceed the order value (RM %s)", base)}
+	}
+
+	// B. Ledger entries
+	if refund > 0 {
+		notes := fmt.Sprintf("Refund for disputed Order #%d", d.OrderID)
+		if err := tx.Wallet.AddTransaction(ctx, d.DropshipperID, "refund", refund, notes); err != nil {
+			return nil, err
+		}
+	}
+	if payout > 0 {
+		subtotals, err := tx.Orders.SupplierSubtotals(ctx, d.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		if len(subtotals) == 0 {
+			subtotals = []models.SupplierSubtotal{{SupplierID: d.SupplierID}}
+		}
+		notes := fmt.Sprintf("Payout for disputed Order #%d", d.OrderID)
+		for i, share := range splitPayout(payout, subtotals) {
+			if share == 0 {
+				continue
+			}
+			if err := tx.Wallet.AddTransaction(ctx, subtotals[i].SupplierID, "payout", share, notes); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// C. Close the order; cancelling an unshipped one gives back its stock
+	// and releases any hold still open, like CancelOrder
+	orderStatus := "cancelled"
+	if payout > 0 {
+		orderStatus = "completed"
+	}
+	var productIDs []int64
+	if orderStatus == "cancelled" && order.Status == "processing" {
+		if productIDs, err = releaseOrderStock(ctx, tx, d.OrderID); err != nil {
+			return nil, err
+		}
+		if err := tx.Wallet.CloseHold(ctx, d.OrderID, store.HoldReleased); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Orders.UpdateStatus(ctx, d.OrderID, orderStatus); err != nil {
+		return nil, err
+	}
+
+	// D. Close the dispute
+	now := time.Now()
+	platform := base - refund - payout
+	d.Status, d.UpdatedAt = "resolved", now
+	d.RefundAmount, d.SupplierAmount, d.PlatformAmount = &refund, &payout, &platform
+	d.ResolutionNote = sql.NullString{String: note, Valid: note != ""}
+	d.ResolvedAt = sql.NullTime{Time: now, Valid: true}
+	if resolvedBy != nil {
+		d.ResolvedBy = sql.NullInt64{Int64: *resolvedBy, Valid: true}
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE disputes
+		SET status = ?, refund_amount = ?, supplier_amount = ?, platform_amount = ?,
+			resolution_note = ?, resolved_by = ?, resolved_at = ?, updated_at = ?
+		WHERE id = ?`,
+		d.Status, refund, payout, platform, d.ResolutionNote, d.ResolvedBy, now, now, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	return productIDs, nil
+}
+
+// splitPayout divides payout between an order's suppliers in proportion to
+// the value of their lines; the last takes the rounding remainder.
+func splitPayout(payout money.Money, subtotals []models.SupplierSubtotal) []money.Money {
+	shares := make([]money.Money, len(subtotals))
+	if len(shares) == 0 {
+		return shares
+	}
+	var total money.Money
+	for _, st := range subtotals {
+		total += st.Amount
+	}
+	left := payout
+	for i, st := range subtotals[:len(subtotals)-1] {
+		if total > 0 {
+			shares[i] = money.Money(int64(payout) * int64(st.Amount) / int64(total))
+		}
+		left -= shares[i]
+	}
+	shares[len(shares)-1] = left
+	return shares
+}
+
+//
+// --- Deadline Job ---
+//
+
+// ProcessDisputeDeadlines resolves open disputes whose supplier missed the
+// response deadline with a full refund to the dropshipper. The background
+// worker calls it every DISPUTE_CHECK_INTERVAL.
+func (h *Handlers) ProcessDisputeDeadlines(ctx context.Context) {
+	rows, err := h.DB.QueryContext(ctx, "SELECT id FROM disputes WHERE status = 'open' AND respond_by < ? ORDER BY respond_by LIMIT 500", time.Now())
+	if err != nil {
+		logging.Errorf("[Disputes] Error fetching overdue disputes: %v", err)
+		return
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			logging.Errorf("[Disputes] Error scanning overdue disputes: %v", err)
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logging.Errorf("[Disputes] Error fetching overdue disputes: %v", err)
+		return
+	}
+
+	// Like ProcessOverdueOrders: stop between disputes on shutdown, never mid-way.
+	for i, id := range ids {
+		if ctx.Err() != nil {
+			logging.Infof("[Disputes] Shutting down, %d overdue disputes left for the next run", len(ids)-i)
+			return
+		}
+		h.autoResolveDispute(context.WithoutCancel(ctx), id)
+	}
+}
+
+// autoResolveDispute refunds one overdue dispute in full.
+func (h *Handlers) autoResolveDispute(ctx context.Context, disputeID int64) {
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		logging.Errorf("[Disputes] Failed to begin tx for Dispute %d: %v", disputeID, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Re-check under the lock: the supplier may have just responded.
+	d, err := getDispute(ctx, tx, disputeID, true)
+	if err != nil {
+		logging.Errorf("[Disputes] Failed to fetch Dispute %d: %v", disputeID, err)
+		return
+	}
+	if d.Status != "open" || time.Now().Before(d.RespondBy) {
+		return
+	}
+
+	note := "Resolved automatically: the supplier did not respond before the deadline."
+	productIDs, err := h.settleDispute(ctx, tx, d, d.OrderTotal, 0, note, nil)
+	if err != nil {
+		logging.Errorf("[Disputes] Failed to resolve Dispute %d: %v", disputeID, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logging.Errorf("[Disputes] Failed to commit tx for Dispute %d: %v", disputeID, err)
+		return
+	}
+	h.invalidateProducts(ctx, productIDs...)
+	h.publishDispute(ctx, d, true)
+
+	logging.Infof("[Disputes] Dispute %d on Order %d refunded in full after the response deadline", d.ID, d.OrderID)
+}
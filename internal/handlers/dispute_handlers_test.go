@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+func TestSplitPayout(t *testing.T) {
+	tests := []struct {
+		name      string
+		payout    money.Money
+		subtotals []money.Money
+		want      []money.Money
+	}{
+		{"one supplier takes all", 10000, []money.Money{2500}, []money.Money{10000}},
+		{"by line value", 9000, []money.Money{1000, 2000}, []money.Money{3000, 6000}},
+		{"remainder to the last", 1000, []money.Money{1, 1, 1}, []money.Money{333, 333, 334}},
+		{"no line value", 500, []money.Money{0, 0}, []money.Money{0, 500}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subtotals := make([]models.SupplierSubtotal, len(tt.subtotals))
+			for i, amount := range tt.subtotals {
+				subtotals[i] = models.SupplierSubtotal{SupplierID: int64(i + 1), Amount: amount}
+			}
+			got := splitPayout(tt.payout, subtotals)
+			var sum money.Money
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("splitPayout = %v, want %v", got, tt.want)
+				}
+				sum += got[i]
+			}
+			if sum != tt.payout {
+				t.Fatalf("shares add up to %s, want %s", sum, tt.payout)
+			}
+		})
+	}
+}
@@ -0,0 +1,559 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/barcode"
+	"github.com/01moynul/taptosell-golang/internal/dedupe"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Duplicate Listings ---
+//
+// The same item listed by several suppliers splits its reviews and sales and
+// clutters the catalogue. The duplicate job pairs up listings sharing a
+// barcode (in any GTIN form) or an image URL (uploads get random names, so a
+// shared one was copied), or with near-identical titles, and groups the pairs
+// into clusters for managers to resolve.
+
+// ProcessDuplicates flags the new suspected duplicates among active and
+// pending products. Each pair is recorded once, so a dismissed pair is not
+// flagged again; a new pair joins the open cluster of either product (merging
+// two clusters it connects) or opens one. The background worker calls it
+// every DUPLICATE_CHECK_INTERVAL.
+func (h *Handlers) ProcessDuplicates(ctx context.Context) {
+	// 1. --- Load the Listings ---
+	listings, err := h.duplicateListings(ctx)
+	if err != nil {
+		logging.Errorf("[Duplicates] Error loading listings: %v", err)
+		return
+	}
+
+	// 2. --- Find Pairs & Skip the Recorded Ones ---
+	pairs := dedupe.Pairs(listings, h.Config.Products.DuplicateSimilarity)
+	known := map[[2]int64]bool{}
+	rows, err := h.DB.QueryContext(ctx, "SELECT product_a, product_b FROM duplicate_pairs")
+	if err != nil {
+		logging.Errorf("[Duplicates] Error fetching recorded pairs: %v", err)
+		return
+	}
+	for rows.Next() {
+		var key [2]int64
+		if err := rows.Scan(&key[0], &key[1]); err != nil {
+			rows.Close()
+			logging.Errorf("[Duplicates] Error scanning recorded pair: %v", err)
+			return
+		}
+		known[key] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logging.Errorf("[Duplicates] Error fetching recorded pairs: %v", err)
+		return
+	}
+
+	// 3. --- Record the New Pairs ---
+	flagged := 0
+	for _, p := range pairs {
+		if known[[2]int64{p.A, p.B}] {
+			continue
+		}
+		if ctx.Err() != nil {
+			logging.Infof("[Duplicates] Shutting down, the rest is left for the next run")
+			break
+		}
+		if err := h.recordDuplicate(context.WithoutCancel(ctx), p); err != nil {
+			logging.Errorf("[Duplicates] Failed to record Products %d and %d: %v", p.A, p.B, err)
+			continue
+		}
+		flagged++
+	}
+	if flagged > 0 {
+		logging.Infof("[Duplicates] Flagged %d new duplicate pairs", flagged)
+	}
+}
+
+// duplicateListings loads the active and pending products with their
+// barcodes (the product's and its variants', as GTIN-14) and images.
+func (h *Handlers) duplicateListings(ctx context.Context) ([]dedupe.Listing, error) {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT id, supplier_id, name, barcode, images FROM products
+		WHERE status IN ('active', 'pending') AND deleted_at IS NULL
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	var listings []dedupe.Listing
+	byID := map[int64]int{}
+	for rows.Next() {
+		var l dedupe.Listing
+		var code sql.NullString
+		var images []byte
+		if err := rows.Scan(&l.ID, &l.SupplierID, &l.Title, &code, &images); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if code.String != "" {
+			l.Barcodes = append(l.Barcodes, gtin(code.String))
+		}
+		_ = json.Unmarshal(images, &l.Images)
+		byID[l.ID] = len(listings)
+		listings = append(listings, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = h.DB.QueryContext(ctx, "SELECT product_id, barcode FROM product_variants WHERE barcode IS NOT NULL AND barcode <> ''")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var productID int64
+		var code string
+		if err := rows.Scan(&productID, &code); err != nil {
+			return nil, err
+		}
+		if i, ok := byID[productID]; ok {
+			listings[i].Barcodes = append(listings[i].Barcodes, gtin(code))
+		}
+	}
+	return listings, rows.Err()
+}
+
+// gtin is the 14-digit form of a stored barcode, so an EAN-13 and the same
+// UPC-A compare equal.
+func gtin(code string) string {
+	forms := barcode.Forms(code)
+	return forms[len(forms)-1]
+}
+
+// recordDuplicate records one new pair in its cluster. Nothing changes when
+// another instance recorded it first.
+func (h *Handlers) recordDuplicate(ctx context.Context, p dedupe.Pair) error {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 1. --- Find the Open Clusters of Either Product ---
+	now := time.Now()
+	rows, err := tx.QueryContext(ctx, `
+		SELECT c.id FROM duplicate_clusters c
+		WHERE c.status = 'open' AND EXISTS (
+			SELECT 1 FROM duplicate_pairs d
+			WHERE d.cluster_id = c.id AND (d.product_a IN (?, ?) OR d.product_b IN (?, ?)))
+		ORDER BY c.id
+		FOR UPDATE`, p.A, p.B, p.A, p.B)
+	if err != nil {
+		return err
+	}
+	var clusters []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		clusters = append(clusters, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// 2. --- Join, Merge or Open a Cluster ---
+	var clusterID int64
+	switch len(clusters) {
+	case 0:
+		result, err := tx.ExecContext(ctx,
+			"INSERT INTO duplicate_clusters (status, created_at, updated_at) VALUES ('open', ?, ?)", now, now)
+		if err != nil {
+			return err
+		}
+		if clusterID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+	default:
+		// The pair connects the clusters: the oldest takes the others' pairs.
+		clusterID = clusters[0]
+		if merged := clusters[1:]; len(merged) > 0 {
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(merged)), ", ")
+			args := []interface{}{clusterID}
+			for _, id := range merged {
+				args = append(args, id)
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE duplicate_pairs SET cluster_id = ? WHERE cluster_id IN ("+placeholders+")", args...); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM duplicate_clusters WHERE id IN ("+placeholders+")", args[1:]...); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE duplicate_clusters SET updated_at = ? WHERE id = ?", now, clusterID); err != nil {
+			return err
+		}
+	}
+
+	// 3. --- Record the Pair ---
+	result, err := tx.ExecContext(ctx, `
+		INSERT IGNORE INTO duplicate_pairs (product_a, product_b, cluster_id, reasons, similarity, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		p.A, p.B, clusterID, strings.Join(p.Reasons, ","), p.Similarity, now)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil // recorded by another instance; the rollback drops any new cluster
+	}
+	return tx.Commit()
+}
+
+// GetDuplicates is the handler for GET /v1/manager/duplicates
+// It lists the duplicate clusters, oldest first within ?filter[status]=
+// (default open), with their listings as they are now and why each two were
+// linked.
+func (h *Handlers) GetDuplicates(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, ok := parseList(c, pagination.ListSpec{Sorts: []string{"createdAt", "-createdAt"}, Filters: []string{"status"}})
+	if !ok {
+		return
+	}
+	status := "open"
+	switch s := list.Filter("status"); s {
+	case "":
+	case "open", "merged", "rejected", "dismissed":
+		status = s
+	default:
+		apierror.BadRequest(c, "status must be one of open, merged, rejected, dismissed")
+		return
+	}
+
+	// 1. --- Fetch the Page of Clusters ---
+	page := list.Page
+	cursorCond, cursorArgs := page.Where("created_at", "id")
+	query := `
+		SELECT id, status, kept_product_id, resolved_by, resolution_note, created_at, resolved_at
+		FROM duplicate_clusters WHERE status = ?` + cursorCond + page.OrderLimit("created_at", "id")
+	rows, err := h.readDB().QueryContext(ctx, query, append([]interface{}{status}, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch duplicates")
+		return
+	}
+	defer rows.Close()
+
+	clusters := []models.DuplicateCluster{}
+	for rows.Next() {
+		var d models.DuplicateCluster
+		var kept, resolvedBy sql.NullInt64
+		var note sql.NullString
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.Status, &kept, &resolvedBy, &note, &d.CreatedAt, &resolvedAt); err != nil {
+			apierror.Internal(c, "Failed to scan duplicate")
+			return
+		}
+		if kept.Valid {
+			d.KeptProductID = &kept.Int64
+		}
+		if resolvedBy.Valid {
+			d.ResolvedBy = &resolvedBy.Int64
+		}
+		if note.Valid {
+			d.ResolutionNote = &note.String
+		}
+		if resolvedAt.Valid {
+			d.ResolvedAt = &resolvedAt.Time
+		}
+		clusters = append(clusters, d)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+	rows.Close()
+
+	clusters, nextCursor := pagination.Paginate(page, clusters, func(d models.DuplicateCluster) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+	})
+
+	// 2. --- Attach Pairs & Listings ---
+	for i := range clusters {
+		if err := h.loadDuplicateCluster(ctx, h.readDB(), &clusters[i]); err != nil {
+			apierror.Internal(c, "Failed to fetch duplicates")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"duplicates": clusters, "nextCursor": nextCursor})
+}
+
+// loadDuplicateCluster fills in the pairs and listings of d.
+func (h *Handlers) loadDuplicateCluster(ctx context.Context, q Querier, d *models.DuplicateCluster) error {
+	rows, err := q.QueryContext(ctx,
+		"SELECT product_a, product_b, reasons, similarity FROM duplicate_pairs WHERE cluster_id = ? ORDER BY product_a, product_b", d.ID)
+	if err != nil {
+		return err
+	}
+	d.Pairs = []models.DuplicatePair{}
+	var ids []interface{}
+	seen := map[int64]bool{}
+	for rows.Next() {
+		var p models.DuplicatePair
+		var reasons string
+		if err := rows.Scan(&p.ProductA, &p.ProductB, &reasons, &p.Similarity); err != nil {
+			rows.Close()
+			return err
+		}
+		p.Reasons = strings.Split(reasons, ",")
+		d.Pairs = append(d.Pairs, p)
+		for _, id := range []int64{p.ProductA, p.ProductB} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	d.Products = []models.DuplicateProduct{}
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	rows, err = q.QueryContext(ctx, `
+		SELECT p.id, p.public_id, p.name, p.supplier_id, u.full_name, p.status, p.barcode,
+			JSON_UNQUOTE(JSON_EXTRACT(p.images, '$[0]')), p.price_to_tts, p.deleted_at IS NOT NULL
+		FROM products p JOIN users u ON u.id = p.supplier_id
+		WHERE p.id IN (`+placeholders+`)
+		ORDER BY p.id`, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.DuplicateProduct
+		var code, image sql.NullString
+		if err := rows.Scan(&p.ID, &p.PublicID, &p.Name, &p.SupplierID, &p.SupplierName, &p.Status, &code,
+			&image, &p.Price, &p.Deleted); err != nil {
+			return err
+		}
+		if code.Valid && code.String != "" {
+			p.Barcode = &code.String
+		}
+		p.Image = image.String
+		d.Products = append(d.Products, p)
+	}
+	return rows.Err()
+}
+
+// ResolveDuplicateInput is the body of POST /v1/manager/duplicates/:id/resolve.
+// KeepProductID is the listing that stays, for merge and reject.
+type ResolveDuplicateInput struct {
+	Action        string `json:"action" binding:"required,oneof=merge reject dismiss"`
+	KeepProductID int64  `json:"keepProductId"`
+	Reason        string `json:"reason" binding:"max=500"`
+}
+
+// ResolveDuplicate is the handler for POST /v1/manager/duplicates/:id/resolve
+// It settles an open cluster:
+//   - merge keeps one listing and deletes the others (restorable like any
+//     deleted product); their watchers now watch the one kept.
+//   - reject keeps one listing and rejects the others with the reason, so
+//     their suppliers can fix and resubmit them.
+//   - dismiss records that the listings are not duplicates.
+//
+// The suppliers of deleted or rejected listings are notified.
+func (h *Handlers) ResolveDuplicate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+	clusterID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Duplicate not found")
+		return
+	}
+
+	var input ResolveDuplicateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if input.Action != "dismiss" && input.KeepProductID == 0 {
+		apierror.BadRequest(c, "keepProductId is required to merge or reject")
+		return
+	}
+	if input.Action == "reject" && input.Reason == "" {
+		apierror.BadRequest(c, "A reason is required when rejecting")
+		return
+	}
+
+	// 2. --- Begin Transaction ---
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	// 3. --- Lock & Check the Cluster ---
+	d := models.DuplicateCluster{ID: clusterID}
+	err = tx.QueryRowContext(ctx, "SELECT status FROM duplicate_clusters WHERE id = ? FOR UPDATE", clusterID).Scan(&d.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.NotFound(c, "Duplicate not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch duplicate")
+		return
+	}
+	if d.Status != "open" {
+		apierror.Conflict(c, "This duplicate has already been resolved")
+		return
+	}
+	if err := h.loadDuplicateCluster(ctx, tx, &d); err != nil {
+		apierror.Internal(c, "Failed to fetch duplicate")
+		return
+	}
+
+	// 4. --- Pick the Listing Kept & the Others ---
+	var kept *models.DuplicateProduct
+	var others []models.DuplicateProduct
+	for i, p := range d.Products {
+		if p.ID == input.KeepProductID {
+			kept = &d.Products[i]
+		} else if !p.Deleted {
+			others = append(others, p)
+		}
+	}
+	if input.Action != "dismiss" {
+		if kept == nil {
+			apierror.BadRequest(c, "keepProductId must be a product of this duplicate")
+			return
+		}
+		if kept.Deleted {
+			apierror.Conflict(c, "The product to keep has been deleted")
+			return
+		}
+	}
+
+	// 5. --- Carry Out the Decision ---
+	now := time.Now()
+	var touched []int64
+	switch input.Action {
+	case "merge":
+		for _, p := range others {
+			result, err := tx.ExecContext(ctx,
+				"UPDATE products SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL", now, p.ID)
+			if err != nil {
+				apierror.Internal(c, "Failed to merge duplicates")
+				return
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				continue
+			}
+			// Watchers move to the listing kept, from its current price and
+			// stock; those already watching it keep their one entry.
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE IGNORE watchlist w JOIN products p ON p.id = ?
+				SET w.product_id = p.id, w.last_price = p.price_to_tts, w.in_stock = `+watchedStock+` > 0, w.checked_at = p.changed_at
+				WHERE w.product_id = ?`, kept.ID, p.ID); err != nil {
+				apierror.Internal(c, "Failed to merge duplicates")
+				return
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM watchlist WHERE product_id = ?", p.ID); err != nil {
+				apierror.Internal(c, "Failed to merge duplicates")
+				return
+			}
+			message := fmt.Sprintf("Your product \"%s\" was removed as a duplicate of another supplier's listing.", p.Name)
+			if input.Reason != "" {
+				message += " Reason: " + input.Reason
+			}
+			if err := h.AddBatchedNotification(ctx, tx, p.SupplierID, "duplicate_removed", message, "/supplier/products"); err != nil {
+				apierror.Internal(c, "Failed to send notification")
+				return
+			}
+			touched = append(touched, p.ID)
+		}
+	case "reject":
+		for _, p := range others {
+			before, err := tx.Products.Snapshot(ctx, p.ID)
+			if err != nil {
+				apierror.Internal(c, "Failed to get product details")
+				return
+			}
+			result, err := tx.ExecContext(ctx, `
+				UPDATE products
+				SET status = 'rejected', rejection_reason = ?, updated_at = ?, version = version + 1
+				WHERE id = ? AND status IN ('active', 'pending') AND deleted_at IS NULL`,
+				input.Reason, now, p.ID)
+			if err != nil {
+				apierror.Internal(c, "Failed to reject product")
+				return
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				continue
+			}
+			if err := h.recordRevision(ctx, tx, p.ID, managerID, "reject", nil, &input.Reason, before); err != nil {
+				apierror.Internal(c, "Failed to record revision")
+				return
+			}
+			message := fmt.Sprintf("Your product \"%s\" was rejected as a duplicate of another supplier's listing. Reason: %s", p.Name, input.Reason)
+			if err := h.AddBatchedNotification(ctx, tx, p.SupplierID, "product_rejected", message, "/supplier/products"); err != nil {
+				apierror.Internal(c, "Failed to send notification")
+				return
+			}
+			touched = append(touched, p.ID)
+		}
+	}
+
+	// 6. --- Close the Cluster ---
+	status := map[string]string{"merge": "merged", "reject": "rejected", "dismiss": "dismissed"}[input.Action]
+	var keptID, note interface{}
+	if kept != nil && input.Action != "dismiss" {
+		keptID = kept.ID
+	}
+	if input.Reason != "" {
+		note = input.Reason
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE duplicate_clusters
+		SET status = ?, kept_product_id = ?, resolved_by = ?, resolution_note = ?, resolved_at = ?, updated_at = ?
+		WHERE id = ?`,
+		status, keptID, managerID, note, now, now, clusterID); err != nil {
+		apierror.Internal(c, "Failed to resolve duplicate")
+		return
+	}
+
+	// 7. --- Commit Transaction ---
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	if len(touched) > 0 {
+		h.invalidateProducts(ctx, touched...)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Duplicate resolved", "status": status, "products": touched})
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Admin: Application Error Log ---
+//
+// Reads the app_errors ring buffer filled by errreport.DB, so operators
+// without log infrastructure can look up what failed, for whom, and where.
+
+// GetAppErrors is the handler for GET /v1/admin/errors
+// Filters: status, route, userId, requestId, panic=true|false, since/until (RFC 3339).
+// Stacks are left out of the list; fetch one error by ID to see its stack.
+func (h *Handlers) GetAppErrors(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"status", "route", "userId", "requestId", "panic", "since", "until"}})
+	if !ok {
+		return
+	}
+	page := list.Page
+
+	// 1. --- Build Filters ---
+	where := "WHERE 1 = 1"
+	var args []interface{}
+	if v := list.Filter("status"); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil {
+			apierror.BadRequest(c, "status must be a number")
+			return
+		}
+		where += " AND status = ?"
+		args = append(args, status)
+	}
+	if v := list.Filter("route"); v != "" {
+		where += " AND route = ?"
+		args = append(args, v)
+	}
+	if v := list.Filter("userId"); v != "" {
+		userID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			apierror.BadRequest(c, "userId must be a number")
+			return
+		}
+		where += " AND user_id = ?"
+		args = append(args, userID)
+	}
+	if v := list.Filter("requestId"); v != "" {
+		where += " AND request_id = ?"
+		args = append(args, v)
+	}
+	if v := list.Filter("panic"); v != "" {
+		isPanic, err := strconv.ParseBool(v)
+		if err != nil {
+			apierror.BadRequest(c, "panic must be true or false")
+			return
+		}
+		where += " AND is_panic = ?"
+		args = append(args, isPanic)
+	}
+	for _, bound := range []struct{ param, cond string }{{"since", " AND created_at >= ?"}, {"until", " AND created_at < ?"}} {
+		v := list.Filter(bound.param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			apierror.BadRequest(c, bound.param+" must be an RFC 3339 timestamp")
+			return
+		}
+		where += bound.cond
+		args = append(args, t)
+	}
+
+	seek, seekArgs := page.Where("created_at", "id")
+	args = append(args, seekArgs...)
+
+	// 2. --- Query (newest first) ---
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT id, request_id, trace_id, user_id, method, route, path, status, is_panic, message, created_at
+		FROM app_errors `+where+seek+page.OrderLimit("created_at", "id"), args...)
+	if err != nil {
+		apierror.Internal(c, "Failed to load errors")
+		return
+	}
+	defer rows.Close()
+
+	appErrors := []models.AppError{}
+	for rows.Next() {
+		var e models.AppError
+		if err := rows.Scan(&e.ID, &e.RequestID, &e.TraceID, &e.UserID, &e.Method, &e.Route, &e.Path, &e.Status, &e.IsPanic, &e.Message, &e.CreatedAt); err != nil {
+			apierror.Internal(c, "Failed to read errors")
+			return
+		}
+		appErrors = append(appErrors, e)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Failed to read errors")
+		return
+	}
+	appErrors, nextCursor := pagination.Paginate(page, appErrors, func(e models.AppError) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: e.CreatedAt, ID: e.ID}
+	})
+
+	// 3. --- Send Response ---
+	c.JSON(http.StatusOK, gin.H{
+		"errors":     appErrors,
+		"nextCursor": nextCursor,
+	})
+}
+
+// GetAppError is the handler for GET /v1/admin/errors/:id
+// It returns one error including its stack trace.
+func (h *Handlers) GetAppError(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.BadRequest(c, "Invalid error ID")
+		return
+	}
+
+	var e models.AppError
+	err = h.DB.QueryRowContext(ctx, `
+		SELECT id, request_id, trace_id, user_id, method, route, path, status, is_panic, message, stack, created_at
+		FROM app_errors WHERE id = ?`, id).
+		Scan(&e.ID, &e.RequestID, &e.TraceID, &e.UserID, &e.Method, &e.Route, &e.Path, &e.Status, &e.IsPanic, &e.Message, &e.Stack, &e.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.NotFound(c, "Error not found (it may have rotated out)")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to load error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"appError": e})
+}
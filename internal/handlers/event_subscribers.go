@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/events"
+)
+
+//
+// --- Domain Event Subscribers ---
+//
+// Side effects that used to be inlined in the handlers. They run after the
+// publishing transaction committed, so they use h.DB, not the request's tx.
+
+// RegisterSubscribers attaches every subscriber to bus. Call it once at startup.
+func (h *Handlers) RegisterSubscribers(bus *events.Bus) {
+	// --- Product Approved ---
+	events.On(bus, "invalidate-product-cache", func(ctx context.Context, e events.ProductApproved) error {
+		h.invalidateProducts(ctx, e.ProductID)
+		return nil
+	})
+	events.On(bus, "notify-supplier", h.notifyProductApproved)
+	events.OnAsync(bus, "resync-channel-listings", h.resyncChannelListings)
+
+	// --- Product Restocked ---
+	events.OnAsync(bus, "convert-preorders", h.convertRestockedPreorders)
+
+	// --- Stock Low ---
+	events.OnAsync(bus, "notify-supplier", h.notifyLowStock)
+
+	// --- Order Paid ---
+	events.OnAsync(bus, "notify-suppliers", h.notifySuppliersOfPaidOrder)
+
+	// --- Order Completed ---
+	events.OnAsync(bus, "reward-referral", h.rewardReferral)
+
+	// --- Dispute Updated ---
+	events.On(bus, "notify-parties", h.notifyDisputeParties)
+
+	// --- Withdrawal Approved ---
+	events.On(bus, "notify-user", func(ctx context.Context, e events.WithdrawalApproved) error {
+		message := fmt.Sprintf("Your withdrawal of RM %s has been approved.", e.Amount)
+		return h.AddNotification(ctx, h.DB, e.UserID, message, "/supplier/wallet")
+	})
+}
+
+// resyncChannelListings queues every channel listing of a newly approved product
+// for the channel sync worker, so marketplaces pick up the approved data.
+func (h *Handlers) resyncChannelListings(ctx context.Context, e events.ProductApproved) error {
+	_, err := h.DB.ExecContext(ctx, `
+		UPDATE channel_listings
+		SET sync_status = 'pending', last_error = NULL, updated_at = ?
+		WHERE product_id = ? AND sync_status <> 'pending'`,
+		time.Now(), e.ProductID)
+	return err
+}
+
+// notifyProductApproved tells the supplier; approvals in bulk are batched.
+func (h *Handlers) notifyProductApproved(ctx context.Context, e events.ProductApproved) error {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	message := fmt.Sprintf("Your product \"%s\" has been approved!", e.ProductName)
+	if err := h.AddBatchedNotification(ctx, tx, e.SupplierID, "product_approved", message, "/supplier/products"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// notifySuppliersOfPaidOrder tells each supplier with items in the order to start fulfilment.
+func (h *Handlers) notifySuppliersOfPaidOrder(ctx context.Context, e events.OrderPaid) error {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT DISTINCT p.supplier_id
+		FROM order_items oi
+		JOIN products p ON oi.product_id = p.id
+		WHERE oi.order_id = ?`, e.OrderID)
+	if err != nil {
+		return err
+	}
+	var supplierIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		supplierIDs = append(supplierIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// One transaction, so a retried job never notifies a supplier twice.
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	link := "/supplier/orders/" + e.OrderPublicID
+	message := fmt.Sprintf("New paid order #%d is ready to ship.", e.OrderID)
+	for _, supplierID := range supplierIDs {
+		if err := h.AddBatchedNotification(ctx, tx, supplierID, "order_paid", message, link); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// notifyDisputeParties tells the dropshipper and the supplier what changed on a dispute.
+func (h *Handlers) notifyDisputeParties(ctx context.Context, e events.DisputeUpdated) error {
+	var toDropshipper, toSupplier string
+	switch e.Status {
+	case "open":
+		toDropshipper = fmt.Sprintf("Your dispute on order #%d was opened. The supplier has been asked to respond.", e.OrderID)
+		toSupplier = fmt.Sprintf("A dispute was opened on order #%d. Respond before the deadline to avoid an automatic refund.", e.OrderID)
+	case "under_review":
+		toDropshipper = fmt.Sprintf("The supplier responded to your dispute on order #%d. A manager will review it.", e.OrderID)
+		toSupplier = fmt.Sprintf("Your response on the dispute for order #%d was recorded. A manager will review it.", e.OrderID)
+	case "resolved":
+		outcome := fmt.Sprintf("Refund RM %s, supplier payout RM %s.", e.RefundAmount, e.SupplierAmount)
+		if e.Automatic {
+			outcome = "The supplier did not respond in time, so the order was refunded in full."
+		}
+		toDropshipper = fmt.Sprintf("Your dispute on order #%d was resolved. %s", e.OrderID, outcome)
+		toSupplier = fmt.Sprintf("The dispute on order #%d was resolved. %s", e.OrderID, outcome)
+	case "withdrawn":
+		toSupplier = fmt.Sprintf("The dispute on order #%d was withdrawn by the dropshipper.", e.OrderID)
+	default:
+		return nil
+	}
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	link := fmt.Sprintf("/disputes/%d", e.DisputeID)
+	if toDropshipper != "" {
+		if err := h.AddNotification(ctx, tx, e.DropshipperID, toDropshipper, "/dropshipper"+link); err != nil {
+			return err
+		}
+	}
+	if err := h.AddNotification(ctx, tx, e.SupplierID, toSupplier, "/supplier"+link); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Order CSV Export ---
+//
+
+// One row per order line, oldest order first. The order columns repeat on each
+// of its lines so the file sorts and filters cleanly in a spreadsheet.
+// Rows are written as they are read, never held in memory.
+
+// exportFlushEvery is how many rows are buffered before they go to the client.
+const exportFlushEvery = 500
+
+// orderStatuses are the values ?status= accepts.
+var orderStatuses = map[string]bool{
+	"on-hold": true, "under-review": true, "pre-order": true, "processing": true, "shipped": true, "completed": true, "cancelled": true,
+}
+
+// exportLinesQuery selects every line of the matching orders; the caller
+// appends its WHERE conditions before exportLinesOrder.
+const exportLinesQuery = `
+	SELECT o.id, o.public_id, o.created_at, o.status, o.total, o.discount_total, o.tax_total,
+		o.courier, o.tracking, o.ship_to,
+		COALESCE(v.sku, p.sku, ''), p.name, v.options, oi.quantity, oi.unit_price, oi.ships_by` + exportLinesFrom
+
+// exportLinesFrom is shared with the row count of an async export.
+const exportLinesFrom = `
+	FROM orders o
+	JOIN order_items oi ON oi.order_id = o.id
+	JOIN products p ON p.id = oi.product_id
+	LEFT JOIN product_variants v ON v.id = oi.variant_id
+	WHERE o.deleted_at IS NULL`
+
+const exportLinesOrder = " ORDER BY o.created_at, o.id, oi.id"
+
+// exportLine is one row of exportLinesQuery.
+type exportLine struct {
+	OrderID       int64
+	PublicID      string
+	CreatedAt     time.Time
+	Status        string
+	Total         money.Money
+	DiscountTotal money.Money
+	TaxTotal      money.Money
+	Courier       sql.NullString
+	Tracking      sql.NullString
+	ShipTo        models.ShipTo
+	SKU           string
+	ProductName   string
+	Options       string // "Color: Red; Size: M"
+	Quantity      int
+	UnitPrice     money.Money
+	ShipsBy       sql.NullTime
+}
+
+// exportColumn is one CSV column: its header and how a line fills it.
+type exportColumn struct {
+	header string
+	value  func(h *Handlers, l *exportLine) string
+}
+
+// Columns shared by both exports. Dates are in SHIPPING_TIMEZONE.
+var (
+	orderExportColumns = []exportColumn{
+		{"Order ID", func(_ *Handlers, l *exportLine) string { return l.PublicID }},
+		{"Order Number", func(_ *Handlers, l *exportLine) string { return strconv.FormatInt(l.OrderID, 10) }},
+		{"Date", func(h *Handlers, l *exportLine) string {
+			return l.CreatedAt.In(h.Config.Shipping.Timezone).Format("2006-01-02 15:04")
+		}},
+		{"Status", func(_ *Handlers, l *exportLine) string { return l.Status }},
+	}
+	lineExportColumns = []exportColumn{
+		{"SKU", func(_ *Handlers, l *exportLine) string { return l.SKU }},
+		{"Product", func(_ *Handlers, l *exportLine) string { return l.ProductName }},
+		{"Options", func(_ *Handlers, l *exportLine) string { return l.Options }},
+		{"Quantity", func(_ *Handlers, l *exportLine) string { return strconv.Itoa(l.Quantity) }},
+		{"Unit Price", func(_ *Handlers, l *exportLine) string { return l.UnitPrice.String() }},
+		{"Line Total", func(_ *Handlers, l *exportLine) string { return l.UnitPrice.Mul(l.Quantity).String() }},
+	}
+	shippingExportColumns = []exportColumn{
+		{"Recipient", func(_ *Handlers, l *exportLine) string { return l.ShipTo.Name }},
+		{"Phone", func(_ *Handlers, l *exportLine) string { return l.ShipTo.Phone }},
+		{"Address Line 1", func(_ *Handlers, l *exportLine) string { return l.ShipTo.AddressLine1 }},
+		{"Address Line 2", func(_ *Handlers, l *exportLine) string { return l.ShipTo.AddressLine2 }},
+		{"City", func(_ *Handlers, l *exportLine) string { return l.ShipTo.City }},
+		{"State", func(_ *Handlers, l *exportLine) string { return l.ShipTo.State }},
+		{"Postcode", func(_ *Handlers, l *exportLine) string { return l.ShipTo.Postcode }},
+		{"Ships By", func(h *Handlers, l *exportLine) string {
+			if !l.ShipsBy.Valid {
+				return ""
+			}
+			return l.ShipsBy.Time.In(h.Config.Shipping.Timezone).Format("2006-01-02")
+		}},
+		{"Courier", func(_ *Handlers, l *exportLine) string { return l.Courier.String }},
+		{"Tracking", func(_ *Handlers, l *exportLine) string { return l.Tracking.String }},
+	}
+	// Order amounts cover every supplier's lines, so only the dropshipper gets them.
+	totalExportColumns = []exportColumn{
+		{"Order Discount", func(_ *Handlers, l *exportLine) string { return l.DiscountTotal.String() }},
+		{"Order SST", func(_ *Handlers, l *exportLine) string { return l.TaxTotal.String() }},
+		{"Order Total", func(_ *Handlers, l *exportLine) string { return l.Total.String() }},
+	}
+
+	dropshipperOrderColumns = concatColumns(orderExportColumns, lineExportColumns, shippingExportColumns, totalExportColumns)
+	supplierOrderColumns    = concatColumns(orderExportColumns, lineExportColumns, shippingExportColumns)
+)
+
+// ExportMyOrders is the handler for GET /v1/dropshipper/orders/export
+// Filters: from/to (YYYY-MM-DD, inclusive) and status (comma-separated).
+func (h *Handlers) ExportMyOrders(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+
+	h.exportOrders(c, " AND o.user_id = ?", dropshipperID, dropshipperOrderColumns)
+}
+
+// ExportSupplierOrders is the handler for GET /v1/supplier/orders/export
+// Same filters as ExportMyOrders; only the supplier's own lines are listed.
+func (h *Handlers) ExportSupplierOrders(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+
+	h.exportOrders(c, " AND p.supplier_id = ?", supplierID, supplierOrderColumns)
+}
+
+func concatColumns(groups ...[]exportColumn) []exportColumn {
+	var all []exportColumn
+	for _, g := range groups {
+		all = append(all, g...)
+	}
+	return all
+}
+
+// exportSpec is one CSV export: the query of its rows and how a row becomes a
+// record. The order exports above stream it; async exports (POST /exports)
+// write it to a file.
+type exportSpec struct {
+	name   string // file name prefix: "orders" gives orders-20240131.csv
+	header []string
+	query  string // the rows, in file order
+	count  string // SELECT COUNT(*) over the same rows, for progress
+	args   []interface{}
+	// scan reads the current row into record (len(header) cells).
+	scan func(rows *sql.Rows, record []string) error
+}
+
+// filename is the download name of the export, dated in SHIPPING_TIMEZONE.
+func (h *Handlers) exportFilename(spec *exportSpec) string {
+	return fmt.Sprintf("%s-%s.csv", spec.name, time.Now().In(h.Config.Shipping.Timezone).Format("20060102"))
+}
+
+// exportDateRange turns the from/to filters (YYYY-MM-DD in SHIPPING_TIMEZONE,
+// both inclusive, either optional) into conditions on col.
+func (h *Handlers) exportDateRange(col, fromStr, toStr string) (string, []interface{}, error) {
+	loc := h.Config.Shipping.Timezone
+	var from, to time.Time
+	for _, bound := range []struct {
+		param string
+		value string
+		t     *time.Time
+	}{{"from", fromStr, &from}, {"to", toStr, &to}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02", bound.value, loc)
+		if err != nil {
+			return "", nil, errors.New(bound.param + " must be a date like 2024-01-31")
+		}
+		*bound.t = t
+	}
+	var where string
+	var args []interface{}
+	if !from.IsZero() {
+		where += " AND " + col + " >= ?"
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		if !from.IsZero() && to.Before(from) {
+			return "", nil, errors.New("to cannot be before from")
+		}
+		where += " AND " + col + " < ?"
+		args = append(args, to.AddDate(0, 0, 1))
+	}
+	return where, args, nil
+}
+
+// orderExportSpec selects the order lines matching ownerCond and the filters:
+// from/to and status (comma-separated).
+func (h *Handlers) orderExportSpec(ownerCond string, ownerID int64, columns []exportColumn, from, to, status string) (*exportSpec, error) {
+	where := ownerCond
+	args := []interface{}{ownerID}
+	dates, dateArgs, err := h.exportDateRange("o.created_at", from, to)
+	if err != nil {
+		return nil, err
+	}
+	where += dates
+	args = append(args, dateArgs...)
+	if status != "" {
+		statuses := strings.Split(status, ",")
+		for i, s := range statuses {
+			statuses[i] = strings.TrimSpace(s)
+			if !orderStatuses[statuses[i]] {
+				return nil, fmt.Errorf("Unknown order status %q", statuses[i])
+			}
+			args = append(args, statuses[i])
+		}
+		where += " AND o.status IN (?" + strings.Repeat(", ?", len(statuses)-1) + ")"
+	}
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.header
+	}
+	return &exportSpec{
+		name:   "orders",
+		header: header,
+		query:  exportLinesQuery + where + exportLinesOrder,
+		count:  "SELECT COUNT(*)" + exportLinesFrom + where,
+		args:   args,
+		scan: func(rows *sql.Rows, record []string) error {
+			var l exportLine
+			var shipTo, options []byte
+			if err := rows.Scan(&l.OrderID, &l.PublicID, &l.CreatedAt, &l.Status, &l.Total, &l.DiscountTotal, &l.TaxTotal,
+				&l.Courier, &l.Tracking, &shipTo, &l.SKU, &l.ProductName, &options, &l.Quantity, &l.UnitPrice, &l.ShipsBy); err != nil {
+				return err
+			}
+			if len(shipTo) > 0 {
+				_ = json.Unmarshal(shipTo, &l.ShipTo)
+			}
+			l.Options = optionsLabel(options)
+			for i, col := range columns {
+				record[i] = col.value(h, &l)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// exportOrders validates the filters, then streams the matching lines as CSV.
+func (h *Handlers) exportOrders(c *gin.Context, ownerCond string, ownerID int64, columns []exportColumn) {
+	ctx := c.Request.Context()
+
+	// 1. --- Build Filters ---
+	spec, err := h.orderExportSpec(ownerCond, ownerID, columns, c.Query("from"), c.Query("to"), c.Query("status"))
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	// 2. --- Query (read replica; rows are consumed as they arrive) ---
+	rows, err := h.readDB().QueryContext(ctx, spec.query, spec.args...)
+	if err != nil {
+		apierror.Internal(c, "Failed to export orders")
+		return
+	}
+	defer rows.Close()
+
+	// 3. --- Stream ---
+	// Past this point the status is sent: a failure can only cut the file short,
+	// so it is logged and the client sees a truncated download.
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", h.exportFilename(spec)))
+	c.Status(http.StatusOK)
+	// HTTP_WRITE_TIMEOUT is sized for JSON; the route's Timeout bounds the export instead.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	n, err := writeExportCSV(c.Writer, rows, spec, func(int) { c.Writer.Flush() })
+	if err != nil {
+		logging.Errorf("[Export] Order export stopped after %d rows: %v", n, err)
+	}
+}
+
+// writeExportCSV writes the header and every row of rows to w. Every
+// exportFlushEvery rows it flushes the CSV buffer and calls flushed with the
+// rows written so far. It returns the number of rows written.
+func writeExportCSV(w io.Writer, rows *sql.Rows, spec *exportSpec, flushed func(n int)) (int, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(spec.header); err != nil {
+		return 0, err
+	}
+	record := make([]string, len(spec.header))
+	n := 0
+	for rows.Next() {
+		if err := spec.scan(rows, record); err != nil {
+			return n, err
+		}
+		for i := range record {
+			record[i] = csvCell(record[i])
+		}
+		if err := cw.Write(record); err != nil {
+			return n, err // the client went away
+		}
+		if n++; n%exportFlushEvery == 0 {
+			cw.Flush()
+			flushed(n)
+		}
+	}
+	cw.Flush()
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	return n, cw.Error()
+}
+
+// optionsLabel renders a variant's options JSON as "Color: Red; Size: M".
+func optionsLabel(optionsJSON []byte) string {
+	if len(optionsJSON) == 0 {
+		return ""
+	}
+	var options []models.ProductVariantOption
+	if err := json.Unmarshal(optionsJSON, &options); err != nil {
+		return ""
+	}
+	parts := make([]string, len(options))
+	for i, o := range options {
+		parts[i] = o.Name + ": " + o.Value
+	}
+	return strings.Join(parts, "; ")
+}
+
+// csvCell stops spreadsheets from running user-entered text (product names,
+// addresses) as a formula by prefixing the characters that start one.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
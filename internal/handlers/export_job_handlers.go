@@ -0,0 +1,478 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/01moynul/taptosell-golang/internal/jobs"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+//
+// --- Async Exports ---
+//
+
+// POST /exports queues an export on the export workers and answers at once;
+// the client polls GET /exports/:id for progress and, once it is done,
+// downloads the file through the signed downloadUrl. Files are kept for
+// EXPORT_RETENTION, then ProcessExports deletes them with their row.
+
+const (
+	// exportTimeout bounds one export's queries and file.
+	exportTimeout = 20 * time.Minute
+	// exportStaleAfter is how long a queued or running export may go without a
+	// status or progress write before ProcessExports gives up on it (its
+	// instance restarted, or it waited behind others for too long).
+	exportStaleAfter = 30 * time.Minute
+)
+
+// exportJobColumns is the column list scanned by scanExportJob.
+const exportJobColumns = `
+	id, user_id, kind, params, status, rows_done, rows_total, file_name, error,
+	created_at, finished_at, expires_at`
+
+type CreateExportInput struct {
+	Kind string `json:"kind" binding:"required,oneof=orders catalog wallet"`
+	models.ExportParams
+}
+
+// catalogExportQuery lists one row per variant (or per simple product).
+const catalogExportQuery = `
+	SELECT p.public_id, p.supplier_id, p.name, p.status, COALESCE(v.sku, p.sku, ''), v.options,
+		COALESCE(v.price_to_tts, p.price_to_tts), p.srp, COALESCE(v.stock_quantity, p.stock_quantity)` + catalogExportFrom
+
+const catalogExportFrom = `
+	FROM products p
+	LEFT JOIN product_variants v ON v.product_id = p.id
+	WHERE p.deleted_at IS NULL`
+
+// CreateExport is the handler for POST /v1/exports
+// Kinds: orders (a dropshipper's orders or a supplier's order lines, as the
+// GET .../orders/export routes), catalog (a supplier's products; every
+// product for managers) and wallet (the caller's wallet transactions).
+// from/to filter on the date of the order, product or transaction.
+func (h *Handlers) CreateExport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get User & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+	role := c.GetString("userRole")
+
+	var input CreateExportInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if input.Status != "" && input.Kind != "orders" {
+		apierror.BadRequest(c, "status only applies to order exports")
+		return
+	}
+
+	// 2. --- Build the Export for the Caller's Role ---
+	var spec *exportSpec
+	var err error
+	switch input.Kind {
+	case "orders":
+		switch role {
+		case "dropshipper":
+			spec, err = h.orderExportSpec(" AND o.user_id = ?", userID, dropshipperOrderColumns, input.From, input.To, input.Status)
+		case "supplier":
+			spec, err = h.orderExportSpec(" AND p.supplier_id = ?", userID, supplierOrderColumns, input.From, input.To, input.Status)
+		default:
+			apierror.Forbidden(c, "Order exports are for dropshippers and suppliers")
+			return
+		}
+	case "catalog":
+		switch role {
+		case "supplier":
+			spec, err = h.catalogExportSpec(" AND p.supplier_id = ?", []interface{}{userID}, input.From, input.To)
+		case "manager", "administrator":
+			spec, err = h.catalogExportSpec("", nil, input.From, input.To)
+		default:
+			apierror.Forbidden(c, "Catalogue exports are for suppliers and managers")
+			return
+		}
+	case "wallet":
+		spec, err = h.walletExportSpec(userID, input.From, input.To)
+	}
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	// 3. --- Limit Exports in Progress ---
+	var active int
+	if err := h.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM export_jobs WHERE user_id = ? AND status IN ('queued', 'running')", userID).Scan(&active); err != nil {
+		apierror.Internal(c, "Failed to check exports in progress")
+		return
+	}
+	if max := h.Config.Exports.MaxActive; active >= max {
+		apierror.Conflict(c, fmt.Sprintf("You already have %d exports in progress; wait for one to finish", max))
+		return
+	}
+
+	// 4. --- Record & Queue ---
+	now := time.Now()
+	job := models.ExportJob{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Kind:      input.Kind,
+		Params:    input.ExportParams,
+		Status:    "queued",
+		CreatedAt: now,
+	}
+	params, _ := json.Marshal(job.Params)
+	if _, err := h.DB.ExecContext(ctx, `
+		INSERT INTO export_jobs (id, user_id, kind, params, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 'queued', ?, ?)`,
+		job.ID, userID, job.Kind, string(params), now, now); err != nil {
+		apierror.Internal(c, "Failed to create export")
+		return
+	}
+	err = h.ExportQueue.Enqueue(jobs.Job{
+		Name: "export " + job.ID,
+		Run: func(ctx context.Context) error {
+			h.runExport(ctx, job.ID, spec)
+			return nil // failures are recorded on the job; a retry would start over
+		},
+	})
+	if err != nil {
+		h.failExport(context.WithoutCancel(ctx), job.ID, "The export queue is full; please try again later")
+		apierror.ServiceUnavailable(c, "Too many exports are queued; try again in a few minutes")
+		return
+	}
+
+	c.Header("Location", apiversion.From(c).Prefix()+"/exports/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Export started", "export": job})
+}
+
+// GetMyExports is the handler for GET /v1/exports
+// It lists the caller's exports that are still kept, newest first.
+func (h *Handlers) GetMyExports(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+
+	rows, err := h.DB.QueryContext(ctx,
+		"SELECT "+exportJobColumns+" FROM export_jobs WHERE user_id = ? ORDER BY created_at DESC LIMIT 50", userID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch exports")
+		return
+	}
+	defer rows.Close()
+	exports := []models.ExportJob{}
+	for rows.Next() {
+		job, err := scanExportJob(rows)
+		if err != nil {
+			apierror.Internal(c, "Failed to read exports")
+			return
+		}
+		exports = append(exports, h.withDownload(job))
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Failed to fetch exports")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"exports": exports})
+}
+
+// GetExport is the handler for GET /v1/exports/:id
+// Poll it for progress; once status is "done" it carries a fresh downloadUrl,
+// valid for EXPORT_URL_TTL.
+func (h *Handlers) GetExport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+
+	job, err := scanExportJob(h.DB.QueryRowContext(ctx,
+		"SELECT "+exportJobColumns+" FROM export_jobs WHERE id = ? AND user_id = ?", c.Param("id"), userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.NotFound(c, "Export not found (it may have expired)")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch export")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"export": h.withDownload(job)})
+}
+
+// DownloadExport handles GET /v1/exports/files/:name
+// The link itself is the authorization: it must carry a valid, unexpired
+// signature from GetExport.
+func (h *Handlers) DownloadExport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	name := c.Param("name")
+	if !h.ExportURLs.Verify(name, c.Query("expires"), c.Query("sig")) {
+		apierror.Forbidden(c, "Invalid or expired download link")
+		return
+	}
+	id := strings.TrimSuffix(name, ".csv")
+	if _, err := uuid.Parse(id); err != nil || id == name {
+		apierror.NotFound(c, "Export not found")
+		return
+	}
+
+	var fileName string
+	err := h.DB.QueryRowContext(ctx,
+		"SELECT file_name FROM export_jobs WHERE id = ? AND status = 'done' AND expires_at > ?", id, time.Now()).Scan(&fileName)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.NotFound(c, "Export not found (it may have expired)")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch export")
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.FileAttachment(filepath.Join(h.Config.Exports.Dir, name), fileName)
+}
+
+// ProcessExports deletes the exports past their expiry, files included, and
+// fails the ones that stopped reporting (see exportStaleAfter). The background
+// worker calls it hourly.
+func (h *Handlers) ProcessExports(ctx context.Context) {
+	now := time.Now()
+	if _, err := h.DB.ExecContext(ctx, `
+		UPDATE export_jobs SET status = 'failed', error = 'The export was interrupted; please start it again',
+			finished_at = ?, expires_at = ?, updated_at = ?
+		WHERE status IN ('queued', 'running') AND updated_at < ?`,
+		now, now.Add(h.Config.Exports.Retention), now, now.Add(-exportStaleAfter)); err != nil {
+		logging.Errorf("[Exports] Error failing stale exports: %v", err)
+	}
+
+	rows, err := h.DB.QueryContext(ctx, "SELECT id FROM export_jobs WHERE expires_at <= ? LIMIT 500", now)
+	if err != nil {
+		logging.Errorf("[Exports] Error fetching expired exports: %v", err)
+		return
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			logging.Errorf("[Exports] Error scanning expired exports: %v", err)
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logging.Errorf("[Exports] Error fetching expired exports: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		if err := os.Remove(filepath.Join(h.Config.Exports.Dir, id+".csv")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logging.Errorf("[Exports] Failed to delete the file of export %s: %v", id, err)
+			continue
+		}
+		if _, err := h.DB.ExecContext(ctx, "DELETE FROM export_jobs WHERE id = ?", id); err != nil {
+			logging.Errorf("[Exports] Failed to delete export %s: %v", id, err)
+		}
+	}
+}
+
+// runExport writes the export's rows to EXPORT_DIR/<id>.csv, recording its
+// progress every exportFlushEvery rows, and marks it done or failed.
+func (h *Handlers) runExport(ctx context.Context, id string, spec *exportSpec) {
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+	// Status writes must land even after a shutdown or the timeout cut the export short.
+	statusCtx := context.WithoutCancel(ctx)
+	fail := func(err error) {
+		logging.Errorf("[Exports] Export %s failed: %v", id, err)
+		message := "The export failed; please try again"
+		if ctx.Err() != nil {
+			message = "The export was interrupted; please start it again"
+		}
+		h.failExport(statusCtx, id, message)
+	}
+
+	// 1. --- Claim (a stale export may have been failed meanwhile) ---
+	res, err := h.DB.ExecContext(ctx,
+		"UPDATE export_jobs SET status = 'running', updated_at = ? WHERE id = ? AND status = 'queued'", time.Now(), id)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return
+	}
+
+	// 2. --- Count (read replica) ---
+	var total int
+	if err := h.readDB().QueryRowContext(ctx, spec.count, spec.args...).Scan(&total); err != nil {
+		fail(err)
+		return
+	}
+	if _, err := h.DB.ExecContext(ctx,
+		"UPDATE export_jobs SET rows_total = ?, updated_at = ? WHERE id = ?", total, time.Now(), id); err != nil {
+		fail(err)
+		return
+	}
+
+	// 3. --- Write the File (staged, then renamed into place) ---
+	dir := h.Config.Exports.Dir
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		fail(err)
+		return
+	}
+	f, err := os.CreateTemp(dir, ".export-*")
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer os.Remove(f.Name()) // a no-op once renamed
+
+	rows, err := h.readDB().QueryContext(ctx, spec.query, spec.args...)
+	if err != nil {
+		f.Close()
+		fail(err)
+		return
+	}
+	n, err := writeExportCSV(f, rows, spec, func(n int) {
+		// Progress is best-effort; a missed write only makes the bar lag.
+		_, _ = h.DB.ExecContext(ctx, "UPDATE export_jobs SET rows_done = ?, updated_at = ? WHERE id = ?", n, time.Now(), id)
+	})
+	rows.Close()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(dir, id+".csv"))
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	// 4. --- Done ---
+	now := time.Now()
+	if _, err := h.DB.ExecContext(statusCtx, `
+		UPDATE export_jobs SET status = 'done', rows_done = ?, rows_total = ?, file_name = ?,
+			finished_at = ?, expires_at = ?, updated_at = ?
+		WHERE id = ?`,
+		n, n, h.exportFilename(spec), now, now.Add(h.Config.Exports.Retention), now, id); err != nil {
+		logging.Errorf("[Exports] Failed to finish export %s: %v", id, err)
+	}
+}
+
+// failExport marks an export failed with a message for the client. Failed
+// exports are kept like finished ones, so the client can see what happened.
+func (h *Handlers) failExport(ctx context.Context, id, message string) {
+	now := time.Now()
+	if _, err := h.DB.ExecContext(ctx, `
+		UPDATE export_jobs SET status = 'failed', error = ?, finished_at = ?, expires_at = ?, updated_at = ?
+		WHERE id = ?`,
+		message, now, now.Add(h.Config.Exports.Retention), now, id); err != nil {
+		logging.Errorf("[Exports] Failed to mark export %s failed: %v", id, err)
+	}
+}
+
+// withDownload fills in the progress and, for a finished export still kept,
+// a signed download link.
+func (h *Handlers) withDownload(job models.ExportJob) models.ExportJob {
+	switch {
+	case job.Status == "done":
+		job.Progress = 100
+	case job.RowsTotal != nil && *job.RowsTotal > 0:
+		job.Progress = min(job.RowsDone*100 / *job.RowsTotal, 99)
+	}
+	if job.Status == "done" && job.ExpiresAt != nil && job.ExpiresAt.After(time.Now()) {
+		job.DownloadURL = h.ExportURLs.URL(h.Config.HTTP.BaseURL, job.ID+".csv")
+	}
+	return job
+}
+
+func scanExportJob(row interface{ Scan(...interface{}) error }) (models.ExportJob, error) {
+	var job models.ExportJob
+	var params []byte
+	err := row.Scan(&job.ID, &job.UserID, &job.Kind, &params, &job.Status, &job.RowsDone, &job.RowsTotal,
+		&job.FileName, &job.Error, &job.CreatedAt, &job.FinishedAt, &job.ExpiresAt)
+	if err != nil {
+		return job, err
+	}
+	_ = json.Unmarshal(params, &job.Params)
+	return job, nil
+}
+
+// catalogExportSpec lists the products matching ownerCond, created between from and to.
+func (h *Handlers) catalogExportSpec(ownerCond string, ownerArgs []interface{}, from, to string) (*exportSpec, error) {
+	dates, dateArgs, err := h.exportDateRange("p.created_at", from, to)
+	if err != nil {
+		return nil, err
+	}
+	where := ownerCond + dates
+	args := append(append([]interface{}{}, ownerArgs...), dateArgs...)
+	return &exportSpec{
+		name:   "catalog",
+		header: []string{"Product ID", "Supplier ID", "Name", "Status", "SKU", "Options", "Price", "SRP", "Stock"},
+		query:  catalogExportQuery + where + " ORDER BY p.id, v.id",
+		count:  "SELECT COUNT(*)" + catalogExportFrom + where,
+		args:   args,
+		scan: func(rows *sql.Rows, record []string) error {
+			var publicID, name, status, sku string
+			var supplierID int64
+			var options []byte
+			var price, srp money.Money
+			var stock int
+			if err := rows.Scan(&publicID, &supplierID, &name, &status, &sku, &options, &price, &srp, &stock); err != nil {
+				return err
+			}
+			copy(record, []string{publicID, fmt.Sprint(supplierID), name, status, sku, optionsLabel(options),
+				price.String(), srp.String(), fmt.Sprint(stock)})
+			return nil
+		},
+	}, nil
+}
+
+// walletExportSpec lists the user's wallet transactions between from and to.
+func (h *Handlers) walletExportSpec(userID int64, from, to string) (*exportSpec, error) {
+	dates, dateArgs, err := h.exportDateRange("created_at", from, to)
+	if err != nil {
+		return nil, err
+	}
+	where := " WHERE user_id = ?" + dates
+	args := append([]interface{}{userID}, dateArgs...)
+	return &exportSpec{
+		name:   "wallet",
+		header: []string{"Transaction ID", "Date", "Type", "Amount", "Balance After", "Details"},
+		query: "SELECT id, created_at, type, amount, balance_after, COALESCE(notes, '') FROM wallet_transactions" +
+			where + " ORDER BY created_at, id",
+		count: "SELECT COUNT(*) FROM wallet_transactions" + where,
+		args:  args,
+		scan: func(rows *sql.Rows, record []string) error {
+			var id int64
+			var createdAt time.Time
+			var kind, notes string
+			var amount, balance money.Money
+			if err := rows.Scan(&id, &createdAt, &kind, &amount, &balance, &notes); err != nil {
+				return err
+			}
+			copy(record, []string{fmt.Sprint(id), createdAt.In(h.Config.Shipping.Timezone).Format("2006-01-02 15:04"),
+				kind, amount.String(), balance.String(), notes})
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Sparse Fieldsets (?fields=) ---
+//
+// Heavy list endpoints accept ?fields=id,name,price so mobile clients only
+// receive the keys they render. Names are the JSON keys of the response items.
+// "id" is always included so clients can key their lists.
+//
+
+// parseFields reads the ?fields= query parameter. nil means "all fields".
+func parseFields(c *gin.Context) map[string]bool {
+	raw := c.Query("fields")
+	if raw == "" {
+		return nil
+	}
+
+	fields := map[string]bool{"id": true}
+	for _, f := range strings.Split(raw, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields[f] = true
+		}
+	}
+	return fields
+}
+
+// wantsAny reports whether any of the given fields were requested.
+// It is used to skip loading relations nobody asked for.
+func wantsAny(fields map[string]bool, names ...string) bool {
+	if fields == nil {
+		return true
+	}
+	for _, n := range names {
+		if fields[n] {
+			return true
+		}
+	}
+	return false
+}
+
+// projectFields trims each item down to the requested JSON keys.
+// With no ?fields= the items are returned unchanged.
+func projectFields[T any](items []T, fields map[string]bool) (interface{}, error) {
+	if fields == nil {
+		return items, nil
+	}
+
+	projected := make([]map[string]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var full map[string]json.RawMessage
+		if err := json.Unmarshal(data, &full); err != nil {
+			return nil, err
+		}
+
+		slim := make(map[string]json.RawMessage, len(fields))
+		for key := range fields {
+			if v, ok := full[key]; ok {
+				slim[key] = v
+			}
+		}
+		projected = append(projected, slim)
+	}
+	return projected, nil
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/01moynul/taptosell-golang/internal/graphql"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- GraphQL Gateway (GRAPHQL_ENABLED) ---
+//
+// One request for a whole storefront page, e.g.
+//
+//	{ product(id: "…") { name price images variants { price options { name value } }
+//	    brand { name } supplier { name away } related(first: 4) { publicId name price images } } }
+//
+// Fields are named as in the REST responses. Product.supplier and
+// Product.related are batched: a list of products costs one query for each,
+// not one per product.
+
+// graphQLMaxQueryBytes bounds the query text.
+const graphQLMaxQueryBytes = 16 << 10
+
+// relatedDefault and relatedMax bound Product.related(first:). related may be
+// selected once per query: nested or aliased, each selection multiplies the
+// products below it.
+const (
+	relatedDefault = 4
+	relatedMax     = 20
+)
+
+// productPage and orderPage are one page of a list and the cursor of the next.
+type productPage struct {
+	Items      []*models.Product `json:"-"`
+	NextCursor *string           `json:"nextCursor"`
+}
+
+type orderPage struct {
+	Items      []models.Order `json:"-"`
+	NextCursor *string        `json:"nextCursor"`
+}
+
+// errDropshipperOnly is returned for the cart and orders of other roles.
+var errDropshipperOnly = errors.New("only dropshippers have a cart and orders")
+
+// GraphQL is the handler for POST /vN/graphql
+// @Summary Run a GraphQL query
+// Errors of single fields come back in "errors" next to the rest of the data;
+// only a query that cannot be read at all is a 400.
+func (h *Handlers) GraphQL(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+
+	var req graphql.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if req.Query == "" {
+		apierror.BadRequest(c, "query is required")
+		return
+	}
+	if len(req.Query) > graphQLMaxQueryBytes {
+		apierror.TooLarge(c, "The query is too long")
+		return
+	}
+
+	resp := h.storefrontSchema(userID, c.GetString("userRole"), apiversion.From(c)).Execute(c.Request.Context(), req)
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, resp)
+}
+
+// storefrontSchema is the schema as seen by one caller; fields backed by a
+// REST response (cart) take that response's shape in the request's version.
+func (h *Handlers) storefrontSchema(userID int64, role string, version apiversion.Version) *graphql.Schema {
+	supplier := &graphql.Object{Name: "Supplier"}
+	product := &graphql.Object{Name: "Product"}
+	relatedUsed := false // the schema is built per request
+	product.Fields = map[string]*graphql.FieldDef{
+		"variants": {Resolve: func(_ context.Context, parent interface{}, _ graphql.Args) (interface{}, error) {
+			return catalogueVariants(parent.(*models.Product).Variants), nil
+		}},
+		"brand": {Resolve: func(_ context.Context, parent interface{}, _ graphql.Args) (interface{}, error) {
+			if p := parent.(*models.Product); len(p.Brands) > 0 {
+				return &p.Brands[0], nil
+			}
+			return nil, nil
+		}},
+		"supplier": {Type: supplier, Batch: h.batchProductSuppliers},
+		"related": {Type: product, Batch: func(ctx context.Context, parents []interface{}, args graphql.Args) ([]interface{}, error) {
+			limit, err := args.Int("first", relatedDefault)
+			if err != nil {
+				return nil, err
+			}
+			if limit < 0 || limit > relatedMax {
+				return nil, errors.New("related(first:) must be between 0 and " + strconv.Itoa(relatedMax))
+			}
+			if relatedUsed {
+				return nil, errors.New("related can be selected once per query")
+			}
+			relatedUsed = true
+			ids := make([]int64, len(parents))
+			for i, p := range parents {
+				ids[i] = p.(*models.Product).ID
+			}
+			related, err := h.Store.Products.Related(ctx, ids, limit)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]interface{}, len(parents))
+			for i, id := range ids {
+				list := related[id]
+				if list == nil {
+					list = []*models.Product{}
+				}
+				out[i] = list
+			}
+			return out, nil
+		}},
+	}
+
+	productList := &graphql.Object{Name: "ProductPage", Fields: map[string]*graphql.FieldDef{
+		"items": {Type: product, Resolve: func(_ context.Context, parent interface{}, _ graphql.Args) (interface{}, error) {
+			return parent.(*productPage).Items, nil
+		}},
+	}}
+
+	order := &graphql.Object{Name: "Order", Fields: map[string]*graphql.FieldDef{
+		"items": {Resolve: func(ctx context.Context, parent interface{}, _ graphql.Args) (interface{}, error) {
+			return h.Store.Orders.Items(ctx, parent.(models.Order).ID)
+		}},
+	}}
+	orderList := &graphql.Object{Name: "OrderPage", Fields: map[string]*graphql.FieldDef{
+		"items": {Type: order, Resolve: func(_ context.Context, parent interface{}, _ graphql.Args) (interface{}, error) {
+			return parent.(*orderPage).Items, nil
+		}},
+	}}
+
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.FieldDef{
+		// product(id: ID!): an active product by publicId (or numeric ID while LEGACY_NUMERIC_IDS is on)
+		"product": {Type: product, Resolve: func(ctx context.Context, _ interface{}, args graphql.Args) (interface{}, error) {
+			id, err := store.ResolveID(ctx, h.DB, store.TableProducts, args.String("id"), h.Config.HTTP.LegacyNumericIDs)
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			p, err := h.Store.Products.GetActive(ctx, id)
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, nil
+			}
+			return p, err
+		}},
+
+		// products(query, categoryId, brandId, minPrice, maxPrice, first, after): the catalogue search
+		"products": {Type: productList, Resolve: func(ctx context.Context, _ interface{}, args graphql.Args) (interface{}, error) {
+			page, err := graphQLPage(args)
+			if err != nil {
+				return nil, err
+			}
+			products, err := h.Store.Products.Search(ctx, store.ProductSearch{
+				Query:         args.String("query"),
+				CategoryID:    args.String("categoryId"),
+				BrandID:       args.String("brandId"),
+				MinPrice:      args.String("minPrice"),
+				MaxPrice:      args.String("maxPrice"),
+				WithRelations: true,
+			}, page)
+			if err != nil {
+				return nil, err
+			}
+			products, next := pagination.Paginate(page, products, productCursor)
+			return &productPage{Items: products, NextCursor: next}, nil
+		}},
+
+		"categories": {Resolve: func(ctx context.Context, _ interface{}, _ graphql.Args) (interface{}, error) {
+			return h.categoryTree(ctx)
+		}},
+
+		"cart": {Resolve: func(ctx context.Context, _ interface{}, _ graphql.Args) (interface{}, error) {
+			if role != "dropshipper" {
+				return nil, errDropshipperOnly
+			}
+			return h.cartContents(ctx, userID, version)
+		}},
+
+		// orders(first, after): the caller's orders, newest first
+		"orders": {Type: orderList, Resolve: func(ctx context.Context, _ interface{}, args graphql.Args) (interface{}, error) {
+			if role != "dropshipper" {
+				return nil, errDropshipperOnly
+			}
+			page, err := graphQLPage(args)
+			if err != nil {
+				return nil, err
+			}
+			orders, err := h.Store.Orders.ListByUser(ctx, userID, page)
+			if err != nil {
+				return nil, err
+			}
+			orders, next := pagination.Paginate(page, orders, orderCursor)
+			return &orderPage{Items: orders, NextCursor: next}, nil
+		}},
+	}}
+
+	return &graphql.Schema{Query: query}
+}
+
+// graphQLPage reads the first and after arguments like ?limit= and ?cursor=.
+func graphQLPage(args graphql.Args) (pagination.Page, error) {
+	limit := ""
+	if _, ok := args["first"]; ok {
+		n, err := args.Int("first", 0)
+		if err != nil {
+			return pagination.Page{}, err
+		}
+		limit = strconv.Itoa(n)
+	}
+	return pagination.Parse(args.String("after"), limit)
+}
+
+// batchProductSuppliers loads the supplier of every product with one query.
+func (h *Handlers) batchProductSuppliers(ctx context.Context, parents []interface{}, _ graphql.Args) ([]interface{}, error) {
+	var ids []int64
+	seen := map[int64]bool{}
+	for _, p := range parents {
+		if id := p.(*models.Product).SupplierID; !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	suppliers := map[int64]models.ProductSupplier{}
+	if len(ids) > 0 {
+		args := make([]interface{}, len(ids))
+		for i, id := range ids {
+			args[i] = id
+		}
+		rows, err := h.readDB().QueryContext(ctx,
+			"SELECT id, public_id, company_name, full_name FROM users WHERE id IN (?"+strings.Repeat(", ?", len(ids)-1)+")",
+			args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			var s models.ProductSupplier
+			var companyName sql.NullString
+			if err := rows.Scan(&id, &s.PublicID, &companyName, &s.Name); err != nil {
+				return nil, err
+			}
+			if companyName.Valid && companyName.String != "" {
+				s.Name = companyName.String
+			}
+			suppliers[id] = s
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]interface{}, len(parents))
+	for i, parent := range parents {
+		p := parent.(*models.Product)
+		s, ok := suppliers[p.SupplierID]
+		if !ok {
+			continue
+		}
+		s.Away, s.BackAt = p.SupplierAway, p.SupplierBackAt
+		out[i] = s
+	}
+	return out, nil
+}
@@ -1,14 +1,73 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"log"
 
 	"github.com/01moynul/taptosell-golang/internal/ai" // ADDED: Import AI package
+	"github.com/01moynul/taptosell-golang/internal/audit"
+	"github.com/01moynul/taptosell-golang/internal/cache"
+	"github.com/01moynul/taptosell-golang/internal/captcha"
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/01moynul/taptosell-golang/internal/errreport"
+	"github.com/01moynul/taptosell-golang/internal/events"
+	"github.com/01moynul/taptosell-golang/internal/imageproc"
+	"github.com/01moynul/taptosell-golang/internal/jobs"
+	"github.com/01moynul/taptosell-golang/internal/pii"
+	"github.com/01moynul/taptosell-golang/internal/settings"
+	"github.com/01moynul/taptosell-golang/internal/status"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/01moynul/taptosell-golang/internal/uploads"
+	"github.com/01moynul/taptosell-golang/internal/webhooks"
 )
 
 // Handlers struct holds all dependencies for our handlers.
 type Handlers struct {
-	DB         *sql.DB       // Primary Read/Write connection
-	DBReadOnly *sql.DB       // Read-Only connection
-	AIService  *ai.AIService // ADDED: The new AI service instance for core AI logic
+	Config     *config.Config       // Validated startup configuration
+	DB         *sql.DB              // Primary Read/Write connection (all writes go here)
+	ReadDB     *sql.DB              // Read replica for heavy reads (search, dashboards); may be the primary
+	DBReadOnly *sql.DB              // Read-Only connection
+	AIService  *ai.AIService        // ADDED: The new AI service instance for core AI logic
+	Cache      cache.Cache          // Hot-read cache (Redis or in-memory)
+	Settings   *settings.Store      // Cached access to the 'settings' table
+	Status     *status.Store        // Cached maintenance windows (pause writes while active)
+	Store      *store.Store         // Typed repositories (products, orders, wallet)
+	Events     *events.Bus          // Domain events; subscribers are in event_subscribers.go
+	Reporter   errreport.Reporter   // Panics and 5xx responses (Sentry or the log)
+	Audit      *audit.Recorder      // Webhook & payment request captures
+	Uploads    *uploads.Service     // Checked image & document storage
+	Images     *imageproc.Processor // Product photo pipeline (square, white background, watermark)
+	PII        *pii.Cipher          // IC, SSM & bank details at rest; nil stores plaintext
+	Captcha    *captcha.Verifier    // Bot check on public auth routes; nil without CAPTCHA_PROVIDER
+	Webhooks   *webhooks.Client     // Outbound webhook deliveries
+
+	ExportQueue *jobs.Queue     // Async CSV exports (EXPORT_WORKERS), apart from the event queue
+	ExportURLs  *uploads.Signer // Signed download links of finished exports
+}
+
+// readDB returns the pool heavy read endpoints should use.
+// Replica lag is acceptable there; anything read-then-write must use h.DB.
+func (h *Handlers) readDB() *sql.DB {
+	if h.ReadDB != nil {
+		return h.ReadDB
+	}
+	return h.DB
+}
+
+// invalidateCache drops cache keys after a successful write.
+// Failures are only logged: the entries still expire via their TTL.
+func (h *Handlers) invalidateCache(ctx context.Context, keys ...string) {
+	if err := h.Cache.Delete(ctx, keys...); err != nil {
+		log.Printf("cache invalidation failed for %v: %v", keys, err)
+	}
+}
+
+// invalidateProducts drops the cached detail payload of each product.
+func (h *Handlers) invalidateProducts(ctx context.Context, productIDs ...int64) {
+	keys := make([]string, 0, len(productIDs))
+	for _, id := range productIDs {
+		keys = append(keys, cache.ProductKey(id))
+	}
+	h.invalidateCache(ctx, keys...)
 }
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/imageproc"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Product Photo Processing (Supplier-Only) ---
+//
+
+// ProcessImage is the handler for POST /v1/supplier/images/process
+// Multipart "image" (one file, checked like POST /upload), with the optional
+// fields "removeBackground" and "watermark" ("true" to apply). The upload is
+// stored as sent, then normalized for listing (square on white, IMAGE_SIZE
+// pixels, a JPEG) and stored again; both URLs are returned.
+func (h *Handlers) ProcessImage(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Supplier ID ---
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+
+	// 2. --- Parse the (size-capped) form ---
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Uploads.MaxRequestBytes)
+	fh, err := c.FormFile("image")
+	if err != nil {
+		h.uploadError(c, err, "No image uploaded (expected image)")
+		return
+	}
+	var opts imageproc.Options
+	for _, f := range []struct {
+		name string
+		set  *bool
+	}{{"removeBackground", &opts.RemoveBackground}, {"watermark", &opts.Watermark}} {
+		if raw := c.PostForm(f.name); raw != "" {
+			if *f.set, err = strconv.ParseBool(raw); err != nil {
+				apierror.BadRequest(c, fmt.Sprintf("%s must be true or false", f.name))
+				return
+			}
+		}
+	}
+	if opts.Watermark && h.Images.Watermark == nil {
+		apierror.BadRequest(c, "Watermarking is not set up on this server")
+		return
+	}
+
+	// 3. --- Store the Original ---
+	original, err := h.Uploads.Images.Save(ctx, fh)
+	if err != nil {
+		h.uploadError(c, err, "Failed to save image")
+		return
+	}
+
+	// 4. --- Process & Store the Result ---
+	// The original stays stored when processing fails; it is not referenced
+	// and is left for the storage's own cleanup.
+	src, err := fh.Open()
+	if err != nil {
+		apierror.Internal(c, "Failed to process image")
+		return
+	}
+	defer src.Close()
+	data, err := h.Images.Process(ctx, src, opts)
+	if err != nil {
+		h.processError(c, err)
+		return
+	}
+	processed, err := h.Uploads.Images.SaveBytes(ctx, data)
+	if err != nil {
+		h.uploadError(c, err, "Failed to save processed image")
+		return
+	}
+
+	// 5. --- Record the Pair ---
+	img := models.ProcessedImage{
+		OriginalURL:       h.Uploads.Images.URL(original),
+		ProcessedURL:      h.Uploads.Images.URL(processed),
+		BackgroundRemoved: opts.RemoveBackground,
+		Watermarked:       opts.Watermark,
+		CreatedAt:         time.Now(),
+	}
+	res, err := h.DB.ExecContext(ctx, `
+		INSERT INTO processed_images (supplier_id, original_url, processed_url, background_removed, watermarked, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		supplierID, img.OriginalURL, img.ProcessedURL, img.BackgroundRemoved, img.Watermarked, img.CreatedAt)
+	if err != nil {
+		apierror.Internal(c, "Failed to save processed image")
+		return
+	}
+	img.ID, _ = res.LastInsertId()
+
+	c.JSON(http.StatusCreated, gin.H{"image": img})
+}
+
+// processError maps an image pipeline failure to its response.
+func (h *Handlers) processError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, imageproc.ErrUnsupported):
+		apierror.UnsupportedMediaType(c, "This image cannot be processed; upload a JPEG, PNG or GIF")
+	case errors.Is(err, imageproc.ErrTooLarge):
+		apierror.TooLarge(c, "Image has too many pixels to process")
+	case errors.Is(err, imageproc.ErrRemoval):
+		log.Printf("image processing failed [%s]: %v", c.GetString(apierror.RequestIDKey), err)
+		apierror.ServiceUnavailable(c, "Background removal is unavailable. Try again later.")
+	default:
+		log.Printf("image processing failed [%s]: %v", c.GetString(apierror.RequestIDKey), err)
+		apierror.Internal(c, "Failed to process image")
+	}
+}
+
+// GetProcessedImages is the handler for GET /v1/supplier/images
+// It lists the supplier's processed photos, newest first.
+func (h *Handlers) GetProcessedImages(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+
+	page := list.Page
+	cursorCond, cursorArgs := page.Where("created_at", "id")
+	query := `
+		SELECT id, original_url, processed_url, background_removed, watermarked, created_at
+		FROM processed_images WHERE supplier_id = ?` + cursorCond + page.OrderLimit("created_at", "id")
+	rows, err := h.readDB().QueryContext(ctx, query, append([]interface{}{supplierID}, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch processed images")
+		return
+	}
+	defer rows.Close()
+
+	images := []models.ProcessedImage{}
+	for rows.Next() {
+		var img models.ProcessedImage
+		if err := rows.Scan(&img.ID, &img.OriginalURL, &img.ProcessedURL, &img.BackgroundRemoved, &img.Watermarked, &img.CreatedAt); err != nil {
+			apierror.Internal(c, "Failed to scan processed image")
+			return
+		}
+		images = append(images, img)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	images, nextCursor := pagination.Paginate(page, images, func(img models.ProcessedImage) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: img.CreatedAt, ID: img.ID}
+	})
+	c.JSON(http.StatusOK, gin.H{"images": images, "nextCursor": nextCursor})
+}
@@ -5,7 +5,10 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
 	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
 	"github.com/gin-gonic/gin"
 	"github.com/gosimple/slug"
 )
@@ -16,16 +19,32 @@ import (
 
 // InventoryItemInput defines the JSON for creating/updating an inventory item
 type InventoryItemInput struct {
-	Name        string  `json:"name" binding:"required"`
-	Description *string `json:"description"`
-	SKU         *string `json:"sku"`
-	Price       float64 `json:"price" binding:"gte=0"`
-	Stock       int     `json:"stock" binding:"gte=0"`
+	Name        string      `json:"name" binding:"required"`
+	Description *string     `json:"description"`
+	SKU         *string     `json:"sku"`
+	Price       money.Money `json:"price" binding:"price"`
+	Stock       int         `json:"stock" binding:"gte=0"`
 	// We will add category/brand linking later
+
+	// Version is only read on update: when sent, a stale version yields 409.
+	Version *int `json:"version"`
+}
+
+// nullString maps an optional input to a nullable column, cleaning it when clean is set.
+func nullString(s *string, clean func(string) string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	if clean != nil {
+		return sql.NullString{String: clean(*s), Valid: true}
+	}
+	return sql.NullString{String: *s, Valid: true}
 }
 
 // CreateInventoryItem is the handler for POST /v1/supplier/inventory
 func (h *Handlers) CreateInventoryItem(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get User ID ---
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
@@ -33,16 +52,16 @@ func (h *Handlers) CreateInventoryItem(c *gin.Context) {
 	// 2. --- Bind & Validate JSON ---
 	var input InventoryItemInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
 	// 3. --- Create Model ---
 	item := &models.InventoryItem{
 		UserID:      userID,
-		Name:        input.Name,
-		Description: sql.NullString{String: *input.Description, Valid: input.Description != nil},
-		SKU:         sql.NullString{String: *input.SKU, Valid: input.SKU != nil},
+		Name:        sanitize.Text(input.Name),
+		Description: nullString(input.Description, sanitize.HTML),
+		SKU:         nullString(input.SKU, nil),
 		Price:       input.Price,
 		Stock:       input.Stock,
 		CreatedAt:   time.Now(),
@@ -55,16 +74,20 @@ func (h *Handlers) CreateInventoryItem(c *gin.Context) {
 		(user_id, name, description, sku, price, stock, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := h.DB.Exec(query,
+	result, err := h.DB.ExecContext(ctx, query,
 		item.UserID, item.Name, item.Description, item.SKU,
 		item.Price, item.Stock, item.CreatedAt, item.UpdatedAt,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create inventory item"})
+		if respondDuplicate(c, err, "This inventory item already exists.") {
+			return
+		}
+		apierror.Internal(c, "Failed to create inventory item")
 		return
 	}
 	id, _ := result.LastInsertId()
 	item.ID = id
+	item.Version = 1
 
 	// 5. --- Send Response ---
 	c.JSON(http.StatusCreated, gin.H{
@@ -75,6 +98,8 @@ func (h *Handlers) CreateInventoryItem(c *gin.Context) {
 
 // GetMyInventoryItems is the handler for GET /v1/supplier/inventory
 func (h *Handlers) GetMyInventoryItems(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get User ID ---
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
@@ -82,14 +107,14 @@ func (h *Handlers) GetMyInventoryItems(c *gin.Context) {
 	// 2. --- Query Database ---
 	query := `
 		SELECT id, user_id, name, description, sku, price, stock, 
-		       promoted_product_id, created_at, updated_at
+		       promoted_product_id, created_at, updated_at, version
 		FROM inventory_items
-		WHERE user_id = ?
+		WHERE user_id = ? AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
-	rows, err := h.DB.Query(query, userID)
+	rows, err := h.DB.QueryContext(ctx, query, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		apierror.Internal(c, "Database query failed")
 		return
 	}
 	defer rows.Close()
@@ -101,9 +126,9 @@ func (h *Handlers) GetMyInventoryItems(c *gin.Context) {
 		if err := rows.Scan(
 			&item.ID, &item.UserID, &item.Name, &item.Description, &item.SKU,
 			&item.Price, &item.Stock, &item.PromotedProductID,
-			&item.CreatedAt, &item.UpdatedAt,
+			&item.CreatedAt, &item.UpdatedAt, &item.Version,
 		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan inventory item"})
+			apierror.Internal(c, "Failed to scan inventory item")
 			return
 		}
 		items = append(items, &item)
@@ -117,6 +142,8 @@ func (h *Handlers) GetMyInventoryItems(c *gin.Context) {
 
 // UpdateInventoryItem is the handler for PUT /v1/supplier/inventory/:id
 func (h *Handlers) UpdateInventoryItem(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get IDs ---
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
@@ -125,36 +152,48 @@ func (h *Handlers) UpdateInventoryItem(c *gin.Context) {
 	// 2. --- Bind & Validate JSON ---
 	var input InventoryItemInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
 	// 3. --- Execute Update ---
 	// This query updates the item *only if* the ID matches AND it belongs to the user
+	// AND (when the client sent one) the version is still current.
 	query := `
 		UPDATE inventory_items
-		SET name = ?, description = ?, sku = ?, price = ?, stock = ?, updated_at = ?
-		WHERE id = ? AND user_id = ?
+		SET name = ?, description = ?, sku = ?, price = ?, stock = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL AND (? IS NULL OR version = ?)
 	`
-	result, err := h.DB.Exec(query,
-		input.Name,
-		sql.NullString{String: *input.Description, Valid: input.Description != nil},
-		sql.NullString{String: *input.SKU, Valid: input.SKU != nil},
+	result, err := h.DB.ExecContext(ctx, query,
+		sanitize.Text(input.Name),
+		nullString(input.Description, sanitize.HTML),
+		nullString(input.SKU, nil),
 		input.Price,
 		input.Stock,
 		time.Now(),
 		itemID,
 		userID,
+		input.Version, input.Version,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item"})
+		if respondDuplicate(c, err, "This inventory item already exists.") {
+			return
+		}
+		apierror.Internal(c, "Failed to update item")
 		return
 	}
 
 	// 4. --- Check Rows Affected ---
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found or you do not have permission to edit it"})
+		// Tell a stale version apart from a missing/foreign item.
+		var exists bool
+		h.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM inventory_items WHERE id = ? AND user_id = ? AND deleted_at IS NULL)", itemID, userID).Scan(&exists)
+		if exists && input.Version != nil {
+			apierror.Conflict(c, "Item was modified by someone else. Reload and try again.")
+			return
+		}
+		apierror.NotFound(c, "Item not found or you do not have permission to edit it")
 		return
 	}
 
@@ -164,23 +203,26 @@ func (h *Handlers) UpdateInventoryItem(c *gin.Context) {
 
 // DeleteInventoryItem is the handler for DELETE /v1/supplier/inventory/:id
 func (h *Handlers) DeleteInventoryItem(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get IDs ---
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
 	itemID := c.Param("id")
 
-	// 2. --- Execute Delete ---
-	query := "DELETE FROM inventory_items WHERE id = ? AND user_id = ?"
-	result, err := h.DB.Exec(query, itemID, userID)
+	// 2. --- Execute (Soft) Delete ---
+	// The row stays restorable until the retention job purges it.
+	query := "UPDATE inventory_items SET deleted_at = ?, version = version + 1 WHERE id = ? AND user_id = ? AND deleted_at IS NULL"
+	result, err := h.DB.ExecContext(ctx, query, time.Now(), itemID, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete item"})
+		apierror.Internal(c, "Failed to delete item")
 		return
 	}
 
 	// 3. --- Check Rows Affected ---
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found or you do not have permission to delete it"})
+		apierror.NotFound(c, "Item not found or you do not have permission to delete it")
 		return
 	}
 
@@ -200,12 +242,14 @@ type InventoryCategoryInput struct {
 
 // CreateInventoryCategory is the handler for POST /v1/supplier/inventory/categories
 func (h *Handlers) CreateInventoryCategory(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
 
 	var input InventoryCategoryInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
@@ -222,9 +266,12 @@ func (h *Handlers) CreateInventoryCategory(c *gin.Context) {
 		INSERT INTO inventory_categories (user_id, name, slug, parent_id, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?)`
 
-	result, err := h.DB.Exec(query, cat.UserID, cat.Name, cat.Slug, cat.ParentID, cat.CreatedAt, cat.UpdatedAt)
+	result, err := h.DB.ExecContext(ctx, query, cat.UserID, cat.Name, cat.Slug, cat.ParentID, cat.CreatedAt, cat.UpdatedAt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create inventory category"})
+		if respondDuplicate(c, err, "You already have a category with this name.") {
+			return
+		}
+		apierror.Internal(c, "Failed to create inventory category")
 		return
 	}
 	id, _ := result.LastInsertId()
@@ -238,6 +285,8 @@ func (h *Handlers) CreateInventoryCategory(c *gin.Context) {
 
 // GetMyInventoryCategories is the handler for GET /v1/supplier/inventory/categories
 func (h *Handlers) GetMyInventoryCategories(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
 
@@ -247,9 +296,9 @@ func (h *Handlers) GetMyInventoryCategories(c *gin.Context) {
 		WHERE user_id = ?
 		ORDER BY name ASC
 	`
-	rows, err := h.DB.Query(query, userID)
+	rows, err := h.DB.QueryContext(ctx, query, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		apierror.Internal(c, "Database query failed")
 		return
 	}
 	defer rows.Close()
@@ -258,7 +307,7 @@ func (h *Handlers) GetMyInventoryCategories(c *gin.Context) {
 	for rows.Next() {
 		var cat models.InventoryCategory
 		if err := rows.Scan(&cat.ID, &cat.UserID, &cat.Name, &cat.Slug, &cat.ParentID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan category"})
+			apierror.Internal(c, "Failed to scan category")
 			return
 		}
 		categories = append(categories, &cat)
@@ -280,12 +329,14 @@ type InventoryBrandInput struct {
 
 // CreateInventoryBrand is the handler for POST /v1/supplier/inventory/brands
 func (h *Handlers) CreateInventoryBrand(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
 
 	var input InventoryBrandInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
@@ -301,9 +352,12 @@ func (h *Handlers) CreateInventoryBrand(c *gin.Context) {
 		INSERT INTO inventory_brands (user_id, name, slug, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?)`
 
-	result, err := h.DB.Exec(query, brand.UserID, brand.Name, brand.Slug, brand.CreatedAt, brand.UpdatedAt)
+	result, err := h.DB.ExecContext(ctx, query, brand.UserID, brand.Name, brand.Slug, brand.CreatedAt, brand.UpdatedAt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create inventory brand"})
+		if respondDuplicate(c, err, "You already have a brand with this name.") {
+			return
+		}
+		apierror.Internal(c, "Failed to create inventory brand")
 		return
 	}
 	id, _ := result.LastInsertId()
@@ -317,6 +371,8 @@ func (h *Handlers) CreateInventoryBrand(c *gin.Context) {
 
 // GetMyInventoryBrands is the handler for GET /v1/supplier/inventory/brands
 func (h *Handlers) GetMyInventoryBrands(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
 
@@ -326,9 +382,9 @@ func (h *Handlers) GetMyInventoryBrands(c *gin.Context) {
 		WHERE user_id = ?
 		ORDER BY name ASC
 	`
-	rows, err := h.DB.Query(query, userID)
+	rows, err := h.DB.QueryContext(ctx, query, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		apierror.Internal(c, "Database query failed")
 		return
 	}
 	defer rows.Close()
@@ -337,7 +393,7 @@ func (h *Handlers) GetMyInventoryBrands(c *gin.Context) {
 	for rows.Next() {
 		var brand models.InventoryBrand
 		if err := rows.Scan(&brand.ID, &brand.UserID, &brand.Name, &brand.Slug); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan brand"})
+			apierror.Internal(c, "Failed to scan brand")
 			return
 		}
 		brands = append(brands, &brand)
@@ -355,15 +411,17 @@ func (h *Handlers) GetMyInventoryBrands(c *gin.Context) {
 // PromoteInventoryItem is the handler for POST /v1/supplier/inventory/:id/promote
 // It copies a private inventory item to the public products table for approval.
 func (h *Handlers) PromoteInventoryItem(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get IDs ---
 	userID_raw, _ := c.Get("userID")
 	supplierID := userID_raw.(int64)
 	inventoryItemID := c.Param("id")
 
 	// 2. --- Begin Transaction ---
-	tx, err := h.DB.Begin()
+	tx, err := h.Store.Begin(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
@@ -373,30 +431,30 @@ func (h *Handlers) PromoteInventoryItem(c *gin.Context) {
 	query := `
 		SELECT id, user_id, name, description, sku, price, stock, promoted_product_id
 		FROM inventory_items
-		WHERE id = ? FOR UPDATE
+		WHERE id = ? AND deleted_at IS NULL FOR UPDATE
 	`
-	err = tx.QueryRow(query, inventoryItemID).Scan(
+	err = tx.QueryRowContext(ctx, query, inventoryItemID).Scan(
 		&item.ID, &item.UserID, &item.Name, &item.Description, &item.SKU,
 		&item.Price, &item.Stock, &item.PromotedProductID,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Inventory item not found"})
+			apierror.NotFound(c, "Inventory item not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inventory item"})
+		apierror.Internal(c, "Failed to get inventory item")
 		return
 	}
 
 	// Security Check
 	if item.UserID != supplierID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to promote this item"})
+		apierror.Forbidden(c, "You do not have permission to promote this item")
 		return
 	}
 
 	// Logic Check
 	if item.PromotedProductID.Valid {
-		c.JSON(http.StatusConflict, gin.H{"error": "This item has already been promoted"})
+		apierror.Conflict(c, "This item has already been promoted")
 		return
 	}
 
@@ -404,6 +462,12 @@ func (h *Handlers) PromoteInventoryItem(c *gin.Context) {
 	// We copy the details from the inventory item to a new product.
 	// The new product's status is 'pending' for manager approval.
 	// We'll assume 0 commission and no shipping data for now.
+	// Its SKU must be free among the supplier's products; a blank one is generated.
+	sku := item.SKU.String
+	if err := h.assignSKUs(ctx, tx, supplierID, 0, "", []*string{&sku}); err != nil {
+		respondSKUError(c, err)
+		return
+	}
 	now := time.Now()
 	productQuery := `
 		INSERT INTO products
@@ -411,35 +475,38 @@ func (h *Handlers) PromoteInventoryItem(c *gin.Context) {
 		 is_variable, status, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, 0, 'pending', ?, ?)`
 
-	result, err := tx.Exec(productQuery,
-		supplierID, item.Name, item.Description, item.SKU,
+	result, err := tx.ExecContext(ctx, productQuery,
+		supplierID, item.Name, item.Description, sku,
 		item.Price, item.Stock, now, now,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create public product"})
+		if respondDuplicate(c, err, "This item was already promoted.") {
+			return
+		}
+		apierror.Internal(c, "Failed to create public product")
 		return
 	}
 	newProductID, err := result.LastInsertId()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get new product ID"})
+		apierror.Internal(c, "Failed to get new product ID")
 		return
 	}
 
 	// 5. --- Link Inventory Item to New Product ---
 	updateQuery := `
 		UPDATE inventory_items
-		SET promoted_product_id = ?, updated_at = ?
+		SET promoted_product_id = ?, updated_at = ?, version = version + 1
 		WHERE id = ?
 	`
-	_, err = tx.Exec(updateQuery, newProductID, now, item.ID)
+	_, err = tx.ExecContext(ctx, updateQuery, newProductID, now, item.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link inventory item to product"})
+		apierror.Internal(c, "Failed to link inventory item to product")
 		return
 	}
 
 	// 6. --- Commit Transaction ---
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		apierror.Internal(c, "Failed to commit transaction")
 		return
 	}
 
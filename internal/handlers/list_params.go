@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Collection Query Parameters ---
+//
+// Every listing reads its paging, filters and sort with parseList, so each
+// collection takes page[size], page[cursor], filter[name] and sort alike and
+// refuses the same mistakes (see pagination.ParseList).
+//
+
+// parseList reads a listing's query string, answering 400 itself when it is
+// invalid.
+func parseList(c *gin.Context, spec pagination.ListSpec) (pagination.List, bool) {
+	list, err := pagination.ParseList(c.Request.URL.Query(), spec)
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return list, false
+	}
+	return list, true
+}
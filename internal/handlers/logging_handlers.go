@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/database"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Manager: Runtime Logging ---
+//
+// The log level and the slow query threshold start from LOG_LEVEL and
+// DB_SLOW_QUERY_THRESHOLD. Changes made here are persisted in the settings
+// table, applied at once on this instance and picked up by the others
+// within a minute (WatchRuntimeSettings).
+
+// Settings keys of the runtime logging configuration.
+const (
+	settingLogLevel         = "log_level"
+	settingSlowQueryMillis  = "slow_query_threshold_ms"
+	runtimeSettingsInterval = time.Minute
+)
+
+// LoggingConfig is the body of GET and PATCH /v1/manager/logging.
+type LoggingConfig struct {
+	Level                string `json:"level"`
+	SlowQueryThresholdMs int64  `json:"slowQueryThresholdMs"` // 0 = slow query logging off
+}
+
+// UpdateLoggingInput changes either value; omitted fields stay as they are.
+type UpdateLoggingInput struct {
+	Level                *string `json:"level" binding:"omitempty,oneof=debug info warn error"`
+	SlowQueryThresholdMs *int64  `json:"slowQueryThresholdMs" binding:"omitempty,min=0"`
+}
+
+func currentLoggingConfig() LoggingConfig {
+	return LoggingConfig{
+		Level:                logging.CurrentLevel().String(),
+		SlowQueryThresholdMs: database.SlowQueryThreshold().Milliseconds(),
+	}
+}
+
+// GetLogging is the handler for GET /v1/manager/logging
+func (h *Handlers) GetLogging(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"logging": currentLoggingConfig()})
+}
+
+// UpdateLogging is the handler for PATCH /v1/manager/logging
+func (h *Handlers) UpdateLogging(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Validate Input ---
+	var input UpdateLoggingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if input.Level == nil && input.SlowQueryThresholdMs == nil {
+		apierror.BadRequest(c, "Nothing to update")
+		return
+	}
+
+	// 2. --- Persist (so restarts and other instances keep the change) ---
+	changes := make(map[string]string)
+	if input.Level != nil {
+		changes[settingLogLevel] = *input.Level
+	}
+	if input.SlowQueryThresholdMs != nil {
+		changes[settingSlowQueryMillis] = strconv.FormatInt(*input.SlowQueryThresholdMs, 10)
+	}
+	for key, value := range changes {
+		_, err := h.DB.ExecContext(ctx, `
+			INSERT INTO settings (setting_key, setting_value)
+			VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE setting_value = VALUES(setting_value)`, key, value)
+		if err != nil {
+			apierror.Internal(c, "Failed to save logging settings")
+			return
+		}
+	}
+	h.Settings.Invalidate(ctx)
+
+	// 3. --- Apply Here & Now ---
+	applyLoggingSettings(changes)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logging settings updated",
+		"logging": currentLoggingConfig(),
+	})
+}
+
+// applyLoggingSettings applies the runtime logging keys present in values.
+// Invalid values (e.g. typed by hand into PATCH /manager/settings) are logged and skipped.
+func applyLoggingSettings(values map[string]string) {
+	if raw, ok := values[settingLogLevel]; ok {
+		if level, err := logging.ParseLevel(raw); err == nil {
+			if level != logging.CurrentLevel() {
+				logging.SetLevel(level)
+				logging.Warnf("[Logging] Log level set to %s", level)
+			}
+		} else {
+			logging.Errorf("[Logging] Ignoring setting %s: %v", settingLogLevel, err)
+		}
+	}
+	if raw, ok := values[settingSlowQueryMillis]; ok {
+		if ms, err := strconv.ParseInt(raw, 10, 64); err == nil && ms >= 0 {
+			threshold := time.Duration(ms) * time.Millisecond
+			if threshold != database.SlowQueryThreshold() {
+				database.SetSlowQueryThreshold(threshold)
+				logging.Warnf("[Logging] Slow query threshold set to %s", threshold)
+			}
+		} else {
+			logging.Errorf("[Logging] Ignoring setting %s=%q: must be a whole number of milliseconds", settingSlowQueryMillis, raw)
+		}
+	}
+}
+
+// ApplyRuntimeSettings applies the persisted logging settings and the saved
+// email and SMS providers, if any.
+func (h *Handlers) ApplyRuntimeSettings(ctx context.Context) error {
+	values, err := h.Settings.All(ctx)
+	if err != nil {
+		return err
+	}
+	applyLoggingSettings(values)
+	return h.applyMessagingProviders(ctx)
+}
+
+// WatchRuntimeSettings re-applies the persisted runtime settings every minute
+// until ctx is cancelled, so a change made on one instance reaches all of them.
+func (h *Handlers) WatchRuntimeSettings(ctx context.Context) {
+	ticker := time.NewTicker(runtimeSettingsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := h.ApplyRuntimeSettings(ctx); err != nil && ctx.Err() == nil {
+				logging.Errorf("[Logging] Could not reload runtime settings: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/email"
+	"github.com/01moynul/taptosell-golang/internal/events"
+	"github.com/01moynul/taptosell-golang/internal/i18n"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/store"
+)
+
+//
+// --- Low-Stock Alerts ---
+//
+
+// SettingLowStockThreshold is the stock below which suppliers are alerted; 0 turns the alerts off.
+const SettingLowStockThreshold = "low_stock_threshold"
+
+// lowStockThreshold reads SettingLowStockThreshold; a missing or invalid value
+// turns the alerts off rather than failing the order.
+func (h *Handlers) lowStockThreshold(ctx context.Context) int {
+	value, err := h.Settings.Get(ctx, SettingLowStockThreshold)
+	if err != nil {
+		return 0
+	}
+	threshold, err := strconv.Atoi(value)
+	if err != nil || threshold < 0 {
+		return 0
+	}
+	return threshold
+}
+
+// takeStock deducts quantity from the stock of the product's variant (or the
+// product when variantID is nil) on tx. When that takes the stock below
+// threshold, it returns the StockLow event to publish once tx commits.
+func takeStock(ctx context.Context, tx *store.Tx, productID int64, variantID *int64, quantity, threshold int) (*events.StockLow, error) {
+	if err := tx.Products.AdjustStock(ctx, productID, variantID, -quantity); err != nil {
+		return nil, err
+	}
+	if threshold <= 0 {
+		return nil, nil
+	}
+	// The row is already locked by the update, so this is the stock the order left.
+	stock, err := tx.Products.StockForUpdate(ctx, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	return lowStockEvent(productID, variantID, stock, quantity, threshold), nil
+}
+
+// lowStockEvent returns the StockLow event when taking taken units left stock
+// below threshold, and nil when it was already below or still is not.
+func lowStockEvent(productID int64, variantID *int64, stock, taken, threshold int) *events.StockLow {
+	if threshold <= 0 || stock >= threshold || stock+taken < threshold {
+		return nil
+	}
+	return &events.StockLow{ProductID: productID, VariantID: variantID, Stock: stock, Threshold: threshold}
+}
+
+// notifyLowStock is the StockLow subscriber: it notifies the supplier and, with
+// LOW_STOCK_DIGEST, queues the product for the daily email.
+func (h *Handlers) notifyLowStock(ctx context.Context, e events.StockLow) error {
+	var supplierID int64
+	var name string
+	var options []byte
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT p.supplier_id, p.name, v.options
+		FROM products p
+		LEFT JOIN product_variants v ON v.id = ? AND v.product_id = p.id
+		WHERE p.id = ? AND p.deleted_at IS NULL`, e.VariantID, e.ProductID).Scan(&supplierID, &name, &options)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil // deleted since
+	}
+	if err != nil {
+		return err
+	}
+	if label := optionsLabel(options); label != "" {
+		name += " (" + label + ")"
+	}
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	message := fmt.Sprintf("Your product \"%s\" is running low: %d left in stock.", name, e.Stock)
+	if err := h.AddBatchedNotification(ctx, tx, supplierID, "low_stock", message, "/supplier/products"); err != nil {
+		return err
+	}
+	if h.Config.Notifications.LowStockDigest {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO low_stock_alerts (supplier_id, product_id, variant_id, stock, created_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			supplierID, e.ProductID, e.VariantID, e.Stock, time.Now()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ProcessLowStockDigest emails each supplier the products that went low on
+// stock since the last digest. The background worker calls it daily at
+// LOW_STOCK_DIGEST_HOUR.
+func (h *Handlers) ProcessLowStockDigest(ctx context.Context) {
+	rows, err := h.DB.QueryContext(ctx, "SELECT DISTINCT supplier_id FROM low_stock_alerts LIMIT 500")
+	if err != nil {
+		logging.Errorf("[LowStock] Error fetching pending alerts: %v", err)
+		return
+	}
+	var supplierIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			logging.Errorf("[LowStock] Error scanning pending alerts: %v", err)
+			return
+		}
+		supplierIDs = append(supplierIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logging.Errorf("[LowStock] Error fetching pending alerts: %v", err)
+		return
+	}
+
+	threshold := h.lowStockThreshold(ctx)
+	for i, supplierID := range supplierIDs {
+		if ctx.Err() != nil {
+			logging.Infof("[LowStock] Shutting down, %d digests left for the next run", len(supplierIDs)-i)
+			return
+		}
+		if err := h.sendLowStockDigest(context.WithoutCancel(ctx), supplierID, threshold); err != nil {
+			logging.Errorf("[LowStock] Failed to send the digest of User %d: %v", supplierID, err)
+		}
+	}
+}
+
+// sendLowStockDigest emails one supplier their pending alerts and deletes them.
+// Products restocked (or deleted) since are left out. The alerts stay locked
+// until the email is sent, so another instance cannot send it twice, and a
+// failed send keeps them for the next day.
+func (h *Handlers) sendLowStockDigest(ctx context.Context, supplierID int64, threshold int) error {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.id, a.product_id, a.variant_id, p.name, v.options,
+			COALESCE(v.stock_quantity, p.stock_quantity), p.deleted_at IS NOT NULL
+		FROM low_stock_alerts a
+		JOIN products p ON p.id = a.product_id
+		LEFT JOIN product_variants v ON v.id = a.variant_id
+		WHERE a.supplier_id = ?
+		ORDER BY a.id
+		FOR UPDATE`, supplierID)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	var lines []string
+	listed := make(map[string]bool)
+	for rows.Next() {
+		var id, productID int64
+		var variantID sql.NullInt64
+		var name string
+		var options []byte
+		var stock int
+		var deleted bool
+		if err := rows.Scan(&id, &productID, &variantID, &name, &options, &stock, &deleted); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+		key := fmt.Sprintf("%d/%d", productID, variantID.Int64)
+		if deleted || listed[key] || (threshold > 0 && stock >= threshold) {
+			continue
+		}
+		listed[key] = true
+		if label := optionsLabel(options); label != "" {
+			name += " (" + label + ")"
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %d left", name, stock))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil // sent by another instance
+	}
+
+	if len(lines) > 0 {
+		var to string
+		err := tx.QueryRowContext(ctx, "SELECT email FROM users WHERE id = ? AND deleted_at IS NULL", supplierID).Scan(&to)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if to != "" {
+			if err := email.SendLowStockDigestEmail(to, lines, i18n.Default); err != nil {
+				return err
+			}
+		}
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM low_stock_alerts WHERE id IN (?"+strings.Repeat(", ?", len(ids)-1)+")", args...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.Infof("[LowStock] Sent the digest of User %d (%d products)", supplierID, len(lines))
+	return nil
+}
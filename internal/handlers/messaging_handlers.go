@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/email"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/pii"
+	"github.com/01moynul/taptosell-golang/internal/sms"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Manager: Email & SMS Providers ---
+//
+// Providers live in messaging_providers, one row per channel, with the
+// password or API token encrypted like the other PII columns. A change is
+// applied at once on this instance and picked up by the others within a
+// minute (ApplyRuntimeSettings). Secrets are never sent back: the views only
+// say whether one is saved.
+
+// Channels of messaging_providers.
+const (
+	channelEmail = "email"
+	channelSMS   = "sms"
+)
+
+// smsTestTimeout bounds a test SMS; SMTP has its own timeout.
+const smsTestTimeout = 30 * time.Second
+
+// defaultSMTPPort is used when an SMTP provider is saved without a port (submission).
+const defaultSMTPPort = 587
+
+// EmailProvider is the stored email configuration, minus the password.
+type EmailProvider struct {
+	Provider    string `json:"provider" binding:"required,oneof=log smtp"`
+	Host        string `json:"host,omitempty" binding:"max=255"`
+	Port        int    `json:"port,omitempty" binding:"omitempty,min=1,max=65535"`
+	Username    string `json:"username,omitempty" binding:"max=255"`
+	FromAddress string `json:"fromAddress,omitempty" binding:"omitempty,email"`
+	FromName    string `json:"fromName,omitempty" binding:"max=100"`
+}
+
+// EmailProviderInput is the body of PUT /v1/manager/messaging/email.
+type EmailProviderInput struct {
+	EmailProvider
+	Password *string `json:"password"` // omitted: keep the saved password
+}
+
+// TestEmailInput is the body of POST /v1/manager/messaging/email/test.
+type TestEmailInput struct {
+	EmailProviderInput
+	To string `json:"to" binding:"required,email"`
+}
+
+// EmailProviderView is what GET /v1/manager/messaging shows of the email provider.
+type EmailProviderView struct {
+	EmailProvider
+	HasPassword bool       `json:"hasPassword"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// SMSProvider is the stored SMS configuration, minus the auth token.
+type SMSProvider struct {
+	Provider   string `json:"provider" binding:"required,oneof=log twilio"`
+	AccountSID string `json:"accountSid,omitempty" binding:"max=64"`
+	From       string `json:"from,omitempty" binding:"max=32"` // number (E.164) or sender ID
+}
+
+// SMSProviderInput is the body of PUT /v1/manager/messaging/sms.
+type SMSProviderInput struct {
+	SMSProvider
+	AuthToken *string `json:"authToken"` // omitted: keep the saved token
+}
+
+// TestSMSInput is the body of POST /v1/manager/messaging/sms/test.
+type TestSMSInput struct {
+	SMSProviderInput
+	To string `json:"to" binding:"required,e164"`
+}
+
+// SMSProviderView is what GET /v1/manager/messaging shows of the SMS provider.
+type SMSProviderView struct {
+	SMSProvider
+	HasAuthToken bool       `json:"hasAuthToken"`
+	UpdatedAt    *time.Time `json:"updatedAt,omitempty"`
+}
+
+// emailSender builds the Sender p describes; the error explains what is missing.
+func (p EmailProvider) emailSender(password string) (email.Sender, error) {
+	if p.Provider == "log" {
+		return email.LogSender{}, nil
+	}
+	if p.Host == "" || p.FromAddress == "" {
+		return nil, errors.New("SMTP needs a host and a fromAddress")
+	}
+	if p.Username != "" && password == "" {
+		return nil, errors.New("SMTP with a username needs a password")
+	}
+	port := p.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	return email.SMTPSender{
+		Host: p.Host, Port: port, Username: p.Username, Password: password,
+		From: p.FromAddress, FromName: p.FromName,
+	}, nil
+}
+
+// smsSender builds the Sender p describes; the error explains what is missing.
+func (p SMSProvider) smsSender(authToken string) (sms.Sender, error) {
+	if p.Provider == "log" {
+		return sms.LogSender{}, nil
+	}
+	if p.AccountSID == "" || p.From == "" || authToken == "" {
+		return nil, errors.New("Twilio needs an accountSid, an authToken and a from number")
+	}
+	return sms.TwilioSender{AccountSID: p.AccountSID, AuthToken: authToken, From: p.From}, nil
+}
+
+// storedProvider is one row of messaging_providers.
+type storedProvider struct {
+	Settings  []byte // JSON of EmailProvider or SMSProvider
+	Secret    string // decrypted
+	UpdatedAt time.Time
+}
+
+// loadProvider reads a channel's row, or returns nil when it was never configured.
+func (h *Handlers) loadProvider(ctx context.Context, channel string) (*storedProvider, error) {
+	var p storedProvider
+	var secret sql.NullString
+	err := h.DB.QueryRowContext(ctx,
+		"SELECT settings, secret, updated_at FROM messaging_providers WHERE channel = ?", channel).
+		Scan(&p.Settings, &secret, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.Secret, err = h.PII.Decrypt(pii.MessagingSecret, secret.String); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// saveProvider writes a channel's row.
+func (h *Handlers) saveProvider(ctx context.Context, channel, provider string, settings interface{}, secret string, userID int64) error {
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	sealed, err := h.PII.Encrypt(pii.MessagingSecret, secret)
+	if err != nil {
+		return err
+	}
+	_, err = h.DB.ExecContext(ctx, `
+		INSERT INTO messaging_providers (channel, provider, settings, secret, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE provider = VALUES(provider), settings = VALUES(settings), secret = VALUES(secret),
+			updated_by = VALUES(updated_by), updated_at = VALUES(updated_at)`,
+		channel, provider, settingsJSON, sql.NullString{String: sealed, Valid: sealed != ""}, userID, time.Now())
+	return err
+}
+
+// savedSecret returns the secret stored for channel ("" when there is none).
+func (h *Handlers) savedSecret(ctx context.Context, channel string) (string, error) {
+	p, err := h.loadProvider(ctx, channel)
+	if err != nil || p == nil {
+		return "", err
+	}
+	return p.Secret, nil
+}
+
+// secretOrSaved is the secret from the input, or the saved one when it is omitted.
+func (h *Handlers) secretOrSaved(ctx context.Context, channel string, input *string) (string, error) {
+	if input != nil {
+		return *input, nil
+	}
+	return h.savedSecret(ctx, channel)
+}
+
+// GetMessaging is the handler for GET /v1/manager/messaging
+// Channels never configured show the log provider.
+func (h *Handlers) GetMessaging(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	emailView := EmailProviderView{EmailProvider: EmailProvider{Provider: "log"}}
+	smsView := SMSProviderView{SMSProvider: SMSProvider{Provider: "log"}}
+	for _, ch := range []struct {
+		channel   string
+		settings  interface{}
+		hasSecret *bool
+		updatedAt **time.Time
+	}{
+		{channelEmail, &emailView.EmailProvider, &emailView.HasPassword, &emailView.UpdatedAt},
+		{channelSMS, &smsView.SMSProvider, &smsView.HasAuthToken, &smsView.UpdatedAt},
+	} {
+		p, err := h.loadProvider(ctx, ch.channel)
+		if err != nil {
+			apierror.Internal(c, "Failed to load messaging providers")
+			return
+		}
+		if p == nil {
+			continue
+		}
+		if err := json.Unmarshal(p.Settings, ch.settings); err != nil {
+			apierror.Internal(c, "Failed to load messaging providers")
+			return
+		}
+		*ch.hasSecret = p.Secret != ""
+		*ch.updatedAt = &p.UpdatedAt
+	}
+
+	c.JSON(http.StatusOK, gin.H{"email": emailView, "sms": smsView})
+}
+
+// UpdateEmailProvider is the handler for PUT /v1/manager/messaging/email
+// Send a test with POST /v1/manager/messaging/email/test first: saving only
+// checks that the configuration is complete.
+func (h *Handlers) UpdateEmailProvider(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+
+	// 1. --- Validate Input ---
+	var input EmailProviderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	password, err := h.secretOrSaved(ctx, channelEmail, input.Password)
+	if err != nil {
+		apierror.Internal(c, "Failed to load messaging providers")
+		return
+	}
+	sender, err := input.emailSender(password)
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	// 2. --- Save & Apply Here ---
+	if err := h.saveProvider(ctx, channelEmail, input.Provider, input.EmailProvider, password, managerID); err != nil {
+		apierror.Internal(c, "Failed to save email provider")
+		return
+	}
+	email.SetSender(sender)
+	logging.Warnf("[Messaging] Email provider set to %s by User %d", input.Provider, managerID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email provider updated"})
+}
+
+// TestEmailProvider is the handler for POST /v1/manager/messaging/email/test
+// It sends a test email with the configuration in the body, saved or not.
+func (h *Handlers) TestEmailProvider(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var input TestEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	password, err := h.secretOrSaved(ctx, channelEmail, input.Password)
+	if err != nil {
+		apierror.Internal(c, "Failed to load messaging providers")
+		return
+	}
+	sender, err := input.emailSender(password)
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	body := "This is a test message from TapToSell. If you can read it, the email provider works."
+	if err := sender.Send(input.To, "TapToSell test email", body); err != nil {
+		apierror.BadRequest(c, fmt.Sprintf("Test email failed: %v", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test email sent"})
+}
+
+// UpdateSMSProvider is the handler for PUT /v1/manager/messaging/sms
+// Like UpdateEmailProvider, test first with POST /v1/manager/messaging/sms/test.
+func (h *Handlers) UpdateSMSProvider(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+
+	// 1. --- Validate Input ---
+	var input SMSProviderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	token, err := h.secretOrSaved(ctx, channelSMS, input.AuthToken)
+	if err != nil {
+		apierror.Internal(c, "Failed to load messaging providers")
+		return
+	}
+	sender, err := input.smsSender(token)
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	// 2. --- Save & Apply Here ---
+	if err := h.saveProvider(ctx, channelSMS, input.Provider, input.SMSProvider, token, managerID); err != nil {
+		apierror.Internal(c, "Failed to save SMS provider")
+		return
+	}
+	sms.SetSender(sender)
+	logging.Warnf("[Messaging] SMS provider set to %s by User %d", input.Provider, managerID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "SMS provider updated"})
+}
+
+// TestSMSProvider is the handler for POST /v1/manager/messaging/sms/test
+// It sends a test message with the configuration in the body, saved or not.
+func (h *Handlers) TestSMSProvider(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var input TestSMSInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	token, err := h.secretOrSaved(ctx, channelSMS, input.AuthToken)
+	if err != nil {
+		apierror.Internal(c, "Failed to load messaging providers")
+		return
+	}
+	sender, err := input.smsSender(token)
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, smsTestTimeout)
+	defer cancel()
+	if err := sender.Send(sendCtx, input.To, "TapToSell test message: your SMS provider works."); err != nil {
+		apierror.BadRequest(c, fmt.Sprintf("Test SMS failed: %v", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test SMS sent"})
+}
+
+// applyMessagingProviders installs the saved providers. A row that no longer
+// builds a sender (e.g. a key was removed) is logged and the current one kept.
+func (h *Handlers) applyMessagingProviders(ctx context.Context) error {
+	if p, err := h.loadProvider(ctx, channelEmail); err != nil {
+		return err
+	} else if p != nil {
+		var cfg EmailProvider
+		err := json.Unmarshal(p.Settings, &cfg)
+		var sender email.Sender
+		if err == nil {
+			sender, err = cfg.emailSender(p.Secret)
+		}
+		if err != nil {
+			logging.Errorf("[Messaging] Ignoring saved email provider: %v", err)
+		} else {
+			email.SetSender(sender)
+		}
+	}
+
+	if p, err := h.loadProvider(ctx, channelSMS); err != nil {
+		return err
+	} else if p != nil {
+		var cfg SMSProvider
+		err := json.Unmarshal(p.Settings, &cfg)
+		var sender sms.Sender
+		if err == nil {
+			sender, err = cfg.smsSender(p.Secret)
+		}
+		if err != nil {
+			logging.Errorf("[Messaging] Ignoring saved SMS provider: %v", err)
+		} else {
+			sms.SetSender(sender)
+		}
+	}
+	return nil
+}
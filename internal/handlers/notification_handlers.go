@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/i18n"
 	"github.com/01moynul/taptosell-golang/internal/models"
 	"github.com/gin-gonic/gin"
 )
@@ -16,8 +20,10 @@ import (
 
 // AddNotification is an internal helper function to create new notifications.
 // It's not a handler itself but will be called by other handlers (like ApproveProduct).
-// NOTE: This function must be called from within a database transaction (tx).
-func (h *Handlers) AddNotification(tx *sql.Tx, userID int64, message string, link string) error {
+// Pass the handler's transaction (tx) so the notification commits with the change,
+// or h.DB from an event subscriber that runs after the commit.
+// The message is stored in English and translated when it is read (GetMyNotifications).
+func (h *Handlers) AddNotification(ctx context.Context, q Querier, userID int64, message string, link string) error {
 	// Create a NullString for the link
 	var nullLink sql.NullString
 	if link != "" {
@@ -31,7 +37,7 @@ func (h *Handlers) AddNotification(tx *sql.Tx, userID int64, message string, lin
 		(user_id, message, link, is_read, created_at)
 		VALUES (?, ?, ?, 0, ?)`
 
-	_, err := tx.Exec(query, userID, message, nullLink, time.Now())
+	_, err := q.ExecContext(ctx, query, userID, message, nullLink, time.Now())
 	if err != nil {
 		// We return a wrapped error to provide more context
 		return fmt.Errorf("failed to add notification: %w", err)
@@ -40,30 +46,128 @@ func (h *Handlers) AddNotification(tx *sql.Tx, userID int64, message string, lin
 	return nil
 }
 
+// notificationBatch is how notifications of one kind are summarized.
+type notificationBatch struct {
+	summary string // fmt with the count, e.g. "%d of your products were approved."
+	link    string
+}
+
+// notificationBatches are the kinds that bulk operations send many of at once.
+var notificationBatches = map[string]notificationBatch{
+	"product_approved":  {"%d of your products were approved.", "/supplier/products"},
+	"product_rejected":  {"%d of your products were rejected.", "/supplier/products"},
+	"order_paid":        {"%d new paid orders are ready to ship.", "/supplier/orders"},
+	"question":          {"%d new questions are waiting for your answer.", "/supplier/questions"},
+	"low_stock":         {"%d of your products are running low on stock.", "/supplier/products"},
+	"watchlist":         {"%d products on your watchlist changed price or came back in stock.", "/dropshipper/watchlist"},
+	"duplicate_removed": {"%d of your products were removed as duplicates.", "/supplier/products"},
+}
+
+// AddBatchedNotification is AddNotification for a kind of notificationBatches.
+// When the user has an unread notification of the same kind from within
+// NOTIFICATION_BATCH_WINDOW, the two are grouped under a summary ("2 of your
+// products were approved"), which later ones join and move to the top.
+// The grouped rows keep their own message and link.
+// Call it on a transaction: the rows it groups are locked.
+func (h *Handlers) AddBatchedNotification(ctx context.Context, q Querier, userID int64, kind, message, link string) error {
+	batch, ok := notificationBatches[kind]
+	window := h.Config.Notifications.BatchWindow
+	if !ok || window <= 0 {
+		_, err := insertNotification(ctx, q, userID, &kind, message, link, nil, 0)
+		return err
+	}
+	now := time.Now()
+	since := now.Add(-window)
+
+	// 1. An open summary takes the new notification.
+	var summaryID int64
+	var count int
+	err := q.QueryRowContext(ctx, `
+		SELECT id, item_count FROM notifications
+		WHERE user_id = ? AND kind = ? AND item_count > 0 AND is_read = 0 AND created_at >= ?
+		ORDER BY id DESC LIMIT 1 FOR UPDATE`,
+		userID, kind, since).Scan(&summaryID, &count)
+	switch {
+	case err == nil:
+		if _, err := insertNotification(ctx, q, userID, &kind, message, link, &summaryID, 0); err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx, "UPDATE notifications SET item_count = ?, message = ?, created_at = ? WHERE id = ?",
+			count+1, fmt.Sprintf(batch.summary, count+1), now, summaryID)
+		if err != nil {
+			return fmt.Errorf("failed to update notification summary: %w", err)
+		}
+		return nil
+	case err != sql.ErrNoRows:
+		return fmt.Errorf("failed to find notification summary: %w", err)
+	}
+
+	// 2. A recent ungrouped one of the same kind: start a summary of both.
+	var previousID int64
+	err = q.QueryRowContext(ctx, `
+		SELECT id FROM notifications
+		WHERE user_id = ? AND kind = ? AND batch_id IS NULL AND item_count = 0 AND is_read = 0 AND created_at >= ?
+		ORDER BY id DESC LIMIT 1 FOR UPDATE`,
+		userID, kind, since).Scan(&previousID)
+	if err == sql.ErrNoRows {
+		_, err = insertNotification(ctx, q, userID, &kind, message, link, nil, 0)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find notification to batch: %w", err)
+	}
+	summaryID, err = insertNotification(ctx, q, userID, &kind, fmt.Sprintf(batch.summary, 2), batch.link, nil, 2)
+	if err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE notifications SET batch_id = ? WHERE id = ?", summaryID, previousID); err != nil {
+		return fmt.Errorf("failed to batch notification: %w", err)
+	}
+	_, err = insertNotification(ctx, q, userID, &kind, message, link, &summaryID, 0)
+	return err
+}
+
+// insertNotification writes one notifications row and returns its ID.
+func insertNotification(ctx context.Context, q Querier, userID int64, kind *string, message, link string, batchID *int64, itemCount int) (int64, error) {
+	result, err := q.ExecContext(ctx, `
+		INSERT INTO notifications
+		(user_id, message, link, is_read, created_at, kind, batch_id, item_count)
+		VALUES (?, ?, ?, 0, ?, ?, ?, ?)`,
+		userID, message, sql.NullString{String: link, Valid: link != ""}, time.Now(), kind, batchID, itemCount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add notification: %w", err)
+	}
+	return result.LastInsertId()
+}
+
 // GetMyNotifications is the handler for GET /v1/notifications
 // It retrieves all notifications for the logged-in user, newest first.
 func (h *Handlers) GetMyNotifications(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get User ID ---
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
 
 	// 2. --- Query Database ---
 	// We'll get all notifications, with unread and newest first
+	// (batched ones through their summary)
 	query := `
-		SELECT id, user_id, message, link, is_read, created_at
+		SELECT id, user_id, message, link, is_read, created_at, kind, item_count
 		FROM notifications
-		WHERE user_id = ?
+		WHERE user_id = ? AND batch_id IS NULL
 		ORDER BY is_read ASC, created_at DESC
 		LIMIT 50` // Limit to 50 to avoid performance issues
 
-	rows, err := h.DB.Query(query, userID)
+	rows, err := h.DB.QueryContext(ctx, query, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		apierror.Internal(c, "Database query failed")
 		return
 	}
 	defer rows.Close()
 
-	// 3. --- Scan Rows into Slice ---
+	// 3. --- Scan Rows into Slice (messages in the caller's language) ---
+	lang := c.GetString(i18n.ContextKey)
 	var notifications []*models.Notification
 	for rows.Next() {
 		var notif models.Notification
@@ -74,15 +178,18 @@ func (h *Handlers) GetMyNotifications(c *gin.Context) {
 			&notif.Link,
 			&notif.IsRead,
 			&notif.CreatedAt,
+			&notif.Kind,
+			&notif.Count,
 		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan notification row"})
+			apierror.Internal(c, "Failed to scan notification row")
 			return
 		}
+		notif.Message = i18n.T(lang, notif.Message)
 		notifications = append(notifications, &notif)
 	}
 
 	if err = rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error iterating notification rows"})
+		apierror.Internal(c, "Error iterating notification rows")
 		return
 	}
 
@@ -95,6 +202,8 @@ func (h *Handlers) GetMyNotifications(c *gin.Context) {
 // MarkNotificationAsRead is the handler for PATCH /v1/notifications/:id/read
 // It marks a single notification as read.
 func (h *Handlers) MarkNotificationAsRead(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get IDs ---
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
@@ -104,28 +213,29 @@ func (h *Handlers) MarkNotificationAsRead(c *gin.Context) {
 	// We update the row *only if* the notification ID matches
 	// AND it belongs to the currently logged-in user.
 	// This prevents a user from marking another user's notifications as read.
+	// Reading a summary reads the notifications it groups.
 	query := `
 		UPDATE notifications
 		SET is_read = 1
-		WHERE id = ? AND user_id = ?`
+		WHERE (id = ? OR batch_id = ?) AND user_id = ?`
 
-	result, err := h.DB.Exec(query, notificationID, userID)
+	result, err := h.DB.ExecContext(ctx, query, notificationID, notificationID, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		apierror.Internal(c, "Failed to update notification")
 		return
 	}
 
 	// 3. --- Check Rows Affected ---
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check affected rows"})
+		apierror.Internal(c, "Failed to check affected rows")
 		return
 	}
 
 	// If 0 rows were affected, the notification either didn't exist
 	// or didn't belong to this user.
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found or you do not have permission to update it"})
+		apierror.NotFound(c, "Notification not found or you do not have permission to update it")
 		return
 	}
 
@@ -134,3 +244,53 @@ func (h *Handlers) MarkNotificationAsRead(c *gin.Context) {
 		"message": "Notification marked as read",
 	})
 }
+
+// GetNotificationItems is the handler for GET /v1/notifications/:id/items
+// It lists the notifications grouped under one of the user's summaries, newest first.
+func (h *Handlers) GetNotificationItems(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs ---
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+	summaryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Notification not found")
+		return
+	}
+
+	// 2. --- Query Database (the user_id check covers the summary's owner) ---
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT id, user_id, message, link, is_read, created_at, kind, item_count
+		FROM notifications
+		WHERE batch_id = ? AND user_id = ?
+		ORDER BY created_at DESC, id DESC`, summaryID, userID)
+	if err != nil {
+		apierror.Internal(c, "Database query failed")
+		return
+	}
+	defer rows.Close()
+
+	// 3. --- Scan Rows (messages in the caller's language) ---
+	lang := c.GetString(i18n.ContextKey)
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notif models.Notification
+		if err := rows.Scan(&notif.ID, &notif.UserID, &notif.Message, &notif.Link, &notif.IsRead, &notif.CreatedAt, &notif.Kind, &notif.Count); err != nil {
+			apierror.Internal(c, "Failed to scan notification row")
+			return
+		}
+		notif.Message = i18n.T(lang, notif.Message)
+		notifications = append(notifications, notif)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating notification rows")
+		return
+	}
+	if len(notifications) == 0 {
+		apierror.NotFound(c, "Notification not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications})
+}
@@ -1,14 +1,26 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/01moynul/taptosell-golang/internal/dto"
+	"github.com/01moynul/taptosell-golang/internal/events"
+	"github.com/01moynul/taptosell-golang/internal/logging"
 	"github.com/01moynul/taptosell-golang/internal/models" // <-- Added this import
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/shipping"
+	"github.com/01moynul/taptosell-golang/internal/store"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,97 +33,257 @@ type CartItemData struct {
 	ProductID int64
 	VariantID *int64 // [NEW] Track the specific variant
 	Quantity  int
-	Price     float64 // Correct price (Variant or Base)
-	Stock     int     // Correct stock (Variant or Base)
+	Price     money.Money // Correct price (Variant or Base)
+	Stock     int         // Correct stock (Variant or Base)
+
+	PreorderOpen bool // the (simple) product takes pre-orders
+	PreorderLeft int  // pre-order units still free under the product's limit
+	SupplierAway bool // the product's supplier is on vacation: it cannot be ordered
+	Preorder     bool // set by Checkout: the line waits for stock instead of taking it
+
+	Restrictions []string // the product's shipping restrictions (see package shipping)
+
+	SupplierID     int64   // tax lines and invoices are per supplier
+	CommissionRate float64 // the variant's commission, else the product's (percent)
+
+	MinQuantity int // the product's minimum order quantity
+	Increment   int // the product's pack size
+}
+
+// checkoutCartQuery fetches the active lines of a cart with the correct
+// price and stock (variant or base). It takes the current time (for the
+// vacation check) and the cart ID. FOR UPDATE does not lock the supplier row:
+// a locking read leaves subqueries alone.
+// [FIX] Phase 8.4: Fetch correct Price/Stock using JOINs on Variants
+var checkoutCartQuery = `
+	SELECT 
+		ci.product_id, 
+		ci.variant_id, 
+		ci.quantity, 
+		COALESCE(v.price_to_tts, p.price_to_tts) as final_price, 
+		COALESCE(v.stock_quantity, p.stock_quantity) as available_stock,
+		(p.is_preorder = 1 AND ci.variant_id IS NULL) as preorder_open,
+		GREATEST(COALESCE(p.preorder_limit, 0) - p.preorder_reserved, 0) as preorder_left,
+		EXISTS (SELECT 1 FROM users s WHERE s.id = p.supplier_id AND ` + store.SupplierAway("s") + `) as supplier_away,
+		p.shipping_restrictions, p.min_order_qty, p.order_increment, p.supplier_id,
+		COALESCE(v.commission_rate, p.commission_rate, 0) as commission_rate
+	FROM cart_items ci
+	JOIN products p ON ci.product_id = p.id
+	LEFT JOIN product_variants v ON ci.variant_id = v.id
+	WHERE ci.cart_id = ? AND p.status = 'active' AND ` + store.NotDeleted("p") + `
+`
+
+// queryCartItems loads a cart's active lines; lock adds FOR UPDATE (use it on a transaction).
+func queryCartItems(ctx context.Context, q Querier, cartID int64, lock bool) ([]CartItemData, error) {
+	query := checkoutCartQuery
+	if lock {
+		query += " FOR UPDATE"
+	}
+	rows, err := q.QueryContext(ctx, query, time.Now(), cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cartItems []CartItemData
+	for rows.Next() {
+		var item CartItemData
+		var restrictions string
+		// Scan the variant_id (which might be nil)
+		if err := rows.Scan(&item.ProductID, &item.VariantID, &item.Quantity, &item.Price, &item.Stock, &item.PreorderOpen, &item.PreorderLeft, &item.SupplierAway, &restrictions, &item.MinQuantity, &item.Increment, &item.SupplierID, &item.CommissionRate); err != nil {
+			return nil, err
+		}
+		item.Restrictions = shipping.Split(restrictions)
+		cartItems = append(cartItems, item)
+	}
+	return cartItems, rows.Err()
+}
+
+// cartSubtotal is the cart total before discounts.
+func cartSubtotal(items []CartItemData) money.Money {
+	var total money.Money
+	for _, item := range items {
+		total += item.Price.Mul(item.Quantity)
+	}
+	return total
+}
+
+// CheckoutInput is the optional JSON body of a checkout (or its preview).
+// The order ships to a saved customer (customerId) or to the one given in
+// customer, who is recorded or updated by phone number; the preview ignores both.
+// blindShip (default true) keeps the supplier off the packing slips; their
+// sender is then senderName, or the dropshipper's business name.
+type CheckoutInput struct {
+	CouponCode string         `json:"couponCode" binding:"max=40"`
+	CustomerID *int64         `json:"customerId"`
+	Customer   *CustomerInput `json:"customer"`
+	BlindShip  *bool          `json:"blindShip"`
+	SenderName string         `json:"senderName" binding:"max=100"`
 }
 
 // Checkout is the handler for POST /v1/dropshipper/checkout
+// The body is optional; without a coupon the best automatic campaign applies.
+// A line beyond the stock of a product taking pre-orders is placed as a
+// pre-order: the order is paid in full now and waits in 'pre-order' status,
+// without taking stock, until ConvertPreorders finds the stock for it.
 func (h *Handlers) Checkout(c *gin.Context) {
-	// 1. --- Get Dropshipper ID ---
+	ctx := c.Request.Context()
+
+	// 1. --- Get Dropshipper ID & Bind Input ---
 	userID_raw, _ := c.Get("userID")
 	dropshipperID := userID_raw.(int64)
 
+	var input CheckoutInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			apierror.Validation(c, err)
+			return
+		}
+	}
+	if input.CustomerID != nil && input.Customer != nil {
+		apierror.BadRequest(c, "Send either customerId or customer, not both")
+		return
+	}
+	var customer *models.Customer
+	if input.Customer != nil {
+		cu, err := customerFromInput(dropshipperID, *input.Customer)
+		if err != nil {
+			apierror.BadRequest(c, "customer: "+err.Error())
+			return
+		}
+		customer = &cu
+	}
+
 	// 2. --- Begin Transaction ---
-	tx, err := h.DB.BeginTx(c, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	tx, err := h.Store.Begin(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback() // Safety net
 
 	// 3. --- Get User's Cart ---
 	var cartID int64
-	err = tx.QueryRow("SELECT id FROM carts WHERE user_id = ?", dropshipperID).Scan(&cartID)
+	err = tx.QueryRowContext(ctx, "SELECT id FROM carts WHERE user_id = ?", dropshipperID).Scan(&cartID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Your cart is empty"})
+			apierror.BadRequest(c, "Your cart is empty")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find cart"})
+		apierror.Internal(c, "Failed to find cart")
 		return
 	}
 
-	// [FIX] Phase 8.4: Fetch correct Price/Stock using JOINs on Variants
-	query := `
-		SELECT 
-			ci.product_id, 
-			ci.variant_id, 
-			ci.quantity, 
-			COALESCE(v.price_to_tts, p.price_to_tts) as final_price, 
-			COALESCE(v.stock_quantity, p.stock_quantity) as available_stock
-		FROM cart_items ci
-		JOIN products p ON ci.product_id = p.id
-		LEFT JOIN product_variants v ON ci.variant_id = v.id
-		WHERE ci.cart_id = ? AND p.status = 'active'
-		FOR UPDATE
-	`
-
-	rows, err := tx.Query(query, cartID)
+	cartItems, err := queryCartItems(ctx, tx, cartID, true)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart items"})
+		apierror.Internal(c, "Failed to get cart items")
 		return
 	}
-	defer rows.Close()
 
-	var cartItems []CartItemData
-	var totalOrderCost float64 = 0.0
+	if len(cartItems) == 0 {
+		apierror.BadRequest(c, "Your cart contains no active products")
+		return
+	}
 
-	for rows.Next() {
-		var item CartItemData
-		// Scan the variant_id (which might be nil)
-		if err := rows.Scan(&item.ProductID, &item.VariantID, &item.Quantity, &item.Price, &item.Stock); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan cart item"})
+	// 4. --- Check Stock (or Pre-order Capacity) & Apply Promotions ---
+	hasPreorder := false
+	for i, item := range cartItems {
+		if item.SupplierAway {
+			apierror.Conflict(c, fmt.Sprintf("Product ID %d cannot be ordered while its supplier is on vacation", item.ProductID))
 			return
 		}
-
-		// 4. --- Check Stock & Calculate Total ---
-		if item.Stock < item.Quantity {
-			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Not enough stock for Product ID %d", item.ProductID)})
+		// The rule may have changed since the line was added.
+		rule := QuantityRule{ProductID: item.ProductID, MinQuantity: item.MinQuantity, Increment: item.Increment}
+		if !checkQuantity(c, http.StatusConflict, rule, item.Quantity) {
 			return
 		}
-		totalOrderCost += item.Price * float64(item.Quantity)
-		cartItems = append(cartItems, item)
+		if !shipping.Shippable(h.Config.Shipping.Couriers, item.Restrictions) {
+			apierror.Conflict(c, fmt.Sprintf("Product ID %d cannot be shipped: no courier accepts %s items", item.ProductID, strings.Join(item.Restrictions, "/")))
+			return
+		}
+		if item.Stock >= item.Quantity {
+			continue
+		}
+		if !item.PreorderOpen {
+			apierror.Conflict(c, fmt.Sprintf("Not enough stock for Product ID %d", item.ProductID))
+			return
+		}
+		if item.Quantity > item.PreorderLeft {
+			apierror.Conflict(c, fmt.Sprintf("Only %d more units of Product ID %d can be pre-ordered", item.PreorderLeft, item.ProductID))
+			return
+		}
+		cartItems[i].Preorder = true
+		hasPreorder = true
 	}
 
-	if len(cartItems) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Your cart contains no active products"})
+	now := time.Now()
+	applied, err := applyPromotion(ctx, tx, dropshipperID, cartItems, normalizeCoupon(input.CouponCode), now, true)
+	var ce *couponError
+	if errors.As(err, &ce) {
+		apierror.BadRequest(c, ce.Error())
 		return
 	}
+	if err != nil {
+		apierror.Internal(c, "Failed to apply promotions")
+		return
+	}
+	subtotal := cartSubtotal(cartItems)
+	var discount money.Money
+	if applied != nil {
+		discount = applied.Amount
+	}
+	taxLines, taxTotal, err := h.orderTax(ctx, tx, cartItems)
+	if err != nil {
+		apierror.Internal(c, "Failed to compute tax")
+		return
+	}
+	totalOrderCost := subtotal - discount + taxTotal
 
 	// 5. --- Check Wallet Balance ---
-	var balance sql.NullFloat64
-	err = tx.QueryRow("SELECT SUM(amount) FROM wallet_transactions WHERE user_id = ?", dropshipperID).Scan(&balance)
+	// Funds held for earlier on-hold orders are spoken for. The wallet stays
+	// locked until the commit, so a concurrent payment waits for this one.
+	walletBalance, err := tx.Wallet.LockBalance(ctx, dropshipperID)
+	if err != nil {
+		apierror.Internal(c, "Failed to get wallet balance")
+		return
+	}
+	held, err := tx.Wallet.Held(ctx, dropshipperID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wallet balance"})
+		apierror.Internal(c, "Failed to get wallet balance")
 		return
 	}
-	walletBalance := balance.Float64 // Will be 0.0 if NULL
+	available := walletBalance - held
+
+	// 5b. --- Record or Load the End-Customer ---
+	switch {
+	case customer != nil:
+		if err := recordCustomer(ctx, tx, customer); err != nil {
+			apierror.Internal(c, "Failed to save customer")
+			return
+		}
+	case input.CustomerID != nil:
+		customer, err = getCustomer(ctx, tx, *input.CustomerID, dropshipperID)
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.BadRequest(c, "customerId does not match one of your customers")
+			return
+		}
+		if err != nil {
+			apierror.Internal(c, "Failed to fetch customer")
+			return
+		}
+	}
 
 	// 6. --- Create Order & Process Payment ---
-	now := time.Now()
 	var orderStatus string
 
-	if walletBalance < totalOrderCost {
+	if hasPreorder {
+		// Pre-orders hold the payment until the stock arrives; there is no unpaid pre-order.
+		if available < totalOrderCost {
+			apierror.PaymentRequired(c, "Pre-orders must be paid in full at checkout: insufficient wallet balance")
+			return
+		}
+		orderStatus = "pre-order"
+	} else if available < totalOrderCost {
 		// [Logic Check] If you want to BLOCK checkout on low balance, return Error here.
 		// Currently, we allow "on-hold" orders.
 		orderStatus = "on-hold"
@@ -119,79 +291,180 @@ func (h *Handlers) Checkout(c *gin.Context) {
 		orderStatus = "processing"
 	}
 
-	// Insert the main order record
-	orderQuery := `
-		INSERT INTO orders (user_id, status, total, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)`
-	result, err := tx.Exec(orderQuery, dropshipperID, orderStatus, totalOrderCost, now, now)
+	// 6b. --- Risk Checks ---
+	// A flagged order waits for a manager instead (see ReviewRisk).
+	var shipTo *models.ShipTo
+	if customer != nil {
+		shipTo = customer.ShipTo()
+	}
+	assessment, err := h.assessRisk(ctx, tx, dropshipperID, shipTo)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
+		apierror.Internal(c, "Failed to check order")
 		return
 	}
-	orderID, err := result.LastInsertId()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get new order ID"})
+	if assessment.Review {
+		orderStatus = orderUnderReview
+	}
+
+	// Insert the main order record
+	order := &models.Order{
+		UserID:        dropshipperID,
+		Status:        orderStatus,
+		Total:         totalOrderCost,
+		DiscountTotal: discount,
+		TaxTotal:      taxTotal,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		BlindShip:     input.BlindShip == nil || *input.BlindShip,
+	}
+	if sender := strings.TrimSpace(sanitize.Text(input.SenderName)); sender != "" {
+		order.SenderName = &sender
+	}
+	if customer != nil {
+		order.CustomerID, order.ShipTo = &customer.ID, customer.ShipTo()
+	}
+	if err := tx.Orders.Create(ctx, order); err != nil {
+		apierror.Internal(c, "Failed to create order")
 		return
 	}
+	orderID := order.ID
+
+	if applied != nil {
+		err := tx.Orders.AddDiscount(ctx, &models.OrderDiscount{
+			OrderID:     orderID,
+			PromotionID: applied.Promotion.ID,
+			UserID:      dropshipperID,
+			Code:        applied.Promotion.Code,
+			Description: applied.Description(),
+			Amount:      applied.Amount,
+			CreatedAt:   now,
+		})
+		if err != nil {
+			apierror.Internal(c, "Failed to save order discount")
+			return
+		}
+	}
 
-	// 7. --- Create Order Items & Update Stock ---
-	// [FIX] Insert variant_id into order_items
-	itemQuery := `
-        INSERT INTO order_items (order_id, product_id, variant_id, quantity, unit_price, created_at)
-        VALUES (?, ?, ?, ?, ?, ?)`
+	if err := tx.Orders.AddTaxLines(ctx, orderID, taxLines); err != nil {
+		apierror.Internal(c, "Failed to save tax lines")
+		return
+	}
 
+	// 7. --- Create Order Items & Update Stock ---
+	orderItems := make([]models.OrderItem, 0, len(cartItems))
 	for _, item := range cartItems {
-		// a. Save Item
-		_, err := tx.Exec(itemQuery, orderID, item.ProductID, item.VariantID, item.Quantity, item.Price, now)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save order item"})
+		orderItems = append(orderItems, models.OrderItem{
+			ProductID:      item.ProductID,
+			VariantID:      item.VariantID,
+			Quantity:       item.Quantity,
+			UnitPrice:      item.Price,
+			Preorder:       item.Preorder,
+			CreatedAt:      now,
+			SupplierID:     item.SupplierID,
+			CommissionRate: item.CommissionRate,
+			Commission:     item.Price.Mul(item.Quantity).Percent(item.CommissionRate),
+		})
+	}
+	if err := tx.Orders.AddItems(ctx, orderID, orderItems); err != nil {
+		apierror.Internal(c, "Failed to save order item")
+		return
+	}
+	if orderStatus == "processing" {
+		if err := h.stampShipsBy(ctx, tx, orderID, now); err != nil {
+			apierror.Internal(c, "Failed to set ships-by dates")
 			return
 		}
-
-		// b. DEDUCT STOCK IMMEDIATELY (Safety Mechanism)
-		// Whether "processing" or "on-hold", we reserve the stock.
-		if item.VariantID != nil && *item.VariantID > 0 {
-			// Deduct from VARIANT table
-			_, err = tx.Exec("UPDATE product_variants SET stock_quantity = stock_quantity - ? WHERE id = ?", item.Quantity, *item.VariantID)
-		} else {
-			// Deduct from PRODUCT table
-			_, err = tx.Exec("UPDATE products SET stock_quantity = stock_quantity - ? WHERE id = ?", item.Quantity, item.ProductID)
+	}
+	if orderStatus != "on-hold" && orderStatus != orderUnderReview {
+		if err := issueInvoices(ctx, tx, orderID, now); err != nil {
+			apierror.Internal(c, "Failed to issue invoices")
+			return
 		}
+	}
 
+	// DEDUCT STOCK IMMEDIATELY (Safety Mechanism)
+	// Whether "processing" or "on-hold", we reserve the stock.
+	// Pre-order lines only hold a place under the product's pre-order limit.
+	threshold := h.lowStockThreshold(ctx)
+	var lowStock []*events.StockLow
+	for _, item := range cartItems {
+		if item.Preorder {
+			if err := tx.Products.ReservePreorder(ctx, item.ProductID, item.Quantity); err != nil {
+				apierror.Internal(c, "Failed to reserve pre-order")
+				return
+			}
+			continue
+		}
+		low, err := takeStock(ctx, tx, item.ProductID, item.VariantID, item.Quantity, threshold)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reserve stock"})
+			apierror.Internal(c, "Failed to reserve stock")
 			return
 		}
+		if low != nil {
+			lowStock = append(lowStock, low)
+		}
 	}
 
-	// c. Only Deduct Wallet if Paying Now
-	if orderStatus == "processing" {
-		err = h.AddWalletTransaction(tx, dropshipperID, "order_payment", -totalOrderCost, fmt.Sprintf("Payment for Order ID %d", orderID))
+	// Only Deduct Wallet if Paying Now
+	if orderStatus != "on-hold" && orderStatus != orderUnderReview {
+		err = tx.Wallet.AddTransaction(ctx, dropshipperID, "order_payment", -totalOrderCost, fmt.Sprintf("Payment for Order ID %d", orderID))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deduct from wallet"})
+			apierror.Internal(c, "Failed to deduct from wallet")
+			return
+		}
+	} else if available > 0 {
+		// Hold what the balance covers, so it is still there at PayOrder
+		// (or when the review approves the order).
+		if err := tx.Wallet.PlaceHold(ctx, dropshipperID, orderID, min(available, totalOrderCost)); err != nil {
+			apierror.Internal(c, "Failed to place wallet hold")
+			return
+		}
+	}
+	if orderStatus == orderUnderReview {
+		if _, err := flagForReview(ctx, tx, dropshipperID, "order", orderID, totalOrderCost, assessment); err != nil {
+			apierror.Internal(c, "Failed to queue order for review")
 			return
 		}
 	}
 
 	// 8. --- Clear the Cart ---
-	_, err = tx.Exec("DELETE FROM cart_items WHERE cart_id = ?", cartID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM cart_items WHERE cart_id = ?", cartID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
+		apierror.Internal(c, "Failed to clear cart")
 		return
 	}
 
 	// 9. --- Commit Transaction ---
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit final transaction"})
+		apierror.Internal(c, "Failed to commit final transaction")
 		return
 	}
 
+	// Stock changed, so cached product details are stale.
+	productIDs := make([]int64, 0, len(cartItems))
+	for _, item := range cartItems {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	h.invalidateProducts(ctx, productIDs...)
+	if orderStatus == "processing" {
+		h.Events.Publish(ctx, events.OrderPaid{OrderID: orderID, OrderPublicID: order.PublicID, UserID: dropshipperID, Total: totalOrderCost})
+	}
+	for _, low := range lowStock {
+		h.Events.Publish(ctx, *low)
+	}
+
 	// 10. --- Send Success Response ---
 	c.JSON(http.StatusCreated, gin.H{
-		"message":   fmt.Sprintf("Order created successfully with status: %s", orderStatus),
-		"orderId":   orderID,
-		"status":    orderStatus,
-		"totalPaid": totalOrderCost,
+		"message":    fmt.Sprintf("Order created successfully with status: %s", orderStatus),
+		"orderId":    orderID,
+		"publicId":   order.PublicID,
+		"status":     orderStatus,
+		"subtotal":   subtotal,
+		"discount":   discount,
+		"tax":        taxTotal,
+		"promotion":  appliedJSON(applied),
+		"customerId": order.CustomerID,
+		"totalPaid":  totalOrderCost,
 	})
 }
 
@@ -199,143 +472,96 @@ func (h *Handlers) Checkout(c *gin.Context) {
 // --- NEW: Order Retrieval Handlers ---
 //
 
-// OrderItemDetail extends the base OrderItem to include Product info
-type OrderItemDetail struct {
-	models.OrderItem
-	ProductName string              `json:"productName"`
-	ProductSKU  string              `json:"productSku"`
-	Options     []map[string]string `json:"options"` // [NEW] To display "Color: Red"
+// orderCursor is the pagination key for order listings.
+func orderCursor(o models.Order) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: o.CreatedAt, ID: o.ID}
 }
 
 // GetMyOrders is the handler for GET /v1/dropshipper/orders
 func (h *Handlers) GetMyOrders(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get Dropshipper ID ---
 	userID_raw, _ := c.Get("userID")
 	dropshipperID := userID_raw.(int64)
 
-	// 2. --- Query Orders ---
-	query := `
-		SELECT id, user_id, status, total, created_at, updated_at, tracking 
-		FROM orders 
-		WHERE user_id = ? 
-		ORDER BY created_at DESC
-	`
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	page := list.Page
 
-	rows, err := h.DB.Query(query, dropshipperID)
+	// 2. --- Query Orders (Keyset Pagination) ---
+	orders, err := h.Store.Orders.ListByUser(ctx, dropshipperID, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
+		apierror.Internal(c, "Failed to fetch orders")
 		return
 	}
-	defer rows.Close()
 
-	// 3. --- Scan Rows ---
-	var orders []models.Order
-	for rows.Next() {
-		var o models.Order
-		var tracking sql.NullString
+	// 3. --- Return Response ---
+	orders, nextCursor := pagination.Paginate(page, orders, orderCursor)
 
-		if err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.Total, &o.CreatedAt, &o.UpdatedAt, &tracking); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan order data"})
-			return
-		}
-		o.Tracking = tracking
-		orders = append(orders, o)
-	}
-
-	// 4. --- Return Response ---
-	if orders == nil {
-		orders = []models.Order{}
+	result, err := projectFields(orders, parseFields(c))
+	if err != nil {
+		apierror.Internal(c, "Failed to build response")
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"orders": orders,
+		"orders":     result,
+		"nextCursor": nextCursor,
 	})
 }
 
 // [FIXED] OrderItemDetail now includes Options
 // GetOrderDetails is the handler for GET /v1/dropshipper/orders/:id
 func (h *Handlers) GetOrderDetails(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get IDs ---
 	userID_raw, _ := c.Get("userID")
 	dropshipperID := userID_raw.(int64)
-	orderID := c.Param("id")
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
 
 	// 2. --- Fetch Order & Verify Ownership ---
-	var o models.Order
-	var tracking sql.NullString
-
-	queryOrder := `
-		SELECT id, user_id, status, total, created_at, updated_at, tracking 
-		FROM orders 
-		WHERE id = ? AND user_id = ?
-	`
-	err := h.DB.QueryRow(queryOrder, orderID, dropshipperID).Scan(
-		&o.ID, &o.UserID, &o.Status, &o.Total, &o.CreatedAt, &o.UpdatedAt, &tracking,
-	)
-
+	o, err := h.Store.Orders.GetForUser(ctx, orderID, dropshipperID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.NotFound(c, "Order not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch order"})
+		apierror.Internal(c, "Failed to fetch order")
 		return
 	}
-	o.Tracking = tracking
 
 	// 3. --- Fetch Order Items with Variant Details ---
-	// [FIX] Phase 8.6: Join product_variants to get specific SKU and Options
-	queryItems := `
-		SELECT 
-			oi.id, oi.order_id, oi.product_id, oi.quantity, oi.unit_price, oi.created_at,
-			p.name, 
-			COALESCE(v.sku, p.sku) as display_sku,
-			v.options
-		FROM order_items oi
-		JOIN products p ON oi.product_id = p.id
-		LEFT JOIN product_variants v ON oi.variant_id = v.id
-		WHERE oi.order_id = ?
-	`
-
-	rows, err := h.DB.Query(queryItems, o.ID)
+	items, err := h.Store.Orders.Items(ctx, o.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch order items"})
+		apierror.Internal(c, "Failed to fetch order items")
 		return
 	}
-	defer rows.Close()
-
-	var items []OrderItemDetail
-	for rows.Next() {
-		var item OrderItemDetail
-		var optionsJSON []byte // Buffer for JSON
-
-		// Scan row
-		if err := rows.Scan(
-			&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.UnitPrice, &item.CreatedAt,
-			&item.ProductName, &item.ProductSKU, &optionsJSON,
-		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan order item"})
-			return
-		}
-
-		// [FIX] Parse Options JSON
-		if len(optionsJSON) > 0 {
-			_ = json.Unmarshal(optionsJSON, &item.Options)
-		} else {
-			item.Options = []map[string]string{}
-		}
 
-		items = append(items, item)
+	discounts, err := h.Store.Orders.Discounts(ctx, o.ID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order discounts")
+		return
 	}
-
-	// 4. --- Return Combined Response ---
-	if items == nil {
-		items = []OrderItemDetail{}
+	taxLines, err := h.Store.Orders.TaxLines(ctx, o.ID, 0)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order tax lines")
+		return
 	}
 
+	// 4. --- Return Combined Response ---
 	c.JSON(http.StatusOK, gin.H{
-		"order": o,
-		"items": items,
+		"order":     o,
+		"items":     items,
+		"discounts": discounts,
+		"taxLines":  taxLines,
 	})
 }
 
@@ -344,45 +570,62 @@ func (h *Handlers) GetOrderDetails(c *gin.Context) {
 // PayOrder handles the payment for an existing "on-hold" order.
 // Route: POST /v1/dropshipper/orders/:id/pay
 func (h *Handlers) PayOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. Get IDs
 	userID_raw, _ := c.Get("userID")
 	dropshipperID := userID_raw.(int64)
-	orderID := c.Param("id")
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
 
 	// 2. Begin Transaction
-	tx, err := h.DB.Begin()
+	tx, err := h.Store.Begin(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
 
-	// 3. Fetch Order Details
-	var totalAmount float64
-	var status string
-	// Lock the row
-	queryOrder := "SELECT total, status FROM orders WHERE id = ? AND user_id = ? FOR UPDATE"
-	err = tx.QueryRow(queryOrder, orderID, dropshipperID).Scan(&totalAmount, &status)
-
+	// 3. Fetch Order Details (row locked)
+	order, err := tx.Orders.GetForUpdate(ctx, orderID, dropshipperID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Order not found"})
+		apierror.Internal(c, "Order not found")
 		return
 	}
 
-	if status != "on-hold" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Order is not on-hold"})
+	if order.Status != "on-hold" {
+		apierror.BadRequest(c, "Order is not on-hold")
 		return
 	}
 
 	// 4. Check Wallet Balance
-	balance, err := h.GetWalletBalance(tx, dropshipperID)
+	// The order's own hold counts towards it; other orders' holds do not.
+	// The wallet stays locked until the commit.
+	balance, err := tx.Wallet.LockBalance(ctx, dropshipperID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check wallet"})
+		apierror.Internal(c, "Failed to check wallet")
 		return
 	}
+	held, err := tx.Wallet.Held(ctx, dropshipperID)
+	if err != nil {
+		apierror.Internal(c, "Failed to check wallet")
+		return
+	}
+	available := balance - held
+	hold, err := tx.Wallet.OrderHold(ctx, orderID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		apierror.Internal(c, "Failed to check wallet")
+		return
+	}
+	if hold != nil {
+		available += hold.Amount
+	}
 
-	if balance < totalAmount {
-		c.JSON(http.StatusPaymentRequired, gin.H{"error": "Insufficient wallet balance"})
+	if available < order.Total {
+		apierror.PaymentRequired(c, "Insufficient wallet balance")
 		return
 	}
 
@@ -392,25 +635,41 @@ func (h *Handlers) PayOrder(c *gin.Context) {
 	// If it's still "on-hold", the stock is safe.
 
 	// 6. Execute Payment
-	err = h.AddWalletTransaction(tx, dropshipperID, "order_payment", -totalAmount, fmt.Sprintf("Payment for Order #%s", orderID))
+	err = tx.Wallet.AddTransaction(ctx, dropshipperID, "order_payment", -order.Total, fmt.Sprintf("Payment for Order #%d", orderID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process payment"})
+		apierror.Internal(c, "Failed to process payment")
+		return
+	}
+	if err := tx.Wallet.CloseHold(ctx, orderID, store.HoldCaptured); err != nil {
+		apierror.Internal(c, "Failed to process payment")
 		return
 	}
 
 	// 7. Update Status
-	_, err = tx.Exec("UPDATE orders SET status = 'processing', updated_at = ? WHERE id = ?", time.Now(), orderID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+	if err := tx.Orders.UpdateStatus(ctx, orderID, "processing"); err != nil {
+		apierror.Internal(c, "Failed to update order status")
+		return
+	}
+	if err := h.stampShipsBy(ctx, tx, orderID, time.Now()); err != nil {
+		apierror.Internal(c, "Failed to set ships-by dates")
+		return
+	}
+	if err := issueInvoices(ctx, tx, orderID, time.Now()); err != nil {
+		apierror.Internal(c, "Failed to issue invoices")
 		return
 	}
 
 	// 8. Commit
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		apierror.Internal(c, "Failed to commit transaction")
 		return
 	}
+	h.Events.Publish(ctx, events.OrderPaid{OrderID: orderID, OrderPublicID: order.PublicID, UserID: dropshipperID, Total: order.Total})
 
+	if apiversion.From(c) >= apiversion.V2 {
+		c.JSON(http.StatusOK, dto.OrderPayment{Message: "Payment successful", Status: "processing"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Payment successful",
 		"new_status": "processing",
@@ -418,312 +677,466 @@ func (h *Handlers) PayOrder(c *gin.Context) {
 }
 
 // GetSupplierSales handles GET /v1/supplier/orders
-// Returns orders that contain the supplier's products.
+// Returns orders that contain the supplier's products, in the supplier's view
+// (models.SupplierOrder).
 func (h *Handlers) GetSupplierSales(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	supplierID := userID_raw.(int64)
 
-	// This query finds unique orders that contain items belonging to this supplier
-	query := `
-		SELECT DISTINCT o.id, o.status, o.total, o.created_at, o.tracking
-		FROM orders o
-		JOIN order_items oi ON o.id = oi.order_id
-		JOIN products p ON oi.product_id = p.id
-		WHERE p.supplier_id = ?
-		ORDER BY o.created_at DESC
-	`
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	page := list.Page
 
-	rows, err := h.DB.Query(query, supplierID)
+	// Unique orders that contain items belonging to this supplier
+	orders, err := h.Store.Orders.ListBySupplier(ctx, supplierID, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sales history"})
+		apierror.Internal(c, "Failed to fetch sales history")
 		return
 	}
-	defer rows.Close()
-
-	var orders []models.Order
-	for rows.Next() {
-		var o models.Order
-		var tracking sql.NullString
-		if err := rows.Scan(&o.ID, &o.Status, &o.Total, &o.CreatedAt, &tracking); err != nil {
-			continue
-		}
-		o.Tracking = tracking
-		orders = append(orders, o)
+	orders, nextCursor := pagination.Paginate(page, orders, orderCursor)
+	views := make([]models.SupplierOrder, 0, len(orders))
+	for _, o := range orders {
+		views = append(views, o.ForSupplier())
 	}
 
-	if orders == nil {
-		orders = []models.Order{}
+	result, err := projectFields(views, parseFields(c))
+	if err != nil {
+		apierror.Internal(c, "Failed to build response")
+		return
 	}
-	c.JSON(http.StatusOK, gin.H{"orders": orders})
+	c.JSON(http.StatusOK, gin.H{"orders": result, "nextCursor": nextCursor})
 }
 
 // UpdateOrderTracking handles PATCH /v1/supplier/orders/:id/ship
 func (h *Handlers) UpdateOrderTracking(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	supplierID := userID_raw.(int64)
-	orderID := c.Param("id")
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
 
 	var input struct {
 		Tracking string `json:"tracking" binding:"required"`
+		Courier  string `json:"courier" binding:"max=32"` // optional, a SHIPPING_COURIERS code
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tracking number is required"})
+		apierror.BadRequest(c, "Tracking number is required")
 		return
 	}
 
+	err = h.shipOrder(ctx, h.DB, h.Store.Orders, orderID, supplierID, input.Tracking, input.Courier)
+	var se *shipmentError
+	if errors.As(err, &se) {
+		se.respond(c, se.message)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to update shipment status")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order marked as shipped", "status": "shipped"})
+}
+
+// shipmentError is a shipment the supplier cannot record, and how to answer it.
+type shipmentError struct {
+	respond func(c *gin.Context, message string)
+	message string
+}
+
+func (e *shipmentError) Error() string { return e.message }
+
+// shipOrder marks an order shipped by one of its suppliers, with the tracking
+// number and courier code (optional). Only a 'processing' order ships: orders
+// without the supplier's items, orders in any other status (held for review,
+// unpaid, waiting for pre-order stock, cancelled, already shipped) and
+// couriers unable to carry the parcel are refused with a *shipmentError.
+func (h *Handlers) shipOrder(ctx context.Context, q Querier, orders store.OrderStore, orderID, supplierID int64, tracking, courierCode string) error {
 	// Verify ownership: Does this order contain items from this supplier?
-	var exists int
-	checkQuery := `
-        SELECT 1 FROM order_items oi 
-        JOIN products p ON oi.product_id = p.id 
-        WHERE oi.order_id = ? AND p.supplier_id = ? LIMIT 1`
+	owns, err := orders.SupplierHasItems(ctx, orderID, supplierID)
+	if err != nil {
+		return err
+	}
+	if !owns {
+		return &shipmentError{apierror.Forbidden, "You cannot fulfill an order that doesn't belong to you"}
+	}
+
+	// Only a paid order cleared for fulfilment ships
+	var status string
+	if err := q.QueryRowContext(ctx, "SELECT status FROM orders WHERE id = ?", orderID).Scan(&status); err != nil {
+		return err
+	}
+	switch status {
+	case "processing":
+	case "pre-order":
+		return &shipmentError{apierror.Conflict, "This pre-order is still waiting for stock and cannot be shipped yet"}
+	case "under-review":
+		return &shipmentError{apierror.Conflict, "This order is held for review and cannot be shipped yet"}
+	default:
+		return errNotShippable
+	}
 
-	err := h.DB.QueryRow(checkQuery, orderID, supplierID).Scan(&exists)
+	// The courier must carry every restricted item of this supplier's parcel
+	var courier *string
+	if courierCode != "" {
+		if len(h.Config.Shipping.Couriers) > 0 {
+			known, ok := shipping.Find(h.Config.Shipping.Couriers, courierCode)
+			if !ok {
+				return &shipmentError{apierror.BadRequest, fmt.Sprintf("Unknown courier %q", courierCode)}
+			}
+			restrictions, err := parcelRestrictions(ctx, q, orderID, supplierID)
+			if err != nil {
+				return err
+			}
+			if !known.Accepts(restrictions) {
+				return &shipmentError{apierror.Conflict, fmt.Sprintf("Courier %q does not accept the %s items in this order", courierCode, strings.Join(restrictions, "/"))}
+			}
+		}
+		courier = &courierCode
+	}
+
+	// Update Order status and tracking, unless a review decision or a
+	// cancellation moved the order meanwhile
+	shipped, err := orders.MarkShipped(ctx, orderID, tracking, courier)
 	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot fulfill an order that doesn't belong to you"})
-		return
+		return err
+	}
+	if !shipped {
+		return errNotShippable
 	}
+	return nil
+}
+
+// errNotShippable is an order that is not (or no longer) processing.
+var errNotShippable = &shipmentError{apierror.Conflict, "Only orders being processed can be shipped"}
 
-	// Update Order status and tracking
-	updateQuery := "UPDATE orders SET status = 'shipped', tracking = ?, updated_at = ? WHERE id = ?"
-	_, err = h.DB.Exec(updateQuery, input.Tracking, time.Now(), orderID)
+// parcelRestrictions returns the shipping restrictions of a supplier's items in an order.
+func parcelRestrictions(ctx context.Context, q Querier, orderID, supplierID int64) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT DISTINCT p.shipping_restrictions FROM order_items oi
+		JOIN products p ON p.id = oi.product_id
+		WHERE oi.order_id = ? AND oi.supplier_id = ? AND p.shipping_restrictions <> ''`, orderID, supplierID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shipment status"})
-		return
+		return nil, err
 	}
+	defer rows.Close()
 
-	c.JSON(http.StatusOK, gin.H{"message": "Order marked as shipped", "status": "shipped"})
+	var all []string
+	for rows.Next() {
+		var set string
+		if err := rows.Scan(&set); err != nil {
+			return nil, err
+		}
+		all = append(all, shipping.Split(set)...)
+	}
+	return shipping.Split(shipping.Join(all)), rows.Err()
 }
 
 // CompleteOrder handles the final step where a dropshipper confirms receipt.
-// This triggers the release of funds to the supplier's available balance.
+// This triggers the release of funds to each supplier's available balance.
 // Route: POST /v1/dropshipper/orders/:id/complete
 func (h *Handlers) CompleteOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	dropshipperID := userID_raw.(int64)
-	orderID := c.Param("id")
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order verification failed")
+		return
+	}
 
-	tx, err := h.DB.Begin()
+	tx, err := h.Store.Begin(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
 
-	var status string
-	var totalAmount float64
-	var supplierID int64
-
-	// Refined query to ensure we get the supplier correctly
-	checkQuery := `
-		SELECT o.status, o.total, p.supplier_id
-		FROM orders o
-		JOIN order_items oi ON o.id = oi.order_id
-		JOIN products p ON oi.product_id = p.id
-		WHERE o.id = ? AND o.user_id = ?
-		LIMIT 1
-	`
-	err = tx.QueryRow(checkQuery, orderID, dropshipperID).Scan(&status, &totalAmount, &supplierID)
+	// Lock the order
+	order, err := tx.Orders.GetForUpdate(ctx, orderID, dropshipperID)
 	if err != nil {
-		fmt.Printf("Error finding supplier for Order %s: %v\n", orderID, err) // DEBUG LOG
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order verification failed"})
+		apierror.NotFound(c, "Order verification failed")
 		return
 	}
 
-	if status != "shipped" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only shipped orders can be completed"})
+	if order.Status != "shipped" {
+		apierror.BadRequest(c, "Only shipped orders can be completed")
 		return
 	}
 
-	// 1. Update Order Status
-	_, err = tx.Exec("UPDATE orders SET status = 'completed', updated_at = ? WHERE id = ?", time.Now(), orderID)
+	// An unresolved dispute holds the payout; the manager's decision settles it
+	var disputed bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM disputes WHERE order_id = ? AND status IN ('open', 'under_review'))", orderID).Scan(&disputed)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+		apierror.Internal(c, "Failed to check disputes")
+		return
+	}
+	if disputed {
+		apierror.Conflict(c, "This order has an open dispute and cannot be completed until it is resolved")
 		return
 	}
 
-	// 2. RELEASE FUNDS: Add transaction to Supplier Wallet
-	notes := fmt.Sprintf("Payout for completed Order #%s", orderID)
-	fmt.Printf("Processing Payout: Supplier %d, Amount %.2f\n", supplierID, totalAmount) // DEBUG LOG
+	// 1. Update Order Status
+	if err := tx.Orders.UpdateStatus(ctx, orderID, "completed"); err != nil {
+		apierror.Internal(c, "Failed to update order status")
+		return
+	}
 
-	err = h.AddWalletTransaction(tx, supplierID, "payout", totalAmount, notes)
+	// 2. RELEASE FUNDS: Add a transaction to each Supplier's Wallet
+	// Every supplier gets their own lines less the commission plus their tax,
+	// as pendingBalance shows it; the platform keeps the commission.
+	payouts, err := tx.Orders.SupplierPayouts(ctx, orderID)
 	if err != nil {
-		fmt.Printf("Payout Transaction Failed: %v\n", err) // DEBUG LOG
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fund release failed"})
+		apierror.Internal(c, "Fund release failed")
 		return
 	}
+	if len(payouts) == 0 {
+		apierror.NotFound(c, "Order verification failed")
+		return
+	}
+	notes := fmt.Sprintf("Payout for completed Order #%d", orderID)
+	for _, p := range payouts {
+		if p.Amount != 0 {
+			if err := tx.Wallet.AddTransaction(ctx, p.SupplierID, "payout", p.Amount, notes); err != nil {
+				fmt.Printf("Payout Transaction Failed: %v\n", err) // DEBUG LOG
+				apierror.Internal(c, "Fund release failed")
+				return
+			}
+		}
+		if p.Commission != 0 {
+			if err := tx.Wallet.CreditCommission(ctx, orderID, p.SupplierID, p.Commission); err != nil {
+				apierror.Internal(c, "Fund release failed")
+				return
+			}
+		}
+	}
 
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Commit failed"})
+		apierror.Internal(c, "Commit failed")
 		return
 	}
+	h.Events.Publish(ctx, events.OrderCompleted{OrderID: orderID, UserID: dropshipperID, Total: order.Total})
 
 	c.JSON(http.StatusOK, gin.H{"message": "Funds released", "status": "completed"})
 }
 
-// SupplierOrderItem represents a single line item for the supplier to pack
-type SupplierOrderItem struct {
-	ProductName string              `json:"productName"`
-	SKU         string              `json:"sku"`
-	Quantity    int                 `json:"quantity"`
-	UnitPrice   float64             `json:"unitPrice"`
-	Options     []map[string]string `json:"options"` // To show "Color: Red"
+// orderValue is the undiscounted value of an order, tax included: what a
+// dispute splits between a refund, the suppliers and the platform. Promotions
+// are platform-funded, so the discount is part of it. Completing the order
+// instead pays each supplier their share (OrderStore.SupplierPayouts).
+func orderValue(o *models.Order) money.Money {
+	return o.Total + o.DiscountTotal
 }
 
 // GetSupplierOrderDetails handles GET /v1/supplier/orders/:id
 func (h *Handlers) GetSupplierOrderDetails(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	supplierID := userID_raw.(int64)
-	orderID := c.Param("id")
-
-	// 1. Fetch Items specific to this Supplier
-	// We join product_variants to get the specific SKU and Options
-	query := `
-		SELECT 
-			p.name, 
-			COALESCE(v.sku, p.sku) as sku,
-			oi.quantity, 
-			oi.unit_price,
-			v.options
-		FROM order_items oi
-		JOIN products p ON oi.product_id = p.id
-		LEFT JOIN product_variants v ON oi.variant_id = v.id
-		WHERE oi.order_id = ? AND p.supplier_id = ?
-	`
-
-	rows, err := h.DB.Query(query, orderID, supplierID)
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch order items"})
+		apierror.NotFound(c, "Order not found")
 		return
 	}
-	defer rows.Close()
 
-	var items []SupplierOrderItem
-	for rows.Next() {
-		var item SupplierOrderItem
-		var optionsJSON []byte
+	// 1. Fetch Items specific to this Supplier (with variant SKU and Options)
+	items, err := h.Store.Orders.SupplierItems(ctx, orderID, supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order items")
+		return
+	}
 
-		err := rows.Scan(&item.ProductName, &item.SKU, &item.Quantity, &item.UnitPrice, &optionsJSON)
-		if err != nil {
-			continue
+	// 2. The order, in the supplier's view (only once it is known to include their items)
+	var order *models.SupplierOrder
+	var shipTo *models.ShipTo
+	if len(items) > 0 {
+		o, err := h.Store.Orders.Get(ctx, orderID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			apierror.Internal(c, "Failed to fetch order")
+			return
 		}
-
-		// Parse options (e.g. [{"name":"Color","value":"Red"}])
-		if len(optionsJSON) > 0 {
-			_ = json.Unmarshal(optionsJSON, &item.Options)
-		} else {
-			item.Options = []map[string]string{}
+		if o != nil {
+			view := o.ForSupplier()
+			order, shipTo = &view, o.ShipTo
 		}
+	}
 
-		items = append(items, item)
+	// 3. Couriers able to carry the parcel (null without SHIPPING_COURIERS)
+	restrictions, err := parcelRestrictions(ctx, h.DB, orderID, supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order items")
+		return
+	}
+
+	// 4. SST charged on the supplier's lines (see GetSupplierInvoice)
+	taxLines, err := h.Store.Orders.TaxLines(ctx, orderID, supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order tax lines")
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"items": items,
+		"order":                order,
+		"items":                items,
+		"shipTo":               shipTo,
+		"shippingRestrictions": restrictions,
+		"couriers":             shipping.Compatible(h.Config.Shipping.Couriers, restrictions),
+		"taxLines":             taxLines,
 	})
 }
 
-// ProcessOverdueOrders checks for unpaid orders older than 24 hours.
-// It cancels them, RESTORES the stock, and adds a penalty strike.
-func (h *Handlers) ProcessOverdueOrders() {
-	// 1. Define cutoff (24 hours ago)
-	cutoffTime := time.Now().Add(-24 * time.Hour)
-	log.Printf("[Cron] Checking for on-hold orders older than %v", cutoffTime)
+// unpaidReminder is how long before its cancellation the dropshipper of an
+// unpaid order is reminded to pay it.
+const unpaidReminder = 24 * time.Hour
 
-	// 2. Find target orders
-	query := `SELECT id, user_id FROM orders WHERE status = 'on-hold' AND created_at < ?`
-	rows, err := h.DB.Query(query, cutoffTime)
+// ProcessOverdueOrders checks for on-hold orders unpaid for UNPAID_ORDER_DAYS.
+// It cancels them, RESTORES the stock and wallet hold, and adds a penalty
+// strike; 24 hours before, it reminds the dropshipper to pay.
+// The context lets the caller (the background worker) stop the run on shutdown.
+func (h *Handlers) ProcessOverdueOrders(ctx context.Context) {
+	// 1. Define cutoff (UNPAID_ORDER_DAYS ago)
+	now := time.Now()
+	window := time.Duration(h.Config.UnpaidOrders.Days) * 24 * time.Hour
+	cutoffTime := now.Add(-window)
+	logging.Debugf("[Cron] Checking for on-hold orders older than %v", cutoffTime)
+
+	// 2. Remind the dropshippers of orders due within a day (once each)
+	due, err := h.Store.Orders.ListUnreminded(ctx, now.Add(unpaidReminder-window))
 	if err != nil {
-		log.Printf("[Cron] Error fetching overdue orders: %v", err)
+		logging.Errorf("[Cron] Error fetching unpaid orders to remind: %v", err)
 		return
 	}
-	defer rows.Close()
+	for i, o := range due {
+		if ctx.Err() != nil {
+			logging.Infof("[Cron] Shutting down, %d unpaid order reminders left for the next run", len(due)-i)
+			return
+		}
+		if o.CreatedAt.Before(cutoffTime) {
+			continue // cancelled below; too late to remind
+		}
+		if err := h.remindUnpaid(context.WithoutCancel(ctx), o, o.CreatedAt.Add(window)); err != nil {
+			logging.Errorf("[Cron] Failed to remind User %d of unpaid Order %d: %v", o.UserID, o.ID, err)
+		}
+	}
 
-	var ordersToCancel []struct {
-		ID     int64
-		UserID int64
+	// 3. Find target orders
+	orders, err := h.Store.Orders.ListOverdue(ctx, cutoffTime)
+	if err != nil {
+		logging.Errorf("[Cron] Error fetching overdue orders: %v", err)
+		return
 	}
 
-	for rows.Next() {
-		var o struct {
-			ID     int64
-			UserID int64
-		}
-		if err := rows.Scan(&o.ID, &o.UserID); err != nil {
-			log.Printf("[Cron] Error scanning row: %v", err)
-			continue
+	// 4. Process each order
+	// On shutdown (ctx cancelled) we stop between orders; each order's
+	// transaction runs to completion so none is left half-cancelled.
+	for i, o := range orders {
+		if ctx.Err() != nil {
+			logging.Infof("[Cron] Shutting down, %d overdue orders left for the next run", len(orders)-i)
+			return
 		}
-		ordersToCancel = append(ordersToCancel, o)
+		h.cancelAndPenalize(context.WithoutCancel(ctx), o)
 	}
+}
 
-	// 3. Process each order
-	for _, o := range ordersToCancel {
-		h.cancelAndPenalize(o.ID, o.UserID)
+// remindUnpaid marks one unpaid order reminded and notifies its dropshipper.
+func (h *Handlers) remindUnpaid(ctx context.Context, o models.Order, deadline time.Time) error {
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
+
+	reminded, err := tx.Orders.MarkReminded(ctx, o.ID, time.Now())
+	if err != nil || !reminded {
+		return err
+	}
+	at := deadline.In(h.Config.Shipping.Timezone).Format("2 Jan 2006 15:04")
+	message := fmt.Sprintf("Order #%d is still unpaid and will be cancelled on %s. Top up your wallet and pay it to keep it.", o.ID, at)
+	if err := h.AddNotification(ctx, tx, o.UserID, message, "/dropshipper/orders/"+o.PublicID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.Infof("[Cron] Reminded User %d of unpaid Order %d (cancelled on %s)", o.UserID, o.ID, at)
+	return nil
 }
 
-// cancelAndPenalize performs the atomic update: Cancel Order -> Restore Stock -> Strike User
-func (h *Handlers) cancelAndPenalize(orderID, userID int64) {
-	tx, err := h.DB.Begin()
+// cancelAndPenalize performs the atomic update: Cancel Order -> Restore Stock (and Held Funds) -> Strike User
+// The order was listed outside the transaction, so it is locked and skipped
+// unless it is still on-hold: a payment may have landed in between.
+func (h *Handlers) cancelAndPenalize(ctx context.Context, o models.Order) {
+	orderID, userID := o.ID, o.UserID
+	tx, err := h.Store.Begin(ctx, nil)
 	if err != nil {
-		log.Printf("[Cron] Failed to begin tx for Order %d: %v", orderID, err)
+		logging.Errorf("[Cron] Failed to begin tx for Order %d: %v", orderID, err)
 		return
 	}
 	defer tx.Rollback()
 
-	// A. Restore Stock (Because we reserved it during Checkout)
-	rows, err := tx.Query("SELECT product_id, variant_id, quantity FROM order_items WHERE order_id = ?", orderID)
+	order, err := tx.Orders.GetForUpdate(ctx, orderID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return
+	}
 	if err != nil {
-		log.Printf("[Cron] Failed to fetch items for Order %d: %v", orderID, err)
+		logging.Errorf("[Cron] Failed to lock Order %d: %v", orderID, err)
 		return
 	}
-
-	type ItemToRestore struct {
-		ProductID int64
-		VariantID *int64
-		Quantity  int
+	if order.Status != "on-hold" {
+		logging.Infof("[Cron] Order %d is %s now, not cancelling it", orderID, order.Status)
+		return
 	}
-	var items []ItemToRestore
 
-	for rows.Next() {
-		var i ItemToRestore
-		if err := rows.Scan(&i.ProductID, &i.VariantID, &i.Quantity); err == nil {
-			items = append(items, i)
-		}
+	// A. Restore Stock (Because we reserved it during Checkout)
+	restoredIDs, err := releaseOrderStock(ctx, tx, orderID)
+	if err != nil {
+		logging.Errorf("[Cron] Failed to restore stock for Order %d: %v", orderID, err)
+		return
 	}
-	rows.Close() // Close immediately after scanning
 
-	for _, item := range items {
-		if item.VariantID != nil && *item.VariantID > 0 {
-			_, err = tx.Exec("UPDATE product_variants SET stock_quantity = stock_quantity + ? WHERE id = ?", item.Quantity, *item.VariantID)
-		} else {
-			_, err = tx.Exec("UPDATE products SET stock_quantity = stock_quantity + ? WHERE id = ?", item.Quantity, item.ProductID)
-		}
-		if err != nil {
-			log.Printf("[Cron] Failed to restore stock for Order %d: %v", orderID, err)
-			return
-		}
+	if err := tx.Wallet.CloseHold(ctx, orderID, store.HoldReleased); err != nil {
+		logging.Errorf("[Cron] Failed to release wallet hold for Order %d: %v", orderID, err)
+		return
 	}
 
 	// B. Update Order Status
-	_, err = tx.Exec("UPDATE orders SET status = 'cancelled', updated_at = ? WHERE id = ?", time.Now(), orderID)
-	if err != nil {
-		log.Printf("[Cron] Failed to cancel Order %d: %v", orderID, err)
+	if err := tx.Orders.UpdateStatus(ctx, orderID, "cancelled"); err != nil {
+		logging.Errorf("[Cron] Failed to cancel Order %d: %v", orderID, err)
 		return
 	}
 
 	// C. Increment User Penalty Strikes
-	_, err = tx.Exec("UPDATE users SET penalty_strikes = penalty_strikes + 1, updated_at = ? WHERE id = ?", time.Now(), userID)
+	_, err = tx.ExecContext(ctx, "UPDATE users SET penalty_strikes = penalty_strikes + 1, updated_at = ?, version = version + 1 WHERE id = ?", time.Now(), userID)
 	if err != nil {
-		log.Printf("[Cron] Failed to penalize User %d: %v", userID, err)
+		logging.Errorf("[Cron] Failed to penalize User %d: %v", userID, err)
+		return
+	}
+
+	// D. Tell the dropshipper
+	message := fmt.Sprintf("Order #%d was cancelled because it was not paid within %d day(s).", orderID, h.Config.UnpaidOrders.Days)
+	if err := h.AddNotification(ctx, tx, userID, message, "/dropshipper/orders/"+o.PublicID); err != nil {
+		logging.Errorf("[Cron] Failed to notify User %d of cancelled Order %d: %v", userID, orderID, err)
 		return
 	}
 
 	if err := tx.Commit(); err != nil {
-		log.Printf("[Cron] Failed to commit tx for Order %d: %v", orderID, err)
+		logging.Errorf("[Cron] Failed to commit tx for Order %d: %v", orderID, err)
 		return
 	}
 
-	log.Printf("[Cron] SUCCESS: Order %d cancelled, Stock restored, User %d penalized.", orderID, userID)
+	h.invalidateProducts(ctx, restoredIDs...)
+
+	logging.Infof("[Cron] SUCCESS: Order %d cancelled, Stock restored, User %d penalized.", orderID, userID)
 }
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Blind Shipping & Packing Slips ---
+//
+// Each role sees its own cut of an order. Suppliers get what they need to
+// ship (models.SupplierOrder): never the dropshipper, their customer record or
+// the order amounts. End customers only see the packing slip, which on a
+// blind order (the default) never names the supplier. Managers see everything,
+// but only through the dispute they are handling.
+
+// businessName is the name a user trades under: the company, else their own.
+func businessName(ctx context.Context, q Querier, userID int64) (string, error) {
+	var name string
+	err := q.QueryRowContext(ctx, "SELECT COALESCE(NULLIF(company_name, ''), full_name) FROM users WHERE id = ?", userID).Scan(&name)
+	return name, err
+}
+
+// packingSlips builds the slip of every supplier's parcel of an order, in the
+// order of their first line; a supplierID > 0 keeps only that supplier's.
+func (h *Handlers) packingSlips(ctx context.Context, o *models.Order, supplierID int64) ([]models.PackingSlip, error) {
+	items, err := h.Store.Orders.Items(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. --- Sender ---
+	// Blind slips all carry the same sender; the others each name their supplier.
+	var blindFrom string
+	if o.BlindShip {
+		if o.SenderName != nil {
+			blindFrom = *o.SenderName
+		} else if blindFrom, err = businessName(ctx, h.DB, o.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. --- One Slip per Supplier ---
+	slips := []models.PackingSlip{}
+	index := map[int64]int{}
+	for _, item := range items {
+		if supplierID > 0 && item.SupplierID != supplierID {
+			continue
+		}
+		i, ok := index[item.SupplierID]
+		if !ok {
+			from := blindFrom
+			if !o.BlindShip {
+				if from, err = businessName(ctx, h.DB, item.SupplierID); err != nil {
+					return nil, err
+				}
+			}
+			i = len(slips)
+			index[item.SupplierID] = i
+			slips = append(slips, models.PackingSlip{
+				OrderPublicID: o.PublicID,
+				OrderDate:     o.CreatedAt,
+				BlindShip:     o.BlindShip,
+				From:          from,
+				ShipTo:        o.ShipTo,
+				Items:         []models.PackingSlipItem{},
+			})
+		}
+		line := models.PackingSlipItem{ProductName: item.ProductName, Options: item.Options, Quantity: item.Quantity}
+		if !o.BlindShip {
+			line.SKU = item.ProductSKU
+		}
+		slips[i].Items = append(slips[i].Items, line)
+	}
+	return slips, nil
+}
+
+// GetSupplierPackingSlip is the handler for GET /v1/supplier/orders/:id/packing-slip
+// It is the slip to print and pack with the supplier's parcel of the order.
+func (h *Handlers) GetSupplierPackingSlip(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+
+	o, err := h.Store.Orders.Get(ctx, orderID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order")
+		return
+	}
+	slips, err := h.packingSlips(ctx, o, supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to build packing slip")
+		return
+	}
+	// Orders without the supplier's lines are not theirs to see.
+	if len(slips) == 0 {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+	c.JSON(http.StatusOK, slips[0])
+}
+
+// GetOrderPackingSlips is the handler for GET /v1/dropshipper/orders/:id/packing-slips
+// One slip per parcel (per supplier), exactly as the end customer receives it.
+func (h *Handlers) GetOrderPackingSlips(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+
+	o, err := h.Store.Orders.GetForUser(ctx, orderID, dropshipperID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order")
+		return
+	}
+	slips, err := h.packingSlips(ctx, o, 0)
+	if err != nil {
+		apierror.Internal(c, "Failed to build packing slips")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"orderPublicId": o.PublicID, "packingSlips": slips})
+}
+
+// DisputeParty is a side of a disputed order, named for the manager.
+type DisputeParty struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// GetDisputeOrder is the handler for GET /v1/manager/disputes/:id/order
+// It is the override of the per-role filtering: the whole disputed order,
+// both parties, the customer it shipped to and the supplier's packing slip.
+// Every look is logged.
+func (h *Handlers) GetDisputeOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+	disputeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+
+	// 1. --- Dispute & Order ---
+	d, err := getDispute(ctx, h.DB, disputeID, false)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Dispute not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch dispute")
+		return
+	}
+	o, err := h.Store.Orders.GetForUser(ctx, d.OrderID, d.DropshipperID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order")
+		return
+	}
+	items, err := h.Store.Orders.Items(ctx, o.ID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order items")
+		return
+	}
+
+	// 2. --- Parties ---
+	dropshipper, supplier := DisputeParty{ID: d.DropshipperID}, DisputeParty{ID: d.SupplierID}
+	for _, p := range []*DisputeParty{&dropshipper, &supplier} {
+		if p.Name, err = businessName(ctx, h.DB, p.ID); err != nil {
+			apierror.Internal(c, "Failed to fetch dispute parties")
+			return
+		}
+	}
+
+	// 3. --- What the Customer Received ---
+	slips, err := h.packingSlips(ctx, o, d.SupplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to build packing slip")
+		return
+	}
+	var slip *models.PackingSlip
+	if len(slips) > 0 {
+		slip = &slips[0]
+	}
+
+	logging.Infof("[Dispute] Manager %d opened the unfiltered order %d of dispute %d", managerID, o.ID, d.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"disputeId":   d.ID,
+		"order":       o,
+		"items":       items,
+		"dropshipper": dropshipper,
+		"supplier":    supplier,
+		"packingSlip": slip,
+	})
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/events"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Pre-orders ---
+//
+// Checkout places the lines beyond the stock of a pre-order product as
+// pre-order lines: the order is paid in full, its pre-order lines hold a place
+// in products.preorder_reserved instead of taking stock, and it waits in
+// status 'pre-order'. Once every pre-order line of an order is covered by
+// stock, the order takes it and converts to 'processing'.
+
+// ProcessPreorders converts every pre-order whose stock has arrived, oldest
+// first. The background worker calls it every PREORDER_CHECK_INTERVAL; a
+// restock converts the orders waiting for that product right away.
+func (h *Handlers) ProcessPreorders(ctx context.Context) {
+	orders, err := h.Store.Orders.ListPreorders(ctx, 0)
+	if err != nil {
+		logging.Errorf("[Preorders] Error fetching pre-orders: %v", err)
+		return
+	}
+	// Like ProcessOverdueOrders: stop between orders on shutdown, never mid-way.
+	for i, o := range orders {
+		if ctx.Err() != nil {
+			logging.Infof("[Preorders] Shutting down, %d pre-orders left for the next run", len(orders)-i)
+			return
+		}
+		if _, err := h.convertPreorder(context.WithoutCancel(ctx), o); err != nil {
+			logging.Errorf("[Preorders] Failed to convert Order %d: %v", o.ID, err)
+		}
+	}
+}
+
+// convertRestockedPreorders is the ProductRestocked subscriber: it converts the
+// pre-orders waiting for the product, oldest first, while its stock lasts.
+func (h *Handlers) convertRestockedPreorders(ctx context.Context, e events.ProductRestocked) error {
+	if e.Stock <= 0 {
+		return nil
+	}
+	orders, err := h.Store.Orders.ListPreorders(ctx, e.ProductID)
+	if err != nil {
+		return err
+	}
+	for _, o := range orders {
+		if _, err := h.convertPreorder(ctx, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertPreorder takes the stock for an order's pre-order lines and moves it
+// to 'processing'. It reports false, changing nothing, while any line is still
+// short of stock or when the order is no longer a pre-order.
+func (h *Handlers) convertPreorder(ctx context.Context, o models.Order) (bool, error) {
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// 1. --- Lock & Re-check the Order ---
+	order, err := tx.Orders.GetForUpdate(ctx, o.ID, o.UserID)
+	if err != nil {
+		return false, err
+	}
+	if order.Status != "pre-order" {
+		return false, nil // cancelled or converted in the meantime
+	}
+
+	// 2. --- Lock the Stock of Every Pre-order Line ---
+	lines, err := tx.Orders.StockLines(ctx, order.ID)
+	if err != nil {
+		return false, err
+	}
+	var waiting []models.OrderItem
+	for _, line := range lines {
+		if !line.Preorder {
+			continue
+		}
+		var stock int
+		err := tx.QueryRowContext(ctx, "SELECT stock_quantity FROM products WHERE id = ? FOR UPDATE", line.ProductID).Scan(&stock)
+		if err != nil {
+			return false, err
+		}
+		if stock < line.Quantity {
+			return false, nil
+		}
+		waiting = append(waiting, line)
+	}
+
+	// 3. --- Take the Stock & Release the Reservation ---
+	threshold := h.lowStockThreshold(ctx)
+	var lowStock []*events.StockLow
+	for _, line := range waiting {
+		low, err := takeStock(ctx, tx, line.ProductID, nil, line.Quantity, threshold)
+		if err != nil {
+			return false, err
+		}
+		if low != nil {
+			lowStock = append(lowStock, low)
+		}
+		if err := tx.Products.ReservePreorder(ctx, line.ProductID, -line.Quantity); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE order_items SET preorder = 0 WHERE order_id = ?", order.ID); err != nil {
+		return false, err
+	}
+	if err := h.stampShipsBy(ctx, tx, order.ID, time.Now()); err != nil {
+		return false, err
+	}
+
+	// 4. --- Convert (already paid at checkout) ---
+	if err := tx.Orders.UpdateStatus(ctx, order.ID, "processing"); err != nil {
+		return false, err
+	}
+	message := fmt.Sprintf("Your pre-order #%d is in stock and now processing.", order.ID)
+	if err := h.AddNotification(ctx, tx, order.UserID, message, "/dropshipper/orders/"+order.PublicID); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	productIDs := make([]int64, 0, len(waiting))
+	for _, line := range waiting {
+		productIDs = append(productIDs, line.ProductID)
+	}
+	h.invalidateProducts(ctx, productIDs...)
+	h.Events.Publish(ctx, events.OrderPaid{OrderID: order.ID, OrderPublicID: order.PublicID, UserID: order.UserID, Total: order.Total})
+	for _, low := range lowStock {
+		h.Events.Publish(ctx, *low)
+	}
+
+	logging.Infof("[Preorders] Order %d converted to processing", order.ID)
+	return true, nil
+}
+
+// CancelOrder handles POST /v1/dropshipper/orders/:id/cancel
+// Only orders still waiting, unpaid ('on-hold') or in 'pre-order', can be
+// cancelled. A pre-order's payment is refunded in full and an on-hold order's
+// wallet hold released; in-stock lines give their stock back and pre-order
+// lines release their place under the product's limit.
+func (h *Handlers) CancelOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+
+	// 2. --- Lock the Order ---
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	order, err := tx.Orders.GetForUpdate(ctx, orderID, dropshipperID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.NotFound(c, "Order not found")
+			return
+		}
+		apierror.Internal(c, "Failed to fetch order")
+		return
+	}
+	if order.Status != "pre-order" && order.Status != "on-hold" {
+		apierror.Conflict(c, "Only unpaid orders and orders still waiting as pre-orders can be cancelled")
+		return
+	}
+
+	// 3. --- Give Back Stock & Pre-order Places ---
+	productIDs, err := releaseOrderStock(ctx, tx, orderID)
+	if err != nil {
+		apierror.Internal(c, "Failed to restore stock")
+		return
+	}
+
+	// 4. --- Refund (or Release the Hold) & Cancel ---
+	refunded, message := order.Total, "Pre-order cancelled and refunded"
+	if order.Status == "pre-order" {
+		notes := fmt.Sprintf("Refund for cancelled pre-order #%d", orderID)
+		if err := tx.Wallet.AddTransaction(ctx, dropshipperID, "refund", order.Total, notes); err != nil {
+			apierror.Internal(c, "Failed to refund payment")
+			return
+		}
+	} else {
+		if err := tx.Wallet.CloseHold(ctx, orderID, store.HoldReleased); err != nil {
+			apierror.Internal(c, "Failed to release wallet hold")
+			return
+		}
+		refunded, message = 0, "Order cancelled"
+	}
+	if err := tx.Orders.UpdateStatus(ctx, orderID, "cancelled"); err != nil {
+		apierror.Internal(c, "Failed to update order status")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+
+	h.invalidateProducts(ctx, productIDs...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  message,
+		"status":   "cancelled",
+		"refunded": refunded,
+	})
+}
+
+// releaseOrderStock gives back what an unshipped order reserved at checkout:
+// the stock of its in-stock lines and the places of its pre-order lines. It
+// returns the products touched, for cache invalidation after the commit.
+func releaseOrderStock(ctx context.Context, tx *store.Tx, orderID int64) ([]int64, error) {
+	lines, err := tx.Orders.StockLines(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	productIDs := make([]int64, 0, len(lines))
+	for _, line := range lines {
+		if line.Preorder {
+			err = tx.Products.ReservePreorder(ctx, line.ProductID, -line.Quantity)
+		} else {
+			err = tx.Products.AdjustStock(ctx, line.ProductID, line.VariantID, line.Quantity)
+		}
+		if err != nil {
+			return nil, err
+		}
+		productIDs = append(productIDs, line.ProductID)
+	}
+	return productIDs, nil
+}
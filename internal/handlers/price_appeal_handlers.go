@@ -6,7 +6,10 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
 	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pricing"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,6 +20,8 @@ import (
 // GetPriceAppeals is the handler for GET /v1/manager/price-requests
 // It retrieves all 'pending' price appeals for managers to review.
 func (h *Handlers) GetPriceAppeals(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Query Database ---
 	// We JOIN with 'products' and 'users' to get all context for the manager
 	query := `
@@ -29,12 +34,12 @@ func (h *Handlers) GetPriceAppeals(c *gin.Context) {
 		FROM price_appeals pa
 		JOIN products p ON pa.product_id = p.id
 		JOIN users u ON pa.supplier_id = u.id
-		WHERE pa.status = 'pending'
+		WHERE pa.status = 'pending' AND p.deleted_at IS NULL
 		ORDER BY pa.created_at ASC
 	`
-	rows, err := h.DB.Query(query)
+	rows, err := h.DB.QueryContext(ctx, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		apierror.Internal(c, "Database query failed")
 		return
 	}
 	defer rows.Close()
@@ -56,14 +61,14 @@ func (h *Handlers) GetPriceAppeals(c *gin.Context) {
 			&appeal.SupplierName,
 			&appeal.SupplierEmail,
 		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan price appeal"})
+			apierror.Internal(c, "Failed to scan price appeal")
 			return
 		}
 		appeals = append(appeals, &appeal)
 	}
 
 	if err = rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error iterating rows"})
+		apierror.Internal(c, "Error iterating rows")
 		return
 	}
 
@@ -81,24 +86,26 @@ type ProcessPriceAppealInput struct {
 
 // ProcessPriceAppeal is the handler for PATCH /v1/manager/price-requests/:id
 func (h *Handlers) ProcessPriceAppeal(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get IDs & Bind Input ---
 	appealID := c.Param("id")
 
 	var input ProcessPriceAppealInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
 	if input.Action == "reject" && input.RejectionReason == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "A rejectionReason is required when rejecting an appeal"})
+		apierror.BadRequest(c, "A rejectionReason is required when rejecting an appeal")
 		return
 	}
 
 	// 2. --- Begin Transaction ---
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
@@ -107,7 +114,7 @@ func (h *Handlers) ProcessPriceAppeal(c *gin.Context) {
 	// Lock the row and check its status
 	var appeal models.PriceAppeal
 	query := "SELECT id, product_id, supplier_id, new_price, status FROM price_appeals WHERE id = ? FOR UPDATE"
-	err = tx.QueryRow(query, appealID).Scan(
+	err = tx.QueryRowContext(ctx, query, appealID).Scan(
 		&appeal.ID,
 		&appeal.ProductID,
 		&appeal.SupplierID,
@@ -116,39 +123,51 @@ func (h *Handlers) ProcessPriceAppeal(c *gin.Context) {
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Price appeal not found"})
+			apierror.NotFound(c, "Price appeal not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get appeal details"})
+		apierror.Internal(c, "Failed to get appeal details")
 		return
 	}
 
 	if appeal.Status != "pending" {
-		c.JSON(http.StatusConflict, gin.H{"error": "This appeal has already been processed"})
+		apierror.Conflict(c, "This appeal has already been processed")
 		return
 	}
 
 	// 4. --- Process Action ---
 	if input.Action == "approve" {
 		// Action: Approve
+		// 0. The new price must still leave dropshippers their margin under the
+		// SRP, which may have changed since the appeal was made.
+		var srp money.Money
+		if err := tx.QueryRowContext(ctx, "SELECT srp FROM products WHERE id = ? FOR UPDATE", appeal.ProductID).Scan(&srp); err != nil {
+			apierror.Internal(c, "Failed to get product details")
+			return
+		}
+		if err := pricing.Validate(appeal.NewPrice, srp, h.Config.Products.MinMargin); err != nil {
+			apierror.Conflict(c, err.Error())
+			return
+		}
+
 		// 1. Update the appeal status
 		appealQuery := "UPDATE price_appeals SET status = 'approved' WHERE id = ?"
-		if _, err := tx.Exec(appealQuery, appealID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve appeal"})
+		if _, err := tx.ExecContext(ctx, appealQuery, appealID); err != nil {
+			apierror.Internal(c, "Failed to approve appeal")
 			return
 		}
 
 		// 2. Update the actual price in the 'products' table
-		productQuery := "UPDATE products SET price = ?, updated_at = ? WHERE id = ?"
-		if _, err := tx.Exec(productQuery, appeal.NewPrice, time.Now(), appeal.ProductID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product price"})
+		productQuery := "UPDATE products SET price_to_tts = ?, updated_at = ?, version = version + 1 WHERE id = ?"
+		if _, err := tx.ExecContext(ctx, productQuery, appeal.NewPrice, time.Now(), appeal.ProductID); err != nil {
+			apierror.Internal(c, "Failed to update product price")
 			return
 		}
 
 		// 3. Add notification to supplier
-		message := fmt.Sprintf("Your price change request for product ID %d to RM %.2f has been approved.", appeal.ProductID, appeal.NewPrice)
-		if err := h.AddNotification(tx, appeal.SupplierID, message, ""); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+		message := fmt.Sprintf("Your price change request for product ID %d to RM %s has been approved.", appeal.ProductID, appeal.NewPrice)
+		if err := h.AddNotification(ctx, tx, appeal.SupplierID, message, ""); err != nil {
+			apierror.Internal(c, "Failed to send notification")
 			return
 		}
 
@@ -156,24 +175,25 @@ func (h *Handlers) ProcessPriceAppeal(c *gin.Context) {
 		// Action: Reject
 		// 1. Update the appeal status and reason
 		appealQuery := "UPDATE price_appeals SET status = 'rejected', rejection_reason = ? WHERE id = ?"
-		if _, err := tx.Exec(appealQuery, input.RejectionReason, appealID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject appeal"})
+		if _, err := tx.ExecContext(ctx, appealQuery, input.RejectionReason, appealID); err != nil {
+			apierror.Internal(c, "Failed to reject appeal")
 			return
 		}
 
 		// 2. Add notification to supplier
 		message := fmt.Sprintf("Your price change request for product ID %d was rejected. Reason: %s", appeal.ProductID, input.RejectionReason)
-		if err := h.AddNotification(tx, appeal.SupplierID, message, ""); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+		if err := h.AddNotification(ctx, tx, appeal.SupplierID, message, ""); err != nil {
+			apierror.Internal(c, "Failed to send notification")
 			return
 		}
 	}
 
 	// 5. --- Commit Transaction ---
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		apierror.Internal(c, "Failed to commit transaction")
 		return
 	}
+	h.invalidateProducts(ctx, appeal.ProductID)
 
 	// 6. --- Send Response ---
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/shipping"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Processing Time & Ships-By Dates ---
+//
+// Suppliers declare handling days and a daily cutoff shop-wide, and may
+// override them per product. Product detail and the cart show the ships-by
+// date of an order paid now; paying stamps it on every line (order_items.ships_by),
+// and ProcessLateShipments tells suppliers when one passes unshipped.
+
+// processingColumns selects a product's effective handling days and cutoff;
+// p is the product alias and s its supplier's.
+func processingColumns(p, s string) string {
+	return "COALESCE(" + p + ".handling_days, " + s + ".handling_days), COALESCE(" + p + ".order_cutoff, " + s + ".order_cutoff)"
+}
+
+// shipsBy is the ships-by date of an order paid at, for the handling days and
+// cutoff read with processingColumns (NULL days use SHIPPING_HANDLING_DAYS).
+func (h *Handlers) shipsBy(days sql.NullInt64, cutoff sql.NullString, at time.Time) time.Time {
+	handlingDays := h.Config.Shipping.HandlingDays
+	if days.Valid {
+		handlingDays = int(days.Int64)
+	}
+	return shipping.ShipsBy(at, handlingDays, cutoff.String, h.Config.Shipping.Timezone)
+}
+
+// ProcessingTimeInput sets a processing time; a null field falls back to the
+// shop-wide setting (on a product) or the platform default (shop-wide).
+type ProcessingTimeInput struct {
+	HandlingDays *int    `json:"handlingDays" binding:"omitempty,min=0,max=30"`
+	Cutoff       *string `json:"cutoff"` // "HH:MM"
+}
+
+// validate checks the cutoff and returns it as a TIME value.
+func (in *ProcessingTimeInput) validate() (*string, error) {
+	if in.Cutoff == nil {
+		return nil, nil
+	}
+	if _, err := shipping.ParseCutoff(*in.Cutoff); err != nil {
+		return nil, err
+	}
+	cutoff := (*in.Cutoff)[:5] + ":00"
+	return &cutoff, nil
+}
+
+// scanProcessingTime fills a ProcessingTime, trimming the cutoff to "HH:MM".
+func scanProcessingTime(row interface{ Scan(...interface{}) error }) (models.ProcessingTime, error) {
+	var pt models.ProcessingTime
+	var days sql.NullInt64
+	var cutoff sql.NullString
+	if err := row.Scan(&days, &cutoff); err != nil {
+		return pt, err
+	}
+	if days.Valid {
+		d := int(days.Int64)
+		pt.HandlingDays = &d
+	}
+	if cutoff.Valid && len(cutoff.String) >= 5 {
+		c := cutoff.String[:5]
+		pt.Cutoff = &c
+	}
+	return pt, nil
+}
+
+// GetProcessingTime is the handler for GET /v1/supplier/processing-time
+func (h *Handlers) GetProcessingTime(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	row := h.DB.QueryRowContext(c.Request.Context(), "SELECT handling_days, order_cutoff FROM users WHERE id = ?", userID_raw.(int64))
+	pt, err := scanProcessingTime(row)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch processing time")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"processingTime": pt, "defaultHandlingDays": h.Config.Shipping.HandlingDays})
+}
+
+// UpdateProcessingTime is the handler for PUT /v1/supplier/processing-time
+// It applies to orders paid from now on; earlier ones keep their ships-by date.
+func (h *Handlers) UpdateProcessingTime(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Supplier ID & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+
+	var input ProcessingTimeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	cutoff, err := input.validate()
+	if err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	// 2. --- Save ---
+	_, err = h.DB.ExecContext(ctx, "UPDATE users SET handling_days = ?, order_cutoff = ?, updated_at = ? WHERE id = ?",
+		input.HandlingDays, cutoff, time.Now(), supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to update processing time")
+		return
+	}
+	h.GetProcessingTime(c)
+}
+
+// stampShipsBy sets the ships-by date of an order's lines that have none,
+// once the order is paid (pre-order lines wait until they convert).
+func (h *Handlers) stampShipsBy(ctx context.Context, q Querier, orderID int64, paidAt time.Time) error {
+	rows, err := q.QueryContext(ctx, `
+		SELECT oi.id, `+processingColumns("p", "s")+`
+		FROM order_items oi
+		JOIN products p ON p.id = oi.product_id
+		JOIN users s ON s.id = oi.supplier_id
+		WHERE oi.order_id = ? AND oi.preorder = 0 AND oi.ships_by IS NULL`, orderID)
+	if err != nil {
+		return err
+	}
+	dates := map[int64]time.Time{}
+	for rows.Next() {
+		var id int64
+		var days sql.NullInt64
+		var cutoff sql.NullString
+		if err := rows.Scan(&id, &days, &cutoff); err != nil {
+			rows.Close()
+			return err
+		}
+		dates[id] = h.shipsBy(days, cutoff, paidAt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, date := range dates {
+		if _, err := q.ExecContext(ctx, "UPDATE order_items SET ships_by = ? WHERE id = ?", date, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//
+// --- Late Shipments (SLA) ---
+//
+
+// lateShipmentsQuery lists each supplier's part of a paid, unshipped order
+// whose earliest ships-by date is before ?. Append HAVING / ORDER BY / LIMIT.
+const lateShipmentsQuery = `
+	SELECT o.id, o.public_id, oi.supplier_id, COALESCE(NULLIF(s.company_name, ''), s.full_name),
+		MIN(oi.ships_by), MIN(oi.late_notified_at IS NOT NULL)
+	FROM order_items oi
+	JOIN orders o ON o.id = oi.order_id
+	JOIN users s ON s.id = oi.supplier_id
+	WHERE o.status = 'processing' AND o.deleted_at IS NULL AND oi.ships_by < ?
+	GROUP BY o.id, o.public_id, oi.supplier_id, s.company_name, s.full_name`
+
+// queryLateShipments runs lateShipmentsQuery with a suffix.
+func queryLateShipments(ctx context.Context, q Querier, now time.Time, suffix string) ([]models.LateShipment, error) {
+	rows, err := q.QueryContext(ctx, lateShipmentsQuery+suffix, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	late := []models.LateShipment{}
+	for rows.Next() {
+		var ls models.LateShipment
+		if err := rows.Scan(&ls.OrderID, &ls.OrderPublicID, &ls.SupplierID, &ls.SupplierName, &ls.ShipsBy, &ls.Notified); err != nil {
+			return nil, err
+		}
+		late = append(late, ls)
+	}
+	return late, rows.Err()
+}
+
+// GetLateShipments is the handler for GET /v1/manager/late-shipments
+// Oldest ships-by date first, at most 200.
+func (h *Handlers) GetLateShipments(c *gin.Context) {
+	late, err := queryLateShipments(c.Request.Context(), h.DB, time.Now(), " ORDER BY MIN(oi.ships_by) ASC LIMIT 200")
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch late shipments")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"lateShipments": late})
+}
+
+// ProcessLateShipments tells each supplier, once, about their parts of paid
+// orders that passed the ships-by date unshipped. The background worker calls
+// it every SHIPPING_LATE_CHECK_INTERVAL.
+func (h *Handlers) ProcessLateShipments(ctx context.Context) {
+	late, err := queryLateShipments(ctx, h.DB, time.Now(), " HAVING MIN(oi.late_notified_at IS NOT NULL) = 0 LIMIT 500")
+	if err != nil {
+		logging.Errorf("[Shipping] Error fetching late shipments: %v", err)
+		return
+	}
+
+	for i, ls := range late {
+		if ctx.Err() != nil {
+			logging.Infof("[Shipping] Shutting down, %d late shipments left for the next run", len(late)-i)
+			return
+		}
+		if err := h.notifyLateShipment(context.WithoutCancel(ctx), ls); err != nil {
+			logging.Errorf("[Shipping] Failed to flag late shipment of Order %d for User %d: %v", ls.OrderID, ls.SupplierID, err)
+		}
+	}
+}
+
+// notifyLateShipment marks one late shipment and notifies the supplier.
+func (h *Handlers) notifyLateShipment(ctx context.Context, ls models.LateShipment) error {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE order_items oi
+		SET oi.late_notified_at = ?
+		WHERE oi.order_id = ? AND oi.supplier_id = ? AND oi.late_notified_at IS NULL`,
+		now, ls.OrderID, ls.SupplierID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil // flagged by another instance
+	}
+	shipsBy := ls.ShipsBy.In(h.Config.Shipping.Timezone).Format("2 Jan 2006")
+	message := fmt.Sprintf("Order #%d was due to ship by %s. Please ship it as soon as possible.", ls.OrderID, shipsBy)
+	if err := h.AddNotification(ctx, tx, ls.SupplierID, message, "/supplier/orders/"+ls.OrderPublicID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.Infof("[Shipping] Order %d is late for User %d (ships by %s)", ls.OrderID, ls.SupplierID, shipsBy)
+	return nil
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Product Export ("Push to My Store", Dropshipper-Only) ---
+//
+
+// exportCurrency is the currency of every exported price.
+const exportCurrency = "MYR"
+
+// ExportProduct is the handler for GET /v1/dropshipper/products/:id/export
+// It returns a catalogue product as a ready-to-list payload for the
+// dropshipper's own storefront, and records the export.
+func (h *Handlers) ExportProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Dropshipper ID ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+
+	// 2. --- Load the Catalogue Product ---
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	p, err := h.Store.Products.GetActive(ctx, productID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Database error")
+		return
+	}
+
+	// 3. --- Record the Export ---
+	now := time.Now()
+	if err := recordProductExport(ctx, h.DB, dropshipperID, p.ID, now); err != nil {
+		apierror.Internal(c, "Failed to record export")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"export": buildProductExport(p, now)})
+}
+
+// recordProductExport counts one more export of the product by the dropshipper.
+func recordProductExport(ctx context.Context, q Querier, dropshipperID, productID int64, now time.Time) error {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO product_exports (dropshipper_id, product_id, export_count, first_exported_at, last_exported_at)
+		VALUES (?, ?, 1, ?, ?)
+		ON DUPLICATE KEY UPDATE export_count = export_count + 1, last_exported_at = VALUES(last_exported_at)`,
+		dropshipperID, productID, now, now)
+	return err
+}
+
+// buildProductExport maps a product to its export payload.
+func buildProductExport(p *models.Product, now time.Time) models.ProductExport {
+	out := models.ProductExport{
+		SourceID:    p.PublicID,
+		SKU:         p.SKU,
+		Barcode:     p.Barcode,
+		Title:       p.Name,
+		Description: sanitize.HTML(p.Description),
+		Categories:  make([]string, 0, len(p.Categories)),
+		Images:      p.Images,
+		VideoURL:    p.VideoURL,
+		Currency:    exportCurrency,
+		Stock:       p.StockQuantity,
+		WeightKg:    p.Weight,
+		Options:     []models.ProductExportOption{},
+		Variants:    make([]models.ProductExportVariant, 0, len(p.Variants)),
+		ExportedAt:  now,
+	}
+	if out.Images == nil {
+		out.Images = []string{}
+	}
+	if p.SRP > 0 {
+		srp := p.SRP
+		out.SRP = &srp
+	}
+	if len(p.Brands) > 0 {
+		out.Brand = &p.Brands[0].Name
+	}
+	for _, cat := range p.Categories {
+		out.Categories = append(out.Categories, cat.Name)
+	}
+	if p.PkgLength != nil && p.PkgWidth != nil && p.PkgHeight != nil {
+		out.Package = &models.PackageDimensions{Length: *p.PkgLength, Width: *p.PkgWidth, Height: *p.PkgHeight}
+	}
+
+	// Options are collected from the variants, first appearance first.
+	optionIndex := map[string]int{}
+	for _, v := range p.Variants {
+		ev := models.ProductExportVariant{SKU: v.SKU, Barcode: v.Barcode, Stock: v.StockQuantity}
+		_ = json.Unmarshal([]byte(v.Options), &ev.Options)
+		if ev.Options == nil {
+			ev.Options = []models.ProductVariantOption{}
+		}
+		for _, o := range ev.Options {
+			i, ok := optionIndex[o.Name]
+			if !ok {
+				i = len(out.Options)
+				optionIndex[o.Name] = i
+				out.Options = append(out.Options, models.ProductExportOption{Name: o.Name, Values: []string{}})
+			}
+			if !slices.Contains(out.Options[i].Values, o.Value) {
+				out.Options[i].Values = append(out.Options[i].Values, o.Value)
+			}
+			if ev.Image == "" {
+				ev.Image = p.VariationImages[o.Value]
+			}
+		}
+		out.Variants = append(out.Variants, ev)
+	}
+	return out
+}
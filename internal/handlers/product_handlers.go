@@ -1,15 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/01moynul/taptosell-golang/internal/cache"
+	"github.com/01moynul/taptosell-golang/internal/dto"
+	"github.com/01moynul/taptosell-golang/internal/events"
 	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/pricing"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/shipping"
+	"github.com/01moynul/taptosell-golang/internal/store"
 	"github.com/gin-gonic/gin"
 	"github.com/gosimple/slug"
 )
@@ -17,20 +31,27 @@ import (
 // --- Inputs ---
 
 type VariantInput struct {
+	// ID names the variant an update edits; without it the variant is matched
+	// on its SKU, and one matching nothing is added (see diffVariants).
+	ID             *int64                        `json:"id,omitempty"`
 	SKU            string                        `json:"sku"`
-	Price          float64                       `json:"price" binding:"gte=0"`
+	Barcode        *string                       `json:"barcode,omitempty"` // see SimpleProductInput.Barcode
+	Price          money.Money                   `json:"price" binding:"price"`
 	Stock          int                           `json:"stock" binding:"gte=0"`
-	SRP            float64                       `json:"srp"`
+	SRP            money.Money                   `json:"srp" binding:"price"`
 	Options        []models.ProductVariantOption `json:"options" binding:"omitempty,min=1"`
 	CommissionRate *float64                      `json:"commissionRate,omitempty" binding:"omitempty,gte=0"`
 }
 
 type SimpleProductInput struct {
-	SKU            string   `json:"sku"`
-	Price          float64  `json:"price" binding:"gte=0"`
-	Stock          int      `json:"stock" binding:"gte=0"`
-	SRP            float64  `json:"srp"`
-	CommissionRate *float64 `json:"commissionRate,omitempty" binding:"omitempty,gte=0"`
+	SKU string `json:"sku"`
+	// Barcode is an EAN-8, UPC-A, EAN-13 or GTIN-14. On update, leaving it
+	// out keeps the current one and "" removes it.
+	Barcode        *string     `json:"barcode,omitempty"`
+	Price          money.Money `json:"price" binding:"price"`
+	Stock          int         `json:"stock" binding:"gte=0"`
+	SRP            money.Money `json:"srp" binding:"price"`
+	CommissionRate *float64    `json:"commissionRate,omitempty" binding:"omitempty,gte=0"`
 }
 
 type PackageDimensionsInput struct {
@@ -39,6 +60,33 @@ type PackageDimensionsInput struct {
 	Height float64 `json:"height" binding:"gte=0"`
 }
 
+// PreorderInput turns pre-orders on or off for a simple product. While on,
+// dropshippers can order up to Limit units beyond the stock; they are paid at
+// checkout and ship once the stock arrives (around AvailableAt).
+type PreorderInput struct {
+	Enabled     bool       `json:"enabled"`
+	AvailableAt *time.Time `json:"availableAt"`
+	Limit       *int       `json:"limit" binding:"omitempty,min=1"`
+}
+
+// validate checks the settings for a product that is (or will be) variable or not.
+func (in *PreorderInput) validate(isVariable bool) error {
+	if !in.Enabled {
+		return nil
+	}
+	switch {
+	case isVariable:
+		return errors.New("pre-orders are only available on simple products")
+	case in.Limit == nil:
+		return errors.New("preorder.limit is required")
+	case in.AvailableAt == nil:
+		return errors.New("preorder.availableAt is required")
+	case !in.AvailableAt.After(time.Now()):
+		return errors.New("preorder.availableAt must be in the future")
+	}
+	return nil
+}
+
 // CreateProductInput - Updated for Phase 8.2
 type CreateProductInput struct {
 	Name        string  `json:"name" binding:"required"`
@@ -49,6 +97,9 @@ type CreateProductInput struct {
 	CategoryIDs []int64 `json:"category_ids"`
 	IsVariable  bool    `json:"isVariable"`
 
+	// Tags label the product for curated collections; new ones are created.
+	Tags []string `json:"tags" binding:"max=20,dive,required,max=50"`
+
 	// --- New Media Fields ---
 	Images          []string               `json:"images"` // Array of URLs
 	VideoURL        string                 `json:"videoUrl"`
@@ -61,119 +112,203 @@ type CreateProductInput struct {
 	Weight            *float64                `json:"weight" binding:"omitempty,gt=0"`
 	PackageDimensions *PackageDimensionsInput `json:"packageDimensions,omitempty"`
 	CommissionRate    *float64                `json:"commissionRate,omitempty" binding:"omitempty,gte=0"`
+
+	// ShippingRestrictions flag batteries, liquids, fragile or oversize goods;
+	// couriers refusing one of them are excluded for the product.
+	ShippingRestrictions []string `json:"shippingRestrictions" binding:"omitempty,dive,oneof=battery liquid fragile oversize"`
+
+	// Order quantity rules for dropshippers (both default to 1):
+	// at least minOrderQty per order line, in multiples of orderIncrement.
+	MinOrderQty    *int `json:"minOrderQty" binding:"omitempty,min=1"`
+	OrderIncrement *int `json:"orderIncrement" binding:"omitempty,min=1"`
+
+	// ProcessingTime overrides the shop-wide handling days and cutoff.
+	ProcessingTime *ProcessingTimeInput `json:"processingTime,omitempty"`
+
+	Preorder *PreorderInput `json:"preorder,omitempty"`
 }
 
 // CreateProduct Handler
 func (h *Handlers) CreateProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		apierror.Unauthorized(c, "User ID not found")
 		return
 	}
 	supplierID := userID_raw.(int64)
 
 	var input CreateProductInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
+	input.sanitize()
 
 	// --- 1. Validation Logic ---
-	isDraft := input.Status == "draft" || input.Status == "private_inventory"
-	if !isDraft {
-		if input.Description == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Description is required for submission."})
-			return
-		}
-		if len(input.CategoryIDs) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Category is required."})
-			return
+	if err := input.validate(); err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "DB Transaction failed")
+		return
+	}
+	defer tx.Rollback()
+
+	// --- 2. Insert Product & Relations ---
+	product, err := h.createProduct(ctx, tx, supplierID, &input)
+	if err != nil {
+		var inputErr *productInputError
+		var skuErr *skuConflictError
+		switch {
+		case errors.As(err, &inputErr):
+			apierror.BadRequest(c, inputErr.message)
+		case errors.As(err, &skuErr):
+			respondSKUError(c, err)
+		case respondDuplicate(c, err, "This product already exists."):
+		default:
+			fmt.Printf("DB Error: %v\n", err)
+			apierror.Internal(c, "Failed to insert product")
 		}
-		if input.BrandID == nil && input.BrandName == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Brand is required."})
-			return
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Commit failed")
+		return
+	}
+	if input.BrandName != "" {
+		h.invalidateCache(ctx, cache.KeyBrandList) // the brand may have just been created
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Product saved", "productId": product.ID, "publicId": product.PublicID})
+}
+
+// checkMargins checks the SRP of a simple product, or of each variant of a
+// variable one, against its price (PRODUCT_MIN_MARGIN; see package pricing).
+func (h *Handlers) checkMargins(isVariable bool, simple *SimpleProductInput, variants []VariantInput) error {
+	minMargin := h.Config.Products.MinMargin
+	if !isVariable {
+		if simple == nil {
+			return nil
 		}
-		if len(input.Images) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "At least 1 product image is required."})
-			return
+		return pricing.Validate(simple.Price, simple.SRP, minMargin)
+	}
+	for _, v := range variants {
+		if err := pricing.Validate(v.Price, v.SRP, minMargin); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		if input.IsVariable {
-			if len(input.Variants) == 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Variants are required."})
-				return
-			}
-		} else {
-			if input.SimpleProduct == nil || input.SimpleProduct.Price <= 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Price is required."})
-				return
-			}
-		}
+// productInputError is a product the supplier described that cannot be saved
+// (unknown brand, bad processing time, ...); its message is the 400 response.
+type productInputError struct {
+	message string
+}
+
+func (e *productInputError) Error() string { return e.message }
+
+// sanitize cleans the free-text fields: name and brand are plain text, the
+// description is allowlisted HTML.
+func (in *CreateProductInput) sanitize() {
+	in.Name = sanitize.Text(in.Name)
+	in.BrandName = sanitize.Text(in.BrandName)
+	in.Description = sanitize.HTML(in.Description)
+	in.Tags = sanitizeTags(in.Tags)
+}
+
+// sanitizeTags cleans tag names as plain text.
+func sanitizeTags(tags []string) []string {
+	for i, t := range tags {
+		tags[i] = sanitize.Text(t)
 	}
+	return tags
+}
 
-	tx, err := h.DB.Begin()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB Transaction failed"})
-		return
+// validate checks what the binding tags cannot: the pre-order settings, and
+// the fields a product needs before it is submitted for review.
+func (in *CreateProductInput) validate() error {
+	if err := parseBarcodes(in.SimpleProduct, in.Variants); err != nil {
+		return err
 	}
-	defer tx.Rollback()
+	if in.Preorder != nil {
+		if err := in.Preorder.validate(in.IsVariable); err != nil {
+			return err
+		}
+	}
+	if in.Status == "draft" || in.Status == "private_inventory" {
+		return nil
+	}
+	switch {
+	case in.Description == "":
+		return errors.New("Description is required for submission.")
+	case len(in.CategoryIDs) == 0:
+		return errors.New("Category is required.")
+	case in.BrandID == nil && in.BrandName == "":
+		return errors.New("Brand is required.")
+	case len(in.Images) == 0:
+		return errors.New("At least 1 product image is required.")
+	case in.IsVariable && len(in.Variants) == 0:
+		return errors.New("Variants are required.")
+	case !in.IsVariable && (in.SimpleProduct == nil || in.SimpleProduct.Price <= 0):
+		return errors.New("Price is required.")
+	}
+	return nil
+}
 
-	// --- 2. Handle Brand ---
+// createProduct inserts a validated product with its brand, categories and
+// variants on tx, generating blank SKUs. It returns a *productInputError or a
+// *skuConflictError for what the supplier must fix, and the store's duplicate
+// key error for a product that already exists.
+func (h *Handlers) createProduct(ctx context.Context, tx *store.Tx, supplierID int64, input *CreateProductInput) (*models.Product, error) {
+	// --- 1. Handle Brand ---
 	var brandID int64
-	var brandNameLegacy string = "Generic"
+	var err error
 	if input.BrandID != nil || input.BrandName != "" {
-		brandID, err = h.getOrCreateBrandID(tx, input.BrandID, input.BrandName)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
+		brandID, err = tx.Products.GetOrCreateBrand(ctx, input.BrandID, input.BrandName)
+		if errors.Is(err, store.ErrInvalidBrand) {
+			return nil, &productInputError{err.Error()}
 		}
-		if input.BrandName != "" {
-			brandNameLegacy = input.BrandName
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	// --- 3. Prepare JSON Data ---
-	imagesJSON, _ := json.Marshal(input.Images)
-	sizeChartJSON, _ := json.Marshal(input.SizeChart)
-	variationImagesJSON, _ := json.Marshal(input.VariationImages)
-
-	// --- 4. Prepare Product Data ---
+	// --- 2. Prepare Product Data ---
+	now := time.Now()
 	product := &models.Product{
-		SupplierID:  supplierID,
-		Name:        input.Name,
-		Description: input.Description,
-		IsVariable:  input.IsVariable,
-		Status:      input.Status,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-		Images:      input.Images,
+		SupplierID:      supplierID,
+		Name:            input.Name,
+		Description:     input.Description,
+		IsVariable:      input.IsVariable,
+		Status:          input.Status,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Images:          input.Images,
+		VideoURL:        input.VideoURL,
+		SizeChart:       input.SizeChart,
+		VariationImages: input.VariationImages,
+		BrandName:       input.BrandName, // legacy text column
 	}
 
-	var srp float64 = 0
-	var weightGrams int = 0
-	var categoryLegacy string = "Uncategorized"
-
-	// [FIX]: Pointers for new Model
-	var commissionRate *float64
-	var sku *string
-
 	if !input.IsVariable && input.SimpleProduct != nil {
 		// SIMPLE PRODUCT
 		product.PriceToTTS = input.SimpleProduct.Price
 		product.StockQuantity = input.SimpleProduct.Stock
-
-		// Assign pointers directly (No sql.NullString needed!)
-		if input.SimpleProduct.SKU != "" {
-			val := input.SimpleProduct.SKU
-			sku = &val
-		}
-		srp = input.SimpleProduct.SRP
-		commissionRate = input.SimpleProduct.CommissionRate
+		product.SRP = input.SimpleProduct.SRP
+		product.CommissionRate = input.SimpleProduct.CommissionRate
+		product.Barcode = input.SimpleProduct.Barcode
 
 	} else if input.IsVariable && len(input.Variants) > 0 {
 		// VARIABLE PRODUCT: Roll-up logic
 		var totalStock int
-		var minPrice float64 = input.Variants[0].Price
+		minPrice := input.Variants[0].Price
 
 		for _, v := range input.Variants {
 			totalStock += v.Stock
@@ -183,199 +318,280 @@ func (h *Handlers) CreateProduct(c *gin.Context) {
 		}
 		product.PriceToTTS = minPrice
 		product.StockQuantity = totalStock
-		commissionRate = input.CommissionRate
+		product.CommissionRate = input.CommissionRate
+	}
+	if err := h.checkMargins(input.IsVariable, input.SimpleProduct, input.Variants); err != nil {
+		return nil, &productInputError{err.Error()}
 	}
 
-	// Assign Pointers directly
-	product.CommissionRate = commissionRate
-	product.SKU = sku
+	if input.Preorder != nil && input.Preorder.Enabled {
+		product.IsPreorder = true
+		product.PreorderAvailableAt = input.Preorder.AvailableAt
+		product.PreorderLimit = input.Preorder.Limit
+	}
 
-	// Dimensions (Direct Pointers)
-	var pkgLength, pkgWidth, pkgHeight *float64
+	// Dimensions
 	if input.Weight != nil {
 		product.Weight = input.Weight
-		weightGrams = int(*input.Weight * 1000)
+		product.WeightGrams = int(*input.Weight * 1000)
 	}
 	if input.PackageDimensions != nil {
 		l := input.PackageDimensions.Length
 		w := input.PackageDimensions.Width
 		h := input.PackageDimensions.Height
-		pkgLength = &l
-		pkgWidth = &w
-		pkgHeight = &h
-	}
-
-	// --- 5. INSERT QUERY ---
-	productQuery := `
-		INSERT INTO products
-		(supplier_id, name, description, price_to_tts, stock_quantity, sku, 
-		is_variable, status, created_at, updated_at, 
-		weight, pkg_length, pkg_width, pkg_height, commission_rate,
-		category, brand, srp, weight_grams,
-		images, video_url, size_chart, variation_images) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-	// [FIX]: Passing pointers directly. SQL driver handles nil automatically.
-	result, err := tx.Exec(productQuery,
-		product.SupplierID, product.Name, product.Description,
-		product.PriceToTTS, product.StockQuantity, product.SKU,
-		product.IsVariable, product.Status, product.CreatedAt, product.UpdatedAt,
-		product.Weight, pkgLength, pkgWidth, pkgHeight, product.CommissionRate,
-		categoryLegacy, brandNameLegacy, srp, weightGrams,
-		string(imagesJSON), input.VideoURL, string(sizeChartJSON), string(variationImagesJSON),
-	)
-	if err != nil {
-		fmt.Printf("DB Error: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to insert product"})
-		return
+		product.PkgLength = &l
+		product.PkgWidth = &w
+		product.PkgHeight = &h
 	}
-	productID, err := result.LastInsertId()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ID"})
-		return
+	product.ShippingRestrictions = input.ShippingRestrictions
+	product.MinOrderQty, product.OrderIncrement = 1, 1
+	if input.MinOrderQty != nil {
+		product.MinOrderQty = *input.MinOrderQty
+	}
+	if input.OrderIncrement != nil {
+		product.OrderIncrement = *input.OrderIncrement
+	}
+	if input.ProcessingTime != nil {
+		cutoff, err := input.ProcessingTime.validate()
+		if err != nil {
+			return nil, &productInputError{err.Error()}
+		}
+		product.HandlingDays, product.OrderCutoff = input.ProcessingTime.HandlingDays, cutoff
 	}
 
-	// --- 6. Link Relations ---
-	if len(input.CategoryIDs) > 0 {
-		catQ := `INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)`
-		for _, cid := range input.CategoryIDs {
-			tx.Exec(catQ, productID, cid)
+	// --- 2b. SKUs (blank ones are generated) ---
+	var skus []*string
+	var variants []models.ProductVariant
+	if product.IsVariable {
+		variants, err = variantModels(input.Variants)
+		if err != nil {
+			return nil, &productInputError{err.Error()}
+		}
+		for i := range variants {
+			skus = append(skus, variants[i].SKU)
 		}
+	} else {
+		sku := ""
+		if input.SimpleProduct != nil {
+			sku = input.SimpleProduct.SKU
+		}
+		product.SKU = &sku
+		skus = append(skus, product.SKU)
 	}
-	if brandID != 0 {
-		tx.Exec(`INSERT INTO product_brands (product_id, brand_id) VALUES (?, ?)`, productID, brandID)
+	category, err := skuCategory(ctx, tx, 0, input.CategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.assignSKUs(ctx, tx, supplierID, 0, category, skus); err != nil {
+		return nil, err
+	}
+	if err := checkBarcodes(ctx, tx, supplierID, 0, productBarcodes(product, variants)); err != nil {
+		return nil, err
 	}
 
-	// --- 7. Handle Variants ---
-	if product.IsVariable && len(input.Variants) > 0 {
-		varQ := `INSERT INTO product_variants (product_id, sku, price_to_tts, stock_quantity, options, commission_rate, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-		for _, v := range input.Variants {
-			optJSON, _ := json.Marshal(v.Options)
-			var vSku *string
-			if v.SKU != "" {
-				s := v.SKU
-				vSku = &s
-			}
-			// Pass pointers directly
-			tx.Exec(varQ, productID, vSku, v.Price, v.Stock, string(optJSON), v.CommissionRate, time.Now(), time.Now())
-		}
+	// --- 3. Insert Product ---
+	if err := tx.Products.Create(ctx, product); err != nil {
+		return nil, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Commit failed"})
-		return
+	// --- 4. Link Relations ---
+	if err := tx.Products.SetCategories(ctx, product.ID, input.CategoryIDs); err != nil {
+		return nil, fmt.Errorf("link categories: %w", err)
+	}
+	if brandID != 0 {
+		if err := tx.Products.SetBrand(ctx, product.ID, brandID); err != nil {
+			return nil, fmt.Errorf("link brand: %w", err)
+		}
+	}
+	if err := tx.Products.SetTags(ctx, product.ID, input.Tags); err != nil {
+		if errors.Is(err, store.ErrInvalidTag) {
+			return nil, &productInputError{err.Error()}
+		}
+		return nil, fmt.Errorf("link tags: %w", err)
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Product saved", "productId": productID})
+	// --- 5. Handle Variants ---
+	if product.IsVariable {
+		if err := tx.Products.SetVariants(ctx, product.ID, variants); err != nil {
+			return nil, fmt.Errorf("save variants: %w", err)
+		}
+	}
+	return product, nil
 }
 
-// getOrCreateBrandID (Helper)
-func (h *Handlers) getOrCreateBrandID(tx *sql.Tx, brandID *int64, brandName string) (int64, error) {
-	if brandID != nil {
-		var exists int
-		err := tx.QueryRow("SELECT 1 FROM brands WHERE id = ?", *brandID).Scan(&exists)
+// variantModels converts the request variants into rows for the store.
+// Every variant gets a SKU pointer; blank ones are filled by assignSKUs.
+// A nil Barcode is one the request left out.
+func variantModels(inputs []VariantInput) ([]models.ProductVariant, error) {
+	variants := make([]models.ProductVariant, 0, len(inputs))
+	for i, v := range inputs {
+		optJSON, err := json.Marshal(v.Options)
 		if err != nil {
-			// FIXED: lowercase "invalid"
-			return 0, errors.New("invalid brandId")
+			return nil, fmt.Errorf("variant %d options: %w", i, err)
+		}
+		sku := v.SKU
+		var id int64
+		if v.ID != nil {
+			id = *v.ID
+		}
+		variants = append(variants, models.ProductVariant{
+			ID:             id,
+			SKU:            &sku,
+			Barcode:        v.Barcode,
+			PriceToTTS:     v.Price,
+			StockQuantity:  v.Stock,
+			Options:        string(optJSON),
+			CommissionRate: v.CommissionRate,
+		})
+	}
+	return variants, nil
+}
+
+// variantDiff is how an update changes a product's variants.
+type variantDiff struct {
+	update []models.ProductVariant // existing variants that changed, with their IDs
+	add    []models.ProductVariant
+	remove []int64
+}
+
+// diffVariants matches the variants of an update to the product's existing
+// ones: by ID when sent, otherwise by SKU; the rest are new, and existing
+// variants left out are removed. A matched variant sent with a blank SKU keeps
+// its SKU, and one sent without a barcode its barcode. Published products keep their variant prices (priceLocked), like
+// their product price, which only changes through a price appeal.
+func diffVariants(existing, variants []models.ProductVariant, priceLocked bool) (variantDiff, error) {
+	byID := make(map[int64]models.ProductVariant, len(existing))
+	bySKU := map[string]int64{}
+	for _, v := range existing {
+		byID[v.ID] = v
+		if v.SKU != nil && *v.SKU != "" {
+			bySKU[*v.SKU] = v.ID
 		}
-		return *brandID, nil
 	}
-	if brandName != "" {
-		var existingID int64
-		slug := slug.Make(brandName)
-		err := tx.QueryRow("SELECT id FROM brands WHERE slug = ?", slug).Scan(&existingID)
-		if err == nil {
-			return existingID, nil
+
+	var diff variantDiff
+	matched := map[int64]bool{}
+	for i := range variants {
+		v := &variants[i]
+		*v.SKU = strings.TrimSpace(*v.SKU)
+		if v.ID == 0 {
+			v.ID = bySKU[*v.SKU]
+		}
+		if v.ID == 0 {
+			diff.add = append(diff.add, *v)
+			continue
 		}
 
-		res, err := tx.Exec(`INSERT INTO brands (name, slug) VALUES (?, ?)`, brandName, slug)
-		if err != nil {
-			return 0, err
+		old, ok := byID[v.ID]
+		if !ok {
+			return variantDiff{}, &productInputError{fmt.Sprintf("Variant %d does not belong to this product.", v.ID)}
+		}
+		if matched[v.ID] {
+			return variantDiff{}, &productInputError{fmt.Sprintf("Variant %d is listed more than once.", v.ID)}
+		}
+		matched[v.ID] = true
+		v.ProductID = old.ProductID
+		if *v.SKU == "" && old.SKU != nil {
+			*v.SKU = *old.SKU
+		}
+		if v.Barcode == nil {
+			v.Barcode = old.Barcode
 		}
-		return res.LastInsertId()
+		if priceLocked && v.PriceToTTS != old.PriceToTTS {
+			return variantDiff{}, &productInputError{fmt.Sprintf("The price of variant %d cannot change while the product is published; request a price change instead.", v.ID)}
+		}
+		if !variantChanged(old, *v) {
+			continue
+		}
+		diff.update = append(diff.update, *v)
+	}
+
+	for _, v := range existing {
+		if !matched[v.ID] {
+			diff.remove = append(diff.remove, v.ID)
+		}
+	}
+	return diff, nil
+}
+
+// variantChanged reports whether the update v rewrites anything of old.
+// A blank SKU (filled later by assignSKUs) counts as a change.
+func variantChanged(old, v models.ProductVariant) bool {
+	sameRate := (old.CommissionRate == nil) == (v.CommissionRate == nil) &&
+		(old.CommissionRate == nil || *old.CommissionRate == *v.CommissionRate)
+	return old.SKU == nil || *old.SKU != *v.SKU || old.PriceToTTS != v.PriceToTTS ||
+		old.StockQuantity != v.StockQuantity || !jsonEqual(old.Options, v.Options) || !sameRate ||
+		!sameBarcode(old.Barcode, v.Barcode)
+}
+
+// productBarcodes lists the barcodes of a product save: the simple product's,
+// or its variants'.
+func productBarcodes(p *models.Product, variants []models.ProductVariant) []*string {
+	if !p.IsVariable {
+		return []*string{p.Barcode}
+	}
+	codes := make([]*string, 0, len(variants))
+	for _, v := range variants {
+		codes = append(codes, v.Barcode)
+	}
+	return codes
+}
+
+// jsonEqual compares two JSON documents by value, so key order and spacing
+// from the database do not count as a change.
+func jsonEqual(a, b string) bool {
+	var x, y interface{}
+	if json.Unmarshal([]byte(a), &x) != nil || json.Unmarshal([]byte(b), &y) != nil {
+		return a == b
 	}
-	// FIXED: Lowercase "brand"
-	return 0, errors.New("brand required")
+	return reflect.DeepEqual(x, y)
+}
+
+// productCursor is the pagination key for product listings.
+func productCursor(p *models.Product) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
 }
 
 // GetMyProducts (Updated to fetch Images)
 func (h *Handlers) GetMyProducts(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		apierror.Unauthorized(c, "User ID not found in context")
 		return
 	}
 	supplierID := userID_raw.(int64)
 
-	statusFilter := c.Query("status")
-
-	// [FIX] Added 'images' to the SELECT query
-	query := `
-		SELECT 
-			id, supplier_id, sku, name, description, price_to_tts, stock_quantity, 
-			is_variable, status, created_at, updated_at,
-			weight, pkg_length, pkg_width, pkg_height, commission_rate,
-			images
-		FROM products
-		WHERE supplier_id = ?`
-
-	args := []interface{}{supplierID}
-
-	if statusFilter != "" {
-		query += " AND status = ?"
-		args = append(args, statusFilter)
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"status"}})
+	if !ok {
+		return
 	}
+	page := list.Page
+	statusFilter := list.Filter("status")
 
-	query += " ORDER BY created_at DESC"
-
-	rows, err := h.DB.Query(query, args...)
+	products, err := h.Store.Products.ListBySupplier(ctx, supplierID, statusFilter, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
-		return
-	}
-	defer rows.Close()
-
-	var products []*models.Product
-
-	for rows.Next() {
-		var product models.Product
-		var dbImages []byte // [FIX] Buffer for the JSON string
-
-		if err := rows.Scan(
-			&product.ID,
-			&product.SupplierID,
-			&product.SKU,
-			&product.Name,
-			&product.Description,
-			&product.PriceToTTS,
-			&product.StockQuantity,
-			&product.IsVariable,
-			&product.Status,
-			&product.CreatedAt,
-			&product.UpdatedAt,
-			&product.Weight,
-			&product.PkgLength,
-			&product.PkgWidth,
-			&product.PkgHeight,
-			&product.CommissionRate,
-			&dbImages, // [FIX] Scan images
-		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan product row"})
-			return
-		}
-
-		// [FIX] Parse the JSON images
-		if len(dbImages) > 0 {
-			json.Unmarshal(dbImages, &product.Images)
-		} else {
-			product.Images = []string{}
-		}
+		apierror.Internal(c, "Database query failed")
+		return
+	}
+	products, nextCursor := pagination.Paginate(page, products, productCursor)
+	total, err := h.Store.Products.CountBySupplier(ctx, supplierID, statusFilter)
+	if err != nil {
+		apierror.Internal(c, "Database query failed")
+		return
+	}
 
-		products = append(products, &product)
+	// Attach Categories, Brands & Variants (one query per relation)
+	if err := h.Store.Products.LoadRelations(ctx, products); err != nil {
+		apierror.Internal(c, "Failed to load product relations")
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"products": products,
+		"products":   products,
+		"total":      total,
+		"nextCursor": nextCursor,
 	})
 }
 
@@ -394,6 +610,9 @@ type UpdateProductInput struct {
 
 	CategoryIDs *[]int64 `json:"category_ids"`
 
+	// Tags replaces the product's tags ([] clears them).
+	Tags *[]string `json:"tags" binding:"omitempty,max=20,dive,required,max=50"`
+
 	// --- MISSING FIELDS ADDED BELOW ---
 	Images          *[]string               `json:"images"`          // Pointer to array
 	VideoURL        *string                 `json:"videoUrl"`        // Pointer to string
@@ -408,117 +627,176 @@ type UpdateProductInput struct {
 
 	Weight            *float64                `json:"weight" binding:"omitempty,gt=0"`
 	PackageDimensions *PackageDimensionsInput `json:"packageDimensions,omitempty"`
+
+	// ShippingRestrictions replaces the product's restrictions ([] clears them).
+	ShippingRestrictions *[]string `json:"shippingRestrictions" binding:"omitempty,dive,oneof=battery liquid fragile oversize"`
+
+	// Order quantity rules; cart lines that no longer fit are refused at checkout.
+	MinOrderQty    *int `json:"minOrderQty" binding:"omitempty,min=1"`
+	OrderIncrement *int `json:"orderIncrement" binding:"omitempty,min=1"`
+
+	// ProcessingTime replaces the product's override (null fields use the shop-wide setting).
+	ProcessingTime *ProcessingTimeInput `json:"processingTime,omitempty"`
+
+	// Preorder replaces the pre-order settings; turning them off keeps the open
+	// pre-orders, which still convert when the stock arrives.
+	Preorder *PreorderInput `json:"preorder,omitempty"`
+
+	// Version is the product version the edit form loaded. When sent, the update
+	// is rejected with 409 if someone else changed the product in the meantime.
+	Version *int `json:"version"`
 }
 
 // 2. Update the Handler to Process these fields
 func (h *Handlers) UpdateProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	supplierID := userID_raw.(int64)
-	productIDStr := c.Param("id")
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found or you do not have permission to edit it")
+		return
+	}
 
 	// Check ownership
-	var currentProduct models.Product
-	err := h.DB.QueryRow("SELECT id, status, price_to_tts, is_variable FROM products WHERE id = ? AND supplier_id = ?", productIDStr, supplierID).Scan(
-		&currentProduct.ID,
-		&currentProduct.Status,
-		&currentProduct.PriceToTTS,
-		&currentProduct.IsVariable,
-	)
+	currentProduct, err := h.Store.Products.GetOwned(ctx, productID, supplierID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found or you do not have permission to edit it"})
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.NotFound(c, "Product not found or you do not have permission to edit it")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error checking ownership"})
+		apierror.Internal(c, "Database error checking ownership")
 		return
 	}
 
 	var input UpdateProductInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
-	tx, err := h.DB.Begin()
+	h.updateProduct(c, currentProduct, supplierID, &input)
+}
+
+// updateProduct applies input to currentProduct and writes the response.
+// editorID is recorded in the revision history; when it is not the product's
+// supplier (a manager editing during review), the supplier is notified.
+func (h *Handlers) updateProduct(c *gin.Context, currentProduct *models.Product, editorID int64, input *UpdateProductInput) {
+	ctx := c.Request.Context()
+	productID := currentProduct.ID
+
+	tx, err := h.Store.Begin(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
 
-	// --- Dynamic SQL Builder ---
-	querySet := "updated_at = ?"
-	queryArgs := []interface{}{time.Now()}
+	// The revision history keeps the product as it was before this update.
+	before, err := tx.Products.Snapshot(ctx, productID)
+	if err != nil {
+		apierror.Internal(c, "Failed to load product")
+		return
+	}
+
+	// --- Collect Changed Columns ---
+	changes := map[string]interface{}{}
 
 	// Standard Fields
 	if input.Name != nil {
-		querySet += ", name = ?"
-		queryArgs = append(queryArgs, *input.Name)
+		changes["name"] = sanitize.Text(*input.Name)
 	}
 	if input.Description != nil {
-		querySet += ", description = ?"
-		queryArgs = append(queryArgs, *input.Description)
+		changes["description"] = sanitize.HTML(*input.Description)
 	}
 	if input.Status != nil {
-		querySet += ", status = ?"
-		queryArgs = append(queryArgs, *input.Status)
+		changes["status"] = *input.Status
 	}
 	if input.IsVariable != nil {
-		querySet += ", is_variable = ?"
-		queryArgs = append(queryArgs, *input.IsVariable)
+		changes["is_variable"] = *input.IsVariable
 		currentProduct.IsVariable = *input.IsVariable // Update local tracking
 	}
 
-	// --- MEDIA FIELDS (These were missing!) ---
+	// --- Pre-order ---
+	if input.Preorder != nil {
+		if err := input.Preorder.validate(currentProduct.IsVariable); err != nil {
+			apierror.BadRequest(c, err.Error())
+			return
+		}
+		changes["is_preorder"] = input.Preorder.Enabled
+		changes["preorder_available_at"] = input.Preorder.AvailableAt
+		changes["preorder_limit"] = input.Preorder.Limit
+		if !input.Preorder.Enabled {
+			changes["preorder_available_at"], changes["preorder_limit"] = nil, nil
+		}
+		currentProduct.IsPreorder = input.Preorder.Enabled
+	}
+	// Pre-order lines have no variant, so a product cannot become variable while it takes them.
+	if currentProduct.IsVariable && (currentProduct.IsPreorder || currentProduct.PreorderReserved > 0) {
+		apierror.BadRequest(c, "Pre-orders are only available on simple products; turn them off and let open pre-orders finish first")
+		return
+	}
+
+	// --- Media Fields ---
 	if input.Images != nil {
 		imagesJSON, _ := json.Marshal(*input.Images)
-		querySet += ", images = ?"
-		queryArgs = append(queryArgs, string(imagesJSON))
+		changes["images"] = string(imagesJSON)
 	}
 	if input.VideoURL != nil {
-		querySet += ", video_url = ?"
-		queryArgs = append(queryArgs, *input.VideoURL)
+		changes["video_url"] = *input.VideoURL
 	}
 	if input.SizeChart != nil {
 		chartJSON, _ := json.Marshal(*input.SizeChart)
-		querySet += ", size_chart = ?"
-		queryArgs = append(queryArgs, string(chartJSON))
+		changes["size_chart"] = string(chartJSON)
 	}
 	if input.VariationImages != nil {
 		varImgJSON, _ := json.Marshal(*input.VariationImages)
-		querySet += ", variation_images = ?"
-		queryArgs = append(queryArgs, string(varImgJSON))
+		changes["variation_images"] = string(varImgJSON)
 	}
 
 	// --- Dimensions ---
 	if input.Weight != nil {
-		querySet += ", weight = ?"
-		queryArgs = append(queryArgs, *input.Weight)
-		// Auto update grams
-		querySet += ", weight_grams = ?"
-		queryArgs = append(queryArgs, int(*input.Weight*1000))
+		changes["weight"] = *input.Weight
+		changes["weight_grams"] = int(*input.Weight * 1000) // Auto update grams
 	}
 	if input.PackageDimensions != nil {
-		querySet += ", pkg_length = ?, pkg_width = ?, pkg_height = ?"
-		queryArgs = append(queryArgs, input.PackageDimensions.Length, input.PackageDimensions.Width, input.PackageDimensions.Height)
+		changes["pkg_length"] = input.PackageDimensions.Length
+		changes["pkg_width"] = input.PackageDimensions.Width
+		changes["pkg_height"] = input.PackageDimensions.Height
+	}
+	if input.ShippingRestrictions != nil {
+		changes["shipping_restrictions"] = shipping.Join(*input.ShippingRestrictions)
+	}
+	if input.MinOrderQty != nil {
+		changes["min_order_qty"] = *input.MinOrderQty
+	}
+	if input.OrderIncrement != nil {
+		changes["order_increment"] = *input.OrderIncrement
+	}
+	if input.ProcessingTime != nil {
+		cutoff, err := input.ProcessingTime.validate()
+		if err != nil {
+			apierror.BadRequest(c, err.Error())
+			return
+		}
+		changes["handling_days"], changes["order_cutoff"] = input.ProcessingTime.HandlingDays, cutoff
 	}
 
 	// --- Simple vs Variable Logic ---
 	// Note: We use the *current* state of the product unless input.IsVariable changed it
 	if !currentProduct.IsVariable && input.SimpleProduct != nil {
-		querySet += ", price_to_tts = ?, stock_quantity = ?, sku = ?"
-		queryArgs = append(queryArgs, input.SimpleProduct.Price, input.SimpleProduct.Stock, input.SimpleProduct.SKU)
-		querySet += ", srp = ?" // Update SRP too
-		queryArgs = append(queryArgs, input.SimpleProduct.SRP)
+		changes["price_to_tts"] = input.SimpleProduct.Price
+		changes["stock_quantity"] = input.SimpleProduct.Stock
+		changes["srp"] = input.SimpleProduct.SRP
 
 		if input.SimpleProduct.CommissionRate != nil {
-			querySet += ", commission_rate = ?"
-			queryArgs = append(queryArgs, *input.SimpleProduct.CommissionRate)
+			changes["commission_rate"] = *input.SimpleProduct.CommissionRate
 		}
 	} else if currentProduct.IsVariable && input.Variants != nil {
 		// Calculate Roll-up values for Variable Products
 		var totalStock int
-		var minPrice float64 = 0
+		var minPrice money.Money
 		if len(*input.Variants) > 0 {
 			minPrice = (*input.Variants)[0].Price
 		}
@@ -529,42 +807,129 @@ func (h *Handlers) UpdateProduct(c *gin.Context) {
 				minPrice = v.Price
 			}
 		}
-		querySet += ", price_to_tts = ?, stock_quantity = ?"
-		queryArgs = append(queryArgs, minPrice, totalStock)
+		changes["price_to_tts"] = minPrice
+		changes["stock_quantity"] = totalStock
 
 		if input.CommissionRate != nil {
-			querySet += ", commission_rate = ?"
-			queryArgs = append(queryArgs, *input.CommissionRate)
+			changes["commission_rate"] = *input.CommissionRate
+		}
+	}
+	var variantInputs []VariantInput
+	if input.Variants != nil {
+		variantInputs = *input.Variants
+	}
+	if err := h.checkMargins(currentProduct.IsVariable, input.SimpleProduct, variantInputs); err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+	if err := parseBarcodes(input.SimpleProduct, variantInputs); err != nil {
+		apierror.BadRequest(c, err.Error())
+		return
+	}
+
+	// --- SKUs (a blank one keeps the current SKU, or is generated) ---
+	var skus []*string
+	var variants []models.ProductVariant
+	var diff variantDiff
+	simpleSKU := ""
+	if !currentProduct.IsVariable && input.SimpleProduct != nil {
+		simpleSKU = input.SimpleProduct.SKU
+		if strings.TrimSpace(simpleSKU) == "" && currentProduct.SKU != nil {
+			simpleSKU = *currentProduct.SKU
+		}
+		skus = append(skus, &simpleSKU)
+	} else if currentProduct.IsVariable && input.Variants != nil {
+		variants, err = variantModels(*input.Variants)
+		if err != nil {
+			apierror.BadRequest(c, err.Error())
+			return
+		}
+		existing, err := tx.Products.VariantsForUpdate(ctx, productID)
+		if err != nil {
+			apierror.Internal(c, "Failed to load variants")
+			return
+		}
+		diff, err = diffVariants(existing, variants, currentProduct.Status == "active")
+		if err != nil {
+			apierror.BadRequest(c, err.Error())
+			return
+		}
+		for i := range variants {
+			skus = append(skus, variants[i].SKU)
 		}
 	}
+	if len(skus) > 0 {
+		var categoryIDs []int64
+		if input.CategoryIDs != nil {
+			categoryIDs = *input.CategoryIDs
+		}
+		category, err := skuCategory(ctx, tx, productID, categoryIDs)
+		if err != nil {
+			apierror.Internal(c, "Failed to assign SKUs")
+			return
+		}
+		if err := h.assignSKUs(ctx, tx, currentProduct.SupplierID, productID, category, skus); err != nil {
+			respondSKUError(c, err)
+			return
+		}
+		if !currentProduct.IsVariable {
+			changes["sku"] = simpleSKU
+		}
 
-	// Execute Main Product Update
-	queryArgs = append(queryArgs, productIDStr) // Add ID for WHERE clause
-	query := fmt.Sprintf("UPDATE products SET %s WHERE id = ?", querySet)
+		// Barcodes, under the same lock (a simple product sent without one keeps its own)
+		var codes []*string
+		if currentProduct.IsVariable {
+			codes = productBarcodes(currentProduct, variants)
+		} else if code := input.SimpleProduct.Barcode; code != nil {
+			codes = []*string{code}
+			changes["barcode"] = sql.NullString{String: *code, Valid: *code != ""}
+		}
+		if err := checkBarcodes(ctx, tx, currentProduct.SupplierID, productID, codes); err != nil {
+			respondSKUError(c, err)
+			return
+		}
+	}
 
-	_, err = tx.Exec(query, queryArgs...)
-	if err != nil {
+	// Execute Main Product Update (optimistic lock on the version)
+	version := currentProduct.Version
+	if input.Version != nil {
+		version = *input.Version
+	}
+	if err := tx.Products.Update(ctx, productID, version, changes); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			apierror.Conflict(c, "Product was modified by someone else. Reload and try again.")
+			return
+		}
+		if respondDuplicate(c, err, "This product already exists.") {
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.NotFound(c, "Product not found or you do not have permission to edit it")
+			return
+		}
 		fmt.Printf("SQL Error: %v\n", err) // Debug log
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update core product details"})
+		apierror.Internal(c, "Failed to update core product details")
 		return
 	}
 
 	// --- Categories Update ---
 	if input.CategoryIDs != nil {
-		_, err := tx.Exec("DELETE FROM product_categories WHERE product_id = ?", productIDStr)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear old categories"})
+		if err := tx.Products.SetCategories(ctx, productID, *input.CategoryIDs); err != nil {
+			apierror.Internal(c, "Failed to update categories")
 			return
 		}
-		if len(*input.CategoryIDs) > 0 {
-			categoryQuery := `INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)`
-			for _, catID := range *input.CategoryIDs {
-				_, err := tx.Exec(categoryQuery, productIDStr, catID)
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link new category"})
-					return
-				}
-			}
+	}
+
+	// --- Tags Update ---
+	if input.Tags != nil {
+		err := tx.Products.SetTags(ctx, productID, sanitizeTags(*input.Tags))
+		if errors.Is(err, store.ErrInvalidTag) {
+			apierror.BadRequest(c, err.Error())
+			return
+		}
+		if err != nil {
+			apierror.Internal(c, "Failed to update tags")
+			return
 		}
 	}
 
@@ -574,49 +939,60 @@ func (h *Handlers) UpdateProduct(c *gin.Context) {
 		if input.BrandName != nil {
 			brandNameStr = *input.BrandName
 		}
-		newBrandID, err := h.getOrCreateBrandID(tx, input.BrandID, brandNameStr)
+		newBrandID, err := tx.Products.GetOrCreateBrand(ctx, input.BrandID, brandNameStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			apierror.BadRequest(c, err.Error())
 			return
 		}
-		// Upsert logic for brand link
-		_, err = tx.Exec("DELETE FROM product_brands WHERE product_id = ?", productIDStr)
-		_, err = tx.Exec("INSERT INTO product_brands (product_id, brand_id) VALUES (?, ?)", productIDStr, newBrandID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update brand link"})
+		if err := tx.Products.SetBrand(ctx, productID, newBrandID); err != nil {
+			apierror.Internal(c, "Failed to update brand link")
 			return
 		}
 	}
 
-	// --- Variant Update (Full Replace Strategy) ---
-	// If variants are provided, we replace them to ensure consistency
+	// --- Variant Update (Diff) ---
+	// Existing variants keep their IDs, so cart lines and order history still
+	// point at them; only the removed ones leave the carts.
 	if currentProduct.IsVariable && input.Variants != nil {
-		_, err := tx.Exec("DELETE FROM product_variants WHERE product_id = ?", productIDStr)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear old variants"})
+		for _, v := range diff.update {
+			if err := tx.Products.UpdateVariant(ctx, v); err != nil {
+				apierror.Internal(c, "Failed to save variants")
+				return
+			}
+		}
+		if err := tx.Products.DeleteVariants(ctx, productID, diff.remove); err != nil {
+			apierror.Internal(c, "Failed to save variants")
 			return
 		}
+		if err := tx.Products.AddVariants(ctx, productID, diff.add); err != nil {
+			apierror.Internal(c, "Failed to save variants")
+			return
+		}
+	}
 
-		varQ := `INSERT INTO product_variants (product_id, sku, price_to_tts, stock_quantity, options, commission_rate, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-		for _, v := range *input.Variants {
-			optJSON, _ := json.Marshal(v.Options)
-			var vSku *string
-			if v.SKU != "" {
-				s := v.SKU
-				vSku = &s
-			}
-			_, err := tx.Exec(varQ, productIDStr, vSku, v.Price, v.Stock, string(optJSON), v.CommissionRate, time.Now(), time.Now())
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save variants"})
-				return
-			}
+	if err := h.recordRevision(ctx, tx, productID, editorID, "update", nil, nil, before); err != nil {
+		apierror.Internal(c, "Failed to record revision")
+		return
+	}
+	if editorID != currentProduct.SupplierID {
+		message := fmt.Sprintf("A manager edited your product \"%s\" during review. See its revision history for the changes.", currentProduct.Name)
+		if err := h.AddNotification(ctx, tx, currentProduct.SupplierID, message, "/supplier/products"); err != nil {
+			apierror.Internal(c, "Failed to notify supplier")
+			return
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		apierror.Internal(c, "Failed to commit transaction")
 		return
 	}
+	h.invalidateProducts(ctx, productID)
+	if input.BrandName != nil && *input.BrandName != "" {
+		h.invalidateCache(ctx, cache.KeyBrandList) // the brand may have just been created
+	}
+	if stock, ok := changes["stock_quantity"].(int); ok && !currentProduct.IsVariable {
+		h.Events.Publish(ctx, events.ProductRestocked{ProductID: productID, Stock: stock})
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Product updated successfully",
@@ -625,250 +1001,410 @@ func (h *Handlers) UpdateProduct(c *gin.Context) {
 
 // DeleteProduct and SearchProducts (Include SearchProducts and RequestPriceChange logic from previous file)
 func (h *Handlers) DeleteProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	supplierID := userID_raw.(int64)
 
-	productIDStr := c.Param("id")
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found or you do not have permission to delete it")
+		return
+	}
 
-	query := "DELETE FROM products WHERE id = ? AND supplier_id = ?"
+	deleted, err := h.Store.Products.Delete(ctx, productID, supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to delete product")
+		return
+	}
+	if !deleted {
+		apierror.NotFound(c, "Product not found or you do not have permission to delete it")
+		return
+	}
+	h.invalidateProducts(ctx, productID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product deleted successfully",
+	})
+}
+
+// RestoreMyProduct is the handler for POST /v1/products/:id/restore
+// It brings back one of the supplier's deleted products (GET /supplier/products?filter[status]=deleted
+// lists them) until a manager purges it or the retention job does.
+func (h *Handlers) RestoreMyProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
 
-	result, err := h.DB.Exec(query, productIDStr, supplierID)
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product"})
+		apierror.NotFound(c, "Product not found or not deleted")
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	restored, err := h.Store.Products.Restore(ctx, productID, supplierID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check affected rows"})
+		apierror.Internal(c, "Failed to restore product")
 		return
 	}
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found or you do not have permission to delete it"})
+	if !restored {
+		apierror.NotFound(c, "Product not found or not deleted")
 		return
 	}
+	h.invalidateProducts(ctx, productID)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Product deleted successfully",
+		"message": "Product restored successfully",
 	})
 }
 
-// [FIXED] SearchProducts with Images and Variants
-func (h *Handlers) SearchProducts(c *gin.Context) {
-	q := c.Query("q")
-	categoryID := c.Query("category")
-	brandID := c.Query("brand")
-	minPrice := c.Query("min_price")
-	maxPrice := c.Query("max_price")
+// ResubmitProduct is the handler for POST /v1/products/:id/resubmit
+// After fixing a rejected product (PUT /products/:id), the supplier sends it
+// back to review. The rejection reason stays on it for the reviewer, and the
+// rejection and resubmission stay in its revision history.
+func (h *Handlers) ResubmitProduct(c *gin.Context) {
+	ctx := c.Request.Context()
 
-	var queryBuilder strings.Builder
-	var args []interface{}
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
 
-	// 1. SELECT - Added p.images and p.variation_images
-	queryBuilder.WriteString(`
-        SELECT DISTINCT
-            p.id, p.supplier_id, p.sku, p.name, p.description,
-            p.price_to_tts, p.stock_quantity, p.srp, p.is_variable, p.status,
-            p.created_at, p.updated_at,
-            p.weight, p.pkg_length, p.pkg_width, p.pkg_height, p.commission_rate,
-            p.images, p.variation_images
-        FROM products p
-    `)
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
 
-	if categoryID != "" {
-		queryBuilder.WriteString(" JOIN product_categories pc ON p.id = pc.product_id")
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
 	}
-	if brandID != "" {
-		queryBuilder.WriteString(" JOIN product_brands pb ON p.id = pb.product_id")
+	defer tx.Rollback()
+
+	// 1. --- Lock & Check Product ---
+	current, err := tx.Products.GetForUpdate(ctx, productID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && current.SupplierID != supplierID) {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch product")
+		return
+	}
+	if current.Status != "rejected" {
+		apierror.Conflict(c, "Only rejected products can be resubmitted")
+		return
+	}
+	before, err := tx.Products.Snapshot(ctx, productID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch product")
+		return
 	}
 
-	// 2. Filter by 'active'
-	queryBuilder.WriteString(" WHERE p.status = ?")
-	args = append(args, "active")
+	// 2. --- Back to Review ---
+	if err := tx.Products.Update(ctx, productID, current.Version, map[string]interface{}{"status": "pending"}); err != nil {
+		apierror.Internal(c, "Failed to resubmit product")
+		return
+	}
+	if err := h.recordRevision(ctx, tx, productID, supplierID, "resubmit", nil, nil, before); err != nil {
+		apierror.Internal(c, "Failed to record revision")
+		return
+	}
 
-	if categoryID != "" {
-		queryBuilder.WriteString(" AND pc.category_id = ?")
-		args = append(args, categoryID)
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
 	}
-	if brandID != "" {
-		queryBuilder.WriteString(" AND pb.brand_id = ?")
-		args = append(args, brandID)
+	h.invalidateProducts(ctx, productID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product resubmitted for review",
+	})
+}
+
+// DuplicateProduct is the handler for POST /v1/products/:id/duplicate
+// It copies one of the supplier's products (content, variants, images,
+// category and brand links) into a new draft. SKUs get the first free "-N"
+// suffix; stock starts at 0, as the copy is a different item to count.
+func (h *Handlers) DuplicateProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
 	}
-	if minPrice != "" {
-		queryBuilder.WriteString(" AND p.price_to_tts >= ?")
-		args = append(args, minPrice)
+
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "DB Transaction failed")
+		return
 	}
-	if maxPrice != "" {
-		queryBuilder.WriteString(" AND p.price_to_tts <= ?")
-		args = append(args, maxPrice)
+	defer tx.Rollback()
+
+	// 1. --- Load the Original ---
+	source, err := tx.Products.Get(ctx, productID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && source.SupplierID != supplierID) {
+		apierror.NotFound(c, "Product not found")
+		return
 	}
-	if q != "" {
-		queryBuilder.WriteString(" AND (p.name LIKE ? OR p.description LIKE ?)")
-		searchTerm := "%" + q + "%"
-		args = append(args, searchTerm, searchTerm)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch product")
+		return
 	}
 
-	queryBuilder.WriteString(" ORDER BY p.created_at DESC")
+	// 2. --- Copy as a Draft ---
+	now := time.Now()
+	product := *source
+	product.ID, product.PublicID, product.Version = 0, "", 0
+	product.Name = source.Name + " (Copy)"
+	product.Status = "draft"
+	product.StockQuantity, product.PreorderReserved = 0, 0
+	product.RatingAvg, product.RatingCount = 0, 0
+	product.CreatedAt, product.UpdatedAt = now, now
+	product.Categories, product.Brands, product.Tags, product.Variants = nil, nil, nil, nil
+	product.Barcode = nil // barcodes are unique per supplier: the copy gets its own
+
+	var skus []*string
+	var variants []models.ProductVariant
+	if product.IsVariable {
+		for _, v := range source.Variants {
+			sku := ""
+			if v.SKU != nil {
+				sku = *v.SKU
+			}
+			v.ID, v.ProductID, v.SKU, v.StockQuantity, v.Barcode = 0, 0, &sku, 0, nil
+			variants = append(variants, v)
+		}
+		for i := range variants {
+			skus = append(skus, variants[i].SKU)
+		}
+	} else {
+		sku := ""
+		if source.SKU != nil {
+			sku = *source.SKU
+		}
+		product.SKU = &sku
+		skus = append(skus, product.SKU)
+	}
 
-	query := queryBuilder.String()
-	rows, err := h.DB.Query(query, args...)
+	// 3. --- New SKUs ---
+	if err := tx.Products.LockSKUs(ctx, supplierID); err != nil {
+		apierror.Internal(c, "Failed to assign SKUs")
+		return
+	}
+	if err := copySKUs(ctx, tx, supplierID, skus); err != nil {
+		apierror.Internal(c, "Failed to assign SKUs")
+		return
+	}
+	category, err := skuCategory(ctx, tx, productID, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed", "details": err.Error()})
-		return
-	}
-	defer rows.Close()
-
-	var products []*models.Product
-
-	// 3. Scan Rows
-	for rows.Next() {
-		var product models.Product
-		var dbImages, dbVariationImages []byte // Buffers for JSON columns
-
-		if err := rows.Scan(
-			&product.ID,
-			&product.SupplierID,
-			&product.SKU,
-			&product.Name,
-			&product.Description,
-			&product.PriceToTTS,
-			&product.StockQuantity,
-			&product.SRP,
-			&product.IsVariable,
-			&product.Status,
-			&product.CreatedAt,
-			&product.UpdatedAt,
-			&product.Weight,
-			&product.PkgLength,
-			&product.PkgWidth,
-			&product.PkgHeight,
-			&product.CommissionRate,
-			&dbImages,          // Scan Images
-			&dbVariationImages, // Scan Variation Images
-		); err != nil {
-			fmt.Printf("Scan Error: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan product row"})
+		apierror.Internal(c, "Failed to assign SKUs")
+		return
+	}
+	if err := h.assignSKUs(ctx, tx, supplierID, 0, category, skus); err != nil {
+		respondSKUError(c, err)
+		return
+	}
+
+	// 4. --- Insert Product & Relations ---
+	if err := tx.Products.Create(ctx, &product); err != nil {
+		apierror.Internal(c, "Failed to insert product")
+		return
+	}
+	categoryIDs := make([]int64, 0, len(source.Categories))
+	for _, cat := range source.Categories {
+		categoryIDs = append(categoryIDs, cat.ID)
+	}
+	if err := tx.Products.SetCategories(ctx, product.ID, categoryIDs); err != nil {
+		apierror.Internal(c, "Failed to link categories")
+		return
+	}
+	if len(source.Brands) > 0 {
+		if err := tx.Products.SetBrand(ctx, product.ID, source.Brands[0].ID); err != nil {
+			apierror.Internal(c, "Failed to link brand")
 			return
 		}
-
-		// 4. Parse JSON Columns (Ensure they aren't nil/empty)
-		if len(dbImages) > 0 {
-			_ = json.Unmarshal(dbImages, &product.Images)
-		} else {
-			product.Images = []string{}
-		}
-
-		// 5. Fetch Variants if Variable
-		if product.IsVariable {
-			vRows, err := h.DB.Query(`
-				SELECT id, sku, price_to_tts, stock_quantity, options 
-				FROM product_variants 
-				WHERE product_id = ?`, product.ID)
-
-			if err == nil {
-				var variants []models.ProductVariant
-				for vRows.Next() {
-					var v models.ProductVariant
-					var optsJSON []byte
-
-					// Scan database columns
-					err := vRows.Scan(&v.ID, &v.SKU, &v.PriceToTTS, &v.StockQuantity, &optsJSON)
-					if err != nil {
-						fmt.Printf("Variant Scan Error: %v\n", err)
-						continue
-					}
-
-					// [FIX] Phase 8.4: Handle JSON delivery to string field
-					// Convert DB bytes to string. If empty/null, provide valid JSON array string "[]"
-					if len(optsJSON) > 0 && string(optsJSON) != "null" && string(optsJSON) != `""` {
-						v.Options = string(optsJSON)
-					} else {
-						v.Options = "[]"
-					}
-
-					variants = append(variants, v)
-				}
-				vRows.Close()
-				product.Variants = variants
-			}
+	}
+	tags := make([]string, 0, len(source.Tags))
+	for _, t := range source.Tags {
+		tags = append(tags, t.Name)
+	}
+	if err := tx.Products.SetTags(ctx, product.ID, tags); err != nil {
+		apierror.Internal(c, "Failed to link tags")
+		return
+	}
+	if product.IsVariable {
+		if err := tx.Products.SetVariants(ctx, product.ID, variants); err != nil {
+			apierror.Internal(c, "Failed to save variants")
+			return
 		}
+	}
 
-		products = append(products, &product)
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Commit failed")
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"products": products,
+	c.JSON(http.StatusCreated, gin.H{"message": "Product duplicated", "productId": product.ID, "publicId": product.PublicID})
+}
+
+// splitTags reads filter[tag], a comma-separated list of tags, as slugs.
+func splitTags(raw string) []string {
+	var tags []string
+	for _, t := range strings.Split(raw, ",") {
+		if s := slug.Make(t); s != "" {
+			tags = append(tags, s)
+		}
+	}
+	return tags
+}
+
+// [FIXED] SearchProducts with Images and Variants
+// filter[tag]=a,b lists the products carrying every one of the tags (a curated
+// collection). ?facets=true adds the category, brand and price-range counts
+// (models.SearchFacets).
+func (h *Handlers) SearchProducts(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, ok := parseList(c, pagination.ListSpec{
+		Filters: []string{"q", "category", "brand", "tag", "min_price", "max_price"},
+		Sorts:   []string{store.SearchRelevance, store.SearchNewest, store.SearchPriceAsc, store.SearchPriceDesc},
 	})
+	if !ok {
+		return
+	}
+	page := list.Page
+
+	// 1. Build the filter from the query string.
+	// Relations are skipped entirely when ?fields= leaves them out. The
+	// default, relevance, lists the newest first when there is no ?q=.
+	fields := parseFields(c)
+	filter := store.ProductSearch{
+		Query:         list.Filter("q"),
+		CategoryID:    list.Filter("category"),
+		BrandID:       list.Filter("brand"),
+		Tags:          splitTags(list.Filter("tag")),
+		MinPrice:      list.Filter("min_price"),
+		MaxPrice:      list.Filter("max_price"),
+		Sort:          list.Sort,
+		WithRelations: wantsAny(fields, "categories", "brands", "tags", "variants"),
+	}
+
+	// 2. Query active products (read replica), with Categories, Brands & Variants
+	products, err := h.Store.Products.Search(ctx, filter, page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		apierror.BadRequest(c, "page[cursor] is from another sort")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Search Error [%s]: %v\n", c.GetString(apierror.RequestIDKey), err)
+		apierror.Internal(c, "Database query failed")
+		return
+	}
+	products, nextCursor := pagination.Paginate(page, products, filter.Cursor)
+	total, err := h.Store.Products.Count(ctx, filter)
+	if err != nil {
+		apierror.Internal(c, "Database query failed")
+		return
+	}
+
+	// 3. Apply ?fields= (sparse fieldset)
+	result, err := projectFields(products, fields)
+	if err != nil {
+		apierror.Internal(c, "Failed to build response")
+		return
+	}
+	response := gin.H{
+		"products":   result,
+		"total":      total,
+		"nextCursor": nextCursor,
+	}
+
+	// 4. Filter counts for the catalogue UI, on ?facets=true
+	if c.Query("facets") == "true" {
+		facets, err := h.Store.Products.Facets(ctx, filter)
+		if err != nil {
+			apierror.Internal(c, "Failed to count search facets")
+			return
+		}
+		response["facets"] = facets
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 type RequestPriceChangeInput struct {
-	NewPrice float64 `json:"newPrice" binding:"required,gt=0"`
-	Reason   string  `json:"reason,omitempty"`
+	NewPrice money.Money `json:"newPrice" binding:"required,gt=0,price"`
+	Reason   string      `json:"reason,omitempty"`
 }
 
 func (h *Handlers) RequestPriceChange(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	supplierID := userID_raw.(int64)
-	productIDStr := c.Param("id")
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
 
 	var input RequestPriceChangeInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
-	tx, err := h.DB.Begin()
+	tx, err := h.Store.Begin(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
 
-	var currentProduct models.Product
-	query := `
-		SELECT id, supplier_id, price_to_tts, status 
-		FROM products 
-		WHERE id = ? FOR UPDATE
-	`
-	err = tx.QueryRow(query, productIDStr).Scan(
-		&currentProduct.ID,
-		&currentProduct.SupplierID,
-		&currentProduct.PriceToTTS,
-		&currentProduct.Status,
-	)
-
+	currentProduct, err := tx.Products.GetForUpdate(ctx, productID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.NotFound(c, "Product not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error checking product"})
+		apierror.Internal(c, "Database error checking product")
 		return
 	}
 
 	if currentProduct.SupplierID != supplierID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to modify this product"})
+		apierror.Forbidden(c, "You do not have permission to modify this product")
 		return
 	}
 	if currentProduct.Status != "active" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Price appeals can only be made for 'active' products. Please edit your 'draft' product directly."})
+		apierror.BadRequest(c, "Price appeals can only be made for 'active' products. Please edit your 'draft' product directly.")
 		return
 	}
 
 	if currentProduct.PriceToTTS == input.NewPrice {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "The new price must be different from the current price"})
+		apierror.BadRequest(c, "The new price must be different from the current price")
+		return
+	}
+	if err := pricing.Validate(input.NewPrice, currentProduct.SRP, h.Config.Products.MinMargin); err != nil {
+		apierror.BadRequest(c, err.Error())
 		return
 	}
 
 	var pendingCount int
 	checkQuery := "SELECT COUNT(*) FROM price_appeals WHERE product_id = ? AND status = 'pending'"
-	err = tx.QueryRow(checkQuery, productIDStr).Scan(&pendingCount)
+	err = tx.QueryRowContext(ctx, checkQuery, productID).Scan(&pendingCount)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check for pending appeals"})
+		apierror.Internal(c, "Failed to check for pending appeals")
 		return
 	}
 	if pendingCount > 0 {
-		c.JSON(http.StatusConflict, gin.H{"error": "An appeal for this product is already pending review."})
+		apierror.Conflict(c, "An appeal for this product is already pending review.")
 		return
 	}
 
@@ -883,7 +1419,7 @@ func (h *Handlers) RequestPriceChange(c *gin.Context) {
 		VALUES (?, ?, ?, ?, ?, 'pending', ?, ?)`
 
 	now := time.Now()
-	_, err = tx.Exec(appealQuery,
+	_, err = tx.ExecContext(ctx, appealQuery,
 		currentProduct.ID,
 		supplierID,
 		currentProduct.PriceToTTS,
@@ -893,12 +1429,12 @@ func (h *Handlers) RequestPriceChange(c *gin.Context) {
 		now,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create price appeal"})
+		apierror.Internal(c, "Failed to create price appeal")
 		return
 	}
 
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		apierror.Internal(c, "Failed to commit transaction")
 		return
 	}
 
@@ -910,23 +1446,46 @@ func (h *Handlers) RequestPriceChange(c *gin.Context) {
 // ProductDetailResponse matches the structure needed by the Frontend "Edit" Form
 type ProductDetailResponse struct {
 	ID          int64   `json:"id"`
+	PublicID    string  `json:"publicId"`
 	SupplierID  int64   `json:"supplierId"`
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
 	Status      string  `json:"status"`
 	IsVariable  bool    `json:"isVariable"`
-	SKU         *string `json:"sku"` // For Simple Products
+	SKU         *string `json:"sku"`     // For Simple Products
+	Barcode     *string `json:"barcode"` // For Simple Products
+	Version     int     `json:"version"`
+
+	// The reason of the last rejection, until approval
+	RejectionReason *string `json:"rejectionReason,omitempty"`
 
 	// Prices & Stock
-	PriceToTTS     float64  `json:"priceToTTS"`
-	SRP            float64  `json:"srp"`
-	StockQuantity  int      `json:"stockQuantity"`
-	CommissionRate *float64 `json:"commissionRate"`
+	PriceToTTS     money.Money `json:"priceToTTS"`
+	SRP            money.Money `json:"srp"`
+	StockQuantity  int         `json:"stockQuantity"`
+	CommissionRate *float64    `json:"commissionRate"`
+
+	// Margin, platform fee and supplier payout of a unit at the price
+	Pricing pricing.Breakdown `json:"pricing"`
 
 	// Dimensions
 	Weight            *float64                `json:"weight"`
 	PackageDimensions *PackageDimensionsInput `json:"packageDimensions"`
 
+	// Shipping: the restrictions, and the configured couriers that accept them
+	// (null when SHIPPING_COURIERS is not set, [] when none does)
+	ShippingRestrictions []string `json:"shippingRestrictions"`
+	Couriers             []string `json:"couriers"`
+
+	// Order quantity rules
+	MinOrderQty    int `json:"minOrderQty"`
+	OrderIncrement int `json:"orderIncrement"`
+
+	// The product's own processing time (null fields use the shop-wide one),
+	// and the ships-by date of an order paid now
+	ProcessingTime models.ProcessingTime `json:"processingTime"`
+	ShipsBy        time.Time             `json:"shipsBy"`
+
 	// Media (Parsed from JSON)
 	Images          []string               `json:"images"`
 	VideoURL        string                 `json:"videoUrl"`
@@ -934,157 +1493,311 @@ type ProductDetailResponse struct {
 	VariationImages map[string]string      `json:"variationImages"`
 
 	// Relations
-	BrandID     int64   `json:"brandId"`
-	BrandName   string  `json:"brandName"`
-	CategoryIDs []int64 `json:"category_ids"`
+	BrandID     int64    `json:"brandId"`
+	BrandName   string   `json:"brandName"`
+	CategoryIDs []int64  `json:"category_ids"`
+	Tags        []string `json:"tags"`
 
 	// Variants
 	Variants []VariantInput `json:"variants"`
 }
 
-// GetProduct (Updated for Edit Page Reliability)
+// productDetailCacheTTL bounds staleness of the cached edit-form payload.
+// Every write to a product (update, delete, approval, price change, stock) invalidates it.
+const productDetailCacheTTL = 5 * time.Minute
+
+// GetProduct is the handler for GET /v1/products/:id
+// The owning supplier and staff get the edit form (ProductDetailResponse);
+// everyone else gets the catalogue view of an active product.
 func (h *Handlers) GetProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		apierror.Unauthorized(c, "User ID not found")
 		return
 	}
 	userID := userID_raw.(int64)
 	userRole := c.GetString("userRole")
-	productID := c.Param("id")
-
-	// 1. Fetch Core Product Data
-	query := `
-		SELECT 
-			id, supplier_id, name, description, status, is_variable, 
-			sku, price_to_tts, srp, stock_quantity, commission_rate,
-			weight, pkg_length, pkg_width, pkg_height,
-			images, video_url, size_chart, variation_images,
-			brand
-		FROM products 
-		WHERE id = ?`
-
-	var p ProductDetailResponse
-	var dbImages, dbSizeChart, dbVariationImages []byte
-	var dbVideoURL, dbSKU, dbBrandName sql.NullString
-	var dbWeight, dbLen, dbWid, dbHgt, dbComm sql.NullFloat64
-
-	err := h.DB.QueryRow(query, productID).Scan(
-		&p.ID, &p.SupplierID, &p.Name, &p.Description, &p.Status, &p.IsVariable,
-		&dbSKU, &p.PriceToTTS, &p.SRP, &p.StockQuantity, &dbComm,
-		&dbWeight, &dbLen, &dbWid, &dbHgt,
-		&dbImages, &dbVideoURL, &dbSizeChart, &dbVariationImages,
-		&dbBrandName,
-	)
-
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": err.Error()})
+		apierror.NotFound(c, "Product not found")
 		return
 	}
-
-	// 2. Security Check
 	isManager := (userRole == "manager" || userRole == "administrator")
-	if !isManager && p.SupplierID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view this product"})
+	if !isManager && userRole != "supplier" {
+		h.getCatalogueProduct(c, productID)
 		return
 	}
 
-	// 3. Process Nullables
-	if dbSKU.Valid {
-		p.SKU = &dbSKU.String
-	}
-	if dbVideoURL.Valid {
-		p.VideoURL = dbVideoURL.String
-	}
-	if dbBrandName.Valid {
-		p.BrandName = dbBrandName.String
-	}
-	if dbComm.Valid {
-		val := dbComm.Float64
-		p.CommissionRate = &val
+	// 1. Load the Product (Cache first, then DB)
+	var p *ProductDetailResponse
+	cacheKey := cache.ProductKey(productID)
+	if found, _ := h.Cache.Get(ctx, cacheKey, &p); !found || p == nil {
+		p, err = h.loadProductDetail(ctx, productID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				apierror.NotFound(c, "Product not found")
+				return
+			}
+			fmt.Printf("Product Load Error [%s]: %v\n", c.GetString(apierror.RequestIDKey), err)
+			apierror.Internal(c, "Database error")
+			return
+		}
+		_ = h.Cache.Set(ctx, cacheKey, p, productDetailCacheTTL)
 	}
 
-	// [FIX] Always initialize arrays to avoid "null" in JSON
-	p.Images = []string{}
-	if len(dbImages) > 0 {
-		json.Unmarshal(dbImages, &p.Images)
+	// 2. Another supplier's product: the catalogue view (applies to cached data too)
+	if !isManager && p.SupplierID != userID {
+		h.getCatalogueProduct(c, productID)
+		return
 	}
 
-	p.SizeChart = nil
-	if len(dbSizeChart) > 0 {
-		json.Unmarshal(dbSizeChart, &p.SizeChart)
+	// 3. Return Final JSON
+	if apiversion.From(c) >= apiversion.V2 {
+		c.JSON(http.StatusOK, gin.H{"product": p.v2()})
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"product": p})
+}
+
+// v2 is the edit form in the v2 shape (dto.ProductDetail).
+func (d *ProductDetailResponse) v2() *dto.ProductDetail {
+	out := &dto.ProductDetail{
+		ID:                   d.ID,
+		PublicID:             d.PublicID,
+		SupplierID:           d.SupplierID,
+		Name:                 d.Name,
+		Description:          d.Description,
+		Status:               d.Status,
+		RejectionReason:      d.RejectionReason,
+		IsVariable:           d.IsVariable,
+		SKU:                  d.SKU,
+		Barcode:              d.Barcode,
+		Version:              d.Version,
+		PriceToTTS:           d.PriceToTTS,
+		SRP:                  d.SRP,
+		StockQuantity:        d.StockQuantity,
+		CommissionRate:       d.CommissionRate,
+		Pricing:              d.Pricing,
+		Weight:               d.Weight,
+		ShippingRestrictions: dto.List(d.ShippingRestrictions),
+		Couriers:             d.Couriers,
+		MinOrderQty:          d.MinOrderQty,
+		OrderIncrement:       d.OrderIncrement,
+		ProcessingTime:       d.ProcessingTime,
+		ShipsBy:              d.ShipsBy,
+		Images:               dto.List(d.Images),
+		VideoURL:             d.VideoURL,
+		SizeChart:            d.SizeChart,
+		VariationImages:      d.VariationImages,
+		BrandID:              d.BrandID,
+		BrandName:            d.BrandName,
+		CategoryIDs:          dto.List(d.CategoryIDs),
+		Tags:                 dto.List(d.Tags),
+		Variants:             make([]dto.ProductVariant, 0, len(d.Variants)),
+	}
+	if d.PackageDimensions != nil {
+		out.PackageDimensions = dto.PackageDimensions(*d.PackageDimensions)
+	}
+	for _, v := range d.Variants {
+		out.Variants = append(out.Variants, dto.ProductVariant{
+			SKU:            v.SKU,
+			Barcode:        v.Barcode,
+			Price:          v.Price,
+			Stock:          v.Stock,
+			SRP:            v.SRP,
+			Options:        dto.List(v.Options),
+			CommissionRate: v.CommissionRate,
+		})
+	}
+	return out
+}
 
-	p.VariationImages = make(map[string]string)
-	if len(dbVariationImages) > 0 {
-		json.Unmarshal(dbVariationImages, &p.VariationImages)
+// loadProductDetail reads a product and its relations into the edit-form shape.
+// It returns store.ErrNotFound when the product does not exist.
+func (h *Handlers) loadProductDetail(ctx context.Context, productID int64) (*ProductDetailResponse, error) {
+	p, err := h.Store.Products.Get(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Core Fields
+	d := &ProductDetailResponse{
+		ID:              p.ID,
+		PublicID:        p.PublicID,
+		SupplierID:      p.SupplierID,
+		Name:            p.Name,
+		Description:     sanitize.HTML(p.Description), // rows stored before sanitization
+		Status:          p.Status,
+		RejectionReason: p.RejectionReason,
+		IsVariable:      p.IsVariable,
+		SKU:             p.SKU,
+		Barcode:         p.Barcode,
+		Version:         p.Version,
+		PriceToTTS:      p.PriceToTTS,
+		SRP:             p.SRP,
+		StockQuantity:   p.StockQuantity,
+		CommissionRate:  p.CommissionRate,
+		Pricing:         pricing.Compute(p.PriceToTTS, p.SRP, p.CommissionRate),
+		Weight:          p.Weight,
+		Images:          p.Images,
+		VideoURL:        p.VideoURL,
+		SizeChart:       p.SizeChart,
+		VariationImages: p.VariationImages,
+		BrandName:       p.BrandName,
+	}
+
+	d.ShippingRestrictions = p.ShippingRestrictions
+	d.Couriers = shipping.Compatible(h.Config.Shipping.Couriers, p.ShippingRestrictions)
+	d.MinOrderQty, d.OrderIncrement = p.MinOrderQty, p.OrderIncrement
+	d.ProcessingTime.HandlingDays = p.HandlingDays
+	if p.OrderCutoff != nil && len(*p.OrderCutoff) >= 5 {
+		cutoff := (*p.OrderCutoff)[:5]
+		d.ProcessingTime.Cutoff = &cutoff
+	}
+	var days sql.NullInt64
+	var cutoff sql.NullString
+	err = h.DB.QueryRowContext(ctx, "SELECT "+processingColumns("p", "s")+
+		" FROM products p JOIN users s ON s.id = p.supplier_id WHERE p.id = ?", p.ID).Scan(&days, &cutoff)
+	if err != nil {
+		return nil, err
 	}
+	d.ShipsBy = h.shipsBy(days, cutoff, time.Now())
 
-	if dbWeight.Valid {
-		val := dbWeight.Float64
-		p.Weight = &val
+	d.PackageDimensions = &PackageDimensionsInput{}
+	if p.PkgLength != nil {
+		d.PackageDimensions.Length = *p.PkgLength
+	}
+	if p.PkgWidth != nil {
+		d.PackageDimensions.Width = *p.PkgWidth
+	}
+	if p.PkgHeight != nil {
+		d.PackageDimensions.Height = *p.PkgHeight
 	}
 
-	p.PackageDimensions = &PackageDimensionsInput{Length: 0, Width: 0, Height: 0}
-	if dbLen.Valid {
-		p.PackageDimensions.Length = dbLen.Float64
+	// 2. Relations (always initialised to avoid "null" in JSON)
+	d.CategoryIDs = []int64{}
+	for _, cat := range p.Categories {
+		d.CategoryIDs = append(d.CategoryIDs, cat.ID)
 	}
-	if dbWid.Valid {
-		p.PackageDimensions.Width = dbWid.Float64
+	if len(p.Brands) > 0 {
+		d.BrandID = p.Brands[0].ID
 	}
-	if dbHgt.Valid {
-		p.PackageDimensions.Height = dbHgt.Float64
+	d.Tags = []string{}
+	for _, t := range p.Tags {
+		d.Tags = append(d.Tags, t.Name)
 	}
 
-	// 4. Fetch Linked Categories (Robust)
-	p.CategoryIDs = []int64{} // Init empty
-	catRows, err := h.DB.Query("SELECT category_id FROM product_categories WHERE product_id = ?", p.ID)
-	if err == nil {
-		defer catRows.Close()
-		for catRows.Next() {
-			var cid int64
-			catRows.Scan(&cid)
-			p.CategoryIDs = append(p.CategoryIDs, cid)
+	// 3. Variants
+	d.Variants = []VariantInput{}
+	for _, v := range p.Variants {
+		vi := VariantInput{
+			Barcode:        v.Barcode,
+			Price:          v.PriceToTTS,
+			Stock:          v.StockQuantity,
+			CommissionRate: v.CommissionRate,
 		}
+		_ = json.Unmarshal([]byte(v.Options), &vi.Options)
+		if v.SKU != nil {
+			vi.SKU = *v.SKU
+		}
+		d.Variants = append(d.Variants, vi)
 	}
 
-	// 5. Fetch Brand ID
-	h.DB.QueryRow("SELECT brand_id FROM product_brands WHERE product_id = ?", p.ID).Scan(&p.BrandID)
-
-	// 6. Fetch Variants
-	p.Variants = []VariantInput{} // Init empty
-	if p.IsVariable {
-		vRows, err := h.DB.Query(`
-			SELECT sku, price_to_tts, stock_quantity, options, commission_rate 
-			FROM product_variants WHERE product_id = ?`, p.ID)
-		if err == nil {
-			defer vRows.Close()
-			for vRows.Next() {
-				var v VariantInput
-				var vOpts []byte
-				var vComm sql.NullFloat64
-				var vSku sql.NullString
-
-				vRows.Scan(&vSku, &v.Price, &v.Stock, &vOpts, &vComm)
-
-				json.Unmarshal(vOpts, &v.Options)
-				if vComm.Valid {
-					val := vComm.Float64
-					v.CommissionRate = &val
-				}
-				if vSku.Valid {
-					v.SKU = vSku.String
-				}
+	return d, nil
+}
 
-				p.Variants = append(p.Variants, v)
-			}
+// catalogueVariants decodes the options of a product's variants for display.
+func catalogueVariants(variants []models.ProductVariant) []models.CatalogueVariant {
+	out := make([]models.CatalogueVariant, 0, len(variants))
+	for _, v := range variants {
+		cv := models.CatalogueVariant{ID: v.ID, SKU: v.SKU, Barcode: v.Barcode, Price: v.PriceToTTS, Stock: v.StockQuantity}
+		_ = json.Unmarshal([]byte(v.Options), &cv.Options)
+		if cv.Options == nil {
+			cv.Options = []models.ProductVariantOption{}
 		}
+		out = append(out, cv)
 	}
+	return out
+}
 
-	// 7. Return Final JSON
-	c.JSON(http.StatusOK, gin.H{"product": p})
+// getCatalogueProduct responds with the catalogue view of an active product.
+// Products outside the catalogue (pending, deleted, hidden by a vacation) are not found.
+func (h *Handlers) getCatalogueProduct(c *gin.Context, productID int64) {
+	d, err := h.loadCatalogueProduct(c.Request.Context(), productID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Database error")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"product": d})
+}
+
+// loadCatalogueProduct reads the catalogue view of an active product from the
+// replica. It returns store.ErrNotFound for products outside the catalogue.
+func (h *Handlers) loadCatalogueProduct(ctx context.Context, productID int64) (*models.CatalogueProduct, error) {
+	// 1. --- Product & Relations (replica) ---
+	p, err := h.Store.Products.GetActive(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	d := models.CatalogueProduct{
+		ID:                   p.ID,
+		PublicID:             p.PublicID,
+		Name:                 p.Name,
+		Description:          sanitize.HTML(p.Description),
+		SKU:                  p.SKU,
+		Barcode:              p.Barcode,
+		Price:                p.PriceToTTS,
+		SRP:                  p.SRP,
+		Stock:                p.StockQuantity,
+		IsVariable:           p.IsVariable,
+		IsPreorder:           p.IsPreorder,
+		PreorderAvailableAt:  p.PreorderAvailableAt,
+		MinOrderQty:          p.MinOrderQty,
+		OrderIncrement:       p.OrderIncrement,
+		Images:               p.Images,
+		VideoURL:             p.VideoURL,
+		SizeChart:            p.SizeChart,
+		VariationImages:      p.VariationImages,
+		ShippingRestrictions: p.ShippingRestrictions,
+		Couriers:             shipping.Compatible(h.Config.Shipping.Couriers, p.ShippingRestrictions),
+		Rating:               p.RatingAvg,
+		RatingCount:          p.RatingCount,
+		Categories:           p.Categories,
+		Variants:             catalogueVariants(p.Variants),
+	}
+	if d.Categories == nil {
+		d.Categories = []models.Category{}
+	}
+	if margin, percent, ok := pricing.Margin(p.PriceToTTS, p.SRP); ok {
+		d.Margin, d.MarginPercent = &margin, &percent
+	}
+	if len(p.Brands) > 0 {
+		d.Brand = &p.Brands[0]
+	}
+
+	// 2. --- Supplier & Processing Time ---
+	var companyName sql.NullString
+	var fullName string
+	var days sql.NullInt64
+	var cutoff sql.NullString
+	err = h.readDB().QueryRowContext(ctx, "SELECT s.public_id, s.company_name, s.full_name, "+processingColumns("p", "s")+
+		" FROM products p JOIN users s ON s.id = p.supplier_id WHERE p.id = ?", p.ID).
+		Scan(&d.Supplier.PublicID, &companyName, &fullName, &days, &cutoff)
+	if err != nil {
+		return nil, err
+	}
+	d.Supplier.Name = fullName
+	if companyName.Valid && companyName.String != "" {
+		d.Supplier.Name = companyName.String
+	}
+	d.Supplier.Away, d.Supplier.BackAt = p.SupplierAway, p.SupplierBackAt
+	d.ShipsBy = h.shipsBy(days, cutoff, time.Now())
+	return &d, nil
 }
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImagesPerUpload caps the files of one POST /products/:id/images.
+const maxImagesPerUpload = 10
+
+// UploadProductImages handles POST /v1/products/:id/images
+// Multipart "images" (repeatable): each file is checked like POST /upload,
+// stored in the image backend (UPLOAD_DIR or S3) and appended to the
+// product's images, in the order sent.
+func (h *Handlers) UploadProductImages(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Check Ownership ---
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found or you do not have permission to edit it")
+		return
+	}
+	product, err := h.Store.Products.Get(ctx, productID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && product.SupplierID != supplierID) {
+		apierror.NotFound(c, "Product not found or you do not have permission to edit it")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to load product")
+		return
+	}
+
+	// 2. --- Parse the (size-capped) form ---
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Uploads.MaxRequestBytes)
+	form, err := c.MultipartForm()
+	if err != nil {
+		h.uploadError(c, err, "Invalid upload")
+		return
+	}
+	files := form.File["images"]
+	if len(files) == 0 {
+		apierror.BadRequest(c, "No images uploaded (expected images)")
+		return
+	}
+	if len(files) > maxImagesPerUpload {
+		apierror.BadRequest(c, fmt.Sprintf("At most %d images can be uploaded at once", maxImagesPerUpload))
+		return
+	}
+
+	// 3. --- Check & Store Each Image ---
+	// A file that fails stops the upload; the ones stored before it are not
+	// referenced by any product and are left for the storage's own cleanup.
+	uploaded := make([]string, 0, len(files))
+	for _, fh := range files {
+		name, err := h.Uploads.Images.Save(ctx, fh)
+		if err != nil {
+			h.uploadError(c, err, "Failed to save image")
+			return
+		}
+		uploaded = append(uploaded, h.Uploads.Images.URL(name))
+	}
+
+	// 4. --- Append to the Product (optimistic lock on the version) ---
+	images := append(product.Images, uploaded...)
+	imagesJSON, _ := json.Marshal(images)
+	if err := h.Store.Products.Update(ctx, productID, product.Version, map[string]interface{}{"images": string(imagesJSON)}); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			apierror.Conflict(c, "Product was modified by someone else. Reload and try again.")
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.NotFound(c, "Product not found or you do not have permission to edit it")
+			return
+		}
+		apierror.Internal(c, "Failed to save product images")
+		return
+	}
+	h.invalidateProducts(ctx, productID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Images uploaded",
+		"uploaded": uploaded,
+		"images":   images,
+	})
+}
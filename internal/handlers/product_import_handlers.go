@@ -0,0 +1,450 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/barcode"
+	"github.com/01moynul/taptosell-golang/internal/cache"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+//
+// --- Bulk Product Import (CSV) ---
+//
+// One row per simple product. Rows sharing a handle are the variants of one
+// variable product and must be consecutive; the product columns are read
+// from the handle's first row, sku/barcode/price/stock/srp/options from each row.
+// Lists are "|"-separated: categories (slugs or IDs), tags, images,
+// shipping_restrictions, and options as "Color:Red|Size:M".
+//
+// Rows are validated as they are read. The file is imported in one
+// transaction, all or nothing: the supplier fixes the reported rows and
+// uploads it again. ?dryRun=true checks everything (SKUs included) and
+// imports nothing.
+
+// maxImportRows bounds one file, and so the import transaction.
+const maxImportRows = 2000
+
+// maxImportErrors stops the validation once this many errors are reported.
+const maxImportErrors = 100
+
+// importColumns are the headers the import understands; name and price are required.
+var importColumns = map[string]bool{
+	"handle": true, "name": true, "description": true, "status": true, "brand": true, "categories": true, "tags": true,
+	"sku": true, "barcode": true, "price": true, "stock": true, "srp": true, "options": true, "commission_rate": true,
+	"images": true, "video_url": true, "weight": true, "length": true, "width": true, "height": true,
+	"shipping_restrictions": true, "min_order_qty": true, "order_increment": true,
+}
+
+// ImportRowError is one problem in an uploaded file. Row 1 is the header.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// productImport is one product of the file, and the row it starts on.
+type productImport struct {
+	row    int
+	handle string
+	input  CreateProductInput
+}
+
+// importRow reads the cells of one record by header name.
+type importRow struct {
+	num    int
+	cells  map[string]string
+	errors []ImportRowError
+}
+
+func (r *importRow) get(col string) string {
+	return strings.TrimSpace(r.cells[col])
+}
+
+func (r *importRow) fail(col, message string) {
+	r.errors = append(r.errors, ImportRowError{Row: r.num, Field: col, Message: message})
+}
+
+// list splits a "|"-separated cell, dropping blanks; nil when the cell is empty.
+func (r *importRow) list(col string) []string {
+	var items []string
+	for _, item := range strings.Split(r.get(col), "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (r *importRow) amount(col string) money.Money {
+	v := r.get(col)
+	if v == "" {
+		return 0
+	}
+	m, err := money.Parse(v)
+	if err != nil {
+		r.fail(col, "must be an amount such as 12.90")
+	}
+	return m
+}
+
+func (r *importRow) whole(col string) *int {
+	v := r.get(col)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		r.fail(col, "must be a whole number")
+		return nil
+	}
+	return &n
+}
+
+func (r *importRow) barcode(col string) *string {
+	v := r.get(col)
+	if v == "" {
+		return nil
+	}
+	code, err := barcode.Parse(v)
+	if err != nil {
+		r.fail(col, "must be an EAN-8, UPC-A, EAN-13 or GTIN-14 with a valid check digit")
+		return nil
+	}
+	return &code
+}
+
+func (r *importRow) number(col string) *float64 {
+	v := r.get(col)
+	if v == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		r.fail(col, "must be a number")
+		return nil
+	}
+	return &f
+}
+
+// ImportProducts is the handler for POST /v1/products/import
+// It takes a CSV file in the multipart field "file" (see the section comment
+// for the columns) and creates every product in it, or none.
+func (h *Handlers) ImportProducts(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	dryRun := c.Query("dryRun") == "true"
+
+	// 1. --- Open the Upload ---
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Uploads.MaxRequestBytes)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		h.uploadError(c, err, "No file uploaded")
+		return
+	}
+	if !strings.EqualFold(filepath.Ext(fh.Filename), ".csv") {
+		apierror.UnsupportedMediaType(c, "Upload the products as a .csv file (save spreadsheets as CSV first)")
+		return
+	}
+	file, err := fh.Open()
+	if err != nil {
+		apierror.Internal(c, "Failed to read the file")
+		return
+	}
+	defer file.Close()
+
+	categories, err := h.categoryLookup(ctx)
+	if err != nil {
+		apierror.Internal(c, "Failed to load categories")
+		return
+	}
+
+	// 2. --- Read & Validate the Rows ---
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		apierror.BadRequest(c, "The file is empty")
+		return
+	}
+	if err != nil {
+		apierror.BadRequest(c, "The file is not valid CSV")
+		return
+	}
+	for i, col := range header {
+		col = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff"))) // Excel's BOM
+		if !importColumns[col] {
+			apierror.BadRequest(c, fmt.Sprintf("Unknown column %q", header[i]))
+			return
+		}
+		header[i] = col
+	}
+	if !slices.Contains(header, "name") || !slices.Contains(header, "price") {
+		apierror.BadRequest(c, "The file needs a name and a price column")
+		return
+	}
+
+	var products []*productImport
+	var rowErrors []ImportRowError
+	var current *productImport
+	seenHandles := map[string]bool{}
+	finish := func() {
+		if current != nil {
+			rowErrors = append(rowErrors, validateImport(current)...)
+		}
+	}
+
+	for num := 2; len(rowErrors) < maxImportErrors; num++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				rowErrors = append(rowErrors, ImportRowError{Row: num, Message: fmt.Sprintf("has %d cells, the header has %d", len(record), len(header))})
+				continue
+			}
+			rowErrors = append(rowErrors, ImportRowError{Row: num, Message: "is not valid CSV"})
+			break
+		}
+		if num-1 > maxImportRows {
+			apierror.BadRequest(c, fmt.Sprintf("The file has more than %d rows; split it into smaller files", maxImportRows))
+			return
+		}
+
+		row := &importRow{num: num, cells: make(map[string]string, len(header))}
+		for i, col := range header {
+			row.cells[col] = record[i]
+		}
+		handle := row.get("handle")
+
+		// A new product starts unless the row continues the current handle.
+		if handle == "" || current == nil || handle != current.handle {
+			finish()
+			if handle != "" && seenHandles[handle] {
+				row.fail("handle", "rows of the same product must be consecutive")
+			}
+			seenHandles[handle] = true
+			current = &productImport{row: num, handle: handle}
+			current.input = importProductInput(row, categories)
+			products = append(products, current)
+		}
+		importVariant(row, &current.input, handle != "")
+		rowErrors = append(rowErrors, row.errors...)
+	}
+	finish()
+
+	if len(rowErrors) > 0 {
+		respondImportErrors(c, rowErrors)
+		return
+	}
+	if len(products) == 0 {
+		apierror.BadRequest(c, "The file has no products")
+		return
+	}
+
+	// 3. --- Insert Everything in One Transaction ---
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "DB Transaction failed")
+		return
+	}
+	defer tx.Rollback()
+
+	type imported struct {
+		Row       int    `json:"row"`
+		ProductID int64  `json:"productId"`
+		PublicID  string `json:"publicId"`
+	}
+	results := make([]imported, 0, len(products))
+	newBrand := false
+	for _, imp := range products {
+		product, err := h.createProduct(ctx, tx, supplierID, &imp.input)
+		var inputErr *productInputError
+		var skuErr *skuConflictError
+		_, duplicate := store.DuplicateKey(err)
+		switch {
+		case err == nil:
+			results = append(results, imported{Row: imp.row, ProductID: product.ID, PublicID: product.PublicID})
+			newBrand = newBrand || imp.input.BrandName != ""
+		case errors.As(err, &inputErr):
+			rowErrors = append(rowErrors, ImportRowError{Row: imp.row, Message: inputErr.message})
+		case errors.As(err, &skuErr):
+			field := "sku"
+			if skuErr.barcode {
+				field = "barcode"
+			}
+			rowErrors = append(rowErrors, ImportRowError{Row: imp.row, Field: field, Message: skuErr.message})
+		case duplicate:
+			rowErrors = append(rowErrors, ImportRowError{Row: imp.row, Message: "This product already exists."})
+		default:
+			fmt.Printf("Import Error [%s]: %v\n", c.GetString(apierror.RequestIDKey), err)
+			apierror.Internal(c, "Failed to import products")
+			return
+		}
+		if len(rowErrors) >= maxImportErrors {
+			break
+		}
+	}
+	if len(rowErrors) > 0 {
+		respondImportErrors(c, rowErrors)
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{"message": "The file is valid", "dryRun": true, "products": len(results)})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Commit failed")
+		return
+	}
+	if newBrand {
+		h.invalidateCache(ctx, cache.KeyBrandList) // brands may have just been created
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Products imported", "imported": len(results), "products": results})
+}
+
+// respondImportErrors responds 400 with the rows to fix; nothing was imported.
+func respondImportErrors(c *gin.Context, rowErrors []ImportRowError) {
+	apierror.WithDetails(c, http.StatusBadRequest, apierror.CodeValidation, "The file has errors; nothing was imported", gin.H{
+		"rows":      rowErrors,
+		"truncated": len(rowErrors) >= maxImportErrors,
+	})
+}
+
+// importProductInput reads the product columns of a product's first row.
+func importProductInput(row *importRow, categories map[string]int64) CreateProductInput {
+	in := CreateProductInput{
+		Name:                 row.get("name"),
+		Description:          row.get("description"),
+		Status:               strings.ToLower(row.get("status")),
+		BrandName:            row.get("brand"),
+		Tags:                 row.list("tags"),
+		IsVariable:           row.get("handle") != "",
+		Images:               row.list("images"),
+		VideoURL:             row.get("video_url"),
+		Weight:               row.number("weight"),
+		CommissionRate:       row.number("commission_rate"),
+		ShippingRestrictions: row.list("shipping_restrictions"),
+		MinOrderQty:          row.whole("min_order_qty"),
+		OrderIncrement:       row.whole("order_increment"),
+	}
+	if in.Status == "" {
+		in.Status = "draft"
+	}
+	if in.Images == nil {
+		in.Images = []string{}
+	}
+
+	length, width, height := row.number("length"), row.number("width"), row.number("height")
+	if length != nil || width != nil || height != nil {
+		in.PackageDimensions = &PackageDimensionsInput{}
+		if length != nil {
+			in.PackageDimensions.Length = *length
+		}
+		if width != nil {
+			in.PackageDimensions.Width = *width
+		}
+		if height != nil {
+			in.PackageDimensions.Height = *height
+		}
+	}
+
+	for _, ref := range row.list("categories") {
+		id, ok := categories[strings.ToLower(ref)]
+		if !ok {
+			row.fail("categories", fmt.Sprintf("unknown category %q", ref))
+			continue
+		}
+		in.CategoryIDs = append(in.CategoryIDs, id)
+	}
+	return in
+}
+
+// importVariant reads the sku, barcode, price, stock, srp and options of a
+// row into the product: its simple product, or one more variant.
+func importVariant(row *importRow, in *CreateProductInput, variable bool) {
+	sku, price, srp := row.get("sku"), row.amount("price"), row.amount("srp")
+	code := row.barcode("barcode")
+	stock := 0
+	if n := row.whole("stock"); n != nil {
+		stock = *n
+	}
+	if row.get("price") == "" {
+		row.fail("price", "is required")
+	}
+
+	if !variable {
+		in.SimpleProduct = &SimpleProductInput{SKU: sku, Barcode: code, Price: price, Stock: stock, SRP: srp, CommissionRate: in.CommissionRate}
+		return
+	}
+	v := VariantInput{SKU: sku, Barcode: code, Price: price, Stock: stock, SRP: srp}
+	for _, opt := range row.list("options") {
+		name, value, ok := strings.Cut(opt, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			row.fail("options", fmt.Sprintf("%q is not Name:Value", opt))
+			continue
+		}
+		v.Options = append(v.Options, models.ProductVariantOption{Name: name, Value: value})
+	}
+	in.Variants = append(in.Variants, v)
+}
+
+// validateImport runs the checks CreateProduct runs on a request body.
+func validateImport(imp *productImport) []ImportRowError {
+	imp.input.sanitize()
+	if err := binding.Validator.ValidateStruct(&imp.input); err != nil {
+		var rowErrors []ImportRowError
+		for _, fe := range apierror.ValidationFields(err) {
+			rowErrors = append(rowErrors, ImportRowError{Row: imp.row, Field: fe.Field, Message: fe.Message})
+		}
+		if rowErrors == nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: imp.row, Message: err.Error()})
+		}
+		return rowErrors
+	}
+	if err := imp.input.validate(); err != nil {
+		return []ImportRowError{{Row: imp.row, Message: err.Error()}}
+	}
+	return nil
+}
+
+// categoryLookup maps every category's slug and ID (as text) to its ID.
+func (h *Handlers) categoryLookup(ctx context.Context) (map[string]int64, error) {
+	rows, err := h.readDB().QueryContext(ctx, "SELECT id, slug FROM categories")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lookup := map[string]int64{}
+	for rows.Next() {
+		var id int64
+		var slug string
+		if err := rows.Scan(&id, &slug); err != nil {
+			return nil, err
+		}
+		lookup[strings.ToLower(slug)] = id
+		lookup[strconv.FormatInt(id, 10)] = id
+	}
+	return lookup, rows.Err()
+}
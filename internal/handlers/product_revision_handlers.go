@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/shipping"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Product Revisions ---
+//
+
+// Every supplier update, manager rollback, rejection and resubmission records
+// the product's editable content before and after (see models.ProductSnapshot). A rollback restores
+// the content a revision started from; stock and status are left as they are,
+// since stock moves with orders and status with review.
+
+// productRevisionLimit caps GET /products/:id/revisions.
+const productRevisionLimit = 100
+
+// recordRevision snapshots the product as it now is on tx and records the
+// change from before. note is the reason of a rejection.
+func (h *Handlers) recordRevision(ctx context.Context, tx *store.Tx, productID, userID int64, action string, rolledBackTo *int64, note *string, before *models.ProductSnapshot) error {
+	after, err := tx.Products.Snapshot(ctx, productID)
+	if err != nil {
+		return err
+	}
+	return tx.Products.AddRevision(ctx, &models.ProductRevision{
+		ProductID:    productID,
+		UserID:       userID,
+		Action:       action,
+		RolledBackTo: rolledBackTo,
+		Note:         note,
+		Before:       *before,
+		After:        *after,
+	})
+}
+
+// GetProductRevisions is the handler for GET /v1/products/:id/revisions
+// The owning supplier and managers see the product's changes, newest first.
+func (h *Handlers) GetProductRevisions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	if role := c.GetString("userRole"); role != "manager" && role != "administrator" {
+		if _, err := h.Store.Products.GetOwned(ctx, productID, userID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				apierror.NotFound(c, "Product not found")
+				return
+			}
+			apierror.Internal(c, "Database error checking ownership")
+			return
+		}
+	}
+
+	revisions, err := h.Store.Products.Revisions(ctx, productID, productRevisionLimit)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch revisions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// RollbackProduct is the handler for POST /v1/manager/products/:id/revisions/:revisionId/rollback
+// It restores the product's content to what it was before the revision, as a
+// new "rollback" revision, and tells the supplier.
+func (h *Handlers) RollbackProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	revisionID, err := strconv.ParseInt(c.Param("revisionId"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Revision not found")
+		return
+	}
+
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "DB Transaction failed")
+		return
+	}
+	defer tx.Rollback()
+
+	// 1. --- Load Product & Revision ---
+	current, err := tx.Products.GetForUpdate(ctx, productID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch product")
+		return
+	}
+	revision, err := tx.Products.Revision(ctx, productID, revisionID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Revision not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch revision")
+		return
+	}
+	before, err := tx.Products.Snapshot(ctx, productID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch product")
+		return
+	}
+	target := revision.Before
+
+	// Pre-order lines have no variant (see UpdateProduct).
+	if target.IsVariable {
+		var reserved int
+		if err := tx.QueryRowContext(ctx, "SELECT preorder_reserved FROM products WHERE id = ?", productID).Scan(&reserved); err != nil {
+			apierror.Internal(c, "Failed to fetch product")
+			return
+		}
+		if reserved > 0 {
+			apierror.Conflict(c, "That revision has variants, but the product has open pre-orders; let them finish first")
+			return
+		}
+	}
+
+	// 2. --- Content Columns ---
+	imagesJSON, _ := json.Marshal(target.Images)
+	sizeChartJSON, _ := json.Marshal(target.SizeChart)
+	variationImagesJSON, _ := json.Marshal(target.VariationImages)
+	weightGrams := 0
+	if target.Weight != nil {
+		weightGrams = int(*target.Weight * 1000)
+	}
+	changes := map[string]interface{}{
+		"name":                  target.Name,
+		"description":           target.Description,
+		"is_variable":           target.IsVariable,
+		"images":                string(imagesJSON),
+		"video_url":             target.VideoURL,
+		"size_chart":            string(sizeChartJSON),
+		"variation_images":      string(variationImagesJSON),
+		"weight":                target.Weight,
+		"weight_grams":          weightGrams,
+		"pkg_length":            target.PkgLength,
+		"pkg_width":             target.PkgWidth,
+		"pkg_height":            target.PkgHeight,
+		"shipping_restrictions": shipping.Join(target.ShippingRestrictions),
+		"min_order_qty":         max(target.MinOrderQty, 1),
+		"order_increment":       max(target.OrderIncrement, 1),
+		"handling_days":         target.HandlingDays,
+		"order_cutoff":          target.OrderCutoff,
+		"is_preorder":           target.IsPreorder && !target.IsVariable,
+		"preorder_available_at": target.PreorderAvailableAt,
+		"preorder_limit":        target.PreorderLimit,
+		"srp":                   target.SRP,
+		"commission_rate":       target.CommissionRate,
+	}
+
+	// 3. --- Price, SKUs, Barcodes & Variants (stock stays as it is) ---
+	existing, err := tx.Products.VariantsForUpdate(ctx, productID)
+	if err != nil {
+		apierror.Internal(c, "Failed to load variants")
+		return
+	}
+	var skus, codes []*string
+	var diff variantDiff
+	simpleSKU := ""
+	if target.IsVariable {
+		variants, err := rollbackVariants(existing, target.Variants)
+		if err != nil {
+			apierror.Internal(c, "Failed to restore variants")
+			return
+		}
+		diff, err = diffVariants(existing, variants, false)
+		if err != nil {
+			apierror.Internal(c, "Failed to restore variants")
+			return
+		}
+		var stock int
+		var minPrice money.Money
+		for i, v := range variants {
+			stock += v.StockQuantity
+			if i == 0 || v.PriceToTTS < minPrice {
+				minPrice = v.PriceToTTS
+			}
+			skus = append(skus, variants[i].SKU)
+			codes = append(codes, variants[i].Barcode)
+		}
+		changes["price_to_tts"], changes["stock_quantity"] = minPrice, stock
+	} else {
+		if target.SKU != nil {
+			simpleSKU = *target.SKU
+		}
+		skus = append(skus, &simpleSKU)
+		codes = append(codes, target.Barcode)
+		changes["price_to_tts"] = target.Price
+		for _, v := range existing {
+			diff.remove = append(diff.remove, v.ID)
+		}
+	}
+	category, err := skuCategory(ctx, tx, productID, target.CategoryIDs)
+	if err != nil {
+		apierror.Internal(c, "Failed to assign SKUs")
+		return
+	}
+	if err := h.assignSKUs(ctx, tx, current.SupplierID, productID, category, skus); err != nil {
+		respondSKUError(c, err)
+		return
+	}
+	if !target.IsVariable {
+		changes["sku"] = simpleSKU
+		changes["barcode"] = target.Barcode
+	}
+	if err := checkBarcodes(ctx, tx, current.SupplierID, productID, codes); err != nil {
+		respondSKUError(c, err)
+		return
+	}
+
+	// 4. --- Write ---
+	if err := tx.Products.Update(ctx, productID, current.Version, changes); err != nil {
+		apierror.Internal(c, "Failed to restore product")
+		return
+	}
+	if err := tx.Products.SetCategories(ctx, productID, target.CategoryIDs); err != nil {
+		apierror.Internal(c, "Failed to update categories")
+		return
+	}
+	if target.BrandID != 0 {
+		if err := tx.Products.SetBrand(ctx, productID, target.BrandID); err != nil {
+			apierror.Internal(c, "Failed to update brand link")
+			return
+		}
+	}
+	if target.Tags != nil {
+		if err := tx.Products.SetTags(ctx, productID, target.Tags); err != nil {
+			apierror.Internal(c, "Failed to update tags")
+			return
+		}
+	}
+	for _, v := range diff.update {
+		if err := tx.Products.UpdateVariant(ctx, v); err != nil {
+			apierror.Internal(c, "Failed to save variants")
+			return
+		}
+	}
+	if err := tx.Products.DeleteVariants(ctx, productID, diff.remove); err != nil {
+		apierror.Internal(c, "Failed to save variants")
+		return
+	}
+	if err := tx.Products.AddVariants(ctx, productID, diff.add); err != nil {
+		apierror.Internal(c, "Failed to save variants")
+		return
+	}
+	if err := h.recordRevision(ctx, tx, productID, managerID, "rollback", &revisionID, nil, before); err != nil {
+		apierror.Internal(c, "Failed to record revision")
+		return
+	}
+
+	message := fmt.Sprintf("A manager restored your product \"%s\" to an earlier version.", target.Name)
+	if err := h.AddNotification(ctx, tx, current.SupplierID, message, "/supplier/products"); err != nil {
+		apierror.Internal(c, "Failed to notify supplier")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	h.invalidateProducts(ctx, productID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product rolled back"})
+}
+
+// rollbackVariants turns a snapshot's variants into rows for diffVariants.
+// A variant that still exists (by ID, else by SKU) keeps its ID and current
+// stock; one deleted since comes back as a new variant with no stock.
+func rollbackVariants(existing []models.ProductVariant, snapshot []models.ProductSnapshotVariant) ([]models.ProductVariant, error) {
+	byID := make(map[int64]models.ProductVariant, len(existing))
+	bySKU := map[string]models.ProductVariant{}
+	for _, v := range existing {
+		byID[v.ID] = v
+		if v.SKU != nil && *v.SKU != "" {
+			bySKU[*v.SKU] = v
+		}
+	}
+
+	variants := make([]models.ProductVariant, 0, len(snapshot))
+	used := map[int64]bool{}
+	for _, s := range snapshot {
+		sku, code := "", ""
+		if s.SKU != nil {
+			sku = *s.SKU
+		}
+		if s.Barcode != nil {
+			code = *s.Barcode
+		}
+		v := models.ProductVariant{
+			SKU:            &sku,
+			Barcode:        &code, // not nil, which diffVariants reads as "keep"
+			PriceToTTS:     s.Price,
+			Options:        string(s.Options),
+			CommissionRate: s.CommissionRate,
+		}
+		if v.Options == "" {
+			v.Options = "[]"
+		}
+		old, ok := byID[s.ID]
+		if !ok && sku != "" {
+			old, ok = bySKU[sku]
+		}
+		if ok && !used[old.ID] {
+			used[old.ID] = true
+			v.ID, v.StockQuantity = old.ID, old.StockQuantity
+		} else if sku != "" {
+			// A SKU matching an existing variant would make diffVariants reuse it.
+			if _, taken := bySKU[sku]; taken {
+				return nil, fmt.Errorf("variant SKU %q is listed twice", sku)
+			}
+		}
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Incremental Product Sync ---
+//
+
+// Mobile and offline clients keep a local copy of the catalogue. They download
+// it once from the change feed, then ask only for what changed since.
+
+// productSyncSettle holds back the newest changes so a transaction that stamped
+// a row just before another one committed is not skipped by a client whose
+// position already moved past it.
+const productSyncSettle = 5 * time.Second
+
+// GetProductChanges is the handler for GET /v1/products/changes
+// filter[since] (RFC 3339) starts the feed at a point in time; without it the
+// feed starts from the beginning, i.e. a full download. Every response carries
+// a nextCursor: store it and send it back as page[cursor] next time. While
+// hasMore is true, call again straight away. page[size] is as in search.
+// The feed only runs forwards (sort=changedAt).
+func (h *Handlers) GetProductChanges(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Parse Position ---
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"since"}, Sorts: []string{"changedAt"}})
+	if !ok {
+		return
+	}
+	page := list.Page
+	after := pagination.Cursor{CreatedAt: time.Unix(0, 0)}
+	if page.After != nil {
+		after = *page.After
+	} else if v := list.Filter("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			apierror.BadRequest(c, "since must be an RFC 3339 timestamp like 2024-01-31T08:00:00Z")
+			return
+		}
+		after.CreatedAt = since
+	}
+
+	// 2. --- Fetch Changes ---
+	changes, err := h.Store.Products.Changes(ctx, after, productSyncSettle, page.Limit)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch product changes")
+		return
+	}
+	hasMore := len(changes) > page.Limit
+	if hasMore {
+		changes = changes[:page.Limit]
+	}
+
+	// 3. --- Next Position (unchanged when nothing new) ---
+	next := after
+	if len(changes) > 0 {
+		last := changes[len(changes)-1]
+		next = pagination.Cursor{CreatedAt: last.ChangedAt, ID: last.ProductID}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"changes":    changes,
+		"nextCursor": next.Encode(),
+		"hasMore":    hasMore,
+	})
+}
+
+// touchSupplierProducts puts every product of a supplier back on the change
+// feed, e.g. when their vacation changes how the listings show.
+func touchSupplierProducts(ctx context.Context, q Querier, supplierID int64) error {
+	_, err := q.ExecContext(ctx, "UPDATE products SET changed_at = CURRENT_TIMESTAMP(3) WHERE supplier_id = ?", supplierID)
+	return err
+}
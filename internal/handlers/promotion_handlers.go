@@ -0,0 +1,636 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/promotions"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Promotions ---
+//
+// Managers create coupon codes and automatic campaigns; checkout applies the
+// best one the cart qualifies for (see internal/promotions). Discounts are
+// platform-funded: the dropshipper pays orders.total, and the supplier payout
+// on completion is orders.total + orders.discount_total.
+
+// couponPattern is the shape of a coupon code, after upper-casing.
+var couponPattern = regexp.MustCompile(`^[A-Z0-9_-]{3,40}$`)
+
+// normalizeCoupon trims and upper-cases a code so lookups ignore case.
+func normalizeCoupon(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// promotionColumns is the column list scanned by scanPromotion.
+const promotionColumns = `
+	p.id, p.name, p.code, p.kind, p.value, p.max_discount, p.min_spend, p.category_id,
+	p.first_order_only, p.usage_limit, p.per_user_limit, p.starts_at, p.ends_at, p.is_active,
+	p.created_by, p.created_at, p.updated_at`
+
+func scanPromotion(row interface{ Scan(...interface{}) error }, extra ...interface{}) (models.Promotion, error) {
+	var p models.Promotion
+	err := row.Scan(append([]interface{}{
+		&p.ID, &p.Name, &p.Code, &p.Kind, &p.Value, &p.MaxDiscount, &p.MinSpend, &p.CategoryID,
+		&p.FirstOrderOnly, &p.UsageLimit, &p.PerUserLimit, &p.StartsAt, &p.EndsAt, &p.IsActive,
+		&p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+	}, extra...)...)
+	return p, err
+}
+
+// couponError is a coupon the dropshipper entered that cannot be applied.
+type couponError struct {
+	code   string
+	reason error
+}
+
+func (e *couponError) Error() string {
+	return fmt.Sprintf("Coupon %s cannot be applied: %s", e.code, e.reason)
+}
+
+// errCouponNotFound is the reason for a code that does not exist.
+var errCouponNotFound = errors.New("this code does not exist")
+
+// applyPromotion prices the cart's discount: the best of the automatic
+// campaigns it qualifies for and the coupon (if code is not empty). It returns
+// nil when nothing applies and a *couponError when the coupon is not valid.
+//
+// With lock the promotion rows are read FOR UPDATE, so concurrent checkouts
+// cannot both take the last redemption of a limited promotion.
+func applyPromotion(ctx context.Context, q Querier, dropshipperID int64, items []CartItemData, code string, now time.Time, lock bool) (*promotions.Applied, error) {
+	// 1. --- Candidate Promotions ---
+	query := "SELECT " + promotionColumns + ` FROM promotions p
+		WHERE (p.code IS NULL AND p.is_active = 1 AND p.starts_at <= ? AND (p.ends_at IS NULL OR p.ends_at > ?))
+		   OR p.code = ?
+		ORDER BY p.id`
+	if lock {
+		query += " FOR UPDATE"
+	}
+	rows, err := q.QueryContext(ctx, query, now, now, code)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []models.Promotion
+	var coupon *models.Promotion
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if p.Code != nil {
+			coupon = &p
+		}
+		candidates = append(candidates, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if code != "" && coupon == nil {
+		return nil, &couponError{code: code, reason: errCouponNotFound}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	// 2. --- Cart, First Order & Redemptions ---
+	cart, err := promotionCart(ctx, q, dropshipperID, items, now)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := promotionUsage(ctx, q, dropshipperID, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. --- Pick the Best (the coupon must be valid on its own) ---
+	if coupon != nil {
+		if _, err := promotions.Discount(*coupon, cart, usage[coupon.ID]); err != nil {
+			return nil, &couponError{code: code, reason: err}
+		}
+	}
+	return promotions.Best(candidates, cart, usage), nil
+}
+
+// promotionCart builds the promotions view of the cart: line amounts with
+// their categories (ancestors included) and whether this is a first order.
+func promotionCart(ctx context.Context, q Querier, dropshipperID int64, items []CartItemData, now time.Time) (promotions.Cart, error) {
+	cart := promotions.Cart{Now: now}
+
+	chains, err := categoryChains(ctx, q, items)
+	if err != nil {
+		return cart, err
+	}
+
+	for _, item := range items {
+		var categoryIDs []int64
+		for _, chain := range chains[item.ProductID] {
+			categoryIDs = append(categoryIDs, chain...)
+		}
+		cart.Lines = append(cart.Lines, promotions.Line{
+			ProductID:   item.ProductID,
+			Amount:      item.Price.Mul(item.Quantity),
+			CategoryIDs: categoryIDs,
+		})
+	}
+
+	var ordered bool
+	err = q.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM orders WHERE user_id = ? AND status <> 'cancelled' AND deleted_at IS NULL)",
+		dropshipperID).Scan(&ordered)
+	cart.FirstOrder = !ordered
+	return cart, err
+}
+
+// categoryChains returns, for each product in items, each of its categories
+// followed by that category's ancestors, nearest first.
+func categoryChains(ctx context.Context, q Querier, items []CartItemData) (map[int64][][]int64, error) {
+	// Categories are a small table; walk the parents in memory.
+	parents := map[int64]int64{}
+	rows, err := q.QueryContext(ctx, "SELECT id, parent_id FROM categories")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var parent sql.NullInt64
+		if err := rows.Scan(&id, &parent); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if parent.Valid {
+			parents[id] = parent.Int64
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	chains := map[int64][][]int64{}
+	rows, err = q.QueryContext(ctx,
+		"SELECT product_id, category_id FROM product_categories WHERE product_id IN (?"+strings.Repeat(", ?", len(productIDs)-1)+")",
+		productIDs...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var productID, categoryID int64
+		if err := rows.Scan(&productID, &categoryID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// The depth bound guards against a parent cycle in bad data.
+		var chain []int64
+		for depth := 0; depth < 32; depth++ {
+			chain = append(chain, categoryID)
+			parent, ok := parents[categoryID]
+			if !ok {
+				break
+			}
+			categoryID = parent
+		}
+		chains[productID] = append(chains[productID], chain)
+	}
+	rows.Close()
+	return chains, rows.Err()
+}
+
+// promotionUsage counts each promotion's redemptions on orders that were not cancelled.
+func promotionUsage(ctx context.Context, q Querier, dropshipperID int64, candidates []models.Promotion) (map[int64]promotions.Usage, error) {
+	args := []interface{}{dropshipperID}
+	for _, p := range candidates {
+		args = append(args, p.ID)
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT od.promotion_id, COUNT(*), COALESCE(SUM(od.user_id = ?), 0)
+		FROM order_discounts od
+		JOIN orders o ON od.order_id = o.id
+		WHERE o.status <> 'cancelled' AND od.promotion_id IN (?`+strings.Repeat(", ?", len(candidates)-1)+`)
+		GROUP BY od.promotion_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := map[int64]promotions.Usage{}
+	for rows.Next() {
+		var id int64
+		var u promotions.Usage
+		if err := rows.Scan(&id, &u.Total, &u.ByUser); err != nil {
+			return nil, err
+		}
+		usage[id] = u
+	}
+	return usage, rows.Err()
+}
+
+// appliedJSON describes the applied promotion in checkout responses.
+func appliedJSON(applied *promotions.Applied) gin.H {
+	if applied == nil {
+		return nil
+	}
+	return gin.H{
+		"id":          applied.Promotion.ID,
+		"code":        applied.Promotion.Code,
+		"description": applied.Description(),
+		"amount":      applied.Amount,
+	}
+}
+
+// PreviewCheckout is the handler for POST /v1/dropshipper/checkout/preview
+// It prices the cart (with the optional coupon) without placing an order.
+func (h *Handlers) PreviewCheckout(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Dropshipper ID & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+
+	var input CheckoutInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			apierror.Validation(c, err)
+			return
+		}
+	}
+	code := normalizeCoupon(input.CouponCode)
+
+	// 2. --- Load the Cart ---
+	var cartID int64
+	err := h.DB.QueryRowContext(ctx, "SELECT id FROM carts WHERE user_id = ?", dropshipperID).Scan(&cartID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.BadRequest(c, "Your cart is empty")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to find cart")
+		return
+	}
+	cartItems, err := queryCartItems(ctx, h.DB, cartID, false)
+	if err != nil {
+		apierror.Internal(c, "Failed to get cart items")
+		return
+	}
+	if len(cartItems) == 0 {
+		apierror.BadRequest(c, "Your cart contains no active products")
+		return
+	}
+
+	// 3. --- Price It ---
+	applied, err := applyPromotion(ctx, h.DB, dropshipperID, cartItems, code, time.Now(), false)
+	var ce *couponError
+	if errors.As(err, &ce) {
+		apierror.BadRequest(c, ce.Error())
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to apply promotions")
+		return
+	}
+
+	subtotal := cartSubtotal(cartItems)
+	var discount money.Money
+	if applied != nil {
+		discount = applied.Amount
+	}
+	taxLines, taxTotal, err := h.orderTax(ctx, h.DB, cartItems)
+	if err != nil {
+		apierror.Internal(c, "Failed to compute tax")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"subtotal":  subtotal,
+		"discount":  discount,
+		"tax":       taxTotal,
+		"taxLines":  taxLines,
+		"total":     subtotal - discount + taxTotal,
+		"promotion": appliedJSON(applied),
+	})
+}
+
+//
+// --- Manager ---
+//
+
+// PromotionInput defines the JSON for creating or replacing a promotion.
+// Leave code empty for an automatic campaign.
+type PromotionInput struct {
+	Name           string       `json:"name" binding:"required,max=255"`
+	Code           string       `json:"code" binding:"max=40"`
+	Kind           string       `json:"kind" binding:"required,oneof=percent fixed"`
+	Value          float64      `json:"value" binding:"required,gt=0"`
+	MaxDiscount    *money.Money `json:"maxDiscount"`
+	MinSpend       money.Money  `json:"minSpend" binding:"gte=0"`
+	CategoryID     *int64       `json:"categoryId"`
+	FirstOrderOnly bool         `json:"firstOrderOnly"`
+	UsageLimit     *int         `json:"usageLimit"`
+	PerUserLimit   *int         `json:"perUserLimit"`
+	StartsAt       *time.Time   `json:"startsAt"` // defaults to now
+	EndsAt         *time.Time   `json:"endsAt"`
+	IsActive       *bool        `json:"isActive"` // defaults to true
+}
+
+// bindPromotion binds and checks a PromotionInput into p (ID and audit
+// fields are left alone). It answers the request itself and returns false
+// when the input is invalid.
+func (h *Handlers) bindPromotion(c *gin.Context, p *models.Promotion) bool {
+	var input PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return false
+	}
+
+	p.Name = sanitize.Text(input.Name)
+	p.Code = nil
+	if code := normalizeCoupon(input.Code); code != "" {
+		if !couponPattern.MatchString(code) {
+			apierror.BadRequest(c, "code must be 3-40 letters, digits, '-' or '_'")
+			return false
+		}
+		p.Code = &code
+	}
+	p.Kind, p.Value, p.MaxDiscount, p.MinSpend = input.Kind, math.Round(input.Value*100)/100, input.MaxDiscount, input.MinSpend
+	p.CategoryID, p.FirstOrderOnly = input.CategoryID, input.FirstOrderOnly
+	p.UsageLimit, p.PerUserLimit = input.UsageLimit, input.PerUserLimit
+	p.StartsAt, p.EndsAt, p.IsActive = time.Now(), input.EndsAt, true
+	if input.StartsAt != nil {
+		p.StartsAt = *input.StartsAt
+	}
+	if input.IsActive != nil {
+		p.IsActive = *input.IsActive
+	}
+	if p.Name == "" {
+		apierror.BadRequest(c, "name is required")
+		return false
+	}
+	if err := promotions.Validate(*p); err != nil {
+		apierror.BadRequest(c, err.Error())
+		return false
+	}
+
+	if p.CategoryID != nil {
+		var exists bool
+		err := h.DB.QueryRowContext(c.Request.Context(), "SELECT EXISTS (SELECT 1 FROM categories WHERE id = ?)", *p.CategoryID).Scan(&exists)
+		if err != nil {
+			apierror.Internal(c, "Failed to check category")
+			return false
+		}
+		if !exists {
+			apierror.BadRequest(c, "categoryId does not exist")
+			return false
+		}
+	}
+	return true
+}
+
+// CreatePromotion is the handler for POST /v1/manager/promotions
+func (h *Handlers) CreatePromotion(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Manager ID & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+
+	var p models.Promotion
+	if !h.bindPromotion(c, &p) {
+		return
+	}
+
+	// 2. --- Insert ---
+	now := time.Now()
+	p.CreatedBy, p.CreatedAt, p.UpdatedAt = managerID, now, now
+	result, err := h.DB.ExecContext(ctx, `
+		INSERT INTO promotions
+		(name, code, kind, value, max_discount, min_spend, category_id, first_order_only,
+		 usage_limit, per_user_limit, starts_at, ends_at, is_active, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.Name, p.Code, p.Kind, p.Value, p.MaxDiscount, p.MinSpend, p.CategoryID, p.FirstOrderOnly,
+		p.UsageLimit, p.PerUserLimit, p.StartsAt, p.EndsAt, p.IsActive, p.CreatedBy, now, now)
+	if err != nil {
+		if respondDuplicate(c, err, "A promotion with this code already exists.") {
+			return
+		}
+		apierror.Internal(c, "Failed to create promotion")
+		return
+	}
+	p.ID, _ = result.LastInsertId()
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Promotion created", "promotion": p})
+}
+
+// UpdatePromotion is the handler for PUT /v1/manager/promotions/:id
+// Orders that already used the promotion keep their discount.
+func (h *Handlers) UpdatePromotion(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get ID & Load ---
+	promotionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Promotion not found")
+		return
+	}
+	p, err := getPromotion(ctx, h.DB, promotionID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Promotion not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch promotion")
+		return
+	}
+
+	// 2. --- Bind & Save ---
+	if !h.bindPromotion(c, p) {
+		return
+	}
+	p.UpdatedAt = time.Now()
+	_, err = h.DB.ExecContext(ctx, `
+		UPDATE promotions
+		SET name = ?, code = ?, kind = ?, value = ?, max_discount = ?, min_spend = ?, category_id = ?,
+		    first_order_only = ?, usage_limit = ?, per_user_limit = ?, starts_at = ?, ends_at = ?,
+		    is_active = ?, updated_at = ?
+		WHERE id = ?`,
+		p.Name, p.Code, p.Kind, p.Value, p.MaxDiscount, p.MinSpend, p.CategoryID,
+		p.FirstOrderOnly, p.UsageLimit, p.PerUserLimit, p.StartsAt, p.EndsAt,
+		p.IsActive, p.UpdatedAt, p.ID)
+	if err != nil {
+		if respondDuplicate(c, err, "A promotion with this code already exists.") {
+			return
+		}
+		apierror.Internal(c, "Failed to update promotion")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion updated", "promotion": p})
+}
+
+// redemptionStats aggregates order_discounts per promotion over orders that
+// were not cancelled.
+const redemptionStats = `
+	LEFT JOIN (
+		SELECT od.promotion_id, COUNT(*) AS redemptions, SUM(od.amount) AS total_discount
+		FROM order_discounts od
+		JOIN orders o ON od.order_id = o.id
+		WHERE o.status <> 'cancelled'
+		GROUP BY od.promotion_id
+	) r ON r.promotion_id = p.id`
+
+// getPromotion loads one promotion with its redemption stats.
+func getPromotion(ctx context.Context, q Querier, id int64) (*models.Promotion, error) {
+	var redemptions int
+	var total money.Money
+	p, err := scanPromotion(q.QueryRowContext(ctx,
+		"SELECT "+promotionColumns+", COALESCE(r.redemptions, 0), COALESCE(r.total_discount, 0) FROM promotions p"+
+			redemptionStats+" WHERE p.id = ?", id),
+		&redemptions, &total)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.Redemptions, p.TotalDiscount = redemptions, total
+	return &p, nil
+}
+
+// promotionCursor is the pagination key for promotion listings.
+func promotionCursor(p models.Promotion) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+// GetPromotions is the handler for GET /v1/manager/promotions
+// Each promotion carries its redemption count and total discount.
+func (h *Handlers) GetPromotions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	page := list.Page
+	cursorCond, cursorArgs := page.Where("p.created_at", "p.id")
+	query := "SELECT " + promotionColumns + ", COALESCE(r.redemptions, 0), COALESCE(r.total_discount, 0) FROM promotions p" +
+		redemptionStats + " WHERE 1 = 1" + cursorCond + page.OrderLimit("p.created_at", "p.id")
+
+	rows, err := h.readDB().QueryContext(ctx, query, cursorArgs...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch promotions")
+		return
+	}
+	defer rows.Close()
+
+	promotions := []models.Promotion{}
+	for rows.Next() {
+		var redemptions int
+		var total money.Money
+		p, err := scanPromotion(rows, &redemptions, &total)
+		if err != nil {
+			apierror.Internal(c, "Failed to scan promotion")
+			return
+		}
+		p.Redemptions, p.TotalDiscount = redemptions, total
+		promotions = append(promotions, p)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	promotions, nextCursor := pagination.Paginate(page, promotions, promotionCursor)
+	c.JSON(http.StatusOK, gin.H{"promotions": promotions, "nextCursor": nextCursor})
+}
+
+// redemptionCursor is the pagination key for redemption reports.
+func redemptionCursor(d models.OrderDiscount) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+}
+
+// GetPromotionRedemptions is the handler for GET /v1/manager/promotions/:id/redemptions
+// It reports the promotion's totals and lists every order that used it
+// (cancelled ones included, but not counted in the totals).
+func (h *Handlers) GetPromotionRedemptions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get ID & Totals ---
+	promotionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Promotion not found")
+		return
+	}
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	page := list.Page
+	p, err := getPromotion(ctx, h.readDB(), promotionID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Promotion not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch promotion")
+		return
+	}
+
+	// 2. --- Redemptions (Keyset Pagination) ---
+	cursorCond, cursorArgs := page.Where("od.created_at", "od.id")
+	query := `
+		SELECT od.id, od.order_id, od.promotion_id, od.user_id, od.code, od.description, od.amount, od.created_at,
+		       o.public_id, o.status, o.total
+		FROM order_discounts od
+		JOIN orders o ON od.order_id = o.id
+		WHERE od.promotion_id = ?` + cursorCond + page.OrderLimit("od.created_at", "od.id")
+
+	rows, err := h.readDB().QueryContext(ctx, query, append([]interface{}{promotionID}, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch redemptions")
+		return
+	}
+	defer rows.Close()
+
+	redemptions := []models.OrderDiscount{}
+	for rows.Next() {
+		var d models.OrderDiscount
+		if err := rows.Scan(
+			&d.ID, &d.OrderID, &d.PromotionID, &d.UserID, &d.Code, &d.Description, &d.Amount, &d.CreatedAt,
+			&d.OrderPublicID, &d.OrderStatus, &d.OrderTotal,
+		); err != nil {
+			apierror.Internal(c, "Failed to scan redemption")
+			return
+		}
+		redemptions = append(redemptions, d)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	redemptions, nextCursor := pagination.Paginate(page, redemptions, redemptionCursor)
+	c.JSON(http.StatusOK, gin.H{
+		"promotion":   p,
+		"redemptions": redemptions,
+		"nextCursor":  nextCursor,
+	})
+}
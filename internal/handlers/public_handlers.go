@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Public Storefront Reads ---
+//
+
+// The /public routes need no login and answer the same for every caller, so
+// middleware.PublicCache lets a CDN keep them (see PUBLIC_CACHE_MAX_AGE).
+// Nothing here may depend on the caller: no userID, no role.
+
+// GetPublicProduct is the handler for GET /v1/public/products/:id
+// It returns the catalogue view of an active product, as dropshippers see it.
+func (h *Handlers) GetPublicProduct(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	h.getCatalogueProduct(c, productID)
+}
+
+// GetPublicCategory is the handler for GET /v1/public/categories/:slug
+// It returns one category with its subcategories, from the cached tree.
+func (h *Handlers) GetPublicCategory(c *gin.Context) {
+	tree, err := h.categoryTree(c.Request.Context())
+	if err != nil {
+		apierror.Internal(c, "Database error")
+		return
+	}
+	category, err := findCategory(tree, c.Param("slug"))
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Category not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"category": category})
+}
+
+// findCategory searches the tree depth-first for slug.
+func findCategory(tree []models.Category, slug string) (*models.Category, error) {
+	for i := range tree {
+		if tree[i].Slug == slug {
+			return &tree[i], nil
+		}
+		if found, err := findCategory(tree[i].Children, slug); err == nil {
+			return found, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
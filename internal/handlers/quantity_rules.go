@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// QuantityRule is a product's order quantity rule: every cart line needs at
+// least MinQuantity units, in multiples of Increment. It is also the details
+// payload of a quantity_rule error, so the cart can offer Suggested instead.
+type QuantityRule struct {
+	ProductID   int64 `json:"productId"`
+	MinQuantity int   `json:"minQuantity"`
+	Increment   int   `json:"increment"`
+	Requested   int   `json:"requested,omitempty"`
+	Suggested   int   `json:"suggested,omitempty"` // the nearest allowed quantity at or above Requested
+}
+
+// quantityRuleOf reads a product's rule.
+func quantityRuleOf(ctx context.Context, q Querier, productID int64) (QuantityRule, error) {
+	r := QuantityRule{ProductID: productID}
+	err := q.QueryRowContext(ctx, "SELECT min_order_qty, order_increment FROM products WHERE id = ?", productID).
+		Scan(&r.MinQuantity, &r.Increment)
+	return r, err
+}
+
+// allows reports whether qty satisfies the rule.
+func (r QuantityRule) allows(qty int) bool {
+	return qty >= r.MinQuantity && qty%max(r.Increment, 1) == 0
+}
+
+// suggest returns the smallest allowed quantity at or above qty.
+func (r QuantityRule) suggest(qty int) int {
+	inc := max(r.Increment, 1)
+	qty = max(qty, r.MinQuantity)
+	return (qty + inc - 1) / inc * inc
+}
+
+// message explains the rule qty breaks.
+func (r QuantityRule) message(qty int) string {
+	if qty < r.MinQuantity {
+		return fmt.Sprintf("Product ID %d has a minimum order quantity of %d", r.ProductID, r.MinQuantity)
+	}
+	return fmt.Sprintf("Product ID %d is sold in multiples of %d", r.ProductID, r.Increment)
+}
+
+// checkQuantity answers with a quantity_rule error (status 400 or 409) and
+// reports false when qty breaks the rule.
+func checkQuantity(c *gin.Context, status int, r QuantityRule, qty int) bool {
+	if r.allows(qty) {
+		return true
+	}
+	r.Requested, r.Suggested = qty, r.suggest(qty)
+	apierror.WithDetails(c, status, apierror.CodeQuantityRule, r.message(qty), r)
+	return false
+}
@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Product Q&A ---
+//
+// Dropshippers ask questions on a product page and the product's supplier
+// answers them; both sides are notified. Managers hide abusive questions,
+// which leave the public listing and the supplier's unanswered count.
+
+// questionColumns is the column list scanned by scanQuestion.
+const questionColumns = `
+	q.id, q.product_id, q.dropshipper_id, q.question, q.answer, q.answered_at,
+	q.status, q.moderation_note, q.moderated_by, q.moderated_at, q.created_at, q.updated_at,
+	u.full_name, p.name`
+
+// questionJoins joins the asker and product for questionColumns.
+const questionJoins = " FROM product_questions q JOIN users u ON q.dropshipper_id = u.id JOIN products p ON q.product_id = p.id "
+
+func scanQuestion(row interface{ Scan(...interface{}) error }) (models.ProductQuestion, error) {
+	var q models.ProductQuestion
+	err := row.Scan(
+		&q.ID, &q.ProductID, &q.DropshipperID, &q.Question, &q.Answer, &q.AnsweredAt,
+		&q.Status, &q.ModerationNote, &q.ModeratedBy, &q.ModeratedAt, &q.CreatedAt, &q.UpdatedAt,
+		&q.AskerName, &q.ProductName,
+	)
+	return q, err
+}
+
+// getQuestion loads one question; lock adds FOR UPDATE (use it on a transaction).
+func getQuestion(ctx context.Context, db Querier, id int64, lock bool) (*models.ProductQuestion, error) {
+	query := "SELECT " + questionColumns + questionJoins + "WHERE q.id = ?"
+	if lock {
+		query += " FOR UPDATE"
+	}
+	q, err := scanQuestion(db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// questionCursor is the pagination key for question listings.
+func questionCursor(q models.ProductQuestion) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: q.CreatedAt, ID: q.ID}
+}
+
+// listQuestions answers one page of the list of questions matching where
+// (starting with "WHERE"). public strips the moderation details and shortens
+// the asker to a first name.
+func (h *Handlers) listQuestions(c *gin.Context, list pagination.List, public bool, where string, args ...interface{}) {
+	ctx := c.Request.Context()
+
+	page := list.Page
+	cursorCond, cursorArgs := page.Where("q.created_at", "q.id")
+	query := "SELECT " + questionColumns + questionJoins + where + cursorCond + page.OrderLimit("q.created_at", "q.id")
+
+	rows, err := h.readDB().QueryContext(ctx, query, append(args, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch questions")
+		return
+	}
+	defer rows.Close()
+
+	questions := []models.ProductQuestion{}
+	for rows.Next() {
+		q, err := scanQuestion(rows)
+		if err != nil {
+			apierror.Internal(c, "Failed to scan question")
+			return
+		}
+		if public {
+			q.Status, q.ModerationNote, q.ModeratedBy, q.ModeratedAt = "", nil, nil, nil
+			q.AskerName = firstName(q.AskerName)
+		}
+		questions = append(questions, q)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	questions, nextCursor := pagination.Paginate(page, questions, questionCursor)
+	c.JSON(http.StatusOK, gin.H{"questions": questions, "nextCursor": nextCursor})
+}
+
+// GetProductQuestions is the handler for GET /v1/products/:id/questions (public)
+// filter[answered]=true lists only the answered questions.
+func (h *Handlers) GetProductQuestions(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"answered"}})
+	if !ok {
+		return
+	}
+	where := "WHERE q.product_id = ? AND q.status = 'published' AND " + store.NotDeleted("p")
+	if list.Filter("answered") == "true" {
+		where += " AND q.answer IS NOT NULL"
+	}
+	h.listQuestions(c, list, true, where, productID)
+}
+
+//
+// --- Dropshipper ---
+//
+
+// AskQuestionInput defines the JSON for a new question
+type AskQuestionInput struct {
+	Question string `json:"question" binding:"required,max=1000"`
+}
+
+// AskQuestion is the handler for POST /v1/dropshipper/products/:id/questions
+func (h *Handlers) AskQuestion(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+
+	var input AskQuestionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	text := sanitize.Text(input.Question)
+	if text == "" {
+		apierror.BadRequest(c, "A question is required")
+		return
+	}
+
+	// 2. --- Check the Product is Listed ---
+	var supplierID int64
+	var productName string
+	err = h.DB.QueryRowContext(ctx,
+		"SELECT supplier_id, name FROM products WHERE id = ? AND status = 'active' AND deleted_at IS NULL",
+		productID).Scan(&supplierID, &productName)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch product")
+		return
+	}
+
+	// 3. --- Insert & Notify the Supplier ---
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	question := models.ProductQuestion{
+		ProductID:     productID,
+		DropshipperID: dropshipperID,
+		Question:      text,
+		Status:        "published",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		ProductName:   productName,
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO product_questions (product_id, dropshipper_id, question, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		question.ProductID, question.DropshipperID, question.Question, question.Status, now, now)
+	if err != nil {
+		apierror.Internal(c, "Failed to save question")
+		return
+	}
+	question.ID, _ = result.LastInsertId()
+
+	message := fmt.Sprintf("New question on \"%s\" is waiting for your answer.", productName)
+	if err := h.AddBatchedNotification(ctx, tx, supplierID, "question", message, "/supplier/questions"); err != nil {
+		apierror.Internal(c, "Failed to notify supplier")
+		return
+	}
+
+	// 4. --- Auto-reply while the Supplier is on Vacation ---
+	autoReply, err := vacationAutoReply(ctx, tx, supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to check the supplier")
+		return
+	}
+	if autoReply != "" {
+		message := fmt.Sprintf("Auto-reply from the supplier of \"%s\": %s", productName, autoReply)
+		if err := h.AddNotification(ctx, tx, dropshipperID, message, "/dropshipper/questions"); err != nil {
+			apierror.Internal(c, "Failed to send notification")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+
+	response := gin.H{"message": "Question posted", "question": question}
+	if autoReply != "" {
+		response["autoReply"] = autoReply
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetMyQuestions is the handler for GET /v1/dropshipper/questions
+func (h *Handlers) GetMyQuestions(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	h.listQuestions(c, list, false, "WHERE q.dropshipper_id = ?", userID_raw.(int64))
+}
+
+//
+// --- Supplier ---
+//
+
+// GetSupplierQuestions is the handler for GET /v1/supplier/questions
+// Questions on the supplier's products, including hidden ones.
+// filter[unanswered]=true lists only the visible questions still waiting for an answer.
+func (h *Handlers) GetSupplierQuestions(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"unanswered"}})
+	if !ok {
+		return
+	}
+	where := "WHERE p.supplier_id = ? AND " + store.NotDeleted("p")
+	if list.Filter("unanswered") == "true" {
+		where += " AND q.answer IS NULL AND q.status = 'published'"
+	}
+	h.listQuestions(c, list, false, where, userID_raw.(int64))
+}
+
+// AnswerQuestionInput defines the JSON for a supplier answer
+type AnswerQuestionInput struct {
+	Answer string `json:"answer" binding:"required,max=2000"`
+}
+
+// AnswerQuestion is the handler for POST /v1/supplier/questions/:id/answer
+// Answering again replaces the earlier answer; the asker is notified once.
+func (h *Handlers) AnswerQuestion(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	questionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Question not found")
+		return
+	}
+
+	var input AnswerQuestionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	answer := sanitize.Text(input.Answer)
+	if answer == "" {
+		apierror.BadRequest(c, "An answer is required")
+		return
+	}
+
+	// 2. --- Load & Verify Ownership ---
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	question, err := getQuestion(ctx, tx, questionID, true)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.NotFound(c, "Question not found")
+		} else {
+			apierror.Internal(c, "Failed to fetch question")
+		}
+		return
+	}
+	var owner int64
+	if err := tx.QueryRowContext(ctx, "SELECT supplier_id FROM products WHERE id = ?", question.ProductID).Scan(&owner); err != nil || owner != supplierID {
+		apierror.NotFound(c, "Question not found")
+		return
+	}
+
+	// 3. --- Save & Notify the Asker ---
+	firstAnswer := question.Answer == nil
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, "UPDATE product_questions SET answer = ?, answered_at = ?, updated_at = ? WHERE id = ?", answer, now, now, question.ID); err != nil {
+		apierror.Internal(c, "Failed to save answer")
+		return
+	}
+	if firstAnswer {
+		message := fmt.Sprintf("The supplier answered your question on \"%s\".", question.ProductName)
+		if err := h.AddNotification(ctx, tx, question.DropshipperID, message, "/dropshipper/questions"); err != nil {
+			apierror.Internal(c, "Failed to notify dropshipper")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	question.Answer, question.AnsweredAt, question.UpdatedAt = &answer, &now, now
+
+	c.JSON(http.StatusOK, gin.H{"message": "Answer saved", "question": question})
+}
+
+//
+// --- Manager: Moderation ---
+//
+
+// GetQuestionsForModeration is the handler for GET /v1/manager/questions
+// filter[status] picks published or hidden; without it every question is listed.
+func (h *Handlers) GetQuestionsForModeration(c *gin.Context) {
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"status"}})
+	if !ok {
+		return
+	}
+	switch status := list.Filter("status"); status {
+	case "":
+		h.listQuestions(c, list, false, "WHERE 1 = 1")
+	case "published", "hidden":
+		h.listQuestions(c, list, false, "WHERE q.status = ?", status)
+	default:
+		apierror.BadRequest(c, "status must be one of published, hidden")
+	}
+}
+
+// ModerateQuestionInput defines the JSON for a moderation decision
+type ModerateQuestionInput struct {
+	Action string `json:"action" binding:"required,oneof=hide publish"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+// ModerateQuestion is the handler for PATCH /v1/manager/questions/:id/moderate
+// "hide" removes an abusive question (and its answer) from the product page;
+// "publish" restores it.
+func (h *Handlers) ModerateQuestion(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+	questionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Question not found")
+		return
+	}
+
+	var input ModerateQuestionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if input.Action == "hide" && input.Note == "" {
+		apierror.BadRequest(c, "A note is required when hiding a question")
+		return
+	}
+
+	// 2. --- Update the Question ---
+	question, err := getQuestion(ctx, h.DB, questionID, false)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Question not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch question")
+		return
+	}
+
+	status := "published"
+	if input.Action == "hide" {
+		status = "hidden"
+	}
+	note := sanitize.Text(input.Note)
+	now := time.Now()
+	_, err = h.DB.ExecContext(ctx, `
+		UPDATE product_questions
+		SET status = ?, moderation_note = ?, moderated_by = ?, moderated_at = ?, updated_at = ?
+		WHERE id = ?`, status, note, managerID, now, now, question.ID)
+	if err != nil {
+		apierror.Internal(c, "Failed to moderate question")
+		return
+	}
+
+	// 3. --- Send Response ---
+	question.Status, question.ModerationNote, question.ModeratedBy, question.ModeratedAt, question.UpdatedAt = status, &note, &managerID, &now, now
+	c.JSON(http.StatusOK, gin.H{"message": "Question " + status, "question": question})
+}
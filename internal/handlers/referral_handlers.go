@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/events"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Referral Program ---
+//
+// Every dropshipper has a referral code. Registering with it records a
+// pending referral; when the new dropshipper completes their first order of
+// at least REFERRAL_MIN_ORDER_TOTAL, the referral is checked for abuse and
+// then rewarded with wallet promo credit (or rejected with the reason).
+
+// referralAlphabet leaves out 0/O and 1/I so codes survive being read aloud.
+const referralAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// referralCodeLength is the length of generated codes.
+const referralCodeLength = 8
+
+// generateReferralCode returns a random code from referralAlphabet.
+func generateReferralCode() (string, error) {
+	buf := make([]byte, referralCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = referralAlphabet[int(b)%len(referralAlphabet)]
+	}
+	return string(buf), nil
+}
+
+// ensureReferralCode returns the user's code, generating it on first use.
+func (h *Handlers) ensureReferralCode(ctx context.Context, userID int64) (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		var code sql.NullString
+		if err := h.DB.QueryRowContext(ctx, "SELECT referral_code FROM users WHERE id = ?", userID).Scan(&code); err != nil {
+			return "", err
+		}
+		if code.Valid {
+			return code.String, nil
+		}
+
+		candidate, err := generateReferralCode()
+		if err != nil {
+			return "", err
+		}
+		// A concurrent request may have set it first; the next round reads it.
+		_, err = h.DB.ExecContext(ctx, "UPDATE users SET referral_code = ? WHERE id = ? AND referral_code IS NULL", candidate, userID)
+		if _, dup := store.DuplicateKey(err); err != nil && !dup {
+			return "", err
+		}
+	}
+	return "", errors.New("referrals: could not generate a unique code")
+}
+
+// referrerByCode returns the active dropshipper who owns code.
+func (h *Handlers) referrerByCode(ctx context.Context, code string) (int64, error) {
+	var id int64
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT id FROM users
+		WHERE referral_code = ? AND role = 'dropshipper' AND status = 'active' AND deleted_at IS NULL`,
+		strings.ToUpper(strings.TrimSpace(code))).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, store.ErrNotFound
+	}
+	return id, err
+}
+
+// attributeReferral records that refereeID registered with referrerID's code.
+// A failure is logged, not returned: it must not fail the registration.
+func (h *Handlers) attributeReferral(ctx context.Context, referrerID, refereeID int64, signupIP string) {
+	now := time.Now()
+	_, err := h.DB.ExecContext(ctx, `
+		INSERT INTO referrals (referrer_id, referee_id, status, signup_ip, created_at, updated_at)
+		VALUES (?, ?, 'pending', ?, ?, ?)`,
+		referrerID, refereeID, signupIP, now, now)
+	if err != nil {
+		logging.Errorf("[Referrals] Failed to attribute user %d to referrer %d: %v", refereeID, referrerID, err)
+	}
+}
+
+// rewardReferral runs when an order is completed. If its dropshipper has a
+// pending referral and the order qualifies, the referral is checked for abuse
+// and rewarded or rejected. It is idempotent, so a retried job pays once.
+func (h *Handlers) rewardReferral(ctx context.Context, e events.OrderCompleted) error {
+	cfg := h.Config.Referrals
+	if cfg.ReferrerReward <= 0 && cfg.RefereeReward <= 0 {
+		return nil
+	}
+	if e.Total < cfg.MinOrderTotal {
+		return nil // too small to qualify; a later order still can
+	}
+
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 1. --- Lock the Pending Referral ---
+	var r models.Referral
+	err = tx.QueryRowContext(ctx,
+		"SELECT id, referrer_id, referee_id, signup_ip FROM referrals WHERE referee_id = ? AND status = 'pending' FOR UPDATE",
+		e.UserID).Scan(&r.ID, &r.ReferrerID, &r.RefereeID, &r.SignupIP)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// 2. --- Anti-Abuse Checks ---
+	reason, err := h.referralAbuse(ctx, tx, &r)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if reason != "" {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE referrals SET status = 'rejected', reject_reason = ?, order_id = ?, updated_at = ? WHERE id = ?",
+			reason, e.OrderID, now, r.ID)
+		if err != nil {
+			return err
+		}
+		logging.Warnf("[Referrals] Referral %d rejected: %s", r.ID, reason)
+		return tx.Commit()
+	}
+
+	// 3. --- Pay the Promo Credit ---
+	notes := fmt.Sprintf("Referral reward (Order #%d)", e.OrderID)
+	if cfg.ReferrerReward > 0 {
+		if err := tx.Wallet.AddTransaction(ctx, r.ReferrerID, "promo_credit", cfg.ReferrerReward, notes); err != nil {
+			return err
+		}
+		message := fmt.Sprintf("A dropshipper you referred completed their first order. RM %s promo credit was added to your wallet.", cfg.ReferrerReward)
+		if err := h.AddNotification(ctx, tx, r.ReferrerID, message, "/dropshipper/referrals"); err != nil {
+			return err
+		}
+	}
+	if cfg.RefereeReward > 0 {
+		if err := tx.Wallet.AddTransaction(ctx, r.RefereeID, "promo_credit", cfg.RefereeReward, notes); err != nil {
+			return err
+		}
+		message := fmt.Sprintf("Welcome bonus: RM %s promo credit was added to your wallet.", cfg.RefereeReward)
+		if err := h.AddNotification(ctx, tx, r.RefereeID, message, "/dropshipper/wallet"); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE referrals
+		SET status = 'rewarded', order_id = ?, referrer_reward = ?, referee_reward = ?, rewarded_at = ?, updated_at = ?
+		WHERE id = ?`,
+		e.OrderID, cfg.ReferrerReward, cfg.RefereeReward, now, now, r.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// referralAbuse returns why a referral must not be rewarded, or "" when it may be.
+func (h *Handlers) referralAbuse(ctx context.Context, q Querier, r *models.Referral) (string, error) {
+	// The referrer must still be an active dropshipper. Locking both users
+	// serializes the rewards of one referrer, so the monthly cap holds.
+	var referrerStatus, referrerPhone, refereePhone string
+	err := q.QueryRowContext(ctx, `
+		SELECT referrer.status, referrer.phone_number, referee.phone_number
+		FROM users referrer, users referee
+		WHERE referrer.id = ? AND referee.id = ? AND referrer.deleted_at IS NULL
+		FOR UPDATE`,
+		r.ReferrerID, r.RefereeID).Scan(&referrerStatus, &referrerPhone, &refereePhone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "referrer account no longer exists", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if referrerStatus != "active" {
+		return "referrer account is not active", nil
+	}
+
+	// The same person behind both accounts.
+	if normalizePhone(referrerPhone) != "" && normalizePhone(referrerPhone) == normalizePhone(refereePhone) {
+		return "referee has the referrer's phone number", nil
+	}
+
+	// Several accounts registered from one network for the same referrer.
+	if r.SignupIP != "" {
+		var seen bool
+		err := q.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM referrals WHERE referrer_id = ? AND signup_ip = ? AND status = 'rewarded' AND id <> ?)`,
+			r.ReferrerID, r.SignupIP, r.ID).Scan(&seen)
+		if err != nil {
+			return "", err
+		}
+		if seen {
+			return "another referral from the same network was already rewarded", nil
+		}
+	}
+
+	// Monthly cap per referrer.
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	var rewarded int
+	err = q.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM referrals WHERE referrer_id = ? AND status = 'rewarded' AND rewarded_at >= ?",
+		r.ReferrerID, monthStart).Scan(&rewarded)
+	if err != nil {
+		return "", err
+	}
+	if rewarded >= h.Config.Referrals.MonthlyCap {
+		return "referrer reached the monthly reward limit", nil
+	}
+	return "", nil
+}
+
+// normalizePhone keeps only the digits of a phone number.
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
+// maskEmail hides an email's local part: "jane@example.com" -> "j***@example.com".
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// firstName is the first word of a full name.
+func firstName(fullName string) string {
+	if fields := strings.Fields(fullName); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// referralCursor is the pagination key for referral listings.
+func referralCursor(r models.Referral) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+}
+
+// GetMyReferrals is the handler for GET /v1/dropshipper/referrals
+// It returns the dropshipper's code, the reward terms, totals and one page of referrals.
+func (h *Handlers) GetMyReferrals(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Dropshipper ID & Code ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	page := list.Page
+	code, err := h.ensureReferralCode(ctx, dropshipperID)
+	if err != nil {
+		apierror.Internal(c, "Failed to get referral code")
+		return
+	}
+
+	// 2. --- Totals ---
+	var stats models.ReferralStats
+	err = h.readDB().QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COALESCE(SUM(status = 'pending'), 0),
+		       COALESCE(SUM(status = 'rewarded'), 0),
+		       COALESCE(SUM(status = 'rejected'), 0),
+		       COALESCE(SUM(referrer_reward), 0)
+		FROM referrals WHERE referrer_id = ?`, dropshipperID).
+		Scan(&stats.Total, &stats.Pending, &stats.Rewarded, &stats.Rejected, &stats.Earned)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch referral stats")
+		return
+	}
+
+	// 3. --- Referrals (Keyset Pagination) ---
+	cursorCond, cursorArgs := page.Where("r.created_at", "r.id")
+	query := `
+		SELECT r.id, r.referrer_id, r.referee_id, r.status, r.referrer_reward, r.referee_reward,
+		       r.reject_reason, r.created_at, r.updated_at, r.rewarded_at, u.full_name, u.email
+		FROM referrals r
+		JOIN users u ON r.referee_id = u.id
+		WHERE r.referrer_id = ?` + cursorCond + page.OrderLimit("r.created_at", "r.id")
+
+	rows, err := h.readDB().QueryContext(ctx, query, append([]interface{}{dropshipperID}, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch referrals")
+		return
+	}
+	defer rows.Close()
+
+	referrals := []models.Referral{}
+	for rows.Next() {
+		var r models.Referral
+		var name, email string
+		if err := rows.Scan(
+			&r.ID, &r.ReferrerID, &r.RefereeID, &r.Status, &r.ReferrerReward, &r.RefereeReward,
+			&r.RejectReason, &r.CreatedAt, &r.UpdatedAt, &r.RewardedAt, &name, &email,
+		); err != nil {
+			apierror.Internal(c, "Failed to scan referral")
+			return
+		}
+		r.RefereeName, r.RefereeEmail = firstName(name), maskEmail(email)
+		referrals = append(referrals, r)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	// 4. --- Return Response ---
+	referrals, nextCursor := pagination.Paginate(page, referrals, referralCursor)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"terms": gin.H{
+			"referrerReward": h.Config.Referrals.ReferrerReward,
+			"refereeReward":  h.Config.Referrals.RefereeReward,
+			"minOrderTotal":  h.Config.Referrals.MinOrderTotal,
+		},
+		"stats":      stats,
+		"referrals":  referrals,
+		"nextCursor": nextCursor,
+	})
+}
@@ -0,0 +1,494 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Product Reviews ---
+//
+// Dropshippers rate products they bought in a completed order. Suppliers can
+// reply to and report reviews of their products; managers hide abusive ones.
+// Hidden reviews leave the public listing and the product's rating.
+
+// reviewColumns is the column list scanned by scanReview.
+const reviewColumns = `
+	r.id, r.product_id, r.dropshipper_id, r.order_id, r.rating, r.comment,
+	r.supplier_reply, r.replied_at, r.status, r.flag_reason, r.moderation_note,
+	r.moderated_by, r.moderated_at, r.created_at, r.updated_at,
+	u.full_name, p.name`
+
+// reviewJoins joins the reviewer and product for reviewColumns.
+const reviewJoins = " FROM product_reviews r JOIN users u ON r.dropshipper_id = u.id JOIN products p ON r.product_id = p.id "
+
+func scanReview(row interface{ Scan(...interface{}) error }) (models.ProductReview, error) {
+	var r models.ProductReview
+	err := row.Scan(
+		&r.ID, &r.ProductID, &r.DropshipperID, &r.OrderID, &r.Rating, &r.Comment,
+		&r.SupplierReply, &r.RepliedAt, &r.Status, &r.FlagReason, &r.ModerationNote,
+		&r.ModeratedBy, &r.ModeratedAt, &r.CreatedAt, &r.UpdatedAt,
+		&r.ReviewerName, &r.ProductName,
+	)
+	return r, err
+}
+
+// getReview loads one review; lock adds FOR UPDATE (use it on a transaction).
+func getReview(ctx context.Context, q Querier, id int64, lock bool) (*models.ProductReview, error) {
+	query := "SELECT " + reviewColumns + reviewJoins + "WHERE r.id = ?"
+	if lock {
+		query += " FOR UPDATE"
+	}
+	r, err := scanReview(q.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// reviewCursor is the pagination key for review listings.
+func reviewCursor(r models.ProductReview) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+}
+
+// listReviews answers one page of the list of reviews matching where
+// (starting with "WHERE"). public strips the moderation details.
+func (h *Handlers) listReviews(c *gin.Context, list pagination.List, public bool, where string, args ...interface{}) {
+	ctx := c.Request.Context()
+
+	page := list.Page
+	cursorCond, cursorArgs := page.Where("r.created_at", "r.id")
+	query := "SELECT " + reviewColumns + reviewJoins + where + cursorCond + page.OrderLimit("r.created_at", "r.id")
+
+	rows, err := h.readDB().QueryContext(ctx, query, append(args, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch reviews")
+		return
+	}
+	defer rows.Close()
+
+	reviews := []models.ProductReview{}
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			apierror.Internal(c, "Failed to scan review")
+			return
+		}
+		if public {
+			r.Status, r.FlagReason, r.ModerationNote, r.ModeratedBy, r.ModeratedAt = "", nil, nil, nil, nil
+		}
+		reviews = append(reviews, r)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	reviews, nextCursor := pagination.Paginate(page, reviews, reviewCursor)
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews, "nextCursor": nextCursor})
+}
+
+// GetProductReviews is the handler for GET /v1/products/:id/reviews (public)
+func (h *Handlers) GetProductReviews(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	h.listReviews(c, list, true, "WHERE r.product_id = ? AND r.status <> 'hidden' AND "+store.NotDeleted("p"), productID)
+}
+
+//
+// --- Dropshipper ---
+//
+
+// ReviewInput defines the JSON for creating or editing a review
+type ReviewInput struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+// CreateReview is the handler for POST /v1/dropshipper/products/:id/reviews
+// Only dropshippers with a completed order containing the product may review it.
+func (h *Handlers) CreateReview(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+
+	var input ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	// 2. --- Verify the Purchase ---
+	var orderID int64
+	err = h.DB.QueryRowContext(ctx, `
+		SELECT o.id
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		WHERE o.user_id = ? AND oi.product_id = ? AND o.status = 'completed' AND o.deleted_at IS NULL
+		ORDER BY o.updated_at DESC
+		LIMIT 1`, dropshipperID, productID).Scan(&orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Forbidden(c, "You can only review products from your completed orders")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to verify purchase")
+		return
+	}
+
+	// 3. --- Insert & Update the Aggregate ---
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	review := models.ProductReview{
+		ProductID:     productID,
+		DropshipperID: dropshipperID,
+		OrderID:       orderID,
+		Rating:        input.Rating,
+		Comment:       sanitize.Text(input.Comment),
+		Status:        "published",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO product_reviews
+		(product_id, dropshipper_id, order_id, rating, comment, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		review.ProductID, review.DropshipperID, review.OrderID, review.Rating, review.Comment, review.Status, now, now)
+	if err != nil {
+		if respondDuplicate(c, err, "You have already reviewed this product. Edit your review instead.") {
+			return
+		}
+		apierror.Internal(c, "Failed to save review")
+		return
+	}
+	review.ID, _ = result.LastInsertId()
+
+	if err := tx.Products.RefreshRating(ctx, productID); err != nil {
+		apierror.Internal(c, "Failed to update product rating")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	h.invalidateProducts(ctx, productID)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Review published", "review": review})
+}
+
+// UpdateReview is the handler for PUT /v1/dropshipper/reviews/:id
+// A hidden review stays hidden after an edit.
+func (h *Handlers) UpdateReview(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	reviewID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Review not found")
+		return
+	}
+
+	var input ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	review, err := getReview(ctx, tx, reviewID, true)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && review.DropshipperID != dropshipperID) {
+		apierror.NotFound(c, "Review not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch review")
+		return
+	}
+
+	review.Rating, review.Comment, review.UpdatedAt = input.Rating, sanitize.Text(input.Comment), time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE product_reviews SET rating = ?, comment = ?, updated_at = ? WHERE id = ?",
+		review.Rating, review.Comment, review.UpdatedAt, review.ID)
+	if err != nil {
+		apierror.Internal(c, "Failed to update review")
+		return
+	}
+	if err := tx.Products.RefreshRating(ctx, review.ProductID); err != nil {
+		apierror.Internal(c, "Failed to update product rating")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	h.invalidateProducts(ctx, review.ProductID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review updated", "review": review})
+}
+
+// GetMyReviews is the handler for GET /v1/dropshipper/reviews
+func (h *Handlers) GetMyReviews(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	h.listReviews(c, list, false, "WHERE r.dropshipper_id = ?", userID_raw.(int64))
+}
+
+//
+// --- Supplier ---
+//
+
+// GetSupplierReviews is the handler for GET /v1/supplier/reviews
+// Reviews of the supplier's products, including hidden ones.
+func (h *Handlers) GetSupplierReviews(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	h.listReviews(c, list, false, "WHERE p.supplier_id = ?", userID_raw.(int64))
+}
+
+// ReplyToReviewInput defines the JSON for a supplier reply
+type ReplyToReviewInput struct {
+	Reply string `json:"reply" binding:"required,max=2000"`
+}
+
+// ReplyToReview is the handler for POST /v1/supplier/reviews/:id/reply
+// Replying again replaces the earlier reply.
+func (h *Handlers) ReplyToReview(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+
+	var input ReplyToReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	reply := sanitize.Text(input.Reply)
+	if reply == "" {
+		apierror.BadRequest(c, "A reply is required")
+		return
+	}
+
+	review, ok := h.supplierReview(c, supplierID)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if _, err := h.DB.ExecContext(ctx, "UPDATE product_reviews SET supplier_reply = ?, replied_at = ?, updated_at = ? WHERE id = ?", reply, now, now, review.ID); err != nil {
+		apierror.Internal(c, "Failed to save reply")
+		return
+	}
+	review.SupplierReply, review.RepliedAt, review.UpdatedAt = &reply, &now, now
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reply saved", "review": review})
+}
+
+// ReportReviewInput defines the JSON for reporting an abusive review
+type ReportReviewInput struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// ReportReview is the handler for POST /v1/supplier/reviews/:id/report
+// The review stays visible and joins the managers' moderation queue.
+func (h *Handlers) ReportReview(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+
+	var input ReportReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	review, ok := h.supplierReview(c, supplierID)
+	if !ok {
+		return
+	}
+	if review.Status != "published" {
+		apierror.Conflict(c, "This review has already been reported or moderated")
+		return
+	}
+
+	reason := sanitize.Text(input.Reason)
+	if _, err := h.DB.ExecContext(ctx, "UPDATE product_reviews SET status = 'flagged', flag_reason = ?, updated_at = ? WHERE id = ? AND status = 'published'", reason, time.Now(), review.ID); err != nil {
+		apierror.Internal(c, "Failed to report review")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review reported. A manager will look at it."})
+}
+
+// supplierReview loads the :id review if it is of one of supplierID's
+// products; otherwise it answers 404 and returns false.
+func (h *Handlers) supplierReview(c *gin.Context, supplierID int64) (*models.ProductReview, bool) {
+	ctx := c.Request.Context()
+
+	reviewID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Review not found")
+		return nil, false
+	}
+	review, err := getReview(ctx, h.DB, reviewID, false)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.NotFound(c, "Review not found")
+		} else {
+			apierror.Internal(c, "Failed to fetch review")
+		}
+		return nil, false
+	}
+	var owner int64
+	if err := h.DB.QueryRowContext(ctx, "SELECT supplier_id FROM products WHERE id = ?", review.ProductID).Scan(&owner); err != nil || owner != supplierID {
+		apierror.NotFound(c, "Review not found")
+		return nil, false
+	}
+	return review, true
+}
+
+//
+// --- Manager: Moderation ---
+//
+
+// GetReviewsForModeration is the handler for GET /v1/manager/reviews
+// filter[status]=flagged is the moderation queue; without it every review is listed.
+func (h *Handlers) GetReviewsForModeration(c *gin.Context) {
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"status"}})
+	if !ok {
+		return
+	}
+	switch status := list.Filter("status"); status {
+	case "":
+		h.listReviews(c, list, false, "WHERE 1 = 1")
+	case "published", "flagged", "hidden":
+		h.listReviews(c, list, false, "WHERE r.status = ?", status)
+	default:
+		apierror.BadRequest(c, "status must be one of published, flagged, hidden")
+	}
+}
+
+// ModerateReviewInput defines the JSON for a moderation decision
+type ModerateReviewInput struct {
+	Action string `json:"action" binding:"required,oneof=hide publish"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+// ModerateReview is the handler for PATCH /v1/manager/reviews/:id/moderate
+// "hide" removes an abusive review from the listing and the rating; "publish"
+// restores it (or dismisses a report).
+func (h *Handlers) ModerateReview(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+	reviewID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Review not found")
+		return
+	}
+
+	var input ModerateReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if input.Action == "hide" && input.Note == "" {
+		apierror.BadRequest(c, "A note is required when hiding a review")
+		return
+	}
+
+	// 2. --- Update the Review & the Aggregate ---
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	review, err := getReview(ctx, tx, reviewID, true)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Review not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch review")
+		return
+	}
+
+	status := "published"
+	if input.Action == "hide" {
+		status = "hidden"
+	}
+	note := sanitize.Text(input.Note)
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		UPDATE product_reviews
+		SET status = ?, moderation_note = ?, moderated_by = ?, moderated_at = ?, updated_at = ?
+		WHERE id = ?`, status, note, managerID, now, now, review.ID)
+	if err != nil {
+		apierror.Internal(c, "Failed to moderate review")
+		return
+	}
+	if err := tx.Products.RefreshRating(ctx, review.ProductID); err != nil {
+		apierror.Internal(c, "Failed to update product rating")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	h.invalidateProducts(ctx, review.ProductID)
+
+	// 3. --- Send Response ---
+	review.Status, review.ModerationNote, review.ModeratedBy, review.ModeratedAt, review.UpdatedAt = status, &note, &managerID, &now, now
+	c.JSON(http.StatusOK, gin.H{"message": "Review " + status, "review": review})
+}
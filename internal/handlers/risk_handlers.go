@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/events"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/risk"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Risk Review ---
+//
+// Checkout and wallet top-up score the operation (see package risk) before
+// going through. A flagged checkout creates its order 'under-review': the
+// stock is reserved and what the balance covers is held, as for an on-hold
+// order, but nothing is paid. A flagged top-up is not credited. Either waits
+// in the manager queue; approving goes on as if it had not been flagged,
+// rejecting cancels the order (or drops the top-up, which then counts as a
+// failed one).
+
+// orderUnderReview is the status of an order waiting for a risk review.
+const orderUnderReview = "under-review"
+
+// riskColumns is the column list scanned by scanRiskReview.
+const riskColumns = `
+	r.id, r.user_id, r.kind, r.order_id, r.amount, r.score, r.reasons, r.status,
+	r.review_note, r.reviewed_by, r.reviewed_at, r.created_at, u.full_name, u.email`
+
+func scanRiskReview(row interface{ Scan(...interface{}) error }) (models.RiskReview, error) {
+	var r models.RiskReview
+	var reasons []byte
+	err := row.Scan(
+		&r.ID, &r.UserID, &r.Kind, &r.OrderID, &r.Amount, &r.Score, &reasons, &r.Status,
+		&r.ReviewNote, &r.ReviewedBy, &r.ReviewedAt, &r.CreatedAt, &r.UserName, &r.UserEmail,
+	)
+	if err != nil {
+		return r, err
+	}
+	err = json.Unmarshal(reasons, &r.Reasons)
+	return r, err
+}
+
+// assessRisk scores a checkout (shipTo is its address) or top-up (shipTo nil)
+// of userID.
+func (h *Handlers) assessRisk(ctx context.Context, q Querier, userID int64, shipTo *models.ShipTo) (risk.Assessment, error) {
+	rules := risk.Rules{
+		MaxOrdersPerHour:  h.Config.Risk.MaxOrdersPerHour,
+		MaxFailedTopups:   h.Config.Risk.MaxFailedTopups,
+		ReviewScore:       h.Config.Risk.ReviewScore,
+		DisposableDomains: h.Config.Risk.DisposableDomains,
+	}
+	var s risk.Signals
+	now := time.Now()
+	if err := q.QueryRowContext(ctx, "SELECT email FROM users WHERE id = ?", userID).Scan(&s.Email); err != nil {
+		return risk.Assessment{}, err
+	}
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM orders WHERE user_id = ? AND created_at >= ?",
+		userID, now.Add(-time.Hour)).Scan(&s.RecentOrders)
+	if err != nil {
+		return risk.Assessment{}, err
+	}
+	err = q.QueryRowContext(ctx, "SELECT COUNT(*) FROM risk_reviews WHERE user_id = ? AND kind = 'topup' AND status = 'rejected' AND reviewed_at >= ?",
+		userID, now.Add(-24*time.Hour)).Scan(&s.FailedTopups)
+	if err != nil {
+		return risk.Assessment{}, err
+	}
+	if shipTo != nil {
+		s.State, s.Postcode = shipTo.State, shipTo.Postcode
+	}
+	return rules.Assess(s), nil
+}
+
+// flagForReview queues a flagged operation and returns the review's ID;
+// orderID is 0 for a top-up.
+func flagForReview(ctx context.Context, q Querier, userID int64, kind string, orderID int64, amount money.Money, a risk.Assessment) (int64, error) {
+	reasons, err := json.Marshal(a.Reasons)
+	if err != nil {
+		return 0, err
+	}
+	result, err := q.ExecContext(ctx, `
+		INSERT INTO risk_reviews (user_id, kind, order_id, amount, score, reasons, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'pending', ?)`,
+		userID, kind, sql.NullInt64{Int64: orderID, Valid: orderID != 0}, amount, a.Score, reasons, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+//
+// --- Manager: Review Queue ---
+//
+
+// riskCursor is the pagination key for the review queue.
+func riskCursor(r models.RiskReview) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+}
+
+// GetRiskReviews is the handler for GET /v1/manager/risk-reviews
+// filter[status] (default pending) and filter[kind] (order or topup) narrow
+// the queue.
+func (h *Handlers) GetRiskReviews(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"status", "kind"}})
+	if !ok {
+		return
+	}
+	where, args := " WHERE r.status = ?", []interface{}{"pending"}
+	switch status := list.Filter("status"); status {
+	case "":
+	case "pending", "approved", "rejected":
+		args[0] = status
+	default:
+		apierror.BadRequest(c, "status must be one of pending, approved, rejected")
+		return
+	}
+	switch kind := list.Filter("kind"); kind {
+	case "":
+	case "order", "topup":
+		where += " AND r.kind = ?"
+		args = append(args, kind)
+	default:
+		apierror.BadRequest(c, "kind must be one of order, topup")
+		return
+	}
+
+	page := list.Page
+	cursorCond, cursorArgs := page.Where("r.created_at", "r.id")
+	query := "SELECT " + riskColumns + " FROM risk_reviews r JOIN users u ON r.user_id = u.id" +
+		where + cursorCond + page.OrderLimit("r.created_at", "r.id")
+	rows, err := h.DB.QueryContext(ctx, query, append(args, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch risk reviews")
+		return
+	}
+	defer rows.Close()
+
+	reviews := []models.RiskReview{}
+	for rows.Next() {
+		r, err := scanRiskReview(rows)
+		if err != nil {
+			apierror.Internal(c, "Failed to scan risk review")
+			return
+		}
+		reviews = append(reviews, r)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	reviews, nextCursor := pagination.Paginate(page, reviews, riskCursor)
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews, "nextCursor": nextCursor})
+}
+
+// ReviewRiskInput defines the JSON for a manager's decision.
+type ReviewRiskInput struct {
+	Action string `json:"action" binding:"required,oneof=approve reject"`
+	Note   string `json:"note" binding:"max=5000"`
+}
+
+// errReviewClosed means the order left 'under-review' behind the review's back.
+var errReviewClosed = errors.New("the order is no longer under review")
+
+// ReviewRisk is the handler for PATCH /v1/manager/risk-reviews/:id
+// Approving an order pays it from the wallet like PayOrder, or leaves it
+// on-hold (keeping the hold) when the balance no longer covers it; the unpaid
+// deadline still runs from checkout. Approving a top-up credits it. Rejecting
+// cancels the order, giving back its stock and hold, or drops the top-up.
+func (h *Handlers) ReviewRisk(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+	reviewID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Risk review not found")
+		return
+	}
+
+	var input ReviewRiskInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if input.Action == "reject" && input.Note == "" {
+		apierror.BadRequest(c, "A note is required when rejecting")
+		return
+	}
+
+	// 2. --- Begin Transaction ---
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	// 3. --- Lock & Check the Review ---
+	r, err := scanRiskReview(tx.QueryRowContext(ctx,
+		"SELECT "+riskColumns+" FROM risk_reviews r JOIN users u ON r.user_id = u.id WHERE r.id = ? FOR UPDATE", reviewID))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.NotFound(c, "Risk review not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch risk review")
+		return
+	}
+	if r.Status != "pending" {
+		apierror.Conflict(c, "This review has already been decided")
+		return
+	}
+
+	// 4. --- Carry Out the Decision ---
+	var order *models.Order
+	var outcome, message string
+	var productIDs []int64
+	switch {
+	case r.Kind == "topup" && input.Action == "approve":
+		notes := fmt.Sprintf("Top-up (risk review #%d)", r.ID)
+		if err := tx.Wallet.AddTransaction(ctx, r.UserID, "topup", r.Amount, notes); err != nil {
+			apierror.Internal(c, "Failed to record transaction")
+			return
+		}
+		outcome = "credited"
+		message = fmt.Sprintf("Your top-up of RM %s was approved and credited.", r.Amount)
+	case r.Kind == "topup":
+		outcome = "rejected"
+		message = fmt.Sprintf("Your top-up of RM %s was not approved.", r.Amount)
+	case input.Action == "approve":
+		order, outcome, err = h.approveReviewedOrder(ctx, tx, r)
+		if errors.Is(err, errReviewClosed) || errors.Is(err, errPreorderUnfunded) {
+			apierror.Conflict(c, err.Error())
+			return
+		}
+		if err != nil {
+			apierror.Internal(c, "Failed to approve order")
+			return
+		}
+		message = fmt.Sprintf("Order #%d passed review and is now %s.", order.ID, outcome)
+	default:
+		order, productIDs, err = h.rejectReviewedOrder(ctx, tx, r)
+		if errors.Is(err, errReviewClosed) {
+			apierror.Conflict(c, err.Error())
+			return
+		}
+		if err != nil {
+			apierror.Internal(c, "Failed to cancel order")
+			return
+		}
+		outcome = "cancelled"
+		message = fmt.Sprintf("Order #%d was cancelled after review.", order.ID)
+	}
+
+	// 5. --- Close the Review & Tell the User ---
+	now := time.Now()
+	r.Status = "approved"
+	if input.Action == "reject" {
+		r.Status = "rejected"
+	}
+	note := sanitize.Text(input.Note)
+	r.ReviewNote = sql.NullString{String: note, Valid: note != ""}
+	r.ReviewedBy = sql.NullInt64{Int64: managerID, Valid: true}
+	r.ReviewedAt = sql.NullTime{Time: now, Valid: true}
+	_, err = tx.ExecContext(ctx, "UPDATE risk_reviews SET status = ?, review_note = ?, reviewed_by = ?, reviewed_at = ? WHERE id = ?",
+		r.Status, r.ReviewNote, managerID, now, r.ID)
+	if err != nil {
+		apierror.Internal(c, "Failed to update risk review")
+		return
+	}
+
+	link := "/dropshipper/wallet"
+	if order != nil {
+		link = "/dropshipper/orders/" + order.PublicID
+	}
+	if err := h.AddNotification(ctx, tx, r.UserID, message, link); err != nil {
+		apierror.Internal(c, "Failed to notify user")
+		return
+	}
+
+	// 6. --- Commit ---
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	h.invalidateProducts(ctx, productIDs...)
+	if order != nil && outcome == "processing" {
+		h.Events.Publish(ctx, events.OrderPaid{OrderID: order.ID, OrderPublicID: order.PublicID, UserID: order.UserID, Total: order.Total})
+	}
+	logging.Infof("[Risk] Review %d (%s of User %d) %s by Manager %d: %s", r.ID, r.Kind, r.UserID, r.Status, managerID, outcome)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Risk review " + r.Status, "outcome": outcome, "review": r})
+}
+
+// errPreorderUnfunded means a pre-order's hold no longer covers it: there is
+// no unpaid pre-order, so it can only be rejected.
+var errPreorderUnfunded = errors.New("the wallet no longer covers this pre-order; reject it instead")
+
+// approveReviewedOrder pays an order under review from the wallet (its own
+// hold counts towards it) and returns it with its new status: 'processing',
+// 'pre-order' when it has pre-order lines, or 'on-hold' when the balance falls
+// short.
+func (h *Handlers) approveReviewedOrder(ctx context.Context, tx *store.Tx, r models.RiskReview) (*models.Order, string, error) {
+	order, err := tx.Orders.GetForUpdate(ctx, r.OrderID.Int64, r.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if order.Status != orderUnderReview {
+		return nil, "", errReviewClosed
+	}
+
+	lines, err := tx.Orders.StockLines(ctx, order.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	status := "processing"
+	for _, line := range lines {
+		if line.Preorder {
+			status = "pre-order"
+		}
+	}
+
+	balance, err := tx.Wallet.LockBalance(ctx, r.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	held, err := tx.Wallet.Held(ctx, r.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	available := balance - held
+	hold, err := tx.Wallet.OrderHold(ctx, order.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, "", err
+	}
+	if hold != nil {
+		available += hold.Amount
+	}
+
+	now := time.Now()
+	if available < order.Total {
+		if status == "pre-order" {
+			return nil, "", errPreorderUnfunded
+		}
+		return order, "on-hold", tx.Orders.UpdateStatus(ctx, order.ID, "on-hold")
+	}
+	notes := fmt.Sprintf("Payment for Order ID %d", order.ID)
+	if err := tx.Wallet.AddTransaction(ctx, r.UserID, "order_payment", -order.Total, notes); err != nil {
+		return nil, "", err
+	}
+	if err := tx.Wallet.CloseHold(ctx, order.ID, store.HoldCaptured); err != nil {
+		return nil, "", err
+	}
+	if err := tx.Orders.UpdateStatus(ctx, order.ID, status); err != nil {
+		return nil, "", err
+	}
+	if status == "processing" {
+		if err := h.stampShipsBy(ctx, tx, order.ID, now); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := issueInvoices(ctx, tx, order.ID, now); err != nil {
+		return nil, "", err
+	}
+	return order, status, nil
+}
+
+// rejectReviewedOrder cancels an order under review, giving back its stock
+// and wallet hold, and returns it with the products whose stock changed.
+func (h *Handlers) rejectReviewedOrder(ctx context.Context, tx *store.Tx, r models.RiskReview) (*models.Order, []int64, error) {
+	order, err := tx.Orders.GetForUpdate(ctx, r.OrderID.Int64, r.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.Status != orderUnderReview {
+		return nil, nil, errReviewClosed
+	}
+	productIDs, err := releaseOrderStock(ctx, tx, order.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Wallet.CloseHold(ctx, order.ID, store.HoldReleased); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Orders.UpdateStatus(ctx, order.ID, "cancelled"); err != nil {
+		return nil, nil, err
+	}
+	return order, productIDs, nil
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Bulk Shipment Upload (CSV) ---
+//
+// One row per parcel: the order (its "Order Number", or its "Order ID", the
+// public ID or the number), the "Tracking" number and optionally the
+// "Courier" code. Other columns are ignored, so the supplier order export
+// (GET /supplier/orders/export) can be filled in and uploaded back: its rows
+// repeat each order per line, which is fine as long as they agree, and rows
+// left without a tracking number are skipped.
+//
+// Like the product import it is all or nothing: every row is checked as a
+// single shipment would be (the order must hold the supplier's items) and
+// nothing is recorded while any row fails. ?dryRun=true only checks.
+
+// shipmentColumns maps the headers the upload reads, lower-cased without
+// spaces or underscores, to their field.
+var shipmentColumns = map[string]string{
+	"ordernumber":    "number",
+	"orderid":        "id",
+	"tracking":       "tracking",
+	"trackingnumber": "tracking",
+	"courier":        "courier",
+}
+
+// shipmentRow is the shipment of one order in the file, and its first row.
+type shipmentRow struct {
+	row      int
+	orderID  int64
+	tracking string
+	courier  string
+}
+
+// ImportShipments is the handler for POST /v1/supplier/orders/shipments/import
+// It takes a CSV file in the multipart field "file" (see the section comment
+// for the columns) and marks every order in it shipped, or none.
+func (h *Handlers) ImportShipments(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	dryRun := c.Query("dryRun") == "true"
+
+	// 1. --- Open the Upload ---
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Uploads.MaxRequestBytes)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		h.uploadError(c, err, "No file uploaded")
+		return
+	}
+	if !strings.EqualFold(filepath.Ext(fh.Filename), ".csv") {
+		apierror.UnsupportedMediaType(c, "Upload the shipments as a .csv file (save spreadsheets as CSV first)")
+		return
+	}
+	file, err := fh.Open()
+	if err != nil {
+		apierror.Internal(c, "Failed to read the file")
+		return
+	}
+	defer file.Close()
+
+	// 2. --- Read the Header ---
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // spreadsheets drop trailing empty cells
+	header, err := reader.Read()
+	if err == io.EOF {
+		apierror.BadRequest(c, "The file is empty")
+		return
+	}
+	if err != nil {
+		apierror.BadRequest(c, "The file is not valid CSV")
+		return
+	}
+	columns := map[string]int{}
+	for i, col := range header {
+		col = strings.ToLower(strings.TrimPrefix(col, "\ufeff")) // Excel's BOM
+		col = strings.NewReplacer(" ", "", "_", "").Replace(col)
+		if field, ok := shipmentColumns[col]; ok {
+			if _, dup := columns[field]; !dup {
+				columns[field] = i
+			}
+		}
+	}
+	orderCol, ok := columns["number"]
+	if !ok {
+		orderCol, ok = columns["id"]
+	}
+	trackingCol, hasTracking := columns["tracking"]
+	if !ok || !hasTracking {
+		apierror.BadRequest(c, "The file needs an Order Number (or Order ID) and a Tracking column")
+		return
+	}
+	courierCol, hasCourier := columns["courier"]
+	cell := func(record []string, i int) string {
+		if i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "DB Transaction failed")
+		return
+	}
+	defer tx.Rollback()
+
+	// 3. --- Read & Validate the Rows ---
+	var shipments []*shipmentRow
+	var rowErrors []ImportRowError
+	byOrder := map[int64]*shipmentRow{}
+	for num := 2; len(rowErrors) < maxImportErrors; num++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: num, Message: "is not valid CSV"})
+			break
+		}
+		if num-1 > maxImportRows {
+			apierror.BadRequest(c, fmt.Sprintf("The file has more than %d rows; split it into smaller files", maxImportRows))
+			return
+		}
+
+		ref, tracking := cell(record, orderCol), cell(record, trackingCol)
+		if tracking == "" {
+			continue // not shipped yet
+		}
+		var courier string
+		if hasCourier {
+			courier = cell(record, courierCol)
+		}
+		if ref == "" {
+			rowErrors = append(rowErrors, ImportRowError{Row: num, Field: "order", Message: "is required"})
+			continue
+		}
+
+		// Order numbers are the IDs; anything else is a public ID.
+		orderID, err := strconv.ParseInt(ref, 10, 64)
+		if err != nil {
+			err = tx.QueryRowContext(ctx, "SELECT id FROM orders WHERE public_id = ? AND deleted_at IS NULL", ref).Scan(&orderID)
+			if err == sql.ErrNoRows {
+				rowErrors = append(rowErrors, ImportRowError{Row: num, Field: "order", Message: "You cannot fulfill an order that doesn't belong to you"})
+				continue
+			}
+			if err != nil {
+				apierror.Internal(c, "Failed to verify order")
+				return
+			}
+		}
+
+		// The export repeats an order on each of its lines.
+		if prev, seen := byOrder[orderID]; seen {
+			if prev.tracking != tracking || prev.courier != courier {
+				rowErrors = append(rowErrors, ImportRowError{Row: num, Field: "tracking", Message: fmt.Sprintf("Order %s has another tracking number or courier on row %d", ref, prev.row)})
+			}
+			continue
+		}
+		s := &shipmentRow{row: num, orderID: orderID, tracking: tracking, courier: courier}
+		byOrder[orderID] = s
+		shipments = append(shipments, s)
+	}
+	if len(rowErrors) > 0 {
+		respondImportErrors(c, rowErrors)
+		return
+	}
+	if len(shipments) == 0 {
+		apierror.BadRequest(c, "The file has no tracking numbers")
+		return
+	}
+
+	// 4. --- Record Every Shipment in One Transaction ---
+	type shipped struct {
+		Row     int   `json:"row"`
+		OrderID int64 `json:"orderId"`
+	}
+	results := make([]shipped, 0, len(shipments))
+	for _, s := range shipments {
+		err := h.shipOrder(ctx, tx, tx.Orders, s.orderID, supplierID, s.tracking, s.courier)
+		var se *shipmentError
+		switch {
+		case err == nil:
+			results = append(results, shipped{Row: s.row, OrderID: s.orderID})
+		case errors.As(err, &se):
+			rowErrors = append(rowErrors, ImportRowError{Row: s.row, Message: se.message})
+		default:
+			apierror.Internal(c, "Failed to update shipment status")
+			return
+		}
+		if len(rowErrors) >= maxImportErrors {
+			break
+		}
+	}
+	if len(rowErrors) > 0 {
+		respondImportErrors(c, rowErrors)
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{"message": "The file is valid", "dryRun": true, "shipments": len(results)})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Commit failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Orders marked as shipped", "shipped": len(results), "orders": results})
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- SKUs ---
+//
+// A supplier's SKUs are unique across their products and variants (the
+// unique index only covers products, so variants are checked here). Blank
+// SKUs are generated from SKU_PATTERN with the supplier's own counter.
+
+// maxSKUAttempts bounds the search for a free generated SKU; numbers are only
+// skipped when a supplier typed SKUs that look like generated ones.
+const maxSKUAttempts = 50
+
+// skuConflictError is a SKU (or, with barcode set, a barcode) that is already
+// taken; its message is the 409 response.
+type skuConflictError struct {
+	message string
+	barcode bool
+}
+
+func (e *skuConflictError) Error() string { return e.message }
+
+// respondSKUError answers a failed assignSKUs call: 409 for a taken SKU.
+func respondSKUError(c *gin.Context, err error) {
+	var conflict *skuConflictError
+	if errors.As(err, &conflict) {
+		apierror.Conflict(c, conflict.message)
+		return
+	}
+	apierror.Internal(c, "Failed to assign SKUs")
+}
+
+// assignSKUs checks the SKUs of one product save against the supplier's other
+// products and variants, and fills the blank ones from SKU_PATTERN. productID
+// is the product being edited (0 on create); its current SKUs are being
+// replaced, so they do not count. tx must be the transaction that writes them.
+func (h *Handlers) assignSKUs(ctx context.Context, tx *store.Tx, supplierID, productID int64, category string, skus []*string) error {
+	if len(skus) == 0 {
+		return nil
+	}
+	if err := tx.Products.LockSKUs(ctx, supplierID); err != nil {
+		return err
+	}
+
+	// 1. --- Check the SKUs the supplier typed ---
+	taken := map[string]bool{}
+	var blank []*string
+	for _, sku := range skus {
+		*sku = strings.TrimSpace(*sku)
+		if *sku == "" {
+			blank = append(blank, sku)
+			continue
+		}
+		if taken[*sku] {
+			return &skuConflictError{message: fmt.Sprintf("SKU %q is used more than once in this product.", *sku)}
+		}
+		taken[*sku] = true
+		use, err := tx.Products.FindSKU(ctx, supplierID, *sku, productID)
+		if err == nil {
+			return &skuConflictError{message: fmt.Sprintf("SKU %q is already used by your product \"%s\".", *sku, use.ProductName)}
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+
+	// 2. --- Generate the blank ones ---
+	for _, sku := range blank {
+		for attempt := 0; *sku == ""; attempt++ {
+			if attempt == maxSKUAttempts {
+				return fmt.Errorf("no free SKU for supplier %d after %d attempts", supplierID, attempt)
+			}
+			seq, err := tx.Products.NextSKUSeq(ctx, supplierID)
+			if err != nil {
+				return err
+			}
+			candidate := h.Config.Products.SKUPattern.Render(supplierID, category, seq)
+			if taken[candidate] {
+				continue
+			}
+			_, err = tx.Products.FindSKU(ctx, supplierID, candidate, productID)
+			if errors.Is(err, store.ErrNotFound) {
+				*sku = candidate
+				taken[candidate] = true
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copySKUs gives the SKUs of a duplicated product the first free "-N" suffix
+// (N from 2) among the supplier's products and variants and each other. Blank
+// ones stay blank for assignSKUs to generate. tx must hold LockSKUs.
+func copySKUs(ctx context.Context, tx *store.Tx, supplierID int64, skus []*string) error {
+	taken := map[string]bool{}
+	for _, sku := range skus {
+		base := strings.TrimSpace(*sku)
+		if base == "" {
+			continue
+		}
+		for n := 2; ; n++ {
+			if n-2 == maxSKUAttempts {
+				return fmt.Errorf("no free copy of SKU %q for supplier %d after %d attempts", base, supplierID, n-2)
+			}
+			candidate := base + "-" + strconv.Itoa(n)
+			if taken[candidate] {
+				continue
+			}
+			_, err := tx.Products.FindSKU(ctx, supplierID, candidate, 0)
+			if errors.Is(err, store.ErrNotFound) {
+				*sku = candidate
+				taken[candidate] = true
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skuCategory is the category slug used for {category}: the first of
+// categoryIDs, or else the first category the product is linked to ("" for none).
+func skuCategory(ctx context.Context, q Querier, productID int64, categoryIDs []int64) (string, error) {
+	var slug string
+	var err error
+	switch {
+	case len(categoryIDs) > 0:
+		err = q.QueryRowContext(ctx, "SELECT slug FROM categories WHERE id = ?", categoryIDs[0]).Scan(&slug)
+	case productID > 0:
+		err = q.QueryRowContext(ctx, `
+			SELECT c.slug FROM product_categories pc JOIN categories c ON c.id = pc.category_id
+			WHERE pc.product_id = ? ORDER BY c.id LIMIT 1`, productID).Scan(&slug)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return slug, err
+}
+
+// CheckSKU is the handler for GET /v1/supplier/skus/check
+// It tells the product form whether ?sku= is free among the supplier's
+// products and variants (ignoring ?productId=, the product being edited).
+// Without a sku it previews the SKU that would be generated for ?categoryId=.
+func (h *Handlers) CheckSKU(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Supplier ID & Parse Query ---
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+
+	var productID, categoryID int64
+	for _, p := range []struct {
+		name, message string
+		dest          *int64
+	}{
+		{"productId", "Invalid productId", &productID},
+		{"categoryId", "Invalid categoryId", &categoryID},
+	} {
+		if raw := c.Query(p.name); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || id < 1 {
+				apierror.BadRequest(c, p.message)
+				return
+			}
+			*p.dest = id
+		}
+	}
+	sku := strings.TrimSpace(c.Query("sku"))
+
+	// 2. --- Preview a Generated SKU ---
+	if sku == "" {
+		var categoryIDs []int64
+		if categoryID > 0 {
+			categoryIDs = []int64{categoryID}
+		}
+		category, err := skuCategory(ctx, h.DB, productID, categoryIDs)
+		if err != nil {
+			apierror.Internal(c, "Failed to check SKU")
+			return
+		}
+		seq, err := h.Store.Products.PeekSKUSeq(ctx, supplierID)
+		if err != nil {
+			apierror.Internal(c, "Failed to check SKU")
+			return
+		}
+		// Only a preview: another save may take this number first.
+		c.JSON(http.StatusOK, gin.H{
+			"sku":       h.Config.Products.SKUPattern.Render(supplierID, category, seq),
+			"available": true,
+			"generated": true,
+		})
+		return
+	}
+
+	// 3. --- Look It Up ---
+	use, err := h.Store.Products.FindSKU(ctx, supplierID, sku, productID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusOK, gin.H{"sku": sku, "available": true})
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to check SKU")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sku": sku, "available": false, "usedBy": use})
+}
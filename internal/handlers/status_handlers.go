@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Platform Status & Changelog ---
+//
+// Managers post incidents, scheduled maintenance windows and release notes;
+// the frontend reads them from GET /v1/status (banners) and GET /v1/changelog
+// (release notes), so neither needs a deploy. A maintenance window with
+// blocksWrites pauses write requests while it is active (AuthMiddleware).
+
+// statusEntryColumns is the column list scanned by scanStatusEntry.
+const statusEntryColumns = `
+	id, kind, title, body, severity, release_tag, starts_at, ends_at,
+	blocks_writes, is_published, created_by, created_at, updated_at`
+
+func scanStatusEntry(row interface{ Scan(...interface{}) error }) (models.StatusEntry, error) {
+	var e models.StatusEntry
+	err := row.Scan(
+		&e.ID, &e.Kind, &e.Title, &e.Body, &e.Severity, &e.ReleaseTag, &e.StartsAt, &e.EndsAt,
+		&e.BlocksWrites, &e.IsPublished, &e.CreatedBy, &e.CreatedAt, &e.UpdatedAt,
+	)
+	return e, err
+}
+
+// getStatusEntry loads one entry.
+func getStatusEntry(ctx context.Context, db Querier, id int64) (*models.StatusEntry, error) {
+	e, err := scanStatusEntry(db.QueryRowContext(ctx, "SELECT "+statusEntryColumns+" FROM status_entries WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// statusEntryCursor is the pagination key for entry listings (by start time).
+func statusEntryCursor(e models.StatusEntry) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: e.StartsAt, ID: e.ID}
+}
+
+// severityRank orders incident severities for the overall status.
+var severityRank = map[string]int{"info": 0, "minor": 1, "major": 2, "critical": 3}
+
+// GetPlatformStatus is the handler for GET /v1/status (public)
+// It returns the overall state, the ongoing incidents and the maintenance
+// windows that are active or still to come. status is "operational",
+// "degraded" (a minor incident), "maintenance" (writes are paused) or
+// "major_outage" (a major or critical incident).
+func (h *Handlers) GetPlatformStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	now := time.Now()
+
+	// 1. --- Ongoing Incidents & Current/Upcoming Maintenance ---
+	query := "SELECT " + statusEntryColumns + ` FROM status_entries
+		WHERE is_published = 1 AND kind IN ('incident', 'maintenance')
+		  AND (ends_at IS NULL OR ends_at > ?)
+		  AND (kind = 'maintenance' OR starts_at <= ?)
+		ORDER BY starts_at ASC, id ASC`
+	rows, err := h.readDB().QueryContext(ctx, query, now, now)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch platform status")
+		return
+	}
+	defer rows.Close()
+
+	incidents := []models.StatusEntry{}
+	maintenance := []models.StatusEntry{}
+	worst, writesPaused := 0, false
+	for rows.Next() {
+		e, err := scanStatusEntry(rows)
+		if err != nil {
+			apierror.Internal(c, "Failed to scan status entry")
+			return
+		}
+		e.CreatedBy = 0
+		if e.Kind == "incident" {
+			incidents = append(incidents, e)
+			worst = max(worst, severityRank[e.Severity])
+			continue
+		}
+		if e.BlocksWrites && !e.StartsAt.After(now) {
+			writesPaused = true
+		}
+		maintenance = append(maintenance, e)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	// 2. --- Overall State (an outage outranks maintenance) ---
+	state := "operational"
+	switch {
+	case worst >= severityRank["major"]:
+		state = "major_outage"
+	case writesPaused:
+		state = "maintenance"
+	case worst == severityRank["minor"]:
+		state = "degraded"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":       state,
+		"writesPaused": writesPaused,
+		"incidents":    incidents,
+		"maintenance":  maintenance,
+		"checkedAt":    now,
+	})
+}
+
+// GetChangelog is the handler for GET /v1/changelog (public)
+// Published release notes, newest first; notes dated in the future appear on their date.
+func (h *Handlers) GetChangelog(c *gin.Context) {
+	list, ok := parseList(c, pagination.ListSpec{Sorts: statusEntrySorts})
+	if !ok {
+		return
+	}
+	h.listStatusEntries(c, list, true, "WHERE kind = 'release' AND is_published = 1 AND starts_at <= ?", time.Now())
+}
+
+// statusEntrySorts are the orders of entry listings, which page by start time.
+var statusEntrySorts = []string{"-startsAt", "startsAt"}
+
+// listStatusEntries answers one page of the list of entries matching where
+// (starting with "WHERE"). public hides who posted the entry.
+func (h *Handlers) listStatusEntries(c *gin.Context, list pagination.List, public bool, where string, args ...interface{}) {
+	ctx := c.Request.Context()
+
+	page := list.Page
+	cursorCond, cursorArgs := page.Where("starts_at", "id")
+	query := "SELECT " + statusEntryColumns + " FROM status_entries " + where + cursorCond + page.OrderLimit("starts_at", "id")
+
+	rows, err := h.readDB().QueryContext(ctx, query, append(args, cursorArgs...)...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch status entries")
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.StatusEntry{}
+	for rows.Next() {
+		e, err := scanStatusEntry(rows)
+		if err != nil {
+			apierror.Internal(c, "Failed to scan status entry")
+			return
+		}
+		if public {
+			e.CreatedBy = 0
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	entries, nextCursor := pagination.Paginate(page, entries, statusEntryCursor)
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "nextCursor": nextCursor})
+}
+
+//
+// --- Manager ---
+//
+
+// StatusEntryInput defines the JSON for creating or replacing a status entry.
+type StatusEntryInput struct {
+	Kind         string     `json:"kind" binding:"required,oneof=incident maintenance release"`
+	Title        string     `json:"title" binding:"required,max=255"`
+	Body         string     `json:"body" binding:"required,max=20000"`
+	Severity     string     `json:"severity" binding:"omitempty,oneof=info minor major critical"` // incidents default to minor
+	ReleaseTag   string     `json:"releaseTag" binding:"max=40"`                                  // releases only, e.g. "2.4.0"
+	StartsAt     *time.Time `json:"startsAt"`                                                     // defaults to now
+	EndsAt       *time.Time `json:"endsAt"`                                                       // required for maintenance
+	BlocksWrites bool       `json:"blocksWrites"`                                                 // maintenance only
+	IsPublished  *bool      `json:"isPublished"`                                                  // defaults to true
+}
+
+// bindStatusEntry binds and checks a StatusEntryInput into e (ID and audit
+// fields are left alone). It answers the request itself and returns false
+// when the input is invalid.
+func bindStatusEntry(c *gin.Context, e *models.StatusEntry) bool {
+	var input StatusEntryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return false
+	}
+
+	e.Kind, e.Title, e.Body = input.Kind, sanitize.Text(input.Title), sanitize.HTML(input.Body)
+	e.Severity = input.Severity
+	if e.Severity == "" {
+		e.Severity = "info"
+		if e.Kind == "incident" {
+			e.Severity = "minor"
+		}
+	}
+	e.ReleaseTag = nil
+	if input.ReleaseTag != "" {
+		tag := sanitize.Text(input.ReleaseTag)
+		e.ReleaseTag = &tag
+	}
+	e.StartsAt, e.EndsAt, e.BlocksWrites, e.IsPublished = time.Now(), input.EndsAt, input.BlocksWrites, true
+	if input.StartsAt != nil {
+		e.StartsAt = *input.StartsAt
+	}
+	if input.IsPublished != nil {
+		e.IsPublished = *input.IsPublished
+	}
+
+	switch {
+	case e.Title == "":
+		apierror.BadRequest(c, "title is required")
+	case e.Kind == "maintenance" && e.EndsAt == nil:
+		apierror.BadRequest(c, "endsAt is required for a maintenance window")
+	case e.EndsAt != nil && !e.EndsAt.After(e.StartsAt):
+		apierror.BadRequest(c, "endsAt must be after startsAt")
+	case e.BlocksWrites && e.Kind != "maintenance":
+		apierror.BadRequest(c, "blocksWrites only applies to maintenance windows")
+	case e.ReleaseTag != nil && e.Kind != "release":
+		apierror.BadRequest(c, "releaseTag only applies to releases")
+	default:
+		return true
+	}
+	return false
+}
+
+// GetStatusEntries is the handler for GET /v1/manager/status-entries
+// Every entry, unpublished ones included; filter[kind] picks a kind.
+func (h *Handlers) GetStatusEntries(c *gin.Context) {
+	list, ok := parseList(c, pagination.ListSpec{Filters: []string{"kind"}, Sorts: statusEntrySorts})
+	if !ok {
+		return
+	}
+	switch kind := list.Filter("kind"); kind {
+	case "":
+		h.listStatusEntries(c, list, false, "WHERE 1 = 1")
+	case "incident", "maintenance", "release":
+		h.listStatusEntries(c, list, false, "WHERE kind = ?", kind)
+	default:
+		apierror.BadRequest(c, "kind must be one of incident, maintenance, release")
+	}
+}
+
+// CreateStatusEntry is the handler for POST /v1/manager/status-entries
+func (h *Handlers) CreateStatusEntry(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Manager ID & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+
+	var e models.StatusEntry
+	if !bindStatusEntry(c, &e) {
+		return
+	}
+
+	// 2. --- Insert ---
+	now := time.Now()
+	e.CreatedBy, e.CreatedAt, e.UpdatedAt = managerID, now, now
+	result, err := h.DB.ExecContext(ctx, `
+		INSERT INTO status_entries
+		(kind, title, body, severity, release_tag, starts_at, ends_at, blocks_writes, is_published,
+		 created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Kind, e.Title, e.Body, e.Severity, e.ReleaseTag, e.StartsAt, e.EndsAt, e.BlocksWrites, e.IsPublished,
+		e.CreatedBy, now, now)
+	if err != nil {
+		apierror.Internal(c, "Failed to create status entry")
+		return
+	}
+	e.ID, _ = result.LastInsertId()
+	h.Status.Invalidate(ctx)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Status entry created", "entry": e})
+}
+
+// UpdateStatusEntry is the handler for PUT /v1/manager/status-entries/:id
+// Ending a maintenance window early is an update of its endsAt.
+func (h *Handlers) UpdateStatusEntry(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get ID & Load ---
+	entryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Status entry not found")
+		return
+	}
+	e, err := getStatusEntry(ctx, h.DB, entryID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Status entry not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch status entry")
+		return
+	}
+
+	// 2. --- Bind & Save ---
+	if !bindStatusEntry(c, e) {
+		return
+	}
+	e.UpdatedAt = time.Now()
+	_, err = h.DB.ExecContext(ctx, `
+		UPDATE status_entries
+		SET kind = ?, title = ?, body = ?, severity = ?, release_tag = ?, starts_at = ?, ends_at = ?,
+		    blocks_writes = ?, is_published = ?, updated_at = ?
+		WHERE id = ?`,
+		e.Kind, e.Title, e.Body, e.Severity, e.ReleaseTag, e.StartsAt, e.EndsAt,
+		e.BlocksWrites, e.IsPublished, e.UpdatedAt, e.ID)
+	if err != nil {
+		apierror.Internal(c, "Failed to update status entry")
+		return
+	}
+	h.Status.Invalidate(ctx)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Status entry updated", "entry": e})
+}
+
+// DeleteStatusEntry is the handler for DELETE /v1/manager/status-entries/:id
+func (h *Handlers) DeleteStatusEntry(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	entryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Status entry not found")
+		return
+	}
+	result, err := h.DB.ExecContext(ctx, "DELETE FROM status_entries WHERE id = ?", entryID)
+	if err != nil {
+		apierror.Internal(c, "Failed to delete status entry")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		apierror.NotFound(c, "Status entry not found")
+		return
+	}
+	h.Status.Invalidate(ctx)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Status entry deleted"})
+}
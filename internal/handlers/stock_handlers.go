@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/events"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+// StockAdjustmentInput defines the JSON for PATCH /products/:id/stock.
+// Exactly one of Delta (units added, negative to remove) and Quantity (the new
+// stock, for a stock count) is sent.
+type StockAdjustmentInput struct {
+	Delta    *int   `json:"delta"`
+	Quantity *int   `json:"quantity" binding:"omitempty,gte=0"`
+	Reason   string `json:"reason" binding:"required,oneof=restock correction damaged lost returned other"`
+	Note     string `json:"note" binding:"max=255"`
+}
+
+// AdjustProductStock is the handler for PATCH /v1/products/:id/stock
+// and PATCH /v1/products/:id/variants/:variantId/stock.
+// It changes only the stock, under a row lock instead of the product's version
+// check, and records the adjustment in stock_movements. A variable product's
+// stock lives on its variants, so it takes the variant route.
+func (h *Handlers) AdjustProductStock(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Check Ownership ---
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found or you do not have permission to edit it")
+		return
+	}
+	var variantID *int64
+	if raw := c.Param("variantId"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			apierror.NotFound(c, "Variant not found")
+			return
+		}
+		variantID = &id
+	}
+	product, err := h.Store.Products.GetOwned(ctx, productID, supplierID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Product not found or you do not have permission to edit it")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Database error checking ownership")
+		return
+	}
+	if product.IsVariable && variantID == nil {
+		apierror.BadRequest(c, "This product has variants; adjust the stock of a variant instead")
+		return
+	}
+	if !product.IsVariable && variantID != nil {
+		apierror.NotFound(c, "Variant not found")
+		return
+	}
+
+	// 2. --- Validate Input ---
+	var input StockAdjustmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if (input.Delta == nil) == (input.Quantity == nil) {
+		apierror.BadRequest(c, "Send either delta or quantity")
+		return
+	}
+	if input.Delta != nil && *input.Delta == 0 {
+		apierror.BadRequest(c, "delta must not be 0")
+		return
+	}
+
+	// 3. --- Apply & Record (stock row locked until commit) ---
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "DB Transaction failed")
+		return
+	}
+	defer tx.Rollback()
+
+	stock, err := tx.Products.StockForUpdate(ctx, productID, variantID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Variant not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to load stock")
+		return
+	}
+	var delta int
+	if input.Delta != nil {
+		delta = *input.Delta
+	} else {
+		delta = *input.Quantity - stock
+	}
+	if stock+delta < 0 {
+		apierror.WithDetails(c, http.StatusBadRequest, apierror.CodeBadRequest,
+			"Stock cannot go below 0", gin.H{"stock": stock})
+		return
+	}
+
+	movement := models.StockMovement{
+		ProductID:     productID,
+		VariantID:     variantID,
+		UserID:        supplierID,
+		Delta:         delta,
+		QuantityAfter: stock + delta,
+		Reason:        input.Reason,
+		Note:          sanitize.Text(input.Note),
+		CreatedAt:     time.Now(),
+	}
+	if delta != 0 {
+		if err := tx.Products.AdjustStock(ctx, productID, variantID, delta); err != nil {
+			apierror.Internal(c, "Failed to update stock")
+			return
+		}
+	}
+	// A stock count that matched is recorded too: it confirms the stock at that time.
+	if err := tx.Products.RecordStockMovement(ctx, &movement); err != nil {
+		apierror.Internal(c, "Failed to record stock movement")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+
+	h.invalidateProducts(ctx, productID)
+	if delta > 0 && variantID == nil {
+		h.Events.Publish(ctx, events.ProductRestocked{ProductID: productID, Stock: movement.QuantityAfter})
+	}
+	if low := lowStockEvent(productID, variantID, movement.QuantityAfter, -delta, h.lowStockThreshold(ctx)); low != nil {
+		h.Events.Publish(ctx, *low)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Stock updated",
+		"movement": movement,
+	})
+}
+
+// GetStockMovements is the handler for GET /v1/products/:id/stock-movements
+// It lists the stock adjustments of one of the supplier's products, newest first.
+func (h *Handlers) GetStockMovements(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Check Ownership ---
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	page := list.Page
+	if _, err := h.Store.Products.GetOwned(ctx, productID, supplierID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.NotFound(c, "Product not found")
+			return
+		}
+		apierror.Internal(c, "Database error checking ownership")
+		return
+	}
+
+	// 2. --- Fetch & Return ---
+	movements, err := h.Store.Products.StockMovements(ctx, productID, page)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch stock movements")
+		return
+	}
+	movements, nextCursor := pagination.Paginate(page, movements, func(m models.StockMovement) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: m.CreatedAt, ID: m.ID}
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"movements":  movements,
+		"nextCursor": nextCursor,
+	})
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Supplier Storefront ---
+//
+
+// fulfillmentDays is the window of the storefront's fulfilment stats.
+const fulfillmentDays = 90
+
+// GetSupplierProfile is the handler for GET /v1/suppliers/:id/profile
+// It returns a supplier's public shop info, rating and fulfilment stats, with
+// one page of their active products (paged, and trimmed by ?fields=, as in search).
+// Unverified, suspended and deleted suppliers are not found.
+func (h *Handlers) GetSupplierProfile(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	supplierID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Supplier not found")
+		return
+	}
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	page := list.Page
+	db := h.readDB()
+	now := time.Now()
+
+	// 1. --- Shop Info ---
+	var profile models.SupplierProfile
+	var companyName sql.NullString
+	var fullName string
+	var away bool
+	var backAt sql.NullTime
+	var awayMessage sql.NullString
+	var handlingDays sql.NullInt64
+	err = db.QueryRowContext(ctx, `
+		SELECT s.public_id, s.company_name, s.full_name, s.city, s.state, s.created_at,
+			`+store.SupplierAway("s")+`, s.vacation_ends_at, s.vacation_message, s.handling_days
+		FROM users s
+		WHERE s.id = ? AND s.role = 'supplier' AND s.status NOT IN ('unverified', 'suspended') AND s.deleted_at IS NULL`,
+		now, supplierID).Scan(&profile.PublicID, &companyName, &fullName, &profile.City, &profile.State, &profile.MemberSince,
+		&away, &backAt, &awayMessage, &handlingDays)
+	if err == sql.ErrNoRows {
+		apierror.NotFound(c, "Supplier not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch supplier")
+		return
+	}
+	profile.Name = fullName
+	if companyName.Valid && companyName.String != "" {
+		profile.Name = companyName.String
+	}
+	profile.Away = away
+	if away {
+		if backAt.Valid {
+			profile.BackAt = &backAt.Time
+		}
+		if awayMessage.Valid && awayMessage.String != "" {
+			profile.AwayMessage = &awayMessage.String
+		}
+	}
+	profile.HandlingDays = h.Config.Shipping.HandlingDays
+	if handlingDays.Valid {
+		profile.HandlingDays = int(handlingDays.Int64)
+	}
+
+	// 2. --- Rating (reviews weighted across products) & Catalogue Size ---
+	err = db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(rating_avg * rating_count) / NULLIF(SUM(rating_count), 0), 0),
+			COALESCE(SUM(rating_count), 0), COALESCE(SUM(status = 'active'), 0)
+		FROM products WHERE supplier_id = ? AND deleted_at IS NULL`,
+		supplierID).Scan(&profile.Rating, &profile.ReviewCount, &profile.ActiveProducts)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch supplier rating")
+		return
+	}
+	profile.Rating = math.Round(profile.Rating*100) / 100
+
+	// 3. --- Fulfilment (their part of the orders paid in the window) ---
+	stats := models.FulfillmentStats{Days: fulfillmentDays}
+	since := now.AddDate(0, 0, -fulfillmentDays)
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(t.status IN ('shipped', 'completed')), 0), COALESCE(SUM(t.late), 0)
+		FROM (
+			SELECT o.id, o.status, MAX(oi.late_notified_at IS NOT NULL) AS late
+			FROM orders o
+			JOIN order_items oi ON oi.order_id = o.id
+			JOIN products p ON p.id = oi.product_id
+			WHERE p.supplier_id = ? AND o.status IN ('processing', 'shipped', 'completed')
+				AND o.created_at >= ? AND o.deleted_at IS NULL
+			GROUP BY o.id, o.status
+		) t`, supplierID, since).Scan(&stats.Orders, &stats.Shipped, &stats.Late)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch fulfillment stats")
+		return
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM disputes WHERE supplier_id = ? AND created_at >= ?", supplierID, since).Scan(&stats.DisputeCount); err != nil {
+		apierror.Internal(c, "Failed to fetch fulfillment stats")
+		return
+	}
+	stats.OnTimeRate = 1
+	if stats.Orders > 0 {
+		stats.OnTimeRate = math.Round((1-float64(stats.Late)/float64(stats.Orders))*1000) / 1000
+	}
+	profile.Fulfillment = stats
+
+	// 4. --- Active Products (same visibility and shape as SearchProducts) ---
+	fields := parseFields(c)
+	products, err := h.Store.Products.Search(ctx, store.ProductSearch{
+		SupplierID:    supplierID,
+		WithRelations: wantsAny(fields, "categories", "brands", "tags", "variants"),
+	}, page)
+	if err != nil {
+		apierror.Internal(c, "Database query failed")
+		return
+	}
+	products, nextCursor := pagination.Paginate(page, products, productCursor)
+	result, err := projectFields(products, fields)
+	if err != nil {
+		apierror.Internal(c, "Failed to build response")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"supplier":   profile,
+		"products":   result,
+		"nextCursor": nextCursor,
+	})
+}
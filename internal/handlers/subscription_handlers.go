@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
 	"github.com/01moynul/taptosell-golang/internal/models"
 	"github.com/gin-gonic/gin"
 )
@@ -17,16 +18,19 @@ import (
 // GetSubscriptionPlans is the handler for GET /v1/subscriptions/plans
 // It retrieves all plans that are marked as 'is_public'.
 func (h *Handlers) GetSubscriptionPlans(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Query Database ---
 	query := `
-		SELECT id, name, description, price, duration_days, ai_credits_included
+		SELECT id, name, description, price, duration_days, ai_credits_included,
+			ai_daily_requests, ai_daily_tokens
 		FROM plans
 		WHERE is_public = 1
 		ORDER BY price ASC
 	`
-	rows, err := h.DB.Query(query)
+	rows, err := h.DB.QueryContext(ctx, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		apierror.Internal(c, "Database query failed")
 		return
 	}
 	defer rows.Close()
@@ -43,8 +47,10 @@ func (h *Handlers) GetSubscriptionPlans(c *gin.Context) {
 			&plan.Price,
 			&plan.DurationDays,
 			&plan.AiCreditsIncluded,
+			&plan.AIDailyRequests,
+			&plan.AIDailyTokens,
 		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan plan row"})
+			apierror.Internal(c, "Failed to scan plan row")
 			return
 		}
 		plan.Description = desc.String
@@ -52,7 +58,7 @@ func (h *Handlers) GetSubscriptionPlans(c *gin.Context) {
 	}
 
 	if err = rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error iterating rows"})
+		apierror.Internal(c, "Error iterating rows")
 		return
 	}
 
@@ -74,33 +80,35 @@ type AssignSubscriptionInput struct {
 // AssignSubscription is the handler for POST /v1/manager/users/:id/subscription
 // It assigns a subscription plan to a user.
 func (h *Handlers) AssignSubscription(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get User ID from URL ---
 	userIDStr := c.Param("id")
 
 	// 2. --- Bind & Validate JSON ---
 	var input AssignSubscriptionInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
 	// 3. --- Begin Transaction ---
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
 
 	// 4. --- Get Plan Details ---
 	var plan models.Plan
-	err = tx.QueryRow("SELECT duration_days, ai_credits_included FROM plans WHERE id = ?", input.PlanID).Scan(&plan.DurationDays, &plan.AiCreditsIncluded)
+	err = tx.QueryRowContext(ctx, "SELECT duration_days, ai_credits_included FROM plans WHERE id = ?", input.PlanID).Scan(&plan.DurationDays, &plan.AiCreditsIncluded)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+			apierror.NotFound(c, "Plan not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get plan details"})
+		apierror.Internal(c, "Failed to get plan details")
 		return
 	}
 
@@ -121,9 +129,9 @@ func (h *Handlers) AssignSubscription(c *gin.Context) {
 		expires_at = VALUES(expires_at),
 		updated_at = VALUES(updated_at)
 	`
-	_, err = tx.Exec(subQuery, userIDStr, input.PlanID, expiresAt, now, now)
+	_, err = tx.ExecContext(ctx, subQuery, userIDStr, input.PlanID, expiresAt, now, now)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign subscription"})
+		apierror.Internal(c, "Failed to assign subscription")
 		return
 	}
 
@@ -138,15 +146,15 @@ func (h *Handlers) AssignSubscription(c *gin.Context) {
 		credits_remaining = credits_remaining + VALUES(credits_remaining),
 		updated_at = VALUES(updated_at)
 	`
-	_, err = tx.Exec(creditQuery, userIDStr, plan.AiCreditsIncluded, now)
+	_, err = tx.ExecContext(ctx, creditQuery, userIDStr, plan.AiCreditsIncluded, now)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add AI credits"})
+		apierror.Internal(c, "Failed to add AI credits")
 		return
 	}
 
 	// 7. --- Commit Transaction ---
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		apierror.Internal(c, "Failed to commit transaction")
 		return
 	}
 
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/01moynul/taptosell-golang/internal/tax"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- SST & Invoices ---
+//
+// Checkout adds SST to the order total: each line is taxed at its category's
+// rate (see package tax) and the tax lines are stored per supplier and rate.
+// Once the order is paid every supplier in it gets an invoice, which copies
+// the amounts and the supplier's SST registration number at issue.
+
+// loadTaxRates reads the manager-set category rates over SST_DEFAULT_RATE.
+func (h *Handlers) loadTaxRates(ctx context.Context, q Querier) (tax.Rates, error) {
+	rates := tax.Rates{Default: h.Config.Tax.DefaultRate, ByCategory: map[int64]float64{}}
+	rows, err := q.QueryContext(ctx, "SELECT category_id, rate FROM tax_rates")
+	if err != nil {
+		return rates, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var categoryID int64
+		var rate float64
+		if err := rows.Scan(&categoryID, &rate); err != nil {
+			return rates, err
+		}
+		rates.ByCategory[categoryID] = rate
+	}
+	return rates, rows.Err()
+}
+
+// orderTax computes the tax lines of a cart and their total.
+func (h *Handlers) orderTax(ctx context.Context, q Querier, items []CartItemData) ([]models.OrderTaxLine, money.Money, error) {
+	rates, err := h.loadTaxRates(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	chains, err := categoryChains(ctx, q, items)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	lines := make([]tax.Line, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, tax.Line{
+			SupplierID: item.SupplierID,
+			Amount:     item.Price.Mul(item.Quantity),
+			Rate:       rates.For(chains[item.ProductID]),
+		})
+	}
+	computed := tax.Compute(lines)
+
+	taxLines := make([]models.OrderTaxLine, 0, len(computed))
+	for _, l := range computed {
+		taxLines = append(taxLines, models.OrderTaxLine{SupplierID: l.SupplierID, Rate: l.Rate, TaxableAmount: l.Taxable, TaxAmount: l.Tax})
+	}
+	return taxLines, tax.Total(computed), nil
+}
+
+// issueInvoices issues the invoice of every supplier in a paid order; a
+// supplier already invoiced keeps theirs. The invoice bills the lines at
+// their full price: platform-funded discounts are not the supplier's.
+func issueInvoices(ctx context.Context, q Querier, orderID int64, at time.Time) error {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO invoices (order_id, supplier_id, supplier_sst_number, subtotal, tax_total, total, issued_at)
+		SELECT ?, s.id, s.sst_number, l.subtotal, COALESCE(t.tax, 0), l.subtotal + COALESCE(t.tax, 0), ?
+		FROM (
+			SELECT supplier_id, ROUND(SUM(quantity * unit_price), 2) AS subtotal
+			FROM order_items
+			WHERE order_id = ?
+			GROUP BY supplier_id
+		) l
+		JOIN users s ON s.id = l.supplier_id
+		LEFT JOIN (
+			SELECT supplier_id, SUM(tax_amount) AS tax FROM order_tax_lines WHERE order_id = ? GROUP BY supplier_id
+		) t ON t.supplier_id = l.supplier_id
+		ON DUPLICATE KEY UPDATE id = id`,
+		orderID, at, orderID, orderID)
+	return err
+}
+
+// invoiceNumber is the printed number of an invoice.
+func invoiceNumber(id int64) string {
+	return fmt.Sprintf("INV-%08d", id)
+}
+
+// orderInvoices loads an order's invoices with their lines and tax lines; a
+// supplierID > 0 keeps only that supplier's.
+func (h *Handlers) orderInvoices(ctx context.Context, orderID, supplierID int64) ([]models.Invoice, error) {
+	query := `
+		SELECT i.id, i.order_id, i.supplier_id, COALESCE(NULLIF(s.company_name, ''), s.full_name),
+			i.supplier_sst_number, i.subtotal, i.tax_total, i.total, i.issued_at
+		FROM invoices i JOIN users s ON s.id = i.supplier_id
+		WHERE i.order_id = ?`
+	args := []interface{}{orderID}
+	if supplierID > 0 {
+		query += " AND i.supplier_id = ?"
+		args = append(args, supplierID)
+	}
+	rows, err := h.DB.QueryContext(ctx, query+" ORDER BY i.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	invoices := []models.Invoice{}
+	for rows.Next() {
+		var inv models.Invoice
+		var name sql.NullString
+		err := rows.Scan(&inv.ID, &inv.OrderID, &inv.SupplierID, &name, &inv.SupplierSSTNumber, &inv.Subtotal, &inv.TaxTotal, &inv.Total, &inv.IssuedAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		inv.Number, inv.SupplierName = invoiceNumber(inv.ID), name.String
+		invoices = append(invoices, inv)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range invoices {
+		inv := &invoices[i]
+		if inv.Items, err = h.Store.Orders.SupplierItems(ctx, orderID, inv.SupplierID); err != nil {
+			return nil, err
+		}
+		if inv.TaxLines, err = h.Store.Orders.TaxLines(ctx, orderID, inv.SupplierID); err != nil {
+			return nil, err
+		}
+	}
+	return invoices, nil
+}
+
+// GetOrderInvoices is the handler for GET /v1/dropshipper/orders/:id/invoices
+// An order has no invoice until it is paid.
+func (h *Handlers) GetOrderInvoices(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Check Ownership ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+	o, err := h.Store.Orders.GetForUser(ctx, orderID, dropshipperID)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch order")
+		return
+	}
+
+	// 2. --- Load ---
+	invoices, err := h.orderInvoices(ctx, o.ID, 0)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch invoices")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"orderPublicId": o.PublicID, "invoices": invoices})
+}
+
+// GetSupplierInvoice is the handler for GET /v1/supplier/orders/:id/invoice
+func (h *Handlers) GetSupplierInvoice(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Invoice not found")
+		return
+	}
+
+	invoices, err := h.orderInvoices(ctx, orderID, supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch invoices")
+		return
+	}
+	if len(invoices) == 0 {
+		apierror.NotFound(c, "Invoice not found")
+		return
+	}
+	c.JSON(http.StatusOK, invoices[0])
+}
+
+//
+// --- Supplier: SST Registration ---
+//
+
+// TaxRegistrationInput sets the supplier's SST registration number; null or
+// empty clears it. It is printed on invoices issued from then on.
+type TaxRegistrationInput struct {
+	SSTNumber *string `json:"sstNumber"`
+}
+
+// GetTaxRegistration is the handler for GET /v1/supplier/tax-registration
+func (h *Handlers) GetTaxRegistration(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	var number *string
+	err := h.DB.QueryRowContext(c.Request.Context(), "SELECT sst_number FROM users WHERE id = ?", userID_raw.(int64)).Scan(&number)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch tax registration")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sstNumber": number})
+}
+
+// UpdateTaxRegistration is the handler for PUT /v1/supplier/tax-registration
+func (h *Handlers) UpdateTaxRegistration(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Supplier ID & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+
+	var input TaxRegistrationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	var number *string
+	if input.SSTNumber != nil && *input.SSTNumber != "" {
+		n, err := tax.NormalizeRegistration(*input.SSTNumber)
+		if err != nil {
+			apierror.BadRequest(c, err.Error())
+			return
+		}
+		number = &n
+	}
+
+	// 2. --- Save ---
+	_, err := h.DB.ExecContext(ctx, "UPDATE users SET sst_number = ?, updated_at = ? WHERE id = ?", number, time.Now(), supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to update tax registration")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sstNumber": number})
+}
+
+//
+// --- Manager: SST Rates ---
+//
+
+// GetTaxRates is the handler for GET /v1/manager/tax-rates
+func (h *Handlers) GetTaxRates(c *gin.Context) {
+	rows, err := h.DB.QueryContext(c.Request.Context(), `
+		SELECT t.category_id, c.name, t.rate, t.updated_by, t.updated_at
+		FROM tax_rates t JOIN categories c ON c.id = t.category_id
+		ORDER BY c.name`)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch tax rates")
+		return
+	}
+	defer rows.Close()
+
+	rates := []models.TaxRate{}
+	for rows.Next() {
+		var r models.TaxRate
+		if err := rows.Scan(&r.CategoryID, &r.CategoryName, &r.Rate, &r.UpdatedBy, &r.UpdatedAt); err != nil {
+			apierror.Internal(c, "Failed to fetch tax rates")
+			return
+		}
+		rates = append(rates, r)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Failed to fetch tax rates")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"defaultRate": h.Config.Tax.DefaultRate, "rates": rates})
+}
+
+// TaxRateInput sets a category's SST rate in percent (0 exempts it).
+type TaxRateInput struct {
+	Rate *float64 `json:"rate" binding:"required,gte=0,lte=100"`
+}
+
+// SetTaxRate is the handler for PUT /v1/manager/tax-rates/:categoryId
+// The rate applies to checkouts from now on and covers the category's
+// subcategories that have none of their own.
+func (h *Handlers) SetTaxRate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	managerID := userID_raw.(int64)
+	categoryID, err := strconv.ParseInt(c.Param("categoryId"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Category not found")
+		return
+	}
+	var input TaxRateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	// 2. --- Check the Category ---
+	var exists bool
+	if err := h.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM categories WHERE id = ?)", categoryID).Scan(&exists); err != nil {
+		apierror.Internal(c, "Failed to fetch category")
+		return
+	}
+	if !exists {
+		apierror.NotFound(c, "Category not found")
+		return
+	}
+
+	// 3. --- Save ---
+	rate := math.Round(*input.Rate*100) / 100
+	_, err = h.DB.ExecContext(ctx, `
+		INSERT INTO tax_rates (category_id, rate, updated_by, updated_at) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE rate = VALUES(rate), updated_by = VALUES(updated_by), updated_at = VALUES(updated_at)`,
+		categoryID, rate, managerID, time.Now())
+	if err != nil {
+		apierror.Internal(c, "Failed to save tax rate")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categoryId": categoryID, "rate": rate})
+}
+
+// DeleteTaxRate is the handler for DELETE /v1/manager/tax-rates/:categoryId
+// The category falls back to its parent's rate, or to the default.
+func (h *Handlers) DeleteTaxRate(c *gin.Context) {
+	categoryID, err := strconv.ParseInt(c.Param("categoryId"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Tax rate not found")
+		return
+	}
+	result, err := h.DB.ExecContext(c.Request.Context(), "DELETE FROM tax_rates WHERE category_id = ?", categoryID)
+	if err != nil {
+		apierror.Internal(c, "Failed to delete tax rate")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		apierror.NotFound(c, "Tax rate not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tax rate removed"})
+}
@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/cache"
 	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/store"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,27 +23,40 @@ func slugify(s string) string {
 	return s
 }
 
+// taxonomyCacheTTL applies to the category tree and brand list.
+// Both change rarely and are invalidated explicitly on every write.
+const taxonomyCacheTTL = 10 * time.Minute
+
 // --- Category Handlers ---
 
 // CreateCategory (Manager Only)
 func (h *Handlers) CreateCategory(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var input models.CreateCategoryInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
-	slug := slugify(input.Name)
+	slug := input.Slug
+	if slug == "" {
+		slug = slugify(input.Name)
+	}
 
 	// Insert into DB
 	query := `INSERT INTO categories (name, slug, parent_id) VALUES (?, ?, ?)`
-	res, err := h.DB.Exec(query, input.Name, slug, input.ParentID)
+	res, err := h.DB.ExecContext(ctx, query, input.Name, slug, input.ParentID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category: " + err.Error()})
+		if respondDuplicate(c, err, "A category with this name already exists.") {
+			return
+		}
+		apierror.Internal(c, "Failed to create category")
 		return
 	}
 
 	id, _ := res.LastInsertId()
+	h.invalidateCache(ctx, cache.KeyCategoryTree)
 
 	// Return the full object so the UI can update the tree immediately
 	newCat := models.Category{
@@ -56,12 +74,27 @@ func (h *Handlers) CreateCategory(c *gin.Context) {
 
 // GetAllCategories (Public - Returns Tree Structure)
 func (h *Handlers) GetAllCategories(c *gin.Context) {
-	// 1. Fetch all categories flat
-	rows, err := h.DB.Query("SELECT id, name, slug, parent_id FROM categories ORDER BY name ASC")
+	rootCats, err := h.categoryTree(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		apierror.Internal(c, "Database error")
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"categories": rootCats})
+}
+
+// categoryTree returns the root categories with their children (cached).
+func (h *Handlers) categoryTree(ctx context.Context) ([]models.Category, error) {
+	// 0. Serve from cache when possible
+	var cachedTree []models.Category
+	if found, _ := h.Cache.Get(ctx, cache.KeyCategoryTree, &cachedTree); found {
+		return cachedTree, nil
+	}
+
+	// 1. Fetch all categories flat
+	rows, err := h.DB.QueryContext(ctx, "SELECT id, name, slug, parent_id FROM categories ORDER BY name ASC")
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var allCats []models.Category
@@ -109,19 +142,23 @@ func (h *Handlers) GetAllCategories(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"categories": rootCats})
+	_ = h.Cache.Set(ctx, cache.KeyCategoryTree, rootCats, taxonomyCacheTTL)
+	return rootCats, nil
 }
 
 // DeleteCategory (Manager Only)
 func (h *Handlers) DeleteCategory(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	id := c.Param("id")
 
 	// Note: We use ON DELETE SET NULL or CASCADE in DB, but let's be safe
-	_, err := h.DB.Exec("DELETE FROM categories WHERE id = ?", id)
+	_, err := h.DB.ExecContext(ctx, "DELETE FROM categories WHERE id = ?", id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		apierror.Internal(c, "Failed to delete category")
 		return
 	}
+	h.invalidateCache(ctx, cache.KeyCategoryTree)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Category deleted"})
 }
@@ -130,21 +167,30 @@ func (h *Handlers) DeleteCategory(c *gin.Context) {
 
 // CreateBrand (Manager Only)
 func (h *Handlers) CreateBrand(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var input models.CreateBrandInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
-	slug := slugify(input.Name)
+	slug := input.Slug
+	if slug == "" {
+		slug = slugify(input.Name)
+	}
 
-	res, err := h.DB.Exec("INSERT INTO brands (name, slug) VALUES (?, ?)", input.Name, slug)
+	res, err := h.DB.ExecContext(ctx, "INSERT INTO brands (name, slug) VALUES (?, ?)", input.Name, slug)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create brand"})
+		if respondDuplicate(c, err, "A brand with this name already exists.") {
+			return
+		}
+		apierror.Internal(c, "Failed to create brand")
 		return
 	}
 
 	id, _ := res.LastInsertId()
+	h.invalidateCache(ctx, cache.KeyBrandList)
 	newBrand := models.Brand{ID: id, Name: input.Name, Slug: slug}
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Brand created", "brand": newBrand})
@@ -152,9 +198,17 @@ func (h *Handlers) CreateBrand(c *gin.Context) {
 
 // GetAllBrands (Public)
 func (h *Handlers) GetAllBrands(c *gin.Context) {
-	rows, err := h.DB.Query("SELECT id, name, slug FROM brands ORDER BY name ASC")
+	ctx := c.Request.Context()
+
+	var cachedBrands []models.Brand
+	if found, _ := h.Cache.Get(ctx, cache.KeyBrandList, &cachedBrands); found {
+		c.JSON(http.StatusOK, gin.H{"brands": cachedBrands})
+		return
+	}
+
+	rows, err := h.DB.QueryContext(ctx, "SELECT id, name, slug FROM brands ORDER BY name ASC")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		apierror.Internal(c, "Database error")
 		return
 	}
 	defer rows.Close()
@@ -168,16 +222,63 @@ func (h *Handlers) GetAllBrands(c *gin.Context) {
 		brands = append(brands, b)
 	}
 
+	_ = h.Cache.Set(ctx, cache.KeyBrandList, brands, taxonomyCacheTTL)
+
 	c.JSON(http.StatusOK, gin.H{"brands": brands})
 }
 
 // DeleteBrand (Manager Only)
 func (h *Handlers) DeleteBrand(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	id := c.Param("id")
-	_, err := h.DB.Exec("DELETE FROM brands WHERE id = ?", id)
+	_, err := h.DB.ExecContext(ctx, "DELETE FROM brands WHERE id = ?", id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete brand"})
+		apierror.Internal(c, "Failed to delete brand")
 		return
 	}
+	h.invalidateCache(ctx, cache.KeyBrandList)
 	c.JSON(http.StatusOK, gin.H{"message": "Brand deleted"})
 }
+
+// --- Tag Handlers ---
+
+// GetAllTags is the handler for GET /v1/tags (Public)
+// Every tag with the number of active products carrying it, by name; the
+// catalogue search filters on them (filter[tag]). Tags are created by saving
+// products with them.
+func (h *Handlers) GetAllTags(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	query := `
+		SELECT t.id, t.name, t.slug, COUNT(p.id)
+		FROM tags t
+		LEFT JOIN product_tags pt ON pt.tag_id = t.id
+		LEFT JOIN products p ON p.id = pt.product_id AND p.status = 'active' AND ` + store.NotDeleted("p") + `
+		GROUP BY t.id, t.name, t.slug
+		ORDER BY t.name ASC`
+	rows, err := h.readDB().QueryContext(ctx, query)
+	if err != nil {
+		apierror.Internal(c, "Database error")
+		return
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		var count int
+		if err := rows.Scan(&t.ID, &t.Name, &t.Slug, &count); err != nil {
+			apierror.Internal(c, "Database error")
+			return
+		}
+		t.ProductCount = &count
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Database error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
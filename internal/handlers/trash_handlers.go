@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Manager: Soft Delete & Restore Handlers ---
+//
+// Deleting users, products, inventory items or orders only sets deleted_at.
+// Managers can list and restore those rows until the retention job purges them.
+
+// trashKinds maps the :kind URL segment to its table and a display label column.
+var trashKinds = map[string]struct {
+	table string
+	label string
+}{
+	"users":     {store.TableUsers, "email"},
+	"products":  {store.TableProducts, "name"},
+	"inventory": {store.TableInventoryItems, "name"},
+	"orders":    {store.TableOrders, "CAST(id AS CHAR)"},
+}
+
+// DeletedRow is one entry of the manager's trash view.
+type DeletedRow struct {
+	ID        int64     `json:"id"`
+	Label     string    `json:"label"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// GetDeleted is the handler for GET /v1/manager/deleted/:kind
+// It lists soft-deleted rows (most recently deleted first) so they can be restored.
+func (h *Handlers) GetDeleted(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	kind, ok := trashKinds[c.Param("kind")]
+	if !ok {
+		apierror.NotFound(c, "Unknown kind (use users, products, inventory or orders)")
+		return
+	}
+
+	// Table and label come from trashKinds, never from the request.
+	query := "SELECT id, " + kind.label + ", deleted_at FROM " + kind.table +
+		" WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT 500"
+	rows, err := h.DB.QueryContext(ctx, query)
+	if err != nil {
+		apierror.Internal(c, "Database query failed")
+		return
+	}
+	defer rows.Close()
+
+	deleted := []DeletedRow{}
+	for rows.Next() {
+		var r DeletedRow
+		var label sql.NullString
+		if err := rows.Scan(&r.ID, &label, &r.DeletedAt); err != nil {
+			apierror.Internal(c, "Failed to scan deleted row")
+			return
+		}
+		r.Label = label.String
+		deleted = append(deleted, r)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+// DeleteUser is the handler for DELETE /v1/manager/users/:id
+// Only dropshipper and supplier accounts can be deleted by managers. The
+// user's tokens are revoked too, so restoring the account later does not
+// revive the sessions it had.
+func (h *Handlers) DeleteUser(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "User not found")
+		return
+	}
+
+	var role string
+	err = h.DB.QueryRowContext(ctx, "SELECT role FROM users WHERE id = ? AND deleted_at IS NULL", id).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.NotFound(c, "User not found")
+			return
+		}
+		apierror.Internal(c, "DB error")
+		return
+	}
+	if role != "dropshipper" && role != "supplier" {
+		apierror.Forbidden(c, "Staff accounts cannot be deleted here")
+		return
+	}
+	if err := store.RevokeTokens(ctx, h.DB, id, time.Now()); err != nil {
+		apierror.Internal(c, "Failed to delete User")
+		return
+	}
+
+	h.softDelete(c, store.TableUsers, id, "User")
+}
+
+// DeleteOrder is the handler for DELETE /v1/manager/orders/:id
+// Only cancelled orders can be deleted; anything else still carries money or stock.
+func (h *Handlers) DeleteOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Order not found")
+		return
+	}
+
+	var status string
+	err = h.DB.QueryRowContext(ctx, "SELECT status FROM orders WHERE id = ? AND deleted_at IS NULL", id).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.NotFound(c, "Order not found")
+			return
+		}
+		apierror.Internal(c, "DB error")
+		return
+	}
+	if status != "cancelled" {
+		apierror.Conflict(c, "Only cancelled orders can be deleted")
+		return
+	}
+
+	h.softDelete(c, store.TableOrders, id, "Order")
+}
+
+// RestoreUser is the handler for PATCH /v1/manager/users/:id/restore
+func (h *Handlers) RestoreUser(c *gin.Context) {
+	h.restore(c, store.TableUsers, "User")
+}
+
+// RestoreProduct is the handler for PATCH /v1/manager/products/:id/restore
+func (h *Handlers) RestoreProduct(c *gin.Context) {
+	h.restore(c, store.TableProducts, "Product")
+}
+
+// RestoreInventoryItem is the handler for PATCH /v1/manager/inventory/:id/restore
+func (h *Handlers) RestoreInventoryItem(c *gin.Context) {
+	h.restore(c, store.TableInventoryItems, "Inventory item")
+}
+
+// RestoreOrder is the handler for PATCH /v1/manager/orders/:id/restore
+func (h *Handlers) RestoreOrder(c *gin.Context) {
+	h.restore(c, store.TableOrders, "Order")
+}
+
+// PurgeProduct is the handler for DELETE /v1/manager/products/:id/purge
+// It hard-deletes a product that is already deleted, without waiting for the
+// retention job. Products that were ever ordered stay: order history joins them.
+func (h *Handlers) PurgeProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found or not deleted")
+		return
+	}
+
+	tx, err := h.Store.Begin(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "DB Transaction failed")
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.Products.Purge(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.NotFound(c, "Product not found or not deleted")
+		return
+	}
+	if errors.Is(err, store.ErrReferenced) {
+		apierror.Conflict(c, "This product has orders, so it is kept for their history")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to purge product")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+	h.invalidateProducts(ctx, id)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product purged"})
+}
+
+// softDelete hides one live row of table and sends the response.
+func (h *Handlers) softDelete(c *gin.Context, table string, id int64, name string) {
+	ctx := c.Request.Context()
+
+	deleted, err := store.SoftDelete(ctx, h.DB, table, id)
+	if err != nil {
+		apierror.Internal(c, "Failed to delete "+name)
+		return
+	}
+	if !deleted {
+		apierror.NotFound(c, name+" not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": name + " deleted successfully"})
+}
+
+// restore brings back the soft-deleted row named by :id and sends the response.
+func (h *Handlers) restore(c *gin.Context, table, name string) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, name+" not found or not deleted")
+		return
+	}
+
+	restored, err := store.Restore(ctx, h.DB, table, id)
+	if err != nil {
+		apierror.Internal(c, "Failed to restore "+name)
+		return
+	}
+	if !restored {
+		apierror.NotFound(c, name+" not found or not deleted")
+		return
+	}
+	if table == store.TableProducts {
+		h.invalidateProducts(ctx, id)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": name + " restored successfully"})
+}
@@ -1,53 +1,216 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/01moynul/taptosell-golang/internal/dto"
+	"github.com/01moynul/taptosell-golang/internal/pii"
+	"github.com/01moynul/taptosell-golang/internal/uploads"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // UploadFile handles POST /v1/upload
-// It saves the file to a local "uploads" folder and returns the URL.
+// It stores a catalogue image in the public uploads folder and returns its URL.
 func (h *Handlers) UploadFile(c *gin.Context) {
-	// 1. Get the file from the request
+	ctx := c.Request.Context()
+
+	// 1. Get the file from the (size-capped) request
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Uploads.MaxRequestBytes)
 	file, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		h.uploadError(c, err, "No file uploaded")
 		return
 	}
 
-	// 2. Create "uploads" directory if it doesn't exist
-	uploadPath := "./uploads"
-	if _, err := os.Stat(uploadPath); os.IsNotExist(err) {
-		os.Mkdir(uploadPath, 0755)
+	// 2. Check and save it under a random name
+	name, err := h.Uploads.Images.Save(ctx, file)
+	if err != nil {
+		h.uploadError(c, err, "Failed to save file")
+		return
 	}
 
-	// 3. Generate a safe unique filename (uuid + extension)
-	ext := filepath.Ext(file.Filename)
-	newFilename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	savePath := filepath.Join(uploadPath, newFilename)
+	// 3. Return the public URL (BASE_URL/uploads, or the bucket's)
+	c.JSON(http.StatusOK, gin.H{
+		"url": h.Uploads.Images.URL(name),
+	})
+}
+
+// UploadSupplierDocuments handles POST /v1/supplier/documents
+// Accepts "ssm_document" and/or "bank_statement" and stores them privately.
+func (h *Handlers) UploadSupplierDocuments(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
 
-	// 4. Save the file
-	if err := c.SaveUploadedFile(file, savePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+	// 1. --- Parse the (size-capped) form ---
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Uploads.MaxRequestBytes)
+	form, err := c.MultipartForm()
+	if err != nil {
+		h.uploadError(c, err, "Invalid upload")
 		return
 	}
 
-	// 5. Return the public URL
-	// Get the dynamic base URL from .env
-	baseURL := os.Getenv("BASE_URL")
-	if baseURL == "" {
-		baseURL = "http://localhost:8080"
+	// 2. --- Save each document that was sent ---
+	columns := map[string]string{"ssm_document": "ssm_document_url", "bank_statement": "bank_statement_url"}
+	saved := map[string]string{}
+	for field, column := range columns {
+		files := form.File[field]
+		if len(files) == 0 {
+			continue
+		}
+		name, err := h.Uploads.Documents.Save(ctx, files[0])
+		if err != nil {
+			h.uploadError(c, err, "Failed to save document")
+			return
+		}
+		saved[column] = name
+	}
+	if len(saved) == 0 {
+		apierror.BadRequest(c, "No documents uploaded (expected ssm_document or bank_statement)")
+		return
 	}
 
-	// Construct the URL dynamically
-	publicURL := fmt.Sprintf("%s/uploads/%s", baseURL, newFilename)
+	// 3. --- Record the stored names (not paths) ---
+	for column, name := range saved {
+		// Column names come from the map above, never from input.
+		if _, err := h.DB.ExecContext(ctx, "UPDATE users SET "+column+" = ?, version = version + 1 WHERE id = ?", name, userID); err != nil {
+			apierror.Internal(c, "Failed to record document")
+			return
+		}
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"url": publicURL,
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "Uploaded", "documents": h.documentLinks(ctx, userID, apiversion.From(c))})
+}
+
+// GetMyDocuments handles GET /v1/supplier/documents
+func (h *Handlers) GetMyDocuments(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	c.JSON(http.StatusOK, gin.H{"documents": h.documentLinks(c.Request.Context(), userID_raw.(int64), apiversion.From(c))})
+}
+
+// GetUserDocuments handles GET /v1/manager/users/:id/documents
+// It also returns the supplier's IC & SSM numbers, decrypted, for verification.
+func (h *Handlers) GetUserDocuments(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "User not found")
+		return
+	}
+	ctx := c.Request.Context()
+
+	// Managers verifying a supplier see the decrypted IC & SSM numbers.
+	var ic, ssm sql.NullString
+	err = h.DB.QueryRowContext(ctx, "SELECT ic_number, ssm_number FROM users WHERE id = ? AND deleted_at IS NULL", userID).Scan(&ic, &ssm)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.NotFound(c, "User not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to load user")
+		return
+	}
+	identity := gin.H{}
+	for field, v := range map[string]struct {
+		column string
+		stored sql.NullString
+	}{"icNumber": {pii.UserICNumber, ic}, "ssmNumber": {pii.UserSSMNumber, ssm}} {
+		if !v.stored.Valid || v.stored.String == "" {
+			continue
+		}
+		plain, err := h.PII.Decrypt(v.column, v.stored.String)
+		if err != nil {
+			log.Printf("identity for user %d: %v", userID, err)
+			apierror.Internal(c, "Failed to read identity details")
+			return
+		}
+		identity[field] = plain
+	}
+
+	c.JSON(http.StatusOK, gin.H{"documents": h.documentLinks(ctx, userID, apiversion.From(c)), "identity": identity})
+}
+
+// ServeDocument handles GET /v1/documents/:name
+// The link itself is the authorization: it must carry a valid, unexpired
+// signature from GetMyDocuments or GetUserDocuments.
+func (h *Handlers) ServeDocument(c *gin.Context) {
+	name := c.Param("name")
+	if !h.Uploads.DocumentURLs.Verify(name, c.Query("expires"), c.Query("sig")) {
+		apierror.Forbidden(c, "Invalid or expired document link")
+		return
+	}
+	path, ok := h.Uploads.Documents.Path(name)
+	if !ok {
+		apierror.NotFound(c, "Document not found")
+		return
+	}
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", name))
+	c.File(path)
+}
+
+// documentLinks returns signed URLs for the user's stored documents: in v1
+// keyed like the upload form fields, with missing documents left out; from v2
+// on as a dto.SupplierDocuments.
+func (h *Handlers) documentLinks(ctx context.Context, userID int64, version apiversion.Version) interface{} {
+	var ssm, bank sql.NullString
+	err := h.DB.QueryRowContext(ctx, "SELECT ssm_document_url, bank_statement_url FROM users WHERE id = ?", userID).Scan(&ssm, &bank)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("document links for user %d: %v", userID, err)
+	}
+
+	links := gin.H{}
+	for field, stored := range map[string]sql.NullString{"ssm_document": ssm, "bank_statement": bank} {
+		if !stored.Valid || stored.String == "" {
+			continue
+		}
+		// Older rows stored a path; the document is addressed by its base name.
+		if _, ok := h.Uploads.Documents.Path(stored.String); ok {
+			links[field] = h.Uploads.DocumentURLs.URL(h.Config.HTTP.BaseURL, filepath.Base(stored.String))
+		}
+	}
+	if version >= apiversion.V2 {
+		var docs dto.SupplierDocuments
+		if url, ok := links["ssm_document"].(string); ok {
+			docs.SSMDocument = &url
+		}
+		if url, ok := links["bank_statement"].(string); ok {
+			docs.BankStatement = &url
+		}
+		return docs
+	}
+	return links
+}
+
+// uploadError maps an upload failure to a response; fallback is the message
+// for errors that are not the client's fault.
+func (h *Handlers) uploadError(c *gin.Context, err error, fallback string) {
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooBig):
+		apierror.TooLarge(c, fmt.Sprintf("Request is larger than %d bytes", h.Uploads.MaxRequestBytes))
+	case errors.Is(err, uploads.ErrTooLarge):
+		apierror.TooLarge(c, fmt.Sprintf("File is larger than %d bytes", h.Uploads.Images.MaxFileBytes))
+	case errors.Is(err, uploads.ErrType):
+		apierror.BadRequest(c, strings.TrimPrefix(err.Error(), "uploads: "))
+	case errors.Is(err, uploads.ErrInfected):
+		apierror.BadRequest(c, "File rejected by virus scan")
+	case errors.Is(err, http.ErrMissingFile), errors.Is(err, http.ErrNotMultipart):
+		apierror.BadRequest(c, fallback)
+	default:
+		log.Printf("upload failed [%s]: %v", c.GetString(apierror.RequestIDKey), err)
+		apierror.Internal(c, fallback)
+	}
 }
@@ -1,19 +1,27 @@
 package handlers
 
 import (
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
 	"github.com/01moynul/taptosell-golang/internal/auth"
 	"github.com/01moynul/taptosell-golang/internal/email"
+	"github.com/01moynul/taptosell-golang/internal/i18n"
 	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pii"
+	"github.com/01moynul/taptosell-golang/internal/store"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // Helper: Converts string to pointer (empty string -> nil)
@@ -30,7 +38,10 @@ type RegisterUserInput struct {
 	FullName    string `json:"fullName" binding:"required"`
 	Email       string `json:"email" binding:"required,email"`
 	Password    string `json:"password" binding:"required,min=8"`
-	PhoneNumber string `json:"phoneNumber" binding:"required"`
+	PhoneNumber string `json:"phoneNumber" binding:"required,myphone"`
+
+	// Dropshipper Fields
+	ReferralCode string `json:"referralCode" binding:"max=16"` // optional; see GetMyReferrals
 
 	// Supplier Fields
 	RegistrationKey string `json:"registrationKey"`
@@ -41,16 +52,33 @@ type RegisterUserInput struct {
 	AddressLine2    string `json:"addressLine2"`
 	City            string `json:"city"`
 	State           string `json:"state"`
-	Postcode        string `json:"postcode"`
+	Postcode        string `json:"postcode" binding:"omitempty,postcode"`
 }
 
 func (h *Handlers) RegisterDropshipper(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var input RegisterUserInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
+	// An unknown referral code is an error, so a typo is not silently dropped
+	var referrerID int64
+	if strings.TrimSpace(input.ReferralCode) != "" {
+		id, err := h.referrerByCode(ctx, input.ReferralCode)
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.BadRequest(c, "Referral code not found")
+			return
+		}
+		if err != nil {
+			apierror.Internal(c, "Failed to check referral code")
+			return
+		}
+		referrerID = id
+	}
+
 	code, _ := generateVerificationCode()
 	expiry := time.Now().Add(15 * time.Minute)
 
@@ -69,37 +97,45 @@ func (h *Handlers) RegisterDropshipper(c *gin.Context) {
 
 	var password models.Password
 	if err := password.Set(input.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apierror.Internal(c, "Failed to hash password")
 		return
 	}
 	user.PasswordHash = password.Hash
 
-	query := `INSERT INTO users (role, status, email, password_hash, full_name, phone_number, created_at, updated_at, version, verification_code, verification_expiry) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	user.PublicID = uuid.NewString()
+	query := `INSERT INTO users (public_id, role, status, email, password_hash, full_name, phone_number, created_at, updated_at, version, verification_code, verification_expiry) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := h.DB.Exec(query, user.Role, user.Status, user.Email, user.PasswordHash, user.FullName, user.PhoneNumber, user.CreatedAt, user.UpdatedAt, user.Version, user.VerificationCode, user.VerificationExpiry)
+	result, err := h.DB.ExecContext(ctx, query, user.PublicID, user.Role, user.Status, user.Email, user.PasswordHash, user.FullName, user.PhoneNumber, user.CreatedAt, user.UpdatedAt, user.Version, user.VerificationCode, user.VerificationExpiry)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		if respondDuplicate(c, err, "This account already exists.") {
+			return
+		}
+		apierror.Internal(c, "Failed to register user")
 		return
 	}
 
 	id, _ := result.LastInsertId()
 	user.ID = id
-	email.SendVerificationEmail(user.Email, code)
+	if referrerID != 0 {
+		h.attributeReferral(ctx, referrerID, id, c.ClientIP())
+	}
+	email.SendVerificationEmail(user.Email, code, c.GetString(i18n.ContextKey))
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Registration successful. Please check your email.", "user": user})
 }
 
 func (h *Handlers) RegisterSupplier(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var input RegisterUserInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
-	var correctKey string
-	err := h.DB.QueryRow("SELECT setting_value FROM settings WHERE setting_key = 'supplier_registration_key'").Scan(&correctKey)
+	correctKey, err := h.Settings.Get(ctx, "supplier_registration_key")
 	if err != nil || input.RegistrationKey != correctKey {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid registration key"})
+		apierror.Forbidden(c, "Invalid registration key")
 		return
 	}
 
@@ -133,18 +169,34 @@ func (h *Handlers) RegisterSupplier(c *gin.Context) {
 	password.Set(input.Password)
 	user.PasswordHash = password.Hash
 
-	query := `INSERT INTO users (role, status, email, password_hash, full_name, phone_number, created_at, updated_at, version, verification_code, verification_expiry, company_name, ic_number, ssm_number, address_line1, address_line2, city, state, postcode) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	// IC and SSM numbers are encrypted at rest; the response echoes the plaintext.
+	icNumber, err := h.PII.EncryptPtr(pii.UserICNumber, user.ICNumber)
+	if err != nil {
+		apierror.Internal(c, "Failed to register supplier")
+		return
+	}
+	ssmNumber, err := h.PII.EncryptPtr(pii.UserSSMNumber, user.SSMNumber)
+	if err != nil {
+		apierror.Internal(c, "Failed to register supplier")
+		return
+	}
 
-	result, err := h.DB.Exec(query, user.Role, user.Status, user.Email, user.PasswordHash, user.FullName, user.PhoneNumber, user.CreatedAt, user.UpdatedAt, user.Version, user.VerificationCode, user.VerificationExpiry, user.CompanyName, user.ICNumber, user.SSMNumber, user.AddressLine1, user.AddressLine2, user.City, user.State, user.Postcode)
+	user.PublicID = uuid.NewString()
+	query := `INSERT INTO users (public_id, role, status, email, password_hash, full_name, phone_number, created_at, updated_at, version, verification_code, verification_expiry, company_name, ic_number, ssm_number, address_line1, address_line2, city, state, postcode) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := h.DB.ExecContext(ctx, query, user.PublicID, user.Role, user.Status, user.Email, user.PasswordHash, user.FullName, user.PhoneNumber, user.CreatedAt, user.UpdatedAt, user.Version, user.VerificationCode, user.VerificationExpiry, user.CompanyName, icNumber, ssmNumber, user.AddressLine1, user.AddressLine2, user.City, user.State, user.Postcode)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register supplier"})
+		if respondDuplicate(c, err, "This account already exists.") {
+			return
+		}
+		apierror.Internal(c, "Failed to register supplier")
 		return
 	}
 
 	id, _ := result.LastInsertId()
 	user.ID = id
-	email.SendVerificationEmail(user.Email, code)
+	email.SendVerificationEmail(user.Email, code, c.GetString(i18n.ContextKey))
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Supplier registration successful.", "user": user})
 }
@@ -157,25 +209,27 @@ type LoginInput struct {
 }
 
 func (h *Handlers) Login(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var input LoginInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
 	var user models.User
-	err := h.DB.QueryRow("SELECT id, password_hash, role, status FROM users WHERE email = ?", input.Email).Scan(&user.ID, &user.PasswordHash, &user.Role, &user.Status)
+	err := h.DB.QueryRowContext(ctx, "SELECT id, password_hash, role, status FROM users WHERE email = ? AND deleted_at IS NULL", input.Email).Scan(&user.ID, &user.PasswordHash, &user.Role, &user.Status)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		apierror.Unauthorized(c, "Invalid credentials")
 		return
 	}
 
 	if user.Status == "unverified" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account not verified."})
+		apierror.Unauthorized(c, "Account not verified.")
 		return
 	}
 	if user.Status == "suspended" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Account suspended."})
+		apierror.Forbidden(c, "Account suspended.")
 		return
 	}
 
@@ -183,11 +237,11 @@ func (h *Handlers) Login(c *gin.Context) {
 	password.Hash = user.PasswordHash
 	match, _ := password.Matches(input.Password)
 	if !match {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		apierror.Unauthorized(c, "Invalid credentials")
 		return
 	}
 
-	token, _ := auth.GenerateToken(user.ID)
+	token, _ := auth.GenerateToken(user.ID, user.Role, user.Status)
 	c.JSON(http.StatusOK, gin.H{"message": "Login successful", "token": token, "user": gin.H{"id": user.ID, "role": user.Role}})
 }
 
@@ -202,40 +256,42 @@ type VerifyEmailInput struct {
 }
 
 func (h *Handlers) VerifyEmail(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var input VerifyEmailInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
 	var user models.User
 	// Scan directly into pointers
-	err := h.DB.QueryRow("SELECT id, status, verification_code, verification_expiry FROM users WHERE email = ?", input.Email).Scan(&user.ID, &user.Status, &user.VerificationCode, &user.VerificationExpiry)
+	err := h.DB.QueryRowContext(ctx, "SELECT id, status, verification_code, verification_expiry FROM users WHERE email = ? AND deleted_at IS NULL", input.Email).Scan(&user.ID, &user.Status, &user.VerificationCode, &user.VerificationExpiry)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		apierror.NotFound(c, "User not found")
 		return
 	}
 
 	if user.Status != "unverified" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Already verified"})
+		apierror.BadRequest(c, "Already verified")
 		return
 	}
 
 	// Safety check for nil pointers
 	if user.VerificationCode == nil || user.VerificationExpiry == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No code found"})
+		apierror.BadRequest(c, "No code found")
 		return
 	}
 	if *user.VerificationCode != input.Code {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		apierror.BadRequest(c, "Invalid code")
 		return
 	}
 	if time.Now().After(*user.VerificationExpiry) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Code expired"})
+		apierror.BadRequest(c, "Code expired")
 		return
 	}
 
-	h.DB.Exec("UPDATE users SET status = 'pending', verification_code = NULL, verification_expiry = NULL WHERE id = ?", user.ID)
+	h.DB.ExecContext(ctx, "UPDATE users SET status = 'pending', verification_code = NULL, verification_expiry = NULL, version = version + 1 WHERE id = ?", user.ID)
 	c.JSON(http.StatusOK, gin.H{"message": "Email verified."})
 }
 
@@ -244,59 +300,167 @@ type ResendVerificationEmailInput struct {
 }
 
 func (h *Handlers) ResendVerificationEmail(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var input ResendVerificationEmailInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 	var user models.User
-	if err := h.DB.QueryRow("SELECT id, status FROM users WHERE email = ?", input.Email).Scan(&user.ID, &user.Status); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err := h.DB.QueryRowContext(ctx, "SELECT id, status FROM users WHERE email = ? AND deleted_at IS NULL", input.Email).Scan(&user.ID, &user.Status); err != nil {
+		apierror.NotFound(c, "User not found")
 		return
 	}
 	if user.Status != "unverified" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Already verified"})
+		apierror.BadRequest(c, "Already verified")
 		return
 	}
 	code, _ := generateVerificationCode()
 	expiry := time.Now().Add(15 * time.Minute)
-	h.DB.Exec("UPDATE users SET verification_code = ?, verification_expiry = ? WHERE id = ?", code, expiry, user.ID)
-	email.SendVerificationEmail(input.Email, code)
+	h.DB.ExecContext(ctx, "UPDATE users SET verification_code = ?, verification_expiry = ?, version = version + 1 WHERE id = ?", code, expiry, user.ID)
+	email.SendVerificationEmail(input.Email, code, c.GetString(i18n.ContextKey))
 	c.JSON(http.StatusOK, gin.H{"message": "New code sent."})
 }
 
-// --- Uploads ---
+// --- Password Reset ---
 
-func (h *Handlers) UploadSupplierDocuments(c *gin.Context) {
-	userID_raw, _ := c.Get("userID")
-	userID := userID_raw.(int64)
-	uploadDir := "./uploads"
-	os.MkdirAll(uploadDir, os.ModePerm)
+// passwordResetTTL is how long an emailed reset token stays valid.
+const passwordResetTTL = time.Hour
 
-	saveFile := func(name string) string {
-		file, header, err := c.Request.FormFile(name)
-		if err != nil {
-			return ""
-		}
-		defer file.Close()
-		path := filepath.Join(uploadDir, fmt.Sprintf("%d-%s-%s", userID, name, filepath.Base(header.Filename)))
-		dst, _ := os.Create(path)
-		defer dst.Close()
-		io.Copy(dst, file)
-		return path
+// generateResetToken returns a random token for the email and the hash stored
+// in users.password_reset_hash.
+func generateResetToken() (token, hash string, err error) {
+	buf := make([]byte, 16)
+	if _, err := cryptorand.Read(buf); err != nil {
+		return "", "", err
 	}
+	token = hex.EncodeToString(buf)
+	return token, hashResetToken(token), nil
+}
 
-	ssm := saveFile("ssm_document")
-	bank := saveFile("bank_statement")
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
 
-	if ssm != "" {
-		h.DB.Exec("UPDATE users SET ssm_document_url = ? WHERE id = ?", ssm, userID)
+// ForgotPassword is the handler for POST /v1/auth/forgot-password
+// It emails a reset token to a dropshipper or supplier. The response is the
+// same whether or not the email has an account, so it cannot be probed.
+func (h *Handlers) ForgotPassword(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
 	}
-	if bank != "" {
-		h.DB.Exec("UPDATE users SET bank_statement_url = ? WHERE id = ?", bank, userID)
+	sent := gin.H{"message": "If that email has an account, a reset code has been sent."}
+
+	// 1. --- Find the Account (staff reset through an administrator) ---
+	var userID int64
+	err := h.DB.QueryRowContext(ctx, "SELECT id FROM users WHERE email = ? AND role IN ('dropshipper', 'supplier') AND deleted_at IS NULL", input.Email).Scan(&userID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusOK, sent)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to start password reset")
+		return
+	}
+
+	// 2. --- Store the Token Hash (replaces any earlier token) ---
+	token, hash, err := generateResetToken()
+	if err != nil {
+		apierror.Internal(c, "Failed to start password reset")
+		return
+	}
+	expiry := time.Now().Add(passwordResetTTL)
+	if _, err := h.DB.ExecContext(ctx, "UPDATE users SET password_reset_hash = ?, password_reset_expiry = ? WHERE id = ?", hash, expiry, userID); err != nil {
+		apierror.Internal(c, "Failed to start password reset")
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Uploaded"})
+	// 3. --- Email the Token ---
+	if err := email.SendPasswordResetEmail(input.Email, token, c.GetString(i18n.ContextKey)); err != nil {
+		apierror.Internal(c, "Failed to send reset email")
+		return
+	}
+	c.JSON(http.StatusOK, sent)
+}
+
+type ResetPasswordInput struct {
+	Email       string `json:"email" binding:"required,email"`
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=8"`
+}
+
+// ResetPassword is the handler for POST /v1/auth/reset-password
+// It sets a new password with a token from ForgotPassword; a token works once.
+func (h *Handlers) ResetPassword(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	var password models.Password
+	if err := password.Set(input.NewPassword); err != nil {
+		apierror.Internal(c, "Failed to hash password")
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	// 1. --- Check the Token (row locked so it is used once) ---
+	var userID int64
+	var storedHash sql.NullString
+	var expiry sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT id, password_reset_hash, password_reset_expiry FROM users WHERE email = ? AND deleted_at IS NULL FOR UPDATE", input.Email).Scan(&userID, &storedHash, &expiry)
+	if err != nil && err != sql.ErrNoRows {
+		apierror.Internal(c, "Failed to reset password")
+		return
+	}
+	hash := hashResetToken(strings.TrimSpace(input.Token))
+	if err == sql.ErrNoRows || !storedHash.Valid || subtle.ConstantTimeCompare([]byte(hash), []byte(storedHash.String)) != 1 {
+		apierror.BadRequest(c, "Invalid or expired reset code")
+		return
+	}
+	now := time.Now()
+	if !expiry.Valid || now.After(expiry.Time) {
+		apierror.BadRequest(c, "Invalid or expired reset code")
+		return
+	}
+
+	// 2. --- Set the Password and Spend the Token ---
+	// Sessions from before the reset end too.
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users
+		SET password_hash = ?, password_reset_hash = NULL, password_reset_expiry = NULL,
+		    tokens_valid_after = ?, updated_at = ?, version = version + 1
+		WHERE id = ?`,
+		password.Hash, now, now, userID)
+	if err != nil {
+		apierror.Internal(c, "Failed to reset password")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated. You can now log in."})
 }
 
 // --- Manager Functions ---
@@ -304,10 +468,12 @@ func (h *Handlers) UploadSupplierDocuments(c *gin.Context) {
 // GetUsers returns all users
 // GET /v1/manager/users
 func (h *Handlers) GetUsers(c *gin.Context) {
-	query := `SELECT id, role, status, email, full_name, phone_number, penalty_strikes, created_at FROM users ORDER BY id DESC`
-	rows, err := h.DB.Query(query)
+	ctx := c.Request.Context()
+
+	query := `SELECT id, public_id, role, status, email, full_name, phone_number, penalty_strikes, created_at, version FROM users WHERE deleted_at IS NULL ORDER BY id DESC`
+	rows, err := h.DB.QueryContext(ctx, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
+		apierror.Internal(c, "DB error")
 		return
 	}
 	defer rows.Close()
@@ -318,8 +484,8 @@ func (h *Handlers) GetUsers(c *gin.Context) {
 		var penaltyStrikes sql.NullInt64
 
 		// [FIX] Scanning pointers matches the updated User struct
-		if err := rows.Scan(&u.ID, &u.Role, &u.Status, &u.Email, &u.FullName, &u.PhoneNumber, &penaltyStrikes, &u.CreatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Scan error"})
+		if err := rows.Scan(&u.ID, &u.PublicID, &u.Role, &u.Status, &u.Email, &u.FullName, &u.PhoneNumber, &penaltyStrikes, &u.CreatedAt, &u.Version); err != nil {
+			apierror.Internal(c, "Scan error")
 			return
 		}
 
@@ -337,19 +503,36 @@ func (h *Handlers) GetUsers(c *gin.Context) {
 
 type UpdateUserPenaltyInput struct {
 	Action string `json:"action" binding:"required,oneof=increment decrement reset"`
+	// Version is the user version shown in the manager's list. When sent,
+	// the change is rejected with 409 if the user changed in the meantime.
+	Version *int `json:"version"`
 }
 
 // UpdateUserPenalty
 func (h *Handlers) UpdateUserPenalty(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	id := c.Param("id")
 	var input UpdateUserPenaltyInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
-	var current int
-	h.DB.QueryRow("SELECT COALESCE(penalty_strikes, 0) FROM users WHERE id = ?", id).Scan(&current)
+	var current, version int
+	err := h.DB.QueryRowContext(ctx, "SELECT COALESCE(penalty_strikes, 0), version FROM users WHERE id = ? AND deleted_at IS NULL", id).Scan(&current, &version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.NotFound(c, "User not found")
+			return
+		}
+		apierror.Internal(c, "DB error")
+		return
+	}
+	if input.Version != nil && *input.Version != version {
+		apierror.Conflict(c, "User was modified by someone else. Reload and try again.")
+		return
+	}
 
 	if input.Action == "increment" {
 		current++
@@ -361,8 +544,20 @@ func (h *Handlers) UpdateUserPenalty(c *gin.Context) {
 		current = 0
 	}
 
-	h.DB.Exec("UPDATE users SET penalty_strikes = ? WHERE id = ?", current, id)
-	c.JSON(http.StatusOK, gin.H{"message": "Penalty updated"})
+	// Optimistic lock: the read-modify-write above only lands if nobody
+	// (e.g. the overdue-order cron) touched the user since we read it.
+	result, err := h.DB.ExecContext(ctx,
+		"UPDATE users SET penalty_strikes = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL",
+		current, time.Now(), id, version)
+	if err != nil {
+		apierror.Internal(c, "DB error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		apierror.Conflict(c, "User was modified by someone else. Reload and try again.")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Penalty updated", "penaltyStrikes": current, "version": version + 1})
 }
 
 // --- Admin ---
@@ -374,9 +569,11 @@ type CreateManagerInput struct {
 }
 
 func (h *Handlers) CreateManager(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var input CreateManagerInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
@@ -387,8 +584,16 @@ func (h *Handlers) CreateManager(c *gin.Context) {
 	password.Set(input.Password)
 	user.PasswordHash = password.Hash
 
-	res, _ := h.DB.Exec("INSERT INTO users (role, status, email, password_hash, full_name, phone_number, created_at, updated_at, version) VALUES (?,?,?,?,?,?,?,?,?)",
-		user.Role, user.Status, user.Email, user.PasswordHash, user.FullName, user.PhoneNumber, user.CreatedAt, user.UpdatedAt, user.Version)
+	user.PublicID = uuid.NewString()
+	res, err := h.DB.ExecContext(ctx, "INSERT INTO users (public_id, role, status, email, password_hash, full_name, phone_number, created_at, updated_at, version) VALUES (?,?,?,?,?,?,?,?,?,?)",
+		user.PublicID, user.Role, user.Status, user.Email, user.PasswordHash, user.FullName, user.PhoneNumber, user.CreatedAt, user.UpdatedAt, user.Version)
+	if err != nil {
+		if respondDuplicate(c, err, "This account already exists.") {
+			return
+		}
+		apierror.Internal(c, "Failed to create manager")
+		return
+	}
 
 	id, _ := res.LastInsertId()
 	user.ID = id
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/sanitize"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Supplier Vacation Mode ---
+//
+// While a supplier is away their products cannot be added to a cart or
+// checked out, the catalogue hides them (hideListings) or marks them with
+// supplierAway, and questions on them get the supplier's auto-reply. A
+// vacation with an end date ends by itself: store.SupplierAway compares
+// against the clock, and ProcessVacations clears it and tells the supplier.
+
+// defaultVacationReply answers questions when the supplier left no message.
+const defaultVacationReply = "The supplier is away right now and will answer when they are back."
+
+// getVacation reads a supplier's vacation settings.
+func getVacation(ctx context.Context, q Querier, supplierID int64) (*models.SupplierVacation, error) {
+	var v models.SupplierVacation
+	err := q.QueryRowContext(ctx, `
+		SELECT vacation_started_at, vacation_ends_at, vacation_message, vacation_hide_listings
+		FROM users WHERE id = ?`, supplierID).Scan(&v.StartedAt, &v.EndsAt, &v.Message, &v.HideListings)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.OnVacation = v.StartedAt != nil && (v.EndsAt == nil || v.EndsAt.After(time.Now()))
+	return &v, nil
+}
+
+// supplierAway reports whether the supplier of a product is on vacation.
+func supplierAway(ctx context.Context, q Querier, productID int64) (bool, error) {
+	var away bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM products p JOIN users s ON s.id = p.supplier_id
+		WHERE p.id = ? AND `+store.SupplierAway("s")+`)`, productID, time.Now()).Scan(&away)
+	return away, err
+}
+
+// GetVacation is the handler for GET /v1/supplier/vacation
+func (h *Handlers) GetVacation(c *gin.Context) {
+	userID_raw, _ := c.Get("userID")
+	v, err := getVacation(c.Request.Context(), h.DB, userID_raw.(int64))
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch vacation settings")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"vacation": v})
+}
+
+// VacationInput defines the JSON for turning vacation mode on or off.
+// The message and hideListings are kept when it is turned off, for next time.
+type VacationInput struct {
+	Enabled      *bool      `json:"enabled" binding:"required"`
+	EndsAt       *time.Time `json:"endsAt"` // optional; visibility comes back by itself then
+	Message      string     `json:"message" binding:"max=500"`
+	HideListings bool       `json:"hideListings"`
+}
+
+// UpdateVacation is the handler for PUT /v1/supplier/vacation
+func (h *Handlers) UpdateVacation(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get Supplier ID & Bind Input ---
+	userID_raw, _ := c.Get("userID")
+	supplierID := userID_raw.(int64)
+
+	var input VacationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	now := time.Now()
+	if *input.Enabled && input.EndsAt != nil && !input.EndsAt.After(now) {
+		apierror.BadRequest(c, "endsAt must be in the future")
+		return
+	}
+
+	// 2. --- Save ---
+	var message *string
+	if text := sanitize.Text(input.Message); text != "" {
+		message = &text
+	}
+	var err error
+	if *input.Enabled {
+		// Keep the original start when only the end date or message changes.
+		_, err = h.DB.ExecContext(ctx, `
+			UPDATE users
+			SET vacation_started_at = IF(`+store.SupplierAway("users")+`, vacation_started_at, ?),
+			    vacation_ends_at = ?, vacation_message = ?, vacation_hide_listings = ?, updated_at = ?
+			WHERE id = ?`,
+			now, now, input.EndsAt, message, input.HideListings, now, supplierID)
+	} else {
+		_, err = h.DB.ExecContext(ctx, `
+			UPDATE users
+			SET vacation_started_at = NULL, vacation_ends_at = NULL,
+			    vacation_message = ?, vacation_hide_listings = ?, updated_at = ?
+			WHERE id = ?`,
+			message, input.HideListings, now, supplierID)
+	}
+	if err == nil {
+		err = touchSupplierProducts(ctx, h.DB, supplierID)
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to update vacation settings")
+		return
+	}
+
+	v, err := getVacation(ctx, h.DB, supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch vacation settings")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Vacation settings updated", "vacation": v})
+}
+
+// vacationAutoReply is the reply a question on supplierID's products gets
+// while the supplier is away, or "" when they are not.
+func vacationAutoReply(ctx context.Context, q Querier, supplierID int64) (string, error) {
+	v, err := getVacation(ctx, q, supplierID)
+	if err != nil || !v.OnVacation {
+		return "", err
+	}
+	if v.Message != nil {
+		return *v.Message, nil
+	}
+	return defaultVacationReply, nil
+}
+
+//
+// --- Vacation Job ---
+//
+
+// ProcessVacations ends the vacations whose end date has passed and tells the
+// supplier. Their listings are already visible again by then; this clears the
+// settings so the supplier's dashboard shows them as back. The background
+// worker calls it every VACATION_CHECK_INTERVAL.
+func (h *Handlers) ProcessVacations(ctx context.Context) {
+	rows, err := h.DB.QueryContext(ctx,
+		"SELECT id FROM users WHERE vacation_started_at IS NOT NULL AND vacation_ends_at <= ? LIMIT 500", time.Now())
+	if err != nil {
+		logging.Errorf("[Vacations] Error fetching ended vacations: %v", err)
+		return
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			logging.Errorf("[Vacations] Error scanning ended vacations: %v", err)
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logging.Errorf("[Vacations] Error fetching ended vacations: %v", err)
+		return
+	}
+
+	for i, id := range ids {
+		if ctx.Err() != nil {
+			logging.Infof("[Vacations] Shutting down, %d vacations left for the next run", len(ids)-i)
+			return
+		}
+		if err := h.endVacation(context.WithoutCancel(ctx), id); err != nil {
+			logging.Errorf("[Vacations] Failed to end vacation of User %d: %v", id, err)
+		}
+	}
+}
+
+// endVacation clears one ended vacation and notifies the supplier.
+func (h *Handlers) endVacation(ctx context.Context, supplierID int64) error {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The supplier may have extended it in the meantime.
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users SET vacation_started_at = NULL, vacation_ends_at = NULL, updated_at = ?
+		WHERE id = ? AND vacation_started_at IS NOT NULL AND vacation_ends_at <= ?`,
+		now, supplierID, now)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil
+	}
+	if err := touchSupplierProducts(ctx, tx, supplierID); err != nil {
+		return err
+	}
+	message := "Welcome back! Your vacation mode has ended and your products can be ordered again."
+	if err := h.AddNotification(ctx, tx, supplierID, message, "/supplier/vacation"); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.Infof("[Vacations] Vacation of User %d ended", supplierID)
+	return nil
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"net/http"
-	"time"
+	"strconv"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/logging"
 	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/store"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,64 +19,42 @@ import (
 // --- Wallet Core Functions ---
 //
 
-// Querier defines a common interface for QueryRow,
-// which is implemented by both *sql.DB and *sql.Tx.
-// This allows our helper to be used in or out of a transaction.
-type Querier interface {
-	QueryRow(query string, args ...interface{}) *sql.Row
-}
+// Querier is implemented by both *sql.DB and *sql.Tx.
+// This allows our helpers to be used in or out of a transaction.
+type Querier = store.DBTX
 
 // GetWalletBalance calculates a user's current wallet balance.
 // It accepts any 'Querier' (a *sql.DB or *sql.Tx).
-func (h *Handlers) GetWalletBalance(q Querier, userID int64) (float64, error) {
-	var balance sql.NullFloat64 // Use NullFloat64 to handle users with 0 transactions
-
-	query := "SELECT SUM(amount) FROM wallet_transactions WHERE user_id = ?"
-
-	err := q.QueryRow(query, userID).Scan(&balance)
-	if err != nil {
-		// This is a common case, not an error.
-		// If a user has no transactions, SUM() returns NULL,
-		// and Scan() returns sql.ErrNoRows.
-		if err == sql.ErrNoRows {
-			return 0.0, nil
-		}
-		return 0.0, err
-	}
-
-	if !balance.Valid {
-		return 0.0, nil // SUM(NULL) is NULL, so treat as 0
-	}
-
-	return balance.Float64, nil
+// Handlers that already hold a store.Tx should use tx.Wallet directly.
+func (h *Handlers) GetWalletBalance(ctx context.Context, q Querier, userID int64) (money.Money, error) {
+	return store.NewWalletStore(q).Balance(ctx, userID)
 }
 
 // AddWalletTransaction creates a new transaction record.
 // This is the *only* function that should be used to modify a balance.
 // It MUST be called from within a transaction (tx).
-// AddWalletTransaction creates a new transaction record.
-func (h *Handlers) AddWalletTransaction(tx *sql.Tx, userID int64, txType string, amount float64, notes string) error {
-	// 1. Get current balance to calculate balance_after
-	var currentBalance sql.NullFloat64
-	err := tx.QueryRow("SELECT SUM(amount) FROM wallet_transactions WHERE user_id = ? FOR UPDATE", userID).Scan(&currentBalance)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to get balance for update: %w", err)
-	}
-
-	newBalance := currentBalance.Float64 + amount
-
-	// 2. Insert using correct column 'notes' and include 'status' and 'balance_after'
-	query := `
-		INSERT INTO wallet_transactions
-		(user_id, type, status, amount, balance_after, notes, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`
-
-	_, err = tx.Exec(query, userID, txType, "completed", amount, newBalance, notes, time.Now())
-	if err != nil {
-		return fmt.Errorf("failed to add wallet transaction: %w", err)
-	}
+func (h *Handlers) AddWalletTransaction(ctx context.Context, tx *sql.Tx, userID int64, txType string, amount money.Money, notes string) error {
+	return store.NewWalletStore(tx).AddTransaction(ctx, userID, txType, amount, notes)
+}
 
-	return nil
+// pendingBalance is a supplier's share of the lines in 'shipped' orders: sold,
+// not yet paid out. It reads the supplier and commission copied onto each line
+// at checkout, so later product edits do not rewrite it, and adds the tax on
+// those lines, as CompleteOrder pays them (OrderStore.SupplierPayouts).
+func (h *Handlers) pendingBalance(ctx context.Context, q Querier, supplierID int64) (money.Money, error) {
+	var pending money.Money
+	err := q.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COALESCE(SUM(oi.unit_price * oi.quantity - oi.commission), 0)
+			FROM order_items oi
+			JOIN orders o ON oi.order_id = o.id
+			WHERE oi.supplier_id = ? AND o.status = 'shipped')
+			+
+			(SELECT COALESCE(SUM(t.tax_amount), 0)
+			FROM order_tax_lines t
+			JOIN orders o ON t.order_id = o.id
+			WHERE t.supplier_id = ? AND o.status = 'shipped')`, supplierID, supplierID).Scan(&pending)
+	return pending, err
 }
 
 //
@@ -79,63 +62,157 @@ func (h *Handlers) AddWalletTransaction(tx *sql.Tx, userID int64, txType string,
 //
 
 // GetMyWallet is the handler for GET /v1/dropshipper/wallet
-// It returns the user's current balance and transaction history.
+// It returns the user's current balance, the part of it held for on-hold
+// orders, and their transaction history.
 func (h *Handlers) GetMyWallet(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get User ID ---
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
 
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+	page := list.Page
+
 	// 2. --- Get Current Balance ---
-	// We pass the main DB connection 'h.DB' which satisfies the Querier interface.
-	balance, err := h.GetWalletBalance(h.DB, userID)
+	balance, err := h.Store.Wallet.Balance(ctx, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wallet balance"})
+		apierror.Internal(c, "Failed to get wallet balance")
 		return
 	}
+	holds, err := h.Store.Wallet.Holds(ctx, userID)
+	if err != nil {
+		apierror.Internal(c, "Failed to get wallet holds")
+		return
+	}
+	var held money.Money
+	for _, hold := range holds {
+		held += hold.Amount
+	}
 
-	// 3. --- Get Transaction History ---
-	// (We will add transaction history retrieval here in a future step)
+	// 3. --- Get Transaction History (Keyset Pagination) ---
+	transactions, err := h.Store.Wallet.ListTransactions(ctx, userID, page)
+	if err != nil {
+		apierror.Internal(c, "Failed to get transaction history")
+		return
+	}
+	transactions, nextCursor := pagination.Paginate(page, transactions, func(t models.WalletTransaction) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: t.CreatedAt, ID: t.ID}
+	})
 
 	// 4. --- Send Response ---
 	c.JSON(http.StatusOK, gin.H{
-		"currentBalance": balance,
-		"transactions":   []models.WalletTransaction{}, // Placeholder
+		"currentBalance":   balance,
+		"heldBalance":      held,
+		"availableBalance": balance - held,
+		"holds":            holds,
+		"transactions":     transactions,
+		"nextCursor":       nextCursor,
 	})
 }
 
 // ManualTopUp handles a simulated deposit for testing/manual adjustments.
 // Route: POST /v1/dropshipper/wallet/topup
+// A top-up the risk checks flag is not credited; it waits for a manager
+// (202, see ReviewRisk).
 func (h *Handlers) ManualTopUp(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	userID_raw, _ := c.Get("userID")
 	userID := userID_raw.(int64)
 
 	var input struct {
-		Amount float64 `json:"amount" binding:"required,gt=0"`
+		Amount money.Money `json:"amount" binding:"required,gt=0"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+		apierror.BadRequest(c, "Invalid amount")
 		return
 	}
 
-	tx, err := h.DB.Begin()
+	tx, err := h.Store.Begin(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
 
+	assessment, err := h.assessRisk(ctx, tx, userID, nil)
+	if err != nil {
+		apierror.Internal(c, "Failed to check top-up")
+		return
+	}
+	if assessment.Review {
+		reviewID, err := flagForReview(ctx, tx, userID, "topup", 0, input.Amount, assessment)
+		if err != nil {
+			apierror.Internal(c, "Failed to queue top-up for review")
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			apierror.Internal(c, "Failed to commit top-up")
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "Top-up is under review", "amount": input.Amount, "reviewId": reviewID})
+		return
+	}
+
 	// Add credit transaction (positive amount)
-	err = h.AddWalletTransaction(tx, userID, "topup", input.Amount, "Manual test top-up")
+	err = tx.Wallet.AddTransaction(ctx, userID, "topup", input.Amount, "Manual test top-up")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
+		apierror.Internal(c, "Failed to record transaction")
 		return
 	}
 
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit top-up"})
+		apierror.Internal(c, "Failed to commit top-up")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Top-up successful", "amount": input.Amount})
 }
+
+//
+// --- Ledger Reconciliation ---
+//
+
+// GetWalletReconciliation is the handler for GET /v1/manager/wallet-reconciliation
+// It replays the ledger (of ?userId= only, when given) and lists the users whose
+// balance_after drifted from the exact sum of their transactions.
+func (h *Handlers) GetWalletReconciliation(c *gin.Context) {
+	var userID int64
+	if v := c.Query("userId"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			apierror.BadRequest(c, "Invalid user ID")
+			return
+		}
+		userID = id
+	}
+
+	mismatches, err := h.Store.Wallet.Reconcile(c.Request.Context(), userID)
+	if err != nil {
+		apierror.Internal(c, "Failed to reconcile wallets")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"mismatches": mismatches})
+}
+
+// ProcessWalletReconciliation logs every user whose ledger no longer adds up.
+// The background worker calls it every WALLET_RECONCILE_INTERVAL.
+func (h *Handlers) ProcessWalletReconciliation(ctx context.Context) {
+	mismatches, err := h.Store.Wallet.Reconcile(ctx, 0)
+	if err != nil {
+		logging.Errorf("[Wallet] Error reconciling the ledger: %v", err)
+		return
+	}
+	for _, m := range mismatches {
+		logging.Errorf("[Wallet] Ledger mismatch for User %d at transaction %d: balance_after RM %s, transactions sum to RM %s",
+			m.UserID, m.TransactionID, m.Recorded, m.Expected)
+	}
+	if len(mismatches) == 0 {
+		logging.Infof("[Wallet] Ledger reconciled")
+	}
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Product Watchlist (Dropshipper-Only) ---
+//
+
+// maxWatchlistItems caps one dropshipper's watchlist.
+const maxWatchlistItems = 500
+
+// watchedStock is the stock of product p as a watcher sees it: for a
+// variable product, the sum of its variants.
+const watchedStock = `IF(p.is_variable, COALESCE((SELECT SUM(v.stock_quantity) FROM product_variants v WHERE v.product_id = p.id), 0), p.stock_quantity)`
+
+// AddToWatchlist is the handler for POST /v1/dropshipper/watchlist/:productId
+// It watches a catalogue product: the dropshipper is notified when its price
+// changes or it comes back in stock. Watching it again changes nothing.
+func (h *Handlers) AddToWatchlist(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 1. --- Get IDs ---
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	productID, err := strconv.ParseInt(c.Param("productId"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+
+	// 2. --- Current Price & Stock (the baseline changes are measured from) ---
+	now := time.Now()
+	var price money.Money
+	var stock int
+	var changedAt time.Time
+	err = h.DB.QueryRowContext(ctx, "SELECT p.price_to_tts, "+watchedStock+", p.changed_at FROM products p WHERE p.id = ? AND "+store.InCatalogue("p"),
+		productID, now).Scan(&price, &stock, &changedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.NotFound(c, "Product not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Database error")
+		return
+	}
+
+	// 3. --- Add (Capped) ---
+	var count int
+	if err := h.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM watchlist WHERE dropshipper_id = ?", dropshipperID).Scan(&count); err != nil {
+		apierror.Internal(c, "Failed to update watchlist")
+		return
+	}
+	if count >= maxWatchlistItems {
+		apierror.Conflict(c, fmt.Sprintf("Your watchlist is full (at most %d products). Remove some first.", maxWatchlistItems))
+		return
+	}
+	result, err := h.DB.ExecContext(ctx, `
+		INSERT IGNORE INTO watchlist (dropshipper_id, product_id, last_price, in_stock, checked_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		dropshipperID, productID, price, stock > 0, changedAt, now)
+	if err != nil {
+		apierror.Internal(c, "Failed to update watchlist")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "Product is already on your watchlist"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Product added to your watchlist"})
+}
+
+// RemoveFromWatchlist is the handler for DELETE /v1/dropshipper/watchlist/:productId
+func (h *Handlers) RemoveFromWatchlist(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	productID, err := strconv.ParseInt(c.Param("productId"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Product is not on your watchlist")
+		return
+	}
+
+	result, err := h.DB.ExecContext(ctx, "DELETE FROM watchlist WHERE dropshipper_id = ? AND product_id = ?", dropshipperID, productID)
+	if err != nil {
+		apierror.Internal(c, "Failed to update watchlist")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		apierror.NotFound(c, "Product is not on your watchlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product removed from your watchlist"})
+}
+
+// GetWatchlist is the handler for GET /v1/dropshipper/watchlist
+// It lists the watched products, most recently added first, with their
+// current price and stock.
+func (h *Handlers) GetWatchlist(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	dropshipperID := userID_raw.(int64)
+	list, ok := parseList(c, pagination.ListSpec{})
+	if !ok {
+		return
+	}
+
+	page := list.Page
+	cursorCond, cursorArgs := page.Where("w.created_at", "w.id")
+	query := `
+		SELECT w.id, p.id, p.public_id, p.name, JSON_UNQUOTE(JSON_EXTRACT(p.images, '$[0]')),
+			p.price_to_tts, ` + watchedStock + `, ` + store.InCatalogue("p") + `, w.created_at
+		FROM watchlist w JOIN products p ON p.id = w.product_id
+		WHERE w.dropshipper_id = ?` + cursorCond + page.OrderLimit("w.created_at", "w.id")
+	args := append([]interface{}{time.Now(), dropshipperID}, cursorArgs...)
+	rows, err := h.readDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch watchlist")
+		return
+	}
+	defer rows.Close()
+
+	items := []models.WatchlistItem{}
+	for rows.Next() {
+		var item models.WatchlistItem
+		var image sql.NullString
+		if err := rows.Scan(&item.ID, &item.ProductID, &item.PublicID, &item.Name, &image,
+			&item.Price, &item.Stock, &item.Available, &item.WatchedAt); err != nil {
+			apierror.Internal(c, "Failed to scan watchlist item")
+			return
+		}
+		item.Image = image.String
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Error iterating rows")
+		return
+	}
+
+	items, nextCursor := pagination.Paginate(page, items, func(item models.WatchlistItem) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: item.WatchedAt, ID: item.ID}
+	})
+	c.JSON(http.StatusOK, gin.H{"items": items, "nextCursor": nextCursor})
+}
+
+//
+// --- Watchlist Check (Background Worker) ---
+//
+
+// ProcessWatchlist notifies watchers of the products that changed since their
+// last check: a new price, or stock again after none. Every code path that
+// reprices or restocks moves products.changed_at, so nothing needs to publish
+// an event for it. The background worker calls it every
+// WATCHLIST_CHECK_INTERVAL.
+func (h *Handlers) ProcessWatchlist(ctx context.Context) {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT w.id, w.dropshipper_id, p.name, w.last_price, p.price_to_tts, w.in_stock,
+			`+watchedStock+`, `+store.InCatalogue("p")+`, p.changed_at
+		FROM watchlist w JOIN products p ON p.id = w.product_id
+		WHERE p.changed_at > w.checked_at
+		ORDER BY p.changed_at
+		LIMIT 500`, time.Now())
+	if err != nil {
+		logging.Errorf("[Watchlist] Error fetching changed products: %v", err)
+		return
+	}
+	var changes []models.WatchlistChange
+	for rows.Next() {
+		var w models.WatchlistChange
+		if err := rows.Scan(&w.WatchID, &w.DropshipperID, &w.ProductName, &w.LastPrice, &w.Price, &w.WasInStock,
+			&w.Stock, &w.Active, &w.ChangedAt); err != nil {
+			rows.Close()
+			logging.Errorf("[Watchlist] Error scanning changed product: %v", err)
+			return
+		}
+		changes = append(changes, w)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logging.Errorf("[Watchlist] Error fetching changed products: %v", err)
+		return
+	}
+
+	for i, w := range changes {
+		if ctx.Err() != nil {
+			logging.Infof("[Watchlist] Shutting down, %d changes left for the next run", len(changes)-i)
+			return
+		}
+		if err := h.notifyWatcher(context.WithoutCancel(ctx), w); err != nil {
+			logging.Errorf("[Watchlist] Failed to check watch %d for User %d: %v", w.WatchID, w.DropshipperID, err)
+		}
+	}
+}
+
+// notifyWatcher records that the watcher has seen the change and, for a
+// product still in the catalogue, tells them about a new price or a restock.
+// While the product is out of the catalogue only checked_at moves, so the
+// watcher hears about the difference once it is back.
+func (h *Handlers) notifyWatcher(ctx context.Context, w models.WatchlistChange) error {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	lastPrice, inStock := w.LastPrice, w.WasInStock
+	if w.Active {
+		lastPrice, inStock = w.Price, w.Stock > 0
+	}
+	result, err := tx.ExecContext(ctx,
+		"UPDATE watchlist SET last_price = ?, in_stock = ?, checked_at = ? WHERE id = ? AND checked_at < ?",
+		lastPrice, inStock, w.ChangedAt, w.WatchID, w.ChangedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 || !w.Active {
+		return tx.Commit() // checked by another instance, or nothing to tell yet
+	}
+
+	var messages []string
+	if w.Price != w.LastPrice {
+		messages = append(messages, fmt.Sprintf("The price of \"%s\" on your watchlist changed from RM %s to RM %s.", w.ProductName, w.LastPrice, w.Price))
+	}
+	if !w.WasInStock && w.Stock > 0 {
+		messages = append(messages, fmt.Sprintf("\"%s\" on your watchlist is back in stock.", w.ProductName))
+	}
+	for _, message := range messages {
+		if err := h.AddBatchedNotification(ctx, tx, w.DropshipperID, "watchlist", message, "/dropshipper/watchlist"); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
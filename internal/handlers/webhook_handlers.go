@@ -0,0 +1,595 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pii"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/01moynul/taptosell-golang/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+//
+// --- Webhooks & Wallet Stream ---
+//
+
+// Every wallet transaction becomes a wallet.credited (amount >= 0) or
+// wallet.debited event, so external accounting tools can mirror the ledger.
+// They get it two ways: webhooks, which ProcessWebhooks fans out from the
+// ledger and POSTs with retries, and GET /wallet/stream, a Server-Sent Events
+// stream that polls the caller's ledger directly.
+
+const (
+	// webhookSettle is how old a transaction must be before it is fanned out.
+	// The cursor only moves forward, so a row that commits after a later ID
+	// would otherwise be skipped.
+	webhookSettle = 5 * time.Second
+	// webhookFanOutBatch is how many transactions one run fans out.
+	webhookFanOutBatch = 500
+	// webhookDeliverBatch and webhookConcurrency bound one run's deliveries,
+	// so one slow endpoint does not hold up the others.
+	webhookDeliverBatch = 100
+	webhookConcurrency  = 8
+	// webhookClaim is how long a claimed delivery is hidden from other
+	// instances; longer than one attempt can take.
+	webhookClaim = 2 * time.Minute
+	// webhookRetention is how long finished deliveries stay in the history.
+	webhookRetention = 30 * 24 * time.Hour
+
+	// walletStreamPoll is how often the stream checks for new transactions;
+	// walletStreamHeartbeat keeps idle proxies from closing it.
+	walletStreamPoll      = 2 * time.Second
+	walletStreamHeartbeat = 15 * time.Second
+)
+
+// webhookRetryDelays are the waits after each failed attempt; a delivery
+// that fails once more after the last one is marked failed.
+var webhookRetryDelays = []time.Duration{
+	time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 8 * time.Hour, 24 * time.Hour,
+}
+
+type CreateWebhookInput struct {
+	URL    string   `json:"url" binding:"required,max=2048"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=wallet.credited wallet.debited"`
+}
+
+// CreateWebhook is the handler for POST /v1/webhooks
+// It registers an endpoint and returns its signing secret, the only time the
+// secret is shown.
+func (h *Handlers) CreateWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+
+	// 1. --- Validate Input ---
+	var input CreateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if err := h.Webhooks.CheckURL(input.URL); err != nil {
+		apierror.BadRequest(c, "The webhook URL must use https and a public address")
+		return
+	}
+
+	// 2. --- Limit Endpoints ---
+	var count int
+	if err := h.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_endpoints WHERE user_id = ?", userID).Scan(&count); err != nil {
+		apierror.Internal(c, "Failed to check webhooks")
+		return
+	}
+	if max := h.Config.Webhooks.MaxEndpoints; count >= max {
+		apierror.Conflict(c, fmt.Sprintf("You already have %d webhooks; delete one first", max))
+		return
+	}
+
+	// 3. --- Create with a New Secret ---
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		apierror.Internal(c, "Failed to create webhook secret")
+		return
+	}
+	sealed, err := h.PII.Encrypt(pii.WebhookSecret, secret)
+	if err != nil {
+		apierror.Internal(c, "Failed to secure webhook secret")
+		return
+	}
+	endpoint := models.WebhookEndpoint{
+		UserID:    userID,
+		URL:       input.URL,
+		Events:    dedupeStrings(input.Events),
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+	res, err := h.DB.ExecContext(ctx,
+		"INSERT INTO webhook_endpoints (user_id, url, secret, events, created_at) VALUES (?, ?, ?, ?, ?)",
+		userID, endpoint.URL, sealed, strings.Join(endpoint.Events, ","), endpoint.CreatedAt)
+	if err != nil {
+		apierror.Internal(c, "Failed to create webhook")
+		return
+	}
+	endpoint.ID, _ = res.LastInsertId()
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Webhook created; store the secret now, it is not shown again", "webhook": endpoint})
+}
+
+// GetMyWebhooks is the handler for GET /v1/webhooks
+func (h *Handlers) GetMyWebhooks(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+
+	rows, err := h.DB.QueryContext(ctx,
+		"SELECT id, user_id, url, events, created_at FROM webhook_endpoints WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch webhooks")
+		return
+	}
+	defer rows.Close()
+	endpoints := []models.WebhookEndpoint{}
+	for rows.Next() {
+		var e models.WebhookEndpoint
+		var events string
+		if err := rows.Scan(&e.ID, &e.UserID, &e.URL, &events, &e.CreatedAt); err != nil {
+			apierror.Internal(c, "Failed to read webhooks")
+			return
+		}
+		e.Events = strings.Split(events, ",")
+		endpoints = append(endpoints, e)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Failed to fetch webhooks")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": endpoints})
+}
+
+// DeleteWebhook is the handler for DELETE /v1/webhooks/:id
+// Deliveries still pending are dropped with it.
+func (h *Handlers) DeleteWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Webhook not found")
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Internal(c, "DB Transaction failed")
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM webhook_endpoints WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		apierror.Internal(c, "Failed to delete webhook")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		apierror.NotFound(c, "Webhook not found")
+		return
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE endpoint_id = ?", id); err != nil {
+		apierror.Internal(c, "Failed to delete webhook deliveries")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Internal(c, "Failed to commit transaction")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// GetWebhookDeliveries is the handler for GET /v1/webhooks/:id/deliveries
+// It lists the endpoint's latest deliveries, for debugging a receiver.
+func (h *Handlers) GetWebhookDeliveries(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.NotFound(c, "Webhook not found")
+		return
+	}
+	var owner int64
+	err = h.DB.QueryRowContext(ctx, "SELECT user_id FROM webhook_endpoints WHERE id = ?", id).Scan(&owner)
+	if err == sql.ErrNoRows || (err == nil && owner != userID) {
+		apierror.NotFound(c, "Webhook not found")
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch webhook")
+		return
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT id, event_id, event_type, status, attempts, next_attempt_at, last_status, last_error, created_at, delivered_at
+		FROM webhook_deliveries WHERE endpoint_id = ? ORDER BY id DESC LIMIT 50`, id)
+	if err != nil {
+		apierror.Internal(c, "Failed to fetch deliveries")
+		return
+	}
+	defer rows.Close()
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var next time.Time
+		if err := rows.Scan(&d.ID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &next,
+			&d.LastStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			apierror.Internal(c, "Failed to read deliveries")
+			return
+		}
+		if d.Status == "pending" {
+			d.NextAttemptAt = &next
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Internal(c, "Failed to fetch deliveries")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// StreamWallet is the handler for GET /v1/wallet/stream
+// A Server-Sent Events stream of the caller's wallet transactions, with the
+// same events webhooks get. Each event's id is its event ID; a reconnecting
+// client sends the last one back (Last-Event-ID, which EventSource does, or
+// ?lastEventId=) and resumes after it. Otherwise the stream starts with the
+// next transaction. The route's Timeout ends the stream; clients reconnect.
+func (h *Handlers) StreamWallet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID_raw, _ := c.Get("userID")
+	userID := userID_raw.(int64)
+
+	// 1. --- Starting Point ---
+	var lastID int64
+	resume := c.GetHeader("Last-Event-ID")
+	if resume == "" {
+		resume = c.Query("lastEventId")
+	}
+	if resume != "" {
+		id, err := strconv.ParseInt(strings.TrimPrefix(resume, walletEventPrefix), 10, 64)
+		if err != nil || id < 0 {
+			apierror.BadRequest(c, "Invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	} else if err := h.DB.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(id), 0) FROM wallet_transactions WHERE user_id = ?", userID).Scan(&lastID); err != nil {
+		apierror.Internal(c, "Failed to open wallet stream")
+		return
+	}
+
+	// 2. --- Stream ---
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // nginx would otherwise hold events back
+	c.Status(http.StatusOK)
+	// HTTP_WRITE_TIMEOUT is sized for JSON; the route's Timeout bounds the stream instead.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	if _, err := fmt.Fprint(c.Writer, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	poll := time.NewTicker(walletStreamPoll)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(walletStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-poll.C:
+			// A user's transactions lock their ledger, so they commit in ID
+			// order and, unlike the fan-out, need no settle delay.
+			events, err := queryWalletEvents(ctx, h.DB, "user_id = ? AND id > ? ORDER BY id LIMIT 100", userID, lastID)
+			if err != nil {
+				if ctx.Err() == nil {
+					logging.Errorf("[Webhooks] Wallet stream for User %d stopped: %v", userID, err)
+				}
+				return // the client reconnects with its Last-Event-ID
+			}
+			for _, ev := range events {
+				data, _ := json.Marshal(ev)
+				if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data); err != nil {
+					return
+				}
+				lastID = ev.Data.TransactionID
+			}
+			if len(events) > 0 {
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+// ProcessWebhooks fans new wallet transactions out to the endpoints
+// subscribed to them, sends due deliveries and prunes old ones. The
+// background worker calls it every WEBHOOK_DISPATCH_INTERVAL.
+func (h *Handlers) ProcessWebhooks(ctx context.Context) {
+	if err := h.fanOutWalletEvents(ctx); err != nil {
+		logging.Errorf("[Webhooks] Error fanning out wallet events: %v", err)
+	}
+	h.deliverWebhooks(ctx)
+	if _, err := h.DB.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < ? LIMIT 1000",
+		time.Now().Add(-webhookRetention)); err != nil {
+		logging.Errorf("[Webhooks] Error pruning deliveries: %v", err)
+	}
+}
+
+// fanOutWalletEvents turns the transactions after the wallet cursor into
+// pending deliveries and moves the cursor past them, in one transaction. The
+// cursor row lock keeps two instances from fanning out the same rows.
+func (h *Handlers) fanOutWalletEvents(ctx context.Context) error {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var lastID int64
+	if err := tx.QueryRowContext(ctx,
+		"SELECT last_id FROM webhook_cursors WHERE source = 'wallet_transactions' FOR UPDATE").Scan(&lastID); err != nil {
+		return fmt.Errorf("reading cursor: %w", err)
+	}
+	events, err := queryWalletEvents(ctx, tx, "id > ? AND created_at <= ? ORDER BY id LIMIT ?",
+		lastID, time.Now().Add(-webhookSettle), webhookFanOutBatch)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	// 1. --- Endpoints of the Users Concerned ---
+	userIDs := make([]interface{}, 0, len(events))
+	seen := make(map[int64]bool)
+	for _, ev := range events {
+		if !seen[ev.UserID] {
+			seen[ev.UserID] = true
+			userIDs = append(userIDs, ev.UserID)
+		}
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(userIDs)), ", ")
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, user_id, events FROM webhook_endpoints WHERE user_id IN ("+placeholders+")", userIDs...)
+	if err != nil {
+		return fmt.Errorf("reading endpoints: %w", err)
+	}
+	type subscription struct {
+		endpointID int64
+		events     string
+	}
+	subscriptions := make(map[int64][]subscription)
+	for rows.Next() {
+		var s subscription
+		var userID int64
+		if err := rows.Scan(&s.endpointID, &userID, &s.events); err != nil {
+			rows.Close()
+			return err
+		}
+		subscriptions[userID] = append(subscriptions[userID], s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// 2. --- Queue Deliveries ---
+	now := time.Now()
+	queued := 0
+	for _, ev := range events {
+		var payload []byte
+		for _, s := range subscriptions[ev.UserID] {
+			if !strings.Contains(","+s.events+",", ","+ev.Type+",") {
+				continue
+			}
+			if payload == nil {
+				payload, _ = json.Marshal(ev)
+			}
+			// IGNORE: a run that failed after queueing leaves rows behind.
+			if _, err := tx.ExecContext(ctx, `
+				INSERT IGNORE INTO webhook_deliveries
+					(endpoint_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+				VALUES (?, ?, ?, ?, 'pending', ?, ?)`,
+				s.endpointID, ev.ID, ev.Type, string(payload), now, now); err != nil {
+				return fmt.Errorf("queueing %s: %w", ev.ID, err)
+			}
+			queued++
+		}
+	}
+
+	lastID = events[len(events)-1].Data.TransactionID
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE webhook_cursors SET last_id = ? WHERE source = 'wallet_transactions'", lastID); err != nil {
+		return fmt.Errorf("moving cursor: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if queued > 0 {
+		logging.Infof("[Webhooks] Queued %d deliveries for %d wallet transactions", queued, len(events))
+	}
+	return nil
+}
+
+// deliverWebhooks sends the deliveries that are due, a few at a time.
+func (h *Handlers) deliverWebhooks(ctx context.Context) {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT id FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= ?
+		ORDER BY next_attempt_at LIMIT ?`, time.Now(), webhookDeliverBatch)
+	if err != nil {
+		logging.Errorf("[Webhooks] Error fetching due deliveries: %v", err)
+		return
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			logging.Errorf("[Webhooks] Error scanning due deliveries: %v", err)
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logging.Errorf("[Webhooks] Error fetching due deliveries: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, webhookConcurrency)
+	for _, id := range ids {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			h.deliverWebhook(ctx, id)
+		}()
+	}
+	wg.Wait()
+}
+
+// deliverWebhook claims one delivery, sends it and records the outcome.
+func (h *Handlers) deliverWebhook(ctx context.Context, id int64) {
+	// 1. --- Claim ---
+	// Moving next_attempt_at hides the delivery from other instances; if this
+	// one dies mid-attempt, the delivery comes due again after webhookClaim.
+	now := time.Now()
+	res, err := h.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?`, now.Add(webhookClaim), id, now)
+	if err != nil {
+		logging.Errorf("[Webhooks] Error claiming delivery %d: %v", id, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return // another instance has it
+	}
+
+	var url, secret, eventID, eventType, payload string
+	var attempts int
+	err = h.DB.QueryRowContext(ctx, `
+		SELECT e.url, e.secret, d.event_id, d.event_type, d.payload, d.attempts
+		FROM webhook_deliveries d
+		JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.id = ?`, id).Scan(&url, &secret, &eventID, &eventType, &payload, &attempts)
+	if err == sql.ErrNoRows {
+		return // the endpoint was deleted meanwhile
+	}
+	if err != nil {
+		logging.Errorf("[Webhooks] Error loading delivery %d: %v", id, err)
+		return
+	}
+	if secret, err = h.PII.Decrypt(pii.WebhookSecret, secret); err != nil {
+		logging.Errorf("[Webhooks] Cannot decrypt the secret of delivery %d: %v", id, err)
+		return
+	}
+
+	// 2. --- Send ---
+	status, sendErr := h.Webhooks.Deliver(ctx, url, secret, eventID, eventType, []byte(payload))
+	attempts++
+	lastStatus := sql.NullInt64{Int64: int64(status), Valid: status != 0}
+
+	// 3. --- Record ---
+	// context.WithoutCancel: a shutdown mid-send still records the attempt.
+	wctx := context.WithoutCancel(ctx)
+	if sendErr == nil {
+		_, err = h.DB.ExecContext(wctx, `
+			UPDATE webhook_deliveries
+			SET status = 'delivered', attempts = ?, last_status = ?, last_error = NULL, delivered_at = ?
+			WHERE id = ?`, attempts, lastStatus, time.Now(), id)
+	} else {
+		lastError := sendErr.Error()
+		if len(lastError) > 255 {
+			lastError = lastError[:255]
+		}
+		newStatus, next := "failed", time.Now()
+		if attempts <= len(webhookRetryDelays) {
+			newStatus, next = "pending", next.Add(webhookRetryDelays[attempts-1])
+		} else {
+			logging.Errorf("[Webhooks] Giving up on delivery %d (%s) after %d attempts: %s", id, eventID, attempts, lastError)
+		}
+		_, err = h.DB.ExecContext(wctx, `
+			UPDATE webhook_deliveries
+			SET status = ?, attempts = ?, next_attempt_at = ?, last_status = ?, last_error = ?
+			WHERE id = ?`, newStatus, attempts, next, lastStatus, lastError, id)
+	}
+	if err != nil {
+		logging.Errorf("[Webhooks] Error recording delivery %d: %v", id, err)
+	}
+}
+
+// walletEventPrefix starts the ID of every wallet event.
+const walletEventPrefix = "wtx_"
+
+// queryWalletEvents reads completed wallet transactions matching where (which
+// may end in ORDER BY and LIMIT) as events.
+func queryWalletEvents(ctx context.Context, q store.DBTX, where string, args ...interface{}) ([]models.WalletEvent, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, user_id, type, amount, balance_after, COALESCE(notes, ''), created_at
+		FROM wallet_transactions
+		WHERE status = 'completed' AND `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []models.WalletEvent
+	for rows.Next() {
+		var ev models.WalletEvent
+		d := &ev.Data
+		if err := rows.Scan(&d.TransactionID, &ev.UserID, &d.Kind, &d.Amount, &d.BalanceAfter, &d.Notes, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		ev.ID = walletEventPrefix + strconv.FormatInt(d.TransactionID, 10)
+		ev.Type = webhooks.WalletCredited
+		if d.Amount < 0 {
+			ev.Type = webhooks.WalletDebited
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// dedupeStrings returns values without repeats, in their first order.
+func dedupeStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := values[:0:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -3,10 +3,16 @@ package handlers
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
 	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/events"
 	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pii"
+	"github.com/01moynul/taptosell-golang/internal/store"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,36 +23,26 @@ import (
 // GetSupplierWallet is the handler for GET /v1/supplier/wallet
 // It returns the supplier's available balance and pending balance.
 func (h *Handlers) GetSupplierWallet(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get Supplier ID ---
 	userID_raw, _ := c.Get("userID")
 	supplierID := userID_raw.(int64)
 
 	// 2. --- Get Available Balance ---
 	// "Available" balance is their current wallet balance.
-	availableBalance, err := h.GetWalletBalance(h.DB, supplierID)
+	availableBalance, err := h.GetWalletBalance(ctx, h.DB, supplierID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get available wallet balance"})
+		apierror.Internal(c, "Failed to get available wallet balance")
 		return
 	}
 
 	// 3. --- Get Pending Balance (from 'shipped' orders) ---
-	// "Pending" balance is the total value of orders that have been
+	// "Pending" balance is the supplier's share of orders that have been
 	// marked as 'shipped' but not yet 'completed' (i.e., not yet paid out).
-	// We need to query the 'order_items' and 'orders' tables.
-	var pendingBalance sql.NullFloat64
-	query := `
-		SELECT SUM(oi.unit_price * oi.quantity)
-		FROM order_items oi
-		JOIN orders o ON oi.order_id = o.id
-		JOIN products p ON oi.product_id = p.id
-		WHERE p.supplier_id = ? AND o.status = 'shipped'
-	`
-	// We'll also need to factor in commission here in the future,
-	// but for now, this gets the total value.
-
-	err = h.DB.QueryRow(query, supplierID).Scan(&pendingBalance)
-	if err != nil && err != sql.ErrNoRows {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pending balance"})
+	pendingBalance, err := h.pendingBalance(ctx, h.DB, supplierID)
+	if err != nil {
+		apierror.Internal(c, "Failed to get pending balance")
 		return
 	}
 
@@ -59,9 +55,9 @@ func (h *Handlers) GetSupplierWallet(c *gin.Context) {
 		ORDER BY created_at DESC
 		LIMIT 20
 	`
-	rows, err := h.DB.Query(historyQuery, supplierID)
+	rows, err := h.DB.QueryContext(ctx, historyQuery, supplierID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get withdrawal history"})
+		apierror.Internal(c, "Failed to get withdrawal history")
 		return
 	}
 	defer rows.Close()
@@ -70,7 +66,7 @@ func (h *Handlers) GetSupplierWallet(c *gin.Context) {
 	for rows.Next() {
 		var req models.WithdrawalRequest
 		if err := rows.Scan(&req.ID, &req.Amount, &req.Status, &req.RejectionReason, &req.CreatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan withdrawal history"})
+			apierror.Internal(c, "Failed to scan withdrawal history")
 			return
 		}
 		history = append(history, req)
@@ -79,19 +75,21 @@ func (h *Handlers) GetSupplierWallet(c *gin.Context) {
 	// 5. --- Send Response ---
 	c.JSON(http.StatusOK, gin.H{
 		"availableBalance": availableBalance,
-		"pendingBalance":   pendingBalance.Float64, // Will be 0.0 if NULL
+		"pendingBalance":   pendingBalance,
 		"history":          history,
 	})
 }
 
 // RequestWithdrawalInput defines the JSON for a withdrawal request
 type RequestWithdrawalInput struct {
-	Amount      float64 `json:"amount" binding:"required,gt=0"`
-	BankDetails string  `json:"bankDetails" binding:"required"`
+	Amount      money.Money `json:"amount" binding:"required,gt=0"`
+	BankDetails string      `json:"bankDetails" binding:"required"`
 }
 
 // RequestWithdrawal is the handler for POST /v1/supplier/wallet/request-withdrawal
 func (h *Handlers) RequestWithdrawal(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get Supplier ID ---
 	userID_raw, _ := c.Get("userID")
 	supplierID := userID_raw.(int64)
@@ -99,29 +97,36 @@ func (h *Handlers) RequestWithdrawal(c *gin.Context) {
 	// 2. --- Bind & Validate JSON ---
 	var input RequestWithdrawalInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
+		return
+	}
+
+	// Bank details are encrypted at rest; managers see them decrypted.
+	bankDetails, err := h.PII.Encrypt(pii.WithdrawalBankDetails, input.BankDetails)
+	if err != nil {
+		apierror.Internal(c, "Failed to create withdrawal request")
 		return
 	}
 
 	// 3. --- Begin Transaction ---
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
 
 	// 4. --- Check Available Balance ---
-	// We pass the transaction 'tx' to GetWalletBalance to ensure
-	// our balance check is part of the atomic operation.
-	availableBalance, err := h.GetWalletBalance(tx, supplierID)
+	// The wallet stays locked until the commit, so two withdrawals (or a
+	// withdrawal and a payment) cannot both pass the check.
+	availableBalance, err := store.NewWalletStore(tx).LockBalance(ctx, supplierID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wallet balance"})
+		apierror.Internal(c, "Failed to get wallet balance")
 		return
 	}
 
 	if availableBalance < input.Amount {
-		c.JSON(http.StatusConflict, gin.H{"error": "Insufficient funds. Your available balance is lower than the requested amount."})
+		apierror.Conflict(c, "Insufficient funds. Your available balance is lower than the requested amount.")
 		return
 	}
 
@@ -132,9 +137,9 @@ func (h *Handlers) RequestWithdrawal(c *gin.Context) {
 		VALUES (?, ?, 'pending', ?, ?, ?)`
 
 	now := time.Now()
-	result, err := tx.Exec(reqQuery, supplierID, input.Amount, input.BankDetails, now, now)
+	result, err := tx.ExecContext(ctx, reqQuery, supplierID, input.Amount, bankDetails, now, now)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create withdrawal request"})
+		apierror.Internal(c, "Failed to create withdrawal request")
 		return
 	}
 	requestID, _ := result.LastInsertId()
@@ -143,15 +148,15 @@ func (h *Handlers) RequestWithdrawal(c *gin.Context) {
 	// As per our WP audit, this deducts the funds from the "available"
 	// balance immediately, holding them in "pending" status.
 	details := fmt.Sprintf("Pending withdrawal (Request ID: %d)", requestID)
-	err = h.AddWalletTransaction(tx, supplierID, "withdrawal", -input.Amount, details)
+	err = h.AddWalletTransaction(ctx, tx, supplierID, "withdrawal", -input.Amount, details)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add wallet transaction"})
+		apierror.Internal(c, "Failed to add wallet transaction")
 		return
 	}
 
 	// 7. --- Commit Transaction ---
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		apierror.Internal(c, "Failed to commit transaction")
 		return
 	}
 
@@ -168,6 +173,8 @@ func (h *Handlers) RequestWithdrawal(c *gin.Context) {
 // GetWithdrawalRequests is the handler for GET /v1/manager/withdrawal-requests
 // It retrieves all 'pending' requests for managers to review.
 func (h *Handlers) GetWithdrawalRequests(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Query Database ---
 	// We JOIN with the 'users' table to get the supplier's name/email
 	query := `
@@ -180,9 +187,9 @@ func (h *Handlers) GetWithdrawalRequests(c *gin.Context) {
 		WHERE wr.status = 'pending'
 		ORDER BY wr.created_at ASC
 	`
-	rows, err := h.DB.Query(query)
+	rows, err := h.DB.QueryContext(ctx, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		apierror.Internal(c, "Database query failed")
 		return
 	}
 	defer rows.Close()
@@ -202,14 +209,19 @@ func (h *Handlers) GetWithdrawalRequests(c *gin.Context) {
 			&req.SupplierName,
 			&req.SupplierEmail,
 		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan withdrawal request"})
+			apierror.Internal(c, "Failed to scan withdrawal request")
+			return
+		}
+		if req.BankDetails, err = h.PII.Decrypt(pii.WithdrawalBankDetails, req.BankDetails); err != nil {
+			log.Printf("withdrawal request %d: %v", req.ID, err)
+			apierror.Internal(c, "Failed to read bank details")
 			return
 		}
 		requests = append(requests, &req)
 	}
 
 	if err = rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error iterating rows"})
+		apierror.Internal(c, "Error iterating rows")
 		return
 	}
 
@@ -227,24 +239,26 @@ type ProcessWithdrawalInput struct {
 
 // ProcessWithdrawalRequest is the handler for PATCH /v1/manager/withdrawal-requests/:id
 func (h *Handlers) ProcessWithdrawalRequest(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// 1. --- Get IDs & Bind Input ---
 	requestID := c.Param("id")
 
 	var input ProcessWithdrawalInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
 	if input.Action == "reject" && input.RejectionReason == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "A rejectionReason is required when rejecting a request"})
+		apierror.BadRequest(c, "A rejectionReason is required when rejecting a request")
 		return
 	}
 
 	// 2. --- Begin Transaction ---
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apierror.Internal(c, "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
@@ -253,18 +267,18 @@ func (h *Handlers) ProcessWithdrawalRequest(c *gin.Context) {
 	// We must lock the row and check its status
 	var req models.WithdrawalRequest
 	query := "SELECT id, user_id, amount, status FROM withdrawal_requests WHERE id = ? FOR UPDATE"
-	err = tx.QueryRow(query, requestID).Scan(&req.ID, &req.UserID, &req.Amount, &req.Status)
+	err = tx.QueryRowContext(ctx, query, requestID).Scan(&req.ID, &req.UserID, &req.Amount, &req.Status)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Withdrawal request not found"})
+			apierror.NotFound(c, "Withdrawal request not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get request details"})
+		apierror.Internal(c, "Failed to get request details")
 		return
 	}
 
 	if req.Status != "pending" {
-		c.JSON(http.StatusConflict, gin.H{"error": "This request has already been processed"})
+		apierror.Conflict(c, "This request has already been processed")
 		return
 	}
 
@@ -273,28 +287,26 @@ func (h *Handlers) ProcessWithdrawalRequest(c *gin.Context) {
 		// Action: Approve
 		// Just update the status. The funds are already deducted.
 		updateQuery := "UPDATE withdrawal_requests SET status = 'approved' WHERE id = ?"
-		if _, err := tx.Exec(updateQuery, requestID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve request"})
+		if _, err := tx.ExecContext(ctx, updateQuery, requestID); err != nil {
+			apierror.Internal(c, "Failed to approve request")
 			return
 		}
 
-		// TODO: Add notification to supplier
-
 	} else {
 		// Action: Reject
 		// 1. Update the request status and reason
 		updateQuery := "UPDATE withdrawal_requests SET status = 'rejected', rejection_reason = ? WHERE id = ?"
-		if _, err := tx.Exec(updateQuery, input.RejectionReason, requestID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject request"})
+		if _, err := tx.ExecContext(ctx, updateQuery, input.RejectionReason, requestID); err != nil {
+			apierror.Internal(c, "Failed to reject request")
 			return
 		}
 
 		// 2. Refund the money to the supplier's wallet
 		// The original amount was negative, so we add a positive amount back.
 		details := fmt.Sprintf("Refund for rejected withdrawal (Request ID: %d)", req.ID)
-		err = h.AddWalletTransaction(tx, req.UserID, "refund", req.Amount, details)
+		err = h.AddWalletTransaction(ctx, tx, req.UserID, "refund", req.Amount, details)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refund wallet"})
+			apierror.Internal(c, "Failed to refund wallet")
 			return
 		}
 
@@ -303,9 +315,12 @@ func (h *Handlers) ProcessWithdrawalRequest(c *gin.Context) {
 
 	// 5. --- Commit Transaction ---
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		apierror.Internal(c, "Failed to commit transaction")
 		return
 	}
+	if input.Action == "approve" {
+		h.Events.Publish(ctx, events.WithdrawalApproved{WithdrawalID: req.ID, UserID: req.UserID, Amount: req.Amount})
+	}
 
 	// 6. --- Send Response ---
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,299 @@
+// Package i18n translates the API's user-facing text: error messages,
+// validation messages, notifications and emails.
+//
+// The English text in the code is the message ID. A catalog maps it to a
+// translation, e.g. locales/ms.json:
+//
+//	{
+//	  "Order not found": "Pesanan tidak dijumpai",
+//	  "New paid order #%d is ready to ship.": "Pesanan berbayar baharu #%d sedia untuk dihantar."
+//	}
+//
+// A key with fmt verbs also translates text that was already formatted with
+// it: "New paid order #42 is ready to ship." matches the key above and comes
+// out as "Pesanan berbayar baharu #42 sedia untuk dihantar.". Translations
+// may reorder the arguments with explicit indexes (%[2]s). Text without a
+// catalog entry is returned unchanged, so English is always the fallback.
+//
+// Catalogs are read once at startup by Load; until then T returns its input.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Default is the language of the source text and the fallback.
+const Default = "en"
+
+// ContextKey is the gin context key holding the request's language (set by middleware.Locale).
+const ContextKey = "lang"
+
+//go:embed locales/*.json
+var builtin embed.FS
+
+// verb matches one fmt verb ("%d", "%.2f", "%[2]s"); "%%" is handled separately.
+var verb = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z]`)
+
+// pattern is a catalog key with fmt verbs, compiled to match formatted text.
+type pattern struct {
+	re     *regexp.Regexp
+	format string // the translation, every verb rewritten to an indexed %[n]s
+	quoted []bool // per argument: the source verb was %q, so it is not translated
+}
+
+// catalog is one language's translations.
+type catalog struct {
+	exact    map[string]string
+	patterns []pattern
+}
+
+// catalogs maps a language to its catalog; swapped whole by Load.
+var catalogs atomic.Pointer[map[string]*catalog]
+
+// Load reads the built-in catalogs, then every <lang>.json in dir (when not
+// empty), whose entries override the built-in ones. It fails on unreadable
+// files and on translations whose verbs do not match their key.
+func Load(dir string) error {
+	raw := map[string]map[string]string{}
+	if err := readCatalogs(builtin, "locales", raw); err != nil {
+		return err
+	}
+	if dir != "" {
+		if err := readCatalogs(os.DirFS(dir), ".", raw); err != nil {
+			return err
+		}
+	}
+
+	loaded := make(map[string]*catalog, len(raw))
+	for lang, entries := range raw {
+		cat, err := compile(entries)
+		if err != nil {
+			return fmt.Errorf("i18n: %s: %w", lang, err)
+		}
+		loaded[lang] = cat
+	}
+	catalogs.Store(&loaded)
+	return nil
+}
+
+// readCatalogs merges every *.json file of dir in fsys into raw, keyed by the file name.
+func readCatalogs(fsys fs.FS, dir string, raw map[string]map[string]string) error {
+	files, err := fs.Glob(fsys, filepath.ToSlash(filepath.Join(dir, "*.json")))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("i18n: %w", err)
+		}
+		var entries map[string]string
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("i18n: %s: %w", file, err)
+		}
+		lang := strings.ToLower(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if raw[lang] == nil {
+			raw[lang] = map[string]string{}
+		}
+		for k, v := range entries {
+			raw[lang][k] = v
+		}
+	}
+	return nil
+}
+
+// compile builds a catalog, turning keys with verbs into patterns.
+func compile(entries map[string]string) (*catalog, error) {
+	cat := &catalog{exact: make(map[string]string, len(entries))}
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	// Longer keys first, so the most specific pattern wins.
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		translation := entries[key]
+		cat.exact[key] = translation
+
+		verbs := verb.FindAllString(strings.ReplaceAll(key, "%%", ""), -1)
+		if len(verbs) == 0 {
+			continue
+		}
+		p, err := compilePattern(key, translation, verbs)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", key, err)
+		}
+		cat.patterns = append(cat.patterns, p)
+	}
+	return cat, nil
+}
+
+func compilePattern(key, translation string, verbs []string) (pattern, error) {
+	p := pattern{quoted: make([]bool, len(verbs))}
+
+	// The key becomes an anchored regexp with one group per verb.
+	var expr strings.Builder
+	expr.WriteString("^")
+	rest := key
+	for i, v := range verbs {
+		at := strings.Index(rest, v)
+		expr.WriteString(regexp.QuoteMeta(strings.ReplaceAll(rest[:at], "%%", "%")))
+		if strings.HasSuffix(v, "q") {
+			p.quoted[i] = true
+			expr.WriteString(`("(?:[^"\\]|\\.)*")`)
+		} else {
+			expr.WriteString("(.+?)")
+		}
+		rest = rest[at+len(v):]
+	}
+	expr.WriteString(regexp.QuoteMeta(strings.ReplaceAll(rest, "%%", "%")))
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return p, err
+	}
+	p.re = re
+
+	// The translation gets the already formatted arguments back as strings.
+	n := 0
+	var bad error
+	p.format = verb.ReplaceAllStringFunc(translation, func(v string) string {
+		n++
+		index := n
+		if m := verb.FindStringSubmatch(v); m[1] != "" {
+			index, _ = strconv.Atoi(strings.Trim(m[1], "[]"))
+			n = index
+		}
+		if index < 1 || index > len(verbs) {
+			bad = fmt.Errorf("translation uses argument %d, the key has %d", index, len(verbs))
+		}
+		return fmt.Sprintf("%%[%d]s", index)
+	})
+	return p, bad
+}
+
+// Languages lists the languages with a catalog, plus Default.
+func Languages() []string {
+	langs := []string{Default}
+	if loaded := catalogs.Load(); loaded != nil {
+		for lang := range *loaded {
+			if lang != Default {
+				langs = append(langs, lang)
+			}
+		}
+	}
+	sort.Strings(langs[1:])
+	return langs
+}
+
+// Supported reports whether lang is Default or has a catalog.
+func Supported(lang string) bool {
+	if lang == Default {
+		return true
+	}
+	loaded := catalogs.Load()
+	if loaded == nil {
+		return false
+	}
+	_, ok := (*loaded)[lang]
+	return ok
+}
+
+// T translates msg into lang. Untranslated text, an unknown language and
+// Default return msg unchanged.
+func T(lang, msg string) string {
+	return translate(lang, msg, true)
+}
+
+// translate looks msg up; nested also translates the text arguments of a
+// pattern match (a notification built from another translatable sentence).
+func translate(lang, msg string, nested bool) string {
+	if lang == "" || lang == Default || msg == "" {
+		return msg
+	}
+	loaded := catalogs.Load()
+	if loaded == nil {
+		return msg
+	}
+	cat, ok := (*loaded)[lang]
+	if !ok {
+		return msg
+	}
+	if t, ok := cat.exact[msg]; ok {
+		return t
+	}
+	for _, p := range cat.patterns {
+		m := p.re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		args := make([]interface{}, len(m)-1)
+		for i, arg := range m[1:] {
+			if nested && !p.quoted[i] {
+				arg = translate(lang, arg, false)
+			}
+			args[i] = arg
+		}
+		return fmt.Sprintf(p.format, args...)
+	}
+	return msg
+}
+
+// Sprintf formats with the translation of format.
+func Sprintf(lang, format string, args ...interface{}) string {
+	return fmt.Sprintf(T(lang, format), args...)
+}
+
+// Negotiate picks the best supported language from an Accept-Language header
+// ("ms-MY,ms;q=0.9,en;q=0.8"), matching on the primary subtag. It returns
+// Default when nothing matches.
+func Negotiate(acceptLanguage string) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary == "" || q <= 0 {
+			continue
+		}
+		choices = append(choices, choice{primary, q})
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+
+	for _, c := range choices {
+		if c.lang == "*" {
+			return Default
+		}
+		if Supported(c.lang) {
+			return c.lang
+		}
+	}
+	return Default
+}
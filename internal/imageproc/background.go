@@ -0,0 +1,141 @@
+package imageproc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// Remover makes the background of a photo transparent.
+type Remover interface {
+	Remove(ctx context.Context, img *image.NRGBA) (*image.NRGBA, error)
+}
+
+// EdgeRemover clears the plain backdrop most product photos are shot on: the
+// colour of the corners, flooded inwards from the edges. Pixels within
+// Tolerance (per channel, 0-255) of it become transparent. A busy background
+// is mostly left alone; IMAGE_BACKGROUND_API_URL handles those.
+type EdgeRemover struct {
+	Tolerance int
+}
+
+func (e EdgeRemover) Remove(_ context.Context, img *image.NRGBA) (*image.NRGBA, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return img, nil
+	}
+
+	// The backdrop is the average of the four corners.
+	var ref [3]int
+	for _, p := range []image.Point{{0, 0}, {w - 1, 0}, {0, h - 1}, {w - 1, h - 1}} {
+		i := img.PixOffset(p.X, p.Y)
+		for c := range ref {
+			ref[c] += int(img.Pix[i+c])
+		}
+	}
+	for c := range ref {
+		ref[c] /= 4
+	}
+	matches := func(i int) bool {
+		for c := range ref {
+			if d := int(img.Pix[i+c]) - ref[c]; d > e.Tolerance || d < -e.Tolerance {
+				return false
+			}
+		}
+		return true
+	}
+
+	out := image.NewNRGBA(img.Bounds())
+	copy(out.Pix, img.Pix)
+	seen := make([]bool, w*h)
+	var queue []image.Point
+	push := func(x, y int) {
+		if x < 0 || y < 0 || x >= w || y >= h || seen[y*w+x] {
+			return
+		}
+		seen[y*w+x] = true
+		if matches(img.PixOffset(x, y)) {
+			queue = append(queue, image.Pt(x, y))
+		}
+	}
+	for x := 0; x < w; x++ {
+		push(x, 0)
+		push(x, h-1)
+	}
+	for y := 0; y < h; y++ {
+		push(0, y)
+		push(w-1, y)
+	}
+	for len(queue) > 0 {
+		p := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		out.Pix[out.PixOffset(p.X, p.Y)+3] = 0
+		push(p.X+1, p.Y)
+		push(p.X-1, p.Y)
+		push(p.X, p.Y+1)
+		push(p.X, p.Y-1)
+	}
+	return out, nil
+}
+
+// HTTPRemover sends the photo to a background-removal API in the remove.bg
+// style: a multipart POST of "image_file" with the key in X-Api-Key,
+// answered by the cut-out as a PNG.
+type HTTPRemover struct {
+	URL    string
+	APIKey string
+	client *http.Client
+}
+
+// NewHTTPRemover returns a remover calling url with apiKey.
+func NewHTTPRemover(url, apiKey string) *HTTPRemover {
+	return &HTTPRemover{URL: url, APIKey: apiKey, client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+func (r *HTTPRemover) Remove(ctx context.Context, img *image.NRGBA) (*image.NRGBA, error) {
+	// 1. --- Build the Request ---
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image_file", "image.png")
+	if err != nil {
+		return nil, err
+	}
+	if err := png.Encode(part, img); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Accept", "image/png")
+	if r.APIKey != "" {
+		req.Header.Set("X-Api-Key", r.APIKey)
+	}
+
+	// 2. --- Send & Decode the Cut-out ---
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRemoval, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%w: %s: %s", ErrRemoval, resp.Status, bytes.TrimSpace(msg))
+	}
+	cut, err := png.Decode(io.LimitReader(resp.Body, 4*maxPixels+1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRemoval, err)
+	}
+	return toNRGBA(cut), nil
+}
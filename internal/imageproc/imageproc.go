@@ -0,0 +1,227 @@
+// Package imageproc normalizes product photos for listing: the background is
+// optionally removed, the photo is cut square onto white, scaled to a fixed
+// size and optionally watermarked. The result is always a JPEG.
+//
+// Only the formats of the standard library decode (JPEG, PNG, GIF); a WebP
+// upload is kept as it is but cannot be processed.
+package imageproc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // decoder registration
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+)
+
+var (
+	// ErrUnsupported is returned for an image the standard decoders cannot read.
+	ErrUnsupported = errors.New("imageproc: image format cannot be processed")
+	// ErrTooLarge is returned for an image over maxPixels.
+	ErrTooLarge = errors.New("imageproc: image has too many pixels")
+	// ErrNoWatermark is returned when a watermark is asked for but IMAGE_WATERMARK is not set.
+	ErrNoWatermark = errors.New("imageproc: no watermark configured")
+	// ErrRemoval is returned when the background-removal API fails.
+	ErrRemoval = errors.New("imageproc: background removal failed")
+)
+
+const (
+	// maxPixels bounds the decoded size, so a small file cannot expand into
+	// gigabytes of memory.
+	maxPixels = 50_000_000
+
+	// contentMargin is the white border kept around a cut-out product, as a
+	// fraction of its longer side.
+	contentMargin = 0.05
+
+	// watermarkWidth is the watermark's width as a fraction of the image's,
+	// and watermarkInset its distance from the bottom-right corner.
+	watermarkWidth = 0.2
+	watermarkInset = 0.03
+
+	jpegQuality = 90
+)
+
+// Options are the steps asked for on one image.
+type Options struct {
+	RemoveBackground bool
+	Watermark        bool
+}
+
+// Processor runs the pipeline with the deployment's settings.
+type Processor struct {
+	Size      int         // output width and height in pixels
+	Remover   Remover     // background removal
+	Watermark image.Image // nil without IMAGE_WATERMARK
+}
+
+// New builds the processor from the image settings, loading the watermark.
+func New(cfg config.Images) (*Processor, error) {
+	p := &Processor{Size: cfg.Size, Remover: EdgeRemover{Tolerance: cfg.BackgroundTolerance}}
+	if cfg.BackgroundAPIURL != "" {
+		p.Remover = NewHTTPRemover(cfg.BackgroundAPIURL, cfg.BackgroundAPIKey)
+	}
+	if cfg.Watermark != "" {
+		f, err := os.Open(cfg.Watermark)
+		if err != nil {
+			return nil, fmt.Errorf("imageproc: watermark: %w", err)
+		}
+		defer f.Close()
+		if p.Watermark, err = png.Decode(f); err != nil {
+			return nil, fmt.Errorf("imageproc: watermark must be a PNG: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// Process reads an image from r and returns the normalized JPEG.
+func (p *Processor) Process(ctx context.Context, r io.Reader, opts Options) ([]byte, error) {
+	if opts.Watermark && p.Watermark == nil {
+		return nil, ErrNoWatermark
+	}
+
+	// 1. --- Decode (the header first, to refuse decompression bombs) ---
+	var buf bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &buf))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	src, _, err := image.Decode(io.MultiReader(&buf, r))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	img := toNRGBA(src)
+
+	// 2. --- Background & Square Frame ---
+	// A cut-out product is framed by its own bounds, so it ends up centred
+	// whatever the original composition; otherwise the centre square is kept.
+	var frame image.Rectangle
+	if opts.RemoveBackground {
+		if img, err = p.Remover.Remove(ctx, img); err != nil {
+			return nil, err
+		}
+		frame = squareAround(opaqueBounds(img), contentMargin)
+	} else {
+		frame = centreSquare(img.Bounds())
+	}
+	square := image.NewNRGBA(image.Rect(0, 0, frame.Dx(), frame.Dy()))
+	draw.Draw(square, square.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(square, square.Bounds(), img, frame.Min, draw.Over)
+
+	// 3. --- Scale & Watermark ---
+	out := resize(square, p.Size, p.Size)
+	if opts.Watermark {
+		p.watermark(out)
+	}
+
+	// 4. --- Encode ---
+	var w bytes.Buffer
+	if err := jpeg.Encode(&w, out, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+// watermark draws the watermark into the bottom-right corner of img.
+func (p *Processor) watermark(img *image.NRGBA) {
+	b := p.Watermark.Bounds()
+	width := int(float64(img.Bounds().Dx()) * watermarkWidth)
+	height := width * b.Dy() / max(b.Dx(), 1)
+	if width < 1 || height < 1 {
+		return
+	}
+	mark := resize(toNRGBA(p.Watermark), width, height)
+	inset := int(float64(img.Bounds().Dx()) * watermarkInset)
+	at := image.Pt(img.Bounds().Max.X-inset-width, img.Bounds().Max.Y-inset-height)
+	draw.Draw(img, image.Rectangle{Min: at, Max: at.Add(image.Pt(width, height))}, mark, image.Point{}, draw.Over)
+}
+
+// toNRGBA copies img into an NRGBA image at the origin.
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+	return out
+}
+
+// centreSquare is the largest square centred in r.
+func centreSquare(r image.Rectangle) image.Rectangle {
+	side := min(r.Dx(), r.Dy())
+	x := r.Min.X + (r.Dx()-side)/2
+	y := r.Min.Y + (r.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// squareAround is the square centred on r, with a margin of its longer side.
+// It may reach past the image; that part stays white.
+func squareAround(r image.Rectangle, margin float64) image.Rectangle {
+	long := max(r.Dx(), r.Dy())
+	side := long + 2*int(float64(long)*margin)
+	cx, cy := (r.Min.X+r.Max.X)/2, (r.Min.Y+r.Max.Y)/2
+	return image.Rect(cx-side/2, cy-side/2, cx-side/2+side, cy-side/2+side)
+}
+
+// opaqueBounds is the smallest rectangle holding every pixel that is not fully
+// transparent; the whole image when every pixel is.
+func opaqueBounds(img *image.NRGBA) image.Rectangle {
+	b := img.Bounds()
+	found := image.Rectangle{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.Pix[img.PixOffset(x, y)+3] == 0 {
+				continue
+			}
+			found = found.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	if found.Empty() {
+		return b
+	}
+	return found
+}
+
+// resize scales src to w x h, averaging the source pixels under each output
+// pixel (weighted by alpha), which keeps downscaled photos free of aliasing.
+func resize(src *image.NRGBA, w, h int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					pa := uint64(src.Pix[i+3])
+					r += uint64(src.Pix[i]) * pa
+					g += uint64(src.Pix[i+1]) * pa
+					b += uint64(src.Pix[i+2]) * pa
+					a += pa
+					n++
+					i += 4
+				}
+			}
+			o := dst.PixOffset(x, y)
+			if a > 0 {
+				dst.Pix[o], dst.Pix[o+1], dst.Pix[o+2] = uint8(r/a), uint8(g/a), uint8(b/a)
+			}
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,140 @@
+// Package jobs is a small in-process background job queue: a bounded buffer
+// drained by a fixed pool of workers, with retries and a graceful shutdown.
+// Jobs are not persisted, so a crash loses whatever is still queued; use it
+// for side effects that are safe to miss (notifications, re-sync triggers).
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/logging"
+)
+
+var (
+	// ErrClosed is returned by Enqueue once Shutdown has started.
+	ErrClosed = errors.New("jobs: queue closed")
+	// ErrFull is returned by Enqueue when the buffer is full; callers never block.
+	ErrFull = errors.New("jobs: queue full")
+)
+
+// maxAttempts is how often a failing job runs before it is dropped.
+const maxAttempts = 3
+
+// Job is one unit of background work.
+type Job struct {
+	Name string // for logs
+	Run  func(ctx context.Context) error
+}
+
+// Queue runs jobs on a worker pool.
+type Queue struct {
+	mu     sync.RWMutex // guards closed against concurrent Enqueue
+	closed bool
+	jobs   chan Job
+	wg     sync.WaitGroup
+
+	// ctx is handed to every job; Shutdown cancels it once the drain budget is spent.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewQueue starts workers goroutines reading from a buffer of size jobs.
+func NewQueue(workers, size int) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		jobs:   make(chan Job, size),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue schedules job without blocking.
+func (q *Queue) Enqueue(job Job) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrClosed
+	}
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		return ErrFull
+	}
+}
+
+// Shutdown stops accepting jobs and waits for the queued ones to finish.
+// When ctx expires first, running jobs see their context cancelled and the
+// rest of the buffer is dropped; ctx.Err() is returned.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for job := range q.jobs {
+		if q.ctx.Err() != nil {
+			logging.Warnf("[Jobs] Dropping %s: queue shutting down", job.Name)
+			continue
+		}
+		q.run(job)
+	}
+}
+
+// run executes job with up to maxAttempts tries and a short linear backoff.
+func (q *Queue) run(job Job) {
+	for attempt := 1; ; attempt++ {
+		err := runSafely(q.ctx, job)
+		if err == nil {
+			return
+		}
+		if attempt == maxAttempts || q.ctx.Err() != nil {
+			logging.Errorf("[Jobs] %s failed after %d attempt(s): %v", job.Name, attempt, err)
+			return
+		}
+		select {
+		case <-time.After(time.Duration(attempt) * time.Second):
+		case <-q.ctx.Done():
+		}
+	}
+}
+
+// runSafely turns a panicking job into an error so one bad job cannot kill a worker.
+func runSafely(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
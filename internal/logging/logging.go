@@ -0,0 +1,80 @@
+// Package logging gates log output by a level that can be changed while the
+// API runs (PATCH /v1/manager/logging), so debugging production does not need
+// a restart. Messages still go through the standard logger.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Level orders messages by severity; only messages at or above the current level are written.
+type Level int32
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = [...]string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("level(%d)", int32(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel accepts debug, info, warn or error (case-insensitive).
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return Level(i), nil
+		}
+	}
+	return Info, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+}
+
+var current atomic.Int32 // holds a Level; the zero value would be Debug, so init sets Info
+
+func init() {
+	current.Store(int32(Info))
+}
+
+// SetLevel changes the level for every goroutine at once.
+func SetLevel(l Level) {
+	current.Store(int32(l))
+}
+
+// CurrentLevel returns the active level.
+func CurrentLevel() Level {
+	return Level(current.Load())
+}
+
+// Enabled reports whether messages at l are written.
+func Enabled(l Level) bool {
+	return l >= CurrentLevel()
+}
+
+func logf(l Level, format string, args ...interface{}) {
+	if !Enabled(l) {
+		return
+	}
+	log.Printf("["+strings.ToUpper(l.String())+"] "+format, args...)
+}
+
+// Debugf logs detail only useful while chasing a problem.
+func Debugf(format string, args ...interface{}) { logf(Debug, format, args...) }
+
+// Infof logs normal operation (requests, completed jobs).
+func Infof(format string, args ...interface{}) { logf(Info, format, args...) }
+
+// Warnf logs something degraded but handled (slow queries, fallbacks).
+func Warnf(format string, args ...interface{}) { logf(Warn, format, args...) }
+
+// Errorf logs a failure that needs attention.
+func Errorf(format string, args ...interface{}) { logf(Error, format, args...) }
@@ -1,9 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"database/sql"
-	"net/http"
+	"errors"
+	"slices"
+	"strings"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
 	"github.com/gin-gonic/gin"
 )
 
@@ -11,133 +15,94 @@ import (
 // --- Role-Based Middleware ---
 //
 // These middleware functions are designed to be USED *AFTER*
-// the main AuthMiddleware(). They read the 'userID' from the context,
-// query the DB for that user's role, and then enforce role permissions.
+// the main AuthMiddleware(). They check the role it put in the context
+// (the token's role claim, which it checked against the user's row) and only
+// query the DB for that user's role when it is missing, then enforce role
+// permissions.
 //
 
 // queryUserRole is a helper to get the user's role from the DB.
-func queryUserRole(db *sql.DB, userID int64) (string, error) {
+func queryUserRole(ctx context.Context, db *sql.DB, userID int64) (string, error) {
 	var role string
-	query := "SELECT role FROM users WHERE id = ?"
-	err := db.QueryRow(query, userID).Scan(&role)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// Use a generic error to avoid exposing user existence
-			return "", &gin.Error{
-				Err:  err,
-				Type: gin.ErrorTypePublic,
-				Meta: gin.H{"error": "Invalid user"},
-			}
-		}
-		return "", &gin.Error{
-			Err:  err,
-			Type: gin.ErrorTypePrivate,
-			Meta: gin.H{"error": "Database error checking role"},
-		}
-	}
-	return role, nil
+	// Soft-deleted users are treated as unknown (401), even with a valid token.
+	query := "SELECT role FROM users WHERE id = ? AND deleted_at IS NULL"
+	err := db.QueryRowContext(ctx, query, userID).Scan(&role)
+	return role, err
 }
 
-// ManagerMiddleware now takes the DB connection as an argument
-// and *returns* the gin.HandlerFunc.
-func ManagerMiddleware(db *sql.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 1. Get userID from AuthMiddleware
-		userID_raw, exists := c.Get("userID")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context (AuthMiddleware must run first)"})
-			c.Abort()
-			return
-		}
-		userID := userID_raw.(int64)
-
-		// 2. Query DB for user's role
-		role, err := queryUserRole(db, userID)
-		if err != nil {
-			gErr := err.(*gin.Error)
-			c.JSON(http.StatusInternalServerError, gErr.Meta)
-			c.Abort()
-			return
-		}
+// abortRoleError responds for a failed queryUserRole.
+func abortRoleError(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		// Use a generic error to avoid exposing user existence
+		apierror.Unauthorized(c, "Invalid user")
+		return
+	}
+	apierror.Internal(c, "Database error checking role")
+}
 
-		// 3. Check permission
-		if role != "manager" && role != "administrator" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Manager or Admin role required"})
-			c.Abort()
-			return
-		}
+// RequireRole lets the request through only when the user's role is one of roles.
+// It reuses the role LoadRole already put in the context, if any.
+func RequireRole(db *sql.DB, roles ...string) gin.HandlerFunc {
+	return requireRole(db, "Access denied: "+strings.Join(roles, " or ")+" role required", roles...)
+}
 
-		// 4. Success! Add role to context and proceed.
-		c.Set("userRole", role)
-		c.Next()
-	}
+// LoadRole puts the user's role into the context ("userRole") without
+// restricting it, for routes every role may use. Deleted users are rejected.
+func LoadRole(db *sql.DB) gin.HandlerFunc {
+	return requireRole(db, "")
 }
 
-// SuperAdminMiddleware also takes the DB connection as an argument.
-func SuperAdminMiddleware(db *sql.DB) gin.HandlerFunc {
+// requireRole is RequireRole with a custom denial message; no roles allows any role.
+func requireRole(db *sql.DB, denied string, roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1. Get userID from AuthMiddleware
-		userID_raw, exists := c.Get("userID")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context (AuthMiddleware must run first)"})
-			c.Abort()
-			return
-		}
-		userID := userID_raw.(int64)
-
-		// 2. Query DB for user's role
-		role, err := queryUserRole(db, userID)
-		if err != nil {
-			gErr := err.(*gin.Error)
-			c.JSON(http.StatusInternalServerError, gErr.Meta)
-			c.Abort()
-			return
+		// 1. Reuse a role loaded earlier in the chain
+		role := c.GetString("userRole")
+		if role == "" {
+			// 2. Get userID from AuthMiddleware
+			userID_raw, exists := c.Get("userID")
+			if !exists {
+				apierror.Unauthorized(c, "User ID not found in context (AuthMiddleware must run first)")
+				return
+			}
+			userID := userID_raw.(int64)
+
+			// 3. Query DB for user's role
+			var err error
+			role, err = queryUserRole(c.Request.Context(), db, userID)
+			if err != nil {
+				abortRoleError(c, err)
+				return
+			}
 		}
 
-		// 3. Check permission
-		if role != "administrator" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Super Admin role required"})
-			c.Abort()
+		// 4. Check permission
+		if len(roles) > 0 && !slices.Contains(roles, role) {
+			apierror.Forbidden(c, denied)
 			return
 		}
 
-		// 4. Success! Add role to context and proceed.
+		// 5. Success! Add role to context and proceed.
 		c.Set("userRole", role)
 		c.Next()
 	}
 }
 
-// DropshipperMiddleware takes the DB connection as an argument.
-// It returns a middleware that checks for 'dropshipper' role ONLY.
-func DropshipperMiddleware(db *sql.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 1. Get userID from AuthMiddleware
-		userID_raw, exists := c.Get("userID")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context (AuthMiddleware must run first)"})
-			c.Abort()
-			return
-		}
-		userID := userID_raw.(int64)
+// ManagerMiddleware allows managers and administrators.
+func ManagerMiddleware(db *sql.DB) gin.HandlerFunc {
+	return requireRole(db, "Access denied: Manager or Admin role required", "manager", "administrator")
+}
 
-		// 2. Query DB for user's role
-		role, err := queryUserRole(db, userID)
-		if err != nil {
-			gErr := err.(*gin.Error)
-			c.JSON(http.StatusInternalServerError, gErr.Meta)
-			c.Abort()
-			return
-		}
+// SuperAdminMiddleware allows administrators only.
+func SuperAdminMiddleware(db *sql.DB) gin.HandlerFunc {
+	return requireRole(db, "Access denied: Super Admin role required", "administrator")
+}
 
-		// 3. Check permission
-		if role != "dropshipper" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Dropshipper role required"})
-			c.Abort()
-			return
-		}
+// DropshipperMiddleware allows dropshippers only.
+func DropshipperMiddleware(db *sql.DB) gin.HandlerFunc {
+	return requireRole(db, "Access denied: Dropshipper role required", "dropshipper")
+}
 
-		// 4. Success! Add role to context and proceed.
-		c.Set("userRole", role)
-		c.Next()
-	}
+// SupplierMiddleware allows suppliers only.
+func SupplierMiddleware(db *sql.DB) gin.HandlerFunc {
+	return requireRole(db, "Access denied: Supplier role required", "supplier")
 }
@@ -2,69 +2,128 @@ package middleware
 
 import (
 	"database/sql"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
 	"github.com/01moynul/taptosell-golang/internal/auth"
+	"github.com/01moynul/taptosell-golang/internal/settings"
+	"github.com/01moynul/taptosell-golang/internal/status"
 	"github.com/gin-gonic/gin"
 )
 
 // AuthMiddleware creates a gin.HandlerFunc that acts as our "security guard".
-// UPDATED: It now accepts 'db' to check for Maintenance Mode.
-func AuthMiddleware(db *sql.DB) gin.HandlerFunc {
+// UPDATED: It now accepts 'db' and the settings store to check for Maintenance Mode,
+// and the status store for scheduled maintenance windows, which pause writes only.
+// The user's role and status come from the token's claims. The user's row,
+// read through users (cached for AUTH_USER_CACHE_TTL, or on every request with
+// AUTH_REVOCATION_CHECK), only revokes: deleted and suspended users, revoked
+// tokens and tokens whose role the row no longer has are refused within that
+// time rather than when the token expires. Tokens from before the claims take
+// the role and status from the row.
+func AuthMiddleware(db *sql.DB, settingsStore *settings.Store, windows *status.Store, users *UserStates) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1. --- CHECK MAINTENANCE MODE ---
-		var maintenanceMode string
-		// We check the settings table. We ignore errors (defaults to empty string)
+		// We check the (cached) settings table. We ignore errors (defaults to empty string)
 		// if the setting hasn't been created yet.
-		_ = db.QueryRow("SELECT setting_value FROM settings WHERE setting_key = 'maintenance_mode'").Scan(&maintenanceMode)
+		maintenanceMode, _ := settingsStore.Get(c.Request.Context(), "maintenance_mode")
 
 		// 2. --- Get Authorization Header ---
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			apierror.Unauthorized(c, "Authorization header required")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format (must be Bearer)"})
-			c.Abort()
+			apierror.Unauthorized(c, "Invalid token format (must be Bearer)")
 			return
 		}
 		tokenString := parts[1]
 
 		// 3. --- Validate Token ---
-		userID, err := auth.ValidateToken(tokenString)
+		claims, err := auth.ValidateToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			apierror.Unauthorized(c, "Invalid or expired token")
 			return
 		}
+		userID := claims.UserID
 
-		// 4. --- ENFORCE MAINTENANCE MODE ---
+		// 4. --- Resolve Role & Status, Then Check for Revocation ---
+		role, userStatus := claims.Role, claims.Status
+		if userStatus == "suspended" {
+			apierror.Forbidden(c, "Account suspended.")
+			return
+		}
+		st, err := users.get(c.Request.Context(), userID)
+		if err != nil {
+			abortRoleError(c, err)
+			return
+		}
+		if st.gone {
+			abortRoleError(c, sql.ErrNoRows)
+			return
+		}
+		// iat has whole seconds only.
+		if !st.validAfter.IsZero() && claims.IssuedAt.Before(st.validAfter.Truncate(time.Second)) {
+			apierror.Unauthorized(c, "Session has ended. Please log in again.")
+			return
+		}
+		if role == "" {
+			role, userStatus = st.role, st.status
+		}
+		if role != st.role {
+			apierror.Unauthorized(c, "Session has ended. Please log in again.")
+			return
+		}
+		if st.status == "suspended" {
+			apierror.Forbidden(c, "Account suspended.")
+			return
+		}
+
+		// 5. --- ENFORCE MAINTENANCE MODE ---
 		// If maintenance is ON ("true"), only Administrators can pass.
 		if maintenanceMode == "true" {
-			var role string
-			err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
-			if err != nil {
-				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service unavailable (maintenance check failed)"})
-				c.Abort()
+			if role != "administrator" {
+				apierror.ServiceUnavailable(c, "⛔ The system is currently in Maintenance Mode. Please try again later.")
 				return
 			}
+		}
 
-			if role != "administrator" {
-				c.JSON(http.StatusServiceUnavailable, gin.H{
-					"error": "⛔ The system is currently in Maintenance Mode. Please try again later.",
-				})
-				c.Abort()
-				return
+		// 6. --- ENFORCE SCHEDULED MAINTENANCE WINDOWS ---
+		// While a window is active, reads keep working but writes are refused,
+		// except for staff, who run the maintenance and may end the window early.
+		// Errors are ignored like the maintenance_mode lookup above.
+		if isWrite(c.Request.Method) {
+			window, _ := windows.Active(c.Request.Context(), time.Now())
+			if window != nil {
+				if role != "administrator" && role != "manager" {
+					c.Header("Retry-After", strconv.Itoa(int(time.Until(window.EndsAt).Seconds())+1))
+					apierror.ServiceUnavailable(c, fmt.Sprintf("Changes are paused for scheduled maintenance until %s. Please try again later.", window.EndsAt.UTC().Format(time.RFC3339)))
+					return
+				}
 			}
 		}
 
-		// 5. --- Success ---
+		// 7. --- Success ---
+		// The role middlewares read "userRole" instead of querying the DB.
+		// userStatus is the status at login, like the role.
 		c.Set("userID", userID)
+		c.Set("userRole", role)
+		c.Set("userStatus", userStatus)
 		c.Next()
 	}
 }
+
+// isWrite reports whether method changes state.
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
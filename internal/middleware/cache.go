@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etagWriter holds the response body back until the ETag is known.
+type etagWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// PublicCache makes a public GET route cacheable by browsers and CDNs.
+// A 200 response gets a weak ETag (a hash of its body; weak since Compress
+// re-encodes it) and "Cache-Control: public, max-age, stale-while-revalidate",
+// so a CDN serves it for maxAge, then serves it stale for up to
+// staleWhileRevalidate more while it revalidates in the background. A request
+// whose If-None-Match matches gets 304 with no body. Anything else (errors,
+// rate limits) is marked no-store, so a CDN never keeps it.
+//
+// Only use it on routes whose response does not depend on the caller.
+func PublicCache(maxAge, staleWhileRevalidate time.Duration) gin.HandlerFunc {
+	cacheControl := fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d",
+		int(maxAge.Seconds()), int(staleWhileRevalidate.Seconds()))
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		w := &etagWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		h := w.Header()
+		if w.Status() != http.StatusOK {
+			h.Set("Cache-Control", "no-store")
+			_, _ = w.ResponseWriter.Write(w.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(w.body.Bytes())
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		h.Set("ETag", etag)
+		h.Set("Cache-Control", cacheControl)
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			h.Del("Content-Type")
+			h.Del("Content-Length")
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag (or is "*").
+// The comparison is weak, as RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
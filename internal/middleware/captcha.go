@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/captcha"
+	"github.com/01moynul/taptosell-golang/internal/logging"
+	"github.com/01moynul/taptosell-golang/internal/settings"
+	"github.com/gin-gonic/gin"
+)
+
+// CaptchaHeader carries the Turnstile/hCaptcha token from the widget.
+const CaptchaHeader = "X-Captcha-Token"
+
+// SettingCaptchaEnabled turns the check on ("true") without a redeploy.
+const SettingCaptchaEnabled = "captcha_enabled"
+
+// Captcha requires a valid CAPTCHA token on public endpoints that bots abuse
+// (registration, login, resend-code) while the 'captcha_enabled' setting is
+// "true". Without a configured provider (verifier is nil) the setting has no
+// effect, so turning it on cannot lock everyone out.
+func Captcha(settingsStore *settings.Store, verifier *captcha.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		// 1. --- Enabled? (cached; a missing setting means off) ---
+		enabled, _ := settingsStore.Get(ctx, SettingCaptchaEnabled)
+		if enabled != "true" {
+			c.Next()
+			return
+		}
+		if verifier == nil {
+			logging.Warnf("[Captcha] %s is on but CAPTCHA_PROVIDER is not set; skipping the check", SettingCaptchaEnabled)
+			c.Next()
+			return
+		}
+
+		// 2. --- Verify the Token ---
+		err := verifier.Verify(ctx, c.GetHeader(CaptchaHeader), c.ClientIP())
+		switch {
+		case err == nil:
+			c.Next()
+		case errors.Is(err, captcha.ErrFailed):
+			apierror.Forbidden(c, "CAPTCHA verification failed. Please complete the challenge and try again.")
+		default:
+			logging.Errorf("[Captcha] %v", err)
+			apierror.ServiceUnavailable(c, "CAPTCHA verification is unavailable. Please try again shortly.")
+		}
+	}
+}
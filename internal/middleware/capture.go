@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/audit"
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Capture records the raw request (body, signature, headers without
+// credentials) and our response of every call to the route; kind is
+// audit.KindWebhook or audit.KindPayment. Register it on the route itself,
+// after AuthMiddleware so the user is known. Responses are captured before
+// Compress encodes them.
+func Capture(recorder *audit.Recorder, kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 1. Read the body (up to the cap) and hand an identical one to the handler.
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, audit.MaxBodyBytes))
+		if err != nil {
+			apierror.BadRequest(c, "Could not read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+
+		// 2. Tee the response.
+		tee := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = tee
+
+		c.Next()
+
+		capture := models.RequestCapture{
+			Kind:           kind,
+			RequestID:      c.GetString(apierror.RequestIDKey),
+			Method:         c.Request.Method,
+			Route:          c.FullPath(),
+			Path:           c.Request.URL.RequestURI(),
+			RequestHeaders: audit.Headers(c.Request.Header),
+			RequestBody:    string(body),
+			Signature:      audit.Signature(c.Request.Header),
+			ResponseStatus: c.Writer.Status(),
+			ResponseBody:   tee.body.String(),
+		}
+		if userID, ok := c.Get("userID"); ok {
+			if id, err := strconv.ParseInt(fmt.Sprint(userID), 10, 64); err == nil {
+				capture.UserID = &id
+			}
+		}
+		if replayOf, ok := audit.ReplayOf(c.Request.Context()); ok {
+			capture.ReplayOf = &replayOf
+		}
+		recorder.Record(c.Request.Context(), capture)
+	}
+}
+
+// captureWriter keeps a copy of the first audit.MaxBodyBytes written.
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.keep(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.keep([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *captureWriter) keep(p []byte) {
+	if room := audit.MaxBodyBytes - w.body.Len(); room > 0 {
+		if len(p) > room {
+			p = p[:room]
+		}
+		w.body.Write(p)
+	}
+}
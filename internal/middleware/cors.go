@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS allows browser calls from origins (see config.HTTP.CORSOrigins).
+// Credentials are allowed, so the matching origin is echoed back instead of
+// "*", with Vary: Origin for caches. A request from any other origin gets no
+// CORS headers, and its preflight is refused with 403.
+func CORS(origins []string) gin.HandlerFunc {
+	exact := make(map[string]bool)
+	var suffixes []string // "https://*.example.com" -> scheme "https://", suffix ".example.com"
+	var schemes []string
+	for _, o := range origins {
+		if scheme, rest, ok := strings.Cut(o, "://*."); ok {
+			schemes = append(schemes, scheme+"://")
+			suffixes = append(suffixes, "."+rest)
+			continue
+		}
+		exact[o] = true
+	}
+
+	allowed := func(origin string) bool {
+		origin = strings.ToLower(origin)
+		if exact[origin] {
+			return true
+		}
+		for i, suffix := range suffixes {
+			host, ok := strings.CutPrefix(origin, schemes[i])
+			// The wildcard covers at least one label: *.example.com is not example.com.
+			if ok && strings.HasSuffix(host, suffix) && len(host) > len(suffix) && !strings.ContainsAny(host, "/@") {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Origin")
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if origin == "" {
+			// Not a browser cross-origin call (curl, server-to-server).
+			c.Next()
+			return
+		}
+		if !allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+		h.Set("Access-Control-Expose-Headers", RequestIDHeader+", API-Version, Deprecation, Sunset, Link")
+
+		if preflight {
+			h.Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, "+CaptchaHeader)
+			h.Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+			h.Set("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/01moynul/taptosell-golang/internal/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// Locale picks the response language from Accept-Language (English when
+// nothing supported is asked for) and stores it for apierror and the
+// handlers. The choice is echoed in Content-Language.
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		c.Set(i18n.ContextKey, lang)
+		c.Header("Content-Language", lang)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
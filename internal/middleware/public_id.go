@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+// PublicID resolves the route param (e.g. "id"), a public UUID or a numeric ID
+// while allowNumeric is set, to the row's primary key and rewrites the param,
+// so the handlers behind it keep parsing an integer.
+func PublicID(db *sql.DB, table, param string, allowNumeric bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := store.ResolveID(c.Request.Context(), db, table, c.Param(param), allowNumeric)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				apierror.NotFound(c, "Resource not found")
+			case errors.Is(err, store.ErrNumericID):
+				apierror.BadRequest(c, "Numeric IDs are no longer supported; use the publicId")
+			default:
+				apierror.Internal(c, "Failed to resolve ID")
+			}
+			return
+		}
+
+		for i := range c.Params {
+			if c.Params[i].Key == param {
+				c.Params[i].Value = strconv.FormatInt(id, 10)
+			}
+		}
+		c.Next()
+	}
+}
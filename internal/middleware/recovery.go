@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/errreport"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Recovery replaces gin.Recovery: a panic becomes a 500 in the standard error
+// envelope and is reported with its stack and request context. Responses with
+// status 5xx are reported too, except 503, which the API only sends on purpose
+// (maintenance mode). Register it right after RequestID and Tracing.
+func Recovery(reporter errreport.Reporter) gin.HandlerFunc {
+	if reporter == nil {
+		reporter = errreport.Log{}
+	}
+	return func(c *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// net/http's own sentinel for "abort silently"; let the server handle it.
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			err, ok := rec.(error)
+			if !ok {
+				err = fmt.Errorf("%v", rec)
+			}
+			// The client went away mid-response: nothing to fix, nothing to answer.
+			if isBrokenPipe(err) {
+				c.Abort()
+				return
+			}
+
+			r := newReport(c, err, http.StatusInternalServerError)
+			r.Panic = true
+			r.Stack = debug.Stack()
+			reporter.Report(c.Request.Context(), r)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			apierror.Internal(c, "Internal server error")
+		}()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < http.StatusInternalServerError || status == http.StatusServiceUnavailable {
+			return
+		}
+		err := error(c.Errors.Last())
+		if err == nil {
+			err = errors.New(http.StatusText(status))
+		}
+		reporter.Report(c.Request.Context(), newReport(c, err, status))
+	}
+}
+
+func newReport(c *gin.Context, err error, status int) errreport.Report {
+	r := errreport.Report{
+		Err:       err,
+		Status:    status,
+		Method:    c.Request.Method,
+		Route:     c.FullPath(),
+		Path:      c.Request.URL.Path,
+		RequestID: c.GetString(apierror.RequestIDKey),
+	}
+	if userID, ok := c.Get("userID"); ok {
+		r.UserID = fmt.Sprint(userID)
+	}
+	if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+		r.TraceID = sc.TraceID().String()
+	}
+	return r
+}
+
+// isBrokenPipe reports a write to a connection the client already closed.
+func isBrokenPipe(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var sysErr *os.SyscallError
+	if !errors.As(opErr, &sysErr) {
+		return false
+	}
+	msg := strings.ToLower(sysErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// baseContextKey stores the original request context so that a route-level
+// Timeout can replace (rather than be capped by) a group-level Timeout.
+const baseContextKey = "baseRequestContext"
+
+// Timeout attaches a deadline to the request context.
+// Handlers pass c.Request.Context() to every query, so when the deadline passes
+// (or the client disconnects) in-flight queries are cancelled and any open
+// transaction is rolled back by database/sql.
+//
+// Timeouts can be stacked: the innermost one wins, which lets a slow route
+// (e.g., AI chat) opt into a longer budget than its group default.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 1. Derive from the original request context, not a previous Timeout.
+		parent := c.Request.Context()
+		if base, exists := c.Get(baseContextKey); exists {
+			parent = base.(context.Context)
+		} else {
+			c.Set(baseContextKey, parent)
+		}
+
+		ctx, cancel := context.WithTimeout(parent, d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		// 2. If the handler gave up because of the deadline and wrote nothing,
+		// tell the client instead of returning an empty 200.
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			apierror.Timeout(c, "Request timed out")
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxCachedUsers bounds the UserStates cache; past it the cache starts over.
+const maxCachedUsers = 50000
+
+// UserStates reads what AuthMiddleware checks a token against: the user's
+// role and status now and when their tokens were revoked. Each read is kept
+// for TTL, so a deletion, suspension or revocation reaches every request
+// within TTL without a query per request; a TTL of 0 reads the row every
+// time. One UserStates serves every API version.
+type UserStates struct {
+	db  *sql.DB
+	ttl time.Duration
+
+	mu     sync.Mutex
+	cached map[int64]userState
+}
+
+// userState is one read of a user's row. Gone is a deleted (or unknown) user.
+type userState struct {
+	role, status string
+	validAfter   time.Time // zero when no token was revoked
+	gone         bool
+	readAt       time.Time
+}
+
+// NewUserStates returns the user reader for AuthMiddleware.
+func NewUserStates(db *sql.DB, ttl time.Duration) *UserStates {
+	return &UserStates{db: db, ttl: ttl, cached: map[int64]userState{}}
+}
+
+// get returns the user's state, from the cache while it is fresh.
+func (s *UserStates) get(ctx context.Context, userID int64) (userState, error) {
+	now := time.Now()
+	if s.ttl > 0 {
+		s.mu.Lock()
+		st, ok := s.cached[userID]
+		s.mu.Unlock()
+		if ok && now.Sub(st.readAt) < s.ttl {
+			return st, nil
+		}
+	}
+
+	st := userState{readAt: now}
+	var validAfter sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		"SELECT role, status, tokens_valid_after FROM users WHERE id = ? AND deleted_at IS NULL", userID).
+		Scan(&st.role, &st.status, &validAfter)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		st.gone = true
+	case err != nil:
+		return userState{}, err
+	}
+	if validAfter.Valid {
+		st.validAfter = validAfter.Time
+	}
+
+	if s.ttl > 0 {
+		s.mu.Lock()
+		if len(s.cached) >= maxCachedUsers {
+			s.cached = map[int64]userState{}
+		}
+		s.cached[userID] = st
+		s.mu.Unlock()
+	}
+	return st, nil
+}
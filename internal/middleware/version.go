@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersion records the version of /vN requests (apiversion.From) and
+// echoes it in the API-Version response header. It runs on the router, ahead
+// of CORS and the other guards, so their errors use the version's envelope too.
+func APIVersion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if v, ok := apiversion.FromPath(c.Request.URL.Path); ok {
+			c.Set(apiversion.ContextKey, v)
+			c.Header("API-Version", v.String())
+		}
+		c.Next()
+	}
+}
+
+// Deprecated announces that the routes it guards are going away: the
+// Deprecation header (RFC 9745) carries since, when the routes were
+// deprecated; Sunset (RFC 8594) the date they stop working, when known; and
+// Link the version to move to. A zero since sends nothing, so the middleware
+// can be mounted before a deprecation is scheduled.
+func Deprecated(since, sunset time.Time, successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !since.IsZero() {
+			h := c.Writer.Header()
+			h.Set("Deprecation", "@"+strconv.FormatInt(since.Unix(), 10))
+			if !sunset.IsZero() {
+				h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			if successor != "" {
+				h.Add("Link", "<"+successor+`>; rel="successor-version"`)
+			}
+		}
+		c.Next()
+	}
+}
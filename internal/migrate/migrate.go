@@ -0,0 +1,392 @@
+// Package migrate applies the versioned SQL migrations embedded in the binary
+// and detects drift between the binary and the database schema.
+//
+// Applied versions are recorded in 'schema_migrations' together with a checksum
+// of the up file, so an edited migration is reported instead of silently ignored.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// lockName serialises migration runs across instances (MySQL GET_LOCK).
+const lockName = "taptosell_schema_migrations"
+
+const createTableSQL = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		dirty BOOLEAN NOT NULL DEFAULT FALSE,
+		applied_at DATETIME NOT NULL
+	)`
+
+// ErrDrift is wrapped by Check when the database does not match the binary.
+var ErrDrift = errors.New("schema drift")
+
+// Migration is one versioned pair of up/down scripts.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // sha256 of Up
+
+	// Irreversible is why the migration cannot be reverted, from a down file
+	// that starts with "-- irreversible: <reason>"; Down refuses it.
+	Irreversible string
+}
+
+// irreversiblePrefix starts a down file that only explains why there is no way back.
+const irreversiblePrefix = "-- irreversible:"
+
+// Applied is a row of 'schema_migrations'.
+type Applied struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads every NNNN_name.(up|down).sql file in fsys, sorted by version.
+// Every version needs an up file; down files are optional, and one that only
+// says "-- irreversible: <reason>" makes Down refuse the version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migrate: unexpected file name %q", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrate: version %d has two names (%s, %s)", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+			sum := sha256.Sum256(body)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(body)
+			if first, _, _ := strings.Cut(strings.TrimSpace(mig.Down), "\n"); strings.HasPrefix(first, irreversiblePrefix) {
+				mig.Irreversible = strings.TrimSpace(strings.TrimPrefix(first, irreversiblePrefix))
+			}
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migrate: version %d has no up file", mig.Version)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator runs migrations against one database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New loads the migrations from fsys.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Migrations returns the migrations known to the binary, in order.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Applied returns the recorded versions, in order. A database without the
+// 'schema_migrations' table has no applied versions.
+func (m *Migrator) Applied(ctx context.Context) ([]Applied, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT version, name, checksum, dirty, applied_at FROM schema_migrations ORDER BY version ASC")
+	if err != nil {
+		if isMissingTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []Applied
+	for rows.Next() {
+		var a Applied
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.Dirty, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+// Check compares the database with the binary and returns an error wrapping
+// ErrDrift when they differ: pending migrations, versions unknown to this
+// binary, edited migrations, or a migration that failed half-way (dirty).
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+	done := make(map[int64]bool, len(applied))
+
+	var problems []string
+	for _, a := range applied {
+		done[a.Version] = true
+		mig, ok := known[a.Version]
+		switch {
+		case a.Dirty:
+			problems = append(problems, fmt.Sprintf("version %d (%s) is dirty: it failed part-way and must be fixed by hand, then `migrate force %d`", a.Version, a.Name, a.Version))
+		case !ok:
+			problems = append(problems, fmt.Sprintf("version %d (%s) is applied but unknown to this binary (database is newer)", a.Version, a.Name))
+		case mig.Checksum != a.Checksum:
+			problems = append(problems, fmt.Sprintf("version %d (%s) was edited after it was applied (checksum mismatch)", a.Version, a.Name))
+		}
+	}
+	for _, mig := range m.migrations {
+		if !done[mig.Version] {
+			problems = append(problems, fmt.Sprintf("version %d (%s) is pending", mig.Version, mig.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w:\n  - %s", ErrDrift, strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// Up applies every pending migration in order and returns the versions applied.
+func (m *Migrator) Up(ctx context.Context) ([]int64, error) {
+	var ran []int64
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedSet(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if a, ok := applied[mig.Version]; ok {
+				if a.Dirty {
+					return fmt.Errorf("migrate: version %d is dirty; fix it by hand, then run `migrate force %d`", a.Version, a.Version)
+				}
+				continue
+			}
+			if err := m.apply(ctx, conn, mig); err != nil {
+				return err
+			}
+			ran = append(ran, mig.Version)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down reverts the last n applied migrations (newest first) and returns the versions reverted.
+func (m *Migrator) Down(ctx context.Context, n int) ([]int64, error) {
+	var reverted []int64
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		known := make(map[int64]Migration, len(m.migrations))
+		for _, mig := range m.migrations {
+			known[mig.Version] = mig
+		}
+
+		for i := len(applied) - 1; i >= 0 && len(reverted) < n; i-- {
+			a := applied[i]
+			mig, ok := known[a.Version]
+			if !ok {
+				return fmt.Errorf("migrate: version %d is unknown to this binary; cannot revert it", a.Version)
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migrate: version %d has no down file", a.Version)
+			}
+			if mig.Irreversible != "" {
+				return fmt.Errorf("migrate: version %d (%s) cannot be reverted: %s", a.Version, mig.Name, mig.Irreversible)
+			}
+			if err := m.markDirty(ctx, conn, mig); err != nil {
+				return err
+			}
+			if err := execScript(ctx, conn, mig.Down); err != nil {
+				return fmt.Errorf("migrate: reverting %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", mig.Version); err != nil {
+				return err
+			}
+			reverted = append(reverted, mig.Version)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force records every known migration up to and including version as applied
+// (and clean) without running it, and forgets any later ones. Use it to adopt
+// a database whose schema was changed by hand, or to clear a dirty version
+// after repairing it. Version 0 keeps only the baseline.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version > ?", version); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+			_, err := conn.ExecContext(ctx, `
+				INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at)
+				VALUES (?, ?, ?, FALSE, ?)
+				ON DUPLICATE KEY UPDATE name = VALUES(name), checksum = VALUES(checksum), dirty = FALSE`,
+				mig.Version, mig.Name, mig.Checksum, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// apply runs one up script. MySQL DDL commits implicitly, so the version is
+// recorded as dirty first and only marked clean once every statement succeeded.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	if err := m.markDirty(ctx, conn, mig); err != nil {
+		return err
+	}
+	if err := execScript(ctx, conn, mig.Up); err != nil {
+		return fmt.Errorf("migrate: applying %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	_, err := conn.ExecContext(ctx, "UPDATE schema_migrations SET dirty = FALSE, applied_at = ? WHERE version = ?", time.Now(), mig.Version)
+	return err
+}
+
+func (m *Migrator) markDirty(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	_, err := conn.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at)
+		VALUES (?, ?, ?, TRUE, ?)
+		ON DUPLICATE KEY UPDATE dirty = TRUE`,
+		mig.Version, mig.Name, mig.Checksum, time.Now())
+	return err
+}
+
+func (m *Migrator) appliedSet(ctx context.Context) (map[int64]Applied, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int64]Applied, len(applied))
+	for _, a := range applied {
+		set[a.Version] = a
+	}
+	return set, nil
+}
+
+// withLock runs fn on a single connection holding the migration lock,
+// creating 'schema_migrations' first if needed.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 30)", lockName).Scan(&got); err != nil {
+		return err
+	}
+	if got.Int64 != 1 {
+		return errors.New("migrate: another migration is running (lock timeout)")
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+
+	if _, err := conn.ExecContext(ctx, createTableSQL); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// execScript runs a migration file statement by statement (the DSN does not
+// enable multiStatements). Statements are split on a ';' that ends a line,
+// so keep one statement terminator per line and avoid procedures/triggers.
+func execScript(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%w\n--- statement ---\n%s", err, stmt)
+		}
+	}
+	return nil
+}
+
+func splitStatements(script string) []string {
+	var stmts []string
+	var cur strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if cur.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue // leading blank lines and comments
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(cur.String()), ";"))
+			cur.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(cur.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
+
+// isMissingTable reports MySQL error 1146 (table doesn't exist).
+func isMissingTable(err error) bool {
+	var myErr *mysql.MySQLError
+	return errors.As(err, &myErr) && myErr.Number == 1146
+}
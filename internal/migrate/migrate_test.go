@@ -0,0 +1,54 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/01moynul/taptosell-golang/migrations"
+)
+
+func TestLoadIrreversible(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("CREATE TABLE a (id INT);\n")},
+		"0001_a.down.sql": {Data: []byte("DROP TABLE a;\n")},
+		"0002_b.up.sql":   {Data: []byte("ALTER TABLE a MODIFY id BIGINT;\n")},
+		"0002_b.down.sql": {Data: []byte("-- irreversible: ids past INT would not fit\n")},
+	}
+	migs, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migs[0].Irreversible != "" {
+		t.Fatalf("0001 irreversible = %q, want none", migs[0].Irreversible)
+	}
+	if got := migs[1].Irreversible; got != "ids past INT would not fit" {
+		t.Fatalf("0002 irreversible = %q", got)
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migs, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migs[0].Version != 0 || migs[0].Name != "baseline" {
+		t.Fatalf("first migration is %d_%s, want 0000_baseline", migs[0].Version, migs[0].Name)
+	}
+	for _, mig := range migs {
+		if mig.Down == "" {
+			t.Errorf("%04d_%s has no down file", mig.Version, mig.Name)
+		}
+		for _, stmt := range splitStatements(mig.Up) {
+			if stmt == "" {
+				t.Errorf("%04d_%s has an empty statement", mig.Version, mig.Name)
+			}
+		}
+	}
+	for _, v := range []int64{0, 11, 27} {
+		for _, mig := range migs {
+			if mig.Version == v && mig.Irreversible == "" {
+				t.Errorf("%04d_%s should refuse to revert", mig.Version, mig.Name)
+			}
+		}
+	}
+}
@@ -9,3 +9,20 @@ type AiUserCredit struct {
 	CreditsRemaining float64   `json:"creditsRemaining" db:"credits_remaining"`
 	UpdatedAt        time.Time `json:"updatedAt" db:"updated_at"`
 }
+
+// AIModerationEvent is a chat message refused, or a reply withheld, by the AI
+// moderation (see package moderation).
+type AIModerationEvent struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    int64     `json:"userId" db:"user_id"`
+	Direction string    `json:"direction" db:"direction"` // input, output
+	Category  string    `json:"category" db:"category"`
+	Rule      string    `json:"rule" db:"rule"`
+	Excerpt   string    `json:"excerpt" db:"excerpt"` // the start of the message or reply
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+
+	// Not in the table; joined from 'users' for the manager list.
+	UserName         string     `json:"userName" db:"-"`
+	UserEmail        string     `json:"userEmail" db:"-"`
+	AISuspendedUntil *time.Time `json:"aiSuspendedUntil,omitempty" db:"-"`
+}
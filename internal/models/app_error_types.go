@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AppError is the model for the 'app_errors' table (see errreport.DB)
+type AppError struct {
+	ID        int64     `json:"id" db:"id"`
+	RequestID string    `json:"requestId" db:"request_id"`
+	TraceID   string    `json:"traceId,omitempty" db:"trace_id"`
+	UserID    *int64    `json:"userId" db:"user_id"`
+	Method    string    `json:"method" db:"method"`
+	Route     string    `json:"route" db:"route"`
+	Path      string    `json:"path" db:"path"`
+	Status    int       `json:"status" db:"status"`
+	IsPanic   bool      `json:"isPanic" db:"is_panic"`
+	Message   string    `json:"message" db:"message"`
+	Stack     *string   `json:"stack,omitempty" db:"stack"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Backup is the model for the 'backups' table (one row per backup run)
+type Backup struct {
+	ID         int64      `json:"id" db:"id"`
+	Status     string     `json:"status" db:"status"` // running, succeeded, failed
+	Target     string     `json:"target" db:"target"`
+	ObjectName string     `json:"objectName" db:"object_name"`
+	SizeBytes  *int64     `json:"sizeBytes" db:"size_bytes"`
+	Error      *string    `json:"error,omitempty" db:"error"`
+	StartedAt  time.Time  `json:"startedAt" db:"started_at"`
+	FinishedAt *time.Time `json:"finishedAt" db:"finished_at"`
+}
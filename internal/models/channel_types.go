@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Channel is the model for the 'channels' table.
+// A channel is an external storefront (e.g., Shopee, Lazada) linked by a dropshipper.
+type Channel struct {
+	ID           int64      `json:"id" db:"id"`
+	UserID       int64      `json:"userId" db:"user_id"`
+	Platform     string     `json:"platform" db:"platform"` // e.g., shopee, lazada, tiktok
+	ShopName     string     `json:"shopName" db:"shop_name"`
+	Status       string     `json:"status" db:"status"` // active, disconnected
+	LastSyncedAt *time.Time `json:"lastSyncedAt" db:"last_synced_at"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// ChannelListing is the model for the 'channel_listings' table.
+// It tracks the sync state of one TapToSell product pushed to one channel.
+type ChannelListing struct {
+	ID            int64      `json:"id" db:"id"`
+	ChannelID     int64      `json:"channelId" db:"channel_id"`
+	ProductID     int64      `json:"productId" db:"product_id"`
+	ExternalID    *string    `json:"externalId,omitempty" db:"external_id"`
+	SyncStatus    string     `json:"syncStatus" db:"sync_status"` // pending, synced, failed
+	LastError     *string    `json:"lastError,omitempty" db:"last_error"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt,omitempty" db:"last_attempt_at"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updatedAt" db:"updated_at"`
+
+	// Populated by handlers for the dashboard view.
+	ProductName string `json:"productName,omitempty" db:"-"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Customer is the model for the 'customers' table: an end-customer a
+// dropshipper sells to.
+type Customer struct {
+	ID           int64     `json:"id" db:"id"`
+	UserID       int64     `json:"userId" db:"user_id"` // The Dropshipper
+	Name         string    `json:"name" db:"name"`
+	Phone        string    `json:"phone" db:"phone"` // digits only
+	AddressLine1 string    `json:"addressLine1" db:"address_line1"`
+	AddressLine2 string    `json:"addressLine2" db:"address_line2"`
+	City         string    `json:"city" db:"city"`
+	State        string    `json:"state" db:"state"`
+	Postcode     string    `json:"postcode" db:"postcode"`
+	Notes        string    `json:"notes" db:"notes"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
+
+	// Order stats, populated on listings.
+	OrderCount  int          `json:"orderCount" db:"-"`
+	LastOrderAt sql.NullTime `json:"lastOrderAt" db:"-"`
+}
+
+// ShipTo returns the customer's details as an order's shipping address.
+func (c Customer) ShipTo() *ShipTo {
+	return &ShipTo{
+		Name:         c.Name,
+		Phone:        c.Phone,
+		AddressLine1: c.AddressLine1,
+		AddressLine2: c.AddressLine2,
+		City:         c.City,
+		State:        c.State,
+		Postcode:     c.Postcode,
+	}
+}
+
+// ShipTo is the shipping address copied onto an order (orders.ship_to).
+type ShipTo struct {
+	Name         string `json:"name"`
+	Phone        string `json:"phone"`
+	AddressLine1 string `json:"addressLine1"`
+	AddressLine2 string `json:"addressLine2,omitempty"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+	Postcode     string `json:"postcode"`
+}
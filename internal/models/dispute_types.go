@@ -0,0 +1,48 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// Dispute is the model for the 'disputes' table
+type Dispute struct {
+	ID               int64          `json:"id" db:"id"`
+	OrderID          int64          `json:"orderId" db:"order_id"`
+	DropshipperID    int64          `json:"dropshipperId" db:"dropshipper_id"`
+	SupplierID       int64          `json:"supplierId" db:"supplier_id"`
+	Reason           string         `json:"reason" db:"reason"` // non_delivery, wrong_item, damaged, other
+	Description      string         `json:"description" db:"description"`
+	Status           string         `json:"status" db:"status"` // open, under_review, resolved, withdrawn
+	SupplierResponse sql.NullString `json:"supplierResponse,omitempty" db:"supplier_response"`
+	RespondBy        time.Time      `json:"respondBy" db:"respond_by"` // supplier deadline; a full refund after it
+	RespondedAt      sql.NullTime   `json:"respondedAt,omitempty" db:"responded_at"`
+	RefundAmount     *money.Money   `json:"refundAmount,omitempty" db:"refund_amount"`     // back to the dropshipper
+	SupplierAmount   *money.Money   `json:"supplierAmount,omitempty" db:"supplier_amount"` // paid out to the supplier
+	PlatformAmount   *money.Money   `json:"platformAmount,omitempty" db:"platform_amount"` // kept by the platform
+	ResolutionNote   sql.NullString `json:"resolutionNote,omitempty" db:"resolution_note"`
+	ResolvedBy       sql.NullInt64  `json:"resolvedBy,omitempty" db:"resolved_by"` // NULL when resolved by the deadline
+	ResolvedAt       sql.NullTime   `json:"resolvedAt,omitempty" db:"resolved_at"`
+	CreatedAt        time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt        time.Time      `json:"updatedAt" db:"updated_at"`
+
+	// Not in the table; joined from 'orders' for display and links.
+	OrderPublicID string      `json:"orderPublicId" db:"-"`
+	OrderTotal    money.Money `json:"orderTotal" db:"-"`
+
+	// Populated on detail views only.
+	Evidence []DisputeEvidence `json:"evidence,omitempty" db:"-"`
+}
+
+// DisputeEvidence is the model for the 'dispute_evidence' table
+type DisputeEvidence struct {
+	ID        int64     `json:"id" db:"id"`
+	DisputeID int64     `json:"disputeId" db:"dispute_id"`
+	UserID    int64     `json:"userId" db:"user_id"`
+	FileName  string    `json:"-" db:"file_name"`
+	URL       string    `json:"url" db:"-"` // signed, short-lived link
+	Note      string    `json:"note" db:"note"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// DuplicateCluster is a group of listings the duplicate job suspects are the
+// same item, sold by different suppliers, as queued for managers. Pairs say
+// why each two were linked.
+type DuplicateCluster struct {
+	ID             int64              `json:"id"`
+	Status         string             `json:"status"` // open, merged, rejected, dismissed
+	KeptProductID  *int64             `json:"keptProductId,omitempty"`
+	ResolvedBy     *int64             `json:"resolvedBy,omitempty"`
+	ResolutionNote *string            `json:"resolutionNote,omitempty"`
+	CreatedAt      time.Time          `json:"createdAt"`
+	ResolvedAt     *time.Time         `json:"resolvedAt,omitempty"`
+	Products       []DuplicateProduct `json:"products"`
+	Pairs          []DuplicatePair    `json:"pairs"`
+}
+
+// DuplicateProduct is one listing of a cluster as it is now.
+type DuplicateProduct struct {
+	ID           int64       `json:"id"`
+	PublicID     string      `json:"publicId"`
+	Name         string      `json:"name"`
+	SupplierID   int64       `json:"supplierId"`
+	SupplierName string      `json:"supplierName"`
+	Status       string      `json:"status"`
+	Barcode      *string     `json:"barcode,omitempty"`
+	Image        string      `json:"image,omitempty"` // the first product image
+	Price        money.Money `json:"price"`
+	Deleted      bool        `json:"deleted"`
+}
+
+// DuplicatePair is two linked listings of a cluster (ProductA < ProductB).
+// Reasons are barcode, title and image; Similarity is how alike the titles
+// are, 0 to 1.
+type DuplicatePair struct {
+	ProductA   int64    `json:"productA"`
+	ProductB   int64    `json:"productB"`
+	Reasons    []string `json:"reasons"`
+	Similarity float64  `json:"similarity"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ExportJob is an asynchronous CSV export (POST /v1/exports). Status moves
+// from queued to running to done or failed; RowsDone counts up while it runs.
+type ExportJob struct {
+	ID         string       `json:"id"`
+	UserID     int64        `json:"-"`
+	Kind       string       `json:"kind"` // orders, catalog or wallet
+	Params     ExportParams `json:"params"`
+	Status     string       `json:"status"`
+	RowsDone   int          `json:"rowsDone"`
+	RowsTotal  *int         `json:"rowsTotal,omitempty"`
+	Progress   int          `json:"progress"` // percent, 100 once done
+	FileName   *string      `json:"fileName,omitempty"`
+	Error      *string      `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	FinishedAt *time.Time   `json:"finishedAt,omitempty"`
+	ExpiresAt  *time.Time   `json:"expiresAt,omitempty"`
+
+	// DownloadURL is a signed link to the file, set while a finished export is kept.
+	DownloadURL string `json:"downloadUrl,omitempty"`
+}
+
+// ExportParams are the filters an export was started with.
+type ExportParams struct {
+	From   string `json:"from,omitempty"`   // YYYY-MM-DD, inclusive
+	To     string `json:"to,omitempty"`     // YYYY-MM-DD, inclusive
+	Status string `json:"status,omitempty"` // orders only, comma-separated
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
 )
 
 // InventoryItem is the model for the 'inventory_items' table
@@ -12,9 +14,10 @@ type InventoryItem struct {
 	Name              string         `json:"name" db:"name"`
 	Description       sql.NullString `json:"description,omitempty" db:"description"`
 	SKU               sql.NullString `json:"sku,omitempty" db:"sku"`
-	Price             float64        `json:"price" db:"price"`
+	Price             money.Money    `json:"price" db:"price"`
 	Stock             int            `json:"stock" db:"stock"`
 	PromotedProductID sql.NullInt64  `json:"promotedProductId,omitempty" db:"promoted_product_id"`
 	CreatedAt         time.Time      `json:"createdAt" db:"created_at"`
 	UpdatedAt         time.Time      `json:"updatedAt" db:"updated_at"`
+	Version           int            `json:"version" db:"version"`
 }
@@ -13,4 +13,6 @@ type Notification struct {
 	Link      sql.NullString `json:"link,omitempty" db:"link"`
 	IsRead    bool           `json:"isRead" db:"is_read"`
 	CreatedAt time.Time      `json:"createdAt" db:"created_at"`
+	Kind      *string        `json:"kind,omitempty" db:"kind"`        // set on batchable notifications, e.g. product_approved
+	Count     int            `json:"count,omitempty" db:"item_count"` // on a summary: the notifications it groups (see /notifications/:id/items)
 }
@@ -3,25 +3,124 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
 )
 
 // Order is the model for the 'orders' table
 type Order struct {
-	ID        int64          `json:"id" db:"id"`
-	UserID    int64          `json:"userId" db:"user_id"` // The Dropshipper
-	Status    string         `json:"status" db:"status"`  // e.g., processing, on-hold, shipped
-	Total     float64        `json:"total" db:"total"`
-	CreatedAt time.Time      `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time      `json:"updatedAt" db:"updated_at"`
-	Tracking  sql.NullString `json:"tracking,omitempty" db:"tracking"`
+	ID            int64          `json:"id" db:"id"`
+	PublicID      string         `json:"publicId" db:"public_id"`           // UUID used in URLs; prefer it over ID
+	UserID        int64          `json:"userId" db:"user_id"`               // The Dropshipper
+	Status        string         `json:"status" db:"status"`                // e.g., processing, on-hold, under-review, pre-order, shipped
+	Total         money.Money    `json:"total" db:"total"`                  // What the dropshipper pays, net of discounts
+	DiscountTotal money.Money    `json:"discountTotal" db:"discount_total"` // Platform-funded promotions (see order_discounts)
+	TaxTotal      money.Money    `json:"taxTotal" db:"tax_total"`           // SST, included in Total (see order_tax_lines)
+	CreatedAt     time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time      `json:"updatedAt" db:"updated_at"`
+	Tracking      sql.NullString `json:"tracking,omitempty" db:"tracking"`
+	Courier       *string        `json:"courier,omitempty" db:"courier"`        // SHIPPING_COURIERS code the supplier shipped with
+	CustomerID    *int64         `json:"customerId,omitempty" db:"customer_id"` // nil for orders placed without one
+	ShipTo        *ShipTo        `json:"shipTo,omitempty" db:"ship_to"`         // stored as JSON
+	BlindShip     bool           `json:"blindShip" db:"blind_ship"`             // packing slips hide the supplier (see PackingSlip)
+	SenderName    *string        `json:"senderName,omitempty" db:"sender_name"` // sender on blind slips; nil: the dropshipper's name
+}
+
+// SupplierOrder is what a supplier sees of an order: what to ship, where to,
+// and how. The dropshipper, their customer record and the order amounts (which
+// cover other suppliers' lines too) are left out.
+type SupplierOrder struct {
+	ID        int64          `json:"id"`
+	PublicID  string         `json:"publicId"`
+	Status    string         `json:"status"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	Tracking  sql.NullString `json:"tracking,omitempty"`
+	Courier   *string        `json:"courier,omitempty"`
+	ShipTo    *ShipTo        `json:"shipTo,omitempty"`
+	BlindShip bool           `json:"blindShip"`
+}
+
+// ForSupplier returns the supplier's view of the order.
+func (o Order) ForSupplier() SupplierOrder {
+	return SupplierOrder{
+		ID:        o.ID,
+		PublicID:  o.PublicID,
+		Status:    o.Status,
+		CreatedAt: o.CreatedAt,
+		UpdatedAt: o.UpdatedAt,
+		Tracking:  o.Tracking,
+		Courier:   o.Courier,
+		ShipTo:    o.ShipTo,
+		BlindShip: o.BlindShip,
+	}
+}
+
+// SupplierSubtotal is the value of one supplier's lines in an order.
+type SupplierSubtotal struct {
+	SupplierID int64
+	Amount     money.Money
+}
+
+// SupplierPayout is what completing an order pays one supplier: their lines
+// less the commission, plus the tax on them. The platform keeps Commission.
+type SupplierPayout struct {
+	SupplierID int64
+	Amount     money.Money
+	Commission money.Money
 }
 
 // OrderItem is the model for the 'order_items' table
 type OrderItem struct {
-	ID        int64     `json:"id" db:"id"`
-	OrderID   int64     `json:"orderId" db:"order_id"`
-	ProductID int64     `json:"productId" db:"product_id"`
-	Quantity  int       `json:"quantity" db:"quantity"`
-	UnitPrice float64   `json:"unitPrice" db:"unit_price"` // Price at the time of purchase
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	ID        int64       `json:"id" db:"id"`
+	OrderID   int64       `json:"orderId" db:"order_id"`
+	ProductID int64       `json:"productId" db:"product_id"`
+	VariantID *int64      `json:"variantId,omitempty" db:"variant_id"` // nil for simple products
+	Quantity  int         `json:"quantity" db:"quantity"`
+	UnitPrice money.Money `json:"unitPrice" db:"unit_price"` // Price at the time of purchase
+	Preorder  bool        `json:"preorder" db:"preorder"`    // still waiting for stock (not deducted yet)
+	CreatedAt time.Time   `json:"createdAt" db:"created_at"`
+
+	// Copied at checkout, like UnitPrice. The commission is not the dropshipper's business.
+	SupplierID     int64       `json:"supplierId" db:"supplier_id"`
+	CommissionRate float64     `json:"-" db:"commission_rate"` // percent of the line total
+	Commission     money.Money `json:"-" db:"commission"`      // what the platform keeps of the line
+}
+
+// OrderItemDetail extends the base OrderItem to include Product info
+type OrderItemDetail struct {
+	OrderItem
+	ProductName string              `json:"productName"`
+	ProductSKU  string              `json:"productSku"`
+	Options     []map[string]string `json:"options"` // To display "Color: Red"
+}
+
+// PackingSlip is the document packed in one supplier's parcel of an order,
+// the one the end customer gets. It never prints prices. On a blind order it
+// names the dropshipper (or the order's sender name) and leaves out SKUs,
+// which can carry the supplier's ID.
+type PackingSlip struct {
+	OrderPublicID string            `json:"orderPublicId"`
+	OrderDate     time.Time         `json:"orderDate"`
+	BlindShip     bool              `json:"blindShip"`
+	From          string            `json:"from"`
+	ShipTo        *ShipTo           `json:"shipTo"`
+	Items         []PackingSlipItem `json:"items"`
+}
+
+// PackingSlipItem is one line of a packing slip.
+type PackingSlipItem struct {
+	ProductName string              `json:"productName"`
+	SKU         string              `json:"sku,omitempty"` // "" on blind slips
+	Options     []map[string]string `json:"options"`
+	Quantity    int                 `json:"quantity"`
+}
+
+// SupplierOrderItem represents a single line item for the supplier to pack
+type SupplierOrderItem struct {
+	ProductName string              `json:"productName"`
+	SKU         string              `json:"sku"`
+	Quantity    int                 `json:"quantity"`
+	UnitPrice   money.Money         `json:"unitPrice"`
+	Options     []map[string]string `json:"options"` // To show "Color: Red"
 }
@@ -1,16 +1,22 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
 
 // Plan defines the model for the 'plans' table
 type Plan struct {
-	ID                int64     `json:"id" db:"id"`
-	Name              string    `json:"name" db:"name"`
-	Description       string    `json:"description" db:"description"`
-	Price             float64   `json:"price" db:"price"`
-	DurationDays      int       `json:"durationDays" db:"duration_days"`
-	AiCreditsIncluded float64   `json:"aiCreditsIncluded" db:"ai_credits_included"`
-	IsPublic          bool      `json:"isPublic" db:"is_public"`
-	CreatedAt         time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt         time.Time `json:"updatedAt" db:"updated_at"`
+	ID                int64       `json:"id" db:"id"`
+	Name              string      `json:"name" db:"name"`
+	Description       string      `json:"description" db:"description"`
+	Price             money.Money `json:"price" db:"price"`
+	DurationDays      int         `json:"durationDays" db:"duration_days"`
+	AiCreditsIncluded float64     `json:"aiCreditsIncluded" db:"ai_credits_included"`
+	AIDailyRequests   *int        `json:"aiDailyRequests" db:"ai_daily_requests"` // daily AI chat cap (null: none)
+	AIDailyTokens     *int        `json:"aiDailyTokens" db:"ai_daily_tokens"`     // daily AI chat cap (null: none)
+	IsPublic          bool        `json:"isPublic" db:"is_public"`
+	CreatedAt         time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt         time.Time   `json:"updatedAt" db:"updated_at"`
 }
@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
 )
 
 // PriceAppeal is the model for the 'price_appeals' table
@@ -10,8 +12,8 @@ type PriceAppeal struct {
 	ID              int64          `json:"id" db:"id"`
 	ProductID       int64          `json:"productId" db:"product_id"`
 	SupplierID      int64          `json:"supplierId" db:"supplier_id"`
-	OldPrice        float64        `json:"oldPrice" db:"old_price"`
-	NewPrice        float64        `json:"newPrice" db:"new_price"`
+	OldPrice        money.Money    `json:"oldPrice" db:"old_price"`
+	NewPrice        money.Money    `json:"newPrice" db:"new_price"`
 	Reason          sql.NullString `json:"reason,omitempty" db:"reason"`
 	Status          string         `json:"status" db:"status"`
 	RejectionReason sql.NullString `json:"rejectionReason,omitempty" db:"rejection_reason"`
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// ProcessingTime is how long a supplier takes to hand an order to the
+// courier, shop-wide ('users') or for one product ('products'). A nil field
+// falls back to the shop-wide setting, then to the platform default.
+type ProcessingTime struct {
+	HandlingDays *int    `json:"handlingDays" db:"handling_days"` // business days after the order counts
+	Cutoff       *string `json:"cutoff" db:"order_cutoff"`        // "HH:MM"; orders paid later count for the next business day
+}
+
+// LateShipment is a supplier's part of a paid order still unshipped after its
+// ships-by date.
+type LateShipment struct {
+	OrderID       int64     `json:"orderId"`
+	OrderPublicID string    `json:"orderPublicId"`
+	SupplierID    int64     `json:"supplierId"`
+	SupplierName  string    `json:"supplierName"`
+	ShipsBy       time.Time `json:"shipsBy"`
+	Notified      bool      `json:"notified"` // the supplier was told it is late
+}
@@ -1,28 +1,59 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pricing"
 )
 
 // Product is the model for the 'products' table.
 // [FIX]: Switched sql.Null* types to Pointers (*string, *float64) for clean JSON serialization.
 type Product struct {
 	ID          int64   `json:"id" db:"id"`
+	PublicID    string  `json:"publicId" db:"public_id"` // UUID used in URLs; prefer it over ID
 	SupplierID  int64   `json:"supplierId" db:"supplier_id"`
-	SKU         *string `json:"sku,omitempty" db:"sku"` // Changed from sql.NullString
+	SKU         *string `json:"sku,omitempty" db:"sku"`         // Changed from sql.NullString
+	Barcode     *string `json:"barcode,omitempty" db:"barcode"` // EAN/UPC of a simple product
 	Name        string  `json:"name" db:"name"`
 	Description string  `json:"description" db:"description"`
 
 	// --- Pricing & Stock ---
-	PriceToTTS    float64 `json:"price" db:"price_to_tts"`
-	StockQuantity int     `json:"stock" db:"stock_quantity"`
-	SRP           float64 `json:"srp" db:"srp"`
+	PriceToTTS    money.Money `json:"price" db:"price_to_tts"`
+	StockQuantity int         `json:"stock" db:"stock_quantity"`
+	SRP           money.Money `json:"srp" db:"srp"`
+
+	// Pricing splits a unit at the price between the dropshipper's margin, the
+	// platform fee and the supplier (set on load; see package pricing).
+	Pricing *pricing.Breakdown `json:"pricing,omitempty" db:"-"`
 
 	// --- Configuration ---
 	IsVariable     bool     `json:"isVariable" db:"is_variable"`
 	Status         string   `json:"status" db:"status"`
 	CommissionRate *float64 `json:"commissionRate,omitempty" db:"commission_rate"` // Changed from sql.NullFloat64
 
+	// RejectionReason is the manager's reason for the last rejection. It stays
+	// while the product is resubmitted, so the reviewer sees it, and is cleared
+	// on approval.
+	RejectionReason *string `json:"rejectionReason,omitempty" db:"rejection_reason"`
+
+	// --- Pre-order (simple products only) ---
+	// Beyond the stock, up to PreorderLimit units can be ordered ahead of
+	// PreorderAvailableAt; PreorderReserved counts those on open pre-orders.
+	IsPreorder          bool       `json:"isPreorder" db:"is_preorder"`
+	PreorderAvailableAt *time.Time `json:"preorderAvailableAt,omitempty" db:"preorder_available_at"`
+	PreorderLimit       *int       `json:"preorderLimit,omitempty" db:"preorder_limit"`
+	PreorderReserved    int        `json:"preorderReserved" db:"preorder_reserved"`
+
+	// --- Order Quantity Rules (every cart line: at least MinOrderQty, in multiples of OrderIncrement) ---
+	MinOrderQty    int `json:"minOrderQty" db:"min_order_qty"`
+	OrderIncrement int `json:"orderIncrement" db:"order_increment"`
+
+	// --- Processing Time (nil = the supplier's shop-wide setting; loaded by Get only) ---
+	HandlingDays *int    `json:"handlingDays,omitempty" db:"handling_days"`
+	OrderCutoff  *string `json:"orderCutoff,omitempty" db:"order_cutoff"` // TIME, "HH:MM:SS"
+
 	// --- Media & Content ---
 	Images          []string               `json:"images"`
 	VideoURL        string                 `json:"videoUrl"`
@@ -37,16 +68,155 @@ type Product struct {
 	PkgWidth    *float64 `json:"pkgWidth,omitempty" db:"pkg_width"`   // Changed from sql.NullFloat64
 	PkgHeight   *float64 `json:"pkgHeight,omitempty" db:"pkg_height"` // Changed from sql.NullFloat64
 
+	// ShippingRestrictions are the handling flags couriers are matched against
+	// (battery, liquid, fragile, oversize; see package shipping).
+	ShippingRestrictions []string `json:"shippingRestrictions" db:"shipping_restrictions"`
+
+	// --- Reviews (aggregate of visible reviews, maintained by the review handlers) ---
+	RatingAvg   float64 `json:"rating" db:"rating_avg"`
+	RatingCount int     `json:"ratingCount" db:"rating_count"`
+
+	// --- Supplier Vacation (set by catalogue search; not in the table) ---
+	// Listings of a supplier on vacation stay visible unless they chose to hide
+	// them, but cannot be ordered until SupplierBackAt (nil = no date set).
+	SupplierAway   bool       `json:"supplierAway,omitempty" db:"-"`
+	SupplierBackAt *time.Time `json:"supplierBackAt,omitempty" db:"-"`
+
+	// Relevance is the full-text score of a search ranked by relevance.
+	Relevance *float64 `json:"relevance,omitempty" db:"-"`
+
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	Version   int       `json:"version" db:"version"` // optimistic-locking token; send it back on update
 
 	// Joins (Not in DB table, populated manually)
 	Categories []Category       `json:"categories,omitempty" db:"-"`
 	Brands     []Brand          `json:"brands,omitempty" db:"-"`
+	Tags       []Tag            `json:"tags,omitempty" db:"-"`
 	Variants   []ProductVariant `json:"variants,omitempty" db:"-"`
 
 	// Flattened fields for UI convenience (populated manually if needed)
 	SupplierName string `json:"supplierName,omitempty" db:"-"`
+
+	// Legacy free-text brand column, kept for the edit form until every row has a product_brands link.
+	BrandName string `json:"-" db:"brand"`
+}
+
+// CatalogueProduct is what a dropshipper sees of an active product on its
+// detail page (GET /v1/products/:id): no commission or package details.
+type CatalogueProduct struct {
+	ID          int64   `json:"id"`
+	PublicID    string  `json:"publicId"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	SKU         *string `json:"sku,omitempty"`
+	Barcode     *string `json:"barcode,omitempty"`
+
+	Price      money.Money `json:"price"`
+	SRP        money.Money `json:"srp"`
+	Stock      int         `json:"stock"`
+	IsVariable bool        `json:"isVariable"`
+
+	// The dropshipper's margin selling at the SRP, and in percent of it (null
+	// without an SRP). The platform fee is not the dropshipper's business.
+	Margin        *money.Money `json:"margin"`
+	MarginPercent *float64     `json:"marginPercent"`
+
+	IsPreorder          bool       `json:"isPreorder"`
+	PreorderAvailableAt *time.Time `json:"preorderAvailableAt,omitempty"`
+	MinOrderQty         int        `json:"minOrderQty"`
+	OrderIncrement      int        `json:"orderIncrement"`
+
+	Images          []string               `json:"images"`
+	VideoURL        string                 `json:"videoUrl,omitempty"`
+	SizeChart       map[string]interface{} `json:"sizeChart,omitempty"`
+	VariationImages map[string]string      `json:"variationImages"`
+
+	// Couriers that accept the restrictions (null when SHIPPING_COURIERS is not
+	// set), and the ships-by date of an order paid now.
+	ShippingRestrictions []string  `json:"shippingRestrictions"`
+	Couriers             []string  `json:"couriers"`
+	ShipsBy              time.Time `json:"shipsBy"`
+
+	Rating      float64 `json:"rating"`
+	RatingCount int     `json:"ratingCount"`
+
+	Brand      *Brand             `json:"brand"`
+	Categories []Category         `json:"categories"`
+	Variants   []CatalogueVariant `json:"variants"`
+	Supplier   ProductSupplier    `json:"supplier"`
+}
+
+// CatalogueVariant is one variant of a CatalogueProduct, options decoded.
+type CatalogueVariant struct {
+	ID      int64                  `json:"id"`
+	SKU     *string                `json:"sku,omitempty"`
+	Barcode *string                `json:"barcode,omitempty"`
+	Price   money.Money            `json:"price"`
+	Stock   int                    `json:"stock"`
+	Options []ProductVariantOption `json:"options"`
+}
+
+// ProductExport is a catalogue product as a dropshipper lists it on their own
+// storefront (GET /v1/dropshipper/products/:id/export). It is marketplace
+// agnostic and priced at the SRP; the supplier's price and identity stay out.
+type ProductExport struct {
+	SourceID    string   `json:"sourceId"` // the product's public ID
+	SKU         *string  `json:"sku,omitempty"`
+	Barcode     *string  `json:"barcode,omitempty"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"` // allowlisted HTML
+	Brand       *string  `json:"brand"`
+	Categories  []string `json:"categories"`
+	Images      []string `json:"images"`
+	VideoURL    string   `json:"videoUrl,omitempty"`
+
+	// SRP is the recommended retail price (null when the supplier set none).
+	SRP      *money.Money `json:"srp"`
+	Currency string       `json:"currency"`
+	Stock    int          `json:"stock"`
+
+	WeightKg *float64           `json:"weightKg"`
+	Package  *PackageDimensions `json:"packageCm"`
+
+	// Options lists every option name with its values, in variant order.
+	Options  []ProductExportOption  `json:"options"`
+	Variants []ProductExportVariant `json:"variants"`
+
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// PackageDimensions is a package size in centimetres.
+type PackageDimensions struct {
+	Length float64 `json:"length"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// ProductExportOption is one option of an exported product, e.g. Colour with
+// Red and Blue.
+type ProductExportOption struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// ProductExportVariant is one variant of a ProductExport. Image is the
+// variation image of its first option value that has one.
+type ProductExportVariant struct {
+	SKU     *string                `json:"sku,omitempty"`
+	Barcode *string                `json:"barcode,omitempty"`
+	Stock   int                    `json:"stock"`
+	Options []ProductVariantOption `json:"options"`
+	Image   string                 `json:"image,omitempty"`
+}
+
+// ProductSupplier is the supplier shown on a product page; PublicID links to
+// their storefront (GET /v1/suppliers/:id/profile).
+type ProductSupplier struct {
+	PublicID string     `json:"publicId"`
+	Name     string     `json:"name"` // company name, or the supplier's name without one
+	Away     bool       `json:"away"`
+	BackAt   *time.Time `json:"backAt,omitempty"`
 }
 
 // ProductVariantOption defines the structure for variant options JSON
@@ -57,13 +227,156 @@ type ProductVariantOption struct {
 
 // ProductVariant is the model for the 'product_variants' table
 type ProductVariant struct {
-	ID             int64     `json:"id" db:"id"`
-	ProductID      int64     `json:"productId" db:"product_id"`
-	SKU            *string   `json:"sku,omitempty" db:"sku"` // Changed from sql.NullString
-	PriceToTTS     float64   `json:"price" db:"price_to_tts"`
-	StockQuantity  int       `json:"stock" db:"stock_quantity"`
-	Options        string    `json:"options" db:"options"`                          // Stored as JSON string in DB
-	CommissionRate *float64  `json:"commissionRate,omitempty" db:"commission_rate"` // Changed from sql.NullFloat64
-	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt      time.Time `json:"updatedAt" db:"updated_at"`
+	ID             int64       `json:"id" db:"id"`
+	ProductID      int64       `json:"productId" db:"product_id"`
+	SKU            *string     `json:"sku,omitempty" db:"sku"` // Changed from sql.NullString
+	Barcode        *string     `json:"barcode,omitempty" db:"barcode"`
+	PriceToTTS     money.Money `json:"price" db:"price_to_tts"`
+	StockQuantity  int         `json:"stock" db:"stock_quantity"`
+	Options        string      `json:"options" db:"options"`                          // Stored as JSON string in DB
+	CommissionRate *float64    `json:"commissionRate,omitempty" db:"commission_rate"` // Changed from sql.NullFloat64
+	CreatedAt      time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+// SKUUse is the product (and variant, for a variant SKU) already using a SKU
+// among one supplier's products, as reported by the SKU pre-check. The
+// barcode check reports a taken barcode the same way.
+type SKUUse struct {
+	ProductID   int64  `json:"productId"`
+	PublicID    string `json:"publicId"`
+	ProductName string `json:"productName"`
+	VariantID   *int64 `json:"variantId,omitempty"`
+}
+
+// BarcodeMatch is a catalogue product carrying a scanned barcode, on the
+// variant VariantID for a variable product.
+type BarcodeMatch struct {
+	ProductID int64
+	VariantID *int64
+}
+
+// StockMovement is one manual stock adjustment of a product, or of one of its
+// variants when VariantID is set: the change (Delta) and the stock it left.
+type StockMovement struct {
+	ID            int64     `json:"id"`
+	ProductID     int64     `json:"productId"`
+	VariantID     *int64    `json:"variantId,omitempty"`
+	UserID        int64     `json:"userId"`
+	Delta         int       `json:"delta"`
+	QuantityAfter int       `json:"quantityAfter"`
+	Reason        string    `json:"reason"`
+	Note          string    `json:"note,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// ProductChange is one entry of the product change feed (GET /v1/products/changes).
+// Type is "created" or "updated" with the current Product, or "deleted" when the
+// product left the catalogue (deleted, no longer active, or hidden by a vacation).
+type ProductChange struct {
+	Type      string    `json:"type"`
+	ProductID int64     `json:"productId"`
+	PublicID  string    `json:"publicId"`
+	ChangedAt time.Time `json:"changedAt"`
+	Product   *Product  `json:"product,omitempty"`
+}
+
+// ProductSnapshot is a product's editable content at one point in time, as
+// kept in product_revisions.
+type ProductSnapshot struct {
+	Name           string      `json:"name"`
+	Description    string      `json:"description"`
+	Status         string      `json:"status"`
+	IsVariable     bool        `json:"isVariable"`
+	SKU            *string     `json:"sku,omitempty"`
+	Barcode        *string     `json:"barcode,omitempty"`
+	Price          money.Money `json:"price"`
+	Stock          int         `json:"stock"`
+	SRP            money.Money `json:"srp"`
+	CommissionRate *float64    `json:"commissionRate,omitempty"`
+
+	IsPreorder          bool       `json:"isPreorder"`
+	PreorderAvailableAt *time.Time `json:"preorderAvailableAt,omitempty"`
+	PreorderLimit       *int       `json:"preorderLimit,omitempty"`
+
+	Weight               *float64 `json:"weight,omitempty"`
+	PkgLength            *float64 `json:"pkgLength,omitempty"`
+	PkgWidth             *float64 `json:"pkgWidth,omitempty"`
+	PkgHeight            *float64 `json:"pkgHeight,omitempty"`
+	ShippingRestrictions []string `json:"shippingRestrictions"`
+	MinOrderQty          int      `json:"minOrderQty"`
+	OrderIncrement       int      `json:"orderIncrement"`
+	HandlingDays         *int     `json:"handlingDays,omitempty"`
+	OrderCutoff          *string  `json:"orderCutoff,omitempty"`
+
+	Images          []string               `json:"images"`
+	VideoURL        string                 `json:"videoUrl,omitempty"`
+	SizeChart       map[string]interface{} `json:"sizeChart,omitempty"`
+	VariationImages map[string]string      `json:"variationImages,omitempty"`
+
+	BrandID     int64                    `json:"brandId,omitempty"`
+	CategoryIDs []int64                  `json:"categoryIds"`
+	Tags        []string                 `json:"tags,omitempty"` // names; absent from snapshots older than tags
+	Variants    []ProductSnapshotVariant `json:"variants,omitempty"`
+	Version     int                      `json:"version"`
+}
+
+// ProductSnapshotVariant is one variant in a ProductSnapshot.
+type ProductSnapshotVariant struct {
+	ID             int64           `json:"id"`
+	SKU            *string         `json:"sku,omitempty"`
+	Barcode        *string         `json:"barcode,omitempty"`
+	Price          money.Money     `json:"price"`
+	Stock          int             `json:"stock"`
+	Options        json.RawMessage `json:"options"`
+	CommissionRate *float64        `json:"commissionRate,omitempty"`
+}
+
+// ProductRevision is one recorded change to a product: a supplier's update,
+// or a manager's rollback to the state before revision RolledBackTo.
+type ProductRevision struct {
+	ID           int64           `json:"id"`
+	ProductID    int64           `json:"productId"`
+	UserID       int64           `json:"userId"`
+	Action       string          `json:"action"` // update, rollback, reject or resubmit
+	RolledBackTo *int64          `json:"rolledBackTo,omitempty"`
+	Note         *string         `json:"note,omitempty"` // the reason of a rejection
+	Before       ProductSnapshot `json:"before"`
+	After        ProductSnapshot `json:"after"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// SearchFacets counts the products of a catalogue search per category, brand
+// and price range. Each facet ignores its own filter, so its counts are what
+// choosing another value would give.
+type SearchFacets struct {
+	Categories []FacetCount  `json:"categories"` // most products first
+	Brands     []FacetCount  `json:"brands"`     // most products first
+	Prices     []PriceBucket `json:"prices"`     // every range, cheapest first
+}
+
+// FacetCount is the number of matching products in a category or brand.
+type FacetCount struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// PriceBucket is the number of matching products priced from Min up to (not
+// including) Max; the last range has no Max.
+type PriceBucket struct {
+	Min   money.Money  `json:"min"`
+	Max   *money.Money `json:"max"`
+	Count int          `json:"count"`
+}
+
+// ProcessedImage is a product photo run through the image pipeline: the
+// upload as sent and the normalized version to list with.
+type ProcessedImage struct {
+	ID                int64     `json:"id"`
+	OriginalURL       string    `json:"originalUrl"`
+	ProcessedURL      string    `json:"processedUrl"`
+	BackgroundRemoved bool      `json:"backgroundRemoved"`
+	Watermarked       bool      `json:"watermarked"`
+	CreatedAt         time.Time `json:"createdAt"`
 }
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// Promotion is the model for the 'promotions' table.
+// A promotion with a Code is a coupon; without one it is an automatic campaign.
+type Promotion struct {
+	ID             int64        `json:"id" db:"id"`
+	Name           string       `json:"name" db:"name"`
+	Code           *string      `json:"code,omitempty" db:"code"`
+	Kind           string       `json:"kind" db:"kind"`                          // percent or fixed
+	Value          float64      `json:"value" db:"value"`                        // percent (0-100] or RM
+	MaxDiscount    *money.Money `json:"maxDiscount,omitempty" db:"max_discount"` // cap for percent promotions
+	MinSpend       money.Money  `json:"minSpend" db:"min_spend"`                 // on the eligible items
+	CategoryID     *int64       `json:"categoryId,omitempty" db:"category_id"`   // only items in this category (or below)
+	FirstOrderOnly bool         `json:"firstOrderOnly" db:"first_order_only"`
+	UsageLimit     *int         `json:"usageLimit,omitempty" db:"usage_limit"`      // redemptions in total
+	PerUserLimit   *int         `json:"perUserLimit,omitempty" db:"per_user_limit"` // redemptions per dropshipper
+	StartsAt       time.Time    `json:"startsAt" db:"starts_at"`
+	EndsAt         *time.Time   `json:"endsAt,omitempty" db:"ends_at"`
+	IsActive       bool         `json:"isActive" db:"is_active"`
+	CreatedBy      int64        `json:"createdBy" db:"created_by"`
+	CreatedAt      time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updatedAt" db:"updated_at"`
+
+	// Redemption stats, populated on manager listings.
+	Redemptions   int         `json:"redemptions" db:"-"`
+	TotalDiscount money.Money `json:"totalDiscount" db:"-"`
+}
+
+// OrderDiscount is the model for the 'order_discounts' table
+type OrderDiscount struct {
+	ID          int64       `json:"id" db:"id"`
+	OrderID     int64       `json:"orderId" db:"order_id"`
+	PromotionID int64       `json:"promotionId" db:"promotion_id"`
+	UserID      int64       `json:"userId" db:"user_id"`
+	Code        *string     `json:"code,omitempty" db:"code"`
+	Description string      `json:"description" db:"description"`
+	Amount      money.Money `json:"amount" db:"amount"`
+	CreatedAt   time.Time   `json:"createdAt" db:"created_at"`
+
+	// Order fields, populated on redemption reports.
+	OrderPublicID string      `json:"orderPublicId,omitempty" db:"-"`
+	OrderStatus   string      `json:"orderStatus,omitempty" db:"-"`
+	OrderTotal    money.Money `json:"orderTotal,omitempty" db:"-"`
+}
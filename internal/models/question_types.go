@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// ProductQuestion is the model for the 'product_questions' table
+type ProductQuestion struct {
+	ID             int64      `json:"id" db:"id"`
+	ProductID      int64      `json:"productId" db:"product_id"`
+	DropshipperID  int64      `json:"dropshipperId" db:"dropshipper_id"`
+	Question       string     `json:"question" db:"question"`
+	Answer         *string    `json:"answer,omitempty" db:"answer"` // the supplier's; nil while unanswered
+	AnsweredAt     *time.Time `json:"answeredAt,omitempty" db:"answered_at"`
+	Status         string     `json:"status" db:"status"` // published, hidden
+	ModerationNote *string    `json:"moderationNote,omitempty" db:"moderation_note"`
+	ModeratedBy    *int64     `json:"moderatedBy,omitempty" db:"moderated_by"`
+	ModeratedAt    *time.Time `json:"moderatedAt,omitempty" db:"moderated_at"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+
+	// Joined for display; not in the table.
+	AskerName   string `json:"askerName" db:"-"`
+	ProductName string `json:"productName,omitempty" db:"-"`
+}
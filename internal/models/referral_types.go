@@ -0,0 +1,37 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// Referral is the model for the 'referrals' table
+type Referral struct {
+	ID             int64           `json:"id" db:"id"`
+	ReferrerID     int64           `json:"referrerId" db:"referrer_id"`
+	RefereeID      int64           `json:"refereeId" db:"referee_id"`
+	Status         string          `json:"status" db:"status"` // pending, rewarded, rejected
+	SignupIP       string          `json:"-" db:"signup_ip"`   // anti-abuse only; never returned
+	OrderID        sql.NullInt64   `json:"-" db:"order_id"`    // the order that earned the reward
+	ReferrerReward sql.NullFloat64 `json:"referrerReward" db:"referrer_reward"`
+	RefereeReward  sql.NullFloat64 `json:"refereeReward" db:"referee_reward"`
+	RejectReason   sql.NullString  `json:"rejectReason" db:"reject_reason"`
+	CreatedAt      time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updatedAt" db:"updated_at"`
+	RewardedAt     sql.NullTime    `json:"rewardedAt" db:"rewarded_at"`
+
+	// Masked referee details, populated on the referrer's dashboard.
+	RefereeName  string `json:"refereeName" db:"-"`
+	RefereeEmail string `json:"refereeEmail" db:"-"`
+}
+
+// ReferralStats summarises a referrer's referrals.
+type ReferralStats struct {
+	Total    int         `json:"total"`
+	Pending  int         `json:"pending"`
+	Rewarded int         `json:"rewarded"`
+	Rejected int         `json:"rejected"`
+	Earned   money.Money `json:"earned"` // promo credit received as referrer
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RequestCapture is the model for the 'request_captures' table (see audit.Recorder)
+type RequestCapture struct {
+	ID             int64               `json:"id" db:"id"`
+	Kind           string              `json:"kind" db:"kind"` // webhook, payment
+	RequestID      string              `json:"requestId" db:"request_id"`
+	UserID         *int64              `json:"userId" db:"user_id"`
+	Method         string              `json:"method" db:"method"`
+	Route          string              `json:"route" db:"route"`
+	Path           string              `json:"path" db:"path"`
+	RequestHeaders map[string][]string `json:"requestHeaders,omitempty" db:"request_headers"` // JSON; credentials redacted
+	RequestBody    string              `json:"requestBody,omitempty" db:"request_body"`
+	Signature      string              `json:"signature,omitempty" db:"signature"`
+	ResponseStatus int                 `json:"responseStatus" db:"response_status"`
+	ResponseBody   string              `json:"responseBody,omitempty" db:"response_body"`
+	ReplayOf       *int64              `json:"replayOf" db:"replay_of"`
+	CreatedAt      time.Time           `json:"createdAt" db:"created_at"`
+}
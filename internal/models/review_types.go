@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// ProductReview is the model for the 'product_reviews' table
+type ProductReview struct {
+	ID             int64      `json:"id" db:"id"`
+	ProductID      int64      `json:"productId" db:"product_id"`
+	DropshipperID  int64      `json:"dropshipperId" db:"dropshipper_id"`
+	OrderID        int64      `json:"orderId" db:"order_id"` // the completed order that verifies the purchase
+	Rating         int        `json:"rating" db:"rating"`    // 1-5
+	Comment        string     `json:"comment" db:"comment"`
+	SupplierReply  *string    `json:"supplierReply,omitempty" db:"supplier_reply"`
+	RepliedAt      *time.Time `json:"repliedAt,omitempty" db:"replied_at"`
+	Status         string     `json:"status" db:"status"` // published, flagged (reported, still visible), hidden
+	FlagReason     *string    `json:"flagReason,omitempty" db:"flag_reason"`
+	ModerationNote *string    `json:"moderationNote,omitempty" db:"moderation_note"`
+	ModeratedBy    *int64     `json:"moderatedBy,omitempty" db:"moderated_by"`
+	ModeratedAt    *time.Time `json:"moderatedAt,omitempty" db:"moderated_at"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+
+	// Joined for display; not in the table.
+	ReviewerName string `json:"reviewerName" db:"-"`
+	ProductName  string `json:"productName,omitempty" db:"-"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// RiskReview is the model for the 'risk_reviews' table: a checkout or wallet
+// top-up the risk checks held for a manager.
+type RiskReview struct {
+	ID         int64          `json:"id" db:"id"`
+	UserID     int64          `json:"userId" db:"user_id"`
+	Kind       string         `json:"kind" db:"kind"` // order, topup
+	OrderID    sql.NullInt64  `json:"orderId,omitempty" db:"order_id"`
+	Amount     money.Money    `json:"amount" db:"amount"` // the order total or top-up amount
+	Score      int            `json:"score" db:"score"`
+	Reasons    []string       `json:"reasons" db:"reasons"` // see package risk
+	Status     string         `json:"status" db:"status"`   // pending, approved, rejected
+	ReviewNote sql.NullString `json:"reviewNote,omitempty" db:"review_note"`
+	ReviewedBy sql.NullInt64  `json:"reviewedBy,omitempty" db:"reviewed_by"`
+	ReviewedAt sql.NullTime   `json:"reviewedAt,omitempty" db:"reviewed_at"`
+	CreatedAt  time.Time      `json:"createdAt" db:"created_at"`
+
+	// Not in the table; joined from 'users' for the queue.
+	UserName  string `json:"userName" db:"-"`
+	UserEmail string `json:"userEmail" db:"-"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// StatusEntry is the model for the 'status_entries' table: an incident, a
+// scheduled maintenance window or a release note.
+type StatusEntry struct {
+	ID           int64      `json:"id" db:"id"`
+	Kind         string     `json:"kind" db:"kind"`         // incident, maintenance, release
+	Title        string     `json:"title" db:"title"`       // plain text
+	Body         string     `json:"body" db:"body"`         // rich text (sanitize.HTML)
+	Severity     string     `json:"severity" db:"severity"` // info, minor, major, critical
+	ReleaseTag   *string    `json:"releaseTag,omitempty" db:"release_tag"`
+	StartsAt     time.Time  `json:"startsAt" db:"starts_at"`         // incident began, window opens, release published
+	EndsAt       *time.Time `json:"endsAt,omitempty" db:"ends_at"`   // incident resolved, window closes
+	BlocksWrites bool       `json:"blocksWrites" db:"blocks_writes"` // maintenance only
+	IsPublished  bool       `json:"isPublished" db:"is_published"`
+	CreatedBy    int64      `json:"createdBy,omitempty" db:"created_by"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updatedAt" db:"updated_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// SupplierProfile is the public storefront of a supplier: no contact or
+// document details, only what a dropshipper browsing the shop needs.
+type SupplierProfile struct {
+	PublicID    string    `json:"publicId"`
+	Name        string    `json:"name"` // company name, or the supplier's name without one
+	City        *string   `json:"city,omitempty"`
+	State       *string   `json:"state,omitempty"`
+	MemberSince time.Time `json:"memberSince"`
+
+	// Away is set while the supplier is on vacation (see GET /supplier/vacation).
+	Away           bool       `json:"away"`
+	BackAt         *time.Time `json:"backAt,omitempty"`
+	AwayMessage    *string    `json:"awayMessage,omitempty"`
+	HandlingDays   int        `json:"handlingDays"` // business days to ship a paid order
+	ActiveProducts int        `json:"activeProducts"`
+
+	Rating      float64          `json:"rating"` // average of every review on their products
+	ReviewCount int              `json:"reviewCount"`
+	Fulfillment FulfillmentStats `json:"fulfillment"`
+}
+
+// FulfillmentStats covers the supplier's part of the orders paid in the last
+// Days days.
+type FulfillmentStats struct {
+	Days         int     `json:"days"`
+	Orders       int     `json:"orders"`       // paid orders with their items
+	Shipped      int     `json:"shipped"`      // of which shipped or completed
+	Late         int     `json:"late"`         // passed the ships-by date unshipped
+	OnTimeRate   float64 `json:"onTimeRate"`   // 1 - late/orders (1 without orders)
+	DisputeCount int     `json:"disputeCount"` // disputes opened on them
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// TaxRate is the SST rate of a category (the 'tax_rates' table).
+type TaxRate struct {
+	CategoryID   int64     `json:"categoryId" db:"category_id"`
+	CategoryName string    `json:"categoryName" db:"-"`
+	Rate         float64   `json:"rate" db:"rate"` // percent
+	UpdatedBy    int64     `json:"updatedBy" db:"updated_by"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// OrderTaxLine is the tax on one supplier's lines of an order at one rate.
+type OrderTaxLine struct {
+	ID            int64       `json:"id" db:"id"`
+	OrderID       int64       `json:"orderId" db:"order_id"`
+	SupplierID    int64       `json:"supplierId" db:"supplier_id"`
+	Rate          float64     `json:"rate" db:"rate"`
+	TaxableAmount money.Money `json:"taxableAmount" db:"taxable_amount"`
+	TaxAmount     money.Money `json:"taxAmount" db:"tax_amount"`
+}
+
+// Invoice is what one supplier bills for a paid order.
+type Invoice struct {
+	ID                int64       `json:"id" db:"id"`
+	Number            string      `json:"number" db:"-"` // INV-00000042, from the ID
+	OrderID           int64       `json:"orderId" db:"order_id"`
+	SupplierID        int64       `json:"supplierId" db:"supplier_id"`
+	SupplierName      string      `json:"supplierName" db:"-"`
+	SupplierSSTNumber *string     `json:"supplierSstNumber" db:"supplier_sst_number"` // nil: not SST-registered
+	Subtotal          money.Money `json:"subtotal" db:"subtotal"`
+	TaxTotal          money.Money `json:"taxTotal" db:"tax_total"`
+	Total             money.Money `json:"total" db:"total"`
+	IssuedAt          time.Time   `json:"issuedAt" db:"issued_at"`
+
+	Items    []SupplierOrderItem `json:"items" db:"-"`
+	TaxLines []OrderTaxLine      `json:"taxLines" db:"-"`
+}
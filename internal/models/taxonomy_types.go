@@ -27,13 +27,25 @@ type Brand struct {
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 }
 
+// Tag labels products for curated collections; GET /v1/tags lists them.
+type Tag struct {
+	ID   int64  `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	Slug string `json:"slug" db:"slug"`
+
+	// Active catalogue products carrying it, populated on GET /v1/tags.
+	ProductCount *int `json:"productCount,omitempty" db:"-"`
+}
+
 // --- API Input/Output Structs ---
 
 type CreateCategoryInput struct {
 	Name     string `json:"name" binding:"required"`
-	ParentID *int64 `json:"parentId"` // Pointer allows sending null for root categories
+	ParentID *int64 `json:"parentId"`                              // Pointer allows sending null for root categories
+	Slug     string `json:"slug" binding:"omitempty,slug,max=100"` // Optional; derived from Name when empty
 }
 
 type CreateBrandInput struct {
 	Name string `json:"name" binding:"required"`
+	Slug string `json:"slug" binding:"omitempty,slug,max=100"` // Optional; derived from Name when empty
 }
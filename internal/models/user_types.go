@@ -10,6 +10,7 @@ import (
 // User Model with Pointers for Nullable Fields
 type User struct {
 	ID           int64  `json:"id" db:"id"`
+	PublicID     string `json:"publicId" db:"public_id"` // UUID used in URLs; prefer it over ID
 	Role         string `json:"role" db:"role"`
 	Status       string `json:"status" db:"status"`
 	Email        string `json:"email" db:"email"`
@@ -22,7 +23,7 @@ type User struct {
 
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
-	Version   int       `json:"-" db:"version"`
+	Version   int       `json:"version" db:"version"`
 
 	// --- Profile Fields (Pointers = Clean JSON) ---
 	CompanyName      *string `json:"companyName,omitempty" db:"company_name"`
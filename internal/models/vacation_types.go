@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// SupplierVacation is a supplier's vacation mode, stored on the 'users' row.
+type SupplierVacation struct {
+	OnVacation   bool       `json:"onVacation"`                                   // derived: started and not yet ended
+	StartedAt    *time.Time `json:"startedAt,omitempty" db:"vacation_started_at"` // nil when off
+	EndsAt       *time.Time `json:"endsAt,omitempty" db:"vacation_ends_at"`       // nil = until turned off
+	Message      *string    `json:"message,omitempty" db:"vacation_message"`      // auto-reply to questions
+	HideListings bool       `json:"hideListings" db:"vacation_hide_listings"`     // hide instead of marking unavailable
+}
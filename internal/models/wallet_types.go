@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
 )
 
 // WalletTransaction is the model for the 'wallet_transactions' table
@@ -10,7 +12,30 @@ type WalletTransaction struct {
 	ID        int64          `json:"id" db:"id"`
 	UserID    int64          `json:"userId" db:"user_id"`
 	Type      string         `json:"type" db:"type"`     // e.g., deposit, withdrawal, order
-	Amount    float64        `json:"amount" db:"amount"` // Can be positive (deposit) or negative (order)
+	Amount    money.Money    `json:"amount" db:"amount"` // Can be positive (deposit) or negative (order)
 	Details   sql.NullString `json:"details,omitempty" db:"details"`
 	CreatedAt time.Time      `json:"createdAt" db:"created_at"`
 }
+
+// LedgerMismatch is the first row of a user's ledger whose balance_after is not
+// the running sum of the amounts before and including it.
+type LedgerMismatch struct {
+	UserID        int64       `json:"userId"`
+	TransactionID int64       `json:"transactionId"`
+	Expected      money.Money `json:"expected"` // running sum of the amounts
+	Recorded      money.Money `json:"recorded"` // balance_after
+	CreatedAt     time.Time   `json:"createdAt"`
+}
+
+// WalletHold sets part of a dropshipper's balance aside for an on-hold order
+// until it is paid (captured) or cancelled (released). Held funds cannot pay
+// for anything else.
+type WalletHold struct {
+	ID        int64       `json:"id" db:"id"`
+	UserID    int64       `json:"userId" db:"user_id"`
+	OrderID   int64       `json:"orderId" db:"order_id"`
+	Amount    money.Money `json:"amount" db:"amount"` // up to the order total: what the balance covered at checkout
+	Status    string      `json:"status" db:"status"` // held, captured, released
+	CreatedAt time.Time   `json:"createdAt" db:"created_at"`
+	ClosedAt  *time.Time  `json:"closedAt,omitempty" db:"closed_at"`
+}
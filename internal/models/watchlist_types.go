@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// WatchlistItem is a product on a dropshipper's watchlist, with its current
+// price and stock. Available is false once the product left the catalogue
+// (deleted, pending re-review, hidden by a vacation); it stays listed.
+type WatchlistItem struct {
+	ID        int64       `json:"id"`
+	ProductID int64       `json:"productId"`
+	PublicID  string      `json:"publicId"`
+	Name      string      `json:"name"`
+	Image     string      `json:"image,omitempty"` // the first product image
+	Price     money.Money `json:"price"`
+	Stock     int         `json:"stock"`
+	Available bool        `json:"available"`
+	WatchedAt time.Time   `json:"watchedAt"`
+}
+
+// WatchlistChange is a watched product whose price or stock moved since the
+// watcher was last told, as found by the watchlist check.
+type WatchlistChange struct {
+	WatchID       int64
+	DropshipperID int64
+	ProductName   string
+	LastPrice     money.Money
+	Price         money.Money
+	WasInStock    bool
+	Stock         int
+	Active        bool
+	ChangedAt     time.Time
+}
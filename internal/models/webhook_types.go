@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// WebhookEndpoint is a URL a user registered (POST /v1/webhooks) to receive
+// events of the listed types.
+type WebhookEndpoint struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Secret signs the deliveries; it is only returned when the endpoint is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookDelivery is one event sent (or being retried) to an endpoint.
+type WebhookDelivery struct {
+	ID            int64      `json:"id"`
+	EventID       string     `json:"eventId"`
+	EventType     string     `json:"eventType"`
+	Status        string     `json:"status"` // pending, delivered or failed
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"` // while pending
+	LastStatus    *int       `json:"lastStatus,omitempty"`    // HTTP status of the last attempt
+	LastError     *string    `json:"lastError,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	DeliveredAt   *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// WalletEvent is a wallet.credited or wallet.debited event, sent to webhooks
+// and on the wallet stream. ID is stable, so receivers can deduplicate.
+type WalletEvent struct {
+	ID        string          `json:"id"` // "wtx_<transaction id>"
+	UserID    int64           `json:"-"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      WalletEventData `json:"data"`
+}
+
+// WalletEventData is the ledger row behind a WalletEvent.
+type WalletEventData struct {
+	TransactionID int64       `json:"transactionId"`
+	Kind          string      `json:"kind"`   // the transaction type, e.g. topup, order_payment
+	Amount        money.Money `json:"amount"` // signed: negative for debits
+	BalanceAfter  money.Money `json:"balanceAfter"`
+	Notes         string      `json:"notes,omitempty"`
+}
@@ -3,13 +3,15 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
 )
 
 // WithdrawalRequest is the model for the 'withdrawal_requests' table
 type WithdrawalRequest struct {
 	ID              int64          `json:"id" db:"id"`
 	UserID          int64          `json:"userId" db:"user_id"`
-	Amount          float64        `json:"amount" db:"amount"`
+	Amount          money.Money    `json:"amount" db:"amount"`
 	Status          string         `json:"status" db:"status"`
 	BankDetails     string         `json:"bankDetails" db:"bank_details"`
 	RejectionReason sql.NullString `json:"rejectionReason,omitempty" db:"rejection_reason"`
@@ -0,0 +1,128 @@
+// Package moderation screens AI assistant chats: the user's message before
+// it reaches the model, and the model's reply before it reaches the user. It
+// is pure; the caller logs violations and decides what they cost the account.
+//
+// Messages are refused for a blocked topic, or for trying to get at other
+// users' data: credentials, other accounts' contact details, prompt
+// injection, or SQL that writes. Replies are withheld when they carry
+// credentials or several people's contact details, whatever the question.
+package moderation
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Categories, as logged with a violation.
+const (
+	CategoryBlockedTopic   = "blocked_topic"
+	CategoryDataExtraction = "data_extraction"
+	CategoryDataLeak       = "data_leak" // in a reply
+)
+
+// Violation is why a message or reply was stopped.
+type Violation struct {
+	Category string
+	Rule     string // the pattern or topic that matched
+}
+
+// DefaultTopics are always blocked; Rules.Topics adds to them.
+var DefaultTopics = []string{
+	"bomb", "explosive", "firearm", "ammunition", "methamphetamine", "heroin", "cocaine",
+	"phishing", "malware", "ransomware", "carding", "money laundering",
+}
+
+// maxContacts is how many distinct email addresses or phone numbers a reply
+// may carry: the user's own, and the one customer or supplier asked about.
+const maxContacts = 2
+
+var extraction = []struct {
+	rule string
+	re   *regexp.Regexp
+}{
+	{"credentials", regexp.MustCompile(`(?i)\bpassword[ _]hash(es)?\b|\b(api|secret)[ _-]?keys?\b|\bjwts?\b|\baccess[ _-]?tokens?\b|\botp codes?\b|` +
+		`\b(users?'?s?|their|his|her|someone'?s|others?'?s?)\s+passwords?\b|\bpasswords?\s+(of|for)\s+(the\s+)?(users?|suppliers?|dropshippers?|customers?|accounts?|managers?|admins?)\b`)},
+	{"other_accounts", regexp.MustCompile(`(?i)\b(other|another|all|every|any)\s+(users?|suppliers?|dropshippers?|customers?|accounts?|sellers?)('s?)?\s+(e-?mails?|phones?|phone numbers?|addresses|address|ic|nric|bank|wallets?|balances?|orders?|details|data)\b`)},
+	{"prompt_injection", regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\s+(all\s+|any\s+)?(the\s+)?(previous|prior|above|earlier)\s+(instructions|rules|prompts?)\b|\bsystem\s+prompt\b|\bdeveloper\s+mode\b|\bjailbreak`)},
+	{"sql_write", regexp.MustCompile(`(?i)\b(drop|truncate|alter)\s+(table|database)\b|\bdelete\s+from\b|\binsert\s+into\b|\bupdate\s+\w+\s+set\b|\bgrant\s+\w+`)},
+}
+
+var (
+	bcryptHash = regexp.MustCompile(`\$2[aby]\$\d{2}\$[./A-Za-z0-9]{53}`)
+	jwtToken   = regexp.MustCompile(`\beyJ[\w-]{10,}\.[\w-]{10,}\.[\w-]{10,}`)
+	email      = regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`)
+	phone      = regexp.MustCompile(`(?:\+?60|\b0)1\d[- ]?\d{3,4}[- ]?\d{4}\b`)
+	nonDigit   = regexp.MustCompile(`\D`)
+)
+
+// Rules are the deployment's settings.
+type Rules struct {
+	Topics []string // blocked besides DefaultTopics (AI_BLOCKED_TOPICS)
+}
+
+// CheckInput screens a user's message; nil lets it through.
+func (r Rules) CheckInput(message string) *Violation {
+	for _, e := range extraction {
+		if e.re.MatchString(message) {
+			return &Violation{Category: CategoryDataExtraction, Rule: e.rule}
+		}
+	}
+	lower := strings.ToLower(message)
+	for _, topic := range append(DefaultTopics[:len(DefaultTopics):len(DefaultTopics)], r.Topics...) {
+		topic = strings.ToLower(strings.TrimSpace(topic))
+		if topic != "" && containsWord(lower, topic) {
+			return &Violation{Category: CategoryBlockedTopic, Rule: topic}
+		}
+	}
+	return nil
+}
+
+// CheckOutput screens the model's reply; nil lets it through.
+func (r Rules) CheckOutput(reply string) *Violation {
+	switch {
+	case bcryptHash.MatchString(reply):
+		return &Violation{Category: CategoryDataLeak, Rule: "password_hash"}
+	case jwtToken.MatchString(reply):
+		return &Violation{Category: CategoryDataLeak, Rule: "token"}
+	case distinct(email.FindAllString(reply, -1), strings.ToLower) > maxContacts:
+		return &Violation{Category: CategoryDataLeak, Rule: "emails"}
+	case distinct(phone.FindAllString(reply, -1), digits) > maxContacts:
+		return &Violation{Category: CategoryDataLeak, Rule: "phone_numbers"}
+	}
+	return nil
+}
+
+// containsWord reports whether phrase appears in s on word boundaries
+// ("bomb" but not "bombastic").
+func containsWord(s, phrase string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], phrase)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(phrase)
+		if (start == 0 || !isWordByte(s[start-1])) && (end == len(s) || !isWordByte(s[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 0x80
+}
+
+// distinct counts the values that differ once normalized.
+func distinct(values []string, normalize func(string) string) int {
+	seen := map[string]bool{}
+	for _, v := range values {
+		seen[normalize(v)] = true
+	}
+	return len(seen)
+}
+
+// digits keeps the national part of a phone number, so +6012... and 012... match.
+func digits(s string) string {
+	d := nonDigit.ReplaceAllString(s, "")
+	return strings.TrimPrefix(strings.TrimPrefix(d, "6"), "0")
+}
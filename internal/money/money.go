@@ -0,0 +1,171 @@
+// Package money holds RM amounts as integer sen, so that sums, balances and
+// comparisons are exact. Money reads and writes DECIMAL columns through their
+// text form and marshals to a JSON number with two decimals, so the API and
+// the schema keep their shape.
+//
+// Percentages (commission, SST and discount rates) stay float64; applying one
+// to an amount rounds once, half away from zero, with Percent.
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in sen (RM 1 = 100).
+type Money int64
+
+// Ringgit is RM 1.
+const Ringgit Money = 100
+
+// ErrSyntax is returned for text that is not an amount.
+var ErrSyntax = errors.New("must be an amount like 12.34")
+
+// FromFloat converts a float amount in RM, rounding to the nearest sen.
+func FromFloat(f float64) Money {
+	return Money(math.Round(f * 100))
+}
+
+// Parse reads a decimal amount in RM such as "12.34", "-0.5" or "12". More
+// than two decimals (as in an AVG over a DECIMAL column) round half away from
+// zero.
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return 0, ErrSyntax
+		}
+		return FromFloat(f), nil
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !digits(whole) || !digits(frac) || len(whole) > 16 {
+		return 0, ErrSyntax
+	}
+
+	var sen int64
+	if whole != "" {
+		sen, _ = strconv.ParseInt(whole, 10, 64)
+	}
+	sen *= 100
+	padded := (frac + "000")[:3]
+	tens, _ := strconv.ParseInt(padded[:2], 10, 64)
+	sen += tens
+	if padded[2] >= '5' {
+		sen++
+	}
+	if neg {
+		sen = -sen
+	}
+	return Money(sen), nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Float64 is the amount in RM, for display math and legacy callers only.
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String formats the amount in RM with two decimals, e.g. "-12.30".
+func (m Money) String() string {
+	sign, sen := "", int64(m)
+	if sen < 0 {
+		sign, sen = "-", -sen
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, sen/100, sen%100)
+}
+
+// Mul is the amount for n units.
+func (m Money) Mul(n int) Money {
+	return m * Money(n)
+}
+
+// Percent is p percent of the amount, rounded half away from zero.
+func (m Money) Percent(p float64) Money {
+	return Money(math.Round(float64(m) * p / 100))
+}
+
+// MarshalJSON writes the amount as a JSON number in RM.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON reads a JSON number (or numeric string) in RM; null leaves m
+// unchanged. Unlike Parse it refuses sub-sen amounts such as 12.345: input is
+// never rounded. Errors are *json.UnmarshalTypeError, so the decoder reports
+// the field they came from.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	text := strings.TrimSpace(strings.Trim(s, `"`))
+	v, err := Parse(text)
+	if err != nil || !exact(text) {
+		return &json.UnmarshalTypeError{Value: "number " + text, Type: reflect.TypeOf(*m)}
+	}
+	*m = v
+	return nil
+}
+
+// exact reports whether the amount text has no digits past the sen.
+func exact(s string) bool {
+	if strings.ContainsAny(s, "eE") {
+		f, _ := strconv.ParseFloat(s, 64)
+		sen := f * 100
+		return math.Abs(sen-math.Round(sen)) < 1e-6
+	}
+	_, frac, _ := strings.Cut(s, ".")
+	return len(frac) <= 2 || strings.Trim(frac[2:], "0") == ""
+}
+
+// Scan reads a DECIMAL (as text), an integer or a float column in RM. NULL,
+// as from a SUM over no rows, scans as zero.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.scanText(string(v))
+	case string:
+		return m.scanText(v)
+	case int64:
+		*m = Money(v) * Ringgit
+	case float64:
+		*m = FromFloat(v)
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	return nil
+}
+
+func (m *Money) scanText(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return fmt.Errorf("money: cannot scan %q: %w", s, err)
+	}
+	*m = v
+	return nil
+}
+
+// Value writes the amount as exact decimal text for a DECIMAL column.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
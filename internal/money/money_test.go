@@ -0,0 +1,77 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		err  bool
+	}{
+		{"12.34", 1234, false},
+		{"12", 1200, false},
+		{" 12.3 ", 1230, false},
+		{".5", 50, false},
+		{"+3.10", 310, false},
+		{"-0.5", -50, false},
+		{"12.345", 1235, false}, // half away from zero
+		{"12.344", 1234, false},
+		{"-12.345", -1235, false},
+		{"0.005", 1, false},
+		{"1.5e2", 15000, false},
+		{"", 0, true},
+		{".", 0, true},
+		{"abc", 0, true},
+		{"1.2.3", 0, true},
+		{"12,34", 0, true},
+		{"12345678901234567", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.err {
+			if !errors.Is(err, ErrSyntax) {
+				t.Errorf("Parse(%q) = %d, %v; want ErrSyntax", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		m    Money
+		p    float64
+		want Money
+	}{
+		{1000, 10, 100},
+		{105, 10, 11}, // 10.5 sen rounds up
+		{-105, 10, -11},
+		{104, 10, 10},
+		{333, 15, 50}, // 49.95 sen
+		{1, 50, 1},
+		{9999, 6, 600}, // SST on RM 99.99: 599.94 sen
+		{1234, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Percent(tt.p); got != tt.want {
+			t.Errorf("%s.Percent(%v) = %d, want %d", tt.m, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalJSONRefusesSubSen(t *testing.T) {
+	var m Money
+	if err := json.Unmarshal([]byte("12.345"), &m); err == nil {
+		t.Fatalf("12.345 unmarshalled to %s, want an error", m)
+	}
+	if err := json.Unmarshal([]byte(`"12.340"`), &m); err != nil || m != 1234 {
+		t.Fatalf("\"12.340\" unmarshalled to %s, %v; want 12.34", m, err)
+	}
+}
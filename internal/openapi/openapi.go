@@ -0,0 +1,98 @@
+// Package openapi serves the API contract: the OpenAPI 3 documents of each
+// version at /vN/openapi.json, and Swagger UI over them at /docs.
+//
+// The documents are generated from the routes and the handlers' input and
+// output structs by cmd/openapi, and committed. Regenerate them after
+// changing a route or a request/response type:
+//
+//	go generate ./internal/openapi
+//
+// The generator builds this package, so the files must exist to run it; a
+// deleted one can be restored as "{}".
+package openapi
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate go run ../../cmd/openapi
+
+//go:embed v1.json v2.json
+var specs embed.FS
+
+// swaggerUI is the swagger-ui-dist release the docs page loads. Pinned, so
+// the page does not change under us.
+const swaggerUI = "https://cdn.jsdelivr.net/npm/swagger-ui-dist@5.17.14"
+
+// docsCSP replaces the API's "default-src 'none'" on the docs page: the UI's
+// script and styles come from the CDN, everything else from this origin.
+// Swagger UI sets some styles inline.
+const docsCSP = "default-src 'none'; script-src 'self' " + swaggerUI + "/; " +
+	"style-src 'self' 'unsafe-inline' " + swaggerUI + "/; img-src 'self' data:; " +
+	"connect-src 'self'; frame-ancestors 'none'"
+
+// Spec serves the OpenAPI document of a version.
+//
+// @Summary OpenAPI document
+// @Tags docs
+// @Success 200 {object} object "The OpenAPI 3.0 document"
+func Spec(version apiversion.Version) gin.HandlerFunc {
+	doc, err := specs.ReadFile("v" + version.String() + ".json")
+	if err != nil {
+		panic(fmt.Sprintf("openapi: no document for %s: %v", version.Prefix(), err))
+	}
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", doc)
+	}
+}
+
+// Docs serves the Swagger UI page.
+func Docs(c *gin.Context) {
+	c.Header("Content-Security-Policy", docsCSP)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+// DocsScript starts Swagger UI. It is a file of its own because the CSP
+// allows no inline script.
+func DocsScript(c *gin.Context) {
+	c.Data(http.StatusOK, "text/javascript; charset=utf-8", []byte(docsScript))
+}
+
+var docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>TapToSell API</title>
+<link rel="stylesheet" href="` + swaggerUI + `/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="` + swaggerUI + `/swagger-ui-bundle.js"></script>
+<script src="` + swaggerUI + `/swagger-ui-standalone-preset.js"></script>
+<script src="/docs/init.js"></script>
+</body>
+</html>
+`
+
+// docsScript lists the versions newest first, so the latest opens by default.
+var docsScript = func() string {
+	urls := ""
+	for i := len(apiversion.Supported) - 1; i >= 0; i-- {
+		v := apiversion.Supported[i]
+		urls += fmt.Sprintf("    { url: %q, name: %q },\n", v.Prefix()+"/openapi.json", "v"+v.String())
+	}
+	return `window.ui = SwaggerUIBundle({
+  urls: [
+` + urls + `  ],
+  dom_id: "#swagger-ui",
+  presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+  layout: "StandaloneLayout",
+  persistAuthorization: true,
+});
+`
+}()
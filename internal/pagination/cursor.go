@@ -0,0 +1,152 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keyset (cursor) pagination over (created_at, id), newest first (or oldest
+// first, see Page.OldestFirst). Listings sorted on another column (a price, a
+// search score) page over (that column, id) instead, see Cursor.Value.
+//
+// Offset pagination makes MySQL read and discard every skipped row, so deep
+// pages get slower and slower. A cursor remembers the last row of the previous
+// page and the next query seeks straight past it using the index.
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 100
+)
+
+// ErrInvalidCursor is returned when a client sends a malformed cursor token.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor identifies the last row of a page.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+
+	// Value is the row's sort column, as text, on listings not sorted on
+	// created_at ("" on those that are).
+	Value string
+}
+
+// Encode turns a cursor into an opaque, URL-safe token.
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.CreatedAt.UnixNano(), c.ID)
+	if c.Value != "" {
+		raw = fmt.Sprintf("v%s:%d", c.Value, c.ID)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// key is the sort column's value of the row.
+func (c Cursor) key() interface{} {
+	if c.Value != "" {
+		return c.Value
+	}
+	return c.CreatedAt
+}
+
+// DecodeCursor parses a token produced by Cursor.Encode.
+func DecodeCursor(token string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	if value, ok := strings.CutPrefix(parts[0], "v"); ok {
+		if value == "" {
+			return Cursor{}, ErrInvalidCursor
+		}
+		return Cursor{ID: id, Value: value}, nil
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{CreatedAt: time.Unix(0, nanos), ID: id}, nil
+}
+
+// Page describes the page a client asked for.
+type Page struct {
+	Limit int
+	After *Cursor // nil = first page
+
+	// OldestFirst walks the rows in ascending order instead, for queues.
+	OldestFirst bool
+}
+
+// Parse reads the ?cursor= and ?limit= query values.
+// An empty limit uses DefaultLimit; larger values are capped at MaxLimit.
+func Parse(cursorToken, limitStr string) (Page, error) {
+	p := Page{Limit: DefaultLimit}
+
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return p, errors.New("limit must be a positive integer")
+		}
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+		p.Limit = limit
+	}
+
+	if cursorToken != "" {
+		cur, err := DecodeCursor(cursorToken)
+		if err != nil {
+			return p, err
+		}
+		p.After = &cur
+	}
+	return p, nil
+}
+
+// Where returns the seek condition (starting with " AND") and its arguments.
+// It is empty on the first page. Columns are passed in so callers can use
+// table aliases, e.g. Where("o.created_at", "o.id"); a listing sorted on
+// another column passes that one (and its cursors carry a Value).
+func (p Page) Where(createdAtCol, idCol string) (string, []interface{}) {
+	if p.After == nil {
+		return "", nil
+	}
+	op := "<"
+	if p.OldestFirst {
+		op = ">"
+	}
+	cond := fmt.Sprintf(" AND (%[1]s %[3]s ? OR (%[1]s = ? AND %[2]s %[3]s ?))", createdAtCol, idCol, op)
+	return cond, []interface{}{p.After.key(), p.After.key(), p.After.ID}
+}
+
+// OrderLimit returns the matching ORDER BY / LIMIT clause.
+// It fetches one extra row so we know whether another page exists.
+func (p Page) OrderLimit(createdAtCol, idCol string) string {
+	dir := "DESC"
+	if p.OldestFirst {
+		dir = "ASC"
+	}
+	return fmt.Sprintf(" ORDER BY %[1]s %[3]s, %[2]s %[3]s LIMIT %[4]d", createdAtCol, idCol, dir, p.Limit+1)
+}
+
+// Paginate trims the extra row fetched by OrderLimit and returns the items
+// for this page plus the next cursor token (nil when this is the last page).
+func Paginate[T any](p Page, items []T, key func(T) Cursor) ([]T, *string) {
+	if len(items) <= p.Limit {
+		return items, nil
+	}
+	items = items[:p.Limit]
+	next := key(items[len(items)-1]).Encode()
+	return items, &next
+}
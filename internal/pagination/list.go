@@ -0,0 +1,107 @@
+package pagination
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Every collection endpoint reads its query string the same way, in the
+// JSON:API style SDKs generate clients for:
+//
+//	?page[size]=20&page[cursor]=<nextCursor>&filter[status]=pending&sort=-createdAt
+//
+// The older spellings keep working: ?limit=, ?cursor= and a filter's bare
+// name (?status=pending). The bracketed one wins when both are sent.
+
+// ListSpec declares what a listing accepts besides paging.
+type ListSpec struct {
+	// Filters are the names accepted as filter[name]; any other is refused.
+	Filters []string
+	// Sorts are the accepted ?sort= values, the default first: a column, or
+	// "-column" for descending. Empty means "-createdAt" (newest first) or
+	// "createdAt". Listings page by their sort column, so there is one per list,
+	// unless they name their own orders (search: "relevance", "price_asc"...)
+	// and read List.Sort themselves.
+	Sorts []string
+}
+
+// List is a collection request: its page, filters and sort.
+type List struct {
+	Page
+	Sort    string
+	Filters map[string]string // only the declared ones that were sent
+}
+
+// Filter returns the value of a filter, "" when it was not sent.
+func (l List) Filter(name string) string {
+	return l.Filters[name]
+}
+
+var defaultSorts = []string{"-createdAt", "createdAt"}
+
+// ParseList reads a listing's query string. Page.OldestFirst follows the
+// sort: ascending sorts walk the rows oldest first.
+func ParseList(q url.Values, spec ListSpec) (List, error) {
+	page, err := Parse(firstOf(q, "page[cursor]", "cursor"), firstOf(q, "page[size]", "limit"))
+	if err != nil {
+		if err != ErrInvalidCursor {
+			err = fmt.Errorf("page[size] must be a positive integer")
+		}
+		return List{}, err
+	}
+	if q.Has("page[number]") || q.Has("page[offset]") {
+		return List{}, fmt.Errorf("pages are cursor-based: follow nextCursor with page[cursor]")
+	}
+
+	// Sort
+	sorts := spec.Sorts
+	if len(sorts) == 0 {
+		sorts = defaultSorts
+	}
+	l := List{Page: page, Sort: sorts[0], Filters: map[string]string{}}
+	if s := q.Get("sort"); s != "" {
+		if !slices.Contains(sorts, s) {
+			return List{}, fmt.Errorf("sort must be one of: %s", strings.Join(sorts, ", "))
+		}
+		l.Sort = s
+	}
+	l.OldestFirst = !strings.HasPrefix(l.Sort, "-")
+
+	// Filters
+	for key := range q {
+		name, ok := strings.CutPrefix(key, "filter[")
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, "]")
+		if !ok || !slices.Contains(spec.Filters, name) {
+			return List{}, unknownFilter(key, spec.Filters)
+		}
+	}
+	for _, name := range spec.Filters {
+		if v := strings.TrimSpace(firstOf(q, "filter["+name+"]", name)); v != "" {
+			l.Filters[name] = v
+		}
+	}
+	return l, nil
+}
+
+func unknownFilter(key string, filters []string) error {
+	if len(filters) == 0 {
+		return fmt.Errorf("unknown filter %s: this list has no filters", key)
+	}
+	names := slices.Sorted(slices.Values(filters))
+	return fmt.Errorf("unknown filter %s: this list filters by %s", key, strings.Join(names, ", "))
+}
+
+// firstOf returns the first of the keys that has a value.
+func firstOf(q url.Values, keys ...string) string {
+	for _, k := range keys {
+		if v := q.Get(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
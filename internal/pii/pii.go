@@ -0,0 +1,129 @@
+// Package pii encrypts sensitive columns (IC numbers, SSM numbers, bank
+// details, messaging provider and webhook secrets) at the application layer with AES-256-GCM.
+//
+// Stored values look like "enc:v1:<kid>:<base64 nonce+ciphertext>". The column
+// name is bound in as associated data, so a value copied into another column
+// does not decrypt. Values without the prefix are plaintext from before
+// encryption was enabled; Decrypt returns them unchanged until
+// `api encrypt-pii` has rewritten them.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Encrypted columns, named "table.column" (also the associated data).
+const (
+	UserICNumber          = "users.ic_number"
+	UserSSMNumber         = "users.ssm_number"
+	WithdrawalBankDetails = "withdrawal_requests.bank_details"
+	MessagingSecret       = "messaging_providers.secret"
+	WebhookSecret         = "webhook_endpoints.secret"
+)
+
+const prefix = "enc:v1:"
+
+// Key is one AES-256 key and the ID stored with the values it encrypts.
+type Key struct {
+	ID  string
+	Key []byte // 32 bytes
+}
+
+// Cipher encrypts with the first key and decrypts with any of them, so keys
+// can be rotated: add the new key first, re-run `api encrypt-pii`, then drop
+// the old one.
+type Cipher struct {
+	activeID string
+	aeads    map[string]cipher.AEAD
+}
+
+// New builds a Cipher; keys[0] encrypts. No keys returns a nil *Cipher, which
+// stores and returns plaintext.
+func New(keys []Key) (*Cipher, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	c := &Cipher{activeID: keys[0].ID, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for _, k := range keys {
+		if len(k.Key) != 32 {
+			return nil, fmt.Errorf("pii: key %q must be 32 bytes, got %d", k.ID, len(k.Key))
+		}
+		block, err := aes.NewCipher(k.Key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c.aeads[k.ID] = aead
+	}
+	return c, nil
+}
+
+// Encrypt seals plain for column. Empty values stay empty.
+func (c *Cipher) Encrypt(column, plain string) (string, error) {
+	if c == nil || plain == "" {
+		return plain, nil
+	}
+	aead := c.aeads[c.activeID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), []byte(column))
+	return prefix + c.activeID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value Encrypt produced for column; plaintext passes through.
+func (c *Cipher) Decrypt(column, stored string) (string, error) {
+	if !IsEncrypted(stored) {
+		return stored, nil
+	}
+	if c == nil {
+		return "", errors.New("pii: value is encrypted but no PII_KEYS are configured")
+	}
+	kid, data, ok := strings.Cut(strings.TrimPrefix(stored, prefix), ":")
+	if !ok {
+		return "", errors.New("pii: malformed value")
+	}
+	aead, ok := c.aeads[kid]
+	if !ok {
+		return "", fmt.Errorf("pii: unknown key %q", kid)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("pii: malformed value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(column))
+	if err != nil {
+		return "", fmt.Errorf("pii: decrypt %s: %w", column, err)
+	}
+	return string(plain), nil
+}
+
+// EncryptPtr is Encrypt for nullable columns.
+func (c *Cipher) EncryptPtr(column string, plain *string) (*string, error) {
+	if plain == nil {
+		return nil, nil
+	}
+	v, err := c.Encrypt(column, *plain)
+	return &v, err
+}
+
+// Current reports whether stored is already encrypted with the active key,
+// i.e. whether `api encrypt-pii` can skip it.
+func (c *Cipher) Current(stored string) bool {
+	return c != nil && strings.HasPrefix(stored, prefix+c.activeID+":")
+}
+
+// IsEncrypted reports whether stored was produced by Encrypt.
+func IsEncrypted(stored string) bool {
+	return strings.HasPrefix(stored, prefix)
+}
@@ -0,0 +1,91 @@
+package pii
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func key(id string, b byte) Key {
+	return Key{ID: id, Key: bytes.Repeat([]byte{b}, 32)}
+}
+
+func TestRoundTrip(t *testing.T) {
+	c, err := New([]Key{key("k1", 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := c.Encrypt(UserICNumber, "900101-14-5678")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(stored) || !strings.HasPrefix(stored, "enc:v1:k1:") || strings.Contains(stored, "5678") {
+		t.Fatalf("Encrypt = %q, want an enc:v1:k1: value without the plaintext", stored)
+	}
+	if again, _ := c.Encrypt(UserICNumber, "900101-14-5678"); again == stored {
+		t.Fatal("Encrypt reused a nonce")
+	}
+	plain, err := c.Decrypt(UserICNumber, stored)
+	if err != nil || plain != "900101-14-5678" {
+		t.Fatalf("Decrypt = %q, %v; want the IC number", plain, err)
+	}
+
+	// The column is bound in: the value does not open in another column.
+	if _, err := c.Decrypt(UserSSMNumber, stored); err == nil {
+		t.Fatal("Decrypt opened a value copied into another column")
+	}
+
+	// Legacy plaintext and empty values pass through.
+	if plain, err := c.Decrypt(UserICNumber, "900101-14-5678"); err != nil || plain != "900101-14-5678" {
+		t.Fatalf("Decrypt(plaintext) = %q, %v", plain, err)
+	}
+	if stored, _ := c.Encrypt(UserICNumber, ""); stored != "" {
+		t.Fatalf("Encrypt(\"\") = %q, want empty", stored)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, _ := New([]Key{key("old", 1)})
+	stored, err := old.Encrypt(WithdrawalBankDetails, "Maybank 1234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// New key first: old values still open, new ones use the new key.
+	rotated, err := New([]Key{key("new", 2), key("old", 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := rotated.Decrypt(WithdrawalBankDetails, stored); err != nil || plain != "Maybank 1234567890" {
+		t.Fatalf("Decrypt with rotated keys = %q, %v", plain, err)
+	}
+	if rotated.Current(stored) {
+		t.Fatal("Current reports an old-key value as current")
+	}
+	rewritten, _ := rotated.Encrypt(WithdrawalBankDetails, "Maybank 1234567890")
+	if !rotated.Current(rewritten) {
+		t.Fatalf("Current(%q) = false after re-encrypting", rewritten)
+	}
+
+	// Once the old key is dropped, only rewritten values open.
+	dropped, _ := New([]Key{key("new", 2)})
+	if _, err := dropped.Decrypt(WithdrawalBankDetails, stored); err == nil || !strings.Contains(err.Error(), `unknown key "old"`) {
+		t.Fatalf("Decrypt with the old key dropped: err = %v, want unknown key", err)
+	}
+	if plain, err := dropped.Decrypt(WithdrawalBankDetails, rewritten); err != nil || plain != "Maybank 1234567890" {
+		t.Fatalf("Decrypt rewritten = %q, %v", plain, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if c, err := New(nil); c != nil || err != nil {
+		t.Fatalf("New(nil) = %v, %v; want a nil Cipher", c, err)
+	}
+	if _, err := New([]Key{{ID: "short", Key: []byte("too short")}}); err == nil {
+		t.Fatal("New accepted a key that is not 32 bytes")
+	}
+	var c *Cipher
+	if _, err := c.Decrypt(UserICNumber, "enc:v1:k1:AAAA"); err == nil {
+		t.Fatal("a nil Cipher decrypted an encrypted value")
+	}
+}
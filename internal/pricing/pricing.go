@@ -0,0 +1,68 @@
+// Package pricing works out who makes what on a unit of a product. It is
+// pure: the caller passes the amounts stored on the product.
+//
+// The dropshipper buys at the price (price_to_tts) and sells at up to the SRP,
+// the suggested retail price; the difference is their margin. The platform
+// keeps its commission out of the price (the platform fee) and the supplier is
+// paid the rest. A zero SRP means none was given: there is no margin to show
+// or to check.
+package pricing
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// Breakdown is the split of one unit sold at the SRP.
+type Breakdown struct {
+	PlatformFee    money.Money  `json:"platformFee"`    // the commission on the price
+	SupplierPayout money.Money  `json:"supplierPayout"` // price - platform fee
+	Margin         *money.Money `json:"margin"`         // SRP - price; null without an SRP
+	MarginPercent  *float64     `json:"marginPercent"`  // the margin, in percent of the SRP
+}
+
+// Compute splits a unit at price, with the given SRP and commission rate
+// (percent; nil is no commission).
+func Compute(price, srp money.Money, commissionRate *float64) Breakdown {
+	var rate float64
+	if commissionRate != nil {
+		rate = *commissionRate
+	}
+	b := Breakdown{PlatformFee: price.Percent(rate)}
+	b.SupplierPayout = price - b.PlatformFee
+	if margin, percent, ok := Margin(price, srp); ok {
+		b.Margin, b.MarginPercent = &margin, &percent
+	}
+	return b
+}
+
+// Margin is what the dropshipper makes selling a unit bought at price for
+// srp, and that margin in percent of the SRP (two decimals). ok is false
+// without an SRP.
+func Margin(price, srp money.Money) (margin money.Money, percent float64, ok bool) {
+	if srp <= 0 {
+		return 0, 0, false
+	}
+	margin = srp - price
+	percent = math.Round(float64(margin)/float64(srp)*10000) / 100
+	return margin, percent, true
+}
+
+// Validate checks that an SRP leaves the dropshipper at least minMargin
+// percent of it; with minMargin 0 the SRP must simply cover the price. A zero
+// SRP is not checked.
+func Validate(price, srp money.Money, minMargin float64) error {
+	margin, percent, ok := Margin(price, srp)
+	if !ok {
+		return nil
+	}
+	if margin < 0 {
+		return fmt.Errorf("SRP (RM %s) cannot be below the price (RM %s)", srp, price)
+	}
+	if percent < minMargin {
+		return fmt.Errorf("SRP (RM %s) must leave dropshippers a margin of at least %g%% over the price (RM %s)", srp, minMargin, price)
+	}
+	return nil
+}
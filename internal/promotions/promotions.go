@@ -0,0 +1,191 @@
+// Package promotions prices checkout discounts. It is pure: the caller loads
+// the cart, the promotion rows and their redemption counts (inside the
+// checkout transaction) and this package decides what each promotion is worth.
+//
+// Promotions do not stack. A checkout gets the single best discount among the
+// automatic campaigns it qualifies for and the coupon it entered, if any.
+package promotions
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// Kinds of discount.
+const (
+	KindPercent = "percent"
+	KindFixed   = "fixed"
+)
+
+// Ineligibility reasons. The message is shown to the dropshipper as is.
+var (
+	ErrInactive       = errors.New("this promotion is not active")
+	ErrNotStarted     = errors.New("this promotion has not started yet")
+	ErrExpired        = errors.New("this promotion has expired")
+	ErrUsageLimit     = errors.New("this promotion has been fully redeemed")
+	ErrPerUserLimit   = errors.New("you have already used this promotion")
+	ErrFirstOrderOnly = errors.New("this promotion is only valid on your first order")
+	ErrNoEligibleItem = errors.New("no item in your cart is eligible for this promotion")
+)
+
+// MinSpendError is returned when the eligible items do not reach the minimum.
+type MinSpendError struct {
+	MinSpend money.Money
+	Base     money.Money
+}
+
+func (e *MinSpendError) Error() string {
+	return fmt.Sprintf("a minimum spend of RM %s on eligible items is required (your cart has RM %s)", e.MinSpend, e.Base)
+}
+
+// Line is one cart line.
+type Line struct {
+	ProductID int64
+	Amount    money.Money // unit price x quantity
+	// CategoryIDs are the product's categories and all of their ancestors,
+	// so a promotion on a parent category covers its subcategories.
+	CategoryIDs []int64
+}
+
+// Cart is what a checkout is priced on.
+type Cart struct {
+	Lines      []Line
+	FirstOrder bool // the dropshipper has no earlier non-cancelled order
+	Now        time.Time
+}
+
+// Subtotal is the cart total before discounts.
+func (c Cart) Subtotal() money.Money {
+	var total money.Money
+	for _, l := range c.Lines {
+		total += l.Amount
+	}
+	return total
+}
+
+// Usage counts the redemptions of one promotion on non-cancelled orders.
+type Usage struct {
+	Total  int
+	ByUser int // by the dropshipper checking out
+}
+
+// Applied is a promotion chosen for a checkout.
+type Applied struct {
+	Promotion models.Promotion
+	Amount    money.Money
+}
+
+// Description is the discount line shown on the order.
+func (a Applied) Description() string {
+	if a.Promotion.Code != nil {
+		return fmt.Sprintf("%s (%s)", a.Promotion.Name, *a.Promotion.Code)
+	}
+	return a.Promotion.Name
+}
+
+// Discount returns what p takes off cart, or the reason it does not apply.
+func Discount(p models.Promotion, cart Cart, usage Usage) (money.Money, error) {
+	// 1. --- Window & Limits ---
+	switch {
+	case !p.IsActive:
+		return 0, ErrInactive
+	case cart.Now.Before(p.StartsAt):
+		return 0, ErrNotStarted
+	case p.EndsAt != nil && !cart.Now.Before(*p.EndsAt):
+		return 0, ErrExpired
+	case p.UsageLimit != nil && usage.Total >= *p.UsageLimit:
+		return 0, ErrUsageLimit
+	case p.PerUserLimit != nil && usage.ByUser >= *p.PerUserLimit:
+		return 0, ErrPerUserLimit
+	case p.FirstOrderOnly && !cart.FirstOrder:
+		return 0, ErrFirstOrderOnly
+	}
+
+	// 2. --- Eligible Base ---
+	var base money.Money
+	for _, l := range cart.Lines {
+		if p.CategoryID == nil || contains(l.CategoryIDs, *p.CategoryID) {
+			base += l.Amount
+		}
+	}
+	if base <= 0 {
+		return 0, ErrNoEligibleItem
+	}
+	if base < p.MinSpend {
+		return 0, &MinSpendError{MinSpend: p.MinSpend, Base: base}
+	}
+
+	// 3. --- Amount (never more than the eligible items) ---
+	var amount money.Money
+	switch p.Kind {
+	case KindPercent:
+		amount = base.Percent(p.Value)
+		if p.MaxDiscount != nil && amount > *p.MaxDiscount {
+			amount = *p.MaxDiscount
+		}
+	case KindFixed:
+		amount = money.FromFloat(p.Value)
+	}
+	return min(amount, base), nil
+}
+
+// Best picks the largest discount among the eligible candidates. Ties go to
+// the earlier candidate. It returns nil when none applies.
+func Best(candidates []models.Promotion, cart Cart, usage map[int64]Usage) *Applied {
+	var best *Applied
+	for _, p := range candidates {
+		amount, err := Discount(p, cart, usage[p.ID])
+		if err != nil || amount <= 0 {
+			continue
+		}
+		if best == nil || amount > best.Amount {
+			best = &Applied{Promotion: p, Amount: amount}
+		}
+	}
+	return best
+}
+
+// Validate checks the fields a manager sets on a promotion.
+func Validate(p models.Promotion) error {
+	switch p.Kind {
+	case KindPercent:
+		if p.Value <= 0 || p.Value > 100 {
+			return errors.New("value must be between 0 and 100 for a percent promotion")
+		}
+	case KindFixed:
+		if p.Value <= 0 {
+			return errors.New("value must be greater than 0")
+		}
+		if p.MaxDiscount != nil {
+			return errors.New("maxDiscount only applies to percent promotions")
+		}
+	default:
+		return fmt.Errorf("kind must be %q or %q", KindPercent, KindFixed)
+	}
+	switch {
+	case p.MaxDiscount != nil && *p.MaxDiscount <= 0:
+		return errors.New("maxDiscount must be greater than 0")
+	case p.MinSpend < 0:
+		return errors.New("minSpend cannot be negative")
+	case p.UsageLimit != nil && *p.UsageLimit < 1:
+		return errors.New("usageLimit must be at least 1")
+	case p.PerUserLimit != nil && *p.PerUserLimit < 1:
+		return errors.New("perUserLimit must be at least 1")
+	case p.EndsAt != nil && !p.EndsAt.After(p.StartsAt):
+		return errors.New("endsAt must be after startsAt")
+	}
+	return nil
+}
+
+func contains(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
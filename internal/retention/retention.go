@@ -0,0 +1,133 @@
+// Package retention hard-deletes soft-deleted rows once they are older than
+// their configured window (see config.Retention).
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/go-sql-driver/mysql"
+)
+
+// batchSize is how many candidate IDs are read per round trip.
+const batchSize = 500
+
+// Result counts what one table's purge did.
+type Result struct {
+	Purged int64 // rows hard-deleted
+	Kept   int64 // rows past the window but still referenced (e.g. by order_items)
+}
+
+// Purge runs every table whose window is non-zero and returns the per-table results.
+// A row that other tables still reference is kept and counted, not treated as an error.
+func Purge(ctx context.Context, db *sql.DB, policy config.Retention) (map[string]Result, error) {
+	windows := []struct {
+		table  string
+		window time.Duration
+	}{
+		// Children before parents: inventory items and orders can reference products and users.
+		{store.TableInventoryItems, policy.InventoryItems},
+		{store.TableOrders, policy.Orders},
+		{store.TableProducts, policy.Products},
+		{store.TableUsers, policy.Users},
+	}
+
+	results := make(map[string]Result)
+	for _, w := range windows {
+		if w.window <= 0 {
+			continue
+		}
+		res, err := purgeTable(ctx, db, w.table, time.Now().Add(-w.window))
+		results[w.table] = res
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// purgeTable deletes rows of table soft-deleted before cutoff, one row at a time
+// so a single referenced row cannot block the rest. It walks IDs in order, so
+// rows it had to keep are not revisited in the same run.
+func purgeTable(ctx context.Context, db *sql.DB, table string, cutoff time.Time) (Result, error) {
+	var res Result
+	var lastID int64
+
+	for {
+		// Table names come from the store constants, never from input.
+		rows, err := db.QueryContext(ctx,
+			"SELECT id FROM "+table+" WHERE deleted_at IS NOT NULL AND deleted_at < ? AND id > ? ORDER BY id LIMIT ?",
+			cutoff, lastID, batchSize)
+		if err != nil {
+			return res, err
+		}
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return res, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return res, err
+		}
+
+		for _, id := range ids {
+			lastID = id
+			// Re-check deleted_at: the row may have been restored meanwhile.
+			_, err := db.ExecContext(ctx,
+				"DELETE FROM "+table+" WHERE id = ? AND deleted_at IS NOT NULL AND deleted_at < ?", id, cutoff)
+			switch {
+			case err == nil:
+				res.Purged++
+			case isForeignKeyViolation(err):
+				res.Kept++
+			default:
+				return res, err
+			}
+		}
+
+		if len(ids) < batchSize {
+			return res, nil
+		}
+	}
+}
+
+// isForeignKeyViolation reports MySQL error 1451 (row is still referenced).
+func isForeignKeyViolation(err error) bool {
+	var myErr *mysql.MySQLError
+	return errors.As(err, &myErr) && myErr.Number == 1451
+}
+
+// Run purges on every tick of policy.Interval until ctx is cancelled.
+func Run(ctx context.Context, db *sql.DB, policy config.Retention) {
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	log.Printf("🧹 Retention Job Started: purging soft-deleted rows every %s", policy.Interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("🧹 Retention Job Stopped")
+			return
+		case <-ticker.C:
+			results, err := Purge(ctx, db, policy)
+			for table, r := range results {
+				if r.Purged > 0 || r.Kept > 0 {
+					log.Printf("[Retention] %s: purged %d, kept %d still referenced", table, r.Purged, r.Kept)
+				}
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Printf("[Retention] Purge failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package risk scores checkouts and wallet top-ups for fraud. It is pure: the
+// caller counts the user's recent activity and passes it in with the email
+// and shipping address.
+//
+// Each signal adds its weight to the score; at Rules.ReviewScore or above the
+// operation waits for a manager instead of going through. A velocity signal
+// alone reaches the default threshold, the others only together.
+package risk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Signal weights.
+const (
+	weightOrderVelocity   = 50
+	weightTopupFailures   = 50
+	weightAddressMismatch = 30
+	weightDisposableEmail = 30
+)
+
+// Reasons, as stored with a review.
+const (
+	ReasonOrderVelocity   = "order_velocity"   // too many orders in the last hour
+	ReasonTopupFailures   = "topup_failures"   // too many failed top-ups in the last day
+	ReasonAddressMismatch = "address_mismatch" // the postcode is not in the state
+	ReasonDisposableEmail = "disposable_email" // the account's email is a throwaway address
+)
+
+// Rules are the thresholds from the configuration (see config.Risk).
+type Rules struct {
+	MaxOrdersPerHour  int      // orders in the last hour before velocity counts
+	MaxFailedTopups   int      // failed top-ups in the last 24 hours before they count
+	ReviewScore       int      // the score that sends an operation to review
+	DisposableDomains []string // added to the built-in list
+}
+
+// Signals are what is known about one checkout or top-up.
+type Signals struct {
+	RecentOrders int    // the user's orders in the last hour, this one excluded
+	FailedTopups int    // the user's failed top-ups in the last 24 hours
+	Email        string // the account's email
+	// The shipping address of a checkout; empty on top-ups.
+	State    string
+	Postcode string
+}
+
+// Assessment is the outcome of scoring one operation.
+type Assessment struct {
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons"`
+	Review  bool     `json:"review"` // hold it for a manager
+}
+
+// Assess scores s under the rules.
+func (r Rules) Assess(s Signals) Assessment {
+	a := Assessment{Reasons: []string{}}
+	add := func(weight int, reason string) {
+		a.Score += weight
+		a.Reasons = append(a.Reasons, reason)
+	}
+	if s.RecentOrders >= r.MaxOrdersPerHour {
+		add(weightOrderVelocity, ReasonOrderVelocity)
+	}
+	if s.FailedTopups >= r.MaxFailedTopups {
+		add(weightTopupFailures, ReasonTopupFailures)
+	}
+	if !PostcodeInState(s.Postcode, s.State) {
+		add(weightAddressMismatch, ReasonAddressMismatch)
+	}
+	if r.Disposable(s.Email) {
+		add(weightDisposableEmail, ReasonDisposableEmail)
+	}
+	a.Review = a.Score >= r.ReviewScore
+	return a
+}
+
+// disposableDomains are common throwaway email providers.
+var disposableDomains = []string{
+	"10minutemail.com", "discard.email", "dispostable.com", "fakeinbox.com",
+	"getnada.com", "guerrillamail.com", "maildrop.cc", "mailinator.com",
+	"mintemail.com", "moakt.com", "mohmal.com", "sharklasers.com",
+	"temp-mail.org", "tempmail.com", "tempmail.net", "throwawaymail.com",
+	"trashmail.com", "yopmail.com",
+}
+
+// Disposable reports whether email is at a throwaway provider (or one of its
+// subdomains).
+func (r Rules) Disposable(email string) bool {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for _, list := range [][]string{disposableDomains, r.DisposableDomains} {
+		for _, d := range list {
+			if domain == d || strings.HasSuffix(domain, "."+d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// postcodeStates maps the first two digits of a Malaysian postcode to the
+// states it is used in; knownStates are the states in it.
+var (
+	postcodeStates = map[string][]string{}
+	knownStates    = map[string]bool{}
+)
+
+func init() {
+	ranges := []struct {
+		from, to int
+		states   []string
+	}{
+		{1, 2, []string{"perlis"}},
+		{5, 9, []string{"kedah"}},
+		{10, 14, []string{"pulaupinang"}},
+		{15, 18, []string{"kelantan"}},
+		{20, 24, []string{"terengganu"}},
+		{25, 28, []string{"pahang"}},
+		{30, 36, []string{"perak"}},
+		{39, 39, []string{"pahang"}},
+		{40, 48, []string{"selangor"}},
+		{49, 49, []string{"pahang"}},
+		{50, 60, []string{"kualalumpur"}},
+		{62, 62, []string{"putrajaya"}},
+		{63, 64, []string{"selangor"}},
+		{68, 68, []string{"selangor", "kualalumpur"}},
+		{69, 69, []string{"pahang"}},
+		{70, 73, []string{"negerisembilan"}},
+		{75, 78, []string{"melaka"}},
+		{79, 86, []string{"johor"}},
+		{87, 87, []string{"labuan"}},
+		{88, 91, []string{"sabah"}},
+		{93, 98, []string{"sarawak"}},
+	}
+	for _, r := range ranges {
+		for p := r.from; p <= r.to; p++ {
+			postcodeStates[fmt.Sprintf("%02d", p)] = r.states
+		}
+		for _, s := range r.states {
+			knownStates[s] = true
+		}
+	}
+}
+
+// stateAliases are other spellings of the states, normalized.
+var stateAliases = map[string]string{
+	"penang":                        "pulaupinang",
+	"pinang":                        "pulaupinang",
+	"kl":                            "kualalumpur",
+	"wilayahpersekutuankualalumpur": "kualalumpur",
+	"wpkualalumpur":                 "kualalumpur",
+	"wilayahpersekutuanputrajaya":   "putrajaya",
+	"wpputrajaya":                   "putrajaya",
+	"wilayahpersekutuanlabuan":      "labuan",
+	"wplabuan":                      "labuan",
+	"malacca":                       "melaka",
+	"nsembilan":                     "negerisembilan",
+}
+
+// normalizeState lower-cases a state name, keeps only its letters and maps
+// aliases to one name.
+func normalizeState(state string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(state) {
+		if r >= 'a' && r <= 'z' {
+			b.WriteRune(r)
+		}
+	}
+	if alias, ok := stateAliases[b.String()]; ok {
+		return alias
+	}
+	return b.String()
+}
+
+// PostcodeInState reports whether a Malaysian postcode belongs to state. What
+// it cannot check (a missing or malformed postcode, an unknown prefix or state
+// name) passes.
+func PostcodeInState(postcode, state string) bool {
+	postcode = strings.TrimSpace(postcode)
+	if len(postcode) != 5 || strings.Trim(postcode, "0123456789") != "" {
+		return true
+	}
+	states, ok := postcodeStates[postcode[:2]]
+	if !ok {
+		return true
+	}
+	name := normalizeState(state)
+	if !knownStates[name] {
+		return true
+	}
+	for _, s := range states {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
@@ -2,94 +2,245 @@ package routes
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/01moynul/taptosell-golang/internal/apierror"
+	"github.com/01moynul/taptosell-golang/internal/apiversion"
+	"github.com/01moynul/taptosell-golang/internal/audit"
 	"github.com/01moynul/taptosell-golang/internal/handlers"
+	"github.com/01moynul/taptosell-golang/internal/logging"
 	"github.com/01moynul/taptosell-golang/internal/middleware"
+	"github.com/01moynul/taptosell-golang/internal/openapi"
+	"github.com/01moynul/taptosell-golang/internal/store"
 	"github.com/gin-gonic/gin"
 )
 
-// --- Secure CORS Middleware ---
-func CORSMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+func SetupRouter(h *handlers.Handlers) *gin.Engine {
+	router := gin.New()
+	// Request lines are info-level: LOG_LEVEL=warn (or the runtime setting) silences them.
+	router.Use(gin.LoggerWithConfig(gin.LoggerConfig{
+		Skip: func(*gin.Context) bool { return !logging.Enabled(logging.Info) },
+	}))
 
-		c.Next()
-	}
-}
+	// Report binding errors by JSON field name ("price", not "Price"), and
+	// register the custom binding tags (myphone, postcode, slug, price).
+	apierror.UseJSONFieldNames()
+	apierror.RegisterValidators()
 
-func SetupRouter(h *handlers.Handlers) *gin.Engine {
-	router := gin.Default()
+	// Every response (including CORS rejections) carries an X-Request-ID.
+	router.Use(middleware.RequestID())
+	// /v1 and /v2 share the routes below; the version picks response shapes.
+	router.Use(middleware.APIVersion())
+	// Error messages follow Accept-Language (English or Malay).
+	router.Use(middleware.Locale())
+	// One trace span per request; DB and AI spans nest under it.
+	router.Use(middleware.Tracing())
+	// Panics and 5xx responses go to the error reporter (Sentry or the log).
+	router.Use(middleware.Recovery(h.Reporter))
+
+	// Hardening headers on everything, uploads included.
+	router.Use(middleware.SecurityHeaders(h.Config.HTTP.HSTS))
 
 	// --- APPLY THE CORS GUARD ---
-	router.Use(CORSMiddleware())
+	router.Use(middleware.CORS(h.Config.HTTP.CORSOrigins))
+
+	// 1. SERVE UPLOADS STATICALLY (public catalogue images only; documents live in DOCUMENT_DIR)
+	router.Static("/uploads", h.Config.Storage.UploadDir)
+
+	// 2. API DOCS: Swagger UI over each version's /vN/openapi.json
+	router.GET("/docs", openapi.Docs)
+	router.GET("/docs/init.js", openapi.DocsScript)
 
-	// 1. SERVE UPLOADS STATICALLY
-	router.Static("/uploads", "./uploads")
+	// One user cache serves every version (none with AUTH_REVOCATION_CHECK).
+	userCacheTTL := h.Config.Auth.UserCacheTTL
+	if h.Config.Auth.RevocationCheck {
+		userCacheTTL = 0
+	}
+	userStates := middleware.NewUserStates(h.DB, userCacheTTL)
+	for _, version := range apiversion.Supported {
+		registerAPI(router, h, version, userStates)
+	}
+
+	return router
+}
 
-	v1 := router.Group("/v1")
+// registerAPI mounts the API under the version's prefix. Handlers whose
+// responses differ between versions branch on apiversion.From.
+func registerAPI(router *gin.Engine, h *handlers.Handlers, version apiversion.Version, userStates *middleware.UserStates) {
+	api := router.Group(version.Prefix())
+	// Deprecation and Sunset headers once API_V1_DEPRECATED_AT is set.
+	if version == apiversion.V1 {
+		api.Use(middleware.Deprecated(h.Config.HTTP.V1DeprecatedAt, h.Config.HTTP.V1Sunset, h.Config.HTTP.BaseURL+apiversion.Latest.Prefix()))
+	}
+	// gzip/Brotli for API responses (uploads are already-compressed media).
+	api.Use(middleware.Compress())
+	// JSON bodies are capped at HTTP_MAX_JSON_BYTES, uploads at UPLOAD_MAX_REQUEST_BYTES;
+	// mutations must send JSON (or multipart on the upload routes).
+	api.Use(middleware.BodyLimit(h.Config.HTTP.MaxJSONBytes, h.Config.Storage.MaxRequestBytes))
+	api.Use(middleware.RequireContentType("application/json", "multipart/form-data"))
+	// Default query budget for every API route; slow routes override it below.
+	api.Use(middleware.Timeout(10 * time.Second))
 	{
 		// --- Ping Route (Public) ---
-		v1.GET("/ping", func(c *gin.Context) {
+		api.GET("/ping", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"message": "pong!"})
 		})
 
+		// --- Platform Status & Changelog (Public; managed under /manager/status-entries) ---
+		api.GET("/status", h.GetPlatformStatus)
+		api.GET("/changelog", h.GetChangelog)
+		// The version's OpenAPI document (browsable at /docs).
+		api.GET("/openapi.json", openapi.Spec(version))
+
 		// --- Auth Routes (Public) ---
-		v1.POST("/register/dropshipper", h.RegisterDropshipper)
-		v1.POST("/register/supplier", h.RegisterSupplier)
-		v1.POST("/login", h.Login)
-		v1.POST("/auth/verify-email", h.VerifyEmail)
-		v1.POST("/auth/resend-code", h.ResendVerificationEmail)
+		// Routes that create accounts or send email need a CAPTCHA token while
+		// the 'captcha_enabled' setting is on; GET /captcha tells the frontend.
+		requireCaptcha := middleware.Captcha(h.Settings, h.Captcha)
+		api.GET("/captcha", h.GetCaptchaConfig)
+		api.POST("/register/dropshipper", requireCaptcha, h.RegisterDropshipper)
+		api.POST("/register/supplier", requireCaptcha, h.RegisterSupplier)
+		api.POST("/login", requireCaptcha, h.Login)
+		api.POST("/auth/verify-email", h.VerifyEmail)
+		api.POST("/auth/resend-code", requireCaptcha, h.ResendVerificationEmail)
+		api.POST("/auth/forgot-password", requireCaptcha, h.ForgotPassword)
+		api.POST("/auth/reset-password", h.ResetPassword)
 
 		// --- Public Product Data ---
-		v1.GET("/products/search", h.SearchProducts)
-		v1.GET("/categories", h.GetAllCategories) // Public Read
-		v1.GET("/brands", h.GetAllBrands)         // Public Read
-		v1.GET("/subscriptions/plans", h.GetSubscriptionPlans)
-
-		// --- Protected Routes (Login Required) ---
-		auth := v1.Group("/")
-		auth.Use(middleware.AuthMiddleware(h.DB))
+		api.GET("/products/search", h.SearchProducts)
+		api.GET("/products/changes", h.GetProductChanges)
+		api.GET("/categories", h.GetAllCategories) // Public Read
+		api.GET("/brands", h.GetAllBrands)         // Public Read
+		api.GET("/tags", h.GetAllTags)             // Public Read
+		api.GET("/subscriptions/plans", h.GetSubscriptionPlans)
+
+		// --- Private Documents (signed links only; see GetMyDocuments) ---
+		api.GET("/documents/:name", h.ServeDocument)
+		api.GET("/exports/files/:name", h.DownloadExport) // signed links from GET /exports/:id
+
+		// --- Public IDs ---
+		// :id of products, orders and users accepts the public UUID (and the
+		// numeric ID while LEGACY_NUMERIC_IDS is on); handlers see the numeric ID.
+		legacyIDs := h.Config.HTTP.LegacyNumericIDs
+		productID := middleware.PublicID(h.DB, store.TableProducts, "id", legacyIDs)
+		orderID := middleware.PublicID(h.DB, store.TableOrders, "id", legacyIDs)
+		userID := middleware.PublicID(h.DB, store.TableUsers, "id", legacyIDs)
+		cartProductID := middleware.PublicID(h.DB, store.TableProducts, "product_id", legacyIDs)
+		watchedProductID := middleware.PublicID(h.DB, store.TableProducts, "productId", legacyIDs)
+
+		// --- Public Product Reviews ---
+		api.GET("/products/:id/reviews", productID, h.GetProductReviews)
+		api.GET("/products/:id/questions", productID, h.GetProductQuestions)
+
+		// --- Supplier Storefronts (Public) ---
+		api.GET("/suppliers/:id/profile", userID, h.GetSupplierProfile)
+
+		// --- Public Storefront Reads (CDN-cacheable; the same for every caller) ---
+		public := api.Group("/public")
+		public.Use(middleware.PublicCache(h.Config.HTTP.PublicCacheMaxAge, h.Config.HTTP.PublicCacheStale))
+		{
+			public.GET("/products/:id", productID, h.GetPublicProduct)
+			public.GET("/categories", h.GetAllCategories)
+			public.GET("/categories/:slug", h.GetPublicCategory)
+		}
+
+		// --- Request Captures ---
+		// Raw requests and responses of money-moving routes are kept for disputes.
+		// Payments are captured for inspection only; webhook routes, once added,
+		// use audit.KindWebhook and can be replayed by admins.
+		capturePayment := middleware.Capture(h.Audit, audit.KindPayment)
+
+		// --- Protected Routes (Login Required, Any Role) ---
+		// Every group below checks the role in middleware; handlers only check
+		// ownership. LoadRole sets userRole for handlers that vary by role.
+		// userStates is shared by every group and version.
+		auth := api.Group("/")
+		auth.Use(middleware.AuthMiddleware(h.DB, h.Settings, h.Status, userStates))
+		auth.Use(middleware.LoadRole(h.DB))
 		{
-			auth.POST("/upload", h.UploadFile)
+			auth.POST("/upload", middleware.Timeout(60*time.Second), h.UploadFile)
 			auth.GET("/profile/me", func(c *gin.Context) {
 				userID, _ := c.Get("userID")
 				c.JSON(http.StatusOK, gin.H{"message": "This is a protected route", "yourUserID": userID})
 			})
 
 			// AI Chat
-			auth.POST("/ai/chat", h.ChatAI)
+			auth.POST("/ai/chat", middleware.Timeout(60*time.Second), h.ChatAI)
 
 			// Notifications
 			auth.GET("/notifications", h.GetMyNotifications)
 			auth.PATCH("/notifications/:id/read", h.MarkNotificationAsRead)
+			auth.GET("/notifications/:id/items", h.GetNotificationItems) // the ones a summary groups
 
-			// Supplier
-			auth.POST("/supplier/documents", h.UploadSupplierDocuments)
-			auth.POST("/products", h.CreateProduct)
-			auth.GET("/products/supplier/me", h.GetMyProducts)
-			auth.GET("/products/:id", h.GetProduct)
-			auth.PUT("/products/:id", h.UpdateProduct)
-			auth.DELETE("/products/:id", h.DeleteProduct)
+			// Catalogue products by barcode, for scanning the item in hand
+			auth.GET("/products/lookup", h.LookupBarcode)
+
+			// Product detail: the edit form for the owning supplier and staff,
+			// the catalogue view for everyone else (see GetProduct)
+			auth.GET("/products/:id", productID, h.GetProduct)
+			// Revision history of every update, for the owning supplier and managers
+			auth.GET("/products/:id/revisions", productID, middleware.RequireRole(h.DB, "supplier", "manager", "administrator"), h.GetProductRevisions)
+
+			// Storefront reads in one round trip (GRAPHQL_ENABLED); REST stays the write path
+			if h.Config.HTTP.GraphQL {
+				auth.POST("/graphql", h.GraphQL)
+			}
+			// Several calls in one round trip (dashboards); each runs with the caller's auth
+			auth.POST("/batch", middleware.Timeout(30*time.Second), h.Batch(router))
+
+			// Async CSV exports (orders, catalog, wallet); the handler checks the role per kind
+			auth.POST("/exports", h.CreateExport)
+			auth.GET("/exports", h.GetMyExports)
+			auth.GET("/exports/:id", h.GetExport)
+
+			// Wallet events (wallet.credited / wallet.debited) for external accounting tools:
+			// signed webhooks, and an SSE stream the route's Timeout ends (clients reconnect)
+			walletOwner := middleware.RequireRole(h.DB, "dropshipper", "supplier")
+			auth.POST("/webhooks", walletOwner, h.CreateWebhook)
+			auth.GET("/webhooks", walletOwner, h.GetMyWebhooks)
+			auth.DELETE("/webhooks/:id", walletOwner, h.DeleteWebhook)
+			auth.GET("/webhooks/:id/deliveries", walletOwner, h.GetWebhookDeliveries)
+			auth.GET("/wallet/stream", walletOwner, middleware.Timeout(30*time.Minute), h.StreamWallet)
+
+			// Dispute evidence from either party (the handler checks which dispute)
+			auth.POST("/disputes/:id/evidence", middleware.RequireRole(h.DB, "dropshipper", "supplier"), middleware.Timeout(60*time.Second), h.AddDisputeEvidence)
+		}
+
+		// --- Supplier ---
+		supplier := api.Group("/")
+		supplier.Use(middleware.AuthMiddleware(h.DB, h.Settings, h.Status, userStates))
+		supplier.Use(middleware.SupplierMiddleware(h.DB))
+		{
+			supplier.POST("/supplier/documents", middleware.Timeout(60*time.Second), h.UploadSupplierDocuments)
+			supplier.GET("/supplier/documents", h.GetMyDocuments)
+			supplier.POST("/products", h.CreateProduct)
+			supplier.POST("/products/import", middleware.Timeout(2*time.Minute), h.ImportProducts) // CSV, all or nothing
+			supplier.GET("/products/supplier/me", h.GetMyProducts)
+			supplier.PUT("/products/:id", productID, h.UpdateProduct)
+			supplier.DELETE("/products/:id", productID, h.DeleteProduct)
+			supplier.POST("/products/:id/restore", productID, h.RestoreMyProduct)
+			supplier.POST("/products/:id/resubmit", productID, h.ResubmitProduct) // a rejected product, back to review
+			supplier.POST("/products/:id/duplicate", productID, h.DuplicateProduct) // into a new draft
+			supplier.POST("/products/:id/images", productID, middleware.Timeout(60*time.Second), h.UploadProductImages)
+			supplier.POST("/supplier/images/process", middleware.Timeout(90*time.Second), h.ProcessImage) // original + normalized copy
+			supplier.GET("/supplier/images", h.GetProcessedImages)
+			// Stock-only changes, each recorded in the product's stock history
+			supplier.PATCH("/products/:id/stock", productID, h.AdjustProductStock)
+			supplier.PATCH("/products/:id/variants/:variantId/stock", productID, h.AdjustProductStock)
+			supplier.GET("/products/:id/stock-movements", productID, h.GetStockMovements)
+			supplier.GET("/supplier/skus/check", h.CheckSKU)
 
 			// Supplier Wallet
-			auth.GET("/supplier/wallet", h.GetSupplierWallet)
-			auth.POST("/supplier/wallet/request-withdrawal", h.RequestWithdrawal)
-			auth.POST("/products/:id/request-price-change", h.RequestPriceChange)
+			supplier.GET("/supplier/wallet", h.GetSupplierWallet)
+			supplier.POST("/supplier/wallet/request-withdrawal", h.RequestWithdrawal)
+			supplier.POST("/products/:id/request-price-change", productID, h.RequestPriceChange)
 
 			// [NEW] Supplier Order Fulfillment
 			// This route allows suppliers to fulfill orders containing their items
-			auth.PATCH("/supplier/orders/:id/ship", h.UpdateOrderTracking)
+			supplier.PATCH("/supplier/orders/:id/ship", orderID, h.UpdateOrderTracking)
+			supplier.POST("/supplier/orders/shipments/import", h.ImportShipments) // CSV of tracking numbers, all or nothing
 
 			// Supplier Inventory
-			supplierInventory := auth.Group("/supplier/inventory")
+			supplierInventory := supplier.Group("/supplier/inventory")
 			{
 				supplierInventory.POST("", h.CreateInventoryItem)
 				supplierInventory.GET("", h.GetMyInventoryItems)
@@ -101,14 +252,43 @@ func SetupRouter(h *handlers.Handlers) *gin.Engine {
 				supplierInventory.POST("/brands", h.CreateInventoryBrand)
 				supplierInventory.GET("/brands", h.GetMyInventoryBrands)
 			}
-			auth.GET("/supplier/dashboard-stats", h.GetSupplierStats)
-			auth.GET("/supplier/orders", h.GetSupplierSales)
-			auth.GET("/supplier/orders/:id", h.GetSupplierOrderDetails)
+			supplier.GET("/supplier/dashboard-stats", h.GetSupplierStats)
+			supplier.GET("/supplier/orders", h.GetSupplierSales)
+			supplier.GET("/supplier/orders/export", middleware.Timeout(2*time.Minute), h.ExportSupplierOrders) // CSV, streamed
+			supplier.GET("/supplier/orders/:id", orderID, h.GetSupplierOrderDetails)
+			supplier.GET("/supplier/orders/:id/invoice", orderID, h.GetSupplierInvoice)
+			supplier.GET("/supplier/orders/:id/packing-slip", orderID, h.GetSupplierPackingSlip) // blind unless the dropshipper opted out
+
+			// Reviews of the supplier's products
+			supplier.GET("/supplier/reviews", h.GetSupplierReviews)
+			supplier.POST("/supplier/reviews/:id/reply", h.ReplyToReview)
+			supplier.POST("/supplier/reviews/:id/report", h.ReportReview)
+
+			// Vacation mode (pauses orders; auto-replies to questions)
+			supplier.GET("/supplier/vacation", h.GetVacation)
+			supplier.PUT("/supplier/vacation", h.UpdateVacation)
+
+			// Shop-wide processing time (handling days & daily cutoff)
+			supplier.GET("/supplier/processing-time", h.GetProcessingTime)
+			supplier.PUT("/supplier/processing-time", h.UpdateProcessingTime)
+
+			// SST registration number (printed on invoices)
+			supplier.GET("/supplier/tax-registration", h.GetTaxRegistration)
+			supplier.PUT("/supplier/tax-registration", h.UpdateTaxRegistration)
+
+			// Questions on the supplier's products
+			supplier.GET("/supplier/questions", h.GetSupplierQuestions)
+			supplier.POST("/supplier/questions/:id/answer", h.AnswerQuestion)
+
+			// Disputes on the supplier's orders
+			supplier.GET("/supplier/disputes", h.GetSupplierDisputes)
+			supplier.GET("/supplier/disputes/:id", h.GetSupplierDispute)
+			supplier.POST("/supplier/disputes/:id/respond", h.RespondToDispute)
 		}
 
 		// --- Manager-Only Routes ---
-		manager := v1.Group("/manager")
-		manager.Use(middleware.AuthMiddleware(h.DB))
+		manager := api.Group("/manager")
+		manager.Use(middleware.AuthMiddleware(h.DB, h.Settings, h.Status, userStates))
 		manager.Use(middleware.ManagerMiddleware(h.DB))
 		{
 			// Dashboard Stats
@@ -122,8 +302,9 @@ func SetupRouter(h *handlers.Handlers) *gin.Engine {
 
 			// Approvals
 			manager.GET("/products/pending", h.GetPendingProducts)
-			manager.PATCH("/products/:id/approve", h.ApproveProduct)
-			manager.PATCH("/products/:id/reject", h.RejectProduct)
+			manager.PATCH("/products/:id/approve", productID, h.ApproveProduct)
+			manager.PATCH("/products/:id/reject", productID, h.RejectProduct)
+			manager.PUT("/products/:id", productID, h.UpdatePendingProduct) // fix-ups during review
 
 			manager.GET("/withdrawal-requests", h.GetWithdrawalRequests)
 			manager.PATCH("/withdrawal-requests/:id", h.ProcessWithdrawalRequest)
@@ -131,42 +312,157 @@ func SetupRouter(h *handlers.Handlers) *gin.Engine {
 			manager.GET("/price-requests", h.GetPriceAppeals)
 			manager.PATCH("/price-requests/:id", h.ProcessPriceAppeal)
 
+			// Review moderation (abusive content)
+			manager.GET("/reviews", h.GetReviewsForModeration)
+			manager.PATCH("/reviews/:id/moderate", h.ModerateReview)
+			manager.GET("/questions", h.GetQuestionsForModeration)
+			manager.PATCH("/questions/:id/moderate", h.ModerateQuestion)
+
+			// Disputes: review queue & adjudication (moves money)
+			manager.GET("/disputes", h.GetDisputes)
+			manager.GET("/disputes/:id", h.GetDispute)
+			manager.GET("/disputes/:id/order", h.GetDisputeOrder) // the unfiltered order, logged
+			manager.PATCH("/disputes/:id/resolve", capturePayment, h.ResolveDispute)
+
+			// Order lines past their ships-by date
+			manager.GET("/late-shipments", h.GetLateShipments)
+
+			// SST rates per category
+			manager.GET("/tax-rates", h.GetTaxRates)
+			manager.PUT("/tax-rates/:categoryId", h.SetTaxRate)
+			manager.DELETE("/tax-rates/:categoryId", h.DeleteTaxRate)
+
+			// Wallet ledger rows whose balance_after drifted
+			manager.GET("/wallet-reconciliation", h.GetWalletReconciliation)
+
+			// Checkouts and top-ups held by the risk checks
+			manager.GET("/risk-reviews", h.GetRiskReviews)
+			manager.PATCH("/risk-reviews/:id", h.ReviewRisk)
+
+			// Duplicate listings across suppliers: merge, reject or dismiss
+			manager.GET("/duplicates", h.GetDuplicates)
+			manager.POST("/duplicates/:id/resolve", h.ResolveDuplicate)
+
+			// AI assistant moderation: violation log & suspensions
+			manager.GET("/ai/violations", h.GetAIViolations)
+			manager.DELETE("/users/:id/ai-suspension", userID, h.LiftAISuspension)
+
+			// Coupons & Campaigns
+			manager.POST("/promotions", h.CreatePromotion)
+			manager.GET("/promotions", h.GetPromotions)
+			manager.PUT("/promotions/:id", h.UpdatePromotion)
+			manager.GET("/promotions/:id/redemptions", h.GetPromotionRedemptions)
+
+			// Status Page: incidents, maintenance windows & release notes
+			manager.GET("/status-entries", h.GetStatusEntries)
+			manager.POST("/status-entries", h.CreateStatusEntry)
+			manager.PUT("/status-entries/:id", h.UpdateStatusEntry)
+			manager.DELETE("/status-entries/:id", h.DeleteStatusEntry)
+
 			// Users & Settings
 			manager.GET("/settings", h.GetSettings)
 			manager.PATCH("/settings", h.UpdateSettings)
+			manager.GET("/logging", h.GetLogging)
+			manager.PATCH("/logging", h.UpdateLogging)
+			manager.GET("/messaging", h.GetMessaging)
+			manager.PUT("/messaging/email", h.UpdateEmailProvider)
+			manager.POST("/messaging/email/test", h.TestEmailProvider)
+			manager.PUT("/messaging/sms", h.UpdateSMSProvider)
+			manager.POST("/messaging/sms/test", h.TestSMSProvider)
 			manager.GET("/users", h.GetUsers)
-			manager.PATCH("/users/:id/penalty", h.UpdateUserPenalty)
-			manager.POST("/users/:id/subscription", h.AssignSubscription)
+			manager.PATCH("/users/:id/penalty", userID, h.UpdateUserPenalty)
+			manager.GET("/users/:id/documents", userID, h.GetUserDocuments)
+			manager.POST("/users/:id/subscription", userID, h.AssignSubscription)
+
+			// Soft Deletes & Restore
+			manager.GET("/deleted/:kind", h.GetDeleted)
+			manager.DELETE("/users/:id", userID, h.DeleteUser)
+			manager.DELETE("/orders/:id", orderID, h.DeleteOrder)
+			manager.PATCH("/users/:id/restore", userID, h.RestoreUser)
+			manager.PATCH("/products/:id/restore", productID, h.RestoreProduct)
+			manager.DELETE("/products/:id/purge", productID, h.PurgeProduct) // only never-ordered products
+			manager.POST("/products/:id/revisions/:revisionId/rollback", productID, h.RollbackProduct)
+			manager.PATCH("/inventory/:id/restore", h.RestoreInventoryItem)
+			manager.PATCH("/orders/:id/restore", orderID, h.RestoreOrder)
 		}
 
 		// --- Super Admin ---
-		admin := v1.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(h.DB))
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(h.DB, h.Settings, h.Status, userStates))
 		admin.Use(middleware.SuperAdminMiddleware(h.DB))
 		{
 			admin.POST("/create-manager", h.CreateManager)
+
+			// Recent application errors (app_errors ring buffer)
+			admin.GET("/errors", h.GetAppErrors)
+			admin.GET("/errors/:id", h.GetAppError)
+
+			// Nightly database backups
+			admin.GET("/backups", h.GetBackupStatus)
+
+			// Webhook & payment captures
+			admin.GET("/captures", h.GetRequestCaptures)
+			admin.GET("/captures/:id", h.GetRequestCapture)
+			admin.POST("/captures/:id/replay", h.ReplayRequestCapture(router))
 		}
 
 		// --- Dropshipper ---
-		dropshipper := v1.Group("/dropshipper")
-		dropshipper.Use(middleware.AuthMiddleware(h.DB))
+		dropshipper := api.Group("/dropshipper")
+		dropshipper.Use(middleware.AuthMiddleware(h.DB, h.Settings, h.Status, userStates))
 		dropshipper.Use(middleware.DropshipperMiddleware(h.DB))
 		{
 			dropshipper.GET("/cart", h.GetCart)
 			dropshipper.POST("/cart/items", h.AddToCart)
-			dropshipper.PUT("/cart/items/:product_id", h.UpdateCartItem)
-			dropshipper.DELETE("/cart/items/:product_id", h.DeleteCartItem)
+			dropshipper.PUT("/cart/items/:product_id", cartProductID, h.UpdateCartItem)
+			dropshipper.DELETE("/cart/items/:product_id", cartProductID, h.DeleteCartItem)
 			dropshipper.GET("/wallet", h.GetMyWallet)
-			dropshipper.POST("/wallet/topup", h.ManualTopUp)
-			dropshipper.POST("/checkout", h.Checkout)
+			dropshipper.POST("/wallet/topup", capturePayment, h.ManualTopUp)
+			dropshipper.POST("/checkout", middleware.Timeout(20*time.Second), capturePayment, h.Checkout)
+			dropshipper.POST("/checkout/preview", h.PreviewCheckout)
 			dropshipper.GET("/orders", h.GetMyOrders)
-			dropshipper.GET("/orders/:id", h.GetOrderDetails)
+			dropshipper.GET("/orders/export", middleware.Timeout(2*time.Minute), h.ExportMyOrders) // CSV, streamed
+			dropshipper.GET("/orders/:id", orderID, h.GetOrderDetails)
+			dropshipper.GET("/orders/:id/invoices", orderID, h.GetOrderInvoices)
+			dropshipper.GET("/orders/:id/packing-slips", orderID, h.GetOrderPackingSlips)
 			dropshipper.GET("/dashboard-stats", h.GetDropshipperStats)
-			dropshipper.POST("/orders/:id/pay", h.PayOrder)
+			dropshipper.GET("/products/:id/export", productID, h.ExportProduct) // listing payload for their own storefront
+
+			// Watchlist: notified of price changes and restocks
+			dropshipper.GET("/watchlist", h.GetWatchlist)
+			dropshipper.POST("/watchlist/:productId", watchedProductID, h.AddToWatchlist)
+			dropshipper.DELETE("/watchlist/:productId", watchedProductID, h.RemoveFromWatchlist)
+			dropshipper.GET("/referrals", h.GetMyReferrals)
+
+			// End-customers (also recorded at checkout)
+			dropshipper.GET("/customers", h.GetMyCustomers)
+			dropshipper.POST("/customers", h.CreateCustomer)
+			dropshipper.GET("/customers/:id", h.GetMyCustomer)
+			dropshipper.PUT("/customers/:id", h.UpdateCustomer)
+			dropshipper.DELETE("/customers/:id", h.DeleteCustomer)
+			dropshipper.POST("/orders/:id/pay", orderID, capturePayment, h.PayOrder)
 			// ✅ ADD THIS LINE:
-			dropshipper.POST("/orders/:id/complete", h.CompleteOrder)
+			dropshipper.POST("/orders/:id/complete", orderID, h.CompleteOrder)
+			dropshipper.POST("/orders/:id/cancel", orderID, capturePayment, h.CancelOrder)
+			dropshipper.POST("/orders/:id/reorder", orderID, h.Reorder)
+
+			// Reviews of products from completed orders
+			dropshipper.POST("/products/:id/reviews", productID, h.CreateReview)
+			dropshipper.PUT("/reviews/:id", h.UpdateReview)
+			dropshipper.GET("/reviews", h.GetMyReviews)
+
+			// Product questions
+			dropshipper.POST("/products/:id/questions", productID, h.AskQuestion)
+			dropshipper.GET("/questions", h.GetMyQuestions)
+
+			// Disputes
+			dropshipper.POST("/orders/:id/disputes", orderID, h.OpenDispute)
+			dropshipper.GET("/disputes", h.GetMyDisputes)
+			dropshipper.GET("/disputes/:id", h.GetMyDispute)
+			dropshipper.POST("/disputes/:id/withdraw", h.WithdrawDispute)
+
+			// Channel Sync
+			dropshipper.GET("/channels/status", h.GetChannelSyncStatus)
+			dropshipper.POST("/channels/listings/:id/retry", h.RetryChannelListing)
 		}
 	}
-
-	return router
 }
@@ -0,0 +1,140 @@
+// Package sanitize is the HTML policy for user- and AI-written content.
+//
+// Rich text (product and inventory descriptions, AI replies) is stored as HTML
+// restricted to a small allowlist of formatting tags; HTML cleans it on the
+// way in and again when legacy rows are read, so clients may render it as
+// HTML. Every other field is plain text: Text strips markup on the way in, and
+// whatever renders it (React, feed writers) must escape it, never inject it.
+package sanitize
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedTags are the formatting tags rich text may use. Anything else is
+// unwrapped: the tag goes, its text stays.
+var allowedTags = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Hr: true,
+	atom.B: true, atom.Strong: true, atom.I: true, atom.Em: true, atom.U: true, atom.S: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true,
+	atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.Blockquote: true, atom.Code: true, atom.Pre: true,
+	atom.Table: true, atom.Thead: true, atom.Tbody: true, atom.Tr: true, atom.Th: true, atom.Td: true,
+	atom.A: true,
+}
+
+// droppedTags are removed together with their content.
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Object: true, atom.Embed: true,
+	atom.Template: true, atom.Noscript: true, atom.Svg: true, atom.Math: true, atom.Title: true,
+	atom.Textarea: true, atom.Select: true,
+}
+
+// textEscaper escapes text content; quotes only matter inside attributes.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// allowedSchemes are the link targets <a href> may use.
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// HTML returns s restricted to the rich-text allowlist. Attributes are dropped
+// except a safe href on links, which also get rel="nofollow noopener noreferrer".
+func HTML(s string) string {
+	if !strings.ContainsAny(s, "<&") {
+		return s
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil {
+		// Unparsed, the input is shown as text, escaped.
+		return html.EscapeString(s)
+	}
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		writeNode(&buf, n)
+	}
+	return buf.String()
+}
+
+func writeNode(buf *bytes.Buffer, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		buf.WriteString(textEscaper.Replace(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		// Comments, doctypes: dropped.
+		return
+	}
+
+	if droppedTags[n.DataAtom] {
+		return
+	}
+	allowed := allowedTags[n.DataAtom]
+	if allowed {
+		buf.WriteByte('<')
+		buf.WriteString(n.DataAtom.String())
+		if n.DataAtom == atom.A {
+			if href, ok := safeHref(n); ok {
+				buf.WriteString(` href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer"`)
+			}
+		}
+		buf.WriteByte('>')
+		if n.DataAtom == atom.Br || n.DataAtom == atom.Hr {
+			return
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		writeNode(buf, child)
+	}
+	if allowed {
+		buf.WriteString("</" + n.DataAtom.String() + ">")
+	}
+}
+
+func safeHref(n *html.Node) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace != "" || a.Key != "href" {
+			continue
+		}
+		u, err := url.Parse(strings.TrimSpace(a.Val))
+		if err != nil || !allowedSchemes[strings.ToLower(u.Scheme)] {
+			return "", false
+		}
+		return u.String(), true
+	}
+	return "", false
+}
+
+// Text returns s as plain text: tags are stripped (script and style content
+// with them) and entities decoded. The result is not escaped; escaping is the
+// renderer's job. Input that cannot be parsed gives "", never the markup.
+func Text(s string) string {
+	if !strings.ContainsAny(s, "<&") {
+		return s
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil {
+		return ""
+	}
+	var buf strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			buf.WriteString(n.Data)
+		case n.Type == html.ElementNode && droppedTags[n.DataAtom]:
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	return buf.String()
+}
@@ -0,0 +1,62 @@
+package sanitize
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain text untouched", "Cotton tee, 100% cotton", "Cotton tee, 100% cotton"},
+		{"allowed formatting kept", "<p>Soft <b>cotton</b><br>tee</p>", "<p>Soft <b>cotton</b><br>tee</p>"},
+		{"script dropped with its content", "<p>Hi<script>alert(1)</script></p>", "<p>Hi</p>"},
+		{"style dropped with its content", "<style>p{color:red}</style><b>bold</b>", "<b>bold</b>"},
+		{"iframe dropped", `<iframe src="https://evil.example"></iframe>ok`, "ok"},
+		{"svg dropped with its handlers", "<svg onload=alert(1)><circle/></svg>after", "after"},
+		{"javascript: href dropped", `<a href="javascript:alert(1)">x</a>`, "<a>x</a>"},
+		{"javascript: href in odd case and spacing", `<a href=" JaVaScRiPt:alert(1)">x</a>`, "<a>x</a>"},
+		{"javascript: href behind an entity", `<a href="&#106;avascript:alert(1)">x</a>`, "<a>x</a>"},
+		{"data: href dropped", `<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`, "<a>x</a>"},
+		{"relative href dropped", `<a href="/admin">x</a>`, "<a>x</a>"},
+		{"https link kept and hardened",
+			`<a href="https://example.com/a?b=1&c=2" onclick="evil()" target="_self">ok</a>`,
+			`<a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">ok</a>`},
+		{"mailto link kept", `<a href="mailto:a@b.co">m</a>`, `<a href="mailto:a@b.co" rel="nofollow noopener noreferrer">m</a>`},
+		{"event handler attributes dropped", `<p onmouseover="evil()" style="color:red">t</p>`, "<p>t</p>"},
+		{"unknown tag unwrapped", `<img src=x onerror=alert(1)><span class="x">kept</span>`, "kept"},
+		{"unclosed tags closed", "<p>unclosed <b>bold", "<p>unclosed <b>bold</b></p>"},
+		{"stray and misnested tags", "</p>stray<div><p>nested</div>", "<p></p>stray<p>nested</p>"},
+		{"broken script tag", "<<script>script>alert(1)<</script>/script>", "&lt;/script&gt;"},
+		{"comments dropped, text escaped", "<!-- c --><p>1 < 2 & 3 > 2</p>", "<p>1 &lt; 2 &amp; 3 &gt; 2</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.in); got != tt.want {
+				t.Fatalf("HTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			// Cleaning is stable: clean output comes back as is.
+			if again := HTML(tt.want); again != tt.want {
+				t.Fatalf("HTML(%q) = %q, want it unchanged", tt.want, again)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain text untouched", "Red T-shirt (L)", "Red T-shirt (L)"},
+		{"tags stripped", "<b>Red</b> <i>T-shirt</i>", "Red T-shirt"},
+		{"script and style content dropped", "<script>alert(1)</script>Name<style>b{}</style>", "Name"},
+		{"entities decoded", "Tom &amp; Jerry &lt;3", "Tom & Jerry <3"},
+		{"malformed markup", "<p>unclosed <b>bold", "unclosed bold"},
+		{"handlers never survive", `<img src=x onerror=alert(1)>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.in); got != tt.want {
+				t.Fatalf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Package secrets resolves sensitive settings (JWT keys, DSNs, API keys) from
+// a pluggable provider instead of plain environment variables:
+//
+//	SECRETS_PROVIDER=env    (default) the process environment
+//	SECRETS_PROVIDER=file   one file per secret in SECRETS_DIR, named like the
+//	                        variable (Docker/Kubernetes secrets, or AWS Secrets
+//	                        Manager and others mounted by a CSI driver)
+//	SECRETS_PROVIDER=vault  a HashiCorp Vault KV v2 secret at VAULT_SECRET_PATH
+//
+// Secrets are looked up by their environment variable name (JWT_SECRET,
+// DB_DSN_PRIMARY, ...). A name the provider does not have falls back to the
+// environment, so non-sensitive deployments need no changes.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Lookup when the provider has no such secret.
+var ErrNotFound = errors.New("secrets: not found")
+
+// Provider looks secrets up by name.
+type Provider interface {
+	Lookup(ctx context.Context, name string) (string, error)
+}
+
+// FromEnv builds the provider selected by SECRETS_PROVIDER.
+func FromEnv() (Provider, error) {
+	switch kind := strings.TrimSpace(os.Getenv("SECRETS_PROVIDER")); kind {
+	case "", "env":
+		return Env{}, nil
+	case "file":
+		dir := strings.TrimSpace(os.Getenv("SECRETS_DIR"))
+		if dir == "" {
+			return nil, errors.New("secrets: SECRETS_PROVIDER=file needs SECRETS_DIR")
+		}
+		return Dir{Path: dir}, nil
+	case "vault":
+		return NewVault(os.Getenv("VAULT_ADDR"), vaultToken(), os.Getenv("VAULT_SECRET_PATH"))
+	default:
+		return nil, fmt.Errorf("secrets: unknown SECRETS_PROVIDER %q (env, file or vault)", kind)
+	}
+}
+
+// Get resolves name through p, falling back to the environment when p does
+// not have it. An empty result means the secret is not set anywhere.
+func Get(ctx context.Context, p Provider, name string) (string, error) {
+	if p != nil {
+		v, err := p.Lookup(ctx, name)
+		if err == nil {
+			return strings.TrimSpace(v), nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(os.Getenv(name)), nil
+}
+
+// Env reads secrets from the process environment.
+type Env struct{}
+
+func (Env) Lookup(_ context.Context, name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+// Dir reads each secret from a file named after it. Files are re-read on every
+// lookup, so a rotated mount is picked up by the next refresh.
+type Dir struct {
+	Path string
+}
+
+func (d Dir) Lookup(_ context.Context, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(d.Path, filepath.Base(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// vaultToken reads VAULT_TOKEN, or the file named by VAULT_TOKEN_FILE (as
+// written by the Vault agent).
+func vaultToken() string {
+	if path := os.Getenv("VAULT_TOKEN_FILE"); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return os.Getenv("VAULT_TOKEN")
+}
+
+// lookupTimeout bounds one provider round trip at startup and on refresh.
+const lookupTimeout = 10 * time.Second
@@ -0,0 +1,96 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vaultCacheTTL is how long one read of the secret document answers lookups;
+// a config load asks for a dozen names in a row.
+const vaultCacheTTL = 30 * time.Second
+
+// Vault reads a HashiCorp Vault KV v2 secret whose keys are the secret names,
+// e.g. VAULT_SECRET_PATH=secret/taptosell holding JWT_SECRET, DB_DSN_PRIMARY, ...
+type Vault struct {
+	addr  string
+	token string
+	mount string
+	path  string
+	http  *http.Client
+
+	mu      sync.Mutex
+	data    map[string]string
+	fetched time.Time
+}
+
+// NewVault validates the settings; path is "<mount>/<secret path>".
+func NewVault(addr, token, path string) (*Vault, error) {
+	addr = strings.TrimSuffix(strings.TrimSpace(addr), "/")
+	mount, rest, ok := strings.Cut(strings.Trim(strings.TrimSpace(path), "/"), "/")
+	switch {
+	case addr == "":
+		return nil, errors.New("secrets: SECRETS_PROVIDER=vault needs VAULT_ADDR")
+	case token == "":
+		return nil, errors.New("secrets: SECRETS_PROVIDER=vault needs VAULT_TOKEN or VAULT_TOKEN_FILE")
+	case !ok || rest == "":
+		return nil, fmt.Errorf("secrets: VAULT_SECRET_PATH %q must be <mount>/<path>", path)
+	}
+	return &Vault{addr: addr, token: token, mount: mount, path: rest, http: &http.Client{Timeout: lookupTimeout}}, nil
+}
+
+func (v *Vault) Lookup(ctx context.Context, name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.data == nil || time.Since(v.fetched) > vaultCacheTTL {
+		data, err := v.fetch(ctx)
+		if err != nil {
+			return "", err
+		}
+		v.data, v.fetched = data, time.Now()
+	}
+	value, ok := v.data[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// fetch reads the latest version of the secret.
+func (v *Vault) fetch(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.addr+"/v1/"+v.mount+"/data/"+v.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	res, err := v.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: vault: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, fmt.Errorf("secrets: vault: %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	var doc struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("secrets: vault: decode: %w", err)
+	}
+	if doc.Data.Data == nil {
+		return map[string]string{}, nil
+	}
+	return doc.Data.Data, nil
+}
@@ -0,0 +1,83 @@
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/cache"
+)
+
+// cacheTTL bounds how stale a setting can be on an instance whose cache
+// did not receive the invalidation (e.g. in-memory caches on other nodes).
+const cacheTTL = time.Minute
+
+// Store reads values from the 'settings' table through the cache.
+// It is shared by the middleware (maintenance mode) and the handlers.
+type Store struct {
+	DB    *sql.DB
+	Cache cache.Cache
+}
+
+// NewStore creates a settings Store.
+func NewStore(db *sql.DB, c cache.Cache) *Store {
+	return &Store{DB: db, Cache: c}
+}
+
+// All returns every setting as key -> value.
+func (s *Store) All(ctx context.Context) (map[string]string, error) {
+	values := make(map[string]string)
+
+	// 1. Try the cache
+	if found, err := s.Cache.Get(ctx, cache.KeySettings, &values); err == nil && found {
+		return values, nil
+	} else if err != nil {
+		log.Printf("settings: cache read failed: %v", err)
+	}
+
+	// 2. Load from the database
+	rows, err := s.DB.QueryContext(ctx, "SELECT setting_key, setting_value FROM settings")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 3. Populate the cache (a failure here only costs us the next lookup)
+	if err := s.Cache.Set(ctx, cache.KeySettings, values, cacheTTL); err != nil {
+		log.Printf("settings: cache write failed: %v", err)
+	}
+	return values, nil
+}
+
+// Get returns a single setting value.
+// It returns sql.ErrNoRows when the setting does not exist, like a direct query would.
+func (s *Store) Get(ctx context.Context, key string) (string, error) {
+	values, err := s.All(ctx)
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return value, nil
+}
+
+// Invalidate drops the cached settings. Call it after every write to the settings table.
+func (s *Store) Invalidate(ctx context.Context) {
+	if err := s.Cache.Delete(ctx, cache.KeySettings); err != nil {
+		log.Printf("settings: cache invalidation failed: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package shipping
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+	_ "time/tzdata" // SHIPPING_TIMEZONE must load on hosts without a zoneinfo database
+)
+
+var clock = regexp.MustCompile(`^([01]\d|2[0-3]):([0-5]\d)(:00)?$`)
+
+// ParseCutoff reads a daily order cutoff, "HH:MM" (or "HH:MM:00" as MySQL
+// returns a TIME), as the time since midnight.
+func ParseCutoff(s string) (time.Duration, error) {
+	m := clock.FindStringSubmatch(s)
+	if m == nil {
+		return 0, errors.New("cutoff must be a time of day like 14:00")
+	}
+	h, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	return time.Duration(h)*time.Hour + time.Duration(min)*time.Minute, nil
+}
+
+// businessDay reports whether day is Monday to Friday.
+func businessDay(day time.Time) bool {
+	return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+}
+
+// ShipsBy is the last moment an order paid at paidAt may be handed to the
+// courier: the end of the handlingDays-th business day after the day the
+// order counts for. An order counts for the day it was paid, or the next
+// business day when paid on a weekend or after cutoff ("" for no cutoff).
+// Days are calendar days in loc.
+func ShipsBy(paidAt time.Time, handlingDays int, cutoff string, loc *time.Location) time.Time {
+	t := paidAt.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	if cutoff != "" {
+		if c, err := ParseCutoff(cutoff); err == nil && t.Sub(day) >= c {
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+	for !businessDay(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	for n := handlingDays; n > 0; {
+		day = day.AddDate(0, 0, 1)
+		if businessDay(day) {
+			n--
+		}
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Second)
+}
@@ -0,0 +1,148 @@
+// Package shipping holds the handling restrictions a product can carry
+// (batteries, liquids, fragile, oversize) and which couriers accept them.
+//
+// Couriers come from SHIPPING_COURIERS, one entry per courier with the
+// restrictions it refuses after a colon, joined by '+':
+//
+//	jnt:battery+liquid,poslaju:oversize,lalamove
+//
+// Without couriers configured nothing is excluded; restrictions are only shown.
+package shipping
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Restrictions a product can carry (products.shipping_restrictions).
+const (
+	Battery  = "battery"  // contains lithium batteries
+	Liquid   = "liquid"   // liquids, gels or aerosols
+	Fragile  = "fragile"  // needs fragile handling
+	Oversize = "oversize" // beyond standard parcel size or weight
+)
+
+// Restrictions lists every restriction, in column order.
+var Restrictions = []string{Battery, Liquid, Fragile, Oversize}
+
+// Valid reports whether r is a known restriction.
+func Valid(r string) bool {
+	for _, known := range Restrictions {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Split reads a SET column value ("battery,liquid"); "" gives an empty list.
+func Split(set string) []string {
+	if set == "" {
+		return []string{}
+	}
+	return strings.Split(set, ",")
+}
+
+// Join is the SET column value of restrictions, deduplicated in column order.
+func Join(restrictions []string) string {
+	var out []string
+	for _, known := range Restrictions {
+		for _, r := range restrictions {
+			if r == known {
+				out = append(out, r)
+				break
+			}
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+// Courier is a carrier suppliers ship with and the restrictions it refuses.
+type Courier struct {
+	Code    string   `json:"code"`
+	Refuses []string `json:"refuses"`
+}
+
+// Accepts reports whether the courier carries a parcel with all of restrictions.
+func (c Courier) Accepts(restrictions []string) bool {
+	for _, r := range restrictions {
+		for _, refused := range c.Refuses {
+			if r == refused {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Compatible returns the codes of the couriers accepting restrictions, or nil
+// when no couriers are configured (everything may ship).
+func Compatible(couriers []Courier, restrictions []string) []string {
+	if len(couriers) == 0 {
+		return nil
+	}
+	codes := []string{}
+	for _, c := range couriers {
+		if c.Accepts(restrictions) {
+			codes = append(codes, c.Code)
+		}
+	}
+	return codes
+}
+
+// Shippable reports whether some courier accepts restrictions; always true
+// when no couriers are configured.
+func Shippable(couriers []Courier, restrictions []string) bool {
+	if len(couriers) == 0 {
+		return true
+	}
+	for _, c := range couriers {
+		if c.Accepts(restrictions) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the courier with code.
+func Find(couriers []Courier, code string) (Courier, bool) {
+	for _, c := range couriers {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return Courier{}, false
+}
+
+var courierCode = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// ParseCouriers reads a SHIPPING_COURIERS value.
+func ParseCouriers(s string) ([]Courier, error) {
+	var couriers []Courier
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		code, refused, _ := strings.Cut(entry, ":")
+		if !courierCode.MatchString(code) {
+			return nil, fmt.Errorf("courier code %q must be 1-32 lowercase letters, digits, '_' or '-'", code)
+		}
+		if _, dup := Find(couriers, code); dup {
+			return nil, fmt.Errorf("courier %q is listed twice", code)
+		}
+		c := Courier{Code: code, Refuses: []string{}}
+		for _, r := range strings.Split(refused, "+") {
+			if r == "" {
+				continue
+			}
+			if !Valid(r) {
+				return nil, fmt.Errorf("courier %q refuses unknown restriction %q (use %s)", code, r, strings.Join(Restrictions, ", "))
+			}
+			c.Refuses = append(c.Refuses, r)
+		}
+		couriers = append(couriers, c)
+	}
+	return couriers, nil
+}
@@ -0,0 +1,84 @@
+// Package sku renders the SKUs generated for products and variants saved
+// without one. A pattern is text with placeholders, e.g. the default
+// "SUP{supplierID}-{category}-{seq}" gives "SUP12-SHOES-00042":
+//
+//	{supplierID}  the supplier's user ID
+//	{category}    the product's first category slug, upper-cased (GEN without one)
+//	{seq}         the supplier's next SKU number, zero-padded to 5 digits
+//
+// {seq} is required: it is what keeps generated SKUs apart.
+package sku
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultPattern is used when SKU_PATTERN is not set.
+const DefaultPattern = "SUP{supplierID}-{category}-{seq}"
+
+// maxCategoryLen caps the {category} part so SKUs stay short.
+const maxCategoryLen = 8
+
+var placeholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// Pattern is a parsed SKU pattern. The zero value renders DefaultPattern.
+type Pattern struct {
+	raw string
+}
+
+// Parse checks a pattern: known placeholders only, and {seq} present.
+func Parse(s string) (Pattern, error) {
+	for _, token := range placeholder.FindAllString(s, -1) {
+		switch token {
+		case "{supplierID}", "{category}", "{seq}":
+		default:
+			return Pattern{}, fmt.Errorf("unknown placeholder %s (use {supplierID}, {category}, {seq})", token)
+		}
+	}
+	if !strings.Contains(s, "{seq}") {
+		return Pattern{}, errors.New("must contain {seq}")
+	}
+	if len(s) > 40 {
+		return Pattern{}, errors.New("must be at most 40 characters")
+	}
+	return Pattern{raw: s}, nil
+}
+
+// String returns the pattern text.
+func (p Pattern) String() string {
+	if p.raw == "" {
+		return DefaultPattern
+	}
+	return p.raw
+}
+
+// Render builds a SKU. category is a category slug ("" for none).
+func (p Pattern) Render(supplierID int64, category string, seq int64) string {
+	return strings.NewReplacer(
+		"{supplierID}", strconv.FormatInt(supplierID, 10),
+		"{category}", categoryCode(category),
+		"{seq}", fmt.Sprintf("%05d", seq),
+	).Replace(p.String())
+}
+
+// categoryCode turns a slug ("mens-shoes") into a SKU part ("MENSSHOE").
+func categoryCode(slug string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(slug) {
+		if b.Len() == maxCategoryLen {
+			break
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "GEN"
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+// Package sms sends text messages through the provider managers configure at
+// runtime (PUT /v1/manager/messaging/sms). Until one is set, messages go to the log.
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Sender delivers one text message.
+type Sender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// sender is the active Sender.
+var sender atomic.Value // holds senderBox
+
+// senderBox keeps every stored value the same concrete type, as atomic.Value requires.
+type senderBox struct{ Sender }
+
+// SetSender replaces the active Sender; safe to call while requests are served.
+func SetSender(s Sender) {
+	sender.Store(senderBox{s})
+}
+
+// Send sends a text message through the active Sender.
+func Send(ctx context.Context, to, body string) error {
+	if box, ok := sender.Load().(senderBox); ok {
+		return box.Send(ctx, to, body)
+	}
+	return LogSender{}.Send(ctx, to, body)
+}
+
+// LogSender writes messages to the log instead of sending them.
+type LogSender struct{}
+
+// Send logs the message.
+func (LogSender) Send(_ context.Context, to, body string) error {
+	log.Printf("--- NEW SMS (PLACEHOLDER) --- To: %s | %s", to, body)
+	return nil
+}
+
+// twilioAPI is the base URL of the Twilio REST API.
+const twilioAPI = "https://api.twilio.com/2010-04-01"
+
+// TwilioSender sends through Twilio's Messages API.
+type TwilioSender struct {
+	AccountSID string
+	AuthToken  string
+	From       string // a Twilio number (E.164) or an alphanumeric sender ID
+}
+
+// client bounds the provider call; Twilio answers in well under a second.
+var client = &http.Client{Timeout: 15 * time.Second}
+
+// Send delivers one message.
+func (t TwilioSender) Send(ctx context.Context, to, body string) error {
+	if t.AccountSID == "" || t.AuthToken == "" || t.From == "" {
+		return fmt.Errorf("twilio: account SID, auth token and sender are required")
+	}
+	form := url.Values{"To": {to}, "From": {t.From}, "Body": {body}}
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", twilioAPI, url.PathEscape(t.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+
+	// Twilio explains the refusal in {"code": 21211, "message": "..."}.
+	var apiErr struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(raw, &apiErr) == nil && apiErr.Message != "" {
+		return fmt.Errorf("twilio: %s (code %d)", apiErr.Message, apiErr.Code)
+	}
+	return fmt.Errorf("twilio: unexpected status %s", resp.Status)
+}
@@ -0,0 +1,99 @@
+// Package status reads the scheduled maintenance windows that pause write
+// requests (enforced by middleware.AuthMiddleware). The windows are
+// 'status_entries' rows managed in handlers/status_handlers.go.
+package status
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/cache"
+)
+
+// cacheTTL bounds how stale the windows can be on an instance whose cache
+// did not receive the invalidation. Start and end are checked per request.
+const cacheTTL = time.Minute
+
+// Window is a published maintenance window that blocks writes.
+type Window struct {
+	ID       int64     `json:"id"`
+	Title    string    `json:"title"`
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+}
+
+// Store reads the windows through the cache.
+type Store struct {
+	DB    *sql.DB
+	Cache cache.Cache
+}
+
+// NewStore creates a status Store.
+func NewStore(db *sql.DB, c cache.Cache) *Store {
+	return &Store{DB: db, Cache: c}
+}
+
+// Windows returns the write-blocking windows that have not ended yet, soonest first.
+func (s *Store) Windows(ctx context.Context) ([]Window, error) {
+	windows := []Window{}
+
+	// 1. Try the cache
+	if found, err := s.Cache.Get(ctx, cache.KeyMaintenanceWindows, &windows); err == nil && found {
+		return windows, nil
+	} else if err != nil {
+		log.Printf("status: cache read failed: %v", err)
+	}
+
+	// 2. Load from the database
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, title, starts_at, ends_at
+		FROM status_entries
+		WHERE kind = 'maintenance' AND blocks_writes = 1 AND is_published = 1 AND ends_at > ?
+		ORDER BY starts_at ASC, id ASC`, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var w Window
+		if err := rows.Scan(&w.ID, &w.Title, &w.StartsAt, &w.EndsAt); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 3. Populate the cache (a failure here only costs us the next lookup)
+	if err := s.Cache.Set(ctx, cache.KeyMaintenanceWindows, windows, cacheTTL); err != nil {
+		log.Printf("status: cache write failed: %v", err)
+	}
+	return windows, nil
+}
+
+// Active returns the window in effect at now (the one ending last when they
+// overlap), or nil.
+func (s *Store) Active(ctx context.Context, now time.Time) (*Window, error) {
+	windows, err := s.Windows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var active *Window
+	for i, w := range windows {
+		if !now.Before(w.StartsAt) && now.Before(w.EndsAt) && (active == nil || w.EndsAt.After(active.EndsAt)) {
+			active = &windows[i]
+		}
+	}
+	return active, nil
+}
+
+// Invalidate drops the cached windows. Call it after every write to status_entries.
+func (s *Store) Invalidate(ctx context.Context) {
+	if err := s.Cache.Delete(ctx, cache.KeyMaintenanceWindows); err != nil {
+		log.Printf("status: cache invalidation failed: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+)
+
+// barcodeArg is the barcode column value of b: NULL for none or blank.
+func barcodeArg(b *string) sql.NullString {
+	if b == nil || *b == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *b, Valid: true}
+}
+
+func (s *productStore) FindBarcode(ctx context.Context, supplierID int64, forms []string, excludeProductID int64) (*models.SKUUse, error) {
+	placeholders, codes := inClause(forms)
+	args := append([]interface{}{supplierID}, codes...)
+	args = append(args, excludeProductID, supplierID)
+	args = append(args, codes...)
+	args = append(args, excludeProductID)
+
+	var use models.SKUUse
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, public_id, name, NULL FROM products
+		WHERE supplier_id = ? AND barcode IN (`+placeholders+`) AND id <> ?
+		UNION ALL
+		SELECT p.id, p.public_id, p.name, v.id FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE p.supplier_id = ? AND v.barcode IN (`+placeholders+`) AND p.id <> ?
+		LIMIT 1`, args...,
+	).Scan(&use.ProductID, &use.PublicID, &use.ProductName, &use.VariantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &use, nil
+}
+
+func (s *productStore) LookupBarcode(ctx context.Context, forms []string, limit int) ([]models.BarcodeMatch, error) {
+	placeholders, codes := inClause(forms)
+	now := time.Now()
+	args := append(append([]interface{}{}, codes...), now)
+	args = append(args, codes...)
+	args = append(args, now, limit)
+
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT p.id, NULL FROM products p
+		WHERE p.barcode IN (`+placeholders+`) AND `+InCatalogue("p")+`
+		UNION ALL
+		SELECT p.id, v.id FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE v.barcode IN (`+placeholders+`) AND `+InCatalogue("p")+`
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []models.BarcodeMatch
+	for rows.Next() {
+		var m models.BarcodeMatch
+		if err := rows.Scan(&m.ProductID, &m.VariantID); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
@@ -0,0 +1,478 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/google/uuid"
+)
+
+// OrderStore owns 'orders', 'order_items', 'order_discounts' and 'order_tax_lines'.
+type OrderStore interface {
+	// Create inserts the order row and sets o.ID (and o.PublicID when empty).
+	Create(ctx context.Context, o *models.Order) error
+	// AddItems saves the order's line items with multi-row INSERTs.
+	AddItems(ctx context.Context, orderID int64, items []models.OrderItem) error
+	// AddDiscount saves a discount line and sets d.ID; o.DiscountTotal must already include it.
+	AddDiscount(ctx context.Context, d *models.OrderDiscount) error
+	// AddTaxLines saves the order's tax lines; o.TaxTotal must already include them.
+	AddTaxLines(ctx context.Context, orderID int64, lines []models.OrderTaxLine) error
+
+	// Get loads an order whoever placed it; the caller checks access.
+	Get(ctx context.Context, id int64) (*models.Order, error)
+	// GetForUser loads an order only if it belongs to userID.
+	GetForUser(ctx context.Context, id, userID int64) (*models.Order, error)
+	// GetForUpdate is GetForUser with a row lock; use it on a transaction-bound store.
+	GetForUpdate(ctx context.Context, id, userID int64) (*models.Order, error)
+	// ListByUser returns one page of a dropshipper's orders (page.Limit+1 rows), newest first.
+	ListByUser(ctx context.Context, userID int64, page pagination.Page) ([]models.Order, error)
+	// ListByCustomer returns one page of a dropshipper's orders for one of their customers, newest first.
+	ListByCustomer(ctx context.Context, userID, customerID int64, page pagination.Page) ([]models.Order, error)
+	// ListBySupplier returns one page of orders containing the supplier's products (page.Limit+1 rows).
+	ListBySupplier(ctx context.Context, supplierID int64, page pagination.Page) ([]models.Order, error)
+	// ListOverdue returns 'on-hold' orders created before cutoff.
+	ListOverdue(ctx context.Context, cutoff time.Time) ([]models.Order, error)
+	// ListUnreminded returns 'on-hold' orders created before cutoff whose
+	// dropshipper has not been reminded to pay them yet.
+	ListUnreminded(ctx context.Context, cutoff time.Time) ([]models.Order, error)
+	// MarkReminded records the payment reminder of an on-hold order. It is
+	// false when the order was already reminded (by another instance).
+	MarkReminded(ctx context.Context, orderID int64, at time.Time) (bool, error)
+	// ListPreorders returns 'pre-order' orders, oldest first. A productID > 0 keeps
+	// only the orders still waiting for that product.
+	ListPreorders(ctx context.Context, productID int64) ([]models.Order, error)
+
+	// Items returns the order's lines with product name, display SKU and variant options.
+	Items(ctx context.Context, orderID int64) ([]models.OrderItemDetail, error)
+	// Discounts returns the order's discount lines.
+	Discounts(ctx context.Context, orderID int64) ([]models.OrderDiscount, error)
+	// TaxLines returns the order's tax lines; a supplierID > 0 keeps only theirs.
+	TaxLines(ctx context.Context, orderID, supplierID int64) ([]models.OrderTaxLine, error)
+	// SupplierItems returns only the lines of an order that belong to supplierID.
+	SupplierItems(ctx context.Context, orderID, supplierID int64) ([]models.SupplierOrderItem, error)
+	// StockLines returns the product, variant, quantity and pre-order flag of every line
+	// (for stock restores; pre-order lines never took stock).
+	StockLines(ctx context.Context, orderID int64) ([]models.OrderItem, error)
+	// SupplierHasItems reports whether the order contains any of the supplier's products.
+	SupplierHasItems(ctx context.Context, orderID, supplierID int64) (bool, error)
+	// SupplierSubtotals returns the value of each supplier's lines, by supplier ID.
+	SupplierSubtotals(ctx context.Context, orderID int64) ([]models.SupplierSubtotal, error)
+	// SupplierPayouts returns what completing the order pays each supplier, by
+	// supplier ID, from the supplier and commission copied onto the lines.
+	SupplierPayouts(ctx context.Context, orderID int64) ([]models.SupplierPayout, error)
+	// SupplierID returns the supplier of the order's first line.
+	SupplierID(ctx context.Context, orderID int64) (int64, error)
+
+	// UpdateStatus moves an order to a new status.
+	UpdateStatus(ctx context.Context, id int64, status string) error
+	// MarkShipped sets a 'processing' order to 'shipped' with its tracking
+	// number and courier (nil when not given). It reports false when the order
+	// was not processing (any more).
+	MarkShipped(ctx context.Context, id int64, tracking string, courier *string) (bool, error)
+}
+
+type orderStore struct {
+	db DBTX
+}
+
+// orderColumns is the column list scanned by scanOrder.
+const orderColumns = "o.id, o.public_id, o.user_id, o.status, o.total, o.discount_total, o.tax_total, o.created_at, o.updated_at, o.tracking, o.courier, o.customer_id, o.ship_to, o.blind_ship, o.sender_name"
+
+func scanOrder(row interface{ Scan(...interface{}) error }) (models.Order, error) {
+	var o models.Order
+	var shipTo []byte
+	err := row.Scan(&o.ID, &o.PublicID, &o.UserID, &o.Status, &o.Total, &o.DiscountTotal, &o.TaxTotal, &o.CreatedAt, &o.UpdatedAt, &o.Tracking, &o.Courier, &o.CustomerID, &shipTo, &o.BlindShip, &o.SenderName)
+	if err == nil && len(shipTo) > 0 {
+		o.ShipTo = &models.ShipTo{}
+		err = json.Unmarshal(shipTo, o.ShipTo)
+	}
+	return o, err
+}
+
+// queryOrders runs an orderColumns query and scans every row.
+func queryOrders(ctx context.Context, db DBTX, query string, args ...interface{}) ([]models.Order, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (s *orderStore) Create(ctx context.Context, o *models.Order) error {
+	query := `
+		INSERT INTO orders (public_id, user_id, status, total, discount_total, tax_total, customer_id, ship_to, blind_ship, sender_name, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if o.PublicID == "" {
+		o.PublicID = uuid.NewString()
+	}
+	var shipTo []byte
+	if o.ShipTo != nil {
+		var err error
+		if shipTo, err = json.Marshal(o.ShipTo); err != nil {
+			return err
+		}
+	}
+	result, err := s.db.ExecContext(ctx, query, o.PublicID, o.UserID, o.Status, o.Total, o.DiscountTotal, o.TaxTotal, o.CustomerID, shipTo, o.BlindShip, o.SenderName, o.CreatedAt, o.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	o.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s *orderStore) AddItems(ctx context.Context, orderID int64, items []models.OrderItem) error {
+	return bulkInsert(ctx, s.db,
+		"INSERT INTO order_items (order_id, product_id, variant_id, supplier_id, quantity, unit_price, commission_rate, commission, preorder, created_at) VALUES ",
+		"(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", len(items),
+		func(i int) ([]interface{}, error) {
+			it := items[i]
+			return []interface{}{orderID, it.ProductID, it.VariantID, it.SupplierID, it.Quantity, it.UnitPrice, it.CommissionRate, it.Commission, it.Preorder, it.CreatedAt}, nil
+		})
+}
+
+func (s *orderStore) AddDiscount(ctx context.Context, d *models.OrderDiscount) error {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO order_discounts (order_id, promotion_id, user_id, code, description, amount, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		d.OrderID, d.PromotionID, d.UserID, d.Code, d.Description, d.Amount, d.CreatedAt)
+	if err != nil {
+		return err
+	}
+	d.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s *orderStore) AddTaxLines(ctx context.Context, orderID int64, lines []models.OrderTaxLine) error {
+	return bulkInsert(ctx, s.db,
+		"INSERT INTO order_tax_lines (order_id, supplier_id, rate, taxable_amount, tax_amount) VALUES ",
+		"(?, ?, ?, ?, ?)", len(lines),
+		func(i int) ([]interface{}, error) {
+			l := lines[i]
+			return []interface{}{orderID, l.SupplierID, l.Rate, l.TaxableAmount, l.TaxAmount}, nil
+		})
+}
+
+func (s *orderStore) Get(ctx context.Context, id int64) (*models.Order, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders o WHERE o.id = ? AND "+NotDeleted("o"), id)
+	o, err := scanOrder(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &o, nil
+}
+
+func (s *orderStore) GetForUser(ctx context.Context, id, userID int64) (*models.Order, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders o WHERE o.id = ? AND o.user_id = ? AND "+NotDeleted("o"), id, userID)
+	o, err := scanOrder(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &o, nil
+}
+
+func (s *orderStore) GetForUpdate(ctx context.Context, id, userID int64) (*models.Order, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders o WHERE o.id = ? AND o.user_id = ? AND "+NotDeleted("o")+" FOR UPDATE", id, userID)
+	o, err := scanOrder(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &o, nil
+}
+
+func (s *orderStore) ListByUser(ctx context.Context, userID int64, page pagination.Page) ([]models.Order, error) {
+	cursorCond, cursorArgs := page.Where("o.created_at", "o.id")
+	query := "SELECT " + orderColumns + " FROM orders o WHERE o.user_id = ? AND " + NotDeleted("o") +
+		cursorCond + page.OrderLimit("o.created_at", "o.id")
+
+	args := append([]interface{}{userID}, cursorArgs...)
+	return queryOrders(ctx, s.db, query, args...)
+}
+
+func (s *orderStore) ListByCustomer(ctx context.Context, userID, customerID int64, page pagination.Page) ([]models.Order, error) {
+	cursorCond, cursorArgs := page.Where("o.created_at", "o.id")
+	query := "SELECT " + orderColumns + " FROM orders o WHERE o.customer_id = ? AND o.user_id = ? AND " + NotDeleted("o") +
+		cursorCond + page.OrderLimit("o.created_at", "o.id")
+
+	args := append([]interface{}{customerID, userID}, cursorArgs...)
+	return queryOrders(ctx, s.db, query, args...)
+}
+
+func (s *orderStore) ListBySupplier(ctx context.Context, supplierID int64, page pagination.Page) ([]models.Order, error) {
+	// Unique orders that contain items sold by this supplier; the line keeps
+	// its supplier, so a product changing hands does not move past orders.
+	cursorCond, cursorArgs := page.Where("o.created_at", "o.id")
+	query := `
+		SELECT DISTINCT ` + orderColumns + `
+		FROM orders o
+		JOIN order_items oi ON o.id = oi.order_id
+		WHERE oi.supplier_id = ? AND ` + NotDeleted("o") + cursorCond + page.OrderLimit("o.created_at", "o.id")
+
+	args := append([]interface{}{supplierID}, cursorArgs...)
+	return queryOrders(ctx, s.db, query, args...)
+}
+
+func (s *orderStore) ListOverdue(ctx context.Context, cutoff time.Time) ([]models.Order, error) {
+	query := "SELECT " + orderColumns + " FROM orders o WHERE o.status = 'on-hold' AND o.created_at < ? AND " + NotDeleted("o")
+	return queryOrders(ctx, s.db, query, cutoff)
+}
+
+func (s *orderStore) ListUnreminded(ctx context.Context, cutoff time.Time) ([]models.Order, error) {
+	query := "SELECT " + orderColumns + " FROM orders o WHERE o.status = 'on-hold' AND o.created_at < ? AND o.unpaid_reminded_at IS NULL AND " + NotDeleted("o")
+	return queryOrders(ctx, s.db, query, cutoff)
+}
+
+func (s *orderStore) MarkReminded(ctx context.Context, orderID int64, at time.Time) (bool, error) {
+	result, err := s.db.ExecContext(ctx, "UPDATE orders SET unpaid_reminded_at = ? WHERE id = ? AND unpaid_reminded_at IS NULL", at, orderID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (s *orderStore) ListPreorders(ctx context.Context, productID int64) ([]models.Order, error) {
+	query := "SELECT " + orderColumns + " FROM orders o WHERE o.status = 'pre-order' AND " + NotDeleted("o")
+	var args []interface{}
+	if productID > 0 {
+		query += " AND EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id AND oi.product_id = ? AND oi.preorder = 1)"
+		args = append(args, productID)
+	}
+	query += " ORDER BY o.created_at ASC, o.id ASC"
+	return queryOrders(ctx, s.db, query, args...)
+}
+
+// parseOptions decodes a variant's options JSON; simple products get an empty list.
+func parseOptions(optionsJSON []byte) []map[string]string {
+	options := []map[string]string{}
+	if len(optionsJSON) > 0 {
+		_ = json.Unmarshal(optionsJSON, &options)
+	}
+	return options
+}
+
+func (s *orderStore) Items(ctx context.Context, orderID int64) ([]models.OrderItemDetail, error) {
+	// Join product_variants to get the specific SKU and Options
+	query := `
+		SELECT
+			oi.id, oi.order_id, oi.product_id, oi.variant_id, oi.supplier_id, oi.quantity, oi.unit_price, oi.preorder, oi.created_at,
+			p.name,
+			COALESCE(v.sku, p.sku, '') as display_sku,
+			v.options
+		FROM order_items oi
+		JOIN products p ON oi.product_id = p.id
+		LEFT JOIN product_variants v ON oi.variant_id = v.id
+		WHERE oi.order_id = ?`
+
+	rows, err := s.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.OrderItemDetail{}
+	for rows.Next() {
+		var item models.OrderItemDetail
+		var optionsJSON []byte
+		if err := rows.Scan(
+			&item.ID, &item.OrderID, &item.ProductID, &item.VariantID, &item.SupplierID, &item.Quantity, &item.UnitPrice, &item.Preorder, &item.CreatedAt,
+			&item.ProductName, &item.ProductSKU, &optionsJSON,
+		); err != nil {
+			return nil, err
+		}
+		item.Options = parseOptions(optionsJSON)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (s *orderStore) Discounts(ctx context.Context, orderID int64) ([]models.OrderDiscount, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, order_id, promotion_id, user_id, code, description, amount, created_at
+		FROM order_discounts WHERE order_id = ? ORDER BY id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	discounts := []models.OrderDiscount{}
+	for rows.Next() {
+		var d models.OrderDiscount
+		if err := rows.Scan(&d.ID, &d.OrderID, &d.PromotionID, &d.UserID, &d.Code, &d.Description, &d.Amount, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		discounts = append(discounts, d)
+	}
+	return discounts, rows.Err()
+}
+
+func (s *orderStore) TaxLines(ctx context.Context, orderID, supplierID int64) ([]models.OrderTaxLine, error) {
+	query := "SELECT id, order_id, supplier_id, rate, taxable_amount, tax_amount FROM order_tax_lines WHERE order_id = ?"
+	args := []interface{}{orderID}
+	if supplierID > 0 {
+		query += " AND supplier_id = ?"
+		args = append(args, supplierID)
+	}
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY supplier_id, rate", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []models.OrderTaxLine{}
+	for rows.Next() {
+		var l models.OrderTaxLine
+		if err := rows.Scan(&l.ID, &l.OrderID, &l.SupplierID, &l.Rate, &l.TaxableAmount, &l.TaxAmount); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+func (s *orderStore) SupplierItems(ctx context.Context, orderID, supplierID int64) ([]models.SupplierOrderItem, error) {
+	query := `
+		SELECT
+			p.name,
+			COALESCE(v.sku, p.sku, '') as sku,
+			oi.quantity,
+			oi.unit_price,
+			v.options
+		FROM order_items oi
+		JOIN products p ON oi.product_id = p.id
+		LEFT JOIN product_variants v ON oi.variant_id = v.id
+		WHERE oi.order_id = ? AND oi.supplier_id = ?`
+
+	rows, err := s.db.QueryContext(ctx, query, orderID, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.SupplierOrderItem{}
+	for rows.Next() {
+		var item models.SupplierOrderItem
+		var optionsJSON []byte
+		if err := rows.Scan(&item.ProductName, &item.SKU, &item.Quantity, &item.UnitPrice, &optionsJSON); err != nil {
+			return nil, err
+		}
+		item.Options = parseOptions(optionsJSON)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (s *orderStore) StockLines(ctx context.Context, orderID int64) ([]models.OrderItem, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT product_id, variant_id, quantity, preorder FROM order_items WHERE order_id = ?", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.OrderItem
+	for rows.Next() {
+		var it models.OrderItem
+		if err := rows.Scan(&it.ProductID, &it.VariantID, &it.Quantity, &it.Preorder); err != nil {
+			return nil, err
+		}
+		it.OrderID = orderID
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+func (s *orderStore) SupplierSubtotals(ctx context.Context, orderID int64) ([]models.SupplierSubtotal, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT supplier_id, SUM(unit_price * quantity) FROM order_items
+		WHERE order_id = ? GROUP BY supplier_id ORDER BY supplier_id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subtotals []models.SupplierSubtotal
+	for rows.Next() {
+		var st models.SupplierSubtotal
+		if err := rows.Scan(&st.SupplierID, &st.Amount); err != nil {
+			return nil, err
+		}
+		subtotals = append(subtotals, st)
+	}
+	return subtotals, rows.Err()
+}
+
+func (s *orderStore) SupplierPayouts(ctx context.Context, orderID int64) ([]models.SupplierPayout, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT oi.supplier_id,
+			SUM(oi.unit_price * oi.quantity - oi.commission) + COALESCE((
+				SELECT SUM(t.tax_amount) FROM order_tax_lines t
+				WHERE t.order_id = oi.order_id AND t.supplier_id = oi.supplier_id), 0),
+			SUM(oi.commission)
+		FROM order_items oi
+		WHERE oi.order_id = ? GROUP BY oi.order_id, oi.supplier_id ORDER BY oi.supplier_id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payouts []models.SupplierPayout
+	for rows.Next() {
+		var p models.SupplierPayout
+		if err := rows.Scan(&p.SupplierID, &p.Amount, &p.Commission); err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, p)
+	}
+	return payouts, rows.Err()
+}
+
+func (s *orderStore) SupplierHasItems(ctx context.Context, orderID, supplierID int64) (bool, error) {
+	var exists int
+	query := `
+		SELECT 1 FROM order_items
+		WHERE order_id = ? AND supplier_id = ? LIMIT 1`
+
+	err := s.db.QueryRowContext(ctx, query, orderID, supplierID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *orderStore) SupplierID(ctx context.Context, orderID int64) (int64, error) {
+	var supplierID int64
+	query := "SELECT supplier_id FROM order_items WHERE order_id = ? LIMIT 1"
+	if err := s.db.QueryRowContext(ctx, query, orderID).Scan(&supplierID); err != nil {
+		return 0, notFound(err)
+	}
+	return supplierID, nil
+}
+
+func (s *orderStore) UpdateStatus(ctx context.Context, id int64, status string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE orders SET status = ?, updated_at = ? WHERE id = ?", status, time.Now(), id)
+	return err
+}
+
+func (s *orderStore) MarkShipped(ctx context.Context, id int64, tracking string, courier *string) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE orders SET status = 'shipped', tracking = ?, courier = ?, updated_at = ? WHERE id = ? AND status = 'processing'",
+		tracking, courier, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
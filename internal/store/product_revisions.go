@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+)
+
+func (s *productStore) Snapshot(ctx context.Context, id int64) (*models.ProductSnapshot, error) {
+	p, err := getProduct(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	snap := &models.ProductSnapshot{
+		Name:                 p.Name,
+		Description:          p.Description,
+		Status:               p.Status,
+		IsVariable:           p.IsVariable,
+		SKU:                  p.SKU,
+		Barcode:              p.Barcode,
+		Price:                p.PriceToTTS,
+		Stock:                p.StockQuantity,
+		SRP:                  p.SRP,
+		CommissionRate:       p.CommissionRate,
+		IsPreorder:           p.IsPreorder,
+		PreorderAvailableAt:  p.PreorderAvailableAt,
+		PreorderLimit:        p.PreorderLimit,
+		Weight:               p.Weight,
+		PkgLength:            p.PkgLength,
+		PkgWidth:             p.PkgWidth,
+		PkgHeight:            p.PkgHeight,
+		ShippingRestrictions: p.ShippingRestrictions,
+		MinOrderQty:          p.MinOrderQty,
+		OrderIncrement:       p.OrderIncrement,
+		HandlingDays:         p.HandlingDays,
+		OrderCutoff:          p.OrderCutoff,
+		Images:               p.Images,
+		VideoURL:             p.VideoURL,
+		SizeChart:            p.SizeChart,
+		VariationImages:      p.VariationImages,
+		CategoryIDs:          []int64{},
+		Version:              p.Version,
+	}
+	for _, c := range p.Categories {
+		snap.CategoryIDs = append(snap.CategoryIDs, c.ID)
+	}
+	if len(p.Brands) > 0 {
+		snap.BrandID = p.Brands[0].ID
+	}
+	snap.Tags = []string{}
+	for _, t := range p.Tags {
+		snap.Tags = append(snap.Tags, t.Name)
+	}
+	for _, v := range p.Variants {
+		snap.Variants = append(snap.Variants, models.ProductSnapshotVariant{
+			ID:             v.ID,
+			SKU:            v.SKU,
+			Barcode:        v.Barcode,
+			Price:          v.PriceToTTS,
+			Stock:          v.StockQuantity,
+			Options:        json.RawMessage(v.Options),
+			CommissionRate: v.CommissionRate,
+		})
+	}
+	return snap, nil
+}
+
+func (s *productStore) AddRevision(ctx context.Context, r *models.ProductRevision) error {
+	before, err := json.Marshal(r.Before)
+	if err != nil {
+		return err
+	}
+	after, err := json.Marshal(r.After)
+	if err != nil {
+		return err
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO product_revisions (product_id, user_id, action, rolled_back_to, note, before_data, after_data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ProductID, r.UserID, r.Action, r.RolledBackTo, r.Note, string(before), string(after), r.CreatedAt)
+	if err != nil {
+		return err
+	}
+	r.ID, err = res.LastInsertId()
+	return err
+}
+
+// productRevisionColumns is the column list scanned by scanProductRevision.
+const productRevisionColumns = "id, product_id, user_id, action, rolled_back_to, note, before_data, after_data, created_at"
+
+func (s *productStore) Revisions(ctx context.Context, productID int64, limit int) ([]models.ProductRevision, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+productRevisionColumns+" FROM product_revisions WHERE product_id = ? ORDER BY id DESC LIMIT ?",
+		productID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	revisions := []models.ProductRevision{}
+	for rows.Next() {
+		r, err := scanProductRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *r)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *productStore) Revision(ctx context.Context, productID, revisionID int64) (*models.ProductRevision, error) {
+	r, err := scanProductRevision(s.db.QueryRowContext(ctx,
+		"SELECT "+productRevisionColumns+" FROM product_revisions WHERE id = ? AND product_id = ?",
+		revisionID, productID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return r, nil
+}
+
+func scanProductRevision(row interface{ Scan(...interface{}) error }) (*models.ProductRevision, error) {
+	var r models.ProductRevision
+	var before, after []byte
+	if err := row.Scan(&r.ID, &r.ProductID, &r.UserID, &r.Action, &r.RolledBackTo, &r.Note, &before, &after, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(before, &r.Before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &r.After); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,1401 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+	"github.com/01moynul/taptosell-golang/internal/pricing"
+	"github.com/01moynul/taptosell-golang/internal/shipping"
+	"github.com/google/uuid"
+	"github.com/gosimple/slug"
+)
+
+// ErrInvalidBrand is returned by GetOrCreateBrand for an unknown brand ID or an empty name.
+var ErrInvalidBrand = errors.New("invalid brandId")
+
+// ErrInvalidTag is returned by SetTags for a tag with nothing to slug (only
+// punctuation).
+var ErrInvalidTag = errors.New("tags must contain letters or digits")
+
+// ProductSearch holds the optional filters of the public catalogue search.
+// Empty fields are ignored.
+type ProductSearch struct {
+	Query      string
+	CategoryID string // the category or any below it
+	BrandID    string
+	Tags       []string // slugs; the product must carry every one
+	MinPrice   string
+	MaxPrice   string
+	SupplierID int64 // 0: every supplier
+
+	// Sort is one of the Search* orders; "" pages on created_at as the page
+	// says (page.OldestFirst).
+	Sort string
+
+	// WithRelations attaches categories, brands, tags and variants to the results.
+	WithRelations bool
+}
+
+// Catalogue search orders (ProductSearch.Sort).
+const (
+	SearchRelevance = "relevance" // best match first; newest without a Query
+	SearchNewest    = "newest"
+	SearchPriceAsc  = "price_asc"
+	SearchPriceDesc = "price_desc"
+)
+
+// order is the search's effective order.
+func (f ProductSearch) order() string {
+	if f.Sort == SearchRelevance && fullTextQuery(f.Query) == "" {
+		return SearchNewest
+	}
+	return f.Sort
+}
+
+// Cursor is the cursor of p in the search's order: ranked and price orders
+// page on the score or the price, so their cursors carry it.
+func (f ProductSearch) Cursor(p *models.Product) pagination.Cursor {
+	c := pagination.Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+	switch f.order() {
+	case SearchRelevance:
+		if p.Relevance != nil {
+			c.Value = strconv.FormatFloat(*p.Relevance, 'g', -1, 64)
+		}
+	case SearchPriceAsc, SearchPriceDesc:
+		c.Value = p.PriceToTTS.String()
+	}
+	return c
+}
+
+// ProductStore owns 'products' and its relation tables
+// (product_categories, product_brands, product_variants, brands).
+type ProductStore interface {
+	// Create inserts the product row and sets p.ID (and p.PublicID when empty).
+	Create(ctx context.Context, p *models.Product) error
+	// Get loads a product with its categories, brands and variants.
+	Get(ctx context.Context, id int64) (*models.Product, error)
+	// GetActive loads a product like Get, but only while it is visible in the
+	// catalogue (as Search decides), with the vacation marks; read from the replica.
+	GetActive(ctx context.Context, id int64) (*models.Product, error)
+	// Related returns, for each of productIDs, up to limit other catalogue products
+	// sharing its categories (most shared first, then most reviewed), without relations.
+	Related(ctx context.Context, productIDs []int64, limit int) (map[int64][]*models.Product, error)
+	// GetOwned loads a product only if it belongs to supplierID.
+	GetOwned(ctx context.Context, id, supplierID int64) (*models.Product, error)
+	// GetForUpdate loads and row-locks a product; use it on a transaction-bound store.
+	GetForUpdate(ctx context.Context, id int64) (*models.Product, error)
+	// Update sets the given columns (plus updated_at) and bumps the version, but only
+	// while the row is still at version; otherwise it returns ErrConflict (or ErrNotFound).
+	// Unknown columns are rejected.
+	Update(ctx context.Context, id int64, version int, changes map[string]interface{}) error
+	// Delete soft-deletes a supplier's product and reports whether a live row matched.
+	// Order history keeps pointing at it; Restore (or the retention job) decides its fate.
+	Delete(ctx context.Context, id, supplierID int64) (bool, error)
+	// Restore brings back a supplier's soft-deleted product and reports whether one matched.
+	Restore(ctx context.Context, id, supplierID int64) (bool, error)
+	// Purge hard-deletes a soft-deleted product with its variants, links, cart
+	// lines and stock history. It returns ErrNotFound when the product is not
+	// deleted and ErrReferenced while order lines point at it. Use it on a
+	// transaction-bound store.
+	Purge(ctx context.Context, id int64) error
+
+	// ListBySupplier returns one page of a supplier's products (status optional;
+	// "deleted" lists the soft-deleted ones instead), newest first.
+	// It fetches page.Limit+1 rows so the caller can pass the result to pagination.Paginate.
+	ListBySupplier(ctx context.Context, supplierID int64, status string, page pagination.Page) ([]*models.Product, error)
+	// CountBySupplier counts what ListBySupplier pages through.
+	CountBySupplier(ctx context.Context, supplierID int64, status string) (int, error)
+	// ListByStatus returns one page of products in a status, oldest first (review queues),
+	// whatever page.OldestFirst says. Like ListBySupplier it fetches page.Limit+1 rows.
+	ListByStatus(ctx context.Context, status string, page pagination.Page) ([]*models.Product, error)
+	// CountByStatus counts the products in a status.
+	CountByStatus(ctx context.Context, status string) (int, error)
+	// Search returns one page of active products matching f (page.Limit+1 rows)
+	// in f's order, read from the replica. Page with f.Cursor; a cursor of
+	// another order is pagination.ErrInvalidCursor.
+	Search(ctx context.Context, f ProductSearch, page pagination.Page) ([]*models.Product, error)
+	// Count counts every active product matching f, read from the replica.
+	Count(ctx context.Context, f ProductSearch) (int, error)
+	// Facets counts the products matching f per category, brand and price
+	// range (see models.SearchFacets), in one query on the replica.
+	Facets(ctx context.Context, f ProductSearch) (*models.SearchFacets, error)
+	// LoadRelations attaches categories, brands and variants with one query per relation.
+	LoadRelations(ctx context.Context, products []*models.Product) error
+	// Changes returns the products changed after the position after (changed_at, id),
+	// oldest change first, up to limit+1 rows; created and updated entries carry the
+	// product with its relations. Changes younger than settle are left for the next call.
+	Changes(ctx context.Context, after pagination.Cursor, settle time.Duration, limit int) ([]*models.ProductChange, error)
+
+	// GetOrCreateBrand validates brandID, or finds/creates a brand by name when brandID is nil.
+	GetOrCreateBrand(ctx context.Context, brandID *int64, name string) (int64, error)
+	// SetCategories replaces the product's category links.
+	SetCategories(ctx context.Context, productID int64, categoryIDs []int64) error
+	// SetBrand replaces the product's brand link.
+	SetBrand(ctx context.Context, productID, brandID int64) error
+	// SetTags replaces the product's tags, creating the ones that do not exist
+	// yet (matched by slug).
+	SetTags(ctx context.Context, productID int64, names []string) error
+	// SetVariants replaces the product's variants.
+	SetVariants(ctx context.Context, productID int64, variants []models.ProductVariant) error
+	// VariantsForUpdate loads and row-locks the product's variants, oldest first;
+	// use it on a transaction-bound store.
+	VariantsForUpdate(ctx context.Context, productID int64) ([]models.ProductVariant, error)
+	// AddVariants inserts variants for the product next to the ones it has.
+	AddVariants(ctx context.Context, productID int64, variants []models.ProductVariant) error
+	// UpdateVariant rewrites the SKU, price, stock, options and commission of v
+	// (matched on v.ID and v.ProductID), keeping its ID for carts and orders.
+	UpdateVariant(ctx context.Context, v models.ProductVariant) error
+	// DeleteVariants removes the product's variants with the given IDs and the
+	// cart lines holding them. Order lines keep the variant ID they were placed with.
+	DeleteVariants(ctx context.Context, productID int64, ids []int64) error
+	// AdjustStock adds delta (negative to reserve) to the variant's stock, or the product's when variantID is nil.
+	AdjustStock(ctx context.Context, productID int64, variantID *int64, delta int) error
+	// StockForUpdate row-locks and returns the stock of the product's variant, or
+	// of the product when variantID is nil; ErrNotFound when the variant is not
+	// the product's. Use it on a transaction-bound store.
+	StockForUpdate(ctx context.Context, productID int64, variantID *int64) (int, error)
+	// RecordStockMovement appends m to the product's stock history and sets m.ID.
+	RecordStockMovement(ctx context.Context, m *models.StockMovement) error
+	// StockMovements returns a page of the product's stock movements, newest first.
+	StockMovements(ctx context.Context, productID int64, page pagination.Page) ([]models.StockMovement, error)
+	// ReservePreorder adds delta (negative to release) to the units held by open pre-orders.
+	// Like RefreshRating it leaves the version alone.
+	ReservePreorder(ctx context.Context, productID int64, delta int) error
+	// RefreshRating recomputes rating_avg and rating_count from the product's visible
+	// reviews. It leaves the version alone: a review is not an edit of the product.
+	RefreshRating(ctx context.Context, productID int64) error
+
+	// Snapshot loads the product's editable content, as kept in its revisions.
+	Snapshot(ctx context.Context, id int64) (*models.ProductSnapshot, error)
+	// AddRevision records a change to a product and sets r.ID.
+	AddRevision(ctx context.Context, r *models.ProductRevision) error
+	// Revisions returns up to limit of the product's revisions, newest first.
+	Revisions(ctx context.Context, productID int64, limit int) ([]models.ProductRevision, error)
+	// Revision loads one revision of the product (ErrNotFound for another product's).
+	Revision(ctx context.Context, productID, revisionID int64) (*models.ProductRevision, error)
+
+	// LockSKUs serializes SKU checks and writes for a supplier until the transaction
+	// ends; use it on a transaction-bound store before FindSKU.
+	LockSKUs(ctx context.Context, supplierID int64) error
+	// FindSKU returns the supplier's product or variant using sku, ignoring
+	// excludeProductID (0 for none), or ErrNotFound when the SKU is free.
+	// Soft-deleted products keep their SKUs.
+	FindSKU(ctx context.Context, supplierID int64, sku string, excludeProductID int64) (*models.SKUUse, error)
+	// NextSKUSeq advances and returns the supplier's SKU counter; use it on a transaction-bound store.
+	NextSKUSeq(ctx context.Context, supplierID int64) (int64, error)
+	// PeekSKUSeq returns the number NextSKUSeq would give next, without advancing it.
+	PeekSKUSeq(ctx context.Context, supplierID int64) (int64, error)
+
+	// FindBarcode returns the supplier's product or variant using any of forms
+	// (see barcode.Forms), ignoring excludeProductID (0 for none), or
+	// ErrNotFound when the barcode is free. Use it under LockSKUs.
+	FindBarcode(ctx context.Context, supplierID int64, forms []string, excludeProductID int64) (*models.SKUUse, error)
+	// LookupBarcode returns the catalogue products (and variants) carrying any
+	// of forms, across suppliers, at most limit of them.
+	LookupBarcode(ctx context.Context, forms []string, limit int) ([]models.BarcodeMatch, error)
+}
+
+type productStore struct {
+	db   DBTX // primary (or the transaction)
+	read DBTX // replica for Search
+}
+
+// productColumns is the column list every product listing scans with scanProduct.
+const productColumns = `
+	p.id, p.public_id, p.supplier_id, p.sku, p.barcode, p.name, p.description,
+	p.price_to_tts, p.stock_quantity, p.srp, p.is_variable, p.status, p.rejection_reason,
+	p.created_at, p.updated_at, p.version,
+	p.weight, p.pkg_length, p.pkg_width, p.pkg_height, p.commission_rate,
+	p.images, p.variation_images, p.rating_avg, p.rating_count,
+	p.is_preorder, p.preorder_available_at, p.preorder_limit, p.preorder_reserved,
+	p.shipping_restrictions, p.min_order_qty, p.order_increment`
+
+// scanProduct reads one row of productColumns.
+// scanProduct scans a row of productColumns, then any extra columns into extra.
+func scanProduct(rows *sql.Rows, extra ...interface{}) (*models.Product, error) {
+	var p models.Product
+	var dbImages, dbVariationImages []byte // JSON columns
+	var restrictions string
+
+	dest := []interface{}{
+		&p.ID, &p.PublicID, &p.SupplierID, &p.SKU, &p.Barcode, &p.Name, &p.Description,
+		&p.PriceToTTS, &p.StockQuantity, &p.SRP, &p.IsVariable, &p.Status, &p.RejectionReason,
+		&p.CreatedAt, &p.UpdatedAt, &p.Version,
+		&p.Weight, &p.PkgLength, &p.PkgWidth, &p.PkgHeight, &p.CommissionRate,
+		&dbImages, &dbVariationImages, &p.RatingAvg, &p.RatingCount,
+		&p.IsPreorder, &p.PreorderAvailableAt, &p.PreorderLimit, &p.PreorderReserved,
+		&restrictions, &p.MinOrderQty, &p.OrderIncrement,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	p.ShippingRestrictions = shipping.Split(restrictions)
+	p.Pricing = breakdown(&p)
+
+	// Always initialise images to avoid "null" in JSON
+	p.Images = []string{}
+	if len(dbImages) > 0 {
+		_ = json.Unmarshal(dbImages, &p.Images)
+	}
+	if len(dbVariationImages) > 0 {
+		_ = json.Unmarshal(dbVariationImages, &p.VariationImages)
+	}
+	return &p, nil
+}
+
+// breakdown is the pricing of a loaded product, at its (lowest) price.
+func breakdown(p *models.Product) *pricing.Breakdown {
+	b := pricing.Compute(p.PriceToTTS, p.SRP, p.CommissionRate)
+	return &b
+}
+
+// queryProducts runs a productColumns query and scans every row.
+func queryProducts(ctx context.Context, db DBTX, query string, args ...interface{}) ([]*models.Product, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+func (s *productStore) Create(ctx context.Context, p *models.Product) error {
+	imagesJSON, _ := json.Marshal(p.Images)
+	sizeChartJSON, _ := json.Marshal(p.SizeChart)
+	variationImagesJSON, _ := json.Marshal(p.VariationImages)
+
+	// 'category' and 'brand' are legacy text columns; the real links live in the relation tables.
+	brandLegacy := p.BrandName
+	if brandLegacy == "" {
+		brandLegacy = "Generic"
+	}
+
+	query := `
+		INSERT INTO products
+		(public_id, supplier_id, name, description, price_to_tts, stock_quantity, sku, barcode,
+		is_variable, status, created_at, updated_at,
+		weight, pkg_length, pkg_width, pkg_height, commission_rate,
+		category, brand, srp, weight_grams,
+		images, video_url, size_chart, variation_images,
+		is_preorder, preorder_available_at, preorder_limit, shipping_restrictions,
+		min_order_qty, order_increment, handling_days, order_cutoff)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	if p.PublicID == "" {
+		p.PublicID = uuid.NewString()
+	}
+	result, err := s.db.ExecContext(ctx, query,
+		p.PublicID, p.SupplierID, p.Name, p.Description,
+		p.PriceToTTS, p.StockQuantity, p.SKU, barcodeArg(p.Barcode),
+		p.IsVariable, p.Status, p.CreatedAt, p.UpdatedAt,
+		p.Weight, p.PkgLength, p.PkgWidth, p.PkgHeight, p.CommissionRate,
+		"Uncategorized", brandLegacy, p.SRP, p.WeightGrams,
+		string(imagesJSON), p.VideoURL, string(sizeChartJSON), string(variationImagesJSON),
+		p.IsPreorder, p.PreorderAvailableAt, p.PreorderLimit, shipping.Join(p.ShippingRestrictions),
+		max(p.MinOrderQty, 1), max(p.OrderIncrement, 1), p.HandlingDays, p.OrderCutoff,
+	)
+	if err != nil {
+		return err
+	}
+	p.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s *productStore) Get(ctx context.Context, id int64) (*models.Product, error) {
+	return getProduct(ctx, s.db, id)
+}
+
+// InCatalogue is the predicate "product <alias> is visible in the catalogue":
+// active, not deleted, and not hidden by its supplier's vacation. Like
+// SupplierAway it takes the current time as its one argument.
+func InCatalogue(alias string) string {
+	return alias + ".status = 'active' AND " + NotDeleted(alias) + " AND NOT EXISTS (" +
+		"SELECT 1 FROM users s WHERE s.id = " + alias + ".supplier_id AND s.vacation_hide_listings = 1 AND " + SupplierAway("s") + ")"
+}
+
+func (s *productStore) GetActive(ctx context.Context, id int64) (*models.Product, error) {
+	var one int
+	err := s.read.QueryRowContext(ctx, "SELECT 1 FROM products p WHERE p.id = ? AND "+InCatalogue("p"),
+		id, time.Now()).Scan(&one)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	p, err := getProduct(ctx, s.read, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := markSuppliersAway(ctx, s.read, []*models.Product{p}); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// getProduct is Get on db.
+func getProduct(ctx context.Context, db DBTX, id int64) (*models.Product, error) {
+	query := `
+		SELECT
+			id, public_id, supplier_id, name, description, status, rejection_reason, is_variable,
+			sku, barcode, price_to_tts, srp, stock_quantity, commission_rate,
+			weight, pkg_length, pkg_width, pkg_height,
+			images, video_url, size_chart, variation_images,
+			brand, rating_avg, rating_count, created_at, updated_at, version,
+			is_preorder, preorder_available_at, preorder_limit, preorder_reserved,
+			shipping_restrictions, min_order_qty, order_increment, handling_days, order_cutoff
+		FROM products
+		WHERE id = ? AND deleted_at IS NULL`
+
+	var p models.Product
+	var dbImages, dbSizeChart, dbVariationImages []byte
+	var dbVideoURL, dbBrandName sql.NullString
+	var restrictions string
+
+	err := db.QueryRowContext(ctx, query, id).Scan(
+		&p.ID, &p.PublicID, &p.SupplierID, &p.Name, &p.Description, &p.Status, &p.RejectionReason, &p.IsVariable,
+		&p.SKU, &p.Barcode, &p.PriceToTTS, &p.SRP, &p.StockQuantity, &p.CommissionRate,
+		&p.Weight, &p.PkgLength, &p.PkgWidth, &p.PkgHeight,
+		&dbImages, &dbVideoURL, &dbSizeChart, &dbVariationImages,
+		&dbBrandName, &p.RatingAvg, &p.RatingCount, &p.CreatedAt, &p.UpdatedAt, &p.Version,
+		&p.IsPreorder, &p.PreorderAvailableAt, &p.PreorderLimit, &p.PreorderReserved,
+		&restrictions, &p.MinOrderQty, &p.OrderIncrement, &p.HandlingDays, &p.OrderCutoff,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	p.ShippingRestrictions = shipping.Split(restrictions)
+	p.Pricing = breakdown(&p)
+	p.VideoURL = dbVideoURL.String
+	p.BrandName = dbBrandName.String
+
+	p.Images = []string{}
+	if len(dbImages) > 0 {
+		_ = json.Unmarshal(dbImages, &p.Images)
+	}
+	if len(dbSizeChart) > 0 {
+		_ = json.Unmarshal(dbSizeChart, &p.SizeChart)
+	}
+	p.VariationImages = map[string]string{}
+	if len(dbVariationImages) > 0 {
+		_ = json.Unmarshal(dbVariationImages, &p.VariationImages)
+	}
+
+	if err := loadRelations(ctx, db, []*models.Product{&p}); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *productStore) Related(ctx context.Context, productIDs []int64, limit int) (map[int64][]*models.Product, error) {
+	related := map[int64][]*models.Product{}
+	if len(productIDs) == 0 || limit <= 0 {
+		return related, nil
+	}
+
+	// 1. --- Pick the IDs, ranked per source product ---
+	placeholders, args := inClause(productIDs)
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT src, id FROM (
+			SELECT pc1.product_id AS src, p.id,
+				ROW_NUMBER() OVER (PARTITION BY pc1.product_id ORDER BY COUNT(*) DESC, p.rating_count DESC, p.id DESC) AS n
+			FROM product_categories pc1
+			JOIN product_categories pc2 ON pc2.category_id = pc1.category_id AND pc2.product_id <> pc1.product_id
+			JOIN products p ON p.id = pc2.product_id
+			WHERE pc1.product_id IN (`+placeholders+`) AND `+InCatalogue("p")+`
+			GROUP BY pc1.product_id, p.id
+		) ranked
+		WHERE n <= ?
+		ORDER BY src, n`,
+		append(append(args, time.Now()), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type pick struct{ src, id int64 }
+	var picks []pick
+	var ids []int64
+	seen := map[int64]bool{}
+	for rows.Next() {
+		var pk pick
+		if err := rows.Scan(&pk.src, &pk.id); err != nil {
+			return nil, err
+		}
+		picks = append(picks, pk)
+		if !seen[pk.id] {
+			seen[pk.id] = true
+			ids = append(ids, pk.id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return related, nil
+	}
+
+	// 2. --- Load them once, however many sources share them ---
+	placeholders, args = inClause(ids)
+	products, err := queryProducts(ctx, s.read, "SELECT "+productColumns+" FROM products p WHERE p.id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := markSuppliersAway(ctx, s.read, products); err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+	for _, pk := range picks {
+		if p := byID[pk.id]; p != nil {
+			related[pk.src] = append(related[pk.src], p)
+		}
+	}
+	return related, nil
+}
+
+func (s *productStore) GetOwned(ctx context.Context, id, supplierID int64) (*models.Product, error) {
+	var p models.Product
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, supplier_id, sku, status, price_to_tts, is_variable, is_preorder, preorder_reserved, version FROM products WHERE id = ? AND supplier_id = ? AND deleted_at IS NULL",
+		id, supplierID,
+	).Scan(&p.ID, &p.SupplierID, &p.SKU, &p.Status, &p.PriceToTTS, &p.IsVariable, &p.IsPreorder, &p.PreorderReserved, &p.Version)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &p, nil
+}
+
+func (s *productStore) GetForUpdate(ctx context.Context, id int64) (*models.Product, error) {
+	var p models.Product
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, supplier_id, status, price_to_tts, srp, commission_rate, is_variable, version FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE",
+		id,
+	).Scan(&p.ID, &p.SupplierID, &p.Status, &p.PriceToTTS, &p.SRP, &p.CommissionRate, &p.IsVariable, &p.Version)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &p, nil
+}
+
+// updatableProductColumns is the allowlist for Update; column names are
+// interpolated into the SQL, so they must never come from user input.
+var updatableProductColumns = map[string]bool{
+	"name": true, "description": true, "status": true, "is_variable": true,
+	"images": true, "video_url": true, "size_chart": true, "variation_images": true,
+	"weight": true, "weight_grams": true, "pkg_length": true, "pkg_width": true, "pkg_height": true,
+	"price_to_tts": true, "stock_quantity": true, "sku": true, "barcode": true, "srp": true, "commission_rate": true,
+	"is_preorder": true, "preorder_available_at": true, "preorder_limit": true,
+	"shipping_restrictions": true, "min_order_qty": true, "order_increment": true,
+	"handling_days": true, "order_cutoff": true, "rejection_reason": true,
+}
+
+func (s *productStore) Update(ctx context.Context, id int64, version int, changes map[string]interface{}) error {
+	// Sorted columns keep the SQL text stable for the same set of changes.
+	columns := make([]string, 0, len(changes))
+	for col := range changes {
+		if !updatableProductColumns[col] {
+			return fmt.Errorf("store: column %q is not updatable", col)
+		}
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	set := "updated_at = ?, version = version + 1"
+	args := []interface{}{time.Now()}
+	for _, col := range columns {
+		set += ", " + col + " = ?"
+		args = append(args, changes[col])
+	}
+	args = append(args, id, version)
+
+	result, err := s.db.ExecContext(ctx, "UPDATE products SET "+set+" WHERE id = ? AND version = ? AND deleted_at IS NULL", args...)
+	if err != nil {
+		return err
+	}
+	// The version bump guarantees a matched row is also a changed row,
+	// so 0 rows means the id or the version no longer matches.
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM products WHERE id = ? AND deleted_at IS NULL)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+func (s *productStore) Delete(ctx context.Context, id, supplierID int64) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE products SET deleted_at = ?, version = version + 1 WHERE id = ? AND supplier_id = ? AND deleted_at IS NULL",
+		time.Now(), id, supplierID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (s *productStore) Restore(ctx context.Context, id, supplierID int64) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE products SET deleted_at = NULL, version = version + 1 WHERE id = ? AND supplier_id = ? AND deleted_at IS NOT NULL",
+		id, supplierID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// productPurgeTables hold rows that belong to a product and go with it.
+var productPurgeTables = []string{
+	"product_variants", "product_categories", "product_brands", "cart_items",
+	"stock_movements", "channel_listings", "product_questions", "price_appeals", "low_stock_alerts",
+	"product_revisions",
+}
+
+func (s *productStore) Purge(ctx context.Context, id int64) error {
+	var one int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT 1 FROM products WHERE id = ? AND deleted_at IS NOT NULL FOR UPDATE", id).Scan(&one)
+	if err != nil {
+		return notFound(err)
+	}
+	var referenced bool
+	err = s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM order_items WHERE product_id = ?)", id).Scan(&referenced)
+	if err != nil {
+		return err
+	}
+	if referenced {
+		return ErrReferenced
+	}
+
+	// Table names come from productPurgeTables, never from input.
+	for _, table := range productPurgeTables {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM "+table+" WHERE product_id = ?", id); err != nil {
+			return err
+		}
+	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM products WHERE id = ?", id)
+	return err
+}
+
+func (s *productStore) ListBySupplier(ctx context.Context, supplierID int64, status string, page pagination.Page) ([]*models.Product, error) {
+	where, args := supplierFilter(supplierID, status)
+
+	// Keyset pagination on (created_at, id)
+	cursorCond, cursorArgs := page.Where("p.created_at", "p.id")
+	query := "SELECT " + productColumns + " FROM products p" + where + cursorCond + page.OrderLimit("p.created_at", "p.id")
+	args = append(args, cursorArgs...)
+
+	return queryProducts(ctx, s.db, query, args...)
+}
+
+func (s *productStore) CountBySupplier(ctx context.Context, supplierID int64, status string) (int, error) {
+	where, args := supplierFilter(supplierID, status)
+	var n int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM products p"+where, args...).Scan(&n)
+	return n, err
+}
+
+// supplierFilter is the WHERE clause shared by ListBySupplier and CountBySupplier.
+func supplierFilter(supplierID int64, status string) (string, []interface{}) {
+	args := []interface{}{supplierID}
+	if status == "deleted" {
+		return " WHERE p.supplier_id = ? AND p.deleted_at IS NOT NULL", args
+	}
+	where := " WHERE p.supplier_id = ? AND " + NotDeleted("p")
+	if status != "" {
+		where += " AND p.status = ?"
+		args = append(args, status)
+	}
+	return where, args
+}
+
+func (s *productStore) ListByStatus(ctx context.Context, status string, page pagination.Page) ([]*models.Product, error) {
+	page.OldestFirst = true
+	cursorCond, cursorArgs := page.Where("p.created_at", "p.id")
+	query := "SELECT " + productColumns + " FROM products p WHERE p.status = ? AND " + NotDeleted("p") +
+		cursorCond + page.OrderLimit("p.created_at", "p.id")
+	return queryProducts(ctx, s.db, query, append([]interface{}{status}, cursorArgs...)...)
+}
+
+func (s *productStore) CountByStatus(ctx context.Context, status string) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM products p WHERE p.status = ? AND "+NotDeleted("p"), status).Scan(&n)
+	return n, err
+}
+
+func (s *productStore) Search(ctx context.Context, f ProductSearch, page pagination.Page) ([]*models.Product, error) {
+	order := f.order()
+	ranked := order == SearchRelevance
+	keyed := ranked || order == SearchPriceAsc || order == SearchPriceDesc
+	if page.After != nil && (page.After.Value != "") != keyed {
+		return nil, pagination.ErrInvalidCursor
+	}
+
+	var b strings.Builder
+	var args []interface{}
+	b.WriteString("SELECT DISTINCT " + productColumns)
+	if ranked {
+		b.WriteString(", " + productMatch + " AS relevance")
+		args = append(args, fullTextQuery(f.Query))
+	}
+	b.WriteString(" FROM products p")
+	args = append(args, writeSearchFilter(&b, f)...)
+
+	// Keyset pagination on (sort column, id)
+	switch order {
+	case SearchRelevance:
+		// The score is an expression, so the seek condition spells it out again.
+		if page.After != nil {
+			terms, score := fullTextQuery(f.Query), page.After.Value
+			b.WriteString(" AND (" + productMatch + " < ? OR (" + productMatch + " = ? AND p.id < ?))")
+			args = append(args, terms, score, terms, score, page.After.ID)
+		}
+		fmt.Fprintf(&b, " ORDER BY relevance DESC, p.id DESC LIMIT %d", page.Limit+1)
+	case SearchPriceAsc, SearchPriceDesc:
+		page.OldestFirst = order == SearchPriceAsc
+		cursorCond, cursorArgs := page.Where("p.price_to_tts", "p.id")
+		b.WriteString(cursorCond)
+		args = append(args, cursorArgs...)
+		b.WriteString(page.OrderLimit("p.price_to_tts", "p.id"))
+	default:
+		if order == SearchNewest {
+			page.OldestFirst = false
+		}
+		cursorCond, cursorArgs := page.Where("p.created_at", "p.id")
+		b.WriteString(cursorCond)
+		args = append(args, cursorArgs...)
+		b.WriteString(page.OrderLimit("p.created_at", "p.id"))
+	}
+
+	rows, err := s.read.QueryContext(ctx, b.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var products []*models.Product
+	for rows.Next() {
+		var score float64
+		var extra []interface{}
+		if ranked {
+			extra = append(extra, &score)
+		}
+		p, err := scanProduct(rows, extra...)
+		if err != nil {
+			return nil, err
+		}
+		if ranked {
+			p.Relevance = &score
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Only the visible page needs relations and vacation marks; the lookahead row is dropped by Paginate.
+	visible := products
+	if len(visible) > page.Limit {
+		visible = visible[:page.Limit]
+	}
+	if f.WithRelations {
+		if err := loadRelations(ctx, s.read, visible); err != nil {
+			return nil, err
+		}
+	}
+	if err := markSuppliersAway(ctx, s.read, visible); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (s *productStore) Count(ctx context.Context, f ProductSearch) (int, error) {
+	var b strings.Builder
+	b.WriteString("SELECT COUNT(DISTINCT p.id) FROM products p")
+	args := writeSearchFilter(&b, f)
+
+	var n int
+	err := s.read.QueryRowContext(ctx, b.String(), args...).Scan(&n)
+	return n, err
+}
+
+// facetLimit caps the categories and brands listed in search facets.
+const facetLimit = 20
+
+// priceFacetBounds split the price facet into ranges: below RM 10, RM 10-25,
+// and so on up to RM 250 and above.
+var priceFacetBounds = []money.Money{1000, 2500, 5000, 10000, 25000}
+
+func (s *productStore) Facets(ctx context.Context, f ProductSearch) (*models.SearchFacets, error) {
+	// One UNION ALL of the three counts; each facet drops its own filter.
+	var b strings.Builder
+	var args []interface{}
+
+	byCategory := f
+	byCategory.CategoryID = ""
+	b.WriteString("SELECT 'category', c.id, c.name, COUNT(DISTINCT p.id) FROM products p" +
+		" JOIN product_categories fc ON fc.product_id = p.id JOIN categories c ON c.id = fc.category_id")
+	args = append(args, writeSearchFilter(&b, byCategory)...)
+	b.WriteString(" GROUP BY c.id, c.name")
+
+	byBrand := f
+	byBrand.BrandID = ""
+	b.WriteString(" UNION ALL SELECT 'brand', br.id, br.name, COUNT(DISTINCT p.id) FROM products p" +
+		" JOIN product_brands fb ON fb.product_id = p.id JOIN brands br ON br.id = fb.brand_id")
+	args = append(args, writeSearchFilter(&b, byBrand)...)
+	b.WriteString(" GROUP BY br.id, br.name")
+
+	byPrice := f
+	byPrice.MinPrice, byPrice.MaxPrice = "", ""
+	bucket := "CASE"
+	for i, bound := range priceFacetBounds {
+		bucket += fmt.Sprintf(" WHEN p.price_to_tts < %s THEN %d", bound, i)
+	}
+	bucket += fmt.Sprintf(" ELSE %d END", len(priceFacetBounds))
+	b.WriteString(" UNION ALL SELECT 'price', " + bucket + " AS bucket, '', COUNT(DISTINCT p.id) FROM products p")
+	args = append(args, writeSearchFilter(&b, byPrice)...)
+	b.WriteString(" GROUP BY bucket")
+
+	rows, err := s.read.QueryContext(ctx, b.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := &models.SearchFacets{Categories: []models.FacetCount{}, Brands: []models.FacetCount{}}
+	for i := range len(priceFacetBounds) + 1 {
+		bucket := models.PriceBucket{}
+		if i > 0 {
+			bucket.Min = priceFacetBounds[i-1]
+		}
+		if i < len(priceFacetBounds) {
+			bucket.Max = &priceFacetBounds[i]
+		}
+		facets.Prices = append(facets.Prices, bucket)
+	}
+	for rows.Next() {
+		var kind string
+		var fc models.FacetCount
+		if err := rows.Scan(&kind, &fc.ID, &fc.Name, &fc.Count); err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "category":
+			facets.Categories = append(facets.Categories, fc)
+		case "brand":
+			facets.Brands = append(facets.Brands, fc)
+		case "price":
+			facets.Prices[fc.ID].Count = fc.Count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	facets.Categories = topFacets(facets.Categories)
+	facets.Brands = topFacets(facets.Brands)
+	return facets, nil
+}
+
+// topFacets orders counts by products (then name) and keeps the first facetLimit.
+func topFacets(counts []models.FacetCount) []models.FacetCount {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	if len(counts) > facetLimit {
+		counts = counts[:facetLimit]
+	}
+	return counts
+}
+
+// writeSearchFilter appends the joins and WHERE clause of a catalogue search
+// to b and returns their arguments.
+func writeSearchFilter(b *strings.Builder, f ProductSearch) []interface{} {
+	var args []interface{}
+	if f.CategoryID != "" {
+		b.WriteString(" JOIN product_categories pc ON p.id = pc.product_id")
+	}
+	if f.BrandID != "" {
+		b.WriteString(" JOIN product_brands pb ON p.id = pb.product_id")
+	}
+
+	// Only 'active' products are visible in the catalogue, minus those of
+	// suppliers on vacation who chose to hide their listings.
+	b.WriteString(" WHERE p.status = ? AND " + NotDeleted("p"))
+	b.WriteString(" AND NOT EXISTS (SELECT 1 FROM users s WHERE s.id = p.supplier_id AND s.vacation_hide_listings = 1 AND " + SupplierAway("s") + ")")
+	args = append(args, "active", time.Now())
+
+	if f.CategoryID != "" {
+		b.WriteString(" AND pc.category_id IN (" + categorySubtree + ")")
+		args = append(args, f.CategoryID)
+	}
+	if f.BrandID != "" {
+		b.WriteString(" AND pb.brand_id = ?")
+		args = append(args, f.BrandID)
+	}
+	if f.SupplierID != 0 {
+		b.WriteString(" AND p.supplier_id = ?")
+		args = append(args, f.SupplierID)
+	}
+	if len(f.Tags) > 0 {
+		placeholders := "?" + strings.Repeat(", ?", len(f.Tags)-1)
+		b.WriteString(" AND p.id IN (SELECT pt.product_id FROM product_tags pt JOIN tags t ON pt.tag_id = t.id" +
+			" WHERE t.slug IN (" + placeholders + ") GROUP BY pt.product_id HAVING COUNT(*) = ?)")
+		for _, tag := range f.Tags {
+			args = append(args, tag)
+		}
+		args = append(args, len(f.Tags))
+	}
+	if f.MinPrice != "" {
+		b.WriteString(" AND p.price_to_tts >= ?")
+		args = append(args, f.MinPrice)
+	}
+	if f.MaxPrice != "" {
+		b.WriteString(" AND p.price_to_tts <= ?")
+		args = append(args, f.MaxPrice)
+	}
+	if f.Query != "" {
+		terms := fullTextQuery(f.Query)
+		if terms == "" {
+			// Nothing searchable (only punctuation): nothing matches.
+			b.WriteString(" AND FALSE")
+		} else {
+			b.WriteString(" AND " + productMatch)
+			args = append(args, terms)
+		}
+	}
+	return args
+}
+
+// categorySubtree selects a category's ID and those of every category below
+// it, so filtering by a parent finds the products linked to its children.
+// UNION (not UNION ALL) ends the walk even if the parents loop.
+const categorySubtree = "WITH RECURSIVE subtree (id) AS (" +
+	"SELECT id FROM categories WHERE id = ?" +
+	" UNION SELECT sc.id FROM categories sc JOIN subtree ON sc.parent_id = subtree.id" +
+	") SELECT id FROM subtree"
+
+// productMatch is the full-text match of a search against the
+// ft_products_search index; its value is the relevance score.
+const productMatch = "MATCH(p.name, p.description) AGAINST (? IN BOOLEAN MODE)"
+
+// fullTextQuery turns what a shopper typed into a boolean-mode query: every
+// word must match, as a prefix ("sho" finds "shoes"). The search operators
+// are not theirs to use, so anything but letters and digits splits words.
+func fullTextQuery(q string) string {
+	words := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = "+" + w + "*"
+	}
+	return strings.Join(words, " ")
+}
+
+func (s *productStore) LoadRelations(ctx context.Context, products []*models.Product) error {
+	return loadRelations(ctx, s.db, products)
+}
+
+func (s *productStore) Changes(ctx context.Context, after pagination.Cursor, settle time.Duration, limit int) ([]*models.ProductChange, error) {
+	// Read from the primary: a lagging replica would let the client's position
+	// move past rows it has not received yet. For the same reason the newest
+	// changes wait until transactions that stamped them have had time to commit.
+	now := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.id, p.public_id, p.created_at, p.changed_at, `+InCatalogue("p")+`
+		FROM products p
+		WHERE (p.changed_at > ? OR (p.changed_at = ? AND p.id > ?))
+			AND p.changed_at <= NOW(3) - INTERVAL ? MICROSECOND
+		ORDER BY p.changed_at, p.id
+		LIMIT ?`,
+		now, after.CreatedAt, after.CreatedAt, after.ID, settle.Microseconds(), limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*models.ProductChange
+	var visibleIDs []int64
+	for rows.Next() {
+		var ch models.ProductChange
+		var createdAt time.Time
+		var visible bool
+		if err := rows.Scan(&ch.ProductID, &ch.PublicID, &createdAt, &ch.ChangedAt, &visible); err != nil {
+			return nil, err
+		}
+		switch {
+		case !visible:
+			ch.Type = "deleted"
+		case createdAt.After(after.CreatedAt):
+			ch.Type = "created"
+		default:
+			ch.Type = "updated"
+		}
+		if visible && len(changes) < limit { // the lookahead row is dropped by Paginate
+			visibleIDs = append(visibleIDs, ch.ProductID)
+		}
+		changes = append(changes, &ch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(visibleIDs) == 0 {
+		return changes, nil
+	}
+
+	// Payloads for the products still in the catalogue.
+	placeholders, args := inClause(visibleIDs)
+	products, err := queryProducts(ctx, s.db, "SELECT "+productColumns+" FROM products p WHERE p.id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadRelations(ctx, s.db, products); err != nil {
+		return nil, err
+	}
+	if err := markSuppliersAway(ctx, s.db, products); err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+	for _, ch := range changes {
+		if ch.Type != "deleted" {
+			ch.Product = byID[ch.ProductID]
+		}
+	}
+	return changes, nil
+}
+
+func (s *productStore) GetOrCreateBrand(ctx context.Context, brandID *int64, name string) (int64, error) {
+	if brandID != nil {
+		var exists int
+		if err := s.db.QueryRowContext(ctx, "SELECT 1 FROM brands WHERE id = ?", *brandID).Scan(&exists); err != nil {
+			return 0, ErrInvalidBrand
+		}
+		return *brandID, nil
+	}
+	if name == "" {
+		return 0, ErrInvalidBrand
+	}
+
+	var existingID int64
+	brandSlug := slug.Make(name)
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM brands WHERE slug = ?", brandSlug).Scan(&existingID)
+	if err == nil {
+		return existingID, nil
+	}
+
+	res, err := s.db.ExecContext(ctx, "INSERT INTO brands (name, slug) VALUES (?, ?)", name, brandSlug)
+	if _, dup := DuplicateKey(err); dup {
+		// Created concurrently (or a different name with the same slug): use it.
+		err = s.db.QueryRowContext(ctx, "SELECT id FROM brands WHERE slug = ?", brandSlug).Scan(&existingID)
+		return existingID, err
+	}
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *productStore) SetCategories(ctx context.Context, productID int64, categoryIDs []int64) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM product_categories WHERE product_id = ?", productID); err != nil {
+		return err
+	}
+	return bulkInsert(ctx, s.db,
+		"INSERT INTO product_categories (product_id, category_id) VALUES ",
+		"(?, ?)", len(categoryIDs),
+		func(i int) ([]interface{}, error) {
+			return []interface{}{productID, categoryIDs[i]}, nil
+		})
+}
+
+func (s *productStore) SetBrand(ctx context.Context, productID, brandID int64) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM product_brands WHERE product_id = ?", productID); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, "INSERT INTO product_brands (product_id, brand_id) VALUES (?, ?)", productID, brandID)
+	return err
+}
+
+func (s *productStore) SetTags(ctx context.Context, productID int64, names []string) error {
+	var ids []int64
+	seen := map[int64]bool{}
+	for _, name := range names {
+		id, err := s.tagID(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM product_tags WHERE product_id = ?", productID); err != nil {
+		return err
+	}
+	return bulkInsert(ctx, s.db,
+		"INSERT INTO product_tags (product_id, tag_id) VALUES ",
+		"(?, ?)", len(ids),
+		func(i int) ([]interface{}, error) {
+			return []interface{}{productID, ids[i]}, nil
+		})
+}
+
+// tagID finds or creates the tag named name, by slug.
+func (s *productStore) tagID(ctx context.Context, name string) (int64, error) {
+	tagSlug := slug.Make(name)
+	if tagSlug == "" {
+		return 0, ErrInvalidTag
+	}
+	var id int64
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM tags WHERE slug = ?", tagSlug).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	res, err := s.db.ExecContext(ctx, "INSERT INTO tags (name, slug, created_at) VALUES (?, ?, ?)", name, tagSlug, time.Now())
+	if _, dup := DuplicateKey(err); dup {
+		// Created concurrently: use it.
+		err = s.db.QueryRowContext(ctx, "SELECT id FROM tags WHERE slug = ?", tagSlug).Scan(&id)
+		return id, err
+	}
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *productStore) SetVariants(ctx context.Context, productID int64, variants []models.ProductVariant) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM product_variants WHERE product_id = ?", productID); err != nil {
+		return err
+	}
+	return s.AddVariants(ctx, productID, variants)
+}
+
+func (s *productStore) VariantsForUpdate(ctx context.Context, productID int64) ([]models.ProductVariant, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM product_variants WHERE product_id = ? FOR UPDATE", productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	p := &models.Product{ID: productID}
+	if err := loadVariants(ctx, s.db, []int64{productID}, map[int64]*models.Product{productID: p}); err != nil {
+		return nil, err
+	}
+	return p.Variants, nil
+}
+
+func (s *productStore) UpdateVariant(ctx context.Context, v models.ProductVariant) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE product_variants
+		SET sku = ?, barcode = ?, price_to_tts = ?, stock_quantity = ?, options = ?, commission_rate = ?, updated_at = ?
+		WHERE id = ? AND product_id = ?`,
+		v.SKU, barcodeArg(v.Barcode), v.PriceToTTS, v.StockQuantity, v.Options, v.CommissionRate, time.Now(), v.ID, v.ProductID)
+	return err
+}
+
+func (s *productStore) DeleteVariants(ctx context.Context, productID int64, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders, args := inClause(ids)
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM cart_items WHERE variant_id IN ("+placeholders+")", args...); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, "DELETE FROM product_variants WHERE product_id = ? AND id IN ("+placeholders+")", append([]interface{}{productID}, args...)...)
+	return err
+}
+
+func (s *productStore) AddVariants(ctx context.Context, productID int64, variants []models.ProductVariant) error {
+	now := time.Now()
+	return bulkInsert(ctx, s.db,
+		"INSERT INTO product_variants (product_id, sku, barcode, price_to_tts, stock_quantity, options, commission_rate, created_at, updated_at) VALUES ",
+		"(?, ?, ?, ?, ?, ?, ?, ?, ?)", len(variants),
+		func(i int) ([]interface{}, error) {
+			v := variants[i]
+			return []interface{}{productID, v.SKU, barcodeArg(v.Barcode), v.PriceToTTS, v.StockQuantity, v.Options, v.CommissionRate, now, now}, nil
+		})
+}
+
+func (s *productStore) AdjustStock(ctx context.Context, productID int64, variantID *int64, delta int) error {
+	var err error
+	if variantID != nil && *variantID > 0 {
+		// Touch the product too, so the change feed picks up the new stock.
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE product_variants v JOIN products p ON p.id = v.product_id
+			SET v.stock_quantity = v.stock_quantity + ?, p.changed_at = CURRENT_TIMESTAMP(3)
+			WHERE v.id = ?`, delta, *variantID)
+	} else {
+		_, err = s.db.ExecContext(ctx, "UPDATE products SET stock_quantity = stock_quantity + ?, version = version + 1 WHERE id = ?", delta, productID)
+	}
+	return err
+}
+
+func (s *productStore) StockForUpdate(ctx context.Context, productID int64, variantID *int64) (int, error) {
+	var stock int
+	var err error
+	if variantID != nil {
+		err = s.db.QueryRowContext(ctx,
+			"SELECT stock_quantity FROM product_variants WHERE id = ? AND product_id = ? FOR UPDATE",
+			*variantID, productID).Scan(&stock)
+	} else {
+		err = s.db.QueryRowContext(ctx,
+			"SELECT stock_quantity FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE", productID).Scan(&stock)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return stock, err
+}
+
+func (s *productStore) RecordStockMovement(ctx context.Context, m *models.StockMovement) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO stock_movements (product_id, variant_id, user_id, delta, quantity_after, reason, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.ProductID, m.VariantID, m.UserID, m.Delta, m.QuantityAfter, m.Reason, m.Note, m.CreatedAt)
+	if err != nil {
+		return err
+	}
+	m.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s *productStore) StockMovements(ctx context.Context, productID int64, page pagination.Page) ([]models.StockMovement, error) {
+	cond, args := page.Where("created_at", "id")
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, product_id, variant_id, user_id, delta, quantity_after, reason, note, created_at
+		FROM stock_movements WHERE product_id = ?`+cond+page.OrderLimit("created_at", "id"),
+		append([]interface{}{productID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	movements := []models.StockMovement{}
+	for rows.Next() {
+		var m models.StockMovement
+		if err := rows.Scan(&m.ID, &m.ProductID, &m.VariantID, &m.UserID, &m.Delta, &m.QuantityAfter,
+			&m.Reason, &m.Note, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		movements = append(movements, m)
+	}
+	return movements, rows.Err()
+}
+
+func (s *productStore) ReservePreorder(ctx context.Context, productID int64, delta int) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE products SET preorder_reserved = preorder_reserved + ? WHERE id = ?", delta, productID)
+	return err
+}
+
+func (s *productStore) RefreshRating(ctx context.Context, productID int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE products p
+		JOIN (
+			SELECT COALESCE(AVG(rating), 0) AS avg_rating, COUNT(*) AS n
+			FROM product_reviews
+			WHERE product_id = ? AND status <> 'hidden'
+		) r
+		SET p.rating_avg = ROUND(r.avg_rating, 2), p.rating_count = r.n
+		WHERE p.id = ?`, productID, productID)
+	return err
+}
+
+//
+// --- Batched Relation Loaders ---
+//
+// Listings load a page of products first and then attach their relations
+// with ONE query per relation (WHERE product_id IN (...)), instead of one
+// query per product.
+//
+
+// loadRelations attaches categories, brands, tags and variants to every product.
+// Images are stored as a JSON column on products, so they are already part of the main query.
+func loadRelations(ctx context.Context, db DBTX, products []*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*models.Product, len(products))
+	ids := make([]int64, 0, len(products))
+	var variableIDs []int64
+	for _, p := range products {
+		byID[p.ID] = p
+		ids = append(ids, p.ID)
+		if p.IsVariable {
+			variableIDs = append(variableIDs, p.ID)
+		}
+	}
+
+	if err := loadCategories(ctx, db, ids, byID); err != nil {
+		return err
+	}
+	if err := loadBrands(ctx, db, ids, byID); err != nil {
+		return err
+	}
+	if err := loadTags(ctx, db, ids, byID); err != nil {
+		return err
+	}
+	if len(variableIDs) > 0 {
+		if err := loadVariants(ctx, db, variableIDs, byID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadCategories fills Product.Categories for the given product IDs.
+func loadCategories(ctx context.Context, db DBTX, ids []int64, byID map[int64]*models.Product) error {
+	placeholders, args := inClause(ids)
+	query := `
+		SELECT pc.product_id, c.id, c.name, c.slug, c.parent_id
+		FROM product_categories pc
+		JOIN categories c ON pc.category_id = c.id
+		WHERE pc.product_id IN (` + placeholders + `)
+		ORDER BY c.name ASC`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int64
+		var cat models.Category
+		if err := rows.Scan(&productID, &cat.ID, &cat.Name, &cat.Slug, &cat.ParentID); err != nil {
+			return err
+		}
+		if p, ok := byID[productID]; ok {
+			p.Categories = append(p.Categories, cat)
+		}
+	}
+	return rows.Err()
+}
+
+// loadBrands fills Product.Brands for the given product IDs.
+func loadBrands(ctx context.Context, db DBTX, ids []int64, byID map[int64]*models.Product) error {
+	placeholders, args := inClause(ids)
+	query := `
+		SELECT pb.product_id, b.id, b.name, b.slug
+		FROM product_brands pb
+		JOIN brands b ON pb.brand_id = b.id
+		WHERE pb.product_id IN (` + placeholders + `)`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int64
+		var brand models.Brand
+		if err := rows.Scan(&productID, &brand.ID, &brand.Name, &brand.Slug); err != nil {
+			return err
+		}
+		if p, ok := byID[productID]; ok {
+			p.Brands = append(p.Brands, brand)
+		}
+	}
+	return rows.Err()
+}
+
+// loadTags fills Product.Tags for the given product IDs.
+func loadTags(ctx context.Context, db DBTX, ids []int64, byID map[int64]*models.Product) error {
+	placeholders, args := inClause(ids)
+	query := `
+		SELECT pt.product_id, t.id, t.name, t.slug
+		FROM product_tags pt
+		JOIN tags t ON pt.tag_id = t.id
+		WHERE pt.product_id IN (` + placeholders + `)
+		ORDER BY t.name ASC`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int64
+		var tag models.Tag
+		if err := rows.Scan(&productID, &tag.ID, &tag.Name, &tag.Slug); err != nil {
+			return err
+		}
+		if p, ok := byID[productID]; ok {
+			p.Tags = append(p.Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+// loadVariants fills Product.Variants for the given (variable) product IDs.
+func loadVariants(ctx context.Context, db DBTX, ids []int64, byID map[int64]*models.Product) error {
+	placeholders, args := inClause(ids)
+	query := `
+		SELECT id, product_id, sku, barcode, price_to_tts, stock_quantity, options, commission_rate
+		FROM product_variants
+		WHERE product_id IN (` + placeholders + `)
+		ORDER BY id ASC`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v models.ProductVariant
+		var optsJSON []byte
+		if err := rows.Scan(&v.ID, &v.ProductID, &v.SKU, &v.Barcode, &v.PriceToTTS, &v.StockQuantity, &optsJSON, &v.CommissionRate); err != nil {
+			return err
+		}
+
+		// Options is delivered as a JSON string; empty/NULL becomes "[]".
+		if len(optsJSON) > 0 && string(optsJSON) != "null" && string(optsJSON) != `""` {
+			v.Options = string(optsJSON)
+		} else {
+			v.Options = "[]"
+		}
+
+		if p, ok := byID[v.ProductID]; ok {
+			p.Variants = append(p.Variants, v)
+		}
+	}
+	return rows.Err()
+}
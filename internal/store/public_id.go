@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// ErrNumericID is returned by ResolveID for a numeric reference once
+// numeric IDs are no longer accepted (LEGACY_NUMERIC_IDS=false).
+var ErrNumericID = errors.New("store: numeric ids are no longer accepted")
+
+// publicIDTables lists the tables with a public_id column.
+var publicIDTables = map[string]bool{
+	TableUsers:    true,
+	TableProducts: true,
+	TableOrders:   true,
+}
+
+// ResolveID turns an external reference into the internal primary key.
+// ref is a public UUID or, while allowNumeric is true (the transition period),
+// the plain numeric ID. Unknown UUIDs return ErrNotFound; numeric IDs are
+// returned as-is, so the caller's own lookup still decides whether they exist.
+func ResolveID(ctx context.Context, db DBTX, table, ref string, allowNumeric bool) (int64, error) {
+	if !publicIDTables[table] {
+		return 0, errors.New("store: table " + table + " has no public_id")
+	}
+
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		if !allowNumeric {
+			return 0, ErrNumericID
+		}
+		return id, nil
+	}
+
+	u, err := uuid.Parse(ref)
+	if err != nil {
+		return 0, ErrNotFound
+	}
+	var id int64
+	err = db.QueryRowContext(ctx, "SELECT id FROM "+table+" WHERE public_id = ?", u.String()).Scan(&id)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	return id, nil
+}
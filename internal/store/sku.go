@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+)
+
+func (s *productStore) LockSKUs(ctx context.Context, supplierID int64) error {
+	// The no-op update still takes the row lock (and creates the row on first use).
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sku_sequences (supplier_id, next_seq) VALUES (?, 0)
+		ON DUPLICATE KEY UPDATE next_seq = next_seq`, supplierID)
+	return err
+}
+
+func (s *productStore) FindSKU(ctx context.Context, supplierID int64, sku string, excludeProductID int64) (*models.SKUUse, error) {
+	var use models.SKUUse
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, public_id, name, NULL FROM products
+		WHERE supplier_id = ? AND sku = ? AND id <> ?
+		UNION ALL
+		SELECT p.id, p.public_id, p.name, v.id FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE p.supplier_id = ? AND v.sku = ? AND p.id <> ?
+		LIMIT 1`,
+		supplierID, sku, excludeProductID, supplierID, sku, excludeProductID,
+	).Scan(&use.ProductID, &use.PublicID, &use.ProductName, &use.VariantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &use, nil
+}
+
+func (s *productStore) NextSKUSeq(ctx context.Context, supplierID int64) (int64, error) {
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO sku_sequences (supplier_id, next_seq) VALUES (?, 1)
+		ON DUPLICATE KEY UPDATE next_seq = next_seq + 1`, supplierID); err != nil {
+		return 0, err
+	}
+	var seq int64
+	err := s.db.QueryRowContext(ctx, "SELECT next_seq FROM sku_sequences WHERE supplier_id = ?", supplierID).Scan(&seq)
+	return seq, err
+}
+
+func (s *productStore) PeekSKUSeq(ctx context.Context, supplierID int64) (int64, error) {
+	var seq int64
+	err := s.db.QueryRowContext(ctx, "SELECT next_seq FROM sku_sequences WHERE supplier_id = ?", supplierID).Scan(&seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 1, nil
+	}
+	return seq + 1, err
+}
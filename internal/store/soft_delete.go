@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Soft-deletable tables. A deleted row keeps its data (and its history in
+// orders, wallets, ...) but disappears from every live read until it is
+// restored or purged by the retention job.
+const (
+	TableUsers          = "users"
+	TableProducts       = "products"
+	TableInventoryItems = "inventory_items"
+	TableOrders         = "orders"
+)
+
+// softDeleteTables lists the tables with a deleted_at column and whether they
+// also carry an optimistic-locking version to bump.
+var softDeleteTables = map[string]bool{
+	TableUsers:          true,
+	TableProducts:       true,
+	TableInventoryItems: true,
+	TableOrders:         false,
+}
+
+// NotDeleted is the predicate that hides soft-deleted rows, e.g.
+// NotDeleted("p") == "p.deleted_at IS NULL". Pass "" for an unaliased table.
+func NotDeleted(alias string) string {
+	if alias == "" {
+		return "deleted_at IS NULL"
+	}
+	return alias + ".deleted_at IS NULL"
+}
+
+// SoftDelete marks a live row as deleted and reports whether one matched.
+func SoftDelete(ctx context.Context, db DBTX, table string, id int64) (bool, error) {
+	return setDeletedAt(ctx, db, table, id, time.Now())
+}
+
+// Restore brings a soft-deleted row back and reports whether one matched.
+func Restore(ctx context.Context, db DBTX, table string, id int64) (bool, error) {
+	return setDeletedAt(ctx, db, table, id, time.Time{})
+}
+
+// setDeletedAt flips deleted_at (zero time = restore). Table names are
+// interpolated, so they must come from softDeleteTables.
+func setDeletedAt(ctx context.Context, db DBTX, table string, id int64, at time.Time) (bool, error) {
+	versioned, ok := softDeleteTables[table]
+	if !ok {
+		return false, fmt.Errorf("store: table %q does not support soft deletes", table)
+	}
+
+	set, where := "deleted_at = ?", "deleted_at IS NULL"
+	var value interface{} = at
+	if at.IsZero() {
+		value, where = nil, "deleted_at IS NOT NULL"
+	}
+	if versioned {
+		set += ", version = version + 1"
+	}
+
+	result, err := db.ExecContext(ctx, "UPDATE "+table+" SET "+set+" WHERE id = ? AND "+where, value, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeTokens ends every session of the user issued before at: the auth
+// middleware refuses older tokens (within AUTH_USER_CACHE_TTL). Call it
+// wherever an account loses access (deleted, suspended), so a later restore
+// does not revive old sessions.
+func RevokeTokens(ctx context.Context, db DBTX, userID int64, at time.Time) error {
+	_, err := db.ExecContext(ctx, "UPDATE users SET tokens_valid_after = ?, version = version + 1 WHERE id = ?", at, userID)
+	return err
+}
@@ -0,0 +1,182 @@
+// Package store holds the typed repositories that own the application's SQL.
+// Handlers depend on the interfaces (ProductStore, OrderStore, WalletStore),
+// so the HTTP layer can be exercised against fakes and the queries live in one place.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrNotFound is returned when a lookup matches no row.
+var ErrNotFound = errors.New("store: not found")
+
+// ErrConflict is returned by versioned updates when the row changed since the
+// caller read it (optimistic locking); handlers map it to 409.
+var ErrConflict = errors.New("store: version conflict")
+
+// ErrReferenced is returned when a row cannot be hard-deleted because history
+// (e.g. order lines) still points at it.
+var ErrReferenced = errors.New("store: still referenced")
+
+// DuplicateKey reports whether err is a MySQL unique-key violation (1062)
+// and returns the violated index name without its table prefix, e.g. "email"
+// or "uq_products_supplier_sku".
+func DuplicateKey(err error) (string, bool) {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) || myErr.Number != 1062 {
+		return "", false
+	}
+	// "Duplicate entry 'a@b.c' for key 'users.email'" (MySQL 8 adds the table)
+	_, key, found := strings.Cut(myErr.Message, "for key '")
+	if !found {
+		return "", true
+	}
+	key = strings.TrimSuffix(key, "'")
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	return key, true
+}
+
+// DBTX is the subset of *sql.DB and *sql.Tx the repositories need,
+// so the same repository code runs inside or outside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Store bundles the repositories bound to the connection pools.
+type Store struct {
+	db *sql.DB
+
+	Products ProductStore
+	Orders   OrderStore
+	Wallet   WalletStore
+}
+
+// New creates a Store. Writes use db; heavy reads (search) use readDB,
+// which may be the same pool when no replica is configured.
+func New(db, readDB *sql.DB) *Store {
+	if readDB == nil {
+		readDB = db
+	}
+	return &Store{
+		db:       db,
+		Products: &productStore{db: db, read: readDB},
+		Orders:   &orderStore{db: db},
+		Wallet:   &walletStore{db: db},
+	}
+}
+
+// Tx is a database transaction with every repository bound to it.
+// The embedded *sql.Tx stays available for tables that have no repository yet.
+type Tx struct {
+	*sql.Tx
+
+	Products ProductStore
+	Orders   OrderStore
+	Wallet   WalletStore
+}
+
+// Begin starts a transaction. Callers must Commit or Rollback it.
+func (s *Store) Begin(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{
+		Tx:       tx,
+		Products: &productStore{db: tx, read: tx},
+		Orders:   &orderStore{db: tx},
+		Wallet:   &walletStore{db: tx},
+	}, nil
+}
+
+// NewWalletStore binds a WalletStore to any connection or transaction.
+func NewWalletStore(db DBTX) WalletStore {
+	return &walletStore{db: db}
+}
+
+// inClause builds the "?, ?, ?" placeholder list and argument slice for an IN (...) filter.
+func inClause[T any](values []T) (string, []interface{}) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return placeholders, args
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound and passes other errors through.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// bulkInsertChunk caps the rows per multi-row INSERT, keeping each statement
+// far below MySQL's 65,535 placeholder limit and max_allowed_packet.
+const bulkInsertChunk = 200
+
+// bulkInsert runs "prefix (row), (row), ..." in chunks of bulkInsertChunk rows.
+// The full-size chunk statement is prepared once and reused; only a trailing
+// partial chunk needs its own statement. rowArgs returns the arguments for row i.
+func bulkInsert(ctx context.Context, db DBTX, prefix, rowPlaceholder string, n int, rowArgs func(i int) ([]interface{}, error)) error {
+	if n == 0 {
+		return nil
+	}
+
+	buildQuery := func(rows int) string {
+		return prefix + strings.TrimSuffix(strings.Repeat(rowPlaceholder+", ", rows), ", ")
+	}
+
+	var fullStmt *sql.Stmt
+	defer func() {
+		if fullStmt != nil {
+			fullStmt.Close()
+		}
+	}()
+
+	for start := 0; start < n; start += bulkInsertChunk {
+		end := start + bulkInsertChunk
+		if end > n {
+			end = n
+		}
+
+		var args []interface{}
+		for i := start; i < end; i++ {
+			rowValues, err := rowArgs(i)
+			if err != nil {
+				return err
+			}
+			args = append(args, rowValues...)
+		}
+
+		if end-start == bulkInsertChunk {
+			if fullStmt == nil {
+				stmt, err := db.PrepareContext(ctx, buildQuery(bulkInsertChunk))
+				if err != nil {
+					return err
+				}
+				fullStmt = stmt
+			}
+			if _, err := fullStmt.ExecContext(ctx, args...); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := db.ExecContext(ctx, buildQuery(end-start), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
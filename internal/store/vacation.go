@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+)
+
+// SupplierAway is the predicate "user <alias> is a supplier on vacation", e.g.
+// SupplierAway("s"). It takes the current time as its one argument, so a
+// vacation ends on schedule even before the vacation job clears it.
+func SupplierAway(alias string) string {
+	return "(" + alias + ".vacation_started_at IS NOT NULL AND (" +
+		alias + ".vacation_ends_at IS NULL OR " + alias + ".vacation_ends_at > ?))"
+}
+
+// markSuppliersAway sets SupplierAway and SupplierBackAt on the products whose
+// supplier is on vacation.
+func markSuppliersAway(ctx context.Context, db DBTX, products []*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	seen := map[int64]bool{}
+	var supplierIDs []int64
+	for _, p := range products {
+		if !seen[p.SupplierID] {
+			seen[p.SupplierID] = true
+			supplierIDs = append(supplierIDs, p.SupplierID)
+		}
+	}
+	placeholders, args := inClause(supplierIDs)
+	query := "SELECT s.id, s.vacation_ends_at FROM users s WHERE " + SupplierAway("s") + " AND s.id IN (" + placeholders + ")"
+
+	rows, err := db.QueryContext(ctx, query, append([]interface{}{time.Now()}, args...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	away := map[int64]*time.Time{}
+	for rows.Next() {
+		var id int64
+		var backAt *time.Time
+		if err := rows.Scan(&id, &backAt); err != nil {
+			return err
+		}
+		away[id] = backAt
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, p := range products {
+		if backAt, ok := away[p.SupplierID]; ok {
+			p.SupplierAway, p.SupplierBackAt = true, backAt
+		}
+	}
+	return nil
+}
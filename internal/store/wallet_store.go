@@ -0,0 +1,250 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/pagination"
+)
+
+// WalletStore owns the 'wallet_transactions' ledger and the platform's
+// 'platform_earnings'.
+type WalletStore interface {
+	// Balance is the sum of all of a user's transactions (0 when there are none).
+	// It is a plain read: inside a transaction it may be an older snapshot, so
+	// never check a payment against it; use LockBalance.
+	Balance(ctx context.Context, userID int64) (money.Money, error)
+	// LockBalance locks the user's row, then returns their balance read with
+	// the ledger locked, so it is the latest one. Every check of a debit against
+	// the balance starts with it, on a transaction-bound store: two payments of
+	// one user then wait for each other instead of both passing the check.
+	LockBalance(ctx context.Context, userID int64) (money.Money, error)
+	// AddTransaction appends a ledger entry. It is the only way to change a balance
+	// and MUST run on a transaction-bound store (Store.Begin) so the balance lock holds.
+	AddTransaction(ctx context.Context, userID int64, txType string, amount money.Money, notes string) error
+	// ListTransactions returns one page of a user's history, newest first.
+	ListTransactions(ctx context.Context, userID int64, page pagination.Page) ([]models.WalletTransaction, error)
+	// Reconcile replays the ledger of userID (0: every user) and returns, per
+	// user, the first row whose balance_after is not the exact running sum.
+	Reconcile(ctx context.Context, userID int64) ([]models.LedgerMismatch, error)
+
+	// Held is the sum of a user's open holds: the part of the balance that is
+	// not available. Like AddTransaction it locks what it sums; call it after
+	// LockBalance, which is what keeps two payments from racing.
+	Held(ctx context.Context, userID int64) (money.Money, error)
+	// Holds lists a user's open holds, oldest first.
+	Holds(ctx context.Context, userID int64) ([]models.WalletHold, error)
+	// PlaceHold sets amount aside for an order.
+	PlaceHold(ctx context.Context, userID, orderID int64, amount money.Money) error
+	// OrderHold is the open hold of an order (ErrNotFound when there is none).
+	OrderHold(ctx context.Context, orderID int64) (*models.WalletHold, error)
+	// CloseHold closes the open hold of an order as 'captured' or 'released';
+	// an order without one is left alone.
+	CloseHold(ctx context.Context, orderID int64, status string) error
+
+	// CreditCommission records the commission the platform keeps of one
+	// supplier's lines in a completed order.
+	CreditCommission(ctx context.Context, orderID, supplierID int64, amount money.Money) error
+}
+
+// Wallet hold statuses.
+const (
+	HoldHeld     = "held"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+)
+
+type walletStore struct {
+	db DBTX
+}
+
+func (s *walletStore) Balance(ctx context.Context, userID int64) (money.Money, error) {
+	var balance money.Money // SUM() over no rows is NULL, which scans as 0
+	err := s.db.QueryRowContext(ctx, "SELECT SUM(amount) FROM wallet_transactions WHERE user_id = ?", userID).Scan(&balance)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	return balance, nil
+}
+
+func (s *walletStore) LockBalance(ctx context.Context, userID int64) (money.Money, error) {
+	var id int64
+	if err := s.db.QueryRowContext(ctx, "SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Scan(&id); err != nil {
+		return 0, notFound(err)
+	}
+	var balance money.Money
+	err := s.db.QueryRowContext(ctx, "SELECT SUM(amount) FROM wallet_transactions WHERE user_id = ? FOR UPDATE", userID).Scan(&balance)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	return balance, nil
+}
+
+func (s *walletStore) AddTransaction(ctx context.Context, userID int64, txType string, amount money.Money, notes string) error {
+	// 1. Get current balance (locked) to calculate balance_after
+	var currentBalance money.Money
+	err := s.db.QueryRowContext(ctx, "SELECT SUM(amount) FROM wallet_transactions WHERE user_id = ? FOR UPDATE", userID).Scan(&currentBalance)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get balance for update: %w", err)
+	}
+
+	newBalance := currentBalance + amount
+
+	// 2. Insert the ledger row
+	query := `
+		INSERT INTO wallet_transactions
+		(user_id, type, status, amount, balance_after, notes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	if _, err := s.db.ExecContext(ctx, query, userID, txType, "completed", amount, newBalance, notes, time.Now()); err != nil {
+		return fmt.Errorf("failed to add wallet transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *walletStore) Held(ctx context.Context, userID int64) (money.Money, error) {
+	var held money.Money
+	err := s.db.QueryRowContext(ctx, "SELECT SUM(amount) FROM wallet_holds WHERE user_id = ? AND status = ? FOR UPDATE", userID, HoldHeld).Scan(&held)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	return held, nil
+}
+
+const holdColumns = "id, user_id, order_id, amount, status, created_at, closed_at"
+
+func scanHold(row interface{ Scan(...interface{}) error }) (models.WalletHold, error) {
+	var h models.WalletHold
+	err := row.Scan(&h.ID, &h.UserID, &h.OrderID, &h.Amount, &h.Status, &h.CreatedAt, &h.ClosedAt)
+	return h, err
+}
+
+func (s *walletStore) Holds(ctx context.Context, userID int64) ([]models.WalletHold, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+holdColumns+" FROM wallet_holds WHERE user_id = ? AND status = ? ORDER BY created_at, id", userID, HoldHeld)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []models.WalletHold{}
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+	return holds, rows.Err()
+}
+
+func (s *walletStore) PlaceHold(ctx context.Context, userID, orderID int64, amount money.Money) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO wallet_holds (user_id, order_id, amount, status, created_at) VALUES (?, ?, ?, ?, ?)",
+		userID, orderID, amount, HoldHeld, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to place wallet hold: %w", err)
+	}
+	return nil
+}
+
+func (s *walletStore) OrderHold(ctx context.Context, orderID int64) (*models.WalletHold, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+holdColumns+" FROM wallet_holds WHERE order_id = ? AND status = ? FOR UPDATE", orderID, HoldHeld)
+	h, err := scanHold(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &h, nil
+}
+
+func (s *walletStore) CloseHold(ctx context.Context, orderID int64, status string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE wallet_holds SET status = ?, closed_at = ? WHERE order_id = ? AND status = ?",
+		status, time.Now(), orderID, HoldHeld)
+	if err != nil {
+		return fmt.Errorf("failed to close wallet hold: %w", err)
+	}
+	return nil
+}
+
+func (s *walletStore) ListTransactions(ctx context.Context, userID int64, page pagination.Page) ([]models.WalletTransaction, error) {
+	cursorCond, cursorArgs := page.Where("created_at", "id")
+	query := `
+		SELECT id, user_id, type, amount, notes, created_at
+		FROM wallet_transactions
+		WHERE user_id = ?` + cursorCond + page.OrderLimit("created_at", "id")
+
+	args := append([]interface{}{userID}, cursorArgs...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []models.WalletTransaction{}
+	for rows.Next() {
+		var t models.WalletTransaction
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Type, &t.Amount, &t.Details, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+func (s *walletStore) Reconcile(ctx context.Context, userID int64) ([]models.LedgerMismatch, error) {
+	// Same order as AddTransaction appends in, served by idx_wallet_tx_user_created.
+	query := "SELECT id, user_id, amount, balance_after, created_at FROM wallet_transactions"
+	var args []interface{}
+	if userID != 0 {
+		query += " WHERE user_id = ?"
+		args = append(args, userID)
+	}
+	query += " ORDER BY user_id, created_at, id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mismatches := []models.LedgerMismatch{}
+	var (
+		current  int64 = -1 // user being replayed
+		running  money.Money
+		reported bool // later rows of a broken ledger repeat the first error
+	)
+	for rows.Next() {
+		var (
+			id, uid              int64
+			amount, balanceAfter money.Money
+			createdAt            time.Time
+		)
+		if err := rows.Scan(&id, &uid, &amount, &balanceAfter, &createdAt); err != nil {
+			return nil, err
+		}
+		if uid != current {
+			current, running, reported = uid, 0, false
+		}
+		running += amount
+		if balanceAfter != running && !reported {
+			mismatches = append(mismatches, models.LedgerMismatch{
+				UserID:        uid,
+				TransactionID: id,
+				Expected:      running,
+				Recorded:      balanceAfter,
+				CreatedAt:     createdAt,
+			})
+			reported = true
+		}
+	}
+	return mismatches, rows.Err()
+}
+
+func (s *walletStore) CreditCommission(ctx context.Context, orderID, supplierID int64, amount money.Money) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO platform_earnings (order_id, supplier_id, kind, amount, created_at) VALUES (?, ?, 'commission', ?, ?)",
+		orderID, supplierID, amount, time.Now())
+	return err
+}
@@ -0,0 +1,118 @@
+// Package tax computes the SST (Malaysian sales tax) on checkouts. It is pure:
+// the caller loads the rates, the category chains of the cart's products and
+// the lines, and this package decides the rate of each line and the tax lines
+// stored on the order.
+//
+// Rates are percentages. A category's rate covers its subcategories unless
+// one of them sets its own; products in no rated category pay the default
+// rate (SST_DEFAULT_RATE, 0 when unset).
+package tax
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+)
+
+// MaxRate bounds a rate set by a manager.
+const MaxRate = 100
+
+// Rates are the SST rates in force.
+type Rates struct {
+	Default    float64
+	ByCategory map[int64]float64 // category ID -> rate
+}
+
+// For returns the rate of a product from its category chains, each one a
+// category followed by its ancestors. The nearest rated category of a chain
+// decides it; across several categories the highest rate applies.
+func (r Rates) For(chains [][]int64) float64 {
+	rate, found := 0.0, false
+	for _, chain := range chains {
+		for _, id := range chain {
+			if v, ok := r.ByCategory[id]; ok {
+				if !found || v > rate {
+					rate, found = v, true
+				}
+				break
+			}
+		}
+	}
+	if !found {
+		return r.Default
+	}
+	return rate
+}
+
+// Line is one order line.
+type Line struct {
+	SupplierID int64
+	Amount     money.Money // unit price x quantity
+	Rate       float64
+}
+
+// TaxLine is the tax on one supplier's lines at one rate.
+type TaxLine struct {
+	SupplierID int64
+	Rate       float64
+	Taxable    money.Money
+	Tax        money.Money
+}
+
+// Compute groups the lines by supplier and rate, ordered by both, and rounds
+// the tax of each group once. Untaxed lines yield no tax line. The base is the
+// line amount: promotions are platform-funded, so they do not lower the price
+// the supplier sells at.
+func Compute(lines []Line) []TaxLine {
+	type key struct {
+		supplierID int64
+		rate       float64
+	}
+	taxable := map[key]money.Money{}
+	for _, l := range lines {
+		if l.Rate <= 0 {
+			continue
+		}
+		taxable[key{l.SupplierID, l.Rate}] += l.Amount
+	}
+
+	out := make([]TaxLine, 0, len(taxable))
+	for k, amount := range taxable {
+		out = append(out, TaxLine{SupplierID: k.supplierID, Rate: k.rate, Taxable: amount, Tax: amount.Percent(k.rate)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].SupplierID != out[j].SupplierID {
+			return out[i].SupplierID < out[j].SupplierID
+		}
+		return out[i].Rate < out[j].Rate
+	})
+	return out
+}
+
+// Total sums the tax of lines.
+func Total(lines []TaxLine) money.Money {
+	var total money.Money
+	for _, l := range lines {
+		total += l.Tax
+	}
+	return total
+}
+
+// registrationPattern is the SST registration number issued by the customs
+// department, e.g. W10-1808-32000123.
+var registrationPattern = regexp.MustCompile(`^[A-Z][0-9]{2}-[0-9]{4}-[0-9]{8}$`)
+
+// ErrRegistration is returned for a malformed SST registration number.
+var ErrRegistration = errors.New("sstNumber must look like W10-1808-32000123")
+
+// NormalizeRegistration upper-cases and checks an SST registration number.
+func NormalizeRegistration(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if !registrationPattern.MatchString(s) {
+		return "", ErrRegistration
+	}
+	return s, nil
+}
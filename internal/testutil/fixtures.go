@@ -0,0 +1,114 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/models"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password is the plain-text password of every fixture user.
+const Password = "password123"
+
+var (
+	fixtureSeq int64
+
+	// passwordHash is computed once; bcrypt is deliberately slow.
+	passwordHash = sync.OnceValue(func() string {
+		h, _ := bcrypt.GenerateFromPassword([]byte(Password), bcrypt.MinCost)
+		return string(h)
+	})
+)
+
+// unique returns a suffix that keeps fixture emails and SKUs distinct
+// across tests sharing the database.
+func unique() string {
+	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), atomic.AddInt64(&fixtureSeq, 1))
+}
+
+// CreateUser inserts an active user with the given role
+// (dropshipper, supplier, manager, admin) and returns its ID.
+func CreateUser(t testing.TB, db *sql.DB, role string) int64 {
+	t.Helper()
+	now := time.Now()
+	res, err := db.Exec(
+		`INSERT INTO users (role, status, email, password_hash, full_name, phone_number, created_at, updated_at, version)
+		 VALUES (?, 'active', ?, ?, ?, '0100000000', ?, ?, 1)`,
+		role, "test-"+unique()+"@example.com", passwordHash(), "Test "+role, now, now,
+	)
+	if err != nil {
+		t.Fatalf("testutil: create %s: %v", role, err)
+	}
+	id, _ := res.LastInsertId()
+	return id
+}
+
+// CreateProduct inserts an active simple product owned by supplierID and returns it.
+func CreateProduct(t testing.TB, db *sql.DB, supplierID int64, price money.Money, stock int) *models.Product {
+	t.Helper()
+	sku := "SKU-" + unique()
+	now := time.Now()
+	p := &models.Product{
+		SupplierID:    supplierID,
+		SKU:           &sku,
+		Name:          "Test Product " + sku,
+		PriceToTTS:    price,
+		SRP:           price.Percent(150),
+		StockQuantity: stock,
+		Status:        "active",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := store.New(db, db).Products.Create(context.Background(), p); err != nil {
+		t.Fatalf("testutil: create product: %v", err)
+	}
+	p.Version = 1
+	return p
+}
+
+// FundWallet credits userID's wallet with a "topup" transaction.
+func FundWallet(t testing.TB, db *sql.DB, userID int64, amount money.Money) {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := store.New(db, db).Begin(ctx, nil)
+	if err != nil {
+		t.Fatalf("testutil: fund wallet: %v", err)
+	}
+	defer tx.Rollback()
+	if err := tx.Wallet.AddTransaction(ctx, userID, "topup", amount, "testutil fixture"); err != nil {
+		t.Fatalf("testutil: fund wallet: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("testutil: fund wallet: %v", err)
+	}
+}
+
+// WalletBalance returns userID's current balance.
+func WalletBalance(t testing.TB, db *sql.DB, userID int64) money.Money {
+	t.Helper()
+	balance, err := store.NewWalletStore(db).Balance(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("testutil: wallet balance: %v", err)
+	}
+	return balance
+}
+
+// SetSetting upserts a row of the 'settings' table (e.g. maintenance_mode).
+func SetSetting(t testing.TB, db *sql.DB, key, value string) {
+	t.Helper()
+	_, err := db.Exec(
+		"INSERT INTO settings (setting_key, setting_value) VALUES (?, ?) ON DUPLICATE KEY UPDATE setting_value = VALUES(setting_value)",
+		key, value,
+	)
+	if err != nil {
+		t.Fatalf("testutil: set setting %s: %v", key, err)
+	}
+}
@@ -0,0 +1,195 @@
+package testutil_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// These flows run against MySQL and skip unless TEST_DB_DSN is set; see the
+// package doc of testutil.
+func TestMain(m *testing.M) { os.Exit(testutil.Main(m)) }
+
+type checkoutResponse struct {
+	OrderID   int64       `json:"orderId"`
+	Status    string      `json:"status"`
+	Subtotal  money.Money `json:"subtotal"`
+	Discount  money.Money `json:"discount"`
+	Tax       money.Money `json:"tax"`
+	TotalPaid money.Money `json:"totalPaid"`
+}
+
+type walletResponse struct {
+	CurrentBalance   money.Money `json:"currentBalance"`
+	HeldBalance      money.Money `json:"heldBalance"`
+	AvailableBalance money.Money `json:"availableBalance"`
+}
+
+func stock(t *testing.T, db *sql.DB, productID int64) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT stock_quantity FROM products WHERE id = ?", productID).Scan(&n); err != nil {
+		t.Fatalf("load stock: %v", err)
+	}
+	return n
+}
+
+func TestCheckoutFlow(t *testing.T) {
+	db := testutil.DB(t)
+	srv := testutil.NewServer(t, db)
+	supplier := testutil.CreateUser(t, db, "supplier")
+	product := testutil.CreateProduct(t, db, supplier, 20*money.Ringgit, 5)
+
+	t.Run("paid from the wallet", func(t *testing.T) {
+		buyer := testutil.CreateUser(t, db, "dropshipper")
+		testutil.FundWallet(t, db, buyer, 500*money.Ringgit)
+		token := srv.Token(t, buyer)
+
+		w := srv.Do(t, "POST", "/v1/dropshipper/checkout", nil, token)
+		testutil.AssertStatus(t, w, http.StatusBadRequest) // nothing in the cart yet
+
+		w = srv.Do(t, "POST", "/v1/dropshipper/cart/items", gin.H{"product_id": product.ID, "quantity": 2}, token)
+		testutil.AssertStatus(t, w, http.StatusCreated)
+		before := stock(t, db, product.ID)
+
+		w = srv.Do(t, "POST", "/v1/dropshipper/checkout", nil, token)
+		testutil.AssertStatus(t, w, http.StatusCreated)
+		var order checkoutResponse
+		testutil.DecodeJSON(t, w, &order)
+		if order.Status != "processing" || order.Subtotal != 40*money.Ringgit {
+			t.Fatalf("order = %+v, want processing with a RM 40.00 subtotal", order)
+		}
+		if order.TotalPaid != order.Subtotal-order.Discount+order.Tax {
+			t.Fatalf("totalPaid %s != subtotal - discount + tax in %+v", order.TotalPaid, order)
+		}
+		if got, want := testutil.WalletBalance(t, db, buyer), 500*money.Ringgit-order.TotalPaid; got != want {
+			t.Fatalf("balance = %s, want %s", got, want)
+		}
+		if got := stock(t, db, product.ID); got != before-2 {
+			t.Fatalf("stock = %d, want %d", got, before-2)
+		}
+	})
+
+	t.Run("on hold when the wallet is short", func(t *testing.T) {
+		buyer := testutil.CreateUser(t, db, "dropshipper")
+		testutil.FundWallet(t, db, buyer, 10*money.Ringgit)
+		token := srv.Token(t, buyer)
+
+		w := srv.Do(t, "POST", "/v1/dropshipper/cart/items", gin.H{"product_id": product.ID, "quantity": 1}, token)
+		testutil.AssertStatus(t, w, http.StatusCreated)
+		w = srv.Do(t, "POST", "/v1/dropshipper/checkout", nil, token)
+		testutil.AssertStatus(t, w, http.StatusCreated)
+		var order checkoutResponse
+		testutil.DecodeJSON(t, w, &order)
+		if order.Status != "on-hold" {
+			t.Fatalf("status = %q, want on-hold", order.Status)
+		}
+
+		// Nothing is debited; what the wallet has is held for the order.
+		w = srv.Do(t, "GET", "/v1/dropshipper/wallet", nil, token)
+		testutil.AssertStatus(t, w, http.StatusOK)
+		var wallet walletResponse
+		testutil.DecodeJSON(t, w, &wallet)
+		if wallet.CurrentBalance != 10*money.Ringgit || wallet.HeldBalance != 10*money.Ringgit || wallet.AvailableBalance != 0 {
+			t.Fatalf("wallet = %+v, want RM 10.00 held of RM 10.00", wallet)
+		}
+	})
+}
+
+func TestWalletTopUpFlow(t *testing.T) {
+	db := testutil.DB(t)
+	srv := testutil.NewServer(t, db)
+	buyer := testutil.CreateUser(t, db, "dropshipper")
+	token := srv.Token(t, buyer)
+
+	w := srv.Do(t, "POST", "/v1/dropshipper/wallet/topup", gin.H{"amount": 0}, token)
+	testutil.AssertStatus(t, w, http.StatusBadRequest)
+
+	for _, amount := range []string{"50.00", "25.50"} {
+		w = srv.Do(t, "POST", "/v1/dropshipper/wallet/topup", fmt.Sprintf(`{"amount":%s}`, amount), token)
+		testutil.AssertStatus(t, w, http.StatusOK)
+	}
+
+	w = srv.Do(t, "GET", "/v1/dropshipper/wallet", nil, token)
+	testutil.AssertStatus(t, w, http.StatusOK)
+	var wallet walletResponse
+	testutil.DecodeJSON(t, w, &wallet)
+	if want := money.Money(7550); wallet.CurrentBalance != want || wallet.AvailableBalance != want {
+		t.Fatalf("wallet = %+v, want RM 75.50 available", wallet)
+	}
+	if got := testutil.WalletBalance(t, db, buyer); got != 7550 {
+		t.Fatalf("ledger balance = %s, want 75.50", got)
+	}
+}
+
+func TestWithdrawalFlow(t *testing.T) {
+	db := testutil.DB(t)
+	srv := testutil.NewServer(t, db)
+	supplier := testutil.CreateUser(t, db, "supplier")
+	manager := testutil.CreateUser(t, db, "manager")
+	testutil.FundWallet(t, db, supplier, 100*money.Ringgit)
+	supplierToken, managerToken := srv.Token(t, supplier), srv.Token(t, manager)
+
+	request := func(amount money.Money) int64 {
+		t.Helper()
+		w := srv.Do(t, "POST", "/v1/supplier/wallet/request-withdrawal",
+			gin.H{"amount": amount, "bankDetails": "Maybank 1234567890"}, supplierToken)
+		testutil.AssertStatus(t, w, http.StatusCreated)
+
+		// The request is deducted at once and listed for managers.
+		w = srv.Do(t, "GET", "/v1/manager/withdrawal-requests", nil, managerToken)
+		testutil.AssertStatus(t, w, http.StatusOK)
+		var list struct {
+			Requests []struct {
+				ID          int64       `json:"id"`
+				UserID      int64       `json:"userId"`
+				Amount      money.Money `json:"amount"`
+				BankDetails string      `json:"bankDetails"`
+			} `json:"requests"`
+		}
+		testutil.DecodeJSON(t, w, &list)
+		for _, r := range list.Requests {
+			if r.UserID == supplier && r.Amount == amount {
+				if r.BankDetails != "Maybank 1234567890" {
+					t.Fatalf("bankDetails = %q", r.BankDetails)
+				}
+				return r.ID
+			}
+		}
+		t.Fatalf("pending requests %+v do not include RM %s from the supplier", list.Requests, amount)
+		return 0
+	}
+
+	w := srv.Do(t, "POST", "/v1/supplier/wallet/request-withdrawal",
+		gin.H{"amount": 150 * money.Ringgit, "bankDetails": "Maybank 1234567890"}, supplierToken)
+	testutil.AssertStatus(t, w, http.StatusConflict)
+
+	approved := request(40 * money.Ringgit)
+	if got := testutil.WalletBalance(t, db, supplier); got != 60*money.Ringgit {
+		t.Fatalf("balance after request = %s, want 60.00", got)
+	}
+	path := fmt.Sprintf("/v1/manager/withdrawal-requests/%d", approved)
+	w = srv.Do(t, "PATCH", path, gin.H{"action": "approve"}, managerToken)
+	testutil.AssertStatus(t, w, http.StatusOK)
+	w = srv.Do(t, "PATCH", path, gin.H{"action": "reject", "rejectionReason": "late"}, managerToken)
+	testutil.AssertStatus(t, w, http.StatusConflict)
+	if got := testutil.WalletBalance(t, db, supplier); got != 60*money.Ringgit {
+		t.Fatalf("balance after approval = %s, want 60.00", got)
+	}
+
+	rejected := request(30 * money.Ringgit)
+	path = fmt.Sprintf("/v1/manager/withdrawal-requests/%d", rejected)
+	w = srv.Do(t, "PATCH", path, gin.H{"action": "reject"}, managerToken)
+	testutil.AssertStatus(t, w, http.StatusBadRequest) // a reason is required
+	w = srv.Do(t, "PATCH", path, gin.H{"action": "reject", "rejectionReason": "Bank details do not match"}, managerToken)
+	testutil.AssertStatus(t, w, http.StatusOK)
+	if got := testutil.WalletBalance(t, db, supplier); got != 60*money.Ringgit {
+		t.Fatalf("balance after rejection = %s, want the 30.00 refunded to 60.00", got)
+	}
+}
@@ -0,0 +1,222 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/audit"
+	"github.com/01moynul/taptosell-golang/internal/auth"
+	"github.com/01moynul/taptosell-golang/internal/cache"
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/01moynul/taptosell-golang/internal/errreport"
+	"github.com/01moynul/taptosell-golang/internal/events"
+	"github.com/01moynul/taptosell-golang/internal/handlers"
+	"github.com/01moynul/taptosell-golang/internal/jobs"
+	"github.com/01moynul/taptosell-golang/internal/money"
+	"github.com/01moynul/taptosell-golang/internal/routes"
+	"github.com/01moynul/taptosell-golang/internal/settings"
+	"github.com/01moynul/taptosell-golang/internal/status"
+	"github.com/01moynul/taptosell-golang/internal/store"
+	"github.com/01moynul/taptosell-golang/internal/uploads"
+	"github.com/01moynul/taptosell-golang/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+// jwtSecret signs test tokens only.
+const jwtSecret = "testutil-secret-at-least-32-characters"
+
+// Server is the full router wired to a test database, for route tests.
+type Server struct {
+	Handlers *handlers.Handlers
+	Router   *gin.Engine
+}
+
+// Config returns a valid configuration for tests; uploads go to a temp dir.
+func Config(t testing.TB) *config.Config {
+	t.Helper()
+	return &config.Config{
+		Env: "development",
+		HTTP: config.HTTP{
+			Port:         "8080",
+			BaseURL:      "http://localhost:8080",
+			CORSOrigins:  []string{"http://localhost:5173"},
+			MaxJSONBytes: 1 << 20,
+
+			BatchMaxRequests:  10,
+			PublicCacheMaxAge: time.Minute,
+			PublicCacheStale:  5 * time.Minute,
+		},
+		Auth: config.Auth{
+			JWTKeys:   []config.SigningKey{{Secret: jwtSecret}},
+			JWTSecret: jwtSecret,
+			TokenTTL:  time.Hour,
+		},
+		Storage: config.Storage{
+			UploadDir:       t.TempDir(),
+			DocumentDir:     t.TempDir(),
+			DocumentURLTTL:  15 * time.Minute,
+			MaxFileBytes:    10 << 20,
+			MaxRequestBytes: 25 << 20,
+		},
+		Exports: config.Exports{
+			Dir:       t.TempDir(),
+			Workers:   1,
+			MaxActive: 3,
+			URLTTL:    15 * time.Minute,
+			Retention: 24 * time.Hour,
+		},
+		Webhooks: config.Webhooks{
+			DispatchInterval: 5 * time.Second,
+			MaxEndpoints:     5,
+		},
+		Referrals: config.Referrals{
+			ReferrerReward: 10 * money.Ringgit,
+			MinOrderTotal:  30 * money.Ringgit,
+			MonthlyCap:     20,
+		},
+		Disputes: config.Disputes{
+			ResponseWindow: 72 * time.Hour,
+			OpenWindow:     30 * 24 * time.Hour,
+			CheckInterval:  15 * time.Minute,
+		},
+		Preorders: config.Preorders{
+			CheckInterval: 10 * time.Minute,
+		},
+		UnpaidOrders: config.UnpaidOrders{
+			Days:          1,
+			CheckInterval: 10 * time.Minute,
+		},
+		Risk: config.Risk{
+			MaxOrdersPerHour: 10,
+			MaxFailedTopups:  3,
+			ReviewScore:      50,
+		},
+		Vacations: config.Vacations{
+			CheckInterval: 15 * time.Minute,
+		},
+		Shipping: config.Shipping{
+			HandlingDays:      2,
+			Timezone:          time.UTC,
+			LateCheckInterval: 30 * time.Minute,
+		},
+		Wallet: config.Wallet{
+			ReconcileInterval: time.Hour,
+		},
+		Notifications: config.Notifications{
+			BatchWindow: 10 * time.Minute,
+		},
+	}
+}
+
+// NewHandlers wires handlers to db with an in-memory cache and no AI service.
+// Async event subscribers run on a job queue that is drained when t ends.
+func NewHandlers(t testing.TB, db *sql.DB) *handlers.Handlers {
+	t.Helper()
+	cfg := Config(t)
+	auth.Configure([]auth.Key{{Secret: []byte(cfg.Auth.JWTSecret)}}, cfg.Auth.TokenTTL)
+
+	queue := jobs.NewQueue(1, 100)
+	t.Cleanup(func() { queue.Shutdown(context.Background()) })
+	bus := events.NewBus(queue)
+	exportQueue := jobs.NewQueue(cfg.Exports.Workers, 100)
+	t.Cleanup(func() { exportQueue.Shutdown(context.Background()) })
+
+	c := cache.NewMemory()
+	h := &handlers.Handlers{
+		Config:     cfg,
+		DB:         db,
+		ReadDB:     db,
+		DBReadOnly: db,
+		Cache:      c,
+		Settings:   settings.NewStore(db, c),
+		Status:     status.NewStore(db, c),
+		Store:      store.New(db, db),
+		Events:     bus,
+		Reporter:   errreport.Log{},
+		Audit:      audit.NewRecorder(db, 1000),
+		Uploads:    uploads.New(cfg.Storage, cfg.HTTP.BaseURL, cfg.Auth.JWTSecret),
+		Webhooks:   webhooks.NewClient(true),
+
+		ExportQueue: exportQueue,
+		ExportURLs:  uploads.NewExportSigner(cfg.Auth.JWTSecret, cfg.Exports.URLTTL),
+	}
+	h.RegisterSubscribers(bus)
+	return h
+}
+
+// NewServer builds the production router on top of NewHandlers.
+func NewServer(t testing.TB, db *sql.DB) *Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	h := NewHandlers(t, db)
+	return &Server{Handlers: h, Router: routes.SetupRouter(h)}
+}
+
+// Token returns a bearer token for userID, with the role and status the user has now.
+func (s *Server) Token(t testing.TB, userID int64) string {
+	t.Helper()
+	var role, status string
+	if err := s.Handlers.DB.QueryRow("SELECT role, status FROM users WHERE id = ?", userID).Scan(&role, &status); err != nil {
+		t.Fatalf("testutil: load user %d: %v", userID, err)
+	}
+	token, err := auth.GenerateToken(userID, role, status)
+	if err != nil {
+		t.Fatalf("testutil: generate token: %v", err)
+	}
+	return token
+}
+
+// Do sends a request through the router. body is JSON-encoded unless it is
+// nil, a string or an io.Reader; token may be empty.
+func (s *Server) Do(t testing.TB, method, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		r = b
+	case string:
+		r = bytes.NewBufferString(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("testutil: encode request body: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, r)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	return w
+}
+
+// AssertStatus fails the test when the response status is not want.
+func AssertStatus(t testing.TB, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d %s, want %d; body: %s", w.Code, http.StatusText(w.Code), want, w.Body.String())
+	}
+}
+
+// DecodeJSON decodes the response body into dest.
+func DecodeJSON(t testing.TB, w *httptest.ResponseRecorder, dest interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), dest); err != nil {
+		t.Fatalf("testutil: decode response: %v; body: %s", err, w.Body.String())
+	}
+}
@@ -0,0 +1,114 @@
+package testutil
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// txDriverName is the database/sql driver that gives every pool its own
+// rolled-back transaction. Register it once with sql.Register.
+const txDriverName = "mysql-txdb"
+
+// txDriver opens a real MySQL connection and immediately starts a transaction
+// on it. Closing the connection rolls that transaction back, so nothing a test
+// writes ever reaches the database.
+//
+// The pool must be limited to ONE connection (see DB): every statement then
+// runs on the same session and sees the test's own uncommitted writes.
+// Transactions the code under test begins become SAVEPOINTs inside it.
+type txDriver struct{}
+
+func (txDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := mysql.MySQLDriver{}.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := conn.(driver.ConnBeginTx).BeginTx(context.Background(), driver.TxOptions{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &txConn{conn: conn, tx: tx}, nil
+}
+
+// txConn forwards everything to the real connection; only Begin and Close differ.
+type txConn struct {
+	conn driver.Conn
+	tx   driver.Tx
+	next int64 // savepoint counter
+}
+
+func (c *txConn) Prepare(query string) (driver.Stmt, error) {
+	return c.conn.Prepare(query)
+}
+
+func (c *txConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.conn.Prepare(query)
+}
+
+func (c *txConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *txConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+// CheckNamedValue keeps the MySQL driver's own argument conversion.
+func (c *txConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if chk, ok := c.conn.(driver.NamedValueChecker); ok {
+		return chk.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *txConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx opens a savepoint; isolation level and read-only options are ignored.
+func (c *txConn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	name := fmt.Sprintf("tu_sp_%d", atomic.AddInt64(&c.next, 1))
+	if _, err := c.ExecContext(ctx, "SAVEPOINT "+name, nil); err != nil {
+		return nil, err
+	}
+	return &savepoint{conn: c, name: name}, nil
+}
+
+// Close rolls back everything the test did, then closes the real connection.
+func (c *txConn) Close() error {
+	rbErr := c.tx.Rollback()
+	if err := c.conn.Close(); err != nil {
+		return err
+	}
+	return rbErr
+}
+
+// savepoint is the driver.Tx handed to code that calls db.BeginTx.
+type savepoint struct {
+	conn *txConn
+	name string
+}
+
+func (s *savepoint) Commit() error {
+	_, err := s.conn.ExecContext(context.Background(), "RELEASE SAVEPOINT "+s.name, nil)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	_, err := s.conn.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+s.name, nil)
+	return err
+}
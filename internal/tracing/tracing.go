@@ -0,0 +1,58 @@
+// Package tracing configures OpenTelemetry. Spans from the Gin middleware, the
+// database driver wrapper and the AI service are exported over OTLP/HTTP to a
+// collector (Jaeger, Tempo, ...), so a slow checkout or a long AI tool loop can
+// be followed end-to-end.
+package tracing
+
+import (
+	"context"
+	"log"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// Setup installs the global tracer provider and W3C trace-context propagation.
+// Without an endpoint nothing is exported and every span is a no-op. The
+// returned function flushes buffered spans; call it once on shutdown.
+func Setup(ctx context.Context, cfg config.Tracing) (shutdown func(context.Context) error, err error) {
+	// Propagate incoming traceparent headers even when we export nothing,
+	// so an upstream proxy's trace is not cut at this service.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Honour the caller's sampling decision; sample our own roots by ratio.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	log.Printf("Tracing enabled: exporting %s spans to %s (sample ratio %.2f)", cfg.ServiceName, cfg.Endpoint, cfg.SampleRatio)
+	return provider.Shutdown, nil
+}
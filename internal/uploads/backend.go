@@ -0,0 +1,32 @@
+package uploads
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// Backend keeps checked files. Store.Save stages and scans every upload in
+// its Dir first, so a backend only ever sees files that passed the checks.
+type Backend interface {
+	// Put stores the file at path under name; the staged file may be gone afterwards.
+	Put(ctx context.Context, name, path, contentType string) error
+	// URL is the public address of a stored name.
+	URL(name string) string
+}
+
+// LocalBackend keeps files in Dir, which the router serves at BaseURL/uploads.
+type LocalBackend struct {
+	Dir     string
+	BaseURL string
+}
+
+// Put moves the staged file into Dir; staging happens in the same directory,
+// so the move is an atomic rename.
+func (b LocalBackend) Put(_ context.Context, name, path, _ string) error {
+	return os.Rename(path, filepath.Join(b.Dir, name))
+}
+
+func (b LocalBackend) URL(name string) string {
+	return b.BaseURL + "/uploads/" + name
+}
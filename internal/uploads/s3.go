@@ -0,0 +1,146 @@
+package uploads
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+)
+
+// S3Backend keeps files in an S3-compatible bucket, signing each PUT with
+// AWS Signature Version 4. Objects get long-lived cache headers: names are
+// random, so a stored object never changes.
+type S3Backend struct {
+	cfg    config.S3
+	client *http.Client
+}
+
+// NewS3Backend returns a backend for the bucket in cfg.
+func NewS3Backend(cfg config.S3) *S3Backend {
+	return &S3Backend{cfg: cfg, client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+func (b *S3Backend) Put(ctx context.Context, name, path, contentType string) error {
+	// 1. --- Hash the body (SigV4 signs the payload) ---
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	payloadHash := hex.EncodeToString(hash.Sum(nil))
+
+	// 2. --- Build & Sign the PUT ---
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.cfg.Endpoint+b.objectPath(name), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	b.sign(req, payloadHash, time.Now().UTC())
+
+	// 3. --- Send ---
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3: PUT %s: %s: %s", name, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (b *S3Backend) URL(name string) string {
+	return b.cfg.PublicURL + "/" + b.cfg.Prefix + name
+}
+
+// objectPath is the escaped path-style path of name: /bucket/prefix/name.
+func (b *S3Backend) objectPath(name string) string {
+	segments := strings.Split(b.cfg.Bucket+"/"+b.cfg.Prefix+name, "/")
+	for i, s := range segments {
+		segments[i] = awsEscape(s)
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// sign adds the SigV4 headers for req, whose body hashes to payloadHash.
+func (b *S3Backend) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := []string{"cache-control", "content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	var headers strings.Builder
+	for _, h := range signed {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		headers.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"", // no query string
+		headers.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + b.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonical)
+
+	key := hmacSHA256([]byte("AWS4"+b.cfg.SecretAccessKey), day)
+	for _, part := range []string{b.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// awsEscape percent-encodes everything but the unreserved characters, as SigV4 requires.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
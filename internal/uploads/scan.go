@@ -0,0 +1,46 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Scanner inspects a stored file before it is accepted. It returns ErrInfected
+// for a file that must be rejected and any other error when it could not decide;
+// both reject the upload.
+type Scanner interface {
+	Scan(ctx context.Context, path string) error
+}
+
+// CommandScanner runs an external scanner with the file path appended, e.g.
+// "clamdscan --no-summary --fdpass". Exit status 1 means infected, as with
+// ClamAV; any other failure is an error.
+type CommandScanner struct {
+	Command []string
+}
+
+// NewCommandScanner parses a command line (UPLOAD_SCAN_COMMAND); an empty one
+// returns nil, which disables scanning.
+func NewCommandScanner(command string) Scanner {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+	return CommandScanner{Command: fields}
+}
+
+func (s CommandScanner) Scan(ctx context.Context, path string) error {
+	args := append(append([]string{}, s.Command[1:]...), path)
+	out, err := exec.CommandContext(ctx, s.Command[0], args...).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return ErrInfected
+	}
+	return fmt.Errorf("uploads: scanner failed: %v: %s", err, strings.TrimSpace(string(out)))
+}
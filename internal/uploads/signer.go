@@ -0,0 +1,61 @@
+package uploads
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Signer issues and checks expiring URLs for private files. The signature
+// covers the file name and the expiry, so a URL cannot be reused for another
+// file or extended.
+type Signer struct {
+	key  []byte
+	path string // route prefix the name is appended to
+	ttl  time.Duration
+}
+
+// NewSigner returns the signer of private document links (GET /v1/documents/:name).
+// It derives the signing key from secret, so the same secret can back other
+// signatures without them being interchangeable.
+func NewSigner(secret string, ttl time.Duration) *Signer {
+	return newSigner(secret, "taptosell/document-urls", "/v1/documents/", ttl)
+}
+
+// NewExportSigner returns the signer of finished export downloads
+// (GET /v1/exports/files/:name), keyed apart from the document links.
+func NewExportSigner(secret string, ttl time.Duration) *Signer {
+	return newSigner(secret, "taptosell/export-urls", "/v1/exports/files/", ttl)
+}
+
+func newSigner(secret, purpose, path string, ttl time.Duration) *Signer {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return &Signer{key: mac.Sum(nil), path: path, ttl: ttl}
+}
+
+// URL returns a link to the signer's route for name, valid for the signer's TTL.
+// baseURL is the public API URL (BASE_URL).
+func (s *Signer) URL(baseURL, name string) string {
+	expires := strconv.FormatInt(time.Now().Add(s.ttl).Unix(), 10)
+	q := url.Values{"expires": {expires}, "sig": {s.sign(name, expires)}}
+	return baseURL + s.path + url.PathEscape(name) + "?" + q.Encode()
+}
+
+// Verify reports whether sig is valid for name and expires has not passed.
+func (s *Signer) Verify(name, expires, sig string) bool {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(s.sign(name, expires)))
+}
+
+func (s *Signer) sign(name, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(name + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,214 @@
+// Package uploads validates and stores user uploads. The file type is sniffed
+// from the content (never taken from the client's filename or Content-Type)
+// and checked against a per-kind whitelist, sizes are capped, files get random
+// names, and an optional virus scanner sees every file before it is kept.
+//
+// Catalogue images are public: served from /uploads, or from an S3-compatible
+// bucket with IMAGE_STORAGE=s3 (see Backend). Supplier documents are private:
+// they live outside the public folder and are only reachable through
+// short-lived signed URLs (see Signer).
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/01moynul/taptosell-golang/internal/config"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTooLarge is returned for a file over the per-file limit.
+	ErrTooLarge = errors.New("uploads: file too large")
+	// ErrType is returned when the sniffed content is not allowed for the kind.
+	ErrType = errors.New("uploads: file type not allowed")
+	// ErrInfected is returned when the scanner flags the file.
+	ErrInfected = errors.New("uploads: file rejected by virus scan")
+)
+
+// Kind is a class of upload: the content types it accepts and the extension
+// each one is stored under.
+type Kind struct {
+	Name  string
+	Types map[string]string // sniffed MIME type -> extension
+}
+
+// Image is a public catalogue image.
+var Image = Kind{Name: "image", Types: map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}}
+
+// Document is a private supplier document (SSM certificate, bank statement).
+var Document = Kind{Name: "document", Types: map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}}
+
+// Allowed lists the extensions of k, for error messages.
+func (k Kind) Allowed() string {
+	exts := make([]string, 0, len(k.Types))
+	for _, ext := range k.Types {
+		exts = append(exts, strings.TrimPrefix(ext, "."))
+	}
+	sort.Strings(exts)
+	return strings.Join(exts, ", ")
+}
+
+// Store checks one kind of upload, staged in Dir, and keeps it in Backend.
+type Store struct {
+	Dir          string
+	Kind         Kind
+	MaxFileBytes int64
+	Scanner      Scanner // nil skips scanning
+	Backend      Backend
+}
+
+// Service holds the stores and the document URL signer the handlers use.
+type Service struct {
+	Images          *Store
+	Documents       *Store
+	DocumentURLs    *Signer
+	MaxRequestBytes int64 // cap on a whole multipart request body
+}
+
+// New builds the upload service from the storage settings. baseURL is the
+// API's public URL (local image links); secret keys the document URL signatures.
+func New(cfg config.Storage, baseURL, secret string) *Service {
+	scanner := NewCommandScanner(cfg.ScanCommand)
+	var images Backend = LocalBackend{Dir: cfg.UploadDir, BaseURL: baseURL}
+	if cfg.ImageBackend == "s3" {
+		images = NewS3Backend(cfg.S3)
+	}
+	return &Service{
+		Images:          &Store{Dir: cfg.UploadDir, Kind: Image, MaxFileBytes: cfg.MaxFileBytes, Scanner: scanner, Backend: images},
+		Documents:       &Store{Dir: cfg.DocumentDir, Kind: Document, MaxFileBytes: cfg.MaxFileBytes, Scanner: scanner, Backend: LocalBackend{Dir: cfg.DocumentDir}},
+		DocumentURLs:    NewSigner(secret, cfg.DocumentURLTTL),
+		MaxRequestBytes: cfg.MaxRequestBytes,
+	}
+}
+
+// Save validates fh and stores it under a random name, which it returns.
+// Nothing is left on disk when it fails.
+func (s *Store) Save(ctx context.Context, fh *multipart.FileHeader) (string, error) {
+	// 1. --- Size (the header is the client's claim; the copy below re-checks) ---
+	if fh.Size > s.MaxFileBytes {
+		return "", ErrTooLarge
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	// 2. --- Content Sniffing ---
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	mimeType, ext, err := s.sniff(head[:n])
+	if err != nil {
+		return "", err
+	}
+	return s.keep(ctx, io.MultiReader(bytes.NewReader(head[:n]), src), mimeType, ext)
+}
+
+// SaveBytes stores a file the server produced itself (a processed image),
+// checked like an upload, under a random name, which it returns.
+func (s *Store) SaveBytes(ctx context.Context, data []byte) (string, error) {
+	if int64(len(data)) > s.MaxFileBytes {
+		return "", ErrTooLarge
+	}
+	mimeType, ext, err := s.sniff(data)
+	if err != nil {
+		return "", err
+	}
+	return s.keep(ctx, bytes.NewReader(data), mimeType, ext)
+}
+
+// sniff detects the content type of a file from its first bytes and returns
+// the extension it is stored under, or ErrType when the kind refuses it.
+func (s *Store) sniff(head []byte) (mimeType, ext string, err error) {
+	mimeType, _, _ = strings.Cut(http.DetectContentType(head), ";")
+	ext, ok := s.Kind.Types[mimeType]
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s (allowed: %s)", ErrType, mimeType, s.Kind.Allowed())
+	}
+	return mimeType, ext, nil
+}
+
+// keep stages r in Dir (capped at MaxFileBytes), scans it and hands it to the
+// backend under a random name with ext.
+func (s *Store) keep(ctx context.Context, r io.Reader, mimeType, ext string) (string, error) {
+	// 3. --- Write to a temp file in the target dir, so the rename is atomic ---
+	if err := os.MkdirAll(s.Dir, 0o750); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	written, err := io.Copy(tmp, io.LimitReader(r, s.MaxFileBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if written > s.MaxFileBytes {
+		return "", ErrTooLarge
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	// 4. --- Virus Scan ---
+	if s.Scanner != nil {
+		if err := s.Scanner.Scan(ctx, tmp.Name()); err != nil {
+			return "", err
+		}
+	}
+
+	// 5. --- Keep under a random name ---
+	name := uuid.NewString() + ext
+	if err := s.Backend.Put(ctx, name, tmp.Name(), mimeType); err != nil {
+		return "", err
+	}
+	keep = true
+	os.Remove(tmp.Name()) // the staged copy, unless LocalBackend moved it
+	return name, nil
+}
+
+// URL is the public address of a stored name.
+func (s *Store) URL(name string) string {
+	return s.Backend.URL(name)
+}
+
+// Path resolves a stored name to its file, refusing anything that is not a
+// plain file name. Older rows stored a full path; only its base name is used.
+func (s *Store) Path(name string) (string, bool) {
+	name = filepath.Base(name)
+	if name == "." || name == "/" || strings.HasPrefix(name, ".") {
+		return "", false
+	}
+	return filepath.Join(s.Dir, name), true
+}
@@ -0,0 +1,151 @@
+// Package webhooks delivers signed event notifications to the URLs users
+// register with POST /v1/webhooks. Each delivery is one POST of a JSON event
+// with these headers:
+//
+//	X-Webhook-ID:        the event ID, the same on every retry (deduplicate on it)
+//	X-Webhook-Event:     the event type, e.g. wallet.credited
+//	X-Webhook-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+//
+// keyed with the endpoint's secret. Any 2xx response acknowledges the event;
+// redirects are not followed and count as failures.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Event types a webhook can subscribe to.
+const (
+	WalletCredited = "wallet.credited"
+	WalletDebited  = "wallet.debited"
+)
+
+// deliverTimeout bounds one delivery, connection included.
+const deliverTimeout = 10 * time.Second
+
+// ErrUnsafeURL is returned for a URL the platform will not call: not HTTPS,
+// or (in production) resolving to a loopback, private or link-local address.
+var ErrUnsafeURL = errors.New("webhooks: url must use https and a public address")
+
+// Client POSTs events to registered endpoints.
+type Client struct {
+	http         *http.Client
+	allowPrivate bool
+}
+
+// NewClient returns a Client. allowPrivate lets it call http:// URLs and
+// private addresses (local receivers in development); production must pass
+// false, so a webhook cannot be aimed at the platform's own network.
+func NewClient(allowPrivate bool) *Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		// Checked on the resolved address, so DNS cannot point a public name inside.
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return ErrUnsafeURL
+			}
+			return nil
+		}
+	}
+	transport := &http.Transport{
+		Proxy:               nil,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConnsPerHost: 2,
+	}
+	return &Client{
+		http: &http.Client{
+			Timeout:   deliverTimeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowPrivate: allowPrivate,
+	}
+}
+
+// CheckURL validates a URL before it is registered.
+func (c *Client) CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return ErrUnsafeURL
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && c.allowPrivate:
+	default:
+		return ErrUnsafeURL
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !c.allowPrivate && !publicIP(ip) {
+		return ErrUnsafeURL
+	}
+	return nil
+}
+
+// Deliver POSTs body to url and returns the response status. err is nil only
+// for a 2xx response.
+func (c *Client) Deliver(ctx context.Context, url, secret, eventID, eventType string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TapToSell-Webhooks/1")
+	req.Header.Set("X-Webhook-ID", eventID)
+	req.Header.Set("X-Webhook-Event", eventType)
+	req.Header.Set("X-Webhook-Signature", Sign(secret, time.Now(), body))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // lets the connection be reused
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhooks: endpoint answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the X-Webhook-Signature value for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret ("whsec_" and 64 hex digits).
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// publicIP reports whether ip is routable on the internet.
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || ip.IsInterfaceLocalMulticast())
+}
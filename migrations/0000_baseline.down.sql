@@ -0,0 +1 @@
+-- irreversible: reverting the baseline would drop every core table; restore from a backup instead
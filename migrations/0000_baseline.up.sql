@@ -0,0 +1,283 @@
+-- The core schema as it stood before versioned migrations (0001 onwards alter
+-- these tables), so `api migrate up` builds a database from empty.
+-- Every table is IF NOT EXISTS: on a database that predates this file the
+-- migration only records itself.
+
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    role VARCHAR(32) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'unverified',
+    email VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    full_name VARCHAR(255) NOT NULL,
+    phone_number VARCHAR(32) NOT NULL DEFAULT '',
+    penalty_strikes INT NOT NULL DEFAULT 0,
+    company_name VARCHAR(255) NULL,
+    ic_number VARCHAR(50) NULL,
+    ssm_number VARCHAR(50) NULL,
+    address_line1 VARCHAR(255) NULL,
+    address_line2 VARCHAR(255) NULL,
+    city VARCHAR(100) NULL,
+    state VARCHAR(100) NULL,
+    postcode VARCHAR(10) NULL,
+    ssm_document_url VARCHAR(500) NULL,
+    bank_statement_url VARCHAR(500) NULL,
+    verification_code VARCHAR(10) NULL,
+    verification_expiry DATETIME NULL,
+    version INT NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_users_email (email)
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    parent_id BIGINT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_categories_slug (slug),
+    INDEX idx_categories_parent (parent_id)
+);
+
+CREATE TABLE IF NOT EXISTS brands (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_brands_slug (slug)
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    supplier_id BIGINT NOT NULL,
+    sku VARCHAR(100) NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    category VARCHAR(255) NULL,
+    brand VARCHAR(255) NULL,
+    price_to_tts DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    srp DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    stock_quantity INT NOT NULL DEFAULT 0,
+    is_variable TINYINT(1) NOT NULL DEFAULT 0,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    commission_rate DECIMAL(5, 2) NULL,
+    weight DECIMAL(10, 2) NULL,
+    weight_grams INT NOT NULL DEFAULT 0,
+    pkg_length DECIMAL(10, 2) NULL,
+    pkg_width DECIMAL(10, 2) NULL,
+    pkg_height DECIMAL(10, 2) NULL,
+    images JSON NULL,
+    video_url VARCHAR(500) NULL,
+    video_status VARCHAR(32) NOT NULL DEFAULT 'none',
+    size_chart JSON NULL,
+    variation_images JSON NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_products_supplier (supplier_id)
+);
+
+CREATE TABLE IF NOT EXISTS product_variants (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    sku VARCHAR(100) NULL,
+    price_to_tts DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    stock_quantity INT NOT NULL DEFAULT 0,
+    options JSON NULL,
+    commission_rate DECIMAL(5, 2) NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_product_variants_product (product_id)
+);
+
+CREATE TABLE IF NOT EXISTS product_categories (
+    product_id BIGINT NOT NULL,
+    category_id BIGINT NOT NULL,
+    PRIMARY KEY (product_id, category_id),
+    INDEX idx_product_categories_category (category_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS product_brands (
+    product_id BIGINT NOT NULL,
+    brand_id BIGINT NOT NULL,
+    PRIMARY KEY (product_id, brand_id),
+    INDEX idx_product_brands_brand (brand_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS price_appeals (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    supplier_id BIGINT NOT NULL,
+    old_price DECIMAL(12, 2) NOT NULL,
+    new_price DECIMAL(12, 2) NOT NULL,
+    reason TEXT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    rejection_reason TEXT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_price_appeals_status (status, created_at),
+    INDEX idx_price_appeals_product (product_id)
+);
+
+CREATE TABLE IF NOT EXISTS carts (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_carts_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    cart_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    variant_id BIGINT NULL,
+    quantity INT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    status ENUM('on-hold', 'processing', 'shipped', 'completed', 'cancelled') NOT NULL DEFAULT 'on-hold',
+    total DECIMAL(12, 2) NOT NULL,
+    tracking VARCHAR(100) NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    variant_id BIGINT NULL,
+    quantity INT NOT NULL,
+    unit_price DECIMAL(12, 2) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_order_items_order (order_id),
+    INDEX idx_order_items_product (product_id)
+);
+
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    type ENUM('topup', 'order_payment', 'withdrawal', 'refund', 'payout') NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'completed',
+    amount DECIMAL(10, 2) NOT NULL,
+    balance_after DECIMAL(10, 2) NULL,
+    notes TEXT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS withdrawal_requests (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    bank_details VARCHAR(255) NOT NULL,
+    rejection_reason TEXT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_withdrawal_requests_user (user_id, created_at),
+    INDEX idx_withdrawal_requests_status (status, created_at)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    message TEXT NOT NULL,
+    link VARCHAR(500) NULL,
+    is_read TINYINT(1) NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_notifications_user (user_id, created_at)
+);
+
+CREATE TABLE IF NOT EXISTS settings (
+    setting_key VARCHAR(100) NOT NULL PRIMARY KEY,
+    setting_value TEXT NOT NULL,
+    description VARCHAR(500) NULL
+);
+
+INSERT IGNORE INTO settings (setting_key, setting_value, description)
+VALUES ('maintenance_mode', 'false', 'Only administrators can sign in while this is true (true/false)');
+
+CREATE TABLE IF NOT EXISTS inventory_categories (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    parent_id BIGINT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_inventory_categories_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS inventory_brands (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_inventory_brands_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS inventory_items (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NULL,
+    sku VARCHAR(100) NULL,
+    price DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    stock INT NOT NULL DEFAULT 0,
+    promoted_product_id BIGINT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_inventory_items_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS plans (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NULL,
+    price DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    duration_days INT NOT NULL,
+    ai_credits_included DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    is_public TINYINT(1) NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_subscriptions (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    plan_id BIGINT NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'active',
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_user_subscriptions_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS ai_user_credits (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    credits_remaining DECIMAL(12, 4) NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_ai_user_credits_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS ai_chat_history (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    user_role VARCHAR(32) NOT NULL,
+    user_message TEXT NOT NULL,
+    ai_response TEXT NOT NULL,
+    tokens_used INT NOT NULL DEFAULT 0,
+    cost_incurred DECIMAL(12, 4) NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_ai_chat_history_user (user_id, created_at)
+);
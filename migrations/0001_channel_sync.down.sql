@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS channel_listings;
+DROP TABLE IF EXISTS channels;
@@ -0,0 +1,27 @@
+-- Channels linked by dropshippers and the per-product listing sync state.
+CREATE TABLE IF NOT EXISTS channels (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    platform VARCHAR(32) NOT NULL,
+    shop_name VARCHAR(255) NOT NULL,
+    status ENUM('active', 'disconnected') NOT NULL DEFAULT 'active',
+    last_synced_at DATETIME NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_channels_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS channel_listings (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    channel_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    external_id VARCHAR(128) NULL,
+    sync_status ENUM('pending', 'synced', 'failed') NOT NULL DEFAULT 'pending',
+    last_error TEXT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_attempt_at DATETIME NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_channel_listings_channel_product (channel_id, product_id),
+    INDEX idx_channel_listings_status (channel_id, sync_status)
+);
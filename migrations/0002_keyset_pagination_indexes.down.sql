@@ -0,0 +1,4 @@
+DROP INDEX idx_wallet_tx_user_created ON wallet_transactions;
+DROP INDEX idx_orders_user_created ON orders;
+DROP INDEX idx_products_status_created ON products;
+DROP INDEX idx_products_supplier_created ON products;
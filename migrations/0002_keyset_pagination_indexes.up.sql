@@ -0,0 +1,6 @@
+-- Composite indexes backing the (created_at, id) keyset pagination used by
+-- the product, order and wallet transaction listings.
+CREATE INDEX idx_products_supplier_created ON products (supplier_id, created_at, id);
+CREATE INDEX idx_products_status_created ON products (status, created_at, id);
+CREATE INDEX idx_orders_user_created ON orders (user_id, created_at, id);
+CREATE INDEX idx_wallet_tx_user_created ON wallet_transactions (user_id, created_at, id);
@@ -0,0 +1,3 @@
+DROP INDEX idx_cart_items_cart_product_variant ON cart_items;
+DROP INDEX idx_orders_user_status ON orders;
+DROP INDEX idx_products_supplier_status ON products;
@@ -0,0 +1,6 @@
+-- Composite indexes for the hot-path filters found in the query audit.
+-- wallet_transactions (user_id, created_at) is already covered by
+-- idx_wallet_tx_user_created from 0002 (user_id, created_at, id).
+CREATE INDEX idx_products_supplier_status ON products (supplier_id, status);
+CREATE INDEX idx_orders_user_status ON orders (user_id, status);
+CREATE INDEX idx_cart_items_cart_product_variant ON cart_items (cart_id, product_id, variant_id);
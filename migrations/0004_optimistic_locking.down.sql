@@ -0,0 +1,2 @@
+ALTER TABLE inventory_items DROP COLUMN version;
+ALTER TABLE products DROP COLUMN version;
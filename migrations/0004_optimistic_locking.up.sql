@@ -0,0 +1,5 @@
+-- Row versions for optimistic locking. Every write bumps version; client
+-- edits send the version they loaded and get 409 Conflict when it is stale.
+-- users.version already exists (written as 1 on signup).
+ALTER TABLE products ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE inventory_items ADD COLUMN version INT NOT NULL DEFAULT 1;
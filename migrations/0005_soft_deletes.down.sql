@@ -0,0 +1,8 @@
+DROP INDEX idx_orders_deleted_at ON orders;
+DROP INDEX idx_inventory_items_deleted_at ON inventory_items;
+DROP INDEX idx_products_deleted_at ON products;
+DROP INDEX idx_users_deleted_at ON users;
+ALTER TABLE orders DROP COLUMN deleted_at;
+ALTER TABLE inventory_items DROP COLUMN deleted_at;
+ALTER TABLE products DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,10 @@
+-- Soft deletes: rows are hidden by setting deleted_at and hard-deleted later
+-- by the retention job (see internal/retention) once past their window.
+ALTER TABLE users ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL;
+ALTER TABLE products ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL;
+ALTER TABLE inventory_items ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL;
+ALTER TABLE orders ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX idx_products_deleted_at ON products (deleted_at);
+CREATE INDEX idx_inventory_items_deleted_at ON inventory_items (deleted_at);
+CREATE INDEX idx_orders_deleted_at ON orders (deleted_at);
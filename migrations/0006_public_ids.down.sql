@@ -0,0 +1,6 @@
+DROP INDEX uq_orders_public_id ON orders;
+ALTER TABLE orders DROP COLUMN public_id;
+DROP INDEX uq_products_public_id ON products;
+ALTER TABLE products DROP COLUMN public_id;
+DROP INDEX uq_users_public_id ON users;
+ALTER TABLE users DROP COLUMN public_id;
@@ -0,0 +1,18 @@
+-- Public IDs: random UUIDs exposed in URLs, responses and notifications instead
+-- of the sequential primary keys (which leak volume and invite enumeration).
+-- Existing rows are backfilled; the expression default covers any insert path
+-- that does not set one explicitly (MySQL 8.0.13+).
+ALTER TABLE users ADD COLUMN public_id CHAR(36) NULL;
+UPDATE users SET public_id = UUID() WHERE public_id IS NULL;
+ALTER TABLE users MODIFY public_id CHAR(36) NOT NULL DEFAULT (UUID());
+CREATE UNIQUE INDEX uq_users_public_id ON users (public_id);
+
+ALTER TABLE products ADD COLUMN public_id CHAR(36) NULL;
+UPDATE products SET public_id = UUID() WHERE public_id IS NULL;
+ALTER TABLE products MODIFY public_id CHAR(36) NOT NULL DEFAULT (UUID());
+CREATE UNIQUE INDEX uq_products_public_id ON products (public_id);
+
+ALTER TABLE orders ADD COLUMN public_id CHAR(36) NULL;
+UPDATE orders SET public_id = UUID() WHERE public_id IS NULL;
+ALTER TABLE orders MODIFY public_id CHAR(36) NOT NULL DEFAULT (UUID());
+CREATE UNIQUE INDEX uq_orders_public_id ON orders (public_id);
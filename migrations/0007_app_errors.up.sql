@@ -0,0 +1,18 @@
+-- Recent application errors (panics and 5xx responses), kept as a ring buffer
+-- by errreport.DB so operators can read them at GET /v1/admin/errors.
+CREATE TABLE IF NOT EXISTS app_errors (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    trace_id VARCHAR(32) NOT NULL DEFAULT '',
+    user_id BIGINT NULL,
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(255) NOT NULL DEFAULT '',
+    path VARCHAR(1024) NOT NULL,
+    status SMALLINT NOT NULL,
+    is_panic BOOLEAN NOT NULL DEFAULT FALSE,
+    message TEXT NOT NULL,
+    stack MEDIUMTEXT NULL,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_app_errors_created (created_at, id),
+    INDEX idx_app_errors_request (request_id)
+);
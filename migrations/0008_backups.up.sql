@@ -0,0 +1,13 @@
+-- One row per backup run (see internal/backup); GET /v1/admin/backups reads it.
+CREATE TABLE IF NOT EXISTS backups (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    status ENUM('running', 'succeeded', 'failed') NOT NULL DEFAULT 'running',
+    target VARCHAR(512) NOT NULL,
+    object_name VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NULL,
+    error TEXT NULL,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME NULL,
+    INDEX idx_backups_started (started_at),
+    INDEX idx_backups_status (status, started_at)
+);
@@ -0,0 +1,21 @@
+-- Raw inbound payloads and our responses for webhook and payment endpoints,
+-- kept as a capped table (see internal/audit) for dispute resolution and replay.
+CREATE TABLE IF NOT EXISTS request_captures (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    kind ENUM('webhook', 'payment') NOT NULL,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    user_id BIGINT NULL,
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    path VARCHAR(1024) NOT NULL,
+    request_headers TEXT NOT NULL,
+    request_body MEDIUMTEXT NOT NULL,
+    signature VARCHAR(1024) NOT NULL DEFAULT '',
+    response_status SMALLINT NOT NULL,
+    response_body MEDIUMTEXT NOT NULL,
+    replay_of BIGINT NULL,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_request_captures_created (created_at, id),
+    INDEX idx_request_captures_kind (kind, created_at),
+    INDEX idx_request_captures_request (request_id)
+);
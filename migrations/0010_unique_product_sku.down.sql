@@ -0,0 +1 @@
+DROP INDEX uq_products_supplier_sku ON products;
@@ -0,0 +1,6 @@
+-- A supplier's SKUs are unique across their products (NULL SKUs are exempt).
+-- Soft-deleted products keep their SKU until the retention job purges them.
+-- Existing duplicates make this fail; find them with:
+--   SELECT supplier_id, sku, COUNT(*) FROM products WHERE sku IS NOT NULL
+--   GROUP BY supplier_id, sku HAVING COUNT(*) > 1;
+CREATE UNIQUE INDEX uq_products_supplier_sku ON products (supplier_id, sku);
@@ -0,0 +1 @@
+-- irreversible: encrypted IC, SSM and bank details do not fit the old column widths; decrypt them and restore from a backup instead
@@ -0,0 +1,7 @@
+-- Room for encrypted values: "enc:v1:<kid>:" plus base64 nonce, ciphertext and
+-- tag (see internal/pii). Existing rows stay plaintext until
+-- `api encrypt-pii` rewrites them.
+-- There is no down migration: encrypted values do not fit the old widths.
+ALTER TABLE users MODIFY COLUMN ic_number VARCHAR(255) NULL;
+ALTER TABLE users MODIFY COLUMN ssm_number VARCHAR(255) NULL;
+ALTER TABLE withdrawal_requests MODIFY COLUMN bank_details TEXT NOT NULL;
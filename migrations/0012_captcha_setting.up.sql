@@ -0,0 +1,4 @@
+-- CAPTCHA on registration, login and resend-code is off until a manager turns
+-- it on (PATCH /v1/manager/settings); it also needs CAPTCHA_PROVIDER.
+INSERT IGNORE INTO settings (setting_key, setting_value, description)
+VALUES ('captcha_enabled', 'false', 'Require a CAPTCHA on registration, login and resend-code (true/false)');
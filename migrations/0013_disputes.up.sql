@@ -0,0 +1,38 @@
+-- Disputes on paid orders (see handlers/dispute_handlers.go). An open dispute
+-- holds the supplier payout until a manager resolves it, or the supplier lets
+-- respond_by pass and it resolves to a full refund.
+CREATE TABLE IF NOT EXISTS disputes (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    dropshipper_id BIGINT NOT NULL,
+    supplier_id BIGINT NOT NULL,
+    reason ENUM('non_delivery', 'wrong_item', 'damaged', 'other') NOT NULL,
+    description TEXT NOT NULL,
+    status ENUM('open', 'under_review', 'resolved', 'withdrawn') NOT NULL DEFAULT 'open',
+    supplier_response TEXT NULL,
+    respond_by DATETIME NOT NULL,
+    responded_at DATETIME NULL,
+    refund_amount DECIMAL(12, 2) NULL,
+    supplier_amount DECIMAL(12, 2) NULL,
+    platform_amount DECIMAL(12, 2) NULL,
+    resolution_note TEXT NULL,
+    resolved_by BIGINT NULL,
+    resolved_at DATETIME NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE INDEX uq_disputes_order (order_id),
+    INDEX idx_disputes_deadline (status, respond_by),
+    INDEX idx_disputes_dropshipper (dropshipper_id, created_at),
+    INDEX idx_disputes_supplier (supplier_id, created_at)
+);
+
+-- Files either side attaches; stored privately like supplier documents.
+CREATE TABLE IF NOT EXISTS dispute_evidence (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    dispute_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    note VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    INDEX idx_dispute_evidence_dispute (dispute_id, id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE products DROP COLUMN rating_avg, DROP COLUMN rating_count;
+DROP TABLE IF EXISTS product_reviews;
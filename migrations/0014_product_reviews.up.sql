@@ -0,0 +1,28 @@
+-- Reviews from dropshippers who completed an order containing the product
+-- (see handlers/review_handlers.go). One review per dropshipper and product.
+CREATE TABLE IF NOT EXISTS product_reviews (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    dropshipper_id BIGINT NOT NULL,
+    order_id BIGINT NOT NULL,
+    rating TINYINT NOT NULL,
+    comment TEXT NOT NULL,
+    supplier_reply TEXT NULL,
+    replied_at DATETIME NULL,
+    status ENUM('published', 'flagged', 'hidden') NOT NULL DEFAULT 'published',
+    flag_reason VARCHAR(500) NULL,
+    moderation_note VARCHAR(500) NULL,
+    moderated_by BIGINT NULL,
+    moderated_at DATETIME NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE INDEX uq_product_reviews_dropshipper (product_id, dropshipper_id),
+    INDEX idx_product_reviews_product (product_id, status, created_at),
+    INDEX idx_product_reviews_status (status, created_at)
+);
+
+-- Aggregate of the visible (not hidden) reviews, kept on the product so
+-- listings and search can show it without a join.
+ALTER TABLE products
+    ADD COLUMN rating_avg DECIMAL(3, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN rating_count INT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN discount_total;
+DROP TABLE IF EXISTS order_discounts;
+DROP TABLE IF EXISTS promotions;
@@ -0,0 +1,41 @@
+-- Checkout promotions (see internal/promotions). A row with a code is a
+-- coupon the dropshipper enters; a row without one is an automatic campaign.
+CREATE TABLE IF NOT EXISTS promotions (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(40) NULL,
+    kind ENUM('percent', 'fixed') NOT NULL,
+    value DECIMAL(12, 2) NOT NULL,
+    max_discount DECIMAL(12, 2) NULL,
+    min_spend DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    category_id BIGINT NULL,
+    first_order_only TINYINT(1) NOT NULL DEFAULT 0,
+    usage_limit INT NULL,
+    per_user_limit INT NULL,
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME NULL,
+    is_active TINYINT(1) NOT NULL DEFAULT 1,
+    created_by BIGINT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE INDEX uq_promotions_code (code),
+    INDEX idx_promotions_active (is_active, starts_at)
+);
+
+-- Discount lines of an order; orders.total is already net of them.
+CREATE TABLE IF NOT EXISTS order_discounts (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    promotion_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    code VARCHAR(40) NULL,
+    description VARCHAR(255) NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    created_at DATETIME NOT NULL,
+    INDEX idx_order_discounts_order (order_id),
+    INDEX idx_order_discounts_promotion (promotion_id, created_at),
+    INDEX idx_order_discounts_user (promotion_id, user_id)
+);
+
+-- Platform-funded: the supplier payout is total + discount_total.
+ALTER TABLE orders ADD COLUMN discount_total DECIMAL(12, 2) NOT NULL DEFAULT 0;
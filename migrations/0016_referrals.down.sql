@@ -0,0 +1,4 @@
+DELETE FROM wallet_transactions WHERE type = 'promo_credit';
+DROP TABLE IF EXISTS referrals;
+DROP INDEX uq_users_referral_code ON users;
+ALTER TABLE users DROP COLUMN referral_code;
@@ -0,0 +1,27 @@
+-- Referral program: every dropshipper gets a code (generated on first use);
+-- a registration with the code is attributed to its owner and rewarded with
+-- wallet promo credit once the new dropshipper completes a qualifying order.
+ALTER TABLE users ADD COLUMN referral_code VARCHAR(16) NULL;
+CREATE UNIQUE INDEX uq_users_referral_code ON users (referral_code);
+
+CREATE TABLE IF NOT EXISTS referrals (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    referrer_id BIGINT NOT NULL,
+    referee_id BIGINT NOT NULL,
+    status ENUM('pending', 'rewarded', 'rejected') NOT NULL DEFAULT 'pending',
+    signup_ip VARCHAR(45) NOT NULL DEFAULT '',
+    order_id BIGINT NULL,
+    referrer_reward DECIMAL(12, 2) NULL,
+    referee_reward DECIMAL(12, 2) NULL,
+    reject_reason VARCHAR(255) NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    rewarded_at DATETIME NULL,
+    UNIQUE INDEX uq_referrals_referee (referee_id),
+    INDEX idx_referrals_referrer_created (referrer_id, created_at, id),
+    INDEX idx_referrals_referrer_status (referrer_id, status, rewarded_at)
+);
+
+-- Rewards are paid as 'promo_credit' ledger entries; a free-form type column
+-- lets new entry types ship without another migration.
+ALTER TABLE wallet_transactions MODIFY COLUMN type VARCHAR(32) NOT NULL;
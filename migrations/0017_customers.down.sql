@@ -0,0 +1,4 @@
+DROP INDEX idx_orders_customer ON orders;
+ALTER TABLE orders DROP COLUMN ship_to;
+ALTER TABLE orders DROP COLUMN customer_id;
+DROP TABLE IF EXISTS customers;
@@ -0,0 +1,25 @@
+-- End-customers of each dropshipper (the people orders ship to). Checkout
+-- records or reuses one; the order keeps its own copy of the address in
+-- ship_to, so later edits to the customer do not rewrite past shipments.
+CREATE TABLE IF NOT EXISTS customers (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    phone VARCHAR(32) NOT NULL,
+    address_line1 VARCHAR(255) NOT NULL,
+    address_line2 VARCHAR(255) NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL,
+    state VARCHAR(100) NOT NULL,
+    postcode VARCHAR(20) NOT NULL,
+    notes VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    -- phone holds digits only, so one number is one customer per dropshipper.
+    UNIQUE INDEX uq_customers_user_phone (user_id, phone),
+    INDEX idx_customers_user_created (user_id, created_at, id)
+);
+
+ALTER TABLE orders ADD COLUMN customer_id BIGINT NULL;
+-- JSON snapshot of the shipping address (models.ShipTo).
+ALTER TABLE orders ADD COLUMN ship_to TEXT NULL;
+CREATE INDEX idx_orders_customer ON orders (customer_id, created_at, id);
@@ -0,0 +1,6 @@
+DROP INDEX idx_orders_status_created ON orders;
+ALTER TABLE order_items DROP COLUMN preorder;
+ALTER TABLE products DROP COLUMN preorder_reserved;
+ALTER TABLE products DROP COLUMN preorder_limit;
+ALTER TABLE products DROP COLUMN preorder_available_at;
+ALTER TABLE products DROP COLUMN is_preorder;
@@ -0,0 +1,14 @@
+-- Pre-orders on simple products. Units ordered beyond the stock are held in
+-- preorder_reserved (capped by preorder_limit) instead of being deducted, and
+-- the order waits in status 'pre-order', already paid, until the stock arrives.
+ALTER TABLE products ADD COLUMN is_preorder TINYINT(1) NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN preorder_available_at DATETIME NULL;
+ALTER TABLE products ADD COLUMN preorder_limit INT NULL;
+ALTER TABLE products ADD COLUMN preorder_reserved INT NOT NULL DEFAULT 0;
+
+-- 1 while the line waits for stock; cleared when the order converts.
+ALTER TABLE order_items ADD COLUMN preorder TINYINT(1) NOT NULL DEFAULT 0;
+
+-- Room for the 'pre-order' status.
+ALTER TABLE orders MODIFY COLUMN status VARCHAR(32) NOT NULL;
+CREATE INDEX idx_orders_status_created ON orders (status, created_at, id);
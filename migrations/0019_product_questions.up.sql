@@ -0,0 +1,19 @@
+-- Questions dropshippers ask on a product page and the supplier's answer
+-- (see handlers/question_handlers.go). Managers hide abusive ones.
+CREATE TABLE IF NOT EXISTS product_questions (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    dropshipper_id BIGINT NOT NULL,
+    question TEXT NOT NULL,
+    answer TEXT NULL,
+    answered_at DATETIME NULL,
+    status ENUM('published', 'hidden') NOT NULL DEFAULT 'published',
+    moderation_note VARCHAR(500) NULL,
+    moderated_by BIGINT NULL,
+    moderated_at DATETIME NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    INDEX idx_product_questions_product (product_id, status, created_at),
+    INDEX idx_product_questions_dropshipper (dropshipper_id, created_at),
+    INDEX idx_product_questions_status (status, created_at)
+);
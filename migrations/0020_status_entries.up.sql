@@ -0,0 +1,20 @@
+-- Manager-edited entries behind GET /v1/status and /v1/changelog (see
+-- handlers/status_handlers.go): incidents, scheduled maintenance windows and
+-- release notes. An active window with blocks_writes pauses write requests.
+CREATE TABLE IF NOT EXISTS status_entries (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    kind ENUM('incident', 'maintenance', 'release') NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    severity ENUM('info', 'minor', 'major', 'critical') NOT NULL DEFAULT 'info',
+    release_tag VARCHAR(40) NULL,
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME NULL,
+    blocks_writes TINYINT(1) NOT NULL DEFAULT 0,
+    is_published TINYINT(1) NOT NULL DEFAULT 1,
+    created_by BIGINT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    INDEX idx_status_entries_kind (kind, is_published, starts_at),
+    INDEX idx_status_entries_ends (kind, ends_at)
+);
@@ -0,0 +1,5 @@
+DROP INDEX idx_users_vacation_ends ON users;
+ALTER TABLE users DROP COLUMN vacation_hide_listings;
+ALTER TABLE users DROP COLUMN vacation_message;
+ALTER TABLE users DROP COLUMN vacation_ends_at;
+ALTER TABLE users DROP COLUMN vacation_started_at;
@@ -0,0 +1,9 @@
+-- Supplier vacation mode (see handlers/vacation_handlers.go). A supplier is
+-- away from vacation_started_at until vacation_ends_at (NULL = until turned
+-- off): new orders for their products are refused, and their listings are
+-- hidden (vacation_hide_listings) or marked as unavailable in the catalogue.
+ALTER TABLE users ADD COLUMN vacation_started_at DATETIME NULL;
+ALTER TABLE users ADD COLUMN vacation_ends_at DATETIME NULL;
+ALTER TABLE users ADD COLUMN vacation_message VARCHAR(500) NULL;
+ALTER TABLE users ADD COLUMN vacation_hide_listings TINYINT(1) NOT NULL DEFAULT 0;
+CREATE INDEX idx_users_vacation_ends ON users (vacation_ends_at);
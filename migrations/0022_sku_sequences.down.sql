@@ -0,0 +1,2 @@
+DROP INDEX idx_product_variants_sku ON product_variants;
+DROP TABLE IF EXISTS sku_sequences;
@@ -0,0 +1,11 @@
+-- Per-supplier counter behind generated SKUs ({seq} in SKU_PATTERN). The row
+-- is also locked while a supplier's SKUs are checked and written, so two
+-- saves cannot claim the same SKU on a product and a variant at once
+-- (uq_products_supplier_sku only covers products).
+CREATE TABLE IF NOT EXISTS sku_sequences (
+    supplier_id BIGINT NOT NULL PRIMARY KEY,
+    next_seq BIGINT NOT NULL DEFAULT 0
+);
+
+-- Variant SKU lookups for the per-supplier uniqueness check.
+CREATE INDEX idx_product_variants_sku ON product_variants (sku);
@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN courier;
+ALTER TABLE products DROP COLUMN shipping_restrictions;
@@ -0,0 +1,6 @@
+-- Handling restrictions of a product (see package shipping). Couriers that
+-- refuse one of them are excluded for it in the cart and when shipping.
+ALTER TABLE products ADD COLUMN shipping_restrictions SET('battery', 'liquid', 'fragile', 'oversize') NOT NULL DEFAULT '';
+
+-- The courier a supplier shipped an order with (a SHIPPING_COURIERS code).
+ALTER TABLE orders ADD COLUMN courier VARCHAR(32) NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE products DROP COLUMN order_increment;
+ALTER TABLE products DROP COLUMN min_order_qty;
@@ -0,0 +1,4 @@
+-- Per-product order quantity rules: a cart line needs at least min_order_qty
+-- units, in multiples of order_increment (e.g. 6 for a product sold in packs of 6).
+ALTER TABLE products ADD COLUMN min_order_qty INT NOT NULL DEFAULT 1;
+ALTER TABLE products ADD COLUMN order_increment INT NOT NULL DEFAULT 1;
@@ -0,0 +1,7 @@
+DROP INDEX idx_order_items_ships_by ON order_items;
+ALTER TABLE order_items DROP COLUMN late_notified_at;
+ALTER TABLE order_items DROP COLUMN ships_by;
+ALTER TABLE products DROP COLUMN order_cutoff;
+ALTER TABLE products DROP COLUMN handling_days;
+ALTER TABLE users DROP COLUMN order_cutoff;
+ALTER TABLE users DROP COLUMN handling_days;
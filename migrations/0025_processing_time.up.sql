@@ -0,0 +1,14 @@
+-- Processing time: handling days (business days) and a daily order cutoff,
+-- shop-wide on the supplier and optionally overridden per product. NULL falls
+-- back to the supplier's setting, then SHIPPING_HANDLING_DAYS / no cutoff.
+ALTER TABLE users ADD COLUMN handling_days TINYINT UNSIGNED NULL;
+ALTER TABLE users ADD COLUMN order_cutoff TIME NULL;
+ALTER TABLE products ADD COLUMN handling_days TINYINT UNSIGNED NULL;
+ALTER TABLE products ADD COLUMN order_cutoff TIME NULL;
+
+-- The date each line must ship by, stamped when the order is paid (a
+-- pre-order line when it converts); late_notified_at marks the supplier as
+-- told once it passed.
+ALTER TABLE order_items ADD COLUMN ships_by DATETIME NULL;
+ALTER TABLE order_items ADD COLUMN late_notified_at DATETIME NULL;
+CREATE INDEX idx_order_items_ships_by ON order_items (ships_by);
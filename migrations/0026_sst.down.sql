@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS order_tax_lines;
+ALTER TABLE orders DROP COLUMN tax_total;
+ALTER TABLE users DROP COLUMN sst_number;
+DROP TABLE IF EXISTS tax_rates;
@@ -0,0 +1,39 @@
+-- SST (see internal/tax). Managers set a rate per category; it covers the
+-- subcategories that set none. Suppliers registered for SST record their
+-- registration number, which is printed on their invoices.
+CREATE TABLE IF NOT EXISTS tax_rates (
+    category_id BIGINT NOT NULL PRIMARY KEY,
+    rate DECIMAL(5, 2) NOT NULL,
+    updated_by BIGINT NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+ALTER TABLE users ADD COLUMN sst_number VARCHAR(20) NULL;
+
+-- Tax lines of an order, one per supplier and rate; orders.total includes
+-- tax_total, and so does the supplier payout.
+ALTER TABLE orders ADD COLUMN tax_total DECIMAL(12, 2) NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS order_tax_lines (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    supplier_id BIGINT NOT NULL,
+    rate DECIMAL(5, 2) NOT NULL,
+    taxable_amount DECIMAL(12, 2) NOT NULL,
+    tax_amount DECIMAL(12, 2) NOT NULL,
+    INDEX idx_order_tax_lines_order (order_id)
+);
+
+-- One invoice per supplier of a paid order. The amounts and the supplier's
+-- registration number are copied at issue so the document never changes.
+CREATE TABLE IF NOT EXISTS invoices (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    supplier_id BIGINT NOT NULL,
+    supplier_sst_number VARCHAR(20) NULL,
+    subtotal DECIMAL(12, 2) NOT NULL,
+    tax_total DECIMAL(12, 2) NOT NULL,
+    total DECIMAL(12, 2) NOT NULL,
+    issued_at DATETIME NOT NULL,
+    UNIQUE INDEX uq_invoices_order_supplier (order_id, supplier_id),
+    INDEX idx_invoices_supplier (supplier_id, issued_at)
+);
@@ -0,0 +1 @@
+-- irreversible: the drifted balance_after values this migration rewrote are not kept; restore from a backup instead
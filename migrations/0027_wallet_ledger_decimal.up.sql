@@ -0,0 +1,16 @@
+-- Amounts are handled as exact sen in Go (see internal/money); the ledger
+-- stores them as DECIMAL so nothing is rounded on the way in or out.
+-- There is no down migration: the drifted balances are not kept.
+ALTER TABLE wallet_transactions MODIFY COLUMN amount DECIMAL(12, 2) NOT NULL;
+ALTER TABLE wallet_transactions MODIFY COLUMN balance_after DECIMAL(12, 2) NULL;
+
+-- balance_after written from float sums may have drifted by a sen: rewrite it
+-- as the exact running sum, in the order AddTransaction appends rows.
+UPDATE wallet_transactions wt
+JOIN (
+    SELECT id, SUM(amount) OVER (PARTITION BY user_id ORDER BY created_at, id) AS running
+    FROM wallet_transactions
+) r ON r.id = wt.id
+SET wt.balance_after = r.running;
+
+ALTER TABLE wallet_transactions MODIFY COLUMN balance_after DECIMAL(12, 2) NOT NULL;
@@ -0,0 +1,7 @@
+-- Summaries go; the rows they grouped show up individually again.
+DELETE FROM notifications WHERE item_count > 0;
+DROP INDEX idx_notifications_batch ON notifications;
+DROP INDEX idx_notifications_user_kind ON notifications;
+ALTER TABLE notifications DROP COLUMN item_count;
+ALTER TABLE notifications DROP COLUMN batch_id;
+ALTER TABLE notifications DROP COLUMN kind;
@@ -0,0 +1,10 @@
+-- Batched notifications: same-kind notifications arriving within
+-- NOTIFICATION_BATCH_WINDOW collapse into one summary row ("32 of your
+-- products were approved"). The individual rows are kept and point at their
+-- summary through batch_id; lists show summaries and ungrouped rows only.
+ALTER TABLE notifications ADD COLUMN kind VARCHAR(32) NULL;
+ALTER TABLE notifications ADD COLUMN batch_id BIGINT NULL;
+-- On a summary: how many rows it groups. 0 on every other row.
+ALTER TABLE notifications ADD COLUMN item_count INT NOT NULL DEFAULT 0;
+CREATE INDEX idx_notifications_user_kind ON notifications (user_id, kind, created_at);
+CREATE INDEX idx_notifications_batch ON notifications (batch_id);
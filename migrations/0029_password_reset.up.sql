@@ -0,0 +1,4 @@
+-- Password reset (POST /v1/auth/forgot-password, /v1/auth/reset-password).
+-- Only the SHA-256 of the emailed token is stored; it is cleared once used.
+ALTER TABLE users ADD COLUMN password_reset_hash CHAR(64) NULL;
+ALTER TABLE users ADD COLUMN password_reset_expiry DATETIME NULL;
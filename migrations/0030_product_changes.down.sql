@@ -0,0 +1,2 @@
+DROP INDEX idx_products_changed ON products;
+ALTER TABLE products DROP COLUMN changed_at;
@@ -0,0 +1,7 @@
+-- Incremental product sync (GET /v1/products/changes). changed_at moves on
+-- every write to the row, including stock, rating and soft deletes; writes
+-- that only touch product_variants bump it explicitly.
+ALTER TABLE products ADD COLUMN changed_at DATETIME(3) NOT NULL
+    DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3);
+UPDATE products SET changed_at = GREATEST(updated_at, COALESCE(deleted_at, updated_at));
+CREATE INDEX idx_products_changed ON products (changed_at, id);
@@ -0,0 +1,4 @@
+-- Token revocation (AUTH_REVOCATION_CHECK). Tokens carry the user's role and
+-- status; with the check on, tokens issued before tokens_valid_after are
+-- refused. A password reset sets it, ending every older session.
+ALTER TABLE users ADD COLUMN tokens_valid_after DATETIME NULL;
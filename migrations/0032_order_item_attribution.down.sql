@@ -0,0 +1,4 @@
+DROP INDEX idx_order_items_supplier ON order_items;
+ALTER TABLE order_items DROP COLUMN commission;
+ALTER TABLE order_items DROP COLUMN commission_rate;
+ALTER TABLE order_items DROP COLUMN supplier_id;
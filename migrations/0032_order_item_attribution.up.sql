@@ -0,0 +1,18 @@
+-- Supplier attribution on order lines. The supplier and commission are copied
+-- at checkout, so a product changing owner or commission later leaves the
+-- history (pending balances, payouts) as it was sold.
+ALTER TABLE order_items ADD COLUMN supplier_id BIGINT NULL;
+-- Percent of the line total the platform keeps, and that amount.
+ALTER TABLE order_items ADD COLUMN commission_rate DECIMAL(5,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN commission DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+-- Existing lines take today's values, the best there is.
+UPDATE order_items oi
+JOIN products p ON p.id = oi.product_id
+LEFT JOIN product_variants v ON v.id = oi.variant_id
+SET oi.supplier_id = p.supplier_id,
+    oi.commission_rate = COALESCE(v.commission_rate, p.commission_rate, 0),
+    oi.commission = ROUND(oi.unit_price * oi.quantity * COALESCE(v.commission_rate, p.commission_rate, 0) / 100, 2);
+
+ALTER TABLE order_items MODIFY supplier_id BIGINT NOT NULL;
+CREATE INDEX idx_order_items_supplier ON order_items (supplier_id, order_id);
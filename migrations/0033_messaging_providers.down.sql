@@ -0,0 +1 @@
+DROP TABLE messaging_providers;
@@ -0,0 +1,14 @@
+-- Email and SMS providers, configured by managers at runtime
+-- (/v1/manager/messaging). One row per channel; settings holds the plain
+-- fields (host, sender, ...) as JSON, secret the password or API token,
+-- encrypted like the other PII columns.
+CREATE TABLE messaging_providers (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    channel VARCHAR(16) NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    settings JSON NOT NULL,
+    secret TEXT NULL,
+    updated_by BIGINT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE KEY uq_messaging_providers_channel (channel)
+);
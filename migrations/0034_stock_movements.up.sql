@@ -0,0 +1,16 @@
+-- Stock adjustments made through PATCH /v1/products/:id/stock (and the
+-- variant route): one row per adjustment with its reason, the change and
+-- the stock it left, so a supplier can trace every manual correction.
+-- variant_id is NULL for a simple product.
+CREATE TABLE stock_movements (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    variant_id BIGINT NULL,
+    user_id BIGINT NOT NULL,
+    delta INT NOT NULL,
+    quantity_after INT NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_stock_movements_product (product_id, created_at, id)
+);
@@ -0,0 +1,21 @@
+-- Asynchronous CSV exports (POST /v1/exports). id is a random UUID, so one
+-- user cannot guess another's export; the finished file is EXPORT_DIR/<id>.csv
+-- and is deleted with the row once expires_at has passed.
+CREATE TABLE export_jobs (
+    id CHAR(36) PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    params JSON NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    rows_done INT NOT NULL DEFAULT 0,
+    rows_total INT NULL,
+    file_name VARCHAR(64) NULL,
+    error VARCHAR(255) NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    finished_at DATETIME(3) NULL,
+    expires_at DATETIME(3) NULL,
+    INDEX idx_export_jobs_user (user_id, status),
+    INDEX idx_export_jobs_status (status, updated_at),
+    INDEX idx_export_jobs_expires (expires_at)
+);
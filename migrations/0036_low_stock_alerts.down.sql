@@ -0,0 +1,2 @@
+DROP TABLE low_stock_alerts;
+DELETE FROM settings WHERE setting_key = 'low_stock_threshold';
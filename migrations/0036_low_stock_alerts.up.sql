@@ -0,0 +1,16 @@
+-- Low-stock alerts: an order that takes a product's or variant's stock below
+-- low_stock_threshold notifies the supplier (0 turns the alerts off).
+INSERT IGNORE INTO settings (setting_key, setting_value, description)
+VALUES ('low_stock_threshold', '5', 'Notify suppliers when an order takes stock below this many units (0 turns it off)');
+
+-- With LOW_STOCK_DIGEST, alerts also wait here for the daily email, which
+-- deletes them once sent.
+CREATE TABLE low_stock_alerts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    supplier_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    variant_id BIGINT NULL,
+    stock INT NOT NULL,
+    created_at DATETIME NOT NULL,
+    INDEX idx_low_stock_alerts_supplier (supplier_id, id)
+);
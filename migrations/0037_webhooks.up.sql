@@ -0,0 +1,42 @@
+-- Outbound webhooks (POST /v1/webhooks). A user registers an endpoint for
+-- some event types; the dispatcher fans new events out into
+-- webhook_deliveries and POSTs them, retrying with backoff. secret is the
+-- HMAC signing key, encrypted like the other PII columns.
+CREATE TABLE webhook_endpoints (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret TEXT NOT NULL,
+    events VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL,
+    INDEX idx_webhook_endpoints_user (user_id)
+);
+
+-- One row per event per endpoint; payload is frozen at fan-out so every
+-- retry sends the same body.
+CREATE TABLE webhook_deliveries (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    endpoint_id BIGINT NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSON NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_status INT NULL,
+    last_error VARCHAR(255) NULL,
+    created_at DATETIME NOT NULL,
+    delivered_at DATETIME NULL,
+    UNIQUE KEY uq_webhook_deliveries_event (endpoint_id, event_id),
+    INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+    INDEX idx_webhook_deliveries_created (created_at)
+);
+
+-- How far the dispatcher has read each event source. It starts at the
+-- current end, so existing history is not replayed.
+CREATE TABLE webhook_cursors (
+    source VARCHAR(32) PRIMARY KEY,
+    last_id BIGINT NOT NULL
+);
+INSERT INTO webhook_cursors (source, last_id)
+SELECT 'wallet_transactions', COALESCE(MAX(id), 0) FROM wallet_transactions;
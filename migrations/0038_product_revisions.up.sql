@@ -0,0 +1,13 @@
+-- Product revision history: every supplier edit (PUT /v1/products/:id) and
+-- manager rollback records the product's editable content before and after.
+CREATE TABLE product_revisions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    rolled_back_to BIGINT NULL,
+    before_data JSON NOT NULL,
+    after_data JSON NOT NULL,
+    created_at DATETIME NOT NULL,
+    INDEX idx_product_revisions_product (product_id, id)
+);
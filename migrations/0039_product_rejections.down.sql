@@ -0,0 +1,2 @@
+ALTER TABLE product_revisions DROP COLUMN note;
+ALTER TABLE products DROP COLUMN rejection_reason;
@@ -0,0 +1,5 @@
+-- The manager's reason for a product's last rejection, shown to the supplier
+-- and kept while it is resubmitted (cleared on approval). Every rejection and
+-- resubmission is also a product revision, the reason in its note.
+ALTER TABLE products ADD COLUMN rejection_reason VARCHAR(500) NULL AFTER status;
+ALTER TABLE product_revisions ADD COLUMN note VARCHAR(500) NULL AFTER rolled_back_to;
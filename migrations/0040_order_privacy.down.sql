@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN sender_name;
+ALTER TABLE orders DROP COLUMN blind_ship;
@@ -0,0 +1,5 @@
+-- Blind shipping, chosen per order at checkout (on by default): the packing
+-- slip in the parcel names the dropshipper, or sender_name when they gave one,
+-- and never the supplier.
+ALTER TABLE orders ADD COLUMN blind_ship BOOLEAN NOT NULL DEFAULT TRUE AFTER ship_to;
+ALTER TABLE orders ADD COLUMN sender_name VARCHAR(100) NULL AFTER blind_ship;
@@ -0,0 +1,3 @@
+-- Catalogue search matches words (and word prefixes) through this index and
+-- ranks products by its relevance score, instead of scanning with LIKE.
+ALTER TABLE products ADD FULLTEXT INDEX ft_products_search (name, description);
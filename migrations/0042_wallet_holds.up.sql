@@ -0,0 +1,15 @@
+-- A hold sets part of a dropshipper's balance aside for an on-hold order
+-- (unpaid at checkout), so the funds are still there when they pay it. It is
+-- captured by the payment or released when the order is cancelled; the
+-- available balance is the ledger balance minus the open ('held') holds.
+CREATE TABLE wallet_holds (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    order_id BIGINT NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'held',
+    created_at DATETIME NOT NULL,
+    closed_at DATETIME NULL,
+    UNIQUE KEY uq_wallet_holds_order (order_id),
+    INDEX idx_wallet_holds_user_status (user_id, status)
+);
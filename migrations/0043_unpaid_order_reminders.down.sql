@@ -0,0 +1 @@
+ALTER TABLE orders DROP COLUMN unpaid_reminded_at;
@@ -0,0 +1,3 @@
+-- When the dropshipper was reminded that an on-hold order is about to be
+-- cancelled for non-payment (24 hours before; see UNPAID_ORDER_DAYS).
+ALTER TABLE orders ADD COLUMN unpaid_reminded_at DATETIME NULL AFTER sender_name;
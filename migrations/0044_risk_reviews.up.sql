@@ -0,0 +1,21 @@
+-- Checkouts and wallet top-ups the risk checks flagged (see package risk) wait
+-- here for a manager. A flagged order is created 'under-review' with its stock
+-- reserved and funds held; a flagged top-up is not credited until approved.
+-- Rejected top-ups count as failures towards later checks.
+CREATE TABLE risk_reviews (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    order_id BIGINT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    score INT NOT NULL,
+    reasons JSON NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    review_note TEXT NULL,
+    reviewed_by BIGINT NULL,
+    reviewed_at DATETIME NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_risk_reviews_order (order_id),
+    INDEX idx_risk_reviews_status (status, created_at, id),
+    INDEX idx_risk_reviews_user (user_id, kind, status, created_at)
+);
@@ -0,0 +1,17 @@
+-- Free-form product tags (e.g. "raya-sale") for curated collections: the
+-- catalogue search filters on them. Tags are created on first use and
+-- matched by slug.
+CREATE TABLE tags (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    slug VARCHAR(60) NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_tags_slug (slug)
+);
+
+CREATE TABLE product_tags (
+    product_id BIGINT NOT NULL,
+    tag_id BIGINT NOT NULL,
+    PRIMARY KEY (product_id, tag_id),
+    INDEX idx_product_tags_tag (tag_id, product_id)
+);
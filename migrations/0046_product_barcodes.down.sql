@@ -0,0 +1,2 @@
+ALTER TABLE product_variants DROP COLUMN barcode;
+ALTER TABLE products DROP COLUMN barcode;
@@ -0,0 +1,7 @@
+-- Barcodes (EAN-8, UPC-A, EAN-13 or GTIN-14) of simple products and of
+-- variants, as the supplier typed them (digits only). A supplier cannot use
+-- one barcode twice; the lookup by barcode searches every supplier.
+ALTER TABLE products ADD COLUMN barcode VARCHAR(14) NULL AFTER sku;
+ALTER TABLE products ADD INDEX idx_products_barcode (barcode);
+ALTER TABLE product_variants ADD COLUMN barcode VARCHAR(14) NULL AFTER sku;
+ALTER TABLE product_variants ADD INDEX idx_product_variants_barcode (barcode);
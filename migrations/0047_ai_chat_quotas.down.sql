@@ -0,0 +1,3 @@
+DROP TABLE ai_usage_daily;
+ALTER TABLE plans DROP COLUMN ai_daily_tokens;
+ALTER TABLE plans DROP COLUMN ai_daily_requests;
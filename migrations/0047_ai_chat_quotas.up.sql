@@ -0,0 +1,15 @@
+-- Daily AI chat caps per subscription plan (NULL: no cap). Users without an
+-- active subscription get AI_FREE_DAILY_REQUESTS and AI_FREE_DAILY_TOKENS.
+ALTER TABLE plans ADD COLUMN ai_daily_requests INT NULL AFTER ai_credits_included;
+ALTER TABLE plans ADD COLUMN ai_daily_tokens INT NULL AFTER ai_daily_requests;
+
+-- Each user's AI chat use per day (server time). The background worker
+-- deletes the days that have ended, which resets the counters.
+CREATE TABLE ai_usage_daily (
+    user_id BIGINT NOT NULL,
+    day DATE NOT NULL,
+    requests INT NOT NULL DEFAULT 0,
+    tokens INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day),
+    INDEX idx_ai_usage_daily_day (day)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN ai_suspended_until;
+DROP TABLE ai_moderation_events;
@@ -0,0 +1,15 @@
+-- AI chat moderation: every refused message and withheld reply, and the
+-- accounts suspended from the assistant for repeated violations.
+CREATE TABLE ai_moderation_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    direction VARCHAR(10) NOT NULL, -- input (the user's message) or output (the reply)
+    category VARCHAR(30) NOT NULL,
+    rule VARCHAR(50) NOT NULL,
+    excerpt VARCHAR(500) NOT NULL,
+    created_at DATETIME NOT NULL,
+    INDEX idx_ai_moderation_user (user_id, created_at),
+    INDEX idx_ai_moderation_created (created_at, id)
+);
+
+ALTER TABLE users ADD COLUMN ai_suspended_until DATETIME NULL;
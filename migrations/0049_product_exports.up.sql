@@ -0,0 +1,11 @@
+-- Which dropshippers exported which catalogue products to list on their own
+-- storefronts (GET /v1/dropshipper/products/:id/export), and how often.
+CREATE TABLE product_exports (
+    dropshipper_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    export_count INT NOT NULL DEFAULT 1,
+    first_exported_at DATETIME NOT NULL,
+    last_exported_at DATETIME NOT NULL,
+    PRIMARY KEY (dropshipper_id, product_id),
+    INDEX idx_product_exports_product (product_id, last_exported_at)
+);
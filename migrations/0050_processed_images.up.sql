@@ -0,0 +1,12 @@
+-- Product photos run through the image pipeline: the upload as sent and the
+-- normalized JPEG (square, white background, optional watermark) made from it.
+CREATE TABLE processed_images (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    supplier_id BIGINT NOT NULL,
+    original_url VARCHAR(500) NOT NULL,
+    processed_url VARCHAR(500) NOT NULL,
+    background_removed BOOLEAN NOT NULL DEFAULT FALSE,
+    watermarked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL,
+    INDEX idx_processed_images_supplier (supplier_id, created_at, id)
+);
@@ -0,0 +1,16 @@
+-- Dropshippers' watched products. last_price and in_stock are what the
+-- watcher was last told (or saw when adding it); the background check
+-- compares them with products changed since checked_at and notifies on a
+-- price change or a return to stock.
+CREATE TABLE watchlist (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    dropshipper_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    last_price DECIMAL(12, 2) NOT NULL,
+    in_stock BOOLEAN NOT NULL,
+    checked_at DATETIME(3) NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_watchlist (dropshipper_id, product_id),
+    INDEX idx_watchlist_dropshipper (dropshipper_id, created_at, id),
+    INDEX idx_watchlist_product (product_id)
+);
@@ -0,0 +1,28 @@
+-- Suspected duplicate listings: products of different suppliers sharing a
+-- barcode or an image, or with near-identical titles. The duplicate job
+-- records each pair once and groups connected pairs into a cluster, which a
+-- manager resolves: merge (keep one product, delete the others), reject the
+-- others, or dismiss (not duplicates). A recorded pair is never flagged again.
+CREATE TABLE duplicate_clusters (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    status ENUM('open', 'merged', 'rejected', 'dismissed') NOT NULL DEFAULT 'open',
+    kept_product_id BIGINT NULL,
+    resolved_by BIGINT NULL,
+    resolution_note VARCHAR(500) NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    resolved_at DATETIME NULL,
+    INDEX idx_duplicate_clusters_status (status, created_at, id)
+);
+
+CREATE TABLE duplicate_pairs (
+    product_a BIGINT NOT NULL,
+    product_b BIGINT NOT NULL,
+    cluster_id BIGINT NOT NULL,
+    reasons VARCHAR(32) NOT NULL,
+    similarity DECIMAL(4, 3) NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (product_a, product_b),
+    INDEX idx_duplicate_pairs_cluster (cluster_id),
+    INDEX idx_duplicate_pairs_b (product_b)
+);
@@ -0,0 +1,13 @@
+-- What the platform keeps of completed orders: the commission copied onto
+-- each line at checkout, one row per order and supplier. Suppliers are paid
+-- the rest of their lines (plus their tax) in wallet_transactions.
+CREATE TABLE platform_earnings (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    supplier_id BIGINT NOT NULL,
+    kind ENUM('commission') NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_platform_earnings (order_id, supplier_id, kind),
+    INDEX idx_platform_earnings_created (created_at)
+);
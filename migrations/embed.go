@@ -0,0 +1,15 @@
+// Package migrations embeds the versioned SQL migrations into the binary.
+//
+// Files are named NNNN_description.up.sql / NNNN_description.down.sql and are
+// applied in version order by internal/migrate, starting from 0000_baseline,
+// the core tables. Never edit a migration that has been applied anywhere; add
+// a new one instead (checksums are verified on boot). A migration that cannot
+// be undone gets a down file of one "-- irreversible: <reason>" line.
+package migrations
+
+import "embed"
+
+// FS holds every *.sql file in this directory.
+//
+//go:embed *.sql
+var FS embed.FS